@@ -28,8 +28,47 @@ const (
 	BlobAssertionPropertyLastModified = "last-modified"
 	// BlobAssertionPropertySize defines the assertion property for the size of a blob object.
 	BlobAssertionPropertySize = "size"
+
+	// DockerAssertionsNamespace defines the namespace for docker image assertions. The subject
+	// of an assertion is an image reference as given to an exec (eg: "ubuntu:latest"),
+	// qualified by the CPU architecture of the image's variant (see DockerAssertionSubject).
+	DockerAssertionsNamespace = "docker"
+
+	// DockerAssertionPropertyDigest defines the assertion property for the digest of the image
+	// to which a docker image reference resolves.
+	DockerAssertionPropertyDigest = "digest"
 )
 
+// DockerAssertionSubject returns the subject of the docker assertion for the image
+// reference image, as resolved for the given CPU architecture, which is empty for
+// the default (amd64) architecture.
+func DockerAssertionSubject(image, arch string) string {
+	if arch == "" {
+		return image
+	}
+	return image + "#" + arch
+}
+
+// ParseDockerAssertionSubject returns the image reference and CPU architecture
+// of the given docker assertion subject.
+func ParseDockerAssertionSubject(subject string) (image, arch string) {
+	if i := strings.LastIndex(subject, "#"); i >= 0 {
+		return subject[:i], subject[i+1:]
+	}
+	return subject, ""
+}
+
+// DockerAssertions returns assertions that the image reference image resolves,
+// for the given CPU architecture, to the image with the digest reference ref
+// (eg: "ubuntu@sha256:...").
+func DockerAssertions(image, arch, ref string) *Assertions {
+	if i := strings.LastIndex(ref, "@"); i >= 0 {
+		ref = ref[i+1:]
+	}
+	k := AssertionKey{Subject: DockerAssertionSubject(image, arch), Namespace: DockerAssertionsNamespace}
+	return AssertionsFromEntry(k, map[string]string{DockerAssertionPropertyDigest: ref})
+}
+
 // AssertionKey represents a subject within a namespace whose properties can be asserted.
 // - Subject represents the unique entity within the Namespace to which this Assertion applies.
 //   (eg: full path to blob object, a Docker Image, etc)
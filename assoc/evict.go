@@ -0,0 +1,90 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package assoc
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/grailbio/base/data"
+	"github.com/grailbio/base/digest"
+)
+
+// An EvictionPolicy determines which cache entries are evicted from an
+// assoc. The policy is LRU: entries are retained in order of most
+// recent access, for as long as they have been accessed within the TTL
+// and their total size is within MaxBytes; the remaining entries are
+// evicted. A zero EvictionPolicy evicts nothing.
+type EvictionPolicy struct {
+	// TTL is the duration after their last access for which entries
+	// are retained. If zero, entries are not evicted by age.
+	TTL time.Duration
+	// MaxBytes is the budget for the total size of the entries'
+	// values. If zero, entries are not evicted by size.
+	MaxBytes int64
+}
+
+// String returns a description of the policy.
+func (p EvictionPolicy) String() string {
+	var (
+		ttl = "none"
+		max = "none"
+	)
+	if p.TTL > 0 {
+		ttl = p.TTL.String()
+	}
+	if p.MaxBytes > 0 {
+		max = data.Size(p.MaxBytes).String()
+	}
+	return fmt.Sprintf("ttl:%s maxsize:%s", ttl, max)
+}
+
+// IsZero tells whether the policy evicts nothing.
+func (p EvictionPolicy) IsZero() bool {
+	return p.TTL <= 0 && p.MaxBytes <= 0
+}
+
+// An Entry describes a cache entry for the purpose of eviction.
+type Entry struct {
+	// Key is the entry's key.
+	Key digest.Digest
+	// LastAccess is the time at which the entry was last accessed.
+	LastAccess time.Time
+	// Size is the size of the entry's value: for filesets, the total
+	// size of the files in them. Since files may be shared among
+	// filesets, the sum of entries' sizes overestimates the storage
+	// they use.
+	Size int64
+}
+
+// Evict returns the entries which are evicted under the policy at
+// time now, ordered from least to most recently accessed. The
+// provided entries are reordered.
+func (p EvictionPolicy) Evict(entries []Entry, now time.Time) []Entry {
+	if p.IsZero() {
+		return nil
+	}
+	// Retain the most recently accessed entries first.
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].LastAccess.After(entries[j].LastAccess)
+	})
+	var (
+		size int64
+		n    = len(entries)
+	)
+	for i, e := range entries {
+		if p.TTL > 0 && now.Sub(e.LastAccess) > p.TTL || p.MaxBytes > 0 && size+e.Size > p.MaxBytes {
+			n = i
+			break
+		}
+		size += e.Size
+	}
+	evicted := append([]Entry{}, entries[n:]...)
+	for i, j := 0, len(evicted)-1; i < j; i, j = i+1, j-1 {
+		evicted[i], evicted[j] = evicted[j], evicted[i]
+	}
+	return evicted
+}
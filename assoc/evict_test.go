@@ -0,0 +1,47 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package assoc
+
+import (
+	"crypto"
+	_ "crypto/sha256"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/grailbio/base/digest"
+)
+
+func TestEvictionPolicy(t *testing.T) {
+	var (
+		digester = digest.Digester(crypto.SHA256)
+		now      = time.Now()
+	)
+	entry := func(name string, age time.Duration, size int64) Entry {
+		return Entry{Key: digester.FromString(name), LastAccess: now.Add(-age), Size: size}
+	}
+	var (
+		a = entry("a", time.Hour, 10)
+		b = entry("b", 2*time.Hour, 20)
+		c = entry("c", 3*time.Hour, 30)
+		d = entry("d", 48*time.Hour, 1)
+	)
+	for _, tc := range []struct {
+		policy EvictionPolicy
+		want   []Entry
+	}{
+		{EvictionPolicy{}, nil},
+		{EvictionPolicy{TTL: 24 * time.Hour}, []Entry{d}},
+		{EvictionPolicy{MaxBytes: 30}, []Entry{d, c}},
+		{EvictionPolicy{MaxBytes: 5}, []Entry{d, c, b, a}},
+		{EvictionPolicy{TTL: 150 * time.Minute, MaxBytes: 100}, []Entry{d, c}},
+		{EvictionPolicy{MaxBytes: 100}, []Entry{}},
+	} {
+		got := tc.policy.Evict([]Entry{c, a, d, b}, now)
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.policy, got, tc.want)
+		}
+	}
+}
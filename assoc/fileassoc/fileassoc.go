@@ -0,0 +1,246 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+// Package fileassoc implements an assoc backed by a directory on the
+// local filesystem. It is intended for local (e.g., offline) runs, in
+// which the assoc is accessed by a single machine.
+package fileassoc
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/grailbio/base/digest"
+	"github.com/grailbio/infra"
+	"github.com/grailbio/reflow"
+	"github.com/grailbio/reflow/assoc"
+	"github.com/grailbio/reflow/errors"
+	"github.com/grailbio/reflow/liveset"
+)
+
+func init() {
+	infra.Register("fileassoc", new(Assoc))
+}
+
+// kinds are the kinds of associations stored by the assoc.
+var kinds = []assoc.Kind{assoc.Fileset, assoc.FilesetV2}
+
+// Assoc implements a filesystem-backed assoc.Assoc. Each association
+// is stored in a file named by its kind and key, of the form
+// Root/<kind>/<key[:2]>/<key[2:]>, which contains the association's
+// value. The file's modification time is the association's last
+// access time.
+type Assoc struct {
+	// Root is the directory in which associations are stored.
+	Root string
+}
+
+// Help implements infra.Provider.
+func (*Assoc) Help() string {
+	return "configure an assoc using a directory on the local filesystem"
+}
+
+// Flags implements infra.Provider.
+func (a *Assoc) Flags(flags *flag.FlagSet) {
+	flags.StringVar(&a.Root, "dir", "/tmp/flow/assoc", "directory in which associations are stored")
+}
+
+// Init implements infra.Provider.
+func (a *Assoc) Init() error {
+	return os.MkdirAll(a.Root, 0777)
+}
+
+// String returns a description of the assoc.
+func (a *Assoc) String() string {
+	return fmt.Sprintf("%T,dir=%s", a, a.Root)
+}
+
+// path returns the path of the file storing association k of the
+// provided kind.
+func (a *Assoc) path(kind assoc.Kind, k digest.Digest) string {
+	hex := k.Hex()
+	return filepath.Join(a.Root, strings.ToLower(kind.String()), hex[:2], hex[2:])
+}
+
+// Store implements assoc.Assoc.
+func (a *Assoc) Store(ctx context.Context, kind assoc.Kind, k, v digest.Digest) error {
+	path := a.path(kind, k)
+	if v.IsZero() {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return errors.E("store", k, err)
+		}
+		return nil
+	}
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return errors.E("store", k, err)
+	}
+	// Values are written to a temporary file which is then renamed,
+	// so that readers never observe partially written values.
+	f, err := ioutil.TempFile(dir, ".store-")
+	if err != nil {
+		return errors.E("store", k, err)
+	}
+	_, err = f.WriteString(v.String())
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return errors.E("store", k, err)
+	}
+	return nil
+}
+
+// Get implements assoc.Assoc. Abbreviated keys are not supported.
+func (a *Assoc) Get(ctx context.Context, kind assoc.Kind, k digest.Digest) (digest.Digest, digest.Digest, error) {
+	if k.IsAbbrev() {
+		return k, digest.Digest{}, errors.E("get", k, errors.NotSupported, errors.New("abbreviated keys are not supported"))
+	}
+	v, err := a.get(kind, k)
+	return k, v, err
+}
+
+// get returns the value of association k of the provided kind and
+// updates its access time.
+func (a *Assoc) get(kind assoc.Kind, k digest.Digest) (digest.Digest, error) {
+	path := a.path(kind, k)
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return digest.Digest{}, errors.E("get", k, errors.NotExist, err)
+	} else if err != nil {
+		return digest.Digest{}, errors.E("get", k, err)
+	}
+	v, err := reflow.Digester.Parse(strings.TrimSpace(string(b)))
+	if err != nil {
+		return digest.Digest{}, errors.E("get", k, errors.Invalid, err)
+	}
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+	return v, nil
+}
+
+// BatchGet implements assoc.Assoc.
+func (a *Assoc) BatchGet(ctx context.Context, batch assoc.Batch) error {
+	for key := range batch {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		v, err := a.get(key.Kind, key.Digest)
+		if errors.Is(errors.NotExist, err) {
+			err = nil
+		}
+		batch[key] = assoc.Result{Digest: v, Error: err}
+	}
+	return nil
+}
+
+// CollectWithThreshold is not supported by file assocs.
+func (a *Assoc) CollectWithThreshold(context.Context, liveset.Liveset, liveset.Liveset, time.Time, int64, bool) error {
+	return errors.E("collect", errors.NotSupported)
+}
+
+// Count implements assoc.Assoc.
+func (a *Assoc) Count(ctx context.Context) (int64, error) {
+	var n int64
+	err := a.walk(kinds, func(assoc.Kind, digest.Digest, string, os.FileInfo) error {
+		n++
+		return nil
+	})
+	return n, err
+}
+
+// Scan implements assoc.Assoc.
+func (a *Assoc) Scan(ctx context.Context, kinds []assoc.Kind, handler assoc.MappingHandler) error {
+	type mapping struct {
+		v          map[assoc.Kind]digest.Digest
+		lastAccess time.Time
+	}
+	mappings := make(map[digest.Digest]*mapping)
+	err := a.walk(kinds, func(kind assoc.Kind, k digest.Digest, path string, info os.FileInfo) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		v, err := reflow.Digester.Parse(strings.TrimSpace(string(b)))
+		if err != nil {
+			return errors.E("scan", path, errors.Invalid, err)
+		}
+		m := mappings[k]
+		if m == nil {
+			m = &mapping{v: make(map[assoc.Kind]digest.Digest)}
+			mappings[k] = m
+		}
+		m.v[kind] = v
+		if info.ModTime().After(m.lastAccess) {
+			m.lastAccess = info.ModTime()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for k, m := range mappings {
+		handler.HandleMapping(ctx, k, m.v, m.lastAccess, nil)
+	}
+	return nil
+}
+
+// Delete implements assoc.Assoc.
+func (a *Assoc) Delete(ctx context.Context, k digest.Digest) error {
+	var deleted bool
+	for _, kind := range kinds {
+		err := os.Remove(a.path(kind, k))
+		switch {
+		case err == nil:
+			deleted = true
+		case !os.IsNotExist(err):
+			return errors.E("delete", k, err)
+		}
+	}
+	if !deleted {
+		return errors.E("delete", k, errors.NotExist)
+	}
+	return nil
+}
+
+// walk calls fn for each association of the provided kinds.
+func (a *Assoc) walk(kinds []assoc.Kind, fn func(kind assoc.Kind, k digest.Digest, path string, info os.FileInfo) error) error {
+	for _, kind := range kinds {
+		root := filepath.Join(a.Root, strings.ToLower(kind.String()))
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) && path == root {
+					return nil
+				}
+				return err
+			}
+			if info.IsDir() || strings.HasPrefix(info.Name(), ".") {
+				return nil
+			}
+			k, err := reflow.Digester.Parse(filepath.Base(filepath.Dir(path)) + info.Name())
+			if err != nil {
+				// Skip files which are not associations.
+				return nil
+			}
+			return fn(kind, k, path, info)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
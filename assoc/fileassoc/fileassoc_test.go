@@ -0,0 +1,72 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package fileassoc
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/grailbio/base/digest"
+	"github.com/grailbio/reflow"
+	"github.com/grailbio/reflow/assoc"
+	"github.com/grailbio/reflow/errors"
+)
+
+func TestAssoc(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileassoc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var (
+		ctx  = context.Background()
+		ass  = &Assoc{Root: dir}
+		k, v = reflow.Digester.FromString("key"), reflow.Digester.FromString("value")
+		miss = reflow.Digester.FromString("missing")
+	)
+	if err := ass.Store(ctx, assoc.FilesetV2, k, v); err != nil {
+		t.Fatal(err)
+	}
+	if _, got, err := ass.Get(ctx, assoc.FilesetV2, k); err != nil || got != v {
+		t.Errorf("got %v, %v, want %v", got, err, v)
+	}
+	if _, _, err := ass.Get(ctx, assoc.Fileset, k); !errors.Is(errors.NotExist, err) {
+		t.Errorf("expected NotExist, got %v", err)
+	}
+	batch := make(assoc.Batch)
+	batch.Add(assoc.Key{Kind: assoc.FilesetV2, Digest: k}, assoc.Key{Kind: assoc.FilesetV2, Digest: miss})
+	if err := ass.BatchGet(ctx, batch); err != nil {
+		t.Fatal(err)
+	}
+	if !batch.Found(assoc.Key{Kind: assoc.FilesetV2, Digest: k}) || batch.Found(assoc.Key{Kind: assoc.FilesetV2, Digest: miss}) {
+		t.Errorf("unexpected batch result %v", batch)
+	}
+	var scanned []digest.Digest
+	err = ass.Scan(ctx, []assoc.Kind{assoc.FilesetV2}, assoc.MappingHandlerFunc(
+		func(k digest.Digest, vs map[assoc.Kind]digest.Digest, lastAccess time.Time, _ []string) {
+			if vs[assoc.FilesetV2] != v || lastAccess.IsZero() {
+				t.Errorf("unexpected mapping %v: %v %v", k, vs, lastAccess)
+			}
+			scanned = append(scanned, k)
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scanned) != 1 || scanned[0] != k {
+		t.Errorf("got %v, want %v", scanned, k)
+	}
+	if n, err := ass.Count(ctx); err != nil || n != 1 {
+		t.Errorf("got %v, %v, want 1", n, err)
+	}
+	if err := ass.Delete(ctx, k); err != nil {
+		t.Fatal(err)
+	}
+	if err := ass.Delete(ctx, k); !errors.Is(errors.NotExist, err) {
+		t.Errorf("expected NotExist, got %v", err)
+	}
+}
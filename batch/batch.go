@@ -20,6 +20,7 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 
 	"github.com/grailbio/base/digest"
 	"github.com/grailbio/base/limiter"
@@ -102,6 +103,8 @@ type Run struct {
 
 	batch *Batch
 	log   *log.Logger
+	// retried tells whether the run failed and was reset for retry.
+	retried bool
 }
 
 // Go runs the run according to its state. If the run was previously
@@ -281,10 +284,18 @@ type Batch struct {
 	// Limiterr should be set prior to running the batch.
 	Limiter *limiter.Limiter
 
-	file   *state.File
-	states map[string]*state.File
-	config config
-	flow   *flow.Flow
+	// Watch, if nonzero, is the interval at which the runs file is
+	// polled while the batch is running. Runs appended to the runs
+	// file are added to the (running) batch.
+	Watch time.Duration
+
+	// mu protects Runs, states and excluded while the batch is running.
+	mu       sync.Mutex
+	file     *state.File
+	states   map[string]*state.File
+	excluded map[string]bool
+	config   config
+	flow     *flow.Flow
 }
 
 // BatchState identifies a batch. It has a unique identifier based on the program and the batch being run.
@@ -372,7 +383,8 @@ func (b *Batch) Close() {
 
 // Run runs the batch until completion, too many errors, or context
 // completion. Run reports batch progress to the batch's logger every
-// 10 seconds.
+// 10 seconds. If Watch is nonzero, runs appended to the runs file
+// while the batch is running are added to it and run.
 func (b *Batch) Run(ctx context.Context) error {
 	done := make(chan *Run)
 	var wg sync.WaitGroup
@@ -381,9 +393,8 @@ func (b *Batch) Run(ctx context.Context) error {
 			wg.Add(1)
 		}
 	}
-	b.Status.Printf("remaining: %d", len(b.Runs))
-	for _, run := range b.Runs {
-		go func(run *Run) {
+	start := func(run *Run) {
+		go func() {
 			err := run.Go(ctx, &wg)
 			switch {
 			case ctx.Err() != nil:
@@ -393,21 +404,63 @@ func (b *Batch) Run(ctx context.Context) error {
 				b.Log.Printf("run %v: done: %v", run.ID, run.State.Result)
 			}
 			done <- run
-		}(run)
+		}()
+	}
+	b.Status.Printf("remaining: %d", len(b.Runs))
+	for _, run := range b.Runs {
+		start(run)
 	}
-	n := 0
-	for n < len(b.Runs) {
+	var watch <-chan time.Time
+	if b.Watch > 0 {
+		ticker := time.NewTicker(b.Watch)
+		defer ticker.Stop()
+		watch = ticker.C
+	}
+	n, total := 0, len(b.Runs)
+	for n < total {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
 		case <-done:
 			n++
-			b.Status.Printf("remaining: %d", len(b.Runs)-n)
+		case <-watch:
+			added, err := b.readAdded()
+			if err != nil {
+				b.Log.Errorf("read %s: %v", b.config.RunsFile, err)
+			}
+			for _, run := range added {
+				b.Log.Printf("run %v: added", run.ID)
+				start(run)
+			}
+			total += len(added)
 		}
+		b.Status.Printf("remaining: %d", total-n)
 	}
 	return nil
 }
 
+// RetryOnly restricts the batch to the runs that failed and were
+// reset for retry by Init.
+func (b *Batch) RetryOnly() {
+	for id, run := range b.Runs {
+		if !run.retried {
+			b.Exclude(id)
+		}
+	}
+}
+
+// Exclude removes the run with the given id from the batch. The run
+// is not added back when the runs file is watched.
+func (b *Batch) Exclude(id string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.Runs, id)
+	if b.excluded == nil {
+		b.excluded = make(map[string]bool)
+	}
+	b.excluded[id] = true
+}
+
 // ReadState populates the current state of the batch run.
 func (b *Batch) ReadState() error {
 	var err error
@@ -441,89 +494,155 @@ func (b *Batch) ReadState() error {
 	return nil
 }
 
-func (b *Batch) read(retry bool) error {
+// runSpec is a run as specified by a row of the runs file.
+type runSpec struct {
+	id   string
+	args map[string]string
+	argv []string
+}
+
+// readSpecs reads the runs file.
+func (b *Batch) readSpecs() ([]runSpec, error) {
 	f, err := os.Open(b.path(b.config.RunsFile))
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer f.Close()
 	r := csv.NewReader(f)
 	r.FieldsPerRecord = -1
 	records, err := r.ReadAll()
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if len(records) < 1 {
-		return errors.New("empty batch")
+		return nil, errors.New("empty batch")
 	}
 	header := records[0]
 	records = records[1:]
-	runs := map[string]*Run{}
-	for _, fields := range records {
+	specs := make([]runSpec, len(records))
+	for i, fields := range records {
 		if len(fields) != len(header) {
-			return errors.Errorf("batch file row [%v] has %v fields, need %v", fields, len(fields), len(header))
+			return nil, errors.Errorf("batch file row [%v] has %v fields, need %v", fields, len(fields), len(header))
 		}
-		id := fields[0]
-		attrs := map[string]string{}
+		spec := runSpec{id: fields[0], args: map[string]string{}, argv: fields[len(header):]}
 		for j := 1; j < len(header); j++ {
-			attrs[header[j]] = fields[j]
+			spec.args[header[j]] = fields[j]
 		}
-		run := b.Runs[id]
-		if run == nil {
-			run = new(Run)
-			// Create fresh run ID the first time we encountered a run.
-			run.RunID = taskdb.NewRunID()
-		}
-		run.ID = id
-		run.Args = attrs
-		run.Argv = fields[len(header):]
-		run.Program = b.path(b.config.Program)
-		var prevRunID taskdb.RunID
-
-		if run.RunID.IsValid() {
-			prevRunID = run.RunID
+		specs[i] = spec
+	}
+	return specs, nil
+}
+
+func (b *Batch) read(retry bool) error {
+	specs, err := b.readSpecs()
+	if err != nil {
+		return err
+	}
+	runs := map[string]*Run{}
+	for _, spec := range specs {
+		if runs[spec.id], err = b.initRun(b.Runs[spec.id], spec, retry); err != nil {
+			return err
 		}
-		// Assign a new run id, and restore state, if any, from the prev run id.
-		run.RunID = taskdb.NewRunID()
-		prefix := filepath.Join(b.Rundir, run.RunID.Hex())
-		if prevRunID.IsValid() {
-			var prevState runner.State
-			if err = state.Unmarshal(filepath.Join(b.Rundir, prevRunID.Hex()), &prevState); err != nil && err != state.ErrNoState {
-				return err
-			}
-			if err = state.Marshal(prefix, prevState); err != nil {
-				return err
-			}
+	}
+	b.Runs = runs
+	b.commit(nil)
+	return nil
+}
+
+// readAdded reads the runs file and initializes the runs which were
+// appended to it since the batch was started. These are added to the
+// batch and returned.
+func (b *Batch) readAdded() ([]*Run, error) {
+	specs, err := b.readSpecs()
+	if err != nil {
+		return nil, err
+	}
+	var added []*Run
+	for _, spec := range specs {
+		b.mu.Lock()
+		_, ok := b.Runs[spec.id]
+		ok = ok || b.excluded[spec.id]
+		b.mu.Unlock()
+		if ok {
+			continue
 		}
-		if retry {
-			switch run.State.Phase {
-			case runner.Done, runner.Retry:
-				if run.State.Err != nil {
-					run.State.Reset()
-					b.Log.Printf("retrying run %v\n", id)
-				}
-			}
+		run, err := b.initRun(nil, spec, false)
+		if err != nil {
+			return added, err
 		}
-		run.Status = b.Status.Start(run.RunID.IDShort())
-		run.Status.Print("waiting")
-		run.batch = b
+		b.mu.Lock()
+		b.Runs[spec.id] = run
+		b.mu.Unlock()
+		added = append(added, run)
+	}
+	if len(added) > 0 {
+		b.commit(nil)
+	}
+	return added, nil
+}
 
-		b.states[id], err = state.Open(filepath.Join(prefix))
-		if err != nil {
-			return err
+// initRun initializes the run with the given spec, restoring its
+// state from run, its previous incarnation (if any). If retry is set,
+// the run is reset if it previously failed.
+func (b *Batch) initRun(run *Run, spec runSpec, retry bool) (*Run, error) {
+	id := spec.id
+	if run == nil {
+		run = new(Run)
+		// Create fresh run ID the first time we encountered a run.
+		run.RunID = taskdb.NewRunID()
+	}
+	run.ID = id
+	run.Args = spec.args
+	run.Argv = spec.argv
+	run.Program = b.path(b.config.Program)
+	var prevRunID taskdb.RunID
+
+	if run.RunID.IsValid() {
+		prevRunID = run.RunID
+	}
+	// Assign a new run id, and restore state, if any, from the prev run id.
+	run.RunID = taskdb.NewRunID()
+	prefix := filepath.Join(b.Rundir, run.RunID.Hex())
+	if prevRunID.IsValid() {
+		var prevState runner.State
+		if err := state.Unmarshal(filepath.Join(b.Rundir, prevRunID.Hex()), &prevState); err != nil && err != state.ErrNoState {
+			return nil, err
 		}
-		if err := b.states[id].Unmarshal(&run.State); err != nil && err != state.ErrNoState {
-			return err
+		if err := state.Marshal(prefix, prevState); err != nil {
+			return nil, err
 		}
-		if !run.State.ID.IsValid() {
-			run.State.ID = run.RunID
+	}
+	run.Status = b.Status.Start(run.RunID.IDShort())
+	run.Status.Print("waiting")
+	run.batch = b
+
+	file, err := state.Open(filepath.Join(prefix))
+	if err != nil {
+		return nil, err
+	}
+	b.mu.Lock()
+	b.states[id] = file
+	b.mu.Unlock()
+	if err := file.Unmarshal(&run.State); err != nil && err != state.ErrNoState {
+		return nil, err
+	}
+	if !run.State.ID.IsValid() {
+		run.State.ID = run.RunID
+	}
+	// The run's state is restored above, so that failed runs may be
+	// identified.
+	if retry {
+		switch run.State.Phase {
+		case runner.Done, runner.Retry:
+			if run.State.Err != nil {
+				run.State.Reset()
+				run.retried = true
+				b.Log.Printf("retrying run %v\n", id)
+			}
 		}
-		runs[id] = run
-		b.commit(run)
 	}
-	b.Runs = runs
-	b.commit(nil)
-	return nil
+	b.commit(run)
+	return run, nil
 }
 
 func (b *Batch) path(path string) string {
@@ -534,6 +653,8 @@ func (b *Batch) path(path string) string {
 }
 
 func (b *Batch) commit(run *Run) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
 	if run == nil {
 		b.file.LockLocal()
 		if err := b.file.Marshal(b.BatchState); err != nil {
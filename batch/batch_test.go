@@ -0,0 +1,81 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package batch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/grailbio/base/status"
+	"github.com/grailbio/reflow/errors"
+	"github.com/grailbio/reflow/runner"
+	"github.com/grailbio/testutil"
+)
+
+func newTestBatch(dir string) *Batch {
+	return &Batch{
+		Dir:            dir,
+		ConfigFilename: "config.json",
+		Rundir:         filepath.Join(dir, "runs"),
+		Status:         new(status.Status).Group("batch"),
+	}
+}
+
+func TestBatchRetryAndAdd(t *testing.T) {
+	dir, cleanup := testutil.TempDir(t, "", "batch")
+	defer cleanup()
+	if err := os.MkdirAll(filepath.Join(dir, "runs"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	write := func(name, contents string) {
+		t.Helper()
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("config.json", `{"program": "test.rf", "runs_file": "runs.csv"}`)
+	write("runs.csv", "id,a\n1,x\n2,y\n")
+
+	b := newTestBatch(dir)
+	if err := b.Init(false, false); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(b.Runs), 2; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	failed := b.Runs["1"]
+	failed.State.Phase = runner.Done
+	failed.State.Err = errors.Recover(errors.New("failed"))
+	b.commit(failed)
+	b.Close()
+
+	b = newTestBatch(dir)
+	if err := b.Init(false, true); err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	b.RetryOnly()
+	if got, want := len(b.Runs), 1; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if run := b.Runs["1"]; run == nil || run.State.Phase != runner.Init {
+		t.Fatalf("run 1 was not reset: %v", run)
+	}
+
+	write("runs.csv", "id,a\n1,x\n2,y\n3,z\n")
+	added, err := b.readAdded()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Run 2, which was excluded by RetryOnly, is not added.
+	if got, want := len(added), 1; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got, want := b.Runs["3"].Args["a"], "z"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -78,6 +78,15 @@ type Bucket interface {
 	Location() string
 }
 
+// A RangeGetter is a Bucket which can retrieve byte ranges of the
+// objects it stores.
+type RangeGetter interface {
+	// GetRange returns a reader of length bytes of the contents at the
+	// provided key, starting at the given offset. If the provided ETag
+	// is nonempty, then it is taken as a precondition for fetching.
+	GetRange(ctx context.Context, key, etag string, offset, length int64) (io.ReadCloser, error)
+}
+
 // A Scanner scans keys in a bucket. Scanners are provided by
 // Bucket implementations. Scanning commences after the first
 // call to Scan.
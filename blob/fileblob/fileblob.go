@@ -0,0 +1,459 @@
+// Copyright 2018 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+// Package fileblob implements the blob interfaces for local and
+// shared (e.g., NFS or Lustre) POSIX filesystems, under the file://
+// scheme. URLs name absolute paths, as in file:///mnt/filer/data/;
+// the store presents a single bucket (with an empty name) rooted
+// at the filesystem root.
+//
+// Access is confined to an allow-list of root directories: the
+// store refuses to read, write or list files outside of its roots,
+// and a store without roots refuses all access.
+//
+// Files are identified by their size and modification time. Since
+// modification times on shared filesystems may be coarse or
+// unreliable, the store can optionally identify files by the
+// checksum of their contents instead, at the cost of reading every
+// file that is inspected.
+package fileblob
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/grailbio/base/digest"
+	"github.com/grailbio/reflow"
+	"github.com/grailbio/reflow/blob"
+	"github.com/grailbio/reflow/errors"
+)
+
+// Scheme is the URL scheme served by the store.
+const Scheme = "file"
+
+// Store implements blob.Store for POSIX filesystems.
+type Store struct {
+	// Checksum identifies files by the checksums of their contents
+	// (which are then also used as their ETags and content hashes)
+	// instead of by their size and modification time.
+	Checksum bool
+	// Roots are the absolute paths of the directories under which
+	// files may be accessed.
+	Roots []string
+}
+
+// Bucket returns the filesystem bucket. Only the (unnamed) local
+// host is supported: file URLs must be of the form file:///path.
+func (s *Store) Bucket(ctx context.Context, name string) (blob.Bucket, error) {
+	if name != "" {
+		return nil, errors.E("fileblob.Bucket", name, errors.NotSupported,
+			errors.New("file URLs must name local paths (file:///path)"))
+	}
+	if len(s.Roots) == 0 {
+		return nil, errors.E("fileblob.Bucket", Scheme+":///", errors.NotAllowed,
+			errors.New("no roots are configured for file URLs"))
+	}
+	roots := make([]string, len(s.Roots))
+	for i, root := range s.Roots {
+		if !filepath.IsAbs(root) {
+			return nil, errors.E("fileblob.Bucket", root, errors.Invalid,
+				errors.New("roots must be absolute paths"))
+		}
+		roots[i] = filepath.Clean(root)
+	}
+	return &Bucket{checksum: s.Checksum, roots: roots}, nil
+}
+
+// Bucket implements blob.Bucket for a POSIX filesystem. Keys are
+// paths relative to the filesystem root.
+type Bucket struct {
+	checksum bool
+	roots    []string
+}
+
+// File returns the metadata of the file at the provided key.
+func (b *Bucket) File(ctx context.Context, key string) (reflow.File, error) {
+	if err := b.allow("fileblob.File", key); err != nil {
+		return reflow.File{}, err
+	}
+	info, err := os.Stat(b.path(key))
+	if err != nil {
+		return reflow.File{}, errors.E("fileblob.File", b.url(key), kind(err), err)
+	}
+	if !info.Mode().IsRegular() {
+		return reflow.File{}, errors.E("fileblob.File", b.url(key), errors.NotExist, errors.New("not a regular file"))
+	}
+	file, err := b.file(key, info)
+	if err != nil {
+		return reflow.File{}, errors.E("fileblob.File", b.url(key), err)
+	}
+	return file, nil
+}
+
+// Scan returns a scanner for the regular files whose keys have the
+// provided prefix. Files are scanned in lexical order of their keys.
+func (b *Bucket) Scan(prefix string) blob.Scanner {
+	return &scanner{bucket: b, prefix: prefix}
+}
+
+// Download copies the contents of the file at the provided key into
+// the provided writer. If the provided etag is nonempty, then it is
+// taken as a precondition for copying.
+func (b *Bucket) Download(ctx context.Context, key, etag string, size int64, w io.WriterAt) (int64, error) {
+	f, _, err := b.open(key, etag)
+	if err != nil {
+		return 0, errors.E("fileblob.Download", b.url(key), err)
+	}
+	defer f.Close()
+	var (
+		buf = make([]byte, 1<<20)
+		n   int64
+	)
+	for {
+		if err = ctx.Err(); err != nil {
+			return n, errors.E("fileblob.Download", b.url(key), err)
+		}
+		m, rerr := f.Read(buf)
+		if m > 0 {
+			if _, err = w.WriteAt(buf[:m], n); err != nil {
+				return n, errors.E("fileblob.Download", b.url(key), err)
+			}
+			n += int64(m)
+		}
+		if rerr == io.EOF {
+			return n, nil
+		}
+		if rerr != nil {
+			return n, errors.E("fileblob.Download", b.url(key), kind(rerr), rerr)
+		}
+	}
+}
+
+// Get returns a reader for the contents of the file at the provided
+// key. If the provided etag is nonempty, then it is taken as a
+// precondition for reading.
+func (b *Bucket) Get(ctx context.Context, key, etag string) (io.ReadCloser, reflow.File, error) {
+	f, file, err := b.open(key, etag)
+	if err != nil {
+		return nil, reflow.File{}, errors.E("fileblob.Get", b.url(key), err)
+	}
+	return f, file, nil
+}
+
+// GetRange returns a reader of length bytes of the file at the
+// provided key, starting at the given offset. It implements
+// blob.RangeGetter.
+func (b *Bucket) GetRange(ctx context.Context, key, etag string, offset, length int64) (io.ReadCloser, error) {
+	f, _, err := b.open(key, etag)
+	if err != nil {
+		return nil, errors.E("fileblob.GetRange", b.url(key), err)
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.NewSectionReader(f, offset, length), f}, nil
+}
+
+// Put writes the provided body to the file at the provided key,
+// creating any parent directories as needed. The file is replaced
+// atomically, so that readers never observe partial contents. The
+// content hash is not stored, as POSIX filesystems provide no
+// portable means of attaching metadata to files.
+func (b *Bucket) Put(ctx context.Context, key string, size int64, body io.Reader, contentHash string) error {
+	if err := b.allow("fileblob.Put", key); err != nil {
+		return err
+	}
+	path := b.path(key)
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return errors.E("fileblob.Put", b.url(key), kind(err), err)
+	}
+	f, err := ioutil.TempFile(dir, "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return errors.E("fileblob.Put", b.url(key), kind(err), err)
+	}
+	_, err = io.Copy(f, body)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		// TempFile creates files which are readable only by their
+		// owner; files on shared filesystems should be readable by
+		// others.
+		err = os.Chmod(f.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return errors.E("fileblob.Put", b.url(key), kind(err), err)
+	}
+	return nil
+}
+
+// Snapshot returns an un-loaded Reflow fileset representing the
+// contents of the provided prefix.
+func (b *Bucket) Snapshot(ctx context.Context, prefix string) (reflow.Fileset, error) {
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		file, err := b.File(ctx, prefix)
+		if err != nil {
+			return reflow.Fileset{}, errors.E("fileblob.Snapshot", b.url(prefix), err)
+		}
+		return reflow.Fileset{Map: map[string]reflow.File{".": file}}, nil
+	}
+	var (
+		dir  = reflow.Fileset{Map: make(map[string]reflow.File)}
+		scan = b.Scan(prefix)
+	)
+	for scan.Scan(ctx) {
+		dir.Map[scan.Key()[len(prefix):]] = scan.File()
+	}
+	return dir, scan.Err()
+}
+
+// Copy copies the file at key src to key dst.
+func (b *Bucket) Copy(ctx context.Context, src, dst, contentHash string) error {
+	if err := b.allow("fileblob.Copy", src); err != nil {
+		return err
+	}
+	f, err := os.Open(b.path(src))
+	if err != nil {
+		return errors.E("fileblob.Copy", b.url(src), kind(err), err)
+	}
+	defer f.Close()
+	if err := b.Put(ctx, dst, 0, f, contentHash); err != nil {
+		return errors.E("fileblob.Copy", b.url(src), err)
+	}
+	return nil
+}
+
+// CopyFrom copies from bucket src and key srcKey into this bucket.
+// Only filesystem buckets are supported.
+func (b *Bucket) CopyFrom(ctx context.Context, srcBucket blob.Bucket, src, dst string) error {
+	if _, ok := srcBucket.(*Bucket); !ok {
+		return errors.E(errors.NotSupported, "fileblob.CopyFrom", srcBucket.Location())
+	}
+	return b.Copy(ctx, src, dst, "")
+}
+
+// Delete removes the files at the provided keys.
+func (b *Bucket) Delete(ctx context.Context, keys ...string) error {
+	for _, key := range keys {
+		if err := b.allow("fileblob.Delete", key); err != nil {
+			return err
+		}
+		if err := os.Remove(b.path(key)); err != nil && !os.IsNotExist(err) {
+			return errors.E("fileblob.Delete", b.url(key), kind(err), err)
+		}
+	}
+	return nil
+}
+
+// Location returns the URL of the filesystem root, file:///.
+func (b *Bucket) Location() string {
+	return Scheme + ":///"
+}
+
+func (b *Bucket) url(key string) string {
+	return b.Location() + key
+}
+
+func (b *Bucket) path(key string) string {
+	return "/" + key
+}
+
+// allowed tells whether the file at the provided key lies under one
+// of the bucket's roots.
+func (b *Bucket) allowed(key string) bool {
+	path := filepath.Clean(b.path(key))
+	for _, root := range b.roots {
+		if root == "/" || path == root || strings.HasPrefix(path, root+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// allow returns an errors.NotAllowed error for the operation op if
+// the file at the provided key does not lie under the bucket's roots.
+func (b *Bucket) allow(op, key string) error {
+	if b.allowed(key) {
+		return nil
+	}
+	return errors.E(op, b.url(key), errors.NotAllowed,
+		errors.Errorf("path is not under any of the configured roots (%s)", strings.Join(b.roots, ", ")))
+}
+
+// open opens the file at the provided key, checking the provided
+// etag (if any) as a precondition.
+func (b *Bucket) open(key, etag string) (*os.File, reflow.File, error) {
+	if err := b.allow("fileblob.open", key); err != nil {
+		return nil, reflow.File{}, err
+	}
+	f, err := os.Open(b.path(key))
+	if err != nil {
+		return nil, reflow.File{}, errors.E(kind(err), err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, reflow.File{}, errors.E(kind(err), err)
+	}
+	file, err := b.file(key, info)
+	if err == nil && etag != "" && file.ETag != etag {
+		err = errors.E(errors.Precondition, errors.Errorf("etag %s (expected) != %s (actual)", etag, file.ETag))
+	}
+	if err != nil {
+		f.Close()
+		return nil, reflow.File{}, err
+	}
+	return f, file, nil
+}
+
+// file returns the metadata of the file at the provided key, with
+// the provided file info.
+func (b *Bucket) file(key string, info os.FileInfo) (reflow.File, error) {
+	file := reflow.File{
+		Source:       b.url(key),
+		Size:         info.Size(),
+		LastModified: info.ModTime(),
+	}
+	if !b.checksum {
+		// As is conventional for web servers serving static files, the
+		// ETag is derived from the file's modification time and size.
+		file.ETag = fmt.Sprintf("%x-%x", info.ModTime().UnixNano(), info.Size())
+		return file, nil
+	}
+	d, err := checksum(b.path(key))
+	if err != nil {
+		return reflow.File{}, err
+	}
+	file.ContentHash = d
+	file.ETag = d.Hex()
+	return file, nil
+}
+
+// checksum returns the digest of the contents of the file at the
+// provided path.
+func checksum(path string) (digest.Digest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return digest.Digest{}, errors.E(kind(err), err)
+	}
+	defer f.Close()
+	w := reflow.Digester.NewWriter()
+	if _, err := io.Copy(w, f); err != nil {
+		return digest.Digest{}, errors.E(kind(err), err)
+	}
+	return w.Digest(), nil
+}
+
+// scanner implements blob.Scanner. The files under the prefix are
+// listed on the first call to Scan.
+type scanner struct {
+	bucket *Bucket
+	prefix string
+
+	started bool
+	keys    []string
+	infos   []os.FileInfo
+	file    reflow.File
+	err     error
+}
+
+func (s *scanner) Scan(ctx context.Context) bool {
+	if s.err != nil {
+		return false
+	}
+	if !s.started {
+		s.started = true
+		if s.err = s.list(); s.err != nil {
+			return false
+		}
+	} else {
+		s.keys, s.infos = s.keys[1:], s.infos[1:]
+	}
+	if len(s.keys) == 0 {
+		return false
+	}
+	if s.err = ctx.Err(); s.err != nil {
+		return false
+	}
+	s.file, s.err = s.bucket.file(s.keys[0], s.infos[0])
+	return s.err == nil
+}
+
+// list lists the regular files whose keys have the scanner's prefix.
+// Only files under the bucket's roots are listed; in particular, a
+// prefix which is not itself under a root is refused, so that a scan
+// never walks the whole filesystem.
+func (s *scanner) list() error {
+	if err := s.bucket.allow("fileblob.Scan", s.prefix); err != nil {
+		return err
+	}
+	// Walk the deepest directory that contains all keys with the prefix.
+	root := s.bucket.path(s.prefix)
+	if !strings.HasSuffix(root, "/") {
+		root = filepath.Dir(root)
+	}
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == root {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		key := strings.TrimPrefix(path, "/")
+		if strings.HasPrefix(key, s.prefix) && s.bucket.allowed(key) {
+			s.keys = append(s.keys, key)
+			s.infos = append(s.infos, info)
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.E("fileblob.Scan", s.bucket.url(s.prefix), kind(err), err)
+	}
+	// Walk visits entries in lexical order of their names within each
+	// directory, which is not necessarily the lexical order of keys.
+	sort.Sort(byKey{s})
+	return nil
+}
+
+func (s *scanner) Err() error        { return s.err }
+func (s *scanner) File() reflow.File { return s.file }
+func (s *scanner) Key() string       { return s.keys[0] }
+
+type byKey struct{ *scanner }
+
+func (b byKey) Len() int           { return len(b.keys) }
+func (b byKey) Less(i, j int) bool { return b.keys[i] < b.keys[j] }
+func (b byKey) Swap(i, j int) {
+	b.keys[i], b.keys[j] = b.keys[j], b.keys[i]
+	b.infos[i], b.infos[j] = b.infos[j], b.infos[i]
+}
+
+// kind returns the error kind of the provided filesystem error.
+func kind(err error) errors.Kind {
+	switch {
+	case os.IsNotExist(err):
+		return errors.NotExist
+	case os.IsPermission(err):
+		return errors.NotAllowed
+	case err == context.Canceled:
+		return errors.Canceled
+	case err == context.DeadlineExceeded:
+		return errors.Timeout
+	}
+	return errors.Other
+}
@@ -0,0 +1,165 @@
+// Copyright 2018 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package fileblob
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grailbio/reflow"
+	"github.com/grailbio/reflow/blob"
+	"github.com/grailbio/reflow/errors"
+)
+
+func TestFileBlob(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileblob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var (
+		ctx    = context.Background()
+		mux    = blob.Mux{Scheme: &Store{Roots: []string{dir}}}
+		prefix = "file://" + dir + "/"
+	)
+	for _, name := range []string{"a", "b/c", "b/d", "b-e"} {
+		if err := mux.Put(ctx, prefix+name, 0, strings.NewReader(name), ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	file, err := mux.File(ctx, prefix+"b/c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := file.Source, prefix+"b/c"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := file.Size, int64(3); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if file.ETag == "" || file.LastModified.IsZero() {
+		t.Errorf("incomplete metadata %v", file)
+	}
+
+	scan, err := mux.Scan(ctx, prefix+"b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for scan.Scan(ctx) {
+		keys = append(keys, strings.TrimPrefix(scan.Key(), strings.TrimPrefix(dir, "/")+"/"))
+	}
+	if err := scan.Err(); err != nil {
+		t.Fatal(err)
+	}
+	// Keys are scanned in lexical order, even across directories.
+	if got, want := keys, []string{"b-e", "b/c", "b/d"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	fs, err := mux.Snapshot(ctx, prefix+"b/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fs.N(), 2; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// Modifying the file invalidates its ETag.
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "b/c"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := mux.Get(ctx, prefix+"b/c", file.ETag); !errors.Is(errors.Precondition, err) {
+		t.Errorf("expected precondition error, got %v", err)
+	}
+	if _, err := mux.File(ctx, prefix+"missing"); !errors.Is(errors.NotExist, err) {
+		t.Errorf("expected NotExist, got %v", err)
+	}
+}
+
+func TestFileBlobChecksum(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileblob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var (
+		ctx = context.Background()
+		mux = blob.Mux{Scheme: &Store{Checksum: true, Roots: []string{dir}}}
+		url = "file://" + dir + "/x"
+	)
+	if err := mux.Put(ctx, url, 0, strings.NewReader("contents"), ""); err != nil {
+		t.Fatal(err)
+	}
+	file, err := mux.File(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := reflow.Digester.NewWriter()
+	w.Write([]byte("contents"))
+	if got, want := file.ContentHash, w.Digest(); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	// The ETag depends only on the file's contents.
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "x"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	rc, _, err := mux.Get(ctx, url, file.ETag)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc.Close()
+}
+
+func TestFileBlobRoots(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileblob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	root := filepath.Join(dir, "root")
+	var (
+		ctx = context.Background()
+		mux = blob.Mux{Scheme: &Store{Roots: []string{root}}}
+	)
+	if err := mux.Put(ctx, "file://"+root+"/a", 0, strings.NewReader("a"), ""); err != nil {
+		t.Fatal(err)
+	}
+	for _, url := range []string{
+		"file://" + dir + "/b",
+		"file://" + root + "/../b",
+		"file://" + root + "2/b",
+	} {
+		if err := mux.Put(ctx, url, 0, strings.NewReader("b"), ""); !errors.Is(errors.NotAllowed, err) {
+			t.Errorf("put %s: expected NotAllowed, got %v", url, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "b")); !os.IsNotExist(err) {
+		t.Errorf("expected no file to be written outside of the root, got %v", err)
+	}
+	for _, url := range []string{"file:///", "file://" + dir + "/"} {
+		if _, err := mux.Snapshot(ctx, url); !errors.Is(errors.NotAllowed, err) {
+			t.Errorf("snapshot %s: expected NotAllowed, got %v", url, err)
+		}
+	}
+	fs, err := mux.Snapshot(ctx, "file://"+root+"/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fs.N(), 1; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if _, err := (blob.Mux{Scheme: new(Store)}).File(ctx, "file://"+root+"/a"); !errors.Is(errors.NotAllowed, err) {
+		t.Errorf("expected NotAllowed without roots, got %v", err)
+	}
+}
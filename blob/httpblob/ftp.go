@@ -0,0 +1,243 @@
+// Copyright 2018 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package httpblob
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grailbio/reflow"
+	"github.com/grailbio/reflow/errors"
+)
+
+// ftpConn is a minimal (anonymous, passive mode) FTP client
+// connection, sufficient to retrieve object metadata and contents.
+type ftpConn struct {
+	*textproto.Conn
+	host string
+	// done is closed when the connection is closed, to stop
+	// watching the context.
+	done chan struct{}
+}
+
+// dialFTP dials and logs in to the FTP server at the provided host
+// (which may include a port). The connection is closed when the
+// context is done.
+func dialFTP(ctx context.Context, host string) (*ftpConn, error) {
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(host, "21")
+	}
+	var d net.Dialer
+	nc, err := d.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return nil, errors.E(errors.Net, err)
+	}
+	c := &ftpConn{Conn: textproto.NewConn(nc), host: host, done: make(chan struct{})}
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.Conn.Close()
+		case <-c.done:
+		}
+	}()
+	if _, _, err = c.ReadResponse(220); err != nil {
+		c.Close()
+		return nil, ftpError(err)
+	}
+	code, _, err := c.cmd(0, "USER anonymous")
+	if err == nil && code == 331 {
+		_, _, err = c.cmd(230, "PASS anonymous@")
+	} else if err == nil && code != 230 {
+		err = errors.Errorf("USER: unexpected response code %d", code)
+	}
+	if err == nil {
+		_, _, err = c.cmd(200, "TYPE I")
+	}
+	if err != nil {
+		c.Close()
+		return nil, ftpError(err)
+	}
+	return c, nil
+}
+
+// cmd issues the provided command and reads its response, which is
+// expected to have the provided code. If code is 0, any code is
+// accepted.
+func (c *ftpConn) cmd(code int, format string, args ...interface{}) (int, string, error) {
+	id, err := c.Cmd(format, args...)
+	if err != nil {
+		return 0, "", err
+	}
+	c.StartResponse(id)
+	defer c.EndResponse(id)
+	return c.ReadResponse(code)
+}
+
+// Close closes the connection.
+func (c *ftpConn) Close() error {
+	select {
+	case <-c.done:
+	default:
+		close(c.done)
+	}
+	return c.Conn.Close()
+}
+
+// size returns the size of the file at the provided path.
+func (c *ftpConn) size(path string) (int64, error) {
+	_, msg, err := c.cmd(213, "SIZE %s", path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(msg), 10, 64)
+}
+
+// modTime returns the modification time of the file at the provided
+// path. Servers that do not support the (optional) MDTM command
+// yield the zero time.
+func (c *ftpConn) modTime(path string) time.Time {
+	_, msg, err := c.cmd(213, "MDTM %s", path)
+	if err != nil {
+		return time.Time{}
+	}
+	// The time is formatted as YYYYMMDDhhmmss, optionally
+	// followed by fractional seconds, and is always in UTC.
+	msg = strings.TrimSpace(msg)
+	if len(msg) < 14 {
+		return time.Time{}
+	}
+	t, err := time.Parse("20060102150405", msg[:14])
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// retrieve returns a reader for the contents of the file at the
+// provided path. The connection is closed when the reader is closed.
+func (c *ftpConn) retrieve(ctx context.Context, path string) (io.ReadCloser, error) {
+	_, msg, err := c.cmd(229, "EPSV")
+	if err != nil {
+		return nil, err
+	}
+	// The response is of the form "Entering Extended Passive Mode (|||port|)".
+	start, end := strings.Index(msg, "(|||"), strings.LastIndex(msg, "|)")
+	if start < 0 || end < start+4 {
+		return nil, errors.Errorf("EPSV: invalid response %q", msg)
+	}
+	port := msg[start+4 : end]
+	host, _, err := net.SplitHostPort(c.host)
+	if err != nil {
+		return nil, err
+	}
+	var d net.Dialer
+	dc, err := d.DialContext(ctx, "tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		return nil, err
+	}
+	id, err := c.Cmd("RETR %s", path)
+	if err == nil {
+		c.StartResponse(id)
+		_, _, err = c.ReadResponse(1)
+		c.EndResponse(id)
+	}
+	if err != nil {
+		dc.Close()
+		return nil, err
+	}
+	go func() {
+		select {
+		case <-ctx.Done():
+			dc.Close()
+		case <-c.done:
+		}
+	}()
+	return &ftpReader{Conn: dc, c: c}, nil
+}
+
+// ftpReader reads the contents of a file over an FTP data connection.
+type ftpReader struct {
+	net.Conn
+	c *ftpConn
+}
+
+// Close closes the data connection and then the control connection,
+// returning an error if the transfer was not completed successfully.
+func (r *ftpReader) Close() error {
+	err := r.Conn.Close()
+	if _, _, rerr := r.c.ReadResponse(2); err == nil {
+		err = rerr
+	}
+	if cerr := r.c.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func (b *Bucket) ftpFile(ctx context.Context, key string) (reflow.File, error) {
+	c, err := dialFTP(ctx, b.host)
+	if err != nil {
+		return reflow.File{}, err
+	}
+	defer c.Close()
+	path := "/" + key
+	size, err := c.size(path)
+	if err != nil {
+		return reflow.File{}, ftpError(err)
+	}
+	return reflow.File{
+		Source:       b.url(key),
+		Size:         size,
+		LastModified: c.modTime(path),
+	}, nil
+}
+
+func (b *Bucket) ftpOpen(ctx context.Context, key string) (io.ReadCloser, reflow.File, error) {
+	c, err := dialFTP(ctx, b.host)
+	if err != nil {
+		return nil, reflow.File{}, err
+	}
+	path := "/" + key
+	size, err := c.size(path)
+	if err != nil {
+		c.Close()
+		return nil, reflow.File{}, ftpError(err)
+	}
+	file := reflow.File{
+		Source:       b.url(key),
+		Size:         size,
+		LastModified: c.modTime(path),
+	}
+	body, err := c.retrieve(ctx, path)
+	if err != nil {
+		c.Close()
+		return nil, reflow.File{}, ftpError(err)
+	}
+	return body, file, nil
+}
+
+// ftpError returns an error of the appropriate kind for the
+// provided FTP error.
+func ftpError(err error) error {
+	tperr, ok := err.(*textproto.Error)
+	if !ok {
+		return errors.E(errors.Net, err)
+	}
+	switch tperr.Code {
+	case 550:
+		return errors.E(errors.NotExist, err)
+	case 530, 532:
+		return errors.E(errors.NotAllowed, err)
+	case 421, 425, 426, 450, 451:
+		return errors.E(errors.Unavailable, err)
+	default:
+		return errors.E(errors.Other, err)
+	}
+}
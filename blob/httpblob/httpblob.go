@@ -0,0 +1,299 @@
+// Copyright 2018 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+// Package httpblob implements read-only blob interfaces for web
+// servers, so that objects named by http, https, and ftp URLs may
+// be interned directly. Buckets correspond to hosts, and keys to
+// the paths (including any query) of objects on those hosts.
+//
+// Web servers cannot in general enumerate their objects, so only
+// individual objects (and not "directories") are supported. Objects
+// are identified by their ETag (if the server provides one), their
+// last modification time, and their size.
+package httpblob
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/grailbio/reflow"
+	"github.com/grailbio/reflow/blob"
+	"github.com/grailbio/reflow/errors"
+)
+
+// Schemes are the URL schemes served by this package.
+var Schemes = []string{"http", "https", "ftp"}
+
+// metaTimeout is used for metadata operations.
+const metaTimeout = 30 * time.Second
+
+// Register registers stores for each of Schemes in the provided
+// mux. The provided HTTP client is used for http and https URLs;
+// if it is nil, http.DefaultClient is used.
+func Register(mux blob.Mux, client *http.Client) {
+	for _, scheme := range Schemes {
+		mux[scheme] = New(scheme, client)
+	}
+}
+
+// Store implements blob.Store for a single URL scheme. Buckets in
+// the store correspond to hosts.
+type Store struct {
+	scheme string
+	client *http.Client
+}
+
+// New returns a new store for the provided scheme (one of Schemes),
+// which uses the provided HTTP client for http and https requests.
+// If the client is nil, http.DefaultClient is used.
+func New(scheme string, client *http.Client) *Store {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Store{scheme: scheme, client: client}
+}
+
+// Bucket returns the bucket for the provided host (which may
+// include a port).
+func (s *Store) Bucket(ctx context.Context, host string) (blob.Bucket, error) {
+	if host == "" {
+		return nil, errors.E("httpblob.Bucket", s.scheme, errors.Invalid, errors.New("empty host"))
+	}
+	switch s.scheme {
+	case "http", "https", "ftp":
+	default:
+		return nil, errors.E("httpblob.Bucket", s.scheme, errors.NotSupported,
+			errors.Errorf("unsupported scheme %s", s.scheme))
+	}
+	return &Bucket{scheme: s.scheme, host: host, client: s.client}, nil
+}
+
+// Bucket implements read-only access to the objects on a single host.
+type Bucket struct {
+	scheme, host string
+	client       *http.Client
+}
+
+// File returns the metadata of the object at the provided key.
+func (b *Bucket) File(ctx context.Context, key string) (reflow.File, error) {
+	ctx, cancel := context.WithTimeout(ctx, metaTimeout)
+	defer cancel()
+	var (
+		file reflow.File
+		err  error
+	)
+	if b.scheme == "ftp" {
+		file, err = b.ftpFile(ctx, key)
+	} else {
+		file, err = b.httpFile(ctx, key)
+	}
+	if err != nil {
+		return reflow.File{}, errors.E("httpblob.File", b.url(key), err)
+	}
+	return file, nil
+}
+
+// Scan returns a scanner which fails with errors.NotSupported:
+// web servers do not provide listings of their objects.
+func (b *Bucket) Scan(prefix string) blob.Scanner {
+	return &scanner{err: errors.E("httpblob.Scan", b.url(prefix), errors.NotSupported,
+		errors.New("directory listing is not supported"))}
+}
+
+// Download downloads the object at the provided key into the provided
+// writer. If the provided etag is nonempty, it is taken as a
+// precondition for fetching.
+func (b *Bucket) Download(ctx context.Context, key, etag string, size int64, w io.WriterAt) (int64, error) {
+	body, _, err := b.open(ctx, key, etag)
+	if err != nil {
+		return 0, errors.E("httpblob.Download", b.url(key), err)
+	}
+	defer body.Close()
+	n, err := io.Copy(&offsetWriter{w: w}, body)
+	if err != nil {
+		return n, errors.E("httpblob.Download", b.url(key), errors.Net, err)
+	}
+	if size > 0 && n != size {
+		return n, errors.E("httpblob.Download", b.url(key), errors.Integrity,
+			errors.Errorf("downloaded %d bytes, expected %d", n, size))
+	}
+	return n, nil
+}
+
+// Get returns a (streaming) reader for the object at the provided key.
+// If the provided etag is nonempty, it is taken as a precondition
+// for fetching.
+func (b *Bucket) Get(ctx context.Context, key, etag string) (io.ReadCloser, reflow.File, error) {
+	body, file, err := b.open(ctx, key, etag)
+	if err != nil {
+		return nil, reflow.File{}, errors.E("httpblob.Get", b.url(key), err)
+	}
+	return body, file, nil
+}
+
+// Put is not supported.
+func (b *Bucket) Put(ctx context.Context, key string, size int64, body io.Reader, contentHash string) error {
+	return errors.E("httpblob.Put", b.url(key), errors.NotSupported)
+}
+
+// Snapshot returns an un-loaded fileset representing the object at
+// the provided key. Prefixes (keys ending in "/") are not supported.
+func (b *Bucket) Snapshot(ctx context.Context, prefix string) (reflow.Fileset, error) {
+	if prefix == "" || strings.HasSuffix(prefix, "/") {
+		return reflow.Fileset{}, errors.E("httpblob.Snapshot", b.url(prefix), errors.NotSupported,
+			errors.New("directory listing is not supported"))
+	}
+	file, err := b.File(ctx, prefix)
+	if err != nil {
+		return reflow.Fileset{}, errors.E("httpblob.Snapshot", b.url(prefix), err)
+	}
+	if file.ETag == "" && file.LastModified.IsZero() {
+		return reflow.Fileset{}, errors.E("httpblob.Snapshot", b.url(prefix), errors.Invalid, errors.New("incomplete metadata"))
+	}
+	return reflow.Fileset{Map: map[string]reflow.File{".": file}}, nil
+}
+
+// Copy is not supported.
+func (b *Bucket) Copy(ctx context.Context, src, dst, contentHash string) error {
+	return errors.E("httpblob.Copy", b.url(src), errors.NotSupported)
+}
+
+// CopyFrom is not supported.
+func (b *Bucket) CopyFrom(ctx context.Context, srcBucket blob.Bucket, src, dst string) error {
+	return errors.E("httpblob.CopyFrom", b.url(dst), errors.NotSupported)
+}
+
+// Delete is not supported.
+func (b *Bucket) Delete(ctx context.Context, keys ...string) error {
+	return errors.E("httpblob.Delete", b.Location(), errors.NotSupported)
+}
+
+// Location returns the URL of this bucket, e.g., https://example.com/.
+func (b *Bucket) Location() string {
+	return b.scheme + "://" + b.host + "/"
+}
+
+func (b *Bucket) url(key string) string {
+	return b.Location() + key
+}
+
+// open returns a reader for the object at the provided key, checking
+// the provided etag (if any) as a precondition.
+func (b *Bucket) open(ctx context.Context, key, etag string) (io.ReadCloser, reflow.File, error) {
+	if b.scheme == "ftp" {
+		// FTP servers do not provide ETags, so there is nothing to check.
+		return b.ftpOpen(ctx, key)
+	}
+	return b.httpOpen(ctx, key, etag)
+}
+
+func (b *Bucket) httpFile(ctx context.Context, key string) (reflow.File, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, b.url(key), nil)
+	if err != nil {
+		return reflow.File{}, errors.E(errors.Invalid, err)
+	}
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return reflow.File{}, errors.E(errors.Net, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return reflow.File{}, statusError(resp)
+	}
+	return b.httpMetadata(key, resp)
+}
+
+func (b *Bucket) httpOpen(ctx context.Context, key, etag string) (io.ReadCloser, reflow.File, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.url(key), nil)
+	if err != nil {
+		return nil, reflow.File{}, errors.E(errors.Invalid, err)
+	}
+	// Weak ETags cannot be used in If-Match preconditions (RFC 7232);
+	// they are instead compared against the response below.
+	if etag != "" && !strings.HasPrefix(etag, "W/") {
+		req.Header.Set("If-Match", etag)
+	}
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, reflow.File{}, errors.E(errors.Net, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, reflow.File{}, statusError(resp)
+	}
+	file, err := b.httpMetadata(key, resp)
+	if err == nil && etag != "" && file.ETag != etag {
+		err = errors.E(errors.Precondition, errors.Errorf("etag %s (expected) != %s (actual)", etag, file.ETag))
+	}
+	if err != nil {
+		resp.Body.Close()
+		return nil, reflow.File{}, err
+	}
+	return resp.Body, file, nil
+}
+
+// httpMetadata returns the file metadata presented in the provided
+// response's headers.
+func (b *Bucket) httpMetadata(key string, resp *http.Response) (reflow.File, error) {
+	if resp.ContentLength < 0 {
+		return reflow.File{}, errors.E(errors.NotSupported, errors.New("server did not provide a content length"))
+	}
+	file := reflow.File{
+		Source: b.url(key),
+		ETag:   resp.Header.Get("ETag"),
+		Size:   resp.ContentLength,
+	}
+	if lm := resp.Header.Get("Last-Modified"); lm != "" {
+		if t, err := http.ParseTime(lm); err == nil {
+			file.LastModified = t
+		}
+	}
+	return file, nil
+}
+
+// statusError returns an error corresponding to the status of
+// an unsuccessful response.
+func statusError(resp *http.Response) error {
+	var kind errors.Kind
+	switch resp.StatusCode {
+	case http.StatusNotFound, http.StatusGone:
+		kind = errors.NotExist
+	case http.StatusUnauthorized, http.StatusForbidden:
+		kind = errors.NotAllowed
+	case http.StatusPreconditionFailed:
+		kind = errors.Precondition
+	case http.StatusTooManyRequests:
+		kind = errors.ResourcesExhausted
+	case http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusGatewayTimeout:
+		kind = errors.Unavailable
+	default:
+		kind = errors.Other
+	}
+	return errors.E(kind, errors.New(resp.Status))
+}
+
+// scanner is a blob.Scanner that fails immediately.
+type scanner struct {
+	err error
+}
+
+func (s *scanner) Scan(ctx context.Context) bool { return false }
+func (s *scanner) Err() error                    { return s.err }
+func (s *scanner) File() reflow.File             { return reflow.File{} }
+func (s *scanner) Key() string                   { return "" }
+
+// offsetWriter adapts an io.WriterAt to a sequential io.Writer.
+type offsetWriter struct {
+	w   io.WriterAt
+	off int64
+}
+
+func (w *offsetWriter) Write(p []byte) (int, error) {
+	n, err := w.w.WriteAt(p, w.off)
+	w.off += int64(n)
+	return n, err
+}
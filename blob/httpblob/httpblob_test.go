@@ -0,0 +1,132 @@
+// Copyright 2018 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package httpblob
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grailbio/reflow/blob"
+	"github.com/grailbio/reflow/errors"
+)
+
+const content = "hello, world"
+
+var modTime = time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func newServer(t *testing.T) (*httptest.Server, blob.Mux) {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/data/hello.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeContent(w, r, "hello.txt", modTime, strings.NewReader(content))
+	}))
+	mux := blob.Mux{}
+	Register(mux, srv.Client())
+	return srv, mux
+}
+
+// bufferAt is an in-memory io.WriterAt.
+type bufferAt []byte
+
+func (b *bufferAt) WriteAt(p []byte, off int64) (int, error) {
+	if n := int(off) + len(p); n > len(*b) {
+		*b = append(*b, make([]byte, n-len(*b))...)
+	}
+	return copy((*b)[off:], p), nil
+}
+
+func TestFile(t *testing.T) {
+	srv, mux := newServer(t)
+	defer srv.Close()
+	ctx := context.Background()
+	url := srv.URL + "/data/hello.txt"
+	file, err := mux.File(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := file.Source, url; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := file.Size, int64(len(content)); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := file.ETag, `"v1"`; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := file.LastModified, modTime; !got.Equal(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if a := blob.Assertions(file); a == nil || a.IsEmpty() {
+		t.Errorf("expected non-empty assertions for %v", file)
+	}
+	if _, err := mux.File(ctx, srv.URL+"/data/missing.txt"); !errors.Is(errors.NotExist, err) {
+		t.Errorf("expected NotExist, got %v", err)
+	}
+}
+
+func TestDownload(t *testing.T) {
+	srv, mux := newServer(t)
+	defer srv.Close()
+	ctx := context.Background()
+	url := srv.URL + "/data/hello.txt"
+	var b bufferAt
+	n, err := mux.Download(ctx, url, `"v1"`, int64(len(content)), &b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := n, int64(len(content)); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := string(b), content; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if _, err := mux.Download(ctx, url, `"v0"`, 0, &bufferAt{}); !errors.Is(errors.Precondition, err) {
+		t.Errorf("expected Precondition, got %v", err)
+	}
+	rc, _, err := mux.Get(ctx, url, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := ioutil.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(p, []byte(content)) {
+		t.Errorf("got %q, want %q", p, content)
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	srv, mux := newServer(t)
+	defer srv.Close()
+	ctx := context.Background()
+	scan, err := mux.Scan(ctx, srv.URL+"/data/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scan.Scan(ctx) {
+		t.Error("expected scan to fail")
+	}
+	if err := scan.Err(); !errors.Is(errors.NotSupported, err) {
+		t.Errorf("expected NotSupported, got %v", err)
+	}
+	if err := mux.Put(ctx, srv.URL+"/data/new.txt", 0, strings.NewReader(""), ""); !errors.Is(errors.NotSupported, err) {
+		t.Errorf("expected NotSupported, got %v", err)
+	}
+	if _, err := mux.CanTransfer(ctx, "s3://bucket/key", srv.URL+"/data/hello.txt"); !errors.Is(errors.NotSupported, err) {
+		t.Errorf("expected NotSupported, got %v", err)
+	}
+}
@@ -0,0 +1,418 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package s3blob
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/grailbio/base/retry"
+	"github.com/grailbio/reflow"
+	"github.com/grailbio/reflow/errors"
+	"github.com/grailbio/reflow/log"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// resumeThreshold is the minimum size of transfers which are
+	// checkpointed (and thus resumable).
+	resumeThreshold = 1 << 30
+	// checkpointInterval is the minimum interval between saves of a
+	// transfer's checkpoint manifest.
+	checkpointInterval = 5 * time.Second
+	// resumeConcurrency is the number of parts transferred
+	// concurrently by resumable transfers.
+	resumeConcurrency = 32
+)
+
+// checkpoints persists the progress of resumable transfers as JSON
+// manifests in a local directory, so that transfers interrupted by a
+// process restart resume from their last checkpoint instead of
+// starting over. Manifests are named by the digest of the transfer's
+// parameters, and are removed once the transfer completes.
+type checkpoints struct {
+	dir string
+}
+
+// path returns the path of the manifest of the transfer identified by
+// the given parameters.
+func (c *checkpoints) path(kind string, params ...interface{}) string {
+	id := reflow.Digester.FromString(fmt.Sprint(params...))
+	return filepath.Join(c.dir, kind+"-"+id.Hex())
+}
+
+// load loads the manifest at the given path into v. It returns false
+// if there is no (valid) manifest.
+func (c *checkpoints) load(path string, v interface{}) bool {
+	p, err := ioutil.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	if err := json.Unmarshal(p, v); err != nil {
+		log.Printf("s3blob: ignoring invalid checkpoint %s: %v", path, err)
+		return false
+	}
+	return true
+}
+
+// save atomically saves v as the manifest at the given path.
+func (c *checkpoints) save(path string, v interface{}) error {
+	p, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(c.dir, 0777); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(c.dir, filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(p)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+	}
+	return err
+}
+
+// remove removes the manifest at the given path.
+func (c *checkpoints) remove(path string) {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		log.Printf("s3blob: remove checkpoint %s: %v", path, err)
+	}
+}
+
+// checkpointer saves a manifest at most every checkpointInterval.
+type checkpointer struct {
+	*checkpoints
+	path string
+
+	mu   sync.Mutex
+	v    interface{}
+	last time.Time
+}
+
+// update applies fn, which modifies the manifest, and saves the
+// manifest if the checkpoint interval has elapsed.
+func (c *checkpointer) update(fn func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fn()
+	if time.Since(c.last) >= checkpointInterval {
+		c.flushLocked()
+	}
+}
+
+// flush saves the manifest.
+func (c *checkpointer) flush() {
+	c.mu.Lock()
+	c.flushLocked()
+	c.mu.Unlock()
+}
+
+func (c *checkpointer) flushLocked() {
+	if err := c.save(c.path, c.v); err != nil {
+		log.Printf("s3blob: save checkpoint %s: %v", c.path, err)
+	}
+	c.last = time.Now()
+}
+
+// uploadManifest is the checkpoint manifest of a resumable upload.
+type uploadManifest struct {
+	UploadID string
+	PartSize int64
+	// Parts maps the numbers of the uploaded parts to their ETags.
+	Parts map[int64]string
+}
+
+// putResumable uploads the object of the given size from body to key
+// as a multipart upload whose progress is checkpointed. If a
+// checkpoint of a previous upload of the same content (as identified
+// by its contentHash) exists, and its multipart upload has not been
+// aborted, the parts which were uploaded are skipped.
+//
+// Multipart uploads which are never resumed are not aborted by
+// putResumable; buckets should be configured to abort incomplete
+// multipart uploads after some time.
+func (b *Bucket) putResumable(ctx context.Context, key string, size int64, body io.ReaderAt, contentHash string) error {
+	partSize, _ := s3TransferParams(size)
+	cp := &checkpointer{
+		checkpoints: b.checkpoints,
+		path:        b.checkpoints.path("upload", b.bucket, key, size, contentHash),
+	}
+	var m uploadManifest
+	resume := cp.load(cp.path, &m) && m.PartSize == partSize
+	if resume && !b.uploadExists(ctx, key, m.UploadID) {
+		log.Printf("s3blob.Put: %s/%s: checkpointed upload %s no longer exists; restarting", b.bucket, key, m.UploadID)
+		resume = false
+	}
+	if resume {
+		log.Printf("s3blob.Put: %s/%s: resuming upload with %d parts done", b.bucket, key, len(m.Parts))
+	} else {
+		resp, err := b.client.CreateMultipartUploadWithContext(ctx, &s3.CreateMultipartUploadInput{
+			Bucket:   aws.String(b.bucket),
+			Key:      aws.String(key),
+			Metadata: map[string]*string{awsContentSha256Key: aws.String(contentHash)},
+		})
+		if err != nil {
+			return err
+		}
+		m = uploadManifest{UploadID: aws.StringValue(resp.UploadId), PartSize: partSize, Parts: make(map[int64]string)}
+	}
+	cp.v = &m
+	cp.flush()
+	var todo []int64
+	for num := int64(1); num <= (size+partSize-1)/partSize; num++ {
+		if _, ok := m.Parts[num]; !ok {
+			todo = append(todo, num)
+		}
+	}
+	var (
+		g, gctx = errgroup.WithContext(ctx)
+		limit   = make(chan struct{}, resumeConcurrency)
+	)
+	for _, num := range todo {
+		num := num
+		offset := (num - 1) * partSize
+		length := partSize
+		if offset+length > size {
+			length = size - offset
+		}
+		select {
+		case limit <- struct{}{}:
+		case <-gctx.Done():
+		}
+		if gctx.Err() != nil {
+			break
+		}
+		g.Go(func() error {
+			defer func() { <-limit }()
+			var (
+				resp *s3.UploadPartOutput
+				err  error
+			)
+			for retries := 0; ; retries++ {
+				resp, err = b.client.UploadPartWithContext(gctx, &s3.UploadPartInput{
+					Bucket:        aws.String(b.bucket),
+					Key:           aws.String(key),
+					UploadId:      aws.String(m.UploadID),
+					PartNumber:    aws.Int64(num),
+					Body:          io.NewSectionReader(body, offset, length),
+					ContentLength: aws.Int64(length),
+				})
+				if err == nil || !retryable(gctx, errors.E(kind(err), err)) {
+					break
+				}
+				if werr := retry.Wait(gctx, b.retrier, retries); werr != nil {
+					break
+				}
+			}
+			if err != nil {
+				return err
+			}
+			cp.update(func() { m.Parts[num] = aws.StringValue(resp.ETag) })
+			return nil
+		})
+	}
+	err := g.Wait()
+	if err == nil {
+		err = ctx.Err()
+	}
+	cp.flush()
+	if err != nil {
+		return err
+	}
+	parts := make([]*s3.CompletedPart, 0, len(m.Parts))
+	for num, etag := range m.Parts {
+		parts = append(parts, &s3.CompletedPart{PartNumber: aws.Int64(num), ETag: aws.String(etag)})
+	}
+	sort.Slice(parts, func(i, j int) bool { return *parts[i].PartNumber < *parts[j].PartNumber })
+	_, err = b.client.CompleteMultipartUploadWithContext(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(b.bucket),
+		Key:             aws.String(key),
+		UploadId:        aws.String(m.UploadID),
+		MultipartUpload: &s3.CompletedMultipartUpload{Parts: parts},
+	})
+	if err != nil {
+		return err
+	}
+	cp.remove(cp.path)
+	return nil
+}
+
+// uploadExists tells whether the multipart upload with the given ID
+// is still in progress.
+func (b *Bucket) uploadExists(ctx context.Context, key, uploadID string) bool {
+	_, err := b.client.ListPartsWithContext(ctx, &s3.ListPartsInput{
+		Bucket:   aws.String(b.bucket),
+		Key:      aws.String(key),
+		UploadId: aws.String(uploadID),
+		MaxParts: aws.Int64(1),
+	})
+	return err == nil
+}
+
+// namedWriterAt is an io.WriterAt backed by a named file.
+type namedWriterAt interface {
+	io.WriterAt
+	Name() string
+}
+
+// downloadManifest is the checkpoint manifest of a resumable download.
+type downloadManifest struct {
+	// Path is the path of the file to which the download is written.
+	Path     string
+	PartSize int64
+	// Done is the set of numbers of the downloaded parts.
+	Done map[int64]bool
+}
+
+// downloadResumable downloads the object of the given size and etag
+// at key into w in parts, checkpointing its progress. If a checkpoint
+// of a previous download of the same object exists, the parts which
+// were downloaded are copied from the previous download's file (which
+// is then removed), and only the remaining parts are downloaded.
+func (b *Bucket) downloadResumable(ctx context.Context, key, etag string, size int64, w namedWriterAt) (int64, error) {
+	partSize, _ := s3TransferParams(size)
+	cp := &checkpointer{
+		checkpoints: b.checkpoints,
+		path:        b.checkpoints.path("download", b.bucket, key, etag, size),
+	}
+	var m downloadManifest
+	if !cp.load(cp.path, &m) || m.PartSize != partSize {
+		m = downloadManifest{PartSize: partSize, Done: make(map[int64]bool)}
+	}
+	if path := w.Name(); m.Path != path {
+		if len(m.Done) > 0 {
+			if err := copyParts(w, m.Path, size, partSize, m.Done); err != nil {
+				log.Printf("s3blob.Download: %s/%s: cannot resume from %s: %v", b.bucket, key, m.Path, err)
+				m.Done = make(map[int64]bool)
+			} else {
+				log.Printf("s3blob.Download: %s/%s: resuming download with %d parts done", b.bucket, key, len(m.Done))
+				_ = os.Remove(m.Path)
+			}
+		}
+		m.Path = path
+	}
+	cp.v = &m
+	cp.flush()
+	var todo []int64
+	for num := int64(1); num <= (size+partSize-1)/partSize; num++ {
+		if !m.Done[num] {
+			todo = append(todo, num)
+		}
+	}
+	var (
+		g, gctx = errgroup.WithContext(ctx)
+		limit   = make(chan struct{}, resumeConcurrency)
+	)
+	for _, num := range todo {
+		num := num
+		offset := (num - 1) * partSize
+		length := partSize
+		if offset+length > size {
+			length = size - offset
+		}
+		select {
+		case limit <- struct{}{}:
+		case <-gctx.Done():
+		}
+		if gctx.Err() != nil {
+			break
+		}
+		g.Go(func() error {
+			defer func() { <-limit }()
+			var err error
+			for retries := 0; ; retries++ {
+				if err = b.downloadPart(gctx, key, etag, offset, length, w); err == nil || !retryable(gctx, errors.E(kind(err), err)) {
+					break
+				}
+				if werr := retry.Wait(gctx, b.retrier, retries); werr != nil {
+					break
+				}
+			}
+			if err != nil {
+				return err
+			}
+			cp.update(func() { m.Done[num] = true })
+			return nil
+		})
+	}
+	err := g.Wait()
+	if err == nil {
+		err = ctx.Err()
+	}
+	cp.flush()
+	if err != nil {
+		return 0, err
+	}
+	cp.remove(cp.path)
+	return size, nil
+}
+
+// downloadPart downloads the given range of the object at key into w.
+func (b *Bucket) downloadPart(ctx context.Context, key, etag string, offset, length int64, w io.WriterAt) error {
+	in := b.getObjectInput(key, etag)
+	in.Range = aws.String(fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+	resp, err := b.client.GetObjectWithContext(ctx, in)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	n, err := io.Copy(&offsetWriter{w, offset}, resp.Body)
+	if err == nil && n != length {
+		err = errors.E(errors.Temporary, errors.Errorf("short read: got %d bytes, want %d", n, length))
+	}
+	return err
+}
+
+// copyParts copies the given parts from the file at path into w.
+func copyParts(w io.WriterAt, path string, size, partSize int64, parts map[int64]bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	for num := range parts {
+		offset := (num - 1) * partSize
+		length := partSize
+		if offset+length > size {
+			length = size - offset
+		}
+		if _, err := io.Copy(&offsetWriter{w, offset}, io.NewSectionReader(f, offset, length)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// offsetWriter is an io.Writer which writes sequentially to an
+// io.WriterAt, starting at an offset.
+type offsetWriter struct {
+	w   io.WriterAt
+	off int64
+}
+
+func (w *offsetWriter) Write(p []byte) (int, error) {
+	n, err := w.w.WriteAt(p, w.off)
+	w.off += int64(n)
+	return n, err
+}
@@ -0,0 +1,89 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package s3blob
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCheckpoints(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoints")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	c := &checkpoints{dir: filepath.Join(dir, "sub")}
+	path := c.path("upload", "bucket", "key", 123, "hash")
+	if path == c.path("upload", "bucket", "key", 124, "hash") {
+		t.Error("checkpoints of different transfers share a path")
+	}
+	var m uploadManifest
+	if c.load(path, &m) {
+		t.Fatal("unexpected checkpoint")
+	}
+	cp := &checkpointer{checkpoints: c, path: path}
+	m = uploadManifest{UploadID: "upload", PartSize: 5 << 20, Parts: map[int64]string{}}
+	cp.v = &m
+	cp.flush()
+	cp.update(func() { m.Parts[1] = "etag1" })
+	cp.flush()
+	var got uploadManifest
+	if !c.load(path, &got) {
+		t.Fatal("missing checkpoint")
+	}
+	if !reflect.DeepEqual(got, m) {
+		t.Errorf("got %+v, want %+v", got, m)
+	}
+	c.remove(path)
+	if c.load(path, &got) {
+		t.Error("checkpoint not removed")
+	}
+}
+
+func TestCopyParts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "copyparts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	const size, partSize = 1000, 300
+	p := make([]byte, size)
+	rand.Read(p)
+	src := filepath.Join(dir, "src")
+	if err := ioutil.WriteFile(src, p, 0644); err != nil {
+		t.Fatal(err)
+	}
+	dst, err := os.Create(filepath.Join(dir, "dst"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dst.Close()
+	if err := copyParts(dst, src, size, partSize, map[int64]bool{2: true, 4: true}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ioutil.ReadFile(dst.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != size {
+		t.Fatalf("got %d bytes, want %d", len(got), size)
+	}
+	for _, r := range [][2]int{{0, 300}, {600, 900}} {
+		if !bytes.Equal(got[r[0]:r[1]], make([]byte, r[1]-r[0])) {
+			t.Errorf("range %v: expected zeroes", r)
+		}
+	}
+	for _, r := range [][2]int{{300, 600}, {900, 1000}} {
+		if !bytes.Equal(got[r[0]:r[1]], p[r[0]:r[1]]) {
+			t.Errorf("range %v: contents differ", r)
+		}
+	}
+}
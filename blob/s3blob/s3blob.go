@@ -15,6 +15,8 @@ import (
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3iface"
@@ -90,11 +92,110 @@ const (
 // desired.
 var DefaultRegion = "us-east-1"
 
+// BucketOptions are options for accessing an S3 bucket.
+type BucketOptions struct {
+	// Accelerate uses the bucket's S3 Transfer Acceleration endpoint.
+	// Transfer acceleration must be enabled on the bucket.
+	Accelerate bool `yaml:"accelerate,omitempty"`
+	// RequesterPays acknowledges that the requester pays for requests
+	// to, and transfers from, the bucket. Requests to requester-pays
+	// buckets fail without it.
+	RequesterPays bool `yaml:"requesterpays,omitempty"`
+	// Shards are buckets, typically in other regions, which together
+	// with this bucket make up a single logical bucket named by this
+	// one. Objects are written to the shard in the local region, and
+	// read from the shard which holds them. See ShardedBucket.
+	Shards []string `yaml:"shards,omitempty"`
+	// Retry configures how requests to the bucket are retried, timed
+	// out and admitted.
+	Retry RetryOptions `yaml:"retry,omitempty"`
+}
+
+// RetryOptions configure the retry policy, timeouts and admission
+// control used to access an S3 bucket. Zero values are replaced by
+// their defaults. They may be tuned for buckets which are chronically
+// throttled.
+type RetryOptions struct {
+	// MaxRetries is the maximum number of times a failed request is
+	// retried (default 3).
+	MaxRetries int `yaml:"maxretries,omitempty"`
+	// Backoff and MaxBackoff are the initial and maximum durations
+	// to wait between retries (default 2s and 1m); backoffs increase
+	// exponentially.
+	Backoff    time.Duration `yaml:"backoff,omitempty"`
+	MaxBackoff time.Duration `yaml:"maxbackoff,omitempty"`
+	// MinTimeout is the minimum timeout of a transfer attempt
+	// (default 1m); larger transfers are given more time.
+	MinTimeout time.Duration `yaml:"mintimeout,omitempty"`
+	// MetaTimeout is the timeout of metadata requests (default 30s).
+	MetaTimeout time.Duration `yaml:"metatimeout,omitempty"`
+	// AdmitLimit is the minimum limit of the AIMD admission controller
+	// which bounds concurrent requests to the bucket (default 500),
+	// and AdmitDecrease the factor by which its limit is decreased
+	// when the bucket throttles requests (default 10).
+	AdmitLimit    int `yaml:"admitlimit,omitempty"`
+	AdmitDecrease int `yaml:"admitdecrease,omitempty"`
+	// HeadLatencyLimit is the maximum acceptable latency of metadata
+	// requests, beyond which they are admitted less (default 300ms).
+	HeadLatencyLimit time.Duration `yaml:"headlatencylimit,omitempty"`
+}
+
+// withDefaults returns the options with zero values replaced by
+// their defaults.
+func (o RetryOptions) withDefaults() RetryOptions {
+	if o.MaxRetries == 0 {
+		o.MaxRetries = defaultMaxRetries
+	}
+	if o.Backoff == 0 {
+		o.Backoff = 2 * time.Second
+	}
+	if o.MaxBackoff == 0 {
+		o.MaxBackoff = time.Minute
+	}
+	if o.MaxBackoff < o.Backoff {
+		o.MaxBackoff = o.Backoff
+	}
+	if o.MinTimeout == 0 {
+		o.MinTimeout = minTimeout
+	}
+	if o.MetaTimeout == 0 {
+		o.MetaTimeout = metaTimeout
+	}
+	if o.AdmitLimit == 0 {
+		o.AdmitLimit = defaultS3MinLimit
+	}
+	if o.AdmitDecrease == 0 {
+		o.AdmitDecrease = defaultS3AIMDDecFactor
+	}
+	if o.HeadLatencyLimit == 0 {
+		o.HeadLatencyLimit = defaultS3HeadLatencyLimit
+	}
+	return o
+}
+
 // Store implements blob.Store for S3. Buckets in store correspond
 // exactly with buckets in S3. Store manages region discovery and
 // session maintenance so that S3 access can be treated uniformly
 // across regions.
 type Store struct {
+	// Options are the access options of buckets, keyed by bucket name.
+	// Buckets without options are accessed with the options keyed by
+	// "*", if any, and otherwise with the default options.
+	Options map[string]BucketOptions
+
+	// CheckpointDir, if set, is the local directory in which the
+	// progress of large uploads and downloads is checkpointed, so that
+	// they can be resumed after a process restart. Only transfers of at
+	// least 1 GiB whose contents are identified (by content hash or ETag)
+	// are checkpointed; uploads must be read from an io.ReaderAt, and
+	// downloads must be written to a named file.
+	CheckpointDir string
+
+	// Region is the local region, used to route the writes and reads
+	// of sharded buckets (see BucketOptions.Shards). If empty, the
+	// region of the store's session is used.
+	Region string
+
 	sess *session.Session
 
 	mu      sync.Mutex
@@ -113,9 +214,39 @@ func New(sess *session.Session) *Store {
 	return s
 }
 
+// WithCredentials returns a store which accesses its buckets with
+// the provided credentials in place of those of the store's session.
+// The returned store shares the receiver's options, but not its
+// buckets. Credentials which can be refreshed are refreshed shortly
+// before they expire, so that long transfers do not outlast them.
+func (s *Store) WithCredentials(creds *reflow.Credentials) blob.Store {
+	provider := credentials.NewStaticCredentials(creds.AccessKeyID, creds.SecretAccessKey, creds.SessionToken)
+	if creds.Refresh != nil {
+		provider = credentials.NewCredentials(&scopedProvider{creds: creds})
+	}
+	sess := s.sess.Copy(&aws.Config{Credentials: provider})
+	scoped := New(sess)
+	scoped.Options = s.Options
+	scoped.CheckpointDir = s.CheckpointDir
+	scoped.Region = s.Region
+	return scoped
+}
+
 // Bucket returns the s3 bucket with the provided name. An
 // errors.NotExist error is returned if the bucket does not exist.
+// Buckets with shards are returned as a *ShardedBucket.
 func (s *Store) Bucket(ctx context.Context, bucket string) (blob.Bucket, error) {
+	if shards := s.Options[bucket].Shards; len(shards) > 0 {
+		return s.shardedBucket(ctx, bucket, shards)
+	}
+	b, err := s.bucket(ctx, bucket)
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func (s *Store) bucket(ctx context.Context, bucket string) (*Bucket, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	for {
@@ -158,23 +289,51 @@ func (s *Store) newBucket(ctx context.Context, bucket string) (*Bucket, error) {
 		log.Printf("s3blob: unable to determine region for bucket %s: %v", bucket, err)
 		region = DefaultRegion
 	}
+	opts := s.options(bucket)
 	config := aws.Config{
-		MaxRetries: aws.Int(10),
-		Region:     aws.String(region),
-		Endpoint:   aws.String(fmt.Sprintf("s3.%s.amazonaws.com", region)),
+		MaxRetries:      aws.Int(10),
+		Region:          aws.String(region),
+		Endpoint:        aws.String(fmt.Sprintf("s3.%s.amazonaws.com", region)),
+		S3UseAccelerate: aws.Bool(opts.Accelerate),
+	}
+	client := s3.New(s.sess, &config)
+	if opts.RequesterPays {
+		client.Handlers.Build.PushBack(requesterPays)
 	}
-	return NewBucket(bucket, s3.New(s.sess, &config)), nil
+	b := newBucket(bucket, client, opts.Retry)
+	b.region = region
+	if s.CheckpointDir != "" {
+		b.checkpoints = &checkpoints{dir: s.CheckpointDir}
+	}
+	return b, nil
+}
+
+// options returns the access options of the provided bucket.
+func (s *Store) options(bucket string) BucketOptions {
+	if opts, ok := s.Options[bucket]; ok {
+		return opts
+	}
+	return s.Options["*"]
+}
+
+// requesterPays is a request handler which acknowledges that the
+// requester is charged for the request, as required by requester-pays
+// buckets. Setting the header (rather than the RequestPayer field of
+// each request's input) covers all requests, including those issued
+// by s3manager.
+func requesterPays(r *request.Request) {
+	r.HTTPRequest.Header.Set("x-amz-request-payer", s3.RequestPayerRequester)
 }
 
-// NewS3RetryPolicy returns a default retry.Policy useful for S3 operations.
-func newS3RetryPolicy() retry.Policy {
-	return retry.MaxRetries(retry.Jitter(retry.Backoff(2*time.Second, time.Minute, 4), 0.25), defaultMaxRetries)
+// NewS3RetryPolicy returns a retry.Policy useful for S3 operations.
+func newS3RetryPolicy(opts RetryOptions) retry.Policy {
+	return retry.MaxRetries(retry.Jitter(retry.Backoff(opts.Backoff, opts.MaxBackoff, 4), 0.25), opts.MaxRetries)
 }
 
-// NewS3AimdPolicy returns a default admit.RetryPolicy backed by an AIMD admission controller.
-func newS3AimdPolicy(varname string) admit.RetryPolicy {
-	rp := retry.MaxRetries(retry.Jitter(retry.Backoff(500*time.Millisecond, time.Minute, 1.5), 0.5), defaultMaxRetries)
-	c := admit.AIMDWithRetry(defaultS3MinLimit, defaultS3AIMDDecFactor, rp)
+// NewS3AimdPolicy returns an admit.RetryPolicy backed by an AIMD admission controller.
+func newS3AimdPolicy(varname string, opts RetryOptions) admit.RetryPolicy {
+	rp := retry.MaxRetries(retry.Jitter(retry.Backoff(opts.Backoff/4, opts.MaxBackoff, 1.5), 0.5), opts.MaxRetries)
+	c := admit.AIMDWithRetry(opts.AdmitLimit, opts.AdmitDecrease, rp)
 	admit.EnableVarExport(c, varname)
 	return c
 }
@@ -191,18 +350,40 @@ type Bucket struct {
 	s3ObjectCopySizeLimit int64
 	// s3MultipartCopyPartSize is the max size of each part when doing a multi-part copy.
 	s3MultipartCopyPartSize int64
+
+	// checkpoints, if non-nil, is used to checkpoint large transfers.
+	checkpoints *checkpoints
+
+	// region is the bucket's region, if known.
+	region string
+
+	// minTimeout and metaTimeout are the minimum timeout of transfer
+	// attempts and the timeout of metadata requests; headLatencyLimit
+	// is the maximum acceptable latency of metadata requests.
+	minTimeout, metaTimeout, headLatencyLimit time.Duration
 }
 
 // NewBucket returns a new S3 bucket that uses the provided client
 // for SDK calls. NewBucket is primarily intended for testing.
 func NewBucket(name string, client s3iface.S3API) *Bucket {
+	return newBucket(name, client, RetryOptions{})
+}
+
+// newBucket returns a new S3 bucket that uses the provided client
+// for SDK calls, and the provided retry options.
+func newBucket(name string, client s3iface.S3API, opts RetryOptions) *Bucket {
+	opts = opts.withDefaults()
 	return &Bucket{
-		name, client,
-		newS3AimdPolicy("s3data"),
-		newS3AimdPolicy("s3head"),
-		newS3RetryPolicy(),
-		defaultS3ObjectCopySizeLimit,
-		defaultS3MultipartCopyPartSize,
+		bucket:                  name,
+		client:                  client,
+		admitter:                newS3AimdPolicy("s3data", opts),
+		fileAdmitter:            newS3AimdPolicy("s3head", opts),
+		retrier:                 newS3RetryPolicy(opts),
+		s3ObjectCopySizeLimit:   defaultS3ObjectCopySizeLimit,
+		s3MultipartCopyPartSize: defaultS3MultipartCopyPartSize,
+		minTimeout:              opts.MinTimeout,
+		metaTimeout:             opts.MetaTimeout,
+		headLatencyLimit:        opts.HeadLatencyLimit,
 	}
 }
 
@@ -212,7 +393,7 @@ func (b *Bucket) File(ctx context.Context, key string) (reflow.File, error) {
 	var err error
 	for retries := 0; ; retries++ {
 		err = admit.Retry(ctx, b.fileAdmitter, 1, func() (admit.CapacityStatus, error) {
-			ctx, cancel := context.WithTimeout(ctx, metaTimeout)
+			ctx, cancel := context.WithTimeout(ctx, b.metaTimeout)
 			defer cancel()
 			start := time.Now()
 			resp, err = b.client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
@@ -225,7 +406,7 @@ func (b *Bucket) File(ctx context.Context, key string) (reflow.File, error) {
 				log.Printf("s3blob.File: %s/%s: %v (over capacity)\n", b.bucket, key, err)
 				return admit.OverNeedRetry, err
 			}
-			if dur > defaultS3HeadLatencyLimit {
+			if dur > b.headLatencyLimit {
 				return admit.OverNoRetry, err
 			}
 			return admit.Within, err
@@ -331,9 +512,12 @@ func transferDuration(size int64, rate int) time.Duration {
 	return time.Duration(size/int64(rate)) * time.Second
 }
 
-func timeoutPolicy(timeout time.Duration) retry.Policy {
-	if timeout < minTimeout {
-		timeout = minTimeout
+// timeoutPolicy returns the policy for the timeouts of successive
+// attempts of a transfer expected to take the given duration, which
+// are at least min.
+func timeoutPolicy(timeout, min time.Duration) retry.Policy {
+	if timeout < min {
+		timeout = min
 	}
 	return retry.Backoff(timeout, 3*timeout, 1.5)
 }
@@ -366,19 +550,34 @@ func isAnyOf(err error, kinds ...errors.Kind) bool {
 
 // Download downloads the object named by the provided key. Download
 // uses the AWS SDK's download manager, performing concurrent
-// downloads to the provided io.WriterAt.
+// downloads to the provided io.WriterAt. Large downloads to named
+// files are checkpointed if the store has a CheckpointDir.
 func (b *Bucket) Download(ctx context.Context, key, etag string, size int64, w io.WriterAt) (int64, error) {
-	// Determine size if unspecified
-	if size == 0 {
+	nw, resumable := w.(namedWriterAt)
+	resumable = resumable && b.checkpoints != nil
+	// Determine size (and, for resumable downloads, etag) if unspecified
+	if size == 0 || (resumable && etag == "") {
 		if rf, err := b.File(ctx, key); err == nil {
-			size = rf.Size
+			if size == 0 {
+				size = rf.Size
+			}
+			if resumable && etag == "" {
+				etag = rf.ETag
+			}
+		}
+	}
+	if resumable && etag != "" && size >= resumeThreshold {
+		n, err := b.downloadResumable(ctx, key, etag, size, nw)
+		if err != nil && kind(err) != errors.Canceled {
+			err = errors.E("s3blob.Download", b.bucket, key, kind(err), err)
 		}
+		return n, err
 	}
 	var (
 		n                         int64
 		err                       error
 		s3partsize, s3concurrency = s3TransferParams(size)
-		policy                    = timeoutPolicy(transferDuration(size, minBPS))
+		policy                    = timeoutPolicy(transferDuration(size, minBPS), b.minTimeout)
 		preferredDur              = transferDuration(size, preferredBPS)
 	)
 	for retries := 0; ; retries++ {
@@ -431,13 +630,35 @@ func (b *Bucket) Get(ctx context.Context, key, etag string) (io.ReadCloser, refl
 	}, nil
 }
 
+// GetRange returns a reader of length bytes of the object named by the
+// provided key, starting at the given offset. It implements
+// blob.RangeGetter.
+func (b *Bucket) GetRange(ctx context.Context, key, etag string, offset, length int64) (io.ReadCloser, error) {
+	in := b.getObjectInput(key, etag)
+	in.Range = aws.String(fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+	resp, err := b.client.GetObjectWithContext(ctx, in)
+	if err != nil {
+		return nil, errors.E("s3blob.GetRange", b.bucket, key, kind(err), err)
+	}
+	return resp.Body, nil
+}
+
 // Put stores the contents of the provided io.Reader at the provided key
-// and attaches the given contentHash to the object's metadata.
+// and attaches the given contentHash to the object's metadata. Large
+// uploads of content read from an io.ReaderAt are checkpointed if the
+// store has a CheckpointDir.
 func (b *Bucket) Put(ctx context.Context, key string, size int64, body io.Reader, contentHash string) error {
+	if ra, ok := body.(io.ReaderAt); ok && b.checkpoints != nil && contentHash != "" && size >= resumeThreshold {
+		err := b.putResumable(ctx, key, size, ra, contentHash)
+		if err != nil && kind(err) != errors.Canceled {
+			err = errors.E("s3blob.Put", b.bucket, key, kind(err), err)
+		}
+		return err
+	}
 	var (
 		err                       error
 		s3partsize, s3concurrency = s3TransferParams(size)
-		policy                    = timeoutPolicy(transferDuration(size, minBPS))
+		policy                    = timeoutPolicy(transferDuration(size, minBPS), b.minTimeout)
 		preferredDur              = transferDuration(size, preferredBPS)
 	)
 	for retries := 0; ; retries++ {
@@ -531,8 +752,16 @@ func (b *Bucket) Copy(ctx context.Context, src, dst string, contentHash string)
 // CopyFrom copies from bucket src and key srcKey into this bucket.
 // This is done directly without streaming the data through the client.
 func (b *Bucket) CopyFrom(ctx context.Context, srcBucket blob.Bucket, src, dst string) error {
-	srcB, ok := srcBucket.(*Bucket)
-	if !ok {
+	var srcB *Bucket
+	switch sb := srcBucket.(type) {
+	case *Bucket:
+		srcB = sb
+	case *ShardedBucket:
+		var err error
+		if srcB, _, err = sb.locate(ctx, src); err != nil {
+			return errors.E("s3blob.CopyFrom", b.bucket, dst, srcBucket.Location(), src, err)
+		}
+	default:
 		return errors.E(errors.NotSupported, "s3blob.CopyFrom", srcBucket.Location())
 	}
 	err := b.copyObject(ctx, dst, srcB, src, "")
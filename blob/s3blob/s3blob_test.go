@@ -17,7 +17,9 @@ import (
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/grailbio/base/data"
 	"github.com/grailbio/base/digest"
@@ -286,7 +288,7 @@ func TestDownload(t *testing.T) {
 }
 
 func TestTimeoutPolicy(t *testing.T) {
-	p := timeoutPolicy(transferDuration(minBPS, minBPS))
+	p := timeoutPolicy(transferDuration(minBPS, minBPS), minTimeout)
 	if got, want := timeout(p, 0), 60*time.Second; got != want {
 		t.Errorf("got %v, want %v", got, want)
 	}
@@ -296,7 +298,7 @@ func TestTimeoutPolicy(t *testing.T) {
 	if got, want := timeout(p, 100), 180*time.Second; got != want {
 		t.Errorf("got %v, want %v", got, want)
 	}
-	p = timeoutPolicy(transferDuration(100*minBPS, minBPS))
+	p = timeoutPolicy(transferDuration(100*minBPS, minBPS), minTimeout)
 	if got, want := timeout(p, 0), 100*time.Second; got != want {
 		t.Errorf("got %v, want %v", got, want)
 	}
@@ -631,3 +633,58 @@ func TestS3TransferParams(t *testing.T) {
 		}
 	}
 }
+
+func TestRequesterPays(t *testing.T) {
+	sess, err := session.NewSession(&aws.Config{
+		Region:      aws.String("us-west-2"),
+		Credentials: credentials.AnonymousCredentials,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, pays := range []bool{false, true} {
+		client := s3.New(sess)
+		if pays {
+			client.Handlers.Build.PushBack(requesterPays)
+		}
+		req, _ := client.HeadObjectRequest(&s3.HeadObjectInput{
+			Bucket: aws.String(name),
+			Key:    aws.String("key"),
+		})
+		if err := req.Build(); err != nil {
+			t.Fatal(err)
+		}
+		want := ""
+		if pays {
+			want = "requester"
+		}
+		if got := req.HTTPRequest.Header.Get("x-amz-request-payer"); got != want {
+			t.Errorf("requester pays %v: got %q, want %q", pays, got, want)
+		}
+	}
+}
+
+func TestRetryOptions(t *testing.T) {
+	s := New(nil)
+	s.Options = map[string]BucketOptions{
+		"throttled": {Retry: RetryOptions{MaxRetries: 10, MinTimeout: 2 * time.Minute}},
+		"*":         {Retry: RetryOptions{AdmitLimit: 100}},
+	}
+	opts := s.options("throttled").Retry.withDefaults()
+	if got, want := opts.MaxRetries, 10; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := opts.AdmitLimit, defaultS3MinLimit; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := opts.MetaTimeout, metaTimeout; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := s.options("other").Retry.AdmitLimit, 100; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	b := newBucket("throttled", nil, s.options("throttled").Retry)
+	if got, want := timeout(timeoutPolicy(transferDuration(minBPS, minBPS), b.minTimeout), 0), 2*time.Minute; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
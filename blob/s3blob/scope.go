@@ -0,0 +1,234 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package s3blob
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
+	"github.com/grailbio/reflow"
+	"github.com/grailbio/reflow/blob"
+	"github.com/grailbio/reflow/errors"
+)
+
+const (
+	// defaultScopeDuration is the default lifetime of scoped credentials.
+	defaultScopeDuration = time.Hour
+	// scopeExpiryWindow is the time before their expiration at which
+	// scoped credentials used in process are refreshed.
+	scopeExpiryWindow = 5 * time.Minute
+)
+
+// Scoper is a blob.Scoper which mints scoped credentials by assuming
+// an IAM role with an inline session policy that permits access only
+// to the requested S3 prefixes. The effective permissions of the
+// credentials are the intersection of the role's permissions and
+// the session policy.
+type Scoper struct {
+	// Client is the STS client used to assume Role.
+	Client stsiface.STSAPI
+	// Role is the ARN of the role assumed for scoped credentials.
+	Role string
+	// Duration is the lifetime of minted credentials. If zero,
+	// credentials last an hour.
+	Duration time.Duration
+}
+
+// NewScoper returns a new Scoper which assumes the provided role
+// with the given session.
+func NewScoper(sess *session.Session, role string) *Scoper {
+	return &Scoper{Client: sts.New(sess), Role: role}
+}
+
+// Scope returns credentials which permit only the provided accesses
+// to S3. Accesses of other schemes are ignored; Scope returns nil
+// credentials if there are no S3 accesses. Credentials are minted
+// for each call, so that they last for the full Duration, and they
+// may be refreshed (see reflow.Credentials.Refresh) by in-process
+// transfers which outlast them.
+func (s *Scoper) Scope(ctx context.Context, accesses ...blob.Access) (*reflow.Credentials, error) {
+	policy, err := scopePolicy(accesses)
+	if err != nil || policy == "" {
+		return nil, err
+	}
+	return s.assume(ctx, policy)
+}
+
+// assume mints credentials by assuming the scoper's role with the
+// given session policy.
+func (s *Scoper) assume(ctx context.Context, policy string) (*reflow.Credentials, error) {
+	duration := s.Duration
+	if duration == 0 {
+		duration = defaultScopeDuration
+	}
+	out, err := s.Client.AssumeRoleWithContext(ctx, &sts.AssumeRoleInput{
+		RoleArn:         aws.String(s.Role),
+		RoleSessionName: aws.String("reflow-task"),
+		Policy:          aws.String(policy),
+		DurationSeconds: aws.Int64(int64(duration / time.Second)),
+	})
+	if err != nil {
+		return nil, errors.E("s3blob.Scope", s.Role, err)
+	}
+	return &reflow.Credentials{
+		AccessKeyID:     aws.StringValue(out.Credentials.AccessKeyId),
+		SecretAccessKey: aws.StringValue(out.Credentials.SecretAccessKey),
+		SessionToken:    aws.StringValue(out.Credentials.SessionToken),
+		Expiration:      aws.TimeValue(out.Credentials.Expiration),
+		Refresh: func(ctx context.Context) (*reflow.Credentials, error) {
+			return s.assume(ctx, policy)
+		},
+	}, nil
+}
+
+// scopedProvider is a credentials.Provider of refreshable scoped
+// credentials. Like stscreds.AssumeRoleProvider, it reports the
+// credentials as expired scopeExpiryWindow before their expiration,
+// so that they are refreshed before requests fail with ExpiredToken.
+type scopedProvider struct {
+	credentials.Expiry
+	creds     *reflow.Credentials
+	retrieved bool
+}
+
+// Retrieve implements credentials.Provider. The first call returns
+// the provider's credentials; later calls refresh them.
+func (p *scopedProvider) Retrieve() (credentials.Value, error) {
+	if p.retrieved {
+		creds, err := p.creds.Refresh(aws.BackgroundContext())
+		if err != nil {
+			return credentials.Value{}, err
+		}
+		p.creds = creds
+	}
+	p.retrieved = true
+	p.SetExpiration(p.creds.Expiration, scopeExpiryWindow)
+	return credentials.Value{
+		AccessKeyID:     p.creds.AccessKeyID,
+		SecretAccessKey: p.creds.SecretAccessKey,
+		SessionToken:    p.creds.SessionToken,
+		ProviderName:    "ScopedProvider",
+	}, nil
+}
+
+type policyStatement struct {
+	Effect    string
+	Action    []string
+	Resource  []string
+	Condition map[string]map[string][]string `json:",omitempty"`
+}
+
+type policyDocument struct {
+	Version   string
+	Statement []policyStatement
+}
+
+// scopePolicy returns the session policy document which permits the
+// provided S3 accesses. Session policies are limited in size, so
+// accesses are consolidated by bucket: all reads from a bucket are
+// permitted under the longest common prefix of the accessed keys,
+// and likewise for writes. scopePolicy returns an empty policy if
+// there are no S3 accesses.
+func scopePolicy(accesses []blob.Access) (string, error) {
+	type prefixes struct {
+		read, write string
+		hasWrite    bool
+	}
+	buckets := make(map[string]*prefixes)
+	for _, a := range accesses {
+		u, err := url.Parse(a.URL)
+		if err != nil {
+			return "", err
+		}
+		if u.Scheme != "s3" {
+			continue
+		}
+		bucket, key := u.Host, strings.TrimPrefix(u.Path, "/")
+		p := buckets[bucket]
+		if p == nil {
+			p = &prefixes{read: key}
+			buckets[bucket] = p
+		}
+		p.read = commonPrefix(p.read, key, true)
+		if a.Write {
+			p.write, p.hasWrite = commonPrefix(p.write, key, p.hasWrite), true
+		}
+	}
+	if len(buckets) == 0 {
+		return "", nil
+	}
+	names := make([]string, 0, len(buckets))
+	for name := range buckets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	doc := policyDocument{Version: "2012-10-17"}
+	for _, name := range names {
+		p := buckets[name]
+		doc.Statement = append(doc.Statement,
+			policyStatement{
+				Effect:   "Allow",
+				Action:   []string{"s3:GetBucketLocation"},
+				Resource: []string{"arn:aws:s3:::" + name},
+			},
+			policyStatement{
+				Effect:   "Allow",
+				Action:   []string{"s3:ListBucket"},
+				Resource: []string{"arn:aws:s3:::" + name},
+				Condition: map[string]map[string][]string{
+					"StringLike": {"s3:prefix": {p.read + "*"}},
+				},
+			},
+			policyStatement{
+				Effect:   "Allow",
+				Action:   []string{"s3:GetObject"},
+				Resource: []string{"arn:aws:s3:::" + name + "/" + p.read + "*"},
+			},
+		)
+		if p.hasWrite {
+			doc.Statement = append(doc.Statement, policyStatement{
+				Effect: "Allow",
+				Action: []string{
+					"s3:PutObject",
+					"s3:AbortMultipartUpload",
+					"s3:ListMultipartUploadParts",
+				},
+				Resource: []string{"arn:aws:s3:::" + name + "/" + p.write + "*"},
+			})
+		}
+	}
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// commonPrefix returns the longest common prefix of prefix and key.
+// If ok is false, prefix is not yet defined and key is returned.
+func commonPrefix(prefix, key string, ok bool) string {
+	if !ok {
+		return key
+	}
+	n := len(prefix)
+	if len(key) < n {
+		n = len(key)
+	}
+	for i := 0; i < n; i++ {
+		if prefix[i] != key[i] {
+			return prefix[:i]
+		}
+	}
+	return prefix[:n]
+}
@@ -0,0 +1,157 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package s3blob
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
+	"github.com/grailbio/reflow"
+	"github.com/grailbio/reflow/blob"
+)
+
+type fakeSTS struct {
+	stsiface.STSAPI
+	policies []string
+}
+
+func (s *fakeSTS) AssumeRoleWithContext(ctx aws.Context, in *sts.AssumeRoleInput, opts ...request.Option) (*sts.AssumeRoleOutput, error) {
+	s.policies = append(s.policies, aws.StringValue(in.Policy))
+	return &sts.AssumeRoleOutput{Credentials: &sts.Credentials{
+		AccessKeyId:     aws.String("key"),
+		SecretAccessKey: aws.String("secret"),
+		SessionToken:    aws.String("token"),
+		Expiration:      aws.Time(time.Now().Add(time.Duration(aws.Int64Value(in.DurationSeconds)) * time.Second)),
+	}}, nil
+}
+
+func TestScopePolicy(t *testing.T) {
+	policy, err := scopePolicy([]blob.Access{
+		{URL: "s3://in/data/sample1/a.bam"},
+		{URL: "s3://in/data/sample2/b.bam"},
+		{URL: "s3://out/results/x", Write: true},
+		{URL: "localfile:///tmp/x"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc policyDocument
+	if err := json.Unmarshal([]byte(policy), &doc); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, stmt := range doc.Statement {
+		for _, action := range stmt.Action {
+			for _, resource := range stmt.Resource {
+				got = append(got, action+" "+resource)
+			}
+		}
+	}
+	want := []string{
+		"s3:GetBucketLocation arn:aws:s3:::in",
+		"s3:ListBucket arn:aws:s3:::in",
+		"s3:GetObject arn:aws:s3:::in/data/sample*",
+		"s3:GetBucketLocation arn:aws:s3:::out",
+		"s3:ListBucket arn:aws:s3:::out",
+		"s3:GetObject arn:aws:s3:::out/results/x*",
+		"s3:PutObject arn:aws:s3:::out/results/x*",
+		"s3:AbortMultipartUpload arn:aws:s3:::out/results/x*",
+		"s3:ListMultipartUploadParts arn:aws:s3:::out/results/x*",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	policy, err = scopePolicy([]blob.Access{{URL: "localfile:///tmp/x"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policy != "" {
+		t.Errorf("got policy %s, want none", policy)
+	}
+}
+
+func TestScoper(t *testing.T) {
+	client := new(fakeSTS)
+	scoper := &Scoper{Client: client, Role: "arn:aws:iam::123:role/reflow-task"}
+	ctx := context.Background()
+	creds, err := scoper.Scope(ctx, blob.Access{URL: "s3://in/a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := creds.SessionToken, "token"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := time.Until(creds.Expiration), 50*time.Minute; got < want {
+		t.Errorf("got lifetime %v, want at least %v", got, want)
+	}
+	// Credentials are minted anew for each call, even for the same
+	// accesses, and when they are refreshed.
+	if _, err := scoper.Scope(ctx, blob.Access{URL: "s3://in/a"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := creds.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(client.policies), 3; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if client.policies[2] != client.policies[0] {
+		t.Errorf("refreshed with policy %s, want %s", client.policies[2], client.policies[0])
+	}
+	creds, err = scoper.Scope(ctx, blob.Access{URL: "localfile:///a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds != nil {
+		t.Errorf("got %v, want nil", creds)
+	}
+}
+
+func TestScopedProvider(t *testing.T) {
+	var refreshes int
+	creds := &reflow.Credentials{
+		AccessKeyID: "key0",
+		Expiration:  time.Now().Add(time.Minute),
+	}
+	creds.Refresh = func(ctx context.Context) (*reflow.Credentials, error) {
+		refreshes++
+		return &reflow.Credentials{
+			AccessKeyID: fmt.Sprintf("key%d", refreshes),
+			Expiration:  time.Now().Add(time.Hour),
+			Refresh:     creds.Refresh,
+		}, nil
+	}
+	p := &scopedProvider{creds: creds}
+	v, err := p.Retrieve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := v.AccessKeyID, "key0"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	// The credentials expire within the expiry window, so they are
+	// refreshed.
+	if !p.IsExpired() {
+		t.Fatal("credentials are not expired")
+	}
+	if v, err = p.Retrieve(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := v.AccessKeyID, "key1"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if p.IsExpired() {
+		t.Error("refreshed credentials are expired")
+	}
+}
@@ -0,0 +1,255 @@
+// Copyright 2018 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package s3blob
+
+import (
+	"context"
+	"io"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/grailbio/reflow"
+	"github.com/grailbio/reflow/blob"
+	"github.com/grailbio/reflow/errors"
+)
+
+// ShardedBucket is a single logical bucket whose objects are spread
+// across a set of S3 buckets (shards), typically one per region, as
+// configured by BucketOptions.Shards. It is used so that a single
+// repository may serve clusters in several regions without incurring
+// cross-region data transfer charges for objects which are produced
+// and consumed in the same region.
+//
+// Objects are always written to the local shard: the shard in the
+// store's region or, if there is none, the bucket which names the
+// logical bucket. Objects are read from the shard which holds them,
+// preferring the local shard. ShardedBucket implements blob.Bucket.
+type ShardedBucket struct {
+	name string
+	// shards are the bucket's shards; the local shard is first.
+	shards []*Bucket
+}
+
+// shardedBucket returns the sharded bucket with the provided name
+// and shards.
+func (s *Store) shardedBucket(ctx context.Context, name string, shards []string) (*ShardedBucket, error) {
+	region := s.Region
+	if region == "" && s.sess != nil {
+		region = aws.StringValue(s.sess.Config.Region)
+	}
+	sb := &ShardedBucket{name: name}
+	for _, shard := range append([]string{name}, shards...) {
+		b, err := s.bucket(ctx, shard)
+		if err != nil {
+			return nil, errors.E("s3blob.shardedBucket", name, err)
+		}
+		sb.shards = append(sb.shards, b)
+	}
+	for i, b := range sb.shards {
+		if b.region == region {
+			sb.shards[0], sb.shards[i] = sb.shards[i], sb.shards[0]
+			break
+		}
+	}
+	return sb, nil
+}
+
+// Local returns the bucket's local shard.
+func (b *ShardedBucket) Local() *Bucket {
+	return b.shards[0]
+}
+
+// File returns metadata for the provided key, from the shard which
+// holds it. The returned file's source is the object's location in
+// that shard.
+func (b *ShardedBucket) File(ctx context.Context, key string) (reflow.File, error) {
+	_, file, err := b.locate(ctx, key)
+	return file, err
+}
+
+// Scan returns a scanner that iterates over all objects in the
+// provided prefix, across all shards. Keys present in multiple shards
+// are scanned once, with the metadata of the earliest shard.
+func (b *ShardedBucket) Scan(prefix string) blob.Scanner {
+	s := &shardedScanner{scanners: make([]blob.Scanner, len(b.shards)), ok: make([]bool, len(b.shards))}
+	for i, shard := range b.shards {
+		s.scanners[i] = shard.Scan(prefix)
+	}
+	return s
+}
+
+// Download downloads the object from the shard which holds it.
+func (b *ShardedBucket) Download(ctx context.Context, key, etag string, size int64, w io.WriterAt) (n int64, err error) {
+	err = b.each(func(shard *Bucket) error {
+		n, err = shard.Download(ctx, key, etag, size, w)
+		return err
+	})
+	return
+}
+
+// Get returns a reader for the object from the shard which holds it.
+func (b *ShardedBucket) Get(ctx context.Context, key, etag string) (rc io.ReadCloser, file reflow.File, err error) {
+	err = b.each(func(shard *Bucket) error {
+		rc, file, err = shard.Get(ctx, key, etag)
+		return err
+	})
+	return
+}
+
+// GetRange returns a reader for a range of the object from the shard
+// which holds it.
+func (b *ShardedBucket) GetRange(ctx context.Context, key, etag string, offset, length int64) (rc io.ReadCloser, err error) {
+	err = b.each(func(shard *Bucket) error {
+		rc, err = shard.GetRange(ctx, key, etag, offset, length)
+		return err
+	})
+	return
+}
+
+// Put stores the provided object in the local shard.
+func (b *ShardedBucket) Put(ctx context.Context, key string, size int64, body io.Reader, contentHash string) error {
+	return b.Local().Put(ctx, key, size, body, contentHash)
+}
+
+// Snapshot returns an un-loaded Reflow fileset representing the
+// contents of the provided prefix, from the first shard (preferring
+// the local shard) which has any.
+func (b *ShardedBucket) Snapshot(ctx context.Context, prefix string) (reflow.Fileset, error) {
+	var local reflow.Fileset
+	for i, shard := range b.shards {
+		fs, err := shard.Snapshot(ctx, prefix)
+		if err != nil {
+			return reflow.Fileset{}, err
+		}
+		if fs.N() > 0 {
+			return fs, nil
+		}
+		if i == 0 {
+			local = fs
+		}
+	}
+	return local, nil
+}
+
+// Copy copies the key src, from the shard which holds it, to the key
+// dst in the local shard.
+func (b *ShardedBucket) Copy(ctx context.Context, src, dst, contentHash string) error {
+	shard, _, err := b.locate(ctx, src)
+	if err == nil {
+		err = b.Local().copyObject(ctx, dst, shard, src, contentHash)
+	}
+	if err != nil {
+		err = errors.E("s3blob.Copy", b.name, src, dst, err)
+	}
+	return err
+}
+
+// CopyFrom copies from bucket src and key srcKey into the local shard.
+func (b *ShardedBucket) CopyFrom(ctx context.Context, srcBucket blob.Bucket, src, dst string) error {
+	return b.Local().CopyFrom(ctx, srcBucket, src, dst)
+}
+
+// Delete removes the provided keys from all shards.
+func (b *ShardedBucket) Delete(ctx context.Context, keys ...string) error {
+	for _, shard := range b.shards {
+		if err := shard.Delete(ctx, keys...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Location returns the s3 URL of the logical bucket, e.g.,
+// s3://grail-reflow/. Objects may be written to (and read from) this
+// location with a store which is configured with the bucket's shards.
+func (b *ShardedBucket) Location() string {
+	return "s3://" + b.name + "/"
+}
+
+// each calls fn for each shard in turn, until a call does not return
+// an errors.NotExist error. If no shard holds the object, the error
+// returned for the local shard is returned.
+func (b *ShardedBucket) each(fn func(shard *Bucket) error) error {
+	var first error
+	for _, shard := range b.shards {
+		err := fn(shard)
+		if err == nil || !errors.Is(errors.NotExist, err) {
+			return err
+		}
+		if first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// locate returns the shard which holds the provided key, together
+// with the object's metadata.
+func (b *ShardedBucket) locate(ctx context.Context, key string) (shard *Bucket, file reflow.File, err error) {
+	err = b.each(func(s *Bucket) error {
+		shard = s
+		file, err = s.File(ctx, key)
+		return err
+	})
+	if err != nil {
+		return nil, reflow.File{}, err
+	}
+	return shard, file, nil
+}
+
+// shardedScanner merges the (ordered) scans of a set of shards.
+type shardedScanner struct {
+	scanners []blob.Scanner
+	// ok tells whether the corresponding scanner is positioned at a key
+	// which has not yet been returned.
+	ok      []bool
+	started bool
+	cur     int
+	err     error
+}
+
+func (s *shardedScanner) Scan(ctx context.Context) bool {
+	if s.err != nil {
+		return false
+	}
+	// Advance the scanners which were positioned at the key that was
+	// last returned (or, initially, all of them).
+	var last string
+	if s.started {
+		if s.cur < 0 {
+			return false
+		}
+		last = s.Key()
+	}
+	for i, scanner := range s.scanners {
+		if s.started && (!s.ok[i] || scanner.Key() != last) {
+			continue
+		}
+		if s.ok[i] = scanner.Scan(ctx); !s.ok[i] {
+			if s.err = scanner.Err(); s.err != nil {
+				return false
+			}
+		}
+	}
+	s.started = true
+	s.cur = -1
+	for i, scanner := range s.scanners {
+		if s.ok[i] && (s.cur < 0 || scanner.Key() < s.scanners[s.cur].Key()) {
+			s.cur = i
+		}
+	}
+	return s.cur >= 0
+}
+
+func (s *shardedScanner) Err() error {
+	return s.err
+}
+
+func (s *shardedScanner) File() reflow.File {
+	return s.scanners[s.cur].File()
+}
+
+func (s *shardedScanner) Key() string {
+	return s.scanners[s.cur].Key()
+}
@@ -0,0 +1,100 @@
+// Copyright 2018 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package s3blob
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	"github.com/grailbio/reflow"
+	"github.com/grailbio/reflow/errors"
+	"github.com/grailbio/testutil/s3test"
+)
+
+func newShardedBucket(t *testing.T) (local, remote *Bucket, sharded *ShardedBucket) {
+	t.Helper()
+	for _, b := range []struct {
+		bucket **Bucket
+		name   string
+		keys   []string
+	}{
+		{&local, "local", []string{"a", "c"}},
+		{&remote, "remote", []string{"b", "c", "d"}},
+	} {
+		client := s3test.NewClient(t, b.name)
+		for _, k := range b.keys {
+			v := content(b.name + k)
+			client.SetFileContentAt(k, v, reflow.Digester.FromBytes(v.Data).Hex())
+		}
+		*b.bucket = NewBucket(b.name, client)
+	}
+	return local, remote, &ShardedBucket{name: "logical", shards: []*Bucket{local, remote}}
+}
+
+func TestShardedBucket(t *testing.T) {
+	local, _, bucket := newShardedBucket(t)
+	ctx := context.Background()
+	if got, want := bucket.Location(), "s3://logical/"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	// Objects are read from the shard which holds them, preferring the local one.
+	for key, want := range map[string]string{"a": "locala", "b": "remoteb", "c": "localc"} {
+		rc, _, err := bucket.Get(ctx, key, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		p, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(p); got != want {
+			t.Errorf("%s: got %v, want %v", key, got, want)
+		}
+	}
+	file, err := bucket.File(ctx, "d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := file.Source, "s3://remote/d"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if _, err := bucket.File(ctx, "missing"); !errors.Is(errors.NotExist, err) {
+		t.Errorf("expected NotExist, got %v", err)
+	}
+	// Objects are written to the local shard.
+	if err := bucket.Put(ctx, "e", 0, bytes.NewReader([]byte("new")), ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := local.File(ctx, "e"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestShardedScanner(t *testing.T) {
+	_, _, bucket := newShardedBucket(t)
+	ctx := context.Background()
+	scan := bucket.Scan("")
+	var keys []string
+	for scan.Scan(ctx) {
+		keys = append(keys, scan.Key())
+		// Keys held by multiple shards are scanned from the local one.
+		if scan.Key() == "c" && scan.File().Size != int64(len("localc")) {
+			t.Errorf("unexpected file %v", scan.File())
+		}
+	}
+	if err := scan.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := keys, []string{"a", "b", "c", "d"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if scan.Scan(ctx) {
+		t.Error("expected scan to be done")
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package blob
+
+import (
+	"context"
+
+	"github.com/grailbio/reflow"
+)
+
+// Access describes the access of a task to the objects under a blob
+// URL prefix.
+type Access struct {
+	// URL is the prefix (e.g., s3://bucket/path/) of the accessed objects.
+	URL string
+	// Write tells whether objects are written; objects are always read.
+	Write bool
+}
+
+// A Scoper mints short-lived credentials which permit only the
+// given accesses, so that tasks which transfer data need not be
+// granted the (broad) permissions of the process or instance on
+// which they run. Scope returns nil credentials if none of the
+// accesses require them.
+type Scoper interface {
+	Scope(ctx context.Context, accesses ...Access) (*reflow.Credentials, error)
+}
+
+// CredentialStore is implemented by stores which can access their
+// buckets with credentials other than their own.
+type CredentialStore interface {
+	Store
+	// WithCredentials returns a store which accesses buckets with the
+	// provided credentials.
+	WithCredentials(creds *reflow.Credentials) Store
+}
+
+// WithCredentials returns a Mux whose stores access their buckets
+// with the provided credentials. Stores which do not implement
+// CredentialStore are used as is. WithCredentials returns m if
+// creds is nil.
+func (m Mux) WithCredentials(creds *reflow.Credentials) Mux {
+	if creds == nil {
+		return m
+	}
+	n := make(Mux, len(m))
+	for scheme, store := range m {
+		if cs, ok := store.(CredentialStore); ok {
+			store = cs.WithCredentials(creds)
+		}
+		n[scheme] = store
+	}
+	return n
+}
@@ -71,12 +71,19 @@ func (b *bucket) get(key string) ([]byte, string, bool) {
 }
 
 func (b *bucket) file(key string) (reflow.File, []byte, bool) {
-	p, _, ok := b.get(key)
-	return reflow.File{
+	p, id, ok := b.get(key)
+	file := reflow.File{
 		Size:   int64(len(p)),
 		Source: fmt.Sprintf("%s/%s", b.name, key),
 		ETag:   fmt.Sprint(crc32.Checksum(p, crc32.IEEETable)),
-	}, p, ok
+	}
+	// As with S3, objects put with a content hash report it.
+	if id != "" {
+		if d, err := reflow.Digester.Parse(id); err == nil {
+			file.ContentHash = d
+		}
+	}
+	return file, p, ok
 }
 
 func (b *bucket) File(ctx context.Context, key string) (reflow.File, error) {
@@ -152,6 +159,20 @@ func (b *bucket) Get(ctx context.Context, key string, etag string) (io.ReadClose
 	return ioutil.NopCloser(bytes.NewReader(p)), file, nil
 }
 
+func (b *bucket) GetRange(ctx context.Context, key, etag string, offset, length int64) (io.ReadCloser, error) {
+	file, p, ok := b.file(key)
+	if !ok {
+		return nil, errors.E("testblob.GetRange", b.name, key, errors.NotExist)
+	}
+	if etag != "" && etag != file.ETag {
+		return nil, errors.E("testblob.GetRange", b.name, key, errors.Precondition)
+	}
+	if offset+length > int64(len(p)) {
+		return nil, errors.E("testblob.GetRange", b.name, key, errors.Invalid, errors.Errorf("range %d-%d out of bounds", offset, offset+length-1))
+	}
+	return ioutil.NopCloser(bytes.NewReader(p[offset : offset+length])), nil
+}
+
 func (b *bucket) Put(ctx context.Context, key string, size int64, body io.Reader, contentHash string) error {
 	if b.putErr != nil {
 		return b.putErr
@@ -22,7 +22,9 @@ func usage() {
 	fmt.Fprintln(os.Stderr, `usage: buildreflow [-o output] [-version version] [-p package]
 
 Buildreflow builds a reflow binary that's usable for distributed
-execution.`)
+execution. The reflowlet image for arm64 cluster instances (see the
+ec2cluster arm64 configuration) is built by running buildreflow with
+GOOS=linux and GOARCH=arm64.`)
 	flag.PrintDefaults()
 	os.Exit(2)
 }
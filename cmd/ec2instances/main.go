@@ -35,8 +35,9 @@ func usage() {
 
 ec2instances generates a Go package with EC2 instance metadata
 by pulling data from http://ec2instances.info/ and fetches AMIs 
-for the latest stable Flatcar release. It includes only x86_64 
-instances with Linux HVM support.
+for the latest stable Flatcar release. It includes x86_64 and arm64
+instances with Linux HVM support. AMIs are fetched for amd64 only;
+arm64 AMIs are configured separately in the cluster configuration.
 `)
 	flag.PrintDefaults()
 	os.Exit(2)
@@ -112,6 +113,8 @@ func generateInstances(dir string) {
 	g.Printf("	Generation string\n")
 	g.Printf("	// Virt stores the virtualization type used by this instance type.\n")
 	g.Printf("	Virt string\n")
+	g.Printf("	// Arch stores the CPU architecture of this instance type (\"x86_64\" or \"arm64\").\n")
+	g.Printf("	Arch string\n")
 	g.Printf("	// NVMe specifies whether EBS block devices are exposed as NVMe volumes.\n")
 	g.Printf("	NVMe bool\n")
 	g.Printf("	// CPUFeatures defines the available CPU features on this instance type\n")
@@ -132,15 +135,15 @@ func generateInstances(dir string) {
 
 	var acceptedTypes []string
 	for _, e := range entries {
-		var ok bool
-		for _, arch := range e.Arch {
-			if arch == "x86_64" {
-				ok = true
+		var arch string
+		for _, a := range e.Arch {
+			if a == "x86_64" || a == "arm64" {
+				arch = a
 				break
 			}
 		}
-		if !ok {
-			log.Printf("excluding instance type %s because it does not support arch x86_64", e.Type)
+		if arch == "" {
+			log.Printf("excluding instance type %s because it supports neither x86_64 nor arm64", e.Type)
 			continue
 		}
 		if strings.HasSuffix(e.Type, ".metal") {
@@ -151,10 +154,6 @@ func generateInstances(dir string) {
 			log.Printf("excluding instance type %s because its network performance can be Low", e.Type)
 			continue
 		}
-		if strings.HasPrefix(e.Type, "a1.") {
-			log.Printf("excluding instance type %s because it uses ARM (would need a different AMI)", e.Type)
-			continue
-		}
 		if strings.HasPrefix(e.Type, "u-") {
 			log.Printf("excluding High Memory instance type %s (purpose-built for large in-memory Databases)", e.Type)
 			continue
@@ -163,13 +162,7 @@ func generateInstances(dir string) {
 			log.Printf("excluding VT* instance type %s (specialized for video transcoding)", e.Type)
 			continue
 		}
-
-		parts := strings.Split(e.Type, ".")
-		if strings.HasSuffix(parts[0], "gd") || strings.HasSuffix(parts[0], "g") {
-			log.Printf("excluding instance type %s because it uses AWS Graviton (would need a different AMI)", e.Type)
-			continue
-		}
-		ok = false
+		var ok bool
 		// TODO(marius): should we prefer a particular virtualization type?
 		var virt string
 		// ec2instances doesn't seem to correctly classify the virtualization type of c5,
@@ -243,6 +236,7 @@ func generateInstances(dir string) {
 		g.Printf("	},\n")
 		g.Printf("	Generation: %q,\n", e.Generation)
 		g.Printf("	Virt: %q,\n", virt)
+		g.Printf("	Arch: %q,\n", arch)
 		g.Printf("	NVMe: %v,\n", e.EbsAsNvme)
 		g.Printf("	CPUFeatures: map[string]bool{\n")
 		if e.IntelAVX {
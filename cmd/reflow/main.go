@@ -17,22 +17,28 @@ import (
 	"github.com/grailbio/reflow"
 	"github.com/grailbio/reflow/assoc"
 	_ "github.com/grailbio/reflow/assoc/dydbassoc"
+	_ "github.com/grailbio/reflow/assoc/fileassoc"
 	_ "github.com/grailbio/reflow/ec2cluster"
 	infra2 "github.com/grailbio/reflow/infra"
 	_ "github.com/grailbio/reflow/localcluster"
 	"github.com/grailbio/reflow/log"
 	"github.com/grailbio/reflow/metrics"
 	_ "github.com/grailbio/reflow/metrics/prometrics"
+	_ "github.com/grailbio/reflow/metrics/statsd"
 	"github.com/grailbio/reflow/pool"
+	_ "github.com/grailbio/reflow/repository/filerepo"
 	_ "github.com/grailbio/reflow/repository/s3"
 	"github.com/grailbio/reflow/runner"
 	"github.com/grailbio/reflow/taskdb"
+	_ "github.com/grailbio/reflow/taskdb/bigqueryexport"
 	_ "github.com/grailbio/reflow/taskdb/dynamodbtask"
 	_ "github.com/grailbio/reflow/taskdb/noptaskdb"
+	_ "github.com/grailbio/reflow/taskdb/redshiftexport"
 	"github.com/grailbio/reflow/tool"
 	"github.com/grailbio/reflow/trace"
 	_ "github.com/grailbio/reflow/trace"
 	_ "github.com/grailbio/reflow/trace/localtrace"
+	_ "github.com/grailbio/reflow/trace/otlptrace"
 	_ "github.com/grailbio/reflow/trace/xraytrace"
 )
 
@@ -83,27 +89,33 @@ func main() {
 		},
 	}
 	cmd.Schema = infra.Schema{
-		infra2.AWSCreds:   new(credentials.Credentials),
-		infra2.Assoc:      new(assoc.Assoc),
-		infra2.AWSTool:    new(aws.AWSTool),
-		infra2.Cache:      new(infra2.CacheProvider),
-		infra2.Cluster:    new(runner.Cluster),
-		infra2.Labels:     make(pool.Labels),
-		infra2.Log:        new(log.Logger),
-		infra2.Bootstrap:  new(infra2.BootstrapImage),
-		infra2.Reflow:     new(infra2.ReflowVersion),
-		infra2.Reflowlet:  new(infra2.ReflowletConfig),
-		infra2.Repository: new(reflow.Repository),
-		infra2.Session:    new(session.Session),
-		infra2.SSHKey:     new(infra2.Ssh),
-		infra2.TLS:        new(tls.Certs),
-		infra2.Username:   new(infra2.User),
-		infra2.Tracer:     new(trace.Tracer),
-		infra2.Metrics:    new(metrics.Client),
-		infra2.TaskDB:     new(taskdb.TaskDB),
-		infra2.Docker:     new(infra2.DockerConfig),
-		infra2.Predictor:  new(infra2.PredictorConfig),
-		infra2.RunID:      new(taskdb.RunID),
+		infra2.AWSCreds:    new(credentials.Credentials),
+		infra2.Assoc:       new(assoc.Assoc),
+		infra2.AWSTool:     new(aws.AWSTool),
+		infra2.Cache:       new(infra2.CacheProvider),
+		infra2.Cluster:     new(runner.Cluster),
+		infra2.Labels:      make(pool.Labels),
+		infra2.Log:         new(log.Logger),
+		infra2.Bootstrap:   new(infra2.BootstrapImage),
+		infra2.Reflow:      new(infra2.ReflowVersion),
+		infra2.Reflowlet:   new(infra2.ReflowletConfig),
+		infra2.Repository:  new(reflow.Repository),
+		infra2.Session:     new(session.Session),
+		infra2.SSHKey:      new(infra2.Ssh),
+		infra2.TLS:         new(tls.Certs),
+		infra2.Username:    new(infra2.User),
+		infra2.Tracer:      new(trace.Tracer),
+		infra2.Metrics:     new(metrics.Client),
+		infra2.TaskDB:      new(taskdb.TaskDB),
+		infra2.Docker:      new(infra2.DockerConfig),
+		infra2.Predictor:   new(infra2.PredictorConfig),
+		infra2.RunID:       new(taskdb.RunID),
+		infra2.Admins:      new(infra2.Admin),
+		infra2.RunExporter: new(taskdb.Exporter),
+		infra2.Templates:   new(infra2.RunTemplates),
+		infra2.S3Buckets:   new(infra2.S3BucketOptions),
+		infra2.FileBlob:    new(infra2.FileBlob),
+		infra2.LabelPolicy: new(infra2.LabelPolicy),
 	}
 	cmd.SchemaKeys = infra.Keys{
 		infra2.AWSCreds:  "awscreds",
@@ -122,6 +134,10 @@ func main() {
 		infra2.Metrics:   "nopmetrics",
 		infra2.Docker:    "docker,memlimit=soft",
 		infra2.RunID:     "runid",
+		infra2.Admins:    "admin",
+		infra2.Templates: "runtemplates",
+		infra2.S3Buckets: "s3bucketoptions",
+		infra2.FileBlob:  "fileblob",
 	}
 	cmd.BootstrapBinary = bootstrapimage
 	cmd.Flags().Parse(os.Args[1:])
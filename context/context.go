@@ -11,4 +11,6 @@ const (
 	SpanKey   key = 1
 
 	MetricsClientKey key = 2
+
+	LogicalIdKey key = 3
 )
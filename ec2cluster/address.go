@@ -0,0 +1,106 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package ec2cluster
+
+import (
+	"fmt"
+	"net"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// IP addressing modes of cluster instances.
+const (
+	// ipv4 instances are launched with a public IPv4 address and are
+	// addressed by their public DNS name. This is the default.
+	ipv4 = "ipv4"
+	// dualStack instances are additionally assigned an IPv6 address.
+	// They are addressed by their public DNS name if they have one,
+	// and by their IPv6 address otherwise.
+	dualStack = "dualstack"
+	// ipv6 instances are launched into IPv6-only subnets, without a
+	// public IPv4 address, and are addressed by their IPv6 address.
+	ipv6 = "ipv6"
+)
+
+// reflowletPort is the port on which the bootstrap and reflowlet
+// servers listen.
+const reflowletPort = "9000"
+
+// validateIPAddressing returns an error if mode is not a valid
+// IP addressing mode. An empty mode is interpreted as ipv4.
+func validateIPAddressing(mode string) error {
+	switch mode {
+	case "", ipv4, dualStack, ipv6:
+		return nil
+	}
+	return fmt.Errorf("invalid ip addressing mode %q (must be one of %s, %s, %s)", mode, ipv4, dualStack, ipv6)
+}
+
+// instanceHost returns the host (a DNS name or an IP address) at which
+// the given instance is reachable in the given IP addressing mode.
+// instanceHost returns an empty string if the instance does not (yet)
+// have an address.
+func instanceHost(inst *ec2.Instance, mode string) string {
+	if dns := aws.StringValue(inst.PublicDnsName); dns != "" && mode != ipv6 {
+		return dns
+	}
+	if mode == "" || mode == ipv4 {
+		return ""
+	}
+	// Prefer the addresses of the primary network interface.
+	nis := append([]*ec2.InstanceNetworkInterface{}, inst.NetworkInterfaces...)
+	sort.SliceStable(nis, func(i, j int) bool {
+		return deviceIndex(nis[i]) < deviceIndex(nis[j])
+	})
+	for _, ni := range nis {
+		for _, addr := range ni.Ipv6Addresses {
+			if ip := aws.StringValue(addr.Ipv6Address); ip != "" {
+				return ip
+			}
+		}
+	}
+	return ""
+}
+
+func deviceIndex(ni *ec2.InstanceNetworkInterface) int64 {
+	if ni.Attachment == nil {
+		return 0
+	}
+	return aws.Int64Value(ni.Attachment.DeviceIndex)
+}
+
+// reflowletAddr returns the address (host and port) of the reflowlet
+// served at the given host. IPv6 literals are bracketed.
+func reflowletAddr(host string) string {
+	return net.JoinHostPort(host, reflowletPort)
+}
+
+// reflowletURL returns the base URL of the reflowlet (or bootstrap)
+// API served at the given host.
+func reflowletURL(host string) string {
+	return fmt.Sprintf("https://%s/v1/", reflowletAddr(host))
+}
+
+// networkInterfaces returns the network interface specification with
+// which to launch instances into the given subnet in the given IP
+// addressing mode, or nil if the default interface is to be used.
+// When an interface is specified, the security group and subnet must
+// be set on it (and not on the launch request itself).
+func networkInterfaces(mode, securityGroup, subnet string) []*ec2.InstanceNetworkInterfaceSpecification {
+	if mode == "" || mode == ipv4 {
+		return nil
+	}
+	return []*ec2.InstanceNetworkInterfaceSpecification{{
+		DeviceIndex:              aws.Int64(0),
+		Groups:                   []*string{aws.String(securityGroup)},
+		SubnetId:                 nonemptyString(subnet),
+		Ipv6AddressCount:         aws.Int64(1),
+		AssociatePublicIpAddress: aws.Bool(mode == dualStack),
+		DeleteOnTermination:      aws.Bool(true),
+	}}
+}
@@ -0,0 +1,88 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package ec2cluster
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func TestInstanceHost(t *testing.T) {
+	const (
+		dns  = "ec2-35-165-199-174.us-west-2.compute.amazonaws.com"
+		addr = "2600:1f14:abc:de00::1"
+	)
+	nis := []*ec2.InstanceNetworkInterface{
+		{
+			Attachment:    &ec2.InstanceNetworkInterfaceAttachment{DeviceIndex: aws.Int64(1)},
+			Ipv6Addresses: []*ec2.InstanceIpv6Address{{Ipv6Address: aws.String("2600:1f14:abc:de00::2")}},
+		},
+		{
+			Attachment:    &ec2.InstanceNetworkInterfaceAttachment{DeviceIndex: aws.Int64(0)},
+			Ipv6Addresses: []*ec2.InstanceIpv6Address{{Ipv6Address: aws.String(addr)}},
+		},
+	}
+	dualInst := &ec2.Instance{PublicDnsName: aws.String(dns), NetworkInterfaces: nis}
+	v6Inst := &ec2.Instance{PublicDnsName: aws.String(""), NetworkInterfaces: nis}
+	for _, tt := range []struct {
+		inst *ec2.Instance
+		mode string
+		want string
+	}{
+		{dualInst, "", dns},
+		{dualInst, ipv4, dns},
+		{dualInst, dualStack, dns},
+		{dualInst, ipv6, addr},
+		{v6Inst, ipv4, ""},
+		{v6Inst, dualStack, addr},
+		{v6Inst, ipv6, addr},
+		{&ec2.Instance{}, ipv6, ""},
+	} {
+		if got, want := instanceHost(tt.inst, tt.mode), tt.want; got != want {
+			t.Errorf("%s: got %v, want %v", tt.mode, got, want)
+		}
+	}
+}
+
+func TestReflowletURL(t *testing.T) {
+	for _, tt := range []struct {
+		host, want string
+	}{
+		{"ec2-35-165-199-174.us-west-2.compute.amazonaws.com", "https://ec2-35-165-199-174.us-west-2.compute.amazonaws.com:9000/v1/"},
+		{"10.0.0.1", "https://10.0.0.1:9000/v1/"},
+		{"2600:1f14::1", "https://[2600:1f14::1]:9000/v1/"},
+	} {
+		if got, want := reflowletURL(tt.host), tt.want; got != want {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	}
+}
+
+func TestNetworkInterfaces(t *testing.T) {
+	if nis := networkInterfaces(ipv4, "sg", "subnet"); nis != nil {
+		t.Errorf("got %v, want nil", nis)
+	}
+	for _, mode := range []string{dualStack, ipv6} {
+		nis := networkInterfaces(mode, "sg", "subnet")
+		if got, want := len(nis), 1; got != want {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+		ni := nis[0]
+		if got, want := aws.Int64Value(ni.Ipv6AddressCount), int64(1); got != want {
+			t.Errorf("got %v, want %v", got, want)
+		}
+		if got, want := aws.BoolValue(ni.AssociatePublicIpAddress), mode == dualStack; got != want {
+			t.Errorf("%s: got %v, want %v", mode, got, want)
+		}
+		if got, want := aws.StringValue(ni.SubnetId), "subnet"; got != want {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	}
+	if err := validateIPAddressing("ipv5"); err == nil {
+		t.Error("expected error")
+	}
+}
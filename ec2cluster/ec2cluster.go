@@ -28,6 +28,8 @@ import (
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
 	sa "github.com/grailbio/base/cloud/spotadvisor"
@@ -47,7 +49,9 @@ import (
 	"github.com/grailbio/reflow/metrics/prometrics"
 	"github.com/grailbio/reflow/pool"
 	"github.com/grailbio/reflow/pool/client"
+	"github.com/grailbio/reflow/sched"
 	"github.com/grailbio/reflow/taskdb"
+	"github.com/grailbio/reflow/trace"
 	"golang.org/x/net/http2"
 	"golang.org/x/time/rate"
 )
@@ -117,6 +121,11 @@ type Cluster struct {
 	// When requesting a spot instance in a particular AZ, the appropriate subnet will be used.
 	// If this list contains duplicate subnets for any AZ, behavior (of which subnet is used) is non-deterministic.
 	Subnets []string `yaml:"subnets,omitempty"`
+	// IPAddressing is the IP addressing mode of cluster instances: one of "ipv4" (the default),
+	// "dualstack" (instances are additionally assigned an IPv6 address) or "ipv6" (instances are
+	// launched into IPv6-only subnets, without a public IPv4 address, and are reached at their
+	// IPv6 address). The "ipv6" mode requires Subnets to be specified.
+	IPAddressing string `yaml:"ipaddressing,omitempty"`
 	// InstanceTypesMap stores the set of admissible instance types.
 	// If nil, all instance types are permitted.
 	InstanceTypesMap map[string]bool `yaml:"-"`
@@ -149,6 +158,8 @@ type Cluster struct {
 	Session *session.Session `yaml:"-"`
 	// TaskDB implementation (if any) where rows are updated for newly created pools.
 	TaskDB taskdb.TaskDB `yaml:"-"`
+	// MetricsClient is the client through which cluster metrics (eg, cost) are emitted.
+	MetricsClient metrics.Client `yaml:"-"`
 
 	// Public SSH keys.
 	SshKeys []string `yaml:"sshkeys"`
@@ -156,6 +167,18 @@ type Cluster struct {
 	KeyName string `yaml:"keyname"`
 	// Immortal determines whether instances should be made immortal.
 	Immortal bool `yaml:"immortal,omitempty"`
+	// LogFormat is the log format of the cluster's reflowlets: "text"
+	// (the default) or "json", in which case reflowlets log structured
+	// JSON records (see log.JSONOutputter).
+	LogFormat string `yaml:"logformat,omitempty"`
+	// Logs is the destination to which the cluster's reflowlets ship
+	// their own logs and the logs of their execs, so that they survive
+	// the termination of their instances: "cloudwatch" (the default)
+	// ships them to CloudWatch Logs (log groups "reflow/reflowlet" and
+	// "reflow" respectively), while an S3 prefix URL (e.g.,
+	// s3://bucket/logs) writes them to objects under the prefix, named
+	// by instance ID, and by run, alloc and exec ID, respectively.
+	Logs string `yaml:"logs,omitempty"`
 	// NodeExporterMetricsPort determines whether to run a prometheus node_exporter daemon
 	// on each Reflowlet. Setting a value runs the node_exporter daemon and configures it to
 	// output prometheus metrics on the given port. Passing a non-zero value also adds an
@@ -170,6 +193,12 @@ type Cluster struct {
 	CloudConfig cloudConfig `yaml:"cloudconfig"`
 	// SpotProbeDepth is the probing depth for spot instance capacity checks.
 	SpotProbeDepth int `yaml:"spotprobedepth,omitempty"`
+	// RemediateUnhealthy enables the automatic remediation of unhealthy
+	// instances: instances whose EC2 status checks report impairment, or
+	// whose reflowlets repeatedly fail health checks, are rebooted and,
+	// if they remain unhealthy, replaced. Each action and its outcome is
+	// logged to the audit trail (log lines prefixed by "audit:").
+	RemediateUnhealthy bool `yaml:"remediateunhealthy,omitempty"`
 
 	// Status is used to report cluster and instance status.
 	Status *status.Group `yaml:"-"`
@@ -177,15 +206,60 @@ type Cluster struct {
 	// InstanceTypes defines the set of allowable EC2 instance types for
 	// this cluster. If empty, all instance types are permitted.
 	InstanceTypes []string `yaml:"instancetypes,omitempty"`
+	// ReservedCoverage maps an instance family (eg, "m5") to the fraction
+	// (between 0 and 1) of its on-demand price which is already paid for by
+	// Reserved Instances or Savings Plans. When launching on-demand instances,
+	// instance types in covered families are preferred accordingly, and the
+	// on-demand instances of covered families are counted at their effective
+	// rate by the cluster's budget (MaxHourlyCostUSD) and cost reporting.
+	ReservedCoverage map[string]float64 `yaml:"reservedcoverage,omitempty"`
+	// DockerSnapshots maps an AMI ID to the ID of an EBS snapshot containing
+	// a pre-seeded docker layer cache for instances launched from that AMI.
+	// The snapshot must contain an ext4 filesystem, labeled "reflow-docker",
+	// holding a docker data root (ie, the contents of /var/lib/docker), with
+	// enough free space for additional images. This reduces the latency of
+	// the first tasks on new instances which use heavyweight images.
+	DockerSnapshots map[string]string `yaml:"dockersnapshots,omitempty"`
+	// DockerDataVolume places the docker data root, and thus its layer
+	// cache, on the instances' EBS data volume (at /mnt/data/docker)
+	// rather than on their root device, so that the cache may grow with
+	// the data volume (which the cache then shares with the reflowlet's
+	// data), and survives reflowlet restarts and upgrades. It may not be
+	// used together with DockerSnapshots.
+	DockerDataVolume bool `yaml:"dockerdatavolume,omitempty"`
+	// DockerCacheSeed is the S3 URL of a tarball of images, as produced
+	// by "docker save", with which the reflowlets of new instances seed
+	// their docker layer cache, so that long-lived instances need not
+	// pull commonly used images.
+	DockerCacheSeed string `yaml:"dockercacheseed,omitempty"`
+	// Arm64 configures the use of arm64 (eg, AWS Graviton) instance types.
+	// Arm64 instance types are considered only if an AMI is configured,
+	// and are used only for execs which require the "arm64" CPU feature.
+	Arm64 ArchConfig `yaml:"arm64,omitempty"`
 	// Name is the name of the cluster config, which defaults to defaultClusterName.
 	// Multiple clusters can be launched/maintained simultaneously by using different names.
 	Name string `yaml:"name,omitempty"`
+	// ExternalASG, if configured, makes the cluster join an externally
+	// managed Auto Scaling Group instead of launching instances itself.
+	ExternalASG ExternalASGConfig `yaml:"externalasg,omitempty"`
 
 	instanceState   *instanceState
 	instanceConfigs map[string]instanceConfig
 
 	mu    sync.Mutex
 	pools map[string]reflowletPool
+	// waiting is the amount of resources waited for by allocations,
+	// as last notified by the manager.
+	waiting reflow.Resources
+
+	// asg is the external group joined by the cluster, if any.
+	asg *externalASG
+
+	// protectMu serializes changes to the termination protection of
+	// instances; protected counts the critical tasks running on each
+	// (protected) instance.
+	protectMu sync.Mutex
+	protected map[string]int
 
 	// manager manages the cluster
 	manager *Manager
@@ -282,6 +356,7 @@ func (c *Cluster) Init(tls tls.Certs, sess *session.Session, labels pool.Labels,
 	c.BootstrapImage = bootstrapimage.Value()
 	c.ReflowVersion = string(*reflowVersion)
 	c.SshKeys = ssh.Keys()
+	c.MetricsClient = mclient
 	if pclient, ok := mclient.(*prometrics.Client); ok {
 		c.NodeExporterMetricsPort = pclient.NodeExporterPort
 	}
@@ -317,15 +392,36 @@ func (c *Cluster) Init(tls tls.Certs, sess *session.Session, labels pool.Labels,
 	if c.AMI == "" {
 		return errors.New("missing AMI parameter")
 	}
-	if c.SecurityGroup == "" {
+	// Instances of an external group are launched by its owners.
+	if c.SecurityGroup == "" && !c.ExternalASG.Enabled() {
 		return errors.New("missing EC2 security group")
 	}
+	if err = validateIPAddressing(c.IPAddressing); err != nil {
+		return err
+	}
+	if c.IPAddressing == ipv6 && len(c.Subnets) == 0 {
+		return errors.New("ipv6 addressing requires (IPv6-only) subnets to be specified")
+	}
+	switch c.LogFormat {
+	case "", "text", "json":
+	default:
+		return errors.New(fmt.Sprintf("invalid reflowlet log format %q: must be text or json", c.LogFormat))
+	}
+	if c.Logs != "" && c.Logs != logsCloudWatch && !strings.HasPrefix(c.Logs, "s3://") {
+		return errors.New(fmt.Sprintf("invalid reflowlet logs destination %q: must be cloudwatch or an S3 URL", c.Logs))
+	}
+	if c.ExternalASG.Enabled() && c.RemediateUnhealthy {
+		return errors.New("unhealthy instances of an external auto scaling group cannot be remediated")
+	}
 
 	// Construct the set of legal instances and set available disk space.
 	var configs []instanceConfig
 	c.instanceConfigs = make(map[string]instanceConfig)
 	for _, config := range instanceTypes {
 		config.Resources["disk"] = float64(c.DiskSpace << 30)
+		if config.Arch == reflow.Arm64 && c.Arm64.AMI == "" {
+			continue
+		}
 		if c.InstanceTypesMap == nil || c.InstanceTypesMap[config.Type] {
 			configs = append(configs, config)
 		}
@@ -339,12 +435,32 @@ func (c *Cluster) Init(tls tls.Certs, sess *session.Session, labels pool.Labels,
 	if len(configs) == 0 {
 		return errors.New("no configured instance types")
 	}
+	if c.Arm64.AMI != "" && (c.Arm64.BootstrapImage == "" || c.Arm64.ReflowletImage == "") {
+		return errors.New("arm64 instances require both a bootstrap and a reflowlet image")
+	}
+	for ami, snapshot := range c.DockerSnapshots {
+		if !strings.HasPrefix(snapshot, "snap-") {
+			return errors.Errorf("invalid docker snapshot ID for AMI %s: %s", ami, snapshot)
+		}
+	}
+	if c.DockerDataVolume && len(c.DockerSnapshots) > 0 {
+		return errors.New("docker snapshots cannot be used with a docker data volume")
+	}
+	if c.DockerCacheSeed != "" && !strings.HasPrefix(c.DockerCacheSeed, "s3://") {
+		return errors.New(fmt.Sprintf("invalid docker cache seed %q: must be an S3 URL", c.DockerCacheSeed))
+	}
+	for family, frac := range c.ReservedCoverage {
+		if frac < 0 || frac > 1 {
+			return errors.Errorf("reserved coverage for instance family %s must be between 0 and 1: %v", family, frac)
+		}
+	}
 	adv, _ := sa.NewSpotAdvisor(c.Log, context.Background().Done())
 	c.instanceState = newInstanceState(configs, unavailableInstanceTypeTtl, c.Region(), adv)
+	c.instanceState.SetCoverage(c.ReservedCoverage)
 	c.manager = NewManager(c, c.MaxHourlyCostUSD, c.MaxPendingInstances, c.Log)
 	c.spotProber = NewSpotProber(
 		func(ctx context.Context, instanceType string, depth int) (bool, error) {
-			return ec2HasCapacity(ctx, c.EC2, c.AMI, instanceType, depth, c.Log)
+			return ec2HasCapacity(ctx, c.EC2, c.archConfig(c.instanceConfigs[instanceType]).AMI, instanceType, depth, c.Log)
 		},
 		c.SpotProbeDepth, 1*time.Minute)
 	c.pools = make(map[string]reflowletPool)
@@ -361,11 +477,39 @@ func (c *Cluster) ExportStats() {
 func (c *Cluster) Verify() error {
 	err := validateBootstrap(c.BootstrapImage, http.DefaultClient)
 	if err != nil {
-		err = errors.E(errors.Fatal, fmt.Sprintf("bootstrap image: %s", c.BootstrapImage), err)
+		return errors.E(errors.Fatal, fmt.Sprintf("bootstrap image: %s", c.BootstrapImage), err)
+	}
+	if c.Arm64.AMI == "" {
+		return nil
+	}
+	if err = validateBootstrap(c.Arm64.BootstrapImage, http.DefaultClient); err != nil {
+		err = errors.E(errors.Fatal, fmt.Sprintf("arm64 bootstrap image: %s", c.Arm64.BootstrapImage), err)
 	}
 	return err
 }
 
+// ArchConfig configures the instances of a particular CPU architecture
+// which differs from that of the default AMI and reflow binary.
+type ArchConfig struct {
+	// AMI is the EC2 AMI used to launch instances of this architecture.
+	AMI string `yaml:"ami,omitempty"`
+	// BootstrapImage is the URL of the bootstrap image built for this architecture.
+	BootstrapImage string `yaml:"bootstrapimage,omitempty"`
+	// ReflowletImage is the URL of a reflow binary built for this architecture
+	// (eg, using buildreflow with GOARCH=arm64). It must be of the same
+	// version as the reflow binary that drives the cluster.
+	ReflowletImage string `yaml:"reflowletimage,omitempty"`
+}
+
+// archConfig returns the architecture-specific settings for instances
+// of the given config.
+func (c *Cluster) archConfig(config instanceConfig) ArchConfig {
+	if config.Arch == reflow.Arm64 {
+		return c.Arm64
+	}
+	return ArchConfig{AMI: c.AMI, BootstrapImage: c.BootstrapImage}
+}
+
 // initialize initializes the cluster by starting maintenance goroutines.
 func (c *Cluster) initialize(ctx context.Context, wg *sync.WaitGroup) {
 	if c.BootstrapExpiry == 0 {
@@ -392,7 +536,22 @@ func (c *Cluster) initialize(ctx context.Context, wg *sync.WaitGroup) {
 	c.reqSpotLimiter = rate.NewLimiter(rate.Every(time.Second), 5) // 5 qps
 	c.refreshLimiter = rate.NewLimiter(rate.Every(time.Second), 1) // 1 qps
 	c.SetCaching(true)
-	c.manager.Start(ctx, wg)
+	if c.ExternalASG.Enabled() {
+		c.asg = newExternalASG(c.ExternalASG, autoscaling.New(c.Session), cloudwatch.New(c.Session), c.Log)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.asg.maintain(ctx)
+		}()
+	}
+	c.manager.Start(metrics.WithClient(ctx, c.MetricsClient), wg)
+	if c.RemediateUnhealthy {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.heal(ctx)
+		}()
+	}
 }
 
 // Region is the AWS region to use for launching new EC2 instances.
@@ -405,6 +564,8 @@ func (c *Cluster) Region() string {
 
 // CanAllocate returns whether this cluster can allocate the given amount of resources.
 func (c *Cluster) CanAllocate(r reflow.Resources) (bool, error) {
+	// Any instance type may be launched on-demand.
+	r, _ = splitOnDemand(r)
 	if !c.instanceState.Available(r) {
 		max := c.instanceState.Largest()
 		return false, errors.E(errors.ResourcesExhausted,
@@ -425,6 +586,11 @@ func (c *Cluster) Allocate(ctx context.Context, req reflow.Requirements, labels
 		return nil, er
 	}
 	c.Log.Debugf("allocate %s", req)
+	defer func() {
+		if err == nil {
+			trace.Note(ctx, "hourlyCostUSD", c.AllocHourlyCostUSD(alloc))
+		}
+	}()
 	const allocTimeout = 30 * time.Second
 	if c.Size() > 0 {
 		c.Log.Debug("attempting to allocate from existing pool")
@@ -436,6 +602,9 @@ func (c *Cluster) Allocate(ctx context.Context, req reflow.Requirements, labels
 		}
 		c.Log.Debugf("failed to allocate from existing pool: %v; provisioning from EC2", err)
 	}
+	if c.asg != nil {
+		return c.allocateExternal(ctx, req, labels)
+	}
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 	ticker := time.NewTicker(allocAttemptInterval)
@@ -533,8 +702,8 @@ probe:
 		defer ec2TerminateInstance(i.EC2, *i.ec2inst.InstanceId, i.Log)
 	}
 	if i.err == nil {
-		iid, dns := *i.ec2inst.InstanceId, *i.ec2inst.PublicDnsName
-		baseurl := fmt.Sprintf("https://%s:9000/v1/", dns)
+		iid, dns := *i.ec2inst.InstanceId, instanceHost(i.ec2inst, c.IPAddressing)
+		baseurl := reflowletURL(dns)
 		if clnt, err := client.New(baseurl, c.HTTPClient, nil); err != nil {
 			c.Log.Errorf("client %s: %v", baseurl, err)
 		} else {
@@ -557,6 +726,7 @@ probe:
 }
 
 func (c *Cluster) newInstance(config instanceConfig) *instance {
+	arch := c.archConfig(config)
 	return &instance{
 		HTTPClient:              c.HTTPClient,
 		ReflowConfig:            c.Configuration,
@@ -570,14 +740,19 @@ func (c *Cluster) newInstance(config instanceConfig) *instance {
 		Spot:                    c.Spot,
 		InstanceProfile:         c.InstanceProfile,
 		SecurityGroup:           c.SecurityGroup,
+		IPAddressing:            c.IPAddressing,
 		Region:                  c.Region(),
-		BootstrapImage:          c.BootstrapImage,
+		BootstrapImage:          arch.BootstrapImage,
 		BootstrapExpiry:         c.BootstrapExpiry,
+		ReflowletImage:          arch.ReflowletImage,
 		Price:                   config.Price[c.Region()],
 		EBSType:                 c.DiskType,
 		EBSSize:                 uint64(config.Resources["disk"]) >> 30,
 		NEBS:                    c.DiskSlices,
-		AMI:                     c.AMI,
+		AMI:                     arch.AMI,
+		DockerSnapshot:          c.DockerSnapshots[arch.AMI],
+		DockerDataVolume:        c.DockerDataVolume,
+		DockerCacheSeed:         c.DockerCacheSeed,
 		SshKeys:                 c.SshKeys,
 		KeyName:                 c.KeyName,
 		SpotProber:              c.spotProber,
@@ -585,6 +760,8 @@ func (c *Cluster) newInstance(config instanceConfig) *instance {
 		DescSpotLimiter:         c.descSpotLimiter,
 		ReqSpotLimiter:          c.reqSpotLimiter,
 		Immortal:                c.Immortal,
+		LogFormat:               c.LogFormat,
+		Logs:                    c.Logs,
 		NodeExporterMetricsPort: c.NodeExporterMetricsPort,
 		CloudConfig:             c.CloudConfig,
 		ReflowVersion:           c.ReflowVersion,
@@ -593,9 +770,38 @@ func (c *Cluster) newInstance(config instanceConfig) *instance {
 
 // Available returns the cheapest available instance specification that
 // has at least the required resources.
-func (c *Cluster) Available(need reflow.Resources, maxPrice float64) (InstanceSpec, bool) {
-	config, ok := c.instanceState.MinAvailable(need, c.Spot, maxPrice)
-	return InstanceSpec{config.Type, config.Resources}, ok
+// If the need includes on-demand capacity, the returned specification
+// is for an on-demand instance, regardless of the cluster's spot setting.
+// Instance types with larger penalties are de-prioritized.
+func (c *Cluster) Available(need reflow.Resources, maxPrice float64, penalties map[string]float64) (InstanceSpec, bool) {
+	need, onDemand := splitOnDemand(need)
+	config, ok := c.instanceState.MinAvailable(need, c.Spot && !onDemand, maxPrice, penalties)
+	spec := InstanceSpec{config.Type, config.Resources}
+	if ok && onDemand {
+		spec.Resources = onDemandResources(config.Resources)
+	}
+	return spec, ok
+}
+
+// splitOnDemand returns the resources r without the on-demand label,
+// and whether r requires on-demand capacity.
+func splitOnDemand(r reflow.Resources) (reflow.Resources, bool) {
+	if r[reflow.OnDemand] == 0 {
+		return r, false
+	}
+	var s reflow.Resources
+	s.Set(r)
+	delete(s, reflow.OnDemand)
+	return s, true
+}
+
+// onDemandResources returns the resources presented by an on-demand
+// instance with resources r.
+func onDemandResources(r reflow.Resources) reflow.Resources {
+	var s reflow.Resources
+	s.Set(r)
+	s[reflow.OnDemand] = r["cpu"]
+	return s
 }
 
 // Launch launches an EC2 instance based on the given spec and returns a ManagedInstance.
@@ -605,6 +811,10 @@ func (c *Cluster) Launch(ctx context.Context, spec InstanceSpec) ManagedInstance
 		return spec.Instance("")
 	}
 	i := c.newInstance(config)
+	onDemand := spec.Resources[reflow.OnDemand] > 0
+	if onDemand {
+		i.Spot = false
+	}
 	i.Task = c.Status.Startf("%s", spec.Type)
 	i.Go(ctx)
 	i.Task.Done()
@@ -620,13 +830,35 @@ func (c *Cluster) Launch(ctx context.Context, spec InstanceSpec) ManagedInstance
 	// case errors.Is(errors.Fatal, inst.Err()):
 	default:
 	}
-	return i.ManagedInstance()
+	mi := i.ManagedInstance()
+	if onDemand {
+		mi.Resources = onDemandResources(mi.Resources)
+	}
+	return mi
 }
 
 func (c *Cluster) Notify(waiting, pending reflow.Resources) {
+	c.mu.Lock()
+	c.waiting.Set(waiting)
+	c.mu.Unlock()
 	c.printState(fmt.Sprintf("waiting%s, pending%s", waiting, pending))
 }
 
+// Need returns the amount of resources which the cluster's pending
+// allocations are waiting for. For clusters which join an external
+// Auto Scaling Group, the need is also published for the group's
+// scaler (see ExternalASGConfig).
+func (c *Cluster) Need() reflow.Resources {
+	if c.asg != nil {
+		return c.asg.Need()
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var need reflow.Resources
+	need.Set(c.waiting)
+	return need
+}
+
 func (c *Cluster) Refresh(ctx context.Context) (map[string]string, error) {
 	state, err := c.getEC2State(ctx)
 	if err != nil {
@@ -644,13 +876,18 @@ func (c *Cluster) Refresh(ctx context.Context) (map[string]string, error) {
 	// Add instances on EC2 that are not in the pool.
 	for id, inst := range state {
 		if _, ok := c.pools[id]; !ok {
-			iid, typ, dns := *inst.InstanceId, *inst.InstanceType, *inst.PublicDnsName
-			baseurl := fmt.Sprintf("https://%s:9000/v1/", dns)
+			iid, typ, dns := *inst.InstanceId, *inst.InstanceType, instanceHost(&inst.Instance, c.IPAddressing)
+			if dns == "" {
+				c.Log.Debugf("instance %s (%s) has no address", iid, typ)
+				continue
+			}
+			baseurl := reflowletURL(dns)
 			clnt, cerr := client.New(baseurl, c.HTTPClient, nil)
 			if cerr != nil {
 				c.Log.Errorf("client %s: %v", baseurl, cerr)
 				continue
 			}
+			clnt.Resolve, clnt.Identity = c.resolveInstance(iid), iid
 			c.Log.Debugf("discovered instance %s (%s) %s", iid, typ, dns)
 			// Add instance to the pool.
 			c.pools[iid] = reflowletPool{inst, clnt}
@@ -670,14 +907,37 @@ func (c *Cluster) Refresh(ctx context.Context) (map[string]string, error) {
 // which have the set of tags returned by `QueryTags`.
 // At the time of writing this, its unclear how much (if any) propagation delay
 // exists between tagging an instance and the instance being returned by the AWS API.
+//
+// If the cluster joins an external group, it instead consists of the
+// group's in-service instances whose reflowlets are of the cluster's
+// reflow version.
 func (c *Cluster) getEC2State(ctx context.Context) (map[string]*reflowletInstance, error) {
+	if c.asg == nil {
+		return c.describeInstances(ctx, c.QueryTags())
+	}
+	ids, err := c.asg.instances(ctx)
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+	return c.describeInstances(ctx, map[string]string{versionKey: c.ReflowVersion}, ids...)
+}
+
+// describeInstances returns the running EC2 instances which have the given set of tags.
+// If instance IDs are provided, only those instances are considered.
+func (c *Cluster) describeInstances(ctx context.Context, tags map[string]string, ids ...string) (map[string]*reflowletInstance, error) {
 	var filters []*ec2.Filter
-	for k, v := range c.QueryTags() {
+	for k, v := range tags {
 		filters = append(filters, &ec2.Filter{
 			Name: aws.String("tag:" + k), Values: []*string{aws.String(v)},
 		})
 	}
-	req := &ec2.DescribeInstancesInput{Filters: filters, MaxResults: aws.Int64(1000)}
+	req := &ec2.DescribeInstancesInput{Filters: filters}
+	if len(ids) > 0 {
+		req.InstanceIds = aws.StringSlice(ids)
+	} else {
+		// MaxResults may not be used with InstanceIds.
+		req.MaxResults = aws.Int64(1000)
+	}
 	state := make(map[string]*reflowletInstance)
 	for req != nil {
 		ctx2, cancel := context.WithTimeout(ctx, 30*time.Second)
@@ -709,9 +969,158 @@ func (c *Cluster) getEC2State(ctx context.Context) (map[string]*reflowletInstanc
 	return state, nil
 }
 
+// resolveInstance returns a function which resolves the current
+// reflowlet address (host and port) of the instance with the given
+// ID. The instance's public DNS name (or IP address) may change over
+// its lifetime (e.g., if it is stopped and started).
+func (c *Cluster) resolveInstance(iid string) func(ctx context.Context) (string, error) {
+	return func(ctx context.Context) (string, error) {
+		if err := c.refreshLimiter.Wait(ctx); err != nil {
+			return "", errors.E("refreshLimiter", errors.Temporary, err)
+		}
+		resp, err := c.EC2.DescribeInstancesWithContext(ctx, &ec2.DescribeInstancesInput{
+			InstanceIds: []*string{aws.String(iid)},
+		})
+		if err != nil {
+			return "", errors.E("describeinstances", iid, err)
+		}
+		for _, resv := range resp.Reservations {
+			for _, inst := range resv.Instances {
+				if aws.StringValue(inst.InstanceId) != iid || aws.StringValue(inst.State.Name) != ec2.InstanceStateNameRunning {
+					continue
+				}
+				if host := instanceHost(inst, c.IPAddressing); host != "" {
+					return reflowletAddr(host), nil
+				}
+			}
+		}
+		return "", errors.E("resolve", iid, errors.NotExist, errors.New("no running instance with an address"))
+	}
+}
+
+// AllUsersPool returns a pool comprising the reflowlet instances of
+// all users of this cluster (ie, instances with the same cluster name
+// and reflow version). Unlike the cluster itself, the returned pool
+// is a snapshot of the current set of instances, and does not manage
+// them. It is intended for use by cluster administrators.
+func (c *Cluster) AllUsersPool(ctx context.Context) (pool.Pool, error) {
+	tags := c.QueryTags()
+	// Instance names are also derived from the user.
+	delete(tags, userKey)
+	delete(tags, "Name")
+	state, err := c.describeInstances(ctx, tags)
+	if err != nil {
+		return nil, err
+	}
+	var pools []pool.Pool
+	for _, inst := range state {
+		host := instanceHost(&inst.Instance, c.IPAddressing)
+		if host == "" {
+			continue
+		}
+		baseurl := reflowletURL(host)
+		clnt, err := client.New(baseurl, c.HTTPClient, nil)
+		if err != nil {
+			c.Log.Errorf("client %s: %v", baseurl, err)
+			continue
+		}
+		pools = append(pools, clnt)
+	}
+	m := new(pool.Mux)
+	m.SetPools(pools)
+	return m, nil
+}
+
+// InstancePriceUSD returns the hourly price in USD of an instance of
+// the given type, as launched by the cluster by default (see Spot).
 func (c *Cluster) InstancePriceUSD(typ string) float64 {
+	return c.instancePriceUSD(typ, c.Spot)
+}
+
+// instancePriceUSD returns the hourly price in USD of an instance of
+// the given type: spot instances are priced at the on-demand price,
+// ie, the maximum price bid for them, and on-demand instances at their
+// effective rate, net of any Reserved Instance or Savings Plan
+// coverage (see ReservedCoverage).
+func (c *Cluster) instancePriceUSD(typ string, spot bool) float64 {
 	config := c.instanceConfigs[typ]
-	return config.Price[c.Region()]
+	if c.instanceState == nil {
+		return config.Price[c.Region()]
+	}
+	return c.instanceState.EffectivePrice(config, spot)
+}
+
+// allocInstance returns the EC2 instance of the given alloc, or nil if
+// it is not known.
+func (c *Cluster) allocInstance(alloc pool.Alloc) *ec2.Instance {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, p := range c.pools {
+		if p.pool.ID() == alloc.Pool().ID() {
+			return p.inst
+		}
+	}
+	return nil
+}
+
+// AllocInstanceType returns the instance type of the given alloc's
+// instance, or "" if the instance is not known.
+// It implements sched.InstanceTyper.
+func (c *Cluster) AllocInstanceType(alloc pool.Alloc) string {
+	if inst := c.allocInstance(alloc); inst != nil {
+		return aws.StringValue(inst.InstanceType)
+	}
+	return ""
+}
+
+// AllocHourlyCostUSD returns the hourly cost in USD attributable to
+// the given alloc, ie, the price of its instance prorated by the
+// (dominant) share of the instance's resources held by the alloc.
+// On-demand instances are priced at their effective rate (see
+// ReservedCoverage).
+// It implements sched.Pricer.
+func (c *Cluster) AllocHourlyCostUSD(alloc pool.Alloc) float64 {
+	inst := c.allocInstance(alloc)
+	if inst == nil {
+		return 0
+	}
+	var (
+		typ  = aws.StringValue(inst.InstanceType)
+		spot = aws.StringValue(inst.InstanceLifecycle) == ec2.InstanceLifecycleTypeSpot
+	)
+	var (
+		total = c.instanceConfigs[typ].Resources
+		share float64
+	)
+	for key, v := range alloc.Resources() {
+		if total[key] > 0 && v/total[key] > share {
+			share = v / total[key]
+		}
+	}
+	if share > 1 {
+		share = 1
+	}
+	return c.instancePriceUSD(typ, spot) * share
+}
+
+// AllocFailureDomain returns the failure domain of the given alloc,
+// ie, the availability zone and the family of its instance.
+// It implements sched.Domainer.
+func (c *Cluster) AllocFailureDomain(alloc pool.Alloc) sched.FailureDomain {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, p := range c.pools {
+		if p.pool.ID() != alloc.Pool().ID() {
+			continue
+		}
+		var d sched.FailureDomain
+		if p.inst.Placement != nil {
+			d.Zone = aws.StringValue(p.inst.Placement.AvailabilityZone)
+		}
+		d.Family = strings.SplitN(aws.StringValue(p.inst.InstanceType), ".", 2)[0]
+		return d
+	}
+	return sched.FailureDomain{}
 }
 
 func (c *Cluster) CheapestInstancePriceUSD() float64 {
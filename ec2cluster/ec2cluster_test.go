@@ -448,3 +448,36 @@ func TestInstanceAllocationRequest(t *testing.T) {
 		}
 	}
 }
+
+func TestAvailableOnDemand(t *testing.T) {
+	cluster, err := getEC2ClusterWithRestrictedInstanceTypes()
+	if err != nil {
+		t.Fatal("ec2cluster: ", err)
+	}
+	cluster.Spot = true
+	need := reflow.Resources{"cpu": 2, "mem": 3.3 * float64(data.GiB)}
+	spec, ok := cluster.Available(need, 10, nil)
+	if !ok {
+		t.Fatalf("no instance available for %v", need)
+	}
+	if got := spec.Resources[reflow.OnDemand]; got != 0 {
+		t.Errorf("got %v on-demand units, want 0", got)
+	}
+	need[reflow.OnDemand] = 2
+	if ok, err := cluster.CanAllocate(need); !ok {
+		t.Fatalf("cannot allocate %v: %v", need, err)
+	}
+	spec, ok = cluster.Available(need, 10, nil)
+	if !ok {
+		t.Fatalf("no instance available for %v", need)
+	}
+	if got, want := spec.Type, "c5.large"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := spec.Resources[reflow.OnDemand], spec.Resources["cpu"]; got != want {
+		t.Errorf("got %v on-demand units, want %v", got, want)
+	}
+	if !spec.Resources.Available(need) {
+		t.Errorf("spec %v does not satisfy %v", spec.Resources, need)
+	}
+}
@@ -0,0 +1,280 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package ec2cluster
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/autoscaling/autoscalingiface"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/cloudwatch/cloudwatchiface"
+	"github.com/grailbio/reflow"
+	"github.com/grailbio/reflow/errors"
+	"github.com/grailbio/reflow/log"
+	"github.com/grailbio/reflow/pool"
+)
+
+const (
+	// defaultNeedNamespace is the CloudWatch namespace in which the need
+	// of a cluster joined to an external ASG is published by default.
+	defaultNeedNamespace = "Reflow"
+	// needMetricName is the name of the CloudWatch metric of the need.
+	needMetricName = "Need"
+	// needPublishInterval is the interval at which the need is published.
+	needPublishInterval = time.Minute
+)
+
+// ExternalASGConfig configures a cluster to join an existing Auto
+// Scaling Group whose instances (and their lifecycle) are managed
+// externally, e.g., by the team which owns an account's EC2
+// infrastructure. Such a cluster launches no instances of its own:
+// it allocates from the group's in-service instances, which must run
+// reflowlets of the cluster's reflow version (e.g., bootstrapped by
+// the group's launch template). Instead, the cluster publishes its
+// need (the resources of the allocations it is waiting to satisfy)
+// as the CloudWatch metric "Need", with dimensions
+// AutoScalingGroupName and Resource (e.g., "cpu", "mem" in bytes),
+// so that the group's scaler may react to it.
+type ExternalASGConfig struct {
+	// Tags identifies the group: exactly one Auto Scaling Group must
+	// have all of these tags. The cluster joins an external group only
+	// if Tags is non-empty.
+	Tags map[string]string `yaml:"tags,omitempty"`
+	// MetricNamespace is the CloudWatch namespace in which the need is
+	// published. It defaults to "Reflow".
+	MetricNamespace string `yaml:"metricnamespace,omitempty"`
+}
+
+// Enabled tells whether the cluster joins an external group.
+func (e ExternalASGConfig) Enabled() bool {
+	return len(e.Tags) > 0
+}
+
+// externalASG maintains the state of the external group joined by
+// a cluster, and publishes the cluster's need.
+type externalASG struct {
+	ExternalASGConfig
+	autoscaling autoscalingiface.AutoScalingAPI
+	cloudwatch  cloudwatchiface.CloudWatchAPI
+	log         *log.Logger
+
+	// kick triggers the immediate publication of the need.
+	kick chan struct{}
+
+	mu sync.Mutex
+	// name is the name of the group, as of the last refresh.
+	name string
+	// need is the sum of the resources of pending allocations.
+	need reflow.Resources
+}
+
+func newExternalASG(config ExternalASGConfig, as autoscalingiface.AutoScalingAPI, cw cloudwatchiface.CloudWatchAPI, log *log.Logger) *externalASG {
+	if config.MetricNamespace == "" {
+		config.MetricNamespace = defaultNeedNamespace
+	}
+	return &externalASG{
+		ExternalASGConfig: config,
+		autoscaling:       as,
+		cloudwatch:        cw,
+		log:               log,
+		kick:              make(chan struct{}, 1),
+	}
+}
+
+// instances returns the IDs of the in-service instances of the group.
+func (a *externalASG) instances(ctx context.Context) ([]string, error) {
+	var (
+		groups []*autoscaling.Group
+		req    = &autoscaling.DescribeAutoScalingGroupsInput{}
+	)
+	for req != nil {
+		resp, err := a.autoscaling.DescribeAutoScalingGroupsWithContext(ctx, req)
+		if err != nil {
+			return nil, errors.E("describeautoscalinggroups", err)
+		}
+		for _, g := range resp.AutoScalingGroups {
+			if hasTags(g.Tags, a.Tags) {
+				groups = append(groups, g)
+			}
+		}
+		if resp.NextToken != nil {
+			req.NextToken = resp.NextToken
+		} else {
+			req = nil
+		}
+	}
+	switch len(groups) {
+	case 0:
+		return nil, errors.E(errors.NotExist, errors.Errorf("no auto scaling group with tags %s", formatTags(a.Tags)))
+	case 1:
+	default:
+		var names []string
+		for _, g := range groups {
+			names = append(names, aws.StringValue(g.AutoScalingGroupName))
+		}
+		return nil, errors.Errorf("multiple auto scaling groups with tags %s: %s", formatTags(a.Tags), strings.Join(names, ", "))
+	}
+	a.mu.Lock()
+	a.name = aws.StringValue(groups[0].AutoScalingGroupName)
+	a.mu.Unlock()
+	var ids []string
+	for _, inst := range groups[0].Instances {
+		if aws.StringValue(inst.LifecycleState) == autoscaling.LifecycleStateInService {
+			ids = append(ids, aws.StringValue(inst.InstanceId))
+		}
+	}
+	return ids, nil
+}
+
+// addNeed adds r to the need until the returned function is called.
+func (a *externalASG) addNeed(r reflow.Resources) (done func()) {
+	a.mu.Lock()
+	a.need.Add(a.need, r)
+	a.mu.Unlock()
+	a.publishSoon()
+	return func() {
+		a.mu.Lock()
+		a.need.Sub(a.need, r)
+		a.mu.Unlock()
+		a.publishSoon()
+	}
+}
+
+// Need returns the current need.
+func (a *externalASG) Need() reflow.Resources {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var need reflow.Resources
+	need.Set(a.need)
+	return need
+}
+
+// publishSoon triggers the publication of the need by maintain.
+func (a *externalASG) publishSoon() {
+	select {
+	case a.kick <- struct{}{}:
+	default:
+	}
+}
+
+// publish publishes the current need. The need for cpu, mem and
+// disk is always published (if zero), so that the group's scaler
+// may scale in.
+func (a *externalASG) publish(ctx context.Context) error {
+	a.mu.Lock()
+	name := a.name
+	a.mu.Unlock()
+	if name == "" {
+		return nil
+	}
+	need := a.Need()
+	for _, key := range []string{"cpu", "mem", "disk"} {
+		if _, ok := need[key]; !ok {
+			need[key] = 0
+		}
+	}
+	keys := make([]string, 0, len(need))
+	for key := range need {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	now := time.Now()
+	input := &cloudwatch.PutMetricDataInput{Namespace: aws.String(a.MetricNamespace)}
+	for _, key := range keys {
+		unit := cloudwatch.StandardUnitCount
+		if key == "mem" || key == "disk" {
+			unit = cloudwatch.StandardUnitBytes
+		}
+		input.MetricData = append(input.MetricData, &cloudwatch.MetricDatum{
+			MetricName: aws.String(needMetricName),
+			Dimensions: []*cloudwatch.Dimension{
+				{Name: aws.String("AutoScalingGroupName"), Value: aws.String(name)},
+				{Name: aws.String("Resource"), Value: aws.String(key)},
+			},
+			Timestamp: aws.Time(now),
+			Unit:      aws.String(unit),
+			Value:     aws.Float64(need[key]),
+		})
+	}
+	_, err := a.cloudwatch.PutMetricDataWithContext(ctx, input)
+	return err
+}
+
+// maintain publishes the need periodically, and whenever it changes,
+// until the provided context is done.
+func (a *externalASG) maintain(ctx context.Context) {
+	tick := time.NewTicker(needPublishInterval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-tick.C:
+		case <-a.kick:
+		case <-ctx.Done():
+			return
+		}
+		if err := a.publish(ctx); err != nil {
+			a.log.Errorf("publish need: %v", err)
+		}
+	}
+}
+
+// allocateExternal allocates from the instances of the cluster's
+// external group. While the allocation cannot be satisfied, its
+// requirements are added to the cluster's need, so that the group's
+// scaler may add capacity.
+func (c *Cluster) allocateExternal(ctx context.Context, req reflow.Requirements, labels pool.Labels) (pool.Alloc, error) {
+	width := req.Width
+	if width == 0 {
+		width = 1
+	}
+	var need reflow.Resources
+	need.Scale(req.Min, float64(width))
+	done := c.asg.addNeed(need)
+	defer done()
+	ticker := time.NewTicker(allocAttemptInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+		actx, acancel := context.WithTimeout(ctx, 30*time.Second)
+		alloc, err := pool.Allocate(actx, c, req, labels)
+		acancel()
+		if err == nil {
+			return alloc, nil
+		}
+		c.Log.Debugf("waiting for capacity in external auto scaling group: %v", err)
+	}
+}
+
+// hasTags tells whether the given group tags include all of tags.
+func hasTags(groupTags []*autoscaling.TagDescription, tags map[string]string) bool {
+	n := 0
+	for _, tag := range groupTags {
+		if v, ok := tags[aws.StringValue(tag.Key)]; ok && v == aws.StringValue(tag.Value) {
+			n++
+		}
+	}
+	return n == len(tags)
+}
+
+// formatTags formats tags as a sorted list of key=value pairs.
+func formatTags(tags map[string]string) string {
+	pairs := make([]string, 0, len(tags))
+	for k, v := range tags {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", k, v))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
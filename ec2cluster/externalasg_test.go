@@ -0,0 +1,153 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package ec2cluster
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/autoscaling/autoscalingiface"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/cloudwatch/cloudwatchiface"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/grailbio/reflow"
+	"github.com/grailbio/reflow/errors"
+	"github.com/grailbio/reflow/log"
+	"golang.org/x/time/rate"
+)
+
+type mockAutoScalingClient struct {
+	autoscalingiface.AutoScalingAPI
+	groups []*autoscaling.Group
+}
+
+func (m *mockAutoScalingClient) DescribeAutoScalingGroupsWithContext(ctx aws.Context, input *autoscaling.DescribeAutoScalingGroupsInput, _ ...request.Option) (*autoscaling.DescribeAutoScalingGroupsOutput, error) {
+	return &autoscaling.DescribeAutoScalingGroupsOutput{AutoScalingGroups: m.groups}, nil
+}
+
+type mockCloudWatchClient struct {
+	cloudwatchiface.CloudWatchAPI
+	inputs []*cloudwatch.PutMetricDataInput
+}
+
+func (m *mockCloudWatchClient) PutMetricDataWithContext(ctx aws.Context, input *cloudwatch.PutMetricDataInput, _ ...request.Option) (*cloudwatch.PutMetricDataOutput, error) {
+	m.inputs = append(m.inputs, input)
+	return &cloudwatch.PutMetricDataOutput{}, nil
+}
+
+func newGroup(name string, tags map[string]string, instances map[string]string) *autoscaling.Group {
+	g := &autoscaling.Group{AutoScalingGroupName: aws.String(name)}
+	for k, v := range tags {
+		g.Tags = append(g.Tags, &autoscaling.TagDescription{Key: aws.String(k), Value: aws.String(v)})
+	}
+	for id, state := range instances {
+		g.Instances = append(g.Instances, &autoscaling.Instance{InstanceId: aws.String(id), LifecycleState: aws.String(state)})
+	}
+	return g
+}
+
+func TestExternalASGInstances(t *testing.T) {
+	as := &mockAutoScalingClient{groups: []*autoscaling.Group{
+		newGroup("other", map[string]string{"team": "infra"}, map[string]string{"i-other": autoscaling.LifecycleStateInService}),
+		newGroup("reflow", map[string]string{"team": "infra", "purpose": "reflow"}, map[string]string{
+			"i-run":     autoscaling.LifecycleStateInService,
+			"i-pending": autoscaling.LifecycleStatePending,
+		}),
+	}}
+	ctx := context.Background()
+	asg := newExternalASG(ExternalASGConfig{Tags: map[string]string{"purpose": "reflow"}}, as, nil, log.Std)
+	ids, err := asg.instances(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := ids, []string{"i-run"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := asg.name, "reflow"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	asg = newExternalASG(ExternalASGConfig{Tags: map[string]string{"team": "infra"}}, as, nil, log.Std)
+	if _, err := asg.instances(ctx); err == nil {
+		t.Error("expected error")
+	}
+	asg = newExternalASG(ExternalASGConfig{Tags: map[string]string{"team": "other"}}, as, nil, log.Std)
+	if _, err := asg.instances(ctx); !errors.Is(errors.NotExist, err) {
+		t.Errorf("got %v, want NotExist", err)
+	}
+}
+
+func TestExternalASGState(t *testing.T) {
+	as := &mockAutoScalingClient{groups: []*autoscaling.Group{
+		newGroup("reflow", map[string]string{"purpose": "reflow"}, map[string]string{"i-run": autoscaling.LifecycleStateInService}),
+	}}
+	i, ri := create("i-run", "running", "v1", "")
+	client := &mockEC2Client{descInstOut: &ec2.DescribeInstancesOutput{Reservations: []*ec2.Reservation{{Instances: []*ec2.Instance{i}}}}}
+	c := &Cluster{EC2: client, ReflowVersion: "v1"}
+	c.asg = newExternalASG(ExternalASGConfig{Tags: map[string]string{"purpose": "reflow"}}, as, nil, log.Std)
+	c.refreshLimiter = rate.NewLimiter(rate.Every(time.Millisecond), 1)
+	state, err := c.getEC2State(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := state, map[string]*reflowletInstance{"i-run": ri}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// Groups without in-service instances contribute no capacity.
+	as.groups[0].Instances = nil
+	if state, err = c.getEC2State(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(state), 0; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestExternalASGNeed(t *testing.T) {
+	as := &mockAutoScalingClient{groups: []*autoscaling.Group{
+		newGroup("reflow", map[string]string{"purpose": "reflow"}, nil),
+	}}
+	cw := new(mockCloudWatchClient)
+	ctx := context.Background()
+	asg := newExternalASG(ExternalASGConfig{Tags: map[string]string{"purpose": "reflow"}}, as, cw, log.Std)
+	if _, err := asg.instances(ctx); err != nil {
+		t.Fatal(err)
+	}
+	done1 := asg.addNeed(reflow.Resources{"cpu": 4, "mem": 8 << 30})
+	done2 := asg.addNeed(reflow.Resources{"cpu": 2, "mem": 4 << 30, "gpu": 1})
+	if got, want := asg.Need(), (reflow.Resources{"cpu": 6, "mem": 12 << 30, "gpu": 1}); !got.Equal(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if err := asg.publish(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(cw.inputs), 1; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got, want := aws.StringValue(cw.inputs[0].Namespace), defaultNeedNamespace; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	published := make(map[string]float64)
+	for _, d := range cw.inputs[0].MetricData {
+		if got, want := aws.StringValue(d.Dimensions[0].Value), "reflow"; got != want {
+			t.Errorf("got %v, want %v", got, want)
+		}
+		published[aws.StringValue(d.Dimensions[1].Value)] = aws.Float64Value(d.Value)
+	}
+	if got, want := published, map[string]float64{"cpu": 6, "mem": 12 << 30, "disk": 0, "gpu": 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	done1()
+	done2()
+	if got, want := asg.Need(), (reflow.Resources{"cpu": 0, "mem": 0, "gpu": 0}); !got.Equal(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
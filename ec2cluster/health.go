@@ -0,0 +1,205 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package ec2cluster
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/grailbio/reflow/errors"
+	"github.com/grailbio/reflow/log"
+)
+
+const (
+	// healthCheckInterval is the interval at which the health of
+	// cluster instances is checked.
+	healthCheckInterval = time.Minute
+	// healthCheckTimeout is the timeout for a single reflowlet health check.
+	healthCheckTimeout = 30 * time.Second
+	// healthFailureThreshold is the number of consecutive failed health
+	// checks after which an instance is remediated.
+	healthFailureThreshold = 3
+	// rebootGracePeriod is the time allotted to a rebooted instance to
+	// become healthy again before it is replaced.
+	rebootGracePeriod = 10 * time.Minute
+	// maxStatusIds is the maximum number of instance IDs per
+	// DescribeInstanceStatus call.
+	maxStatusIds = 100
+)
+
+// instanceHealth is the health state of an unhealthy instance.
+type instanceHealth struct {
+	// failures is the number of consecutive failed health checks.
+	failures int
+	// rebooted is the time at which the instance was rebooted by the
+	// healer, if it was.
+	rebooted time.Time
+}
+
+// healer remediates unhealthy instances. An instance is unhealthy if
+// EC2 status checks report it (or its underlying system) as impaired,
+// or if its reflowlet fails health checks. Instances which are
+// unhealthy for healthFailureThreshold consecutive checks are first
+// rebooted and, if they remain unhealthy past rebootGracePeriod,
+// terminated; the cluster manager launches replacements as needed.
+// Each action and its outcome is recorded in the audit log.
+type healer struct {
+	ec2 ec2iface.EC2API
+	log *log.Logger
+
+	health map[string]*instanceHealth
+}
+
+func newHealer(api ec2iface.EC2API, log *log.Logger) *healer {
+	return &healer{ec2: api, log: log, health: make(map[string]*instanceHealth)}
+}
+
+// check checks the health of the given instances, keyed by instance
+// ID, and remediates unhealthy ones. Instances launched less than
+// instanceLaunchTimeout ago are not checked, since their reflowlets
+// may not yet be running.
+func (h *healer) check(ctx context.Context, instances map[string]reflowletPool) {
+	for id := range h.health {
+		if _, ok := instances[id]; !ok {
+			delete(h.health, id)
+		}
+	}
+	var ids []string
+	for id, p := range instances {
+		if launched := aws.TimeValue(p.inst.LaunchTime); !launched.IsZero() && time.Since(launched) < instanceLaunchTimeout {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	impaired, err := h.impaired(ctx, ids)
+	if err != nil {
+		h.log.Errorf("health check: %v", err)
+	}
+	for _, id := range ids {
+		reason := impaired[id]
+		if reason == "" {
+			pctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+			_, err := instances[id].pool.Offers(pctx)
+			cancel()
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				reason = "reflowlet health check failed: " + err.Error()
+			}
+		}
+		health := h.health[id]
+		if reason == "" {
+			if health != nil && !health.rebooted.IsZero() {
+				h.log.Printf("audit: instance %s: recovered after reboot", id)
+			}
+			delete(h.health, id)
+			continue
+		}
+		if health == nil {
+			health = new(instanceHealth)
+			h.health[id] = health
+		}
+		health.failures++
+		h.log.Debugf("instance %s unhealthy (%d/%d): %s", id, health.failures, healthFailureThreshold, reason)
+		if health.failures < healthFailureThreshold {
+			continue
+		}
+		switch {
+		case health.rebooted.IsZero():
+			_, err := h.ec2.RebootInstancesWithContext(ctx, &ec2.RebootInstancesInput{
+				InstanceIds: []*string{aws.String(id)},
+			})
+			h.audit(id, "reboot", reason, err)
+			if err == nil {
+				health.rebooted = time.Now()
+				health.failures = 0
+			}
+		case time.Since(health.rebooted) >= rebootGracePeriod:
+			_, err := h.ec2.TerminateInstancesWithContext(ctx, &ec2.TerminateInstancesInput{
+				InstanceIds: []*string{aws.String(id)},
+			})
+			h.audit(id, "replace", reason, err)
+			if err == nil {
+				delete(h.health, id)
+			}
+		}
+	}
+}
+
+// impaired returns the instances, among the given ones, whose EC2
+// status checks report impairment, along with the reason.
+func (h *healer) impaired(ctx context.Context, ids []string) (map[string]string, error) {
+	impaired := make(map[string]string)
+	for len(ids) > 0 {
+		batch := ids
+		if len(batch) > maxStatusIds {
+			batch = batch[:maxStatusIds]
+		}
+		ids = ids[len(batch):]
+		req := &ec2.DescribeInstanceStatusInput{InstanceIds: aws.StringSlice(batch)}
+		for {
+			resp, err := h.ec2.DescribeInstanceStatusWithContext(ctx, req)
+			if err != nil {
+				return impaired, errors.E("describeinstancestatus", err)
+			}
+			for _, s := range resp.InstanceStatuses {
+				var reasons []string
+				if s.SystemStatus != nil && aws.StringValue(s.SystemStatus.Status) == ec2.SummaryStatusImpaired {
+					reasons = append(reasons, "system status check impaired")
+				}
+				if s.InstanceStatus != nil && aws.StringValue(s.InstanceStatus.Status) == ec2.SummaryStatusImpaired {
+					reasons = append(reasons, "instance status check impaired")
+				}
+				if len(reasons) > 0 {
+					impaired[aws.StringValue(s.InstanceId)] = strings.Join(reasons, ", ")
+				}
+			}
+			if resp.NextToken == nil {
+				break
+			}
+			req.NextToken = resp.NextToken
+		}
+	}
+	return impaired, nil
+}
+
+// audit records a remediation action taken on an instance, along
+// with its reason and outcome.
+func (h *healer) audit(id, action, reason string, err error) {
+	outcome := "succeeded"
+	if err != nil {
+		outcome = "failed: " + err.Error()
+	}
+	h.log.Printf("audit: instance %s: %s (%s): %s", id, action, reason, outcome)
+}
+
+// heal periodically checks the health of the cluster's instances and
+// remediates unhealthy ones, until the context is done.
+func (c *Cluster) heal(ctx context.Context) {
+	h := newHealer(c.EC2, c.Log)
+	ticker := time.NewTicker(healthCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		c.mu.Lock()
+		instances := make(map[string]reflowletPool, len(c.pools))
+		for id, p := range c.pools {
+			instances[id] = p
+		}
+		c.mu.Unlock()
+		h.check(ctx, instances)
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package ec2cluster
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	rlog "github.com/grailbio/reflow/log"
+	"github.com/grailbio/reflow/pool"
+)
+
+type mockHealthClient struct {
+	ec2iface.EC2API
+	impaired             map[string]bool
+	rebooted, terminated []string
+}
+
+func (e *mockHealthClient) DescribeInstanceStatusWithContext(ctx aws.Context, input *ec2.DescribeInstanceStatusInput, _ ...request.Option) (*ec2.DescribeInstanceStatusOutput, error) {
+	var out ec2.DescribeInstanceStatusOutput
+	for _, id := range aws.StringValueSlice(input.InstanceIds) {
+		status := ec2.SummaryStatusOk
+		if e.impaired[id] {
+			status = ec2.SummaryStatusImpaired
+		}
+		out.InstanceStatuses = append(out.InstanceStatuses, &ec2.InstanceStatus{
+			InstanceId:     aws.String(id),
+			InstanceStatus: &ec2.InstanceStatusSummary{Status: aws.String(status)},
+			SystemStatus:   &ec2.InstanceStatusSummary{Status: aws.String(ec2.SummaryStatusOk)},
+		})
+	}
+	return &out, nil
+}
+
+func (e *mockHealthClient) RebootInstancesWithContext(ctx aws.Context, input *ec2.RebootInstancesInput, _ ...request.Option) (*ec2.RebootInstancesOutput, error) {
+	e.rebooted = append(e.rebooted, aws.StringValueSlice(input.InstanceIds)...)
+	return &ec2.RebootInstancesOutput{}, nil
+}
+
+func (e *mockHealthClient) TerminateInstancesWithContext(ctx aws.Context, input *ec2.TerminateInstancesInput, _ ...request.Option) (*ec2.TerminateInstancesOutput, error) {
+	e.terminated = append(e.terminated, aws.StringValueSlice(input.InstanceIds)...)
+	return &ec2.TerminateInstancesOutput{}, nil
+}
+
+type healthPool struct {
+	pool.Pool
+	err error
+}
+
+func (p *healthPool) Offers(ctx context.Context) ([]pool.Offer, error) {
+	return nil, p.err
+}
+
+func TestHealer(t *testing.T) {
+	var (
+		ctx    = context.Background()
+		client = &mockHealthClient{impaired: map[string]bool{"i-impaired": true}}
+		logger = rlog.New(log.New(os.Stderr, "", log.LstdFlags), rlog.DebugLevel)
+		h      = newHealer(client, logger)
+		old    = aws.Time(time.Now().Add(-time.Hour))
+	)
+	newPool := func(launched *time.Time, err error) reflowletPool {
+		return reflowletPool{newReflowletInstance(&ec2.Instance{LaunchTime: launched}), &healthPool{err: err}}
+	}
+	instances := map[string]reflowletPool{
+		"i-healthy":  newPool(old, nil),
+		"i-impaired": newPool(old, nil),
+		"i-down":     newPool(old, errors.New("connection refused")),
+		"i-new":      newPool(aws.Time(time.Now()), errors.New("connection refused")),
+	}
+	for i := 0; i < healthFailureThreshold-1; i++ {
+		h.check(ctx, instances)
+	}
+	if got := len(client.rebooted); got != 0 {
+		t.Fatalf("got %d reboots before threshold, want 0", got)
+	}
+	h.check(ctx, instances)
+	if got, want := client.rebooted, []string{"i-down", "i-impaired"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got rebooted %v, want %v", got, want)
+	}
+	// The instance recovers after reboot.
+	client.impaired = nil
+	// Still unhealthy, but within the grace period.
+	for i := 0; i < healthFailureThreshold; i++ {
+		h.check(ctx, instances)
+	}
+	if _, ok := h.health["i-impaired"]; ok {
+		t.Error("recovered instance still tracked")
+	}
+	if got := len(client.terminated); got != 0 {
+		t.Fatalf("got %d terminations within grace period, want 0", got)
+	}
+	h.health["i-down"].rebooted = time.Now().Add(-rebootGracePeriod)
+	h.check(ctx, instances)
+	if got, want := client.terminated, []string{"i-down"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got terminated %v, want %v", got, want)
+	}
+	if got, want := client.rebooted, []string{"i-down", "i-impaired"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got rebooted %v, want %v", got, want)
+	}
+}
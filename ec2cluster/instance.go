@@ -107,6 +107,8 @@ type instanceConfig struct {
 	SpotOk bool
 	// NVMe specifies whether EBS is exposed as NVMe devices.
 	NVMe bool
+	// Arch is the CPU architecture of this instance type ("x86_64" or "arm64").
+	Arch string
 }
 
 var (
@@ -117,6 +119,14 @@ var (
 	reflowletFile reflow.File
 )
 
+// dockerSnapshotLabel is the filesystem label of the volume, created from
+// an instance's DockerSnapshot, which holds a pre-seeded docker data root.
+const dockerSnapshotLabel = "reflow-docker"
+
+// dockerDataDir is the directory on the EBS data volume which holds the
+// docker data root of instances with a DockerDataVolume.
+const dockerDataDir = "/mnt/data/docker"
+
 // instance represents a concrete instance; it is launched from an instanceConfig
 // and additional parameters.
 type instance struct {
@@ -132,17 +142,24 @@ type instance struct {
 	Spot                    bool
 	InstanceProfile         string
 	SecurityGroup           string
+	IPAddressing            string
 	Region                  string
 	BootstrapImage          string
 	BootstrapExpiry         time.Duration
+	ReflowletImage          string
 	Price                   float64
 	EBSType                 string
 	EBSSize                 uint64
 	NEBS                    int
 	AMI                     string
+	DockerSnapshot          string
+	DockerDataVolume        bool
+	DockerCacheSeed         string
 	KeyName                 string
 	SshKeys                 []string
 	Immortal                bool
+	LogFormat               string
+	Logs                    string
 	NodeExporterMetricsPort int
 	CloudConfig             cloudConfig
 	ReflowVersion           string
@@ -315,14 +332,17 @@ func (i *instance) Go(ctx context.Context) {
 			_, i.err = i.EC2.CreateTags(&ec2.CreateTagsInput{Resources: resources, Tags: i.getTags()})
 		case stateDescribeDns:
 			i.print(id, state.String())
-			if dnsPtr := i.ec2inst.PublicDnsName; dnsPtr == nil || aws.StringValue(dnsPtr) == "" {
+			if instanceHost(i.ec2inst, i.IPAddressing) == "" {
 				i.ec2inst, i.err = describeInstance(ctx, i.DescInstLimiter, id, i.Log)
 			}
 			if i.err != nil {
 				i.err = errors.E(state.String(), errors.Temporary, i.err)
 				break
 			}
-			dns = aws.StringValue(i.ec2inst.PublicDnsName)
+			if dns = instanceHost(i.ec2inst, i.IPAddressing); dns == "" {
+				i.err = errors.E(state.String(), errors.Temporary, errors.New("instance has no address"))
+				break
+			}
 			spot := ""
 			if i.Spot {
 				spot = "spot "
@@ -358,7 +378,7 @@ func (i *instance) Go(ctx context.Context) {
 			}
 			i.print(id, state.String())
 			var c *bootc.Client
-			c, i.err = bootc.New(fmt.Sprintf("https://%s:9000/v1/", dns), i.HTTPClient, nil)
+			c, i.err = bootc.New(reflowletURL(dns), i.HTTPClient, nil)
 			if i.err != nil {
 				i.err = errors.E(errors.Fatal, i.err)
 				break
@@ -371,28 +391,34 @@ func (i *instance) Go(ctx context.Context) {
 			}
 		case stateInstallImage:
 			i.print(id, state.String())
-			clnt, err := bootc.New(fmt.Sprintf("https://%s:9000/v1/", *i.ec2inst.PublicDnsName), i.HTTPClient, nil)
-			if err != nil {
-				i.err = errors.E(errors.Fatal, err)
-				break
-			}
-			var repo reflow.Repository
-			err = i.ReflowConfig.Instance(&repo)
+			clnt, err := bootc.New(reflowletURL(dns), i.HTTPClient, nil)
 			if err != nil {
 				i.err = errors.E(errors.Fatal, err)
 				break
 			}
-			ctx2, cancel := context.WithTimeout(ctx, 5*time.Minute)
-			err = getReflowletFile(ctx2, repo, i.Log)
-			cancel()
-			if err != nil {
-				i.err = errors.E(errors.Fatal, err)
-				break
+			// Instances of a different architecture than the driver's embedded
+			// image are configured with an explicit reflowlet image.
+			reflowletPath := i.ReflowletImage
+			if reflowletPath == "" {
+				var repo reflow.Repository
+				err = i.ReflowConfig.Instance(&repo)
+				if err != nil {
+					i.err = errors.E(errors.Fatal, err)
+					break
+				}
+				ctx2, cancel := context.WithTimeout(ctx, 5*time.Minute)
+				err = getReflowletFile(ctx2, repo, i.Log)
+				cancel()
+				if err != nil {
+					i.err = errors.E(errors.Fatal, err)
+					break
+				}
+				reflowletPath = reflowletFile.Source
 			}
-			ctx2, cancel = context.WithTimeout(ctx, 1*time.Minute)
+			ctx2, cancel := context.WithTimeout(ctx, 1*time.Minute)
 			reflowletimage := common.Image{
-				Path: reflowletFile.Source,
-				Args: reflowletArgs,
+				Path: reflowletPath,
+				Args: i.reflowletArgs(),
 				Name: "reflowlet",
 			}
 			i.Log.Debugf("installing reflowlet image %v", reflowletimage)
@@ -422,7 +448,7 @@ func (i *instance) Go(ctx context.Context) {
 			}
 			i.print(id, state.String())
 			var c *poolc.Client
-			c, i.err = poolc.New(fmt.Sprintf("https://%s:9000/v1/", dns), i.HTTPClient, nil)
+			c, i.err = poolc.New(reflowletURL(dns), i.HTTPClient, nil)
 			if i.err != nil {
 				i.err = errors.E(errors.Fatal, i.err)
 				break
@@ -604,6 +630,10 @@ func (i *instance) bootstrapExpiryArgs() []string {
 
 const ReflowletCloudwatchFlushMs = 5000
 
+// logsCloudWatch is the (default) Cluster.Logs destination which ships
+// logs to CloudWatch Logs.
+const logsCloudWatch = "cloudwatch"
+
 func (i *instance) launch(ctx context.Context) (string, error) {
 	// First we need to construct the cloud-config that's passed to
 	// our instances via EC2's user-data mechanism.
@@ -640,6 +670,11 @@ func (i *instance) launch(ctx context.Context) (string, error) {
 	}
 	// The remote side does not need a cluster implementation.
 	delete(keys, infra2.Cluster)
+	// The configuration is readable by anyone permitted to describe the
+	// instance's attributes, so registry passwords must be secrets.
+	if _, err = ecrauth.ParseRegistries(keys["registries"], nil); err != nil {
+		return "", err
+	}
 	// If prometheus metrics are enabled, but a port for metrics is not set,
 	// then set a default port for reflowlet metrics.
 	if p := keys[infra2.Metrics]; p != nil {
@@ -711,18 +746,64 @@ func (i *instance) launch(ctx context.Context) (string, error) {
 	if i.NEBS < 1 {
 		i.NEBS = 1
 	}
-	devices := make([]string, i.NEBS)
-	for idx := range devices {
-		if i.Config.NVMe {
-			devices[idx] = fmt.Sprintf("nvme%dn1", idx+1)
-		} else {
+	if i.Config.NVMe {
+		// NVMe device names are assigned in no particular order, and may
+		// also refer to instance store or to the docker snapshot volume.
+		// The EBS data volumes are instead identified by their NVMe serial
+		// numbers, which are their volume IDs.
+		c.AppendFile(CloudFile{
+			Path:        "/opt/bin/ebs-data-devices",
+			Permissions: "0755",
+			Owner:       "root",
+			Content: tmpl(`
+			#!/bin/bash
+			# Prints the {{.n}} EBS data devices of this instance once they are attached.
+			for try in $(seq 1 60); do
+				devices=()
+				for dev in /sys/block/nvme*n1; do
+					name=$(basename $dev)
+					# Skip instance store volumes, whose serials are not volume IDs.
+					[[ $(cat $dev/device/serial) == vol* ]] || continue
+					# Skip the (partitioned) root volume.
+					ls $dev | grep -q "^${name}p" && continue
+					# Skip the docker snapshot volume.
+					[[ $(blkid -s LABEL -o value /dev/$name) == {{.label}} ]] && continue
+					devices+=(/dev/$name)
+				done
+				if [[ ${#devices[@]} -eq {{.n}} ]]; then
+					echo ${devices[@]}
+					exit 0
+				fi
+				sleep 5
+			done
+			echo "found ${#devices[@]} EBS data devices, want {{.n}}" >&2
+			exit 1
+			`, args{"n": i.NEBS, "label": dockerSnapshotLabel}),
+		})
+		c.AppendUnit(CloudUnit{
+			Name:    fmt.Sprintf("format-%s.service", lvmGroupName),
+			Command: "start",
+			Content: tmpl(`
+			[Unit]
+			Description=Format /dev/{{.name}}_group/{{.name}}_vol (after setting up LVM RAID0)
+			[Service]
+			Type=oneshot
+			RemainAfterExit=yes
+			ExecStartPre=/bin/bash -c '/usr/sbin/pvcreate $(/opt/bin/ebs-data-devices)'
+			ExecStartPre=/bin/bash -c '/usr/sbin/vgcreate {{.name}}_group $(/opt/bin/ebs-data-devices)'
+			ExecStartPre=/usr/sbin/lvcreate -l 100%%VG --stripes {{.n}} --stripesize 256 -n {{.name}}_vol {{.name}}_group
+			ExecStart=-/usr/sbin/mkfs.ext4 /dev/{{.name}}_group/{{.name}}_vol
+		`, args{"n": i.NEBS, "name": lvmGroupName}),
+		})
+	} else {
+		devices := make([]string, i.NEBS)
+		for idx := range devices {
 			devices[idx] = fmt.Sprintf("xvd%c", 'b'+idx)
 		}
-	}
-	c.AppendUnit(CloudUnit{
-		Name:    fmt.Sprintf("format-%s.service", lvmGroupName),
-		Command: "start",
-		Content: tmpl(`
+		c.AppendUnit(CloudUnit{
+			Name:    fmt.Sprintf("format-%s.service", lvmGroupName),
+			Command: "start",
+			Content: tmpl(`
 			[Unit]
 			Description=Format /dev/{{.name}}_group/{{.name}}_vol (after setting up LVM RAID0)
 			After={{range $_, $name :=  .devices}}dev-{{$name}}.device {{end}}
@@ -735,7 +816,8 @@ func (i *instance) launch(ctx context.Context) (string, error) {
 			ExecStartPre=/usr/sbin/lvcreate -l 100%%VG --stripes {{.devices|len}} --stripesize 256 -n {{.name}}_vol {{.name}}_group
 			ExecStart=-/usr/sbin/mkfs.ext4 /dev/{{.name}}_group/{{.name}}_vol
 		`, args{"devices": devices, "name": lvmGroupName}),
-	})
+		})
+	}
 	c.AppendUnit(CloudUnit{
 		Name:    "mnt-data.mount",
 		Command: "start",
@@ -754,38 +836,66 @@ func (i *instance) launch(ctx context.Context) (string, error) {
 		`, args{"mortal": !i.Immortal, "name": deviceName}),
 	})
 
-	c.AppendFile(CloudFile{
-		Path:        "/etc/journald-cloudwatch-logs.conf",
-		Permissions: "0644",
-		Owner:       "root",
-		Content: tmpl(`log_group = "reflow/reflowlet"
-fields = ["_HOSTNAME", "PRIORITY", "MESSAGE"]
-queue_poll_duration_ms = 1000
-queue_flush_log_ms = {{.flush_log_ms}}
-field_length = 1024
-`, args{"flush_log_ms": ReflowletCloudwatchFlushMs}),
-	})
+	if i.DockerSnapshot != "" {
+		// Mount the pre-seeded docker layer cache before docker starts.
+		// The volume is identified by its filesystem label since device
+		// naming differs across instance types (and, for NVMe devices, is
+		// not ordered by the block device mappings). If the mount fails, docker
+		// falls back to an empty cache on the root device.
+		c.AppendUnit(CloudUnit{
+			Name:    "var-lib-docker.mount",
+			Command: "start",
+			Content: tmpl(`
+			[Unit]
+			Description=docker layer cache on path /var/lib/docker
+			Before=docker.service containerd.service
+			[Mount]
+			What=/dev/disk/by-label/{{.label}}
+			Where=/var/lib/docker
+			Type=ext4
+		`, args{"label": dockerSnapshotLabel}),
+		})
+	}
 
-	c.AppendUnit(CloudUnit{
-		Name:    "journald-cloudwatch-logs.service",
-		Enable:  true,
-		Command: "start",
-		Content: tmpl(`
-		[Unit]
-		Description=journald-cloudwatch-logs
-		Wants=basic.target
-		After=basic.target network.target
-		[Service]
-		LogLevelMax=5
-		Type=simple
-		ExecStartPre=/usr/bin/sh -c "/usr/bin/echo 'log_stream = \"'$(curl http://169.254.169.254/latest/meta-data/public-hostname)'\"' | /usr/bin/cat - /etc/journald-cloudwatch-logs.conf > /tmp/journald-cloudwatch-logs.conf"
-		ExecStartPre=/usr/bin/wget https://github.com/advantageous/systemd-cloud-watch/releases/download/v0.2.1/systemd-cloud-watch_linux -O /tmp/systemd-cloud-watch_linux
-		ExecStartPre=/usr/bin/chmod +x /tmp/systemd-cloud-watch_linux
-		ExecStart=/tmp/systemd-cloud-watch_linux /tmp/journald-cloudwatch-logs.conf
-		Restart=on-failure
-		RestartSec=30s
-		`, args{}),
-	})
+	if i.DockerDataVolume {
+		// Bind the docker data root on the data volume before docker
+		// starts, so that the layer cache is not limited by the size
+		// of the root device.
+		c.AppendUnit(CloudUnit{
+			Name:    "docker-data.service",
+			Command: "start",
+			Content: tmpl(`
+			[Unit]
+			Description=docker data root on path {{.dir}}
+			Requires=mnt-data.mount
+			After=mnt-data.mount
+			[Service]
+			Type=oneshot
+			RemainAfterExit=yes
+			ExecStart=/bin/mkdir -p {{.dir}}
+		`, args{"dir": dockerDataDir}),
+		})
+		c.AppendUnit(CloudUnit{
+			Name:    "var-lib-docker.mount",
+			Command: "start",
+			Content: tmpl(`
+			[Unit]
+			Description=docker layer cache on path /var/lib/docker
+			Requires=docker-data.service
+			After=docker-data.service
+			Before=docker.service containerd.service
+			[Mount]
+			What={{.dir}}
+			Where=/var/lib/docker
+			Type=none
+			Options=bind
+		`, args{"dir": dockerDataDir}),
+		})
+	}
+
+	if !i.shipsLogsToBlob() {
+		i.appendCloudWatchLogs(&c)
+	}
 
 	var profile, akey, secret, token string
 	if i.InstanceProfile != "" {
@@ -975,14 +1085,20 @@ func (i *instance) ec2RunSpotInstance(ctx context.Context, az string) (string, e
 			SecurityGroupIds: []*string{aws.String(i.SecurityGroup)},
 		},
 	}
+	var subnet string
 	if az != "" {
 		// Use an availability zone only if specified.
 		params.LaunchSpecification.Placement = &ec2.SpotPlacement{AvailabilityZone: aws.String(az)}
 		// And if an availability zone is specified, determine if a specific subnet is known for it.
-		if subnet := subnetForAZ(az); subnet != "" {
+		if subnet = subnetForAZ(az); subnet != "" {
 			params.LaunchSpecification.SubnetId = aws.String(subnet)
 		}
 	}
+	if nis := networkInterfaces(i.IPAddressing, i.SecurityGroup, subnet); nis != nil {
+		params.LaunchSpecification.NetworkInterfaces = nis
+		params.LaunchSpecification.SecurityGroupIds = nil
+		params.LaunchSpecification.SubnetId = nil
+	}
 	var (
 		policy = retry.MaxRetries(retry.Jitter(retry.Backoff(5*time.Second, 10*time.Second, 1.2), 0.2), spotReqRetryLim)
 		resp   *ec2.RequestSpotInstancesOutput
@@ -1136,6 +1252,69 @@ func ec2HasCapacity(ctx context.Context, api ec2iface.EC2API, imageId, instanceT
 	return false, fmt.Errorf("spot probing %s (depth=%d) exhausted retries", instanceType, n)
 }
 
+// reflowletArgs returns the arguments passed to the reflow binary to
+// run the instance's reflowlet.
+func (i *instance) reflowletArgs() []string {
+	if i.LogFormat == "" && !i.shipsLogsToBlob() && i.DockerCacheSeed == "" {
+		return reflowletArgs
+	}
+	args := append([]string{}, commonArgs...)
+	if i.LogFormat != "" {
+		args = append(args, "-logformat", i.LogFormat)
+	}
+	args = append(args, "serve", "-ec2cluster")
+	if i.shipsLogsToBlob() {
+		args = append(args, "-logs", i.Logs)
+	}
+	if i.DockerCacheSeed != "" {
+		args = append(args, "-dockercacheseed", i.DockerCacheSeed)
+	}
+	return args
+}
+
+// appendCloudWatchLogs appends to the cloud config c the units which
+// ship the reflowlet's logs (from journald) to CloudWatch Logs.
+func (i *instance) appendCloudWatchLogs(c *cloudConfig) {
+	c.AppendFile(CloudFile{
+		Path:        "/etc/journald-cloudwatch-logs.conf",
+		Permissions: "0644",
+		Owner:       "root",
+		Content: tmpl(`log_group = "reflow/reflowlet"
+fields = ["_HOSTNAME", "PRIORITY", "MESSAGE"]
+queue_poll_duration_ms = 1000
+queue_flush_log_ms = {{.flush_log_ms}}
+field_length = 1024
+`, args{"flush_log_ms": ReflowletCloudwatchFlushMs}),
+	})
+
+	c.AppendUnit(CloudUnit{
+		Name:    "journald-cloudwatch-logs.service",
+		Enable:  true,
+		Command: "start",
+		Content: tmpl(`
+		[Unit]
+		Description=journald-cloudwatch-logs
+		Wants=basic.target
+		After=basic.target network.target
+		[Service]
+		LogLevelMax=5
+		Type=simple
+		ExecStartPre=/usr/bin/sh -c "/usr/bin/echo 'log_stream = \"'$(curl -sf http://169.254.169.254/latest/meta-data/public-hostname || curl -s http://169.254.169.254/latest/meta-data/instance-id)'\"' | /usr/bin/cat - /etc/journald-cloudwatch-logs.conf > /tmp/journald-cloudwatch-logs.conf"
+		ExecStartPre=/usr/bin/wget https://github.com/advantageous/systemd-cloud-watch/releases/download/v0.2.1/systemd-cloud-watch_linux -O /tmp/systemd-cloud-watch_linux
+		ExecStartPre=/usr/bin/chmod +x /tmp/systemd-cloud-watch_linux
+		ExecStart=/tmp/systemd-cloud-watch_linux /tmp/journald-cloudwatch-logs.conf
+		Restart=on-failure
+		RestartSec=30s
+		`, args{}),
+	})
+}
+
+// shipsLogsToBlob tells whether the instance's reflowlet ships its
+// logs to a blob store instead of CloudWatch Logs.
+func (i *instance) shipsLogsToBlob() bool {
+	return i.Logs != "" && i.Logs != logsCloudWatch
+}
+
 func ec2TerminateInstance(api ec2iface.EC2API, id string, log *log.Logger) {
 	req := &ec2.TerminateInstancesInput{InstanceIds: aws.StringSlice([]string{id})}
 	resp, err := api.TerminateInstances(req)
@@ -1169,6 +1348,10 @@ func (i *instance) ec2RunInstance() (string, error) {
 		UserData:         aws.String(i.userData),
 		SecurityGroupIds: []*string{aws.String(i.SecurityGroup)},
 	}
+	if nis := networkInterfaces(i.IPAddressing, i.SecurityGroup, anySubnet()); nis != nil {
+		params.NetworkInterfaces = nis
+		params.SecurityGroupIds = nil
+	}
 	i.Log.Debugf("EC2RunInstances %v", params)
 	resv, err := i.EC2.RunInstances(params)
 	if err != nil {
@@ -1208,6 +1391,19 @@ func (i *instance) ebsDeviceMappings() []*ec2.BlockDeviceMapping {
 			},
 		})
 	}
+	if i.DockerSnapshot != "" {
+		// The docker layer cache is attached after the data devices, so
+		// that it does not displace them. The volume size is that of the
+		// snapshot.
+		mappings = append(mappings, &ec2.BlockDeviceMapping{
+			DeviceName: aws.String(fmt.Sprintf("/dev/xvd%c", 'b'+i.NEBS)),
+			Ebs: &ec2.EbsBlockDevice{
+				DeleteOnTermination: aws.Bool(true),
+				SnapshotId:          aws.String(i.DockerSnapshot),
+				VolumeType:          aws.String(i.EBSType),
+			},
+		})
+	}
 	return mappings
 }
 
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"reflect"
 	"testing"
 	"time"
 
@@ -43,6 +44,43 @@ func TestConfigureEBS(t *testing.T) {
 	}
 }
 
+func TestEBSDeviceMappingsDockerSnapshot(t *testing.T) {
+	i := &instance{EBSType: "gp2", EBSSize: 100, NEBS: 2}
+	if got, want := len(i.ebsDeviceMappings()), 3; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	i.DockerSnapshot = "snap-0123456789"
+	mappings := i.ebsDeviceMappings()
+	if got, want := len(mappings), 4; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	last := mappings[len(mappings)-1]
+	if got, want := *last.DeviceName, "/dev/xvdd"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := *last.Ebs.SnapshotId, i.DockerSnapshot; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if last.Ebs.VolumeSize != nil {
+		t.Errorf("got volume size %v, want snapshot size", *last.Ebs.VolumeSize)
+	}
+}
+
+func TestReflowletArgsDockerCacheSeed(t *testing.T) {
+	i := &instance{}
+	if got, want := i.reflowletArgs(), reflowletArgs; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	i.DockerCacheSeed = "s3://bucket/images.tar"
+	args := i.reflowletArgs()
+	if got, want := args[len(args)-2:], []string{"-dockercacheseed", i.DockerCacheSeed}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := len(reflowletArgs), len(args)-2; got != want {
+		t.Errorf("got %v default args, want %v", got, want)
+	}
+}
+
 type counter struct {
 	nextId int
 }
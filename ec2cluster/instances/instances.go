@@ -26,6 +26,8 @@ type Type struct {
 	Generation string
 	// Virt stores the virtualization type used by this instance type.
 	Virt string
+	// Arch stores the CPU architecture of this instance type ("x86_64" or "arm64").
+	Arch string
 	// NVMe specifies whether EBS block devices are exposed as NVMe volumes.
 	NVMe bool
 	// CPUFeatures defines the available CPU features on this instance type
@@ -81,6 +83,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -126,6 +129,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -151,6 +155,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -176,6 +181,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -195,6 +201,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -214,6 +221,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -238,6 +246,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -275,6 +284,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -309,6 +319,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -341,6 +352,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -371,6 +383,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -401,6 +414,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -436,6 +450,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -464,6 +479,7 @@ var Types = []Type{
 		},
 		Generation: "previous",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -507,6 +523,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -544,6 +561,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -570,6 +588,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -610,6 +629,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -655,6 +675,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -695,6 +716,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -735,6 +757,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -767,6 +790,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -812,6 +836,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -844,6 +869,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -864,6 +890,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -909,6 +936,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -939,6 +967,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -977,6 +1006,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -1022,6 +1052,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -1055,6 +1086,7 @@ var Types = []Type{
 		},
 		Generation: "previous",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -1098,6 +1130,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -1128,6 +1161,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -1166,6 +1200,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -1201,6 +1236,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -1225,6 +1261,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -1265,6 +1302,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -1298,6 +1336,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -1343,6 +1382,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -1385,6 +1425,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -1425,6 +1466,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -1448,6 +1490,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -1485,6 +1528,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":  true,
@@ -1523,6 +1567,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -1551,6 +1596,7 @@ var Types = []Type{
 		},
 		Generation: "previous",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -1579,6 +1625,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -1613,6 +1660,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -1643,6 +1691,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -1668,6 +1717,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -1698,6 +1748,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -1734,6 +1785,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -1771,6 +1823,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -1815,6 +1868,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -1860,6 +1914,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -1891,6 +1946,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -1921,6 +1977,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -1961,6 +2018,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -2005,6 +2063,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -2044,6 +2103,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -2074,6 +2134,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -2107,6 +2168,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -2151,6 +2213,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -2181,6 +2244,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -2221,6 +2285,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -2259,6 +2324,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -2302,6 +2368,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -2327,6 +2394,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -2356,6 +2424,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -2396,6 +2465,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -2441,6 +2511,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -2479,6 +2550,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -2511,6 +2583,7 @@ var Types = []Type{
 		},
 		Generation: "previous",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -2549,6 +2622,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -2593,6 +2667,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -2632,6 +2707,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -2662,6 +2738,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -2701,6 +2778,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -2729,6 +2807,7 @@ var Types = []Type{
 		},
 		Generation: "previous",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -2763,6 +2842,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -2806,6 +2886,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -2840,6 +2921,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -2869,6 +2951,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -2913,6 +2996,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -2956,6 +3040,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -2993,6 +3078,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -3029,6 +3115,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":  true,
@@ -3072,6 +3159,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -3109,6 +3197,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -3149,6 +3238,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -3192,6 +3282,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -3237,6 +3328,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -3262,6 +3354,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -3294,6 +3387,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -3338,6 +3432,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -3363,6 +3458,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -3399,6 +3495,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":  true,
@@ -3422,6 +3519,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -3452,6 +3550,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -3482,6 +3581,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -3506,6 +3606,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -3545,6 +3646,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -3584,6 +3686,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -3619,6 +3722,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -3659,6 +3763,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -3704,6 +3809,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -3739,6 +3845,7 @@ var Types = []Type{
 		},
 		Generation: "previous",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -3767,6 +3874,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -3787,6 +3895,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -3822,6 +3931,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -3854,6 +3964,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -3883,6 +3994,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -3923,6 +4035,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -3960,6 +4073,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -4004,6 +4118,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -4040,6 +4155,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -4065,6 +4181,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -4110,6 +4227,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -4143,6 +4261,7 @@ var Types = []Type{
 		},
 		Generation: "previous",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -4174,6 +4293,7 @@ var Types = []Type{
 		},
 		Generation: "previous",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -4215,6 +4335,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -4257,6 +4378,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -4296,6 +4418,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -4326,6 +4449,7 @@ var Types = []Type{
 		},
 		Generation: "previous",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -4354,6 +4478,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -4394,6 +4519,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -4419,6 +4545,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -4451,6 +4578,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -4487,6 +4615,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -4507,6 +4636,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -4552,6 +4682,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -4587,6 +4718,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -4632,6 +4764,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -4665,6 +4798,7 @@ var Types = []Type{
 		},
 		Generation: "previous",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -4703,6 +4837,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -4733,6 +4868,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -4772,6 +4908,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -4807,6 +4944,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -4849,6 +4987,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -4889,6 +5028,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -4908,6 +5048,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -4943,6 +5084,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -4972,6 +5114,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -4997,6 +5140,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -5037,6 +5181,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -5070,6 +5215,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -5094,6 +5240,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -5134,6 +5281,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -5167,6 +5315,7 @@ var Types = []Type{
 		},
 		Generation: "previous",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -5201,6 +5350,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -5246,6 +5396,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -5276,6 +5427,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -5311,6 +5463,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -5336,6 +5489,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -5376,6 +5530,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -5413,6 +5568,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -5438,6 +5594,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -5471,6 +5628,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -5494,6 +5652,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -5531,6 +5690,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":  true,
@@ -5572,6 +5732,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -5611,6 +5772,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -5643,6 +5805,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -5681,6 +5844,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -5714,6 +5878,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -5739,6 +5904,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -5771,6 +5937,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -5803,6 +5970,7 @@ var Types = []Type{
 		},
 		Generation: "previous",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -5834,6 +6002,7 @@ var Types = []Type{
 		},
 		Generation: "previous",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -5861,6 +6030,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -5892,6 +6062,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -5927,6 +6098,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -5957,6 +6129,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -5989,6 +6162,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":  true,
@@ -6029,6 +6203,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -6049,6 +6224,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -6080,6 +6256,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -6125,6 +6302,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -6170,6 +6348,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -6204,6 +6383,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -6238,6 +6418,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -6262,6 +6443,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -6282,6 +6464,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -6320,6 +6503,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -6352,6 +6536,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -6384,6 +6569,7 @@ var Types = []Type{
 		},
 		Generation: "previous",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -6414,6 +6600,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -6451,6 +6638,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":  true,
@@ -6473,6 +6661,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -6512,6 +6701,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -6552,6 +6742,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -6597,6 +6788,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -6634,6 +6826,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -6676,6 +6869,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -6710,6 +6904,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -6755,6 +6950,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -6799,6 +6995,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -6829,6 +7026,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -6854,6 +7052,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -6892,6 +7091,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -6920,6 +7120,7 @@ var Types = []Type{
 		},
 		Generation: "previous",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -6957,6 +7158,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -6997,6 +7199,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -7035,6 +7238,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -7069,6 +7273,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -7114,6 +7319,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -7147,6 +7353,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -7177,6 +7384,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -7217,6 +7425,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -7254,6 +7463,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -7284,6 +7494,7 @@ var Types = []Type{
 		},
 		Generation: "previous",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -7320,6 +7531,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -7344,6 +7556,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -7372,6 +7585,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -7402,6 +7616,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -7421,6 +7636,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -7448,6 +7664,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -7480,6 +7697,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -7514,6 +7732,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -7549,6 +7768,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -7586,6 +7806,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -7628,6 +7849,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -7668,6 +7890,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -7711,6 +7934,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -7753,6 +7977,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -7789,6 +8014,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -7819,6 +8045,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -7861,6 +8088,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -7885,6 +8113,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -7913,6 +8142,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -7945,6 +8175,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -7974,6 +8205,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -8001,6 +8233,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -8044,6 +8277,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -8084,6 +8318,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -8129,6 +8364,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -8154,6 +8390,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -8193,6 +8430,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -8233,6 +8471,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -8266,6 +8505,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -8304,6 +8544,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -8335,6 +8576,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -8365,6 +8607,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -8410,6 +8653,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -8455,6 +8699,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -8485,6 +8730,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -8515,6 +8761,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -8547,6 +8794,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -8579,6 +8827,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -8604,6 +8853,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -8629,6 +8879,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -8654,6 +8905,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -8691,6 +8943,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -8723,6 +8976,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -8752,6 +9006,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -8779,6 +9034,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -8812,6 +9068,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -8850,6 +9107,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -8875,6 +9133,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -8907,6 +9166,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -8926,6 +9186,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -8954,6 +9215,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -8992,6 +9254,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -9030,6 +9293,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -9068,6 +9332,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -9091,6 +9356,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -9129,6 +9395,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -9160,6 +9427,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -9192,6 +9460,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -9216,6 +9485,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -9243,6 +9513,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -9268,6 +9539,7 @@ var Types = []Type{
 		},
 		Generation: "previous",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -9303,6 +9575,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -9339,6 +9612,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -9363,6 +9637,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -9388,6 +9663,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -9408,6 +9684,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -9446,6 +9723,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -9466,6 +9744,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -9497,6 +9776,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -9542,6 +9822,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -9575,6 +9856,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -9619,6 +9901,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -9651,6 +9934,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -9694,6 +9978,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -9734,6 +10019,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -9771,6 +10057,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -9800,6 +10087,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -9825,6 +10113,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -9857,6 +10146,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -9889,6 +10179,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -9909,6 +10200,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -9937,6 +10229,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -9972,6 +10265,7 @@ var Types = []Type{
 		},
 		Generation: "previous",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -10015,6 +10309,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -10051,6 +10346,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -10091,6 +10387,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -10122,6 +10419,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -10162,6 +10460,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -10207,6 +10506,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -10243,6 +10543,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -10270,6 +10571,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -10309,6 +10611,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -10352,6 +10655,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -10377,6 +10681,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -10402,6 +10707,7 @@ var Types = []Type{
 		},
 		Generation: "previous",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -10424,6 +10730,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -10463,6 +10770,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -10498,6 +10806,7 @@ var Types = []Type{
 		},
 		Generation: "previous",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -10535,6 +10844,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -10563,6 +10873,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -10588,6 +10899,7 @@ var Types = []Type{
 		},
 		Generation:  "previous",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        false,
 		CPUFeatures: map[string]bool{},
 	},
@@ -10615,6 +10927,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -10655,6 +10968,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -10692,6 +11006,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -10726,6 +11041,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -10746,6 +11062,7 @@ var Types = []Type{
 		},
 		Generation:  "previous",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        false,
 		CPUFeatures: map[string]bool{},
 	},
@@ -10771,6 +11088,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -10811,6 +11129,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -10843,6 +11162,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -10887,6 +11207,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -10926,6 +11247,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -10966,6 +11288,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -11011,6 +11334,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -11038,6 +11362,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
@@ -11082,6 +11407,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -11121,6 +11447,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -11160,6 +11487,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -11188,6 +11516,7 @@ var Types = []Type{
 		},
 		Generation: "current",
 		Virt:       "HVM",
+		Arch:       "x86_64",
 		NVMe:       true,
 		CPUFeatures: map[string]bool{
 			"intel_avx":    true,
@@ -11212,6 +11541,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -11235,6 +11565,7 @@ var Types = []Type{
 		},
 		Generation:  "previous",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        false,
 		CPUFeatures: map[string]bool{},
 	},
@@ -11269,6 +11600,7 @@ var Types = []Type{
 		},
 		Generation:  "current",
 		Virt:        "HVM",
+		Arch:        "x86_64",
 		NVMe:        true,
 		CPUFeatures: map[string]bool{},
 	},
@@ -11284,6 +11616,7 @@ var Types = []Type{
 		Price:          map[string]float64{},
 		Generation:     "previous",
 		Virt:           "HVM",
+		Arch:           "x86_64",
 		NVMe:           false,
 		CPUFeatures: map[string]bool{
 			"intel_avx":   true,
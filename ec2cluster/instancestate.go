@@ -39,6 +39,7 @@ func init() {
 			// instances not supported by spot.
 			SpotOk: typ.Generation == "current" && !strings.HasPrefix(typ.Name, "t2."),
 			NVMe:   typ.NVMe,
+			Arch:   typ.Arch,
 		}
 		for key, ok := range typ.CPUFeatures {
 			if !ok {
@@ -47,6 +48,11 @@ func init() {
 			// Allocate one feature per VCPU.
 			instanceTypes[typ.Name].Resources[key] = float64(typ.VCPU)
 		}
+		// arm64 is presented as a feature, so that only execs which
+		// require it are placed on arm64 instances.
+		if typ.Arch == reflow.Arm64 {
+			instanceTypes[typ.Name].Resources[reflow.Arm64] = float64(typ.VCPU)
+		}
 	}
 
 	if err := initOnce.Do(func() error {
@@ -84,6 +90,9 @@ type instanceState struct {
 
 	mu          sync.Mutex
 	unavailable map[string]time.Time
+	// coverage maps an instance family to the fraction of its on-demand
+	// price which is already paid for by Reserved Instances or Savings Plans.
+	coverage map[string]float64
 }
 
 func newInstanceState(configs []instanceConfig, sleep time.Duration, region string, adv advisor) *instanceState {
@@ -110,6 +119,19 @@ func newInstanceState(configs []instanceConfig, sleep time.Duration, region stri
 	return s
 }
 
+// SetCoverage sets the Reserved Instance and Savings Plan coverage,
+// keyed by instance family (eg, "m5"), used to compute the effective
+// price of on-demand instances. Each value is the fraction of the
+// on-demand price that is already paid for, and must be in [0, 1].
+func (s *instanceState) SetCoverage(coverage map[string]float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.coverage = make(map[string]float64, len(coverage))
+	for family, frac := range coverage {
+		s.coverage[family] = frac
+	}
+}
+
 // Unavailable marks the given instance config as busy.
 func (s *instanceState) Unavailable(config instanceConfig) {
 	s.mu.Lock()
@@ -121,7 +143,7 @@ func (s *instanceState) Unavailable(config instanceConfig) {
 // available as an EC2 instance.
 func (s *instanceState) Available(need reflow.Resources) bool {
 	for _, config := range s.configs {
-		if config.Resources.Available(need) {
+		if fits(config, need) {
 			return true
 		}
 	}
@@ -168,7 +190,7 @@ func (s *instanceState) MaxAvailable(need reflow.Resources, spot bool) (instance
 			if time.Since(s.unavailable[config.Type]) < s.sleepTime || (spot && !config.SpotOk) {
 				continue
 			}
-			if !config.Resources.Available(need) {
+			if !fits(config, need) {
 				continue
 			}
 			if spot && !s.withinThreshold(config, prob) {
@@ -184,14 +206,22 @@ func (s *instanceState) MaxAvailable(need reflow.Resources, spot bool) (instance
 			break
 		}
 	}
-	return best, best.Resources.Available(need)
+	return best, fits(best, need)
 }
 
 // MinAvailable returns the cheapest instance type that has at least the required
 // resources, is believed to be currently available and is less expensive than
 // maxPrice. Spot restricts instances to those that may be launched via EC2 spot
-// market and tries to minimize interrupt probability.
-func (s *instanceState) MinAvailable(need reflow.Resources, spot bool, maxPrice float64) (instanceConfig, bool) {
+// market and tries to minimize interrupt probability. For on-demand instances,
+// prices are compared after discounting any Reserved Instance or Savings Plan
+// coverage (see SetCoverage), so that covered instance families are preferred.
+// maxPrice is always compared against the undiscounted on-demand price.
+// Instance types are ranked as if their prices were increased by the
+// given penalties (see sched.InstanceTypePenalties): a type with
+// penalty p is ranked as if it cost 1+p times as much, so that types
+// which perform poorly are de-prioritized, but still chosen if no
+// other type is viable.
+func (s *instanceState) MinAvailable(need reflow.Resources, spot bool, maxPrice float64, penalties map[string]float64) (instanceConfig, bool) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	var (
@@ -207,7 +237,7 @@ func (s *instanceState) MinAvailable(need reflow.Resources, spot bool, maxPrice
 			if time.Since(s.unavailable[config.Type]) < s.sleepTime || (spot && !config.SpotOk) {
 				continue
 			}
-			if !config.Resources.Available(need) {
+			if !fits(config, need) {
 				continue
 			}
 			if price, ok = config.Price[s.region]; !ok {
@@ -220,7 +250,8 @@ func (s *instanceState) MinAvailable(need reflow.Resources, spot bool, maxPrice
 				continue
 			}
 			viable = append(viable, config)
-			if price < bestPrice {
+			price = s.effectivePrice(config, spot) * (1 + penalties[config.Type])
+			if price < bestPrice || (price == bestPrice && config.Price[s.region] < best.Price[s.region]) {
 				bestPrice = price
 				best = config
 			}
@@ -235,7 +266,7 @@ func (s *instanceState) MinAvailable(need reflow.Resources, spot bool, maxPrice
 
 	// Choose a higher cost but better EBS throughput instance type if applicable.
 	for _, config := range viable {
-		price = config.Price[s.region]
+		price = s.effectivePrice(config, spot) * (1 + penalties[config.Type])
 		// Prefer a reasonably more expensive one with higher EBS throughput
 		if !found &&
 			(price < bestPrice+ebsThroughputPremiumCost ||
@@ -251,7 +282,52 @@ func (s *instanceState) MinAvailable(need reflow.Resources, spot bool, maxPrice
 			best = config
 		}
 	}
-	return best, best.Resources.Available(need)
+	return best, fits(best, need)
+}
+
+// effectivePrice returns the hourly price of the given config, net of any
+// Reserved Instance or Savings Plan coverage of its instance family.
+// Coverage does not apply to spot instances.
+func (s *instanceState) effectivePrice(config instanceConfig, spot bool) float64 {
+	price := config.Price[s.region]
+	if spot {
+		return price
+	}
+	return CoveredPrice(config.Type, price, s.coverage)
+}
+
+// EffectivePrice returns the hourly price of the given config, net of
+// any coverage of its instance family (see effectivePrice).
+func (s *instanceState) EffectivePrice(config instanceConfig, spot bool) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.effectivePrice(config, spot)
+}
+
+// CoveredPrice returns the given on-demand hourly price of an instance
+// of type typ, net of the fraction of the price of its instance family
+// which is already paid for by Reserved Instances or Savings Plans, as
+// given by coverage (see Cluster.ReservedCoverage).
+func CoveredPrice(typ string, price float64, coverage map[string]float64) float64 {
+	if frac, ok := coverage[instanceFamily(typ)]; ok {
+		price *= 1 - frac
+	}
+	return price
+}
+
+// instanceFamily returns the family of the given instance type,
+// eg, "m5" for "m5.2xlarge".
+func instanceFamily(typ string) string {
+	if i := strings.Index(typ, "."); i >= 0 {
+		return typ[:i]
+	}
+	return typ
+}
+
+// fits tells whether the given config provides the needed resources
+// and is of the architecture they require.
+func fits(config instanceConfig, need reflow.Resources) bool {
+	return config.Resources.Available(need) && config.Resources.ArchCompatible(need)
 }
 
 func (s *instanceState) Type(typ string) (instanceConfig, bool) {
@@ -283,7 +359,7 @@ func (s *instanceState) withinThreshold(config instanceConfig, desiredProb sa.In
 // `spot` determines whether we should consider instance types that are available
 // as spot instances or not.
 func InstanceType(need reflow.Resources, spot bool, maxPrice float64) (string, reflow.Resources) {
-	config, _ := allInstancesState.MinAvailable(need, spot, maxPrice)
+	config, _ := allInstancesState.MinAvailable(need, spot, maxPrice, nil)
 	return config.Type, config.Resources
 }
 
@@ -34,7 +34,7 @@ func TestInstanceState(t *testing.T) {
 		{reflow.Resources{"mem": 120 << 30, "cpu": 32, "disk": 2000 << 30}, "r5a.8xlarge", "x1e.32xlarge"},
 	} {
 		for _, spot := range []bool{true, false} {
-			if got, _ := is.MinAvailable(tc.r, spot, testMaxPrice); got.Type != tc.wantMin {
+			if got, _ := is.MinAvailable(tc.r, spot, testMaxPrice, nil); got.Type != tc.wantMin {
 				t.Errorf("got %v, want %v for spot %v, resources %v", got.Type, tc.wantMin, spot, tc.r)
 			}
 			if got, _ := is.MaxAvailable(tc.r, spot); got.Type != tc.wantMax {
@@ -92,23 +92,134 @@ func TestInstanceStateUnavailable(t *testing.T) {
 		[]instanceConfig{instanceTypes["c5.2xlarge"]},
 		sleepTime, "us-west-2", nil)
 	cfg, _ := instances.Type("c5.2xlarge")
-	gotCfg, gotAvail := instances.MinAvailable(reflow.Resources{"mem": 2 << 30, "cpu": 1}, true, 100.0)
+	gotCfg, gotAvail := instances.MinAvailable(reflow.Resources{"mem": 2 << 30, "cpu": 1}, true, 100.0, nil)
 	if wantCfg, wantAvail := cfg, true; !reflect.DeepEqual(gotCfg, wantCfg) || gotAvail != wantAvail {
 		t.Errorf("Instance type: got %v, want %v, available: got %v, want %v ", gotCfg, wantCfg, gotAvail, wantAvail)
 	}
 	instances.Unavailable(cfg)
 	zeroCfg := instanceConfig{}
-	gotCfg, gotAvail = instances.MinAvailable(reflow.Resources{"mem": 2 << 30, "cpu": 1}, true, 100.0)
+	gotCfg, gotAvail = instances.MinAvailable(reflow.Resources{"mem": 2 << 30, "cpu": 1}, true, 100.0, nil)
 	if wantCfg, wantAvail := zeroCfg, false; !reflect.DeepEqual(gotCfg, wantCfg) || gotAvail != wantAvail {
 		t.Errorf("Instance type: got %v, want %v, available: got %v, want %v ", gotCfg, wantCfg, gotAvail, wantAvail)
 	}
 	time.Sleep(sleepTime)
-	gotCfg, gotAvail = instances.MinAvailable(reflow.Resources{"mem": 2 << 30, "cpu": 1}, true, 100.0)
+	gotCfg, gotAvail = instances.MinAvailable(reflow.Resources{"mem": 2 << 30, "cpu": 1}, true, 100.0, nil)
 	if wantCfg, wantAvail := cfg, true; !reflect.DeepEqual(gotCfg, wantCfg) || gotAvail != wantAvail {
 		t.Errorf("Instance type: got %v, want %v, available: got %v, want %v ", gotCfg, wantCfg, gotAvail, wantAvail)
 	}
 }
 
+func TestInstanceStateCoverage(t *testing.T) {
+	instances := newInstanceState(
+		[]instanceConfig{instanceTypes["c5.2xlarge"], instanceTypes["m5.2xlarge"]},
+		1*time.Second, "us-west-2", nil)
+	need := reflow.Resources{"mem": 4 << 30, "cpu": 4}
+	for _, spot := range []bool{true, false} {
+		if got, _ := instances.MinAvailable(need, spot, testMaxPrice, nil); got.Type != "c5.2xlarge" {
+			t.Errorf("got %v, want %v for spot %v", got.Type, "c5.2xlarge", spot)
+		}
+	}
+	instances.SetCoverage(map[string]float64{"m5": 0.5})
+	for _, tc := range []struct {
+		spot bool
+		want string
+	}{
+		// Coverage does not apply to spot instances.
+		{true, "c5.2xlarge"},
+		{false, "m5.2xlarge"},
+	} {
+		if got, _ := instances.MinAvailable(need, tc.spot, testMaxPrice, nil); got.Type != tc.want {
+			t.Errorf("got %v, want %v for spot %v", got.Type, tc.want, tc.spot)
+		}
+	}
+	// maxPrice applies to the undiscounted price.
+	if _, ok := instances.MinAvailable(need, false, 0.35, nil); !ok {
+		t.Error("expected an available instance type")
+	}
+	if got, _ := instances.MinAvailable(need, false, 0.35, nil); got.Type != "c5.2xlarge" {
+		t.Errorf("got %v, want %v", got.Type, "c5.2xlarge")
+	}
+	// Covered on-demand instances are priced at their effective rate.
+	m5 := instanceTypes["m5.2xlarge"]
+	price := m5.Price["us-west-2"]
+	if got, want := instances.EffectivePrice(m5, false), price/2; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := instances.EffectivePrice(m5, true), price; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := CoveredPrice("c5.2xlarge", 1, map[string]float64{"m5": 0.5, "c5": 1}), 0.0; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := CoveredPrice("c5.2xlarge", 1, nil), 1.0; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestInstanceStatePenalties(t *testing.T) {
+	instances := newInstanceState(
+		[]instanceConfig{instanceTypes["c5.2xlarge"], instanceTypes["m5.2xlarge"]},
+		1*time.Second, "us-west-2", nil)
+	need := reflow.Resources{"mem": 4 << 30, "cpu": 4}
+	for _, tc := range []struct {
+		penalties map[string]float64
+		want      string
+	}{
+		{nil, "c5.2xlarge"},
+		// A small penalty does not outweigh the price difference.
+		{map[string]float64{"c5.2xlarge": 0.01}, "c5.2xlarge"},
+		{map[string]float64{"c5.2xlarge": 1}, "m5.2xlarge"},
+		{map[string]float64{"c5.2xlarge": 1, "m5.2xlarge": 1}, "c5.2xlarge"},
+	} {
+		if got, _ := instances.MinAvailable(need, false, testMaxPrice, tc.penalties); got.Type != tc.want {
+			t.Errorf("%v: got %v, want %v", tc.penalties, got.Type, tc.want)
+		}
+	}
+	// Penalized instance types are still used if no other is viable.
+	need = reflow.Resources{"mem": 16 << 30, "cpu": 4}
+	if got, _ := instances.MinAvailable(need, false, testMaxPrice, map[string]float64{"m5.2xlarge": 1}); got.Type != "m5.2xlarge" {
+		t.Errorf("got %v, want %v", got.Type, "m5.2xlarge")
+	}
+}
+
+func TestInstanceStateArch(t *testing.T) {
+	graviton := instanceConfig{
+		Type:      "c6g.2xlarge",
+		Resources: reflow.Resources{"cpu": 8, reflow.Arm64: 8},
+		Price:     map[string]float64{"us-west-2": 0.272},
+		SpotOk:    true,
+		Arch:      reflow.Arm64,
+	}
+	instances := newInstanceState(
+		[]instanceConfig{instanceTypes["c5.2xlarge"], graviton},
+		1*time.Second, "us-west-2", nil)
+	for _, tc := range []struct {
+		need reflow.Resources
+		want string
+	}{
+		// arm64 instances are used only when explicitly required,
+		// even if they are cheaper.
+		{reflow.Resources{"cpu": 4}, "c5.2xlarge"},
+		{reflow.Resources{"cpu": 4, reflow.Arm64: 4}, "c6g.2xlarge"},
+	} {
+		if !instances.Available(tc.need) {
+			t.Errorf("expected %v to be available", tc.need)
+		}
+		for _, spot := range []bool{true, false} {
+			if got, _ := instances.MinAvailable(tc.need, spot, testMaxPrice, nil); got.Type != tc.want {
+				t.Errorf("min %v: got %v, want %v (spot %v)", tc.need, got.Type, tc.want, spot)
+			}
+			if got, _ := instances.MaxAvailable(tc.need, spot); got.Type != tc.want {
+				t.Errorf("max %v: got %v, want %v (spot %v)", tc.need, got.Type, tc.want, spot)
+			}
+		}
+	}
+	instances = newInstanceState([]instanceConfig{instanceTypes["c5.2xlarge"]}, 1*time.Second, "us-west-2", nil)
+	if need := (reflow.Resources{"cpu": 4, reflow.Arm64: 4}); instances.Available(need) {
+		t.Errorf("expected %v to be unavailable", need)
+	}
+}
+
 func TestInstanceStateWithAdvisor(t *testing.T) {
 	var instances []instanceConfig
 	testAdvisorAllHighInterrupt := testAdvisor{}
@@ -161,7 +272,7 @@ func TestInstanceStateWithAdvisor(t *testing.T) {
 			// create an instanceState using the testcase's advisor
 			is := newInstanceState(instances, 1*time.Second, "us-west-2", tc.adv)
 
-			if got, _ := is.MinAvailable(tc.r, tc.spot, testMaxPrice); got.Type != tc.wantMin {
+			if got, _ := is.MinAvailable(tc.r, tc.spot, testMaxPrice, nil); got.Type != tc.wantMin {
 				t.Errorf("got %v, want %v for spot %v, resources %v", got.Type, tc.wantMin, tc.spot, tc.r)
 			}
 			if got, _ := is.MaxAvailable(tc.r, tc.spot); got.Type != tc.wantMax {
@@ -15,6 +15,8 @@ import (
 	"github.com/grailbio/base/sync/ctxsync"
 	"github.com/grailbio/reflow"
 	"github.com/grailbio/reflow/log"
+	"github.com/grailbio/reflow/metrics"
+	"github.com/grailbio/reflow/sched"
 )
 
 const (
@@ -57,13 +59,16 @@ type ManagedCluster interface {
 
 	// Available returns any available instance specification that can satisfy the need.
 	// The returned InstanceSpec should be subsequently be 'Launch'able.
-	Available(need reflow.Resources, maxPrice float64) (InstanceSpec, bool)
+	// Instance types with larger penalties (see sched.InstanceTypePenalties)
+	// should be de-prioritized.
+	Available(need reflow.Resources, maxPrice float64, penalties map[string]float64) (InstanceSpec, bool)
 
 	// Notify notifies the managed cluster of the currently waiting and pending
 	// amount of resources.
 	Notify(waiting, pending reflow.Resources)
 
-	// InstancePriceUSD returns the maximum hourly price bid in USD for the given instance type.
+	// InstancePriceUSD returns the maximum hourly price bid in USD for the given instance type,
+	// net of any reserved coverage of on-demand instances.
 	InstancePriceUSD(typ string) float64
 
 	// CheapestInstancePriceUSD returns the minimum hourly price bid in USD for all known instance types.
@@ -74,6 +79,9 @@ type waiter struct {
 	reflow.Requirements
 	ctx context.Context
 	c   chan struct{}
+	// penalties are the instance type penalties of the tasks for
+	// which the requirements are requested.
+	penalties map[string]float64
 }
 
 func (w *waiter) notify() {
@@ -162,6 +170,7 @@ func (m *Manager) Allocate(ctx context.Context, req reflow.Requirements) <-chan
 		Requirements: req,
 		ctx:          ctx,
 		c:            make(chan struct{}),
+		penalties:    sched.InstanceTypePenalties(ctx),
 	}
 	select {
 	case m.waitc <- w: // waitc is unbuffered so this is blocking
@@ -230,8 +239,13 @@ func (m *Manager) remainingBudgetUSD(includePending bool) float64 {
 
 // getInstanceAllocations returns the instances needed to satisfy the waiters.
 // It uses a greedy algorithm to group as many waiter requests as possible into a instance.
+// The instance type of each group is chosen subject to the instance type penalties of
+// the group's waiters.
 func (m *Manager) getInstanceAllocations(waiters []*waiter) (todo []InstanceSpec) {
-	var resources []reflow.Resources
+	var (
+		resources []reflow.Resources
+		penalties []map[string]float64
+	)
 	for _, w := range waiters {
 		width := w.Width
 		if width == 0 {
@@ -239,6 +253,7 @@ func (m *Manager) getInstanceAllocations(waiters []*waiter) (todo []InstanceSpec
 		}
 		for i := width; i > 0; i-- {
 			resources = append(resources, w.Min)
+			penalties = append(penalties, w.penalties)
 		}
 	}
 	var (
@@ -247,6 +262,8 @@ func (m *Manager) getInstanceAllocations(waiters []*waiter) (todo []InstanceSpec
 		oldMin, min InstanceSpec
 		ok          bool
 		i           int
+		// groupPenalties are the instance type penalties of the current group.
+		groupPenalties map[string]float64
 	)
 	// Return early if we don't have any budget to work with.
 	if budget, cheapest := m.remainingBudgetUSD(false), m.cluster.CheapestInstancePriceUSD(); budget-cheapest < 0 {
@@ -257,15 +274,18 @@ func (m *Manager) getInstanceAllocations(waiters []*waiter) (todo []InstanceSpec
 	for i < len(resources) {
 		res := resources[i]
 		need.Add(need, res)
-		min, ok = m.cluster.Available(need, m.remainingBudgetUSD(false))
+		groupPenalties = mergePenalties(groupPenalties, penalties[i])
+		min, ok = m.cluster.Available(need, m.remainingBudgetUSD(false), groupPenalties)
 		switch {
 		case group == 0 && !ok:
 			i++
 			need.Set(reflow.Resources{})
+			groupPenalties = nil
 			m.log.Debugf("no currently available instance type can satisfy resource requirements %v", res)
 		case !ok:
 			todo = append(todo, oldMin)
 			need.Set(reflow.Resources{})
+			groupPenalties = nil
 			group = 0
 		case ok:
 			oldMin = min
@@ -279,6 +299,27 @@ func (m *Manager) getInstanceAllocations(waiters []*waiter) (todo []InstanceSpec
 	return
 }
 
+// mergePenalties returns the largest penalty of each instance type in
+// the penalties p and q. It does not modify p or q.
+func mergePenalties(p, q map[string]float64) map[string]float64 {
+	if len(q) == 0 {
+		return p
+	}
+	if len(p) == 0 {
+		return q
+	}
+	merged := make(map[string]float64, len(p)+len(q))
+	for typ, v := range p {
+		merged[typ] = v
+	}
+	for typ, v := range q {
+		if v > merged[typ] {
+			merged[typ] = v
+		}
+	}
+	return merged
+}
+
 // loop services requests to expand the cluster's capacity.
 func (m *Manager) loop(pctx context.Context) {
 	var (
@@ -476,11 +517,35 @@ func (m *Manager) forceSync() {
 	_ = m.cond.Wait(context.Background())
 }
 
+// costMeter accumulates the cost of the cluster's instances over time.
+type costMeter struct {
+	// last is the time of the last update.
+	last time.Time
+	// hourly is the hourly cost (in USD) as of the last update.
+	hourly float64
+	// total is the cumulative cost (in USD) as of the last update.
+	total float64
+}
+
+// update records that the hourly cost of the cluster is hourly (in USD)
+// as of now, and returns the cumulative cost (in USD). The cost incurred
+// since the last update is accounted at the previously recorded rate.
+func (c *costMeter) update(now time.Time, hourly float64) float64 {
+	if !c.last.IsZero() && now.After(c.last) {
+		c.total += c.hourly * now.Sub(c.last).Hours()
+	}
+	c.last, c.hourly = now, hourly
+	return c.total
+}
+
 // maintain periodically refreshes the managed cluster. Also services requests
 // (through calls to `forceSync` or `wait`) to refresh the managed cluster's state.
+// After each refresh, the cluster's live (hourly) and cumulative cost are
+// emitted as metrics.
 func (m *Manager) maintain(ctx context.Context) {
 	tick := time.NewTicker(m.refreshInterval)
 	defer tick.Stop()
+	var cost costMeter
 	for {
 		select {
 		case <-tick.C:
@@ -497,5 +562,9 @@ func (m *Manager) maintain(ctx context.Context) {
 		m.pool = typesByID
 		m.cond.Broadcast()
 		m.mu.Unlock()
+		hourly := m.hourlyCostUSD()
+		total := cost.update(time.Now(), hourly)
+		metrics.GetClusterHourlyCostUsdGauge(ctx).Set(hourly)
+		metrics.GetClusterCostUsdGauge(ctx).Set(total)
 	}
 }
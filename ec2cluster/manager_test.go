@@ -8,6 +8,7 @@ import (
 	"context"
 	"fmt"
 	"math/rand"
+	"reflect"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -89,7 +90,7 @@ func (c *testManagedCluster) clearLive() {
 	}
 }
 
-func (c *testManagedCluster) Available(need reflow.Resources, maxPrice float64) (InstanceSpec, bool) {
+func (c *testManagedCluster) Available(need reflow.Resources, maxPrice float64, penalties map[string]float64) (InstanceSpec, bool) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	var min testConfig
@@ -180,6 +181,30 @@ func TestManagerStart(t *testing.T) {
 	checkState(t, c, "i-running")
 }
 
+func TestCostMeter(t *testing.T) {
+	var (
+		cost  costMeter
+		start = time.Now()
+	)
+	for _, tt := range []struct {
+		elapsed time.Duration
+		hourly  float64
+		want    float64
+	}{
+		{0, 2, 0},
+		{30 * time.Minute, 4, 1},
+		{2 * time.Hour, 1, 7},
+		// Updates with no elapsed time incur no cost.
+		{2 * time.Hour, 3, 7},
+		{3 * time.Hour, 0, 10},
+		{4 * time.Hour, 0, 10},
+	} {
+		if got, want := cost.update(start.Add(tt.elapsed), tt.hourly), tt.want; got != want {
+			t.Errorf("%s: got %v, want %v", tt.elapsed, got, want)
+		}
+	}
+}
+
 func TestManagerBasic(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
 	defer cancel()
@@ -376,3 +401,17 @@ func testCluster(t *testing.T, c *testManagedCluster, maxHourlyCostUSD float64,
 		t.Errorf("got %v, want %v", got, want)
 	}
 }
+
+func TestMergePenalties(t *testing.T) {
+	p := map[string]float64{"c5.xlarge": 0.5, "r5.large": 1}
+	q := map[string]float64{"c5.xlarge": 0.75, "m5.large": 0.5}
+	if got, want := mergePenalties(p, q), map[string]float64{"c5.xlarge": 0.75, "r5.large": 1, "m5.large": 0.5}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := p["c5.xlarge"], 0.5; got != want {
+		t.Errorf("penalties were modified: got %v, want %v", got, want)
+	}
+	if got := mergePenalties(nil, nil); got != nil {
+		t.Errorf("got %v, want nil", got)
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package ec2cluster
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/grailbio/reflow/errors"
+	"github.com/grailbio/reflow/pool"
+)
+
+// unprotectTimeout is the timeout for removing the termination
+// protection of an instance.
+const unprotectTimeout = time.Minute
+
+// ProtectAlloc protects the instance of the given alloc from
+// termination through the EC2 API (e.g., by administrators or by
+// health remediation) until the returned function is called.
+// Protection is counted, so that an instance remains protected while
+// any of its allocs run critical tasks. Protection does not prevent
+// idle instances from shutting themselves down, so instances whose
+// protection is not removed (e.g., because the process exited) are
+// still reclaimed. It implements sched.Protector.
+func (c *Cluster) ProtectAlloc(ctx context.Context, alloc pool.Alloc) (unprotect func(), err error) {
+	c.mu.Lock()
+	var iid string
+	for id, p := range c.pools {
+		if p.pool.ID() == alloc.Pool().ID() {
+			iid = id
+			break
+		}
+	}
+	c.mu.Unlock()
+	if iid == "" {
+		return nil, errors.E(errors.NotExist, "protect", alloc.ID(), errors.New("instance not found"))
+	}
+	c.protectMu.Lock()
+	defer c.protectMu.Unlock()
+	if c.protected[iid] == 0 {
+		if err = c.setTerminationProtection(ctx, iid, true); err != nil {
+			return nil, err
+		}
+		c.Log.Printf("instance %s: termination protection enabled", iid)
+	}
+	if c.protected == nil {
+		c.protected = make(map[string]int)
+	}
+	c.protected[iid]++
+	var once bool
+	return func() {
+		c.protectMu.Lock()
+		defer c.protectMu.Unlock()
+		if once {
+			return
+		}
+		once = true
+		if c.protected[iid]--; c.protected[iid] > 0 {
+			return
+		}
+		delete(c.protected, iid)
+		ctx, cancel := context.WithTimeout(context.Background(), unprotectTimeout)
+		defer cancel()
+		if err := c.setTerminationProtection(ctx, iid, false); err != nil {
+			c.Log.Errorf("instance %s: %v", iid, err)
+			return
+		}
+		c.Log.Printf("instance %s: termination protection disabled", iid)
+	}, nil
+}
+
+// setTerminationProtection sets or removes the termination protection
+// of the instance with the given ID.
+func (c *Cluster) setTerminationProtection(ctx context.Context, iid string, protect bool) error {
+	_, err := c.EC2.ModifyInstanceAttributeWithContext(ctx, &ec2.ModifyInstanceAttributeInput{
+		InstanceId:            aws.String(iid),
+		DisableApiTermination: &ec2.AttributeBooleanValue{Value: aws.Bool(protect)},
+	})
+	if err != nil {
+		return errors.E("set termination protection", fmt.Sprint(protect), err)
+	}
+	return nil
+}
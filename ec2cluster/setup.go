@@ -91,6 +91,15 @@ func setupEC2SecurityGroup(svc *ec2.EC2) (string, error) {
 	})
 
 	id := aws.StringValue(resp.GroupId)
+	// Permit IPv6 traffic as well, so that the security group may be used
+	// with dual-stack and IPv6-only instances.
+	var vpcIpv6Ranges []*ec2.Ipv6Range
+	for _, assoc := range vpc.Ipv6CidrBlockAssociationSet {
+		if assoc.Ipv6CidrBlock != nil {
+			vpcIpv6Ranges = append(vpcIpv6Ranges, &ec2.Ipv6Range{CidrIpv6: assoc.Ipv6CidrBlock})
+		}
+	}
+	anyIpv6 := []*ec2.Ipv6Range{{CidrIpv6: aws.String("::/0")}}
 	log.Printf("authorizing ingress traffic for security group %s", id)
 	_, err = svc.AuthorizeSecurityGroupIngress(&ec2.AuthorizeSecurityGroupIngressInput{
 		GroupName: aws.String(securityGroup),
@@ -99,6 +108,7 @@ func setupEC2SecurityGroup(svc *ec2.EC2) (string, error) {
 			{
 				IpProtocol: aws.String("-1"),
 				IpRanges:   []*ec2.IpRange{{CidrIp: vpc.CidrBlock}},
+				Ipv6Ranges: vpcIpv6Ranges,
 				FromPort:   aws.Int64(0),
 				ToPort:     aws.Int64(0),
 			},
@@ -106,6 +116,7 @@ func setupEC2SecurityGroup(svc *ec2.EC2) (string, error) {
 			{
 				IpProtocol: aws.String("tcp"),
 				IpRanges:   []*ec2.IpRange{{CidrIp: aws.String("0.0.0.0/0")}},
+				Ipv6Ranges: anyIpv6,
 				FromPort:   aws.Int64(22),
 				ToPort:     aws.Int64(22),
 			},
@@ -113,6 +124,7 @@ func setupEC2SecurityGroup(svc *ec2.EC2) (string, error) {
 			{
 				IpProtocol: aws.String("tcp"),
 				IpRanges:   []*ec2.IpRange{{CidrIp: aws.String("0.0.0.0/0")}},
+				Ipv6Ranges: anyIpv6,
 				FromPort:   aws.Int64(9000),
 				ToPort:     aws.Int64(9000),
 			},
@@ -84,6 +84,18 @@ func subnetForAZ(azName string) string {
 	return azNameToSubnet[azName]
 }
 
+// anySubnet returns a subnet (that of the lexically first availability-zone) among those
+// computed by computeAzSubnetMap, or an empty string if there are none.
+func anySubnet() string {
+	var az string
+	for name := range azNameToSubnet {
+		if az == "" || name < az {
+			az = name
+		}
+	}
+	return azNameToSubnet[az]
+}
+
 // GetSpotPlacementScores returns spot placement scores for the given instance type in the given region.
 // GetSpotPlacementScores returns a map of each Availability Zone name (within the given region) to the score.
 // Note that the region is stripped from the AZ names
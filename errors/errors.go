@@ -28,6 +28,7 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"syscall"
 
 	"github.com/grailbio/base/digest"
 	baseerrors "github.com/grailbio/base/errors"
@@ -81,6 +82,14 @@ const (
 	Module
 	// DockerExec indicates a reflow exec error.
 	DockerExec
+	// OutOfDisk indicates that there was insufficient disk space.
+	OutOfDisk
+	// BudgetExceeded indicates that a run exceeded its cost budget.
+	BudgetExceeded
+	// ExecTimeout indicates that an exec was killed after exceeding
+	// its timeout. Unlike Timeout, it is not transient: an exec which
+	// ran away is likely to do so again.
+	ExecTimeout
 
 	maxKind
 )
@@ -126,6 +135,12 @@ func (k Kind) String() string {
 		return "module"
 	case DockerExec:
 		return "docker exec"
+	case OutOfDisk:
+		return "out of disk space"
+	case BudgetExceeded:
+		return "cost budget exceeded"
+	case ExecTimeout:
+		return "exec timeout exceeded"
 	}
 }
 
@@ -149,6 +164,9 @@ var kind2string = [maxKind]string{
 	OOM:                "OOM",
 	Module:             "Module",
 	DockerExec:         "DockerExec",
+	OutOfDisk:          "OutOfDisk",
+	BudgetExceeded:     "BudgetExceeded",
+	ExecTimeout:        "ExecTimeout",
 }
 
 var string2kind = map[string]Kind{
@@ -171,6 +189,16 @@ var string2kind = map[string]Kind{
 	"OOM":                OOM,
 	"Module":             Module,
 	"DockerExec":         DockerExec,
+	"OutOfDisk":          OutOfDisk,
+	"BudgetExceeded":     BudgetExceeded,
+	"ExecTimeout":        ExecTimeout,
+}
+
+// ParseKind returns the kind with the given name (e.g., "Net"), as
+// it is rendered in JSON-encoded errors.
+func ParseKind(name string) (Kind, bool) {
+	k, ok := string2kind[name]
+	return k, ok
 }
 
 // Error defines a Reflow error. It is used to indicate an error
@@ -221,7 +249,9 @@ type temporary interface {
 // if it returns true, the error's kind is set to Temporary. (4) If
 // the underyling error is context.Canceled, the error's kind is set
 // to Canceled. (5) If the underlying error is an os.IsNotExist
-// error, the error's kind is set to NotExist.
+// error, the error's kind is set to NotExist. (6) If the underlying
+// error is (or wraps) syscall.ENOSPC, the error's kind is set to
+// OutOfDisk.
 func E(args ...interface{}) error {
 	if len(args) == 0 {
 		panic("no args")
@@ -284,6 +314,9 @@ func E(args ...interface{}) error {
 		if os.IsNotExist(e.Err) {
 			e.Kind = NotExist
 		}
+		if goerrors.Is(e.Err, syscall.ENOSPC) {
+			e.Kind = OutOfDisk
+		}
 	}
 	return e
 }
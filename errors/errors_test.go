@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"os"
 	"sync"
+	"syscall"
 	"testing"
 
 	baseerrors "github.com/grailbio/base/errors"
@@ -77,6 +78,11 @@ func TestE(t *testing.T) {
 	if got, want := e, E("fetch", Timeout, E("lookup")); !Match(want, got) {
 		t.Errorf("got %v, want %v", got, want)
 	}
+
+	e = E("write", &os.PathError{Op: "write", Path: "/tmp/x", Err: syscall.ENOSPC})
+	if !Is(OutOfDisk, e) {
+		t.Errorf("error %v is not %v", e, OutOfDisk)
+	}
 }
 
 func TestError(t *testing.T) {
@@ -139,6 +145,8 @@ func TestRestartable(t *testing.T) {
 		{E(Fatal, E(Timeout, "some timeout error")), false, false},
 		{E(Eval, E(OOM, "some oom error")), false, false},
 		{E(OOM, "some oom error"), false, false},
+		{E(ExecTimeout, "some exec timeout error"), false, false},
+		{E(Eval, E(ExecTimeout, "some exec timeout error")), false, false},
 		{E(NotAllowed, E(Timeout, "some timeout error")), false, false},
 		{E(Net, "some network error"), false, true},
 		{E(Eval, baseerrors.E(baseerrors.TooManyTries, "some too many tries error")), true, true},
@@ -0,0 +1,142 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package errors
+
+import (
+	"strings"
+	"sync"
+)
+
+// A Hint describes how users may remediate a well-known failure,
+// such as spot capacity being exhausted or a registry's pull rate
+// limit being exceeded. Hints are machine-readable, so that they may
+// be included in reports as well as rendered by tools.
+type Hint struct {
+	// Name identifies the failure, e.g., "spotcapacity".
+	Name string `json:"name"`
+	// Problem describes the failure.
+	Problem string `json:"problem"`
+	// Actions are the steps which users can take to remediate
+	// the failure.
+	Actions []string `json:"actions"`
+}
+
+// A signature identifies a well-known failure by the rendering of
+// its error. Errors are matched by their renderings (and not, e.g.,
+// by their kinds) because the failures of interest originate in
+// other systems (AWS, Docker), and their errors are often relayed by
+// reflowlets, which preserve only their messages.
+type signature struct {
+	hint Hint
+	// any are the strings of which the error must contain at least one.
+	any []string
+	// all are the strings which the error must all contain.
+	all []string
+}
+
+func (s signature) match(msg string) bool {
+	for _, sub := range s.all {
+		if !strings.Contains(msg, strings.ToLower(sub)) {
+			return false
+		}
+	}
+	for _, sub := range s.any {
+		if strings.Contains(msg, strings.ToLower(sub)) {
+			return true
+		}
+	}
+	return len(s.any) == 0
+}
+
+var (
+	signaturesMu sync.Mutex
+	signatures   = []signature{
+		{
+			hint: Hint{
+				Name:    "spotcapacity",
+				Problem: "EC2 does not have enough (spot) capacity of the requested instance types.",
+				Actions: []string{
+					"Allow more instance types (ec2cluster instancetypes), so that capacity may be found in other pools.",
+					"Use on-demand instances instead of spot instances (ec2cluster spot: false).",
+					"Retry later: spot capacity varies over time.",
+				},
+			},
+			any: []string{"InsufficientInstanceCapacity", "capacity-not-available", "MaxSpotInstanceCountExceeded", "SpotMaxPriceTooLow"},
+		},
+		{
+			hint: Hint{
+				Name:    "ecrauth",
+				Problem: "Access to an image in an ECR repository was denied.",
+				Actions: []string{
+					"Refresh your AWS credentials, and check that they (and the cluster's instance profile) may pull from the repository (ecr:GetAuthorizationToken, ecr:BatchGetImage, ecr:GetDownloadUrlForLayer).",
+					"Check that the image's repository, region and tag exist.",
+				},
+			},
+			all: []string{".dkr.ecr."},
+			any: []string{"no basic auth credentials", "denied", "authorization token has expired", "403 forbidden"},
+		},
+		{
+			hint: Hint{
+				Name:    "registryauth",
+				Problem: "A container registry rejected the credentials used to pull an image.",
+				Actions: []string{
+					"Configure credentials for the image's registry (configuration key registries), e.g., a Docker Hub access token or a GCR service account key.",
+					"Check that the configured credentials (or the secrets which store them) are current and may pull the image.",
+				},
+			},
+			any: []string{"unauthorized: authentication required", "unauthorized: incorrect username or password", "unauthorized: access to the requested resource is not authorized"},
+		},
+		{
+			hint: Hint{
+				Name:    "dynamodbthrottling",
+				Problem: "Requests to a DynamoDB table (the assoc or taskdb) were throttled.",
+				Actions: []string{
+					"Increase the table's provisioned capacity, or switch it to on-demand capacity (PAY_PER_REQUEST).",
+					"Reduce the number of concurrent runs which use the table.",
+				},
+			},
+			any: []string{"ProvisionedThroughputExceededException", "dynamodb: ThrottlingException", "ThrottlingException: Rate of requests exceeds"},
+		},
+		{
+			hint: Hint{
+				Name:    "dockerratelimit",
+				Problem: "The Docker registry's pull rate limit was exceeded.",
+				Actions: []string{
+					"Mirror the image into a private registry (e.g., ECR), and refer to it there.",
+					"Authenticate pulls from Docker Hub, which raises the limit.",
+					"Retry later: the limit applies to a sliding time window.",
+				},
+			},
+			any: []string{"toomanyrequests", "pull rate limit"},
+		},
+	}
+)
+
+// RegisterHint registers a hint for errors whose renderings contain
+// any of the given strings (ignoring case).
+func RegisterHint(hint Hint, contains ...string) {
+	signaturesMu.Lock()
+	signatures = append(signatures, signature{hint: hint, any: contains})
+	signaturesMu.Unlock()
+}
+
+// Hints returns the hints for remediating err, by matching it
+// against the signatures of well-known failures. It returns nil if
+// err does not match any of them.
+func Hints(err error) []Hint {
+	if err == nil {
+		return nil
+	}
+	msg := strings.ToLower(err.Error())
+	signaturesMu.Lock()
+	defer signaturesMu.Unlock()
+	var hints []Hint
+	for _, s := range signatures {
+		if s.match(msg) {
+			hints = append(hints, s.hint)
+		}
+	}
+	return hints
+}
@@ -0,0 +1,59 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package errors
+
+import (
+	"reflect"
+	"testing"
+)
+
+func hintNames(err error) []string {
+	var names []string
+	for _, h := range Hints(err) {
+		names = append(names, h.Name)
+	}
+	return names
+}
+
+func TestHints(t *testing.T) {
+	for _, c := range []struct {
+		err   error
+		names []string
+	}{
+		{nil, nil},
+		{E("exec", New("exit status 1")), nil},
+		{E("launch", ResourcesExhausted, New("InsufficientInstanceCapacity: We currently do not have sufficient c5.24xlarge capacity")), []string{"spotcapacity"}},
+		{E("pull", New("Error response from daemon: Get https://123.dkr.ecr.us-west-2.amazonaws.com/v2/x/manifests/y: no basic auth credentials")), []string{"ecrauth"}},
+		{E("pull", New("pull access denied for ubuntu, repository does not exist")), nil},
+		{E("pull", New("Error response from daemon: Head https://gcr.io/v2/project/image/manifests/latest: unauthorized: authentication required")), []string{"registryauth"}},
+		{E("assoc.Get", New("ProvisionedThroughputExceededException: The level of configured provisioned throughput for the table was exceeded")), []string{"dynamodbthrottling"}},
+		{E("pull", New("toomanyrequests: You have reached your pull rate limit.")), []string{"dockerratelimit"}},
+	} {
+		if got, want := hintNames(c.err), c.names; !reflect.DeepEqual(got, want) {
+			t.Errorf("%v: got %v, want %v", c.err, got, want)
+		}
+	}
+}
+
+func TestHintsJSON(t *testing.T) {
+	// Hints survive the serialization of errors, e.g., from reflowlets.
+	err := E("exec", "image", Unavailable, New("toomanyrequests: Too Many Requests"))
+	var e Error
+	if err := roundtripJSON(err, &e); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := hintNames(&e), []string{"dockerratelimit"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestRegisterHint(t *testing.T) {
+	saved := signatures
+	defer func() { signatures = saved }()
+	RegisterHint(Hint{Name: "quota"}, "QuotaExceeded")
+	if got, want := hintNames(New("request failed: quotaexceeded")), []string{"quota"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
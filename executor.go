@@ -9,6 +9,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"math"
 	"net/url"
 	"sort"
 	"strings"
@@ -64,8 +65,33 @@ type Arg struct {
 	Out bool
 	// Fileset is the fileset used as an input argument.
 	Fileset *Fileset `json:",omitempty"`
-	// Index is the output argument index.
+	// Index is the output (or scratch) argument index.
 	Index int
+	// Scratch is true if this is a scratch argument: a temporary
+	// directory private to the exec, whose contents are neither
+	// returned nor uploaded to the repository.
+	Scratch bool `json:",omitempty"`
+	// Inline is true if this is an inline input argument: a (small)
+	// file whose contents, Data, are materialized directly into the
+	// exec's sandbox.
+	Inline bool `json:",omitempty"`
+	// Data is the contents of an inline argument.
+	Data string `json:",omitempty"`
+}
+
+// Credentials are short-lived AWS credentials. They are minted for,
+// and scoped to the data accessed by, a single task.
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	// Expiration is the time at which the credentials expire.
+	Expiration time.Time
+	// Refresh, if not nil, mints new credentials with the same scope.
+	// Stores which use the credentials in process call it before they
+	// expire. It is not serialized: executors are passed credentials
+	// which cannot be refreshed.
+	Refresh func(ctx context.Context) (*Credentials, error) `json:"-"`
 }
 
 // ExecConfig contains all the necessary information to perform an
@@ -107,9 +133,53 @@ type ExecConfig struct {
 	// NeedDockerAccess indicates that the exec needs access to the host docker daemon
 	NeedDockerAccess bool
 
+	// intern, extern: credentials, scoped to URL, with which the data
+	// is transferred in place of the executor's own. Credentials are
+	// never persisted by executors, nor returned by (Exec).Inspect.
+	Credentials *Credentials `json:",omitempty"`
+
 	// OutputIsDir tells whether an output argument (by index)
 	// is a directory.
 	OutputIsDir []bool `json:",omitempty"`
+
+	// exec: the maximum (wallclock) duration for which the exec may run.
+	// Execs which exceed their timeout are killed and fail with an error
+	// of kind errors.ExecTimeout. A zero timeout means no limit.
+	Timeout time.Duration `json:",omitempty"`
+
+	// exec: the number of times the exec is retried if it fails, whatever
+	// its error. Execs which fail with errors known to be transient (e.g.,
+	// OOMs) are retried up to a fixed number of times, or Retries times
+	// if it is larger.
+	Retries int `json:",omitempty"`
+
+	// exec: (small) inline data provided to the command's standard input.
+	Stdin string `json:",omitempty"`
+
+	// exec: the priority of the exec relative to other execs running
+	// on the same host. As with task priorities, lower numbers indicate
+	// higher priority; zero is the default priority. Executors use the
+	// priority to apportion CPU and block I/O, and to choose which
+	// execs are killed first when the host runs out of memory.
+	Priority int `json:",omitempty"`
+
+	// exec: environment variables defined in the exec's environment, in
+	// addition to those defined by the runtime (see Env).
+	EnvVars map[string]string `json:",omitempty"`
+
+	// exec: secrets defined in the exec's environment. Each maps an
+	// environment variable to a secret reference (see ParseSecretRef),
+	// whose value is fetched by the executor when the exec starts.
+	// Secret values are never persisted by executors, nor returned by
+	// (Exec).Inspect.
+	Secrets map[string]string `json:",omitempty"`
+
+	// exec: the index of the exec in its task array, and the array's
+	// size (see sched.NewTaskArray). Execs of task arrays are told of
+	// their index and the array's size through their environment (see
+	// Env). ArraySize is zero for execs which are not part of an array.
+	ArrayIndex int `json:",omitempty"`
+	ArraySize  int `json:",omitempty"`
 }
 
 func (e ExecConfig) String() string {
@@ -122,6 +192,10 @@ func (e ExecConfig) String() string {
 		for i, a := range e.Args {
 			if a.Out {
 				args[i] = fmt.Sprintf("out[%d]", a.Index)
+			} else if a.Scratch {
+				args[i] = fmt.Sprintf("scratch[%d]", a.Index)
+			} else if a.Inline {
+				args[i] = fmt.Sprintf("inline[%d]", len(a.Data))
 			} else {
 				args[i] = a.Fileset.Short()
 			}
@@ -129,9 +203,170 @@ func (e ExecConfig) String() string {
 		s += fmt.Sprintf(" image %s cmd %q args [%s]", e.Image, e.Cmd, strings.Join(args, ", "))
 	}
 	s += fmt.Sprintf(" resources %s", e.Resources)
+	if e.Timeout > 0 {
+		s += fmt.Sprintf(" timeout %s", e.Timeout)
+	}
+	if e.Retries > 0 {
+		s += fmt.Sprintf(" retries %d", e.Retries)
+	}
+	if e.Stdin != "" {
+		s += fmt.Sprintf(" stdin[%d]", len(e.Stdin))
+	}
+	if e.Priority != 0 {
+		s += fmt.Sprintf(" priority %d", e.Priority)
+	}
+	if len(e.EnvVars) > 0 {
+		s += fmt.Sprintf(" env[%d]", len(e.EnvVars))
+	}
+	if len(e.Secrets) > 0 {
+		s += fmt.Sprintf(" secrets[%d]", len(e.Secrets))
+	}
+	if e.ArraySize > 0 {
+		s += fmt.Sprintf(" array %d/%d", e.ArrayIndex, e.ArraySize)
+	}
 	return s
 }
 
+// Execs are told of their reserved resources through the following
+// environment variables, so that tools may size themselves (e.g.,
+// their thread pools, or a JVM's heap) to the actual reservation,
+// which may differ from the one requested by the program.
+const (
+	// ExecCPUEnv is the number of reserved CPUs, rounded up to a
+	// whole number.
+	ExecCPUEnv = "REFLOW_CPU"
+	// ExecMemEnv is the amount of reserved memory, in bytes.
+	ExecMemEnv = "REFLOW_MEM"
+	// ExecDiskEnv is the amount of reserved disk space, in bytes.
+	ExecDiskEnv = "REFLOW_DISK"
+	// ExecProgressEnv is the path of the file to which an exec may
+	// report its progress (see ExecProgressPath).
+	ExecProgressEnv = "REFLOW_PROGRESS"
+	// ExecArrayIndexEnv and ExecArraySizeEnv are the index of the
+	// exec in its task array, and the array's size; they are defined
+	// only for the execs of task arrays.
+	ExecArrayIndexEnv = "REFLOW_ARRAY_INDEX"
+	ExecArraySizeEnv  = "REFLOW_ARRAY_SIZE"
+)
+
+// ExecProgressPath is the path, inside of an exec's sandbox, of the
+// file to which the exec may report its progress, either as a
+// percentage (e.g., "42" or "42%") or as a fraction of work done
+// (e.g., "21/50"). The file is read periodically while the exec
+// runs; the last value written is reported as the exec's "progress"
+// gauge.
+const ExecProgressPath = "/tmp/.reflow_progress"
+
+// ProgressGauge is the name of the gauge which reports an exec's
+// progress, in percent.
+const ProgressGauge = "progress"
+
+// Env returns the environment variables, as "key=value" strings,
+// which tell an exec of its reserved resources, of where to report
+// its progress, and of its index in its task array, if any.
+func (e ExecConfig) Env() []string {
+	cpu := math.Ceil(e.Resources["cpu"])
+	if cpu < 1 {
+		cpu = 1
+	}
+	env := []string{
+		fmt.Sprintf("%s=%d", ExecCPUEnv, int64(cpu)),
+		fmt.Sprintf("%s=%d", ExecMemEnv, int64(e.Resources["mem"])),
+		fmt.Sprintf("%s=%d", ExecDiskEnv, int64(e.Resources["disk"])),
+		ExecProgressEnv + "=" + ExecProgressPath,
+	}
+	if e.ArraySize > 0 {
+		env = append(env,
+			fmt.Sprintf("%s=%d", ExecArrayIndexEnv, e.ArrayIndex),
+			fmt.Sprintf("%s=%d", ExecArraySizeEnv, e.ArraySize))
+	}
+	return env
+}
+
+// CheckEnvName returns an error if name may not be the name of an
+// environment variable defined by an exec (through EnvVars or
+// Secrets): names must be shell identifiers, and may not override
+// the variables defined by the runtime.
+func CheckEnvName(name string) error {
+	if name == "" {
+		return errors.E(errors.Invalid, errors.New("empty environment variable name"))
+	}
+	for i, r := range name {
+		if r == '_' || 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || i > 0 && '0' <= r && r <= '9' {
+			continue
+		}
+		return errors.E(errors.Invalid, errors.Errorf("invalid environment variable name %q", name))
+	}
+	switch {
+	case strings.HasPrefix(name, "REFLOW_"):
+	case name == "tmp", name == "TMPDIR", name == "HOME", name == "out":
+	case name == "AWS_ACCESS_KEY_ID", name == "AWS_SECRET_ACCESS_KEY", name == "AWS_SESSION_TOKEN":
+	default:
+		return nil
+	}
+	return errors.E(errors.Invalid, errors.Errorf("environment variable %s is defined by the runtime", name))
+}
+
+// Services which store the secrets referenced by execs.
+const (
+	// SecretsManager is AWS Secrets Manager.
+	SecretsManager = "secretsmanager"
+	// SSM is the AWS Systems Manager Parameter Store.
+	SSM = "ssm"
+)
+
+// SecretRef is a reference to a secret.
+type SecretRef struct {
+	// Service is the service which stores the secret: SecretsManager
+	// or SSM.
+	Service string
+	// Name is the name (or ARN) of the secret or parameter.
+	Name string
+	// Key, if set, is the key of the value to use, in a secret whose
+	// value is a JSON object. Keys are supported only by
+	// SecretsManager.
+	Key string
+}
+
+// ParseSecretRef parses a secret reference, of the form
+// "secretsmanager:name[#key]" or "ssm:name".
+func ParseSecretRef(ref string) (SecretRef, error) {
+	parts := strings.SplitN(ref, ":", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return SecretRef{}, errors.E(errors.Invalid, errors.Errorf("invalid secret reference %q: expected service:name", ref))
+	}
+	r := SecretRef{Service: parts[0], Name: parts[1]}
+	switch r.Service {
+	case SecretsManager:
+		if i := strings.LastIndex(r.Name, "#"); i >= 0 {
+			r.Name, r.Key = r.Name[:i], r.Name[i+1:]
+			if r.Name == "" || r.Key == "" {
+				return SecretRef{}, errors.E(errors.Invalid, errors.Errorf("invalid secret reference %q", ref))
+			}
+		}
+	case SSM:
+	default:
+		return SecretRef{}, errors.E(errors.Invalid, errors.Errorf("invalid secret reference %q: unknown service %s", ref, r.Service))
+	}
+	return r, nil
+}
+
+// String returns the reference in the form parsed by ParseSecretRef.
+func (r SecretRef) String() string {
+	if r.Key != "" {
+		return r.Service + ":" + r.Name + "#" + r.Key
+	}
+	return r.Service + ":" + r.Name
+}
+
+// ImagePullProfile is the key of the profile entry which reports the
+// time an exec spent ensuring that its image was present before it
+// started, i.e., pulling the image or waiting for a concurrent pull
+// (e.g., a prefetch; see ImagePrefetcher). The entry's First and Last
+// times bound the pull, and its Max and Mean are its duration in
+// seconds. The pull is not part of the exec's runtime.
+const ImagePullProfile = "pull"
+
 // Profile stores keyed statistical summaries (currently: mean, max, N).
 type Profile map[string]struct {
 	Max, Mean, Var float64
@@ -239,6 +474,7 @@ type RemoteLogsType string
 const (
 	RemoteLogsTypeUnknown    RemoteLogsType = "Unknown"
 	RemoteLogsTypeCloudwatch RemoteLogsType = "cloudwatch"
+	RemoteLogsTypeBlob       RemoteLogsType = "blob"
 )
 
 // RemoteLogs is a description of remote logs primarily useful for storing a reference.
@@ -251,6 +487,8 @@ type RemoteLogs struct {
 	LogGroupName string
 	// LogStreamName is the log stream name (applicable if Type is 'Cloudwatch')
 	LogStreamName string
+	// URL is the URL of the log object (applicable if Type is 'Blob')
+	URL string `json:",omitempty"`
 }
 
 // InspectResponse is the value returned by a call to an exec's Inspect. Either Inspect or RunInfo will be populated,
@@ -263,6 +501,9 @@ type InspectResponse struct {
 }
 
 func (r RemoteLogs) String() string {
+	if r.Type == RemoteLogsTypeBlob {
+		return fmt.Sprintf("(%s) URL: %s", r.Type, r.URL)
+	}
 	return fmt.Sprintf("(%s) LogGroupName: %s, LogStreamName: %s", r.Type, r.LogGroupName, r.LogStreamName)
 }
 
@@ -348,3 +589,13 @@ type Executor interface {
 	// Repository returns the Repository associated with this Executor.
 	Repository() Repository
 }
+
+// ImagePrefetcher is implemented by executors which can fetch container
+// images ahead of the execs which need them, so that the execs need not
+// wait for their images to be pulled.
+type ImagePrefetcher interface {
+	// PrefetchImages begins fetching the given images, if they are not
+	// already present. It does not wait for the fetches to complete:
+	// execs which need an image being fetched wait for it instead.
+	PrefetchImages(ctx context.Context, images []string) error
+}
@@ -5,6 +5,7 @@
 package reflow_test
 
 import (
+	"reflect"
 	"testing"
 	"time"
 
@@ -40,3 +41,64 @@ func TestRuntime(t *testing.T) {
 		}
 	}
 }
+
+func TestExecConfigEnv(t *testing.T) {
+	cfg := reflow.ExecConfig{Resources: reflow.Resources{"cpu": 1.5, "mem": 4 << 30, "disk": 10 << 30}}
+	want := []string{"REFLOW_CPU=2", "REFLOW_MEM=4294967296", "REFLOW_DISK=10737418240", "REFLOW_PROGRESS=/tmp/.reflow_progress"}
+	if got := cfg.Env(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := (reflow.ExecConfig{}).Env()[0], "REFLOW_CPU=1"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	cfg.ArrayIndex, cfg.ArraySize = 3, 8
+	want = append(want, "REFLOW_ARRAY_INDEX=3", "REFLOW_ARRAY_SIZE=8")
+	if got := cfg.Env(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestParseSecretRef(t *testing.T) {
+	for _, c := range []struct {
+		ref  string
+		want reflow.SecretRef
+	}{
+		{"ssm:/prod/db/password", reflow.SecretRef{Service: reflow.SSM, Name: "/prod/db/password"}},
+		{"secretsmanager:prod/db", reflow.SecretRef{Service: reflow.SecretsManager, Name: "prod/db"}},
+		{"secretsmanager:prod/db#password", reflow.SecretRef{Service: reflow.SecretsManager, Name: "prod/db", Key: "password"}},
+		{
+			"secretsmanager:arn:aws:secretsmanager:us-west-2:123456789012:secret:prod/db-AbCdEf#user",
+			reflow.SecretRef{Service: reflow.SecretsManager, Name: "arn:aws:secretsmanager:us-west-2:123456789012:secret:prod/db-AbCdEf", Key: "user"},
+		},
+	} {
+		got, err := reflow.ParseSecretRef(c.ref)
+		if err != nil {
+			t.Errorf("%s: %v", c.ref, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("got %v, want %v", got, c.want)
+		}
+		if got, want := got.String(), c.ref; got != want {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	}
+	for _, ref := range []string{"", "ssm", "ssm:", "vault:secret", "secretsmanager:db#", "secretsmanager:#key"} {
+		if _, err := reflow.ParseSecretRef(ref); err == nil {
+			t.Errorf("%q: expected error", ref)
+		}
+	}
+}
+
+func TestCheckEnvName(t *testing.T) {
+	for _, name := range []string{"FOO", "_foo", "foo_2"} {
+		if err := reflow.CheckEnvName(name); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "2FOO", "FOO-BAR", "FOO=BAR", "REFLOW_CPU", "HOME", "AWS_SECRET_ACCESS_KEY"} {
+		if err := reflow.CheckEnvName(name); err == nil {
+			t.Errorf("%q: expected error", name)
+		}
+	}
+}
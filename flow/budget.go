@@ -0,0 +1,96 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package flow
+
+import (
+	"fmt"
+
+	"github.com/grailbio/reflow/errors"
+	"github.com/grailbio/reflow/sched"
+)
+
+// MaxCostPolicy determines how the evaluator handles a run whose
+// accumulated cost exceeds EvalConfig.MaxCost.
+type MaxCostPolicy int
+
+const (
+	// MaxCostAbort fails the evaluation as soon as the cost is exceeded,
+	// canceling any running tasks.
+	MaxCostAbort MaxCostPolicy = iota
+	// MaxCostPause stops submitting new tasks once the cost is exceeded;
+	// tasks which are already running are allowed to complete (so that
+	// their results are cached) before the evaluation fails.
+	MaxCostPause
+)
+
+// String returns the name of the policy, as accepted by ParseMaxCostPolicy.
+func (p MaxCostPolicy) String() string {
+	switch p {
+	case MaxCostAbort:
+		return "abort"
+	case MaxCostPause:
+		return "pause"
+	default:
+		return fmt.Sprintf("MaxCostPolicy(%d)", p)
+	}
+}
+
+// ParseMaxCostPolicy parses a MaxCostPolicy from its name.
+func ParseMaxCostPolicy(s string) (MaxCostPolicy, error) {
+	switch s {
+	case "abort":
+		return MaxCostAbort, nil
+	case "pause":
+		return MaxCostPause, nil
+	default:
+		return MaxCostAbort, fmt.Errorf("unknown max cost policy %q (must be one of \"abort\", \"pause\")", s)
+	}
+}
+
+// CostUSD returns the accumulated estimated cost (in USD) of the
+// tasks completed by this evaluation.
+func (e *Eval) CostUSD() float64 {
+	e.costMu.Lock()
+	defer e.costMu.Unlock()
+	return e.costUSD
+}
+
+// addCost accounts the cost of the given (completed) task. It
+// returns a BudgetExceeded error if the evaluation's cost now
+// exceeds its budget and the evaluation should be aborted.
+func (e *Eval) addCost(task *sched.Task) error {
+	cost := task.CostUSD()
+	if cost == 0 {
+		return nil
+	}
+	e.costMu.Lock()
+	before := e.costUSD
+	e.costUSD += cost
+	after := e.costUSD
+	e.costMu.Unlock()
+	if e.MaxCost <= 0 || after <= e.MaxCost {
+		return nil
+	}
+	if before <= e.MaxCost {
+		e.Log.Printf("estimated cost $%.2f exceeds the maximum cost $%.2f: %s", after, e.MaxCost, e.MaxCostPolicy)
+	}
+	if e.MaxCostPolicy == MaxCostAbort {
+		return e.budgetErr()
+	}
+	return nil
+}
+
+// budgetErr returns a BudgetExceeded error if the evaluation's cost
+// exceeds its budget.
+func (e *Eval) budgetErr() *errors.Error {
+	if e.MaxCost <= 0 {
+		return nil
+	}
+	if cost := e.CostUSD(); cost > e.MaxCost {
+		return errors.Recover(errors.E("eval", e.RunID.ID(), errors.BudgetExceeded,
+			fmt.Errorf("estimated cost $%.2f exceeds the maximum cost $%.2f", cost, e.MaxCost)))
+	}
+	return nil
+}
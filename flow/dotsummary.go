@@ -0,0 +1,298 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package flow
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"gonum.org/v1/gonum/graph"
+	"gonum.org/v1/gonum/graph/encoding"
+	"gonum.org/v1/gonum/graph/simple"
+)
+
+// DefaultDotMaxNodes is the number of nodes above which the fan-outs
+// of a flowgraph are collapsed before it is written in dot format,
+// unless configured otherwise (see DotOptions.MaxNodes). Graphviz
+// cannot practically lay out much larger graphs.
+const DefaultDotMaxNodes = 10000
+
+// DotOptions configures the summarization of flowgraphs written in
+// dot format, so that graphs of large evaluations (with, e.g., 100k
+// nodes) remain renderable. Summarization is applied in order: the
+// depth limit first, then the identifier filter, and finally the
+// collapsing of fan-outs.
+type DotOptions struct {
+	// Depth, if positive, limits the graph to nodes at most Depth-1
+	// dependencies away from the graph's roots. Nodes whose
+	// dependencies are elided are drawn with a double outline.
+	Depth int
+
+	// Ident, if non-nil, limits the graph to nodes whose identifiers
+	// it matches. Each remaining node is connected to the remaining
+	// nodes on which it (transitively) depends.
+	Ident *regexp.Regexp
+
+	// Collapse collapses fan-outs (e.g., of maps): the dependencies of
+	// a node (or meta-node) which share an operation, identifier and
+	// source position are drawn as a single meta-node annotated with
+	// their count, as are their own dependencies, recursively.
+	Collapse bool
+
+	// MaxNodes is the number of nodes above which fan-outs are
+	// collapsed even if Collapse is false. If zero, DefaultDotMaxNodes
+	// is used; if negative, fan-outs are collapsed only if Collapse
+	// is true.
+	MaxNodes int
+}
+
+// summarize returns the summary of flowgraph g as configured by o.
+// If no summarization is required, g is returned as is.
+func (o DotOptions) summarize(g graph.Directed) graph.Directed {
+	maxNodes := o.MaxNodes
+	if maxNodes == 0 {
+		maxNodes = DefaultDotMaxNodes
+	}
+	collapse := o.Collapse || (maxNodes > 0 && g.Nodes().Len() > maxNodes)
+	if o.Depth <= 0 && o.Ident == nil && !collapse {
+		return g
+	}
+	s := summaryGraph(g)
+	if o.Depth > 0 {
+		s = limitDepth(s, o.Depth)
+	}
+	if o.Ident != nil {
+		s = filterIdent(s, o.Ident)
+	}
+	if collapse {
+		s = collapseFanouts(s)
+	}
+	return s
+}
+
+// summaryNode is a node in a summarized flowgraph: either a flow
+// node, or a meta-node which stands in for count like flow nodes.
+type summaryNode struct {
+	Node
+	// count is the number of flow nodes represented by this node.
+	count int
+	// truncated tells whether the node's dependencies were elided.
+	truncated bool
+}
+
+// DOTID implements dot.Node.
+func (n *summaryNode) DOTID() string {
+	if n.count > 1 {
+		return fmt.Sprintf("%v-x%d", n.Node.DOTID(), n.count)
+	}
+	return n.Node.DOTID()
+}
+
+// Attributes implements encoding.Attributer.
+func (n *summaryNode) Attributes() []encoding.Attribute {
+	attrs := n.Node.Attributes()
+	if n.count > 1 {
+		attrs = append(attrs,
+			encoding.Attribute{Key: "count", Value: fmt.Sprint(n.count)},
+			encoding.Attribute{Key: "shape", Value: "box3d"},
+		)
+	}
+	if n.truncated {
+		attrs = append(attrs,
+			encoding.Attribute{Key: "truncated", Value: "true"},
+			encoding.Attribute{Key: "peripheries", Value: "2"},
+		)
+	}
+	return attrs
+}
+
+// fanoutKey identifies like nodes, which are collapsed together.
+type fanoutKey struct {
+	op              Op
+	ident, position string
+}
+
+// summaryGraph returns a copy of flowgraph g whose nodes are
+// summaryNodes.
+func summaryGraph(g graph.Directed) *simple.DirectedGraph {
+	s := simple.NewDirectedGraph()
+	for nodes := g.Nodes(); nodes.Next(); {
+		s.AddNode(&summaryNode{Node: nodes.Node().(Node), count: 1})
+	}
+	for nodes := g.Nodes(); nodes.Next(); {
+		u := s.Node(nodes.Node().ID())
+		for deps := g.From(u.ID()); deps.Next(); {
+			v := s.Node(deps.Node().ID())
+			s.SetEdge(Edge{Edge: s.NewEdge(u, v), dynamic: edgeDynamic(g, u, v)})
+		}
+	}
+	return s
+}
+
+// limitDepth returns the subgraph of g of the nodes at most depth-1
+// dependencies away from its roots.
+func limitDepth(g *simple.DirectedGraph, depth int) *simple.DirectedGraph {
+	s := simple.NewDirectedGraph()
+	level := graphRoots(g)
+	for _, n := range level {
+		s.AddNode(n)
+	}
+	for d := 1; len(level) > 0; d++ {
+		var next []graph.Node
+		for _, u := range level {
+			for _, v := range sortedDeps(g, u) {
+				switch {
+				case s.Node(v.ID()) != nil:
+				case d < depth:
+					s.AddNode(v)
+					next = append(next, v)
+				default:
+					u.(*summaryNode).truncated = true
+					continue
+				}
+				s.SetEdge(g.Edge(u.ID(), v.ID()))
+			}
+		}
+		level = next
+	}
+	return s
+}
+
+// filterIdent returns the graph of the nodes of g whose identifiers
+// match re, where each node is connected to the matching nodes on
+// which it depends, directly or through nodes which do not match.
+func filterIdent(g *simple.DirectedGraph, re *regexp.Regexp) *simple.DirectedGraph {
+	var (
+		s    = simple.NewDirectedGraph()
+		keep = func(n graph.Node) bool { return re.MatchString(n.(*summaryNode).Ident) }
+	)
+	for nodes := g.Nodes(); nodes.Next(); {
+		if keep(nodes.Node()) {
+			s.AddNode(nodes.Node())
+		}
+	}
+	for nodes := s.Nodes(); nodes.Next(); {
+		u := nodes.Node()
+		visited := make(map[int64]bool)
+		var walk func(n graph.Node, dynamic bool)
+		walk = func(n graph.Node, dynamic bool) {
+			for deps := g.From(n.ID()); deps.Next(); {
+				v := deps.Node()
+				dynamic := dynamic || edgeDynamic(g, n, v)
+				switch {
+				case keep(v):
+					if e := s.Edge(u.ID(), v.ID()); e == nil || (dynamic && !e.(Edge).dynamic) {
+						s.SetEdge(Edge{Edge: s.NewEdge(u, v), dynamic: dynamic})
+					}
+				case !visited[v.ID()]:
+					visited[v.ID()] = true
+					walk(v, dynamic)
+				}
+			}
+		}
+		walk(u, false)
+	}
+	return s
+}
+
+// collapseFanouts returns the graph g in which the like dependencies
+// of each node are collapsed into meta-nodes. Starting from the
+// roots of g, the dependencies of the members of each (meta-)node
+// which were not yet collapsed are grouped by fanoutKey, and each
+// group becomes a meta-node in turn.
+func collapseFanouts(g *simple.DirectedGraph) *simple.DirectedGraph {
+	var (
+		s       = simple.NewDirectedGraph()
+		classOf = make(map[int64]*summaryNode)
+		queue   [][]graph.Node
+	)
+	newClass := func(members []graph.Node) *summaryNode {
+		rep := members[0].(*summaryNode)
+		n := &summaryNode{Node: rep.Node}
+		for _, m := range members {
+			m := m.(*summaryNode)
+			n.count += m.count
+			n.truncated = n.truncated || m.truncated
+			classOf[m.ID()] = n
+		}
+		s.AddNode(n)
+		queue = append(queue, members)
+		return n
+	}
+	link := func(u, v *summaryNode, dynamic bool) {
+		if u == v {
+			return
+		}
+		if e := s.Edge(u.ID(), v.ID()); e != nil {
+			dynamic = dynamic || e.(Edge).dynamic
+		}
+		s.SetEdge(Edge{Edge: s.NewEdge(u, v), dynamic: dynamic})
+	}
+	for _, n := range graphRoots(g) {
+		newClass([]graph.Node{n})
+	}
+	for len(queue) > 0 {
+		members := queue[0]
+		queue = queue[1:]
+		u := classOf[members[0].ID()]
+		var (
+			keys    []fanoutKey
+			groups  = make(map[fanoutKey][]graph.Node)
+			dynamic = make(map[fanoutKey]bool)
+			grouped = make(map[int64]bool)
+		)
+		for _, m := range members {
+			for _, v := range sortedDeps(g, m) {
+				if c := classOf[v.ID()]; c != nil {
+					link(u, c, edgeDynamic(g, m, v))
+					continue
+				}
+				n := v.(*summaryNode)
+				key := fanoutKey{n.Op, n.Ident, n.Position}
+				if !grouped[v.ID()] {
+					if _, ok := groups[key]; !ok {
+						keys = append(keys, key)
+					}
+					groups[key] = append(groups[key], v)
+					grouped[v.ID()] = true
+				}
+				dynamic[key] = dynamic[key] || edgeDynamic(g, m, v)
+			}
+		}
+		for _, key := range keys {
+			link(u, newClass(groups[key]), dynamic[key])
+		}
+	}
+	return s
+}
+
+// graphRoots returns the nodes of g on which no other node depends,
+// ordered by ID.
+func graphRoots(g graph.Directed) []graph.Node {
+	var roots []graph.Node
+	for nodes := g.Nodes(); nodes.Next(); {
+		if g.To(nodes.Node().ID()).Len() == 0 {
+			roots = append(roots, nodes.Node())
+		}
+	}
+	sort.Slice(roots, func(i, j int) bool { return roots[i].ID() < roots[j].ID() })
+	return roots
+}
+
+// sortedDeps returns the dependencies of node n in g, ordered by ID,
+// so that summaries are deterministic.
+func sortedDeps(g graph.Directed, n graph.Node) []graph.Node {
+	deps := graph.NodesOf(g.From(n.ID()))
+	sort.Slice(deps, func(i, j int) bool { return deps[i].ID() < deps[j].ID() })
+	return deps
+}
+
+// edgeDynamic tells whether the edge from u to v in g was
+// dynamically explored.
+func edgeDynamic(g graph.Directed, u, v graph.Node) bool {
+	e, ok := g.Edge(u.ID(), v.ID()).(Edge)
+	return ok && e.dynamic
+}
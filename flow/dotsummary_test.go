@@ -0,0 +1,85 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package flow_test
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/grailbio/reflow/flow"
+	op "github.com/grailbio/reflow/test/flow"
+	"github.com/grailbio/reflow/test/testutil"
+	"gonum.org/v1/gonum/graph"
+)
+
+// fanout returns a flow which merges n execs, each of which depends
+// on its own intern.
+func fanout(n int) *flow.Flow {
+	execs := make([]*flow.Flow, n)
+	for i := range execs {
+		in := op.Intern(fmt.Sprintf("s3://bucket/sample%d", i))
+		in.Ident = "input"
+		execs[i] = op.Exec("image", fmt.Sprintf("align %d", i), testutil.Resources, in)
+		execs[i].Ident = "align"
+	}
+	merge := op.Merge(execs...)
+	merge.Ident = "merge"
+	return merge
+}
+
+func dotIDs(g graph.Graph) []string {
+	var ids []string
+	for nodes := g.Nodes(); nodes.Next(); {
+		ids = append(ids, nodes.Node().(interface{ DOTID() string }).DOTID())
+	}
+	return ids
+}
+
+func TestSummarizeFlowgraph(t *testing.T) {
+	f := fanout(50)
+	for _, tt := range []struct {
+		opts  flow.DotOptions
+		nodes int
+		// counts are the suffixes of the meta-nodes' DOT IDs.
+		counts []string
+	}{
+		{flow.DotOptions{}, 101, nil},
+		{flow.DotOptions{Collapse: true}, 3, []string{"-x50"}},
+		{flow.DotOptions{MaxNodes: 100}, 3, []string{"-x50"}},
+		{flow.DotOptions{MaxNodes: -1}, 101, nil},
+		{flow.DotOptions{Depth: 2}, 51, nil},
+		{flow.DotOptions{Depth: 2, Collapse: true}, 2, []string{"-x50"}},
+		{flow.DotOptions{Ident: regexp.MustCompile("input")}, 50, nil},
+		{flow.DotOptions{Ident: regexp.MustCompile("input"), Collapse: true}, 50, nil},
+	} {
+		g := flow.SummarizeFlowgraph(f, tt.opts)
+		if got, want := g.Nodes().Len(), tt.nodes; got != want {
+			t.Errorf("%+v: got %v nodes, want %v", tt.opts, got, want)
+		}
+		var counts []string
+		for _, id := range dotIDs(g) {
+			if i := strings.LastIndex(id, "-x"); i >= 0 {
+				counts = append(counts, id[i:])
+			}
+		}
+		if got, want := strings.Join(counts, ","), strings.Join(tt.counts, ","); got != want {
+			t.Errorf("%+v: got meta-nodes %v, want %v", tt.opts, got, want)
+		}
+	}
+}
+
+func TestSummarizeFlowgraphIdentEdges(t *testing.T) {
+	f := fanout(10)
+	g := flow.SummarizeFlowgraph(f, flow.DotOptions{Ident: regexp.MustCompile("^(merge|input)$")})
+	// The merge is connected directly to the interns.
+	if got, want := g.Nodes().Len(), 11; got != want {
+		t.Fatalf("got %v nodes, want %v", got, want)
+	}
+	if got, want := g.From(flow.Node{Flow: f}.ID()).Len(), 10; got != want {
+		t.Errorf("got %v dependencies, want %v", got, want)
+	}
+}
@@ -0,0 +1,177 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package flow
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/grailbio/base/digest"
+	"github.com/grailbio/reflow"
+	"github.com/grailbio/reflow/errors"
+)
+
+// dryRunOp is the op of the errors with which nodes which would run
+// fail in dry runs. The error's first argument is the node's name.
+const dryRunOp = "dryrun"
+
+// A DryRunExec describes an exec, intern or extern which would run in
+// a dry-run evaluation (see EvalConfig.DryRun).
+type DryRunExec struct {
+	// Ident is the identifier of the node.
+	Ident string
+	// Position is the source position of the node.
+	Position string
+	// Op is the node's operation.
+	Op string
+	// FlowID is the digest of the node.
+	FlowID digest.Digest
+	// Resources are the resources requested by the node.
+	Resources reflow.Resources
+	// URL is the URL of an intern or extern.
+	URL string
+	// Image is the image of an exec.
+	Image string
+	// Reason explains why the node would run: why its cache lookup
+	// missed (if it was looked up), and which of the nodes on which it
+	// depends would run.
+	Reason string
+}
+
+// A DryRunReport is the outcome of a dry-run evaluation.
+type DryRunReport struct {
+	// Execs are the execs, interns and externs which would run,
+	// ordered by ident.
+	Execs []DryRunExec
+	// Unexpanded is the number of maps and continuations which could
+	// not be expanded because they depend on nodes which would run.
+	// The execs which they would produce are not included in Execs.
+	Unexpanded int
+}
+
+// DryRunReport returns the report of a dry-run evaluation. It should
+// be called only after the evaluation is complete.
+func (e *Eval) DryRunReport() DryRunReport {
+	var r DryRunReport
+	e.dryRunMu.Lock()
+	r.Execs = append(r.Execs, e.wouldRun...)
+	e.dryRunMu.Unlock()
+	sort.Slice(r.Execs, func(i, j int) bool {
+		if r.Execs[i].Ident != r.Execs[j].Ident {
+			return r.Execs[i].Ident < r.Execs[j].Ident
+		}
+		return r.Execs[i].Position < r.Execs[j].Position
+	})
+	for v := e.root.Visitor(); v.Walk(); v.Visit() {
+		if v.Parent != nil {
+			v.Push(v.Parent)
+		}
+		switch v.Op {
+		case Map, K, Kctx:
+			if v.State == Done && isDryRun(v.Err) {
+				r.Unexpanded++
+			}
+		}
+	}
+	return r
+}
+
+// Prewarm returns a flow which interns the URLs of the interns which
+// would run, and the distinct images of the execs which would run,
+// ordered by ident. Interning the URLs (e.g., with Eval) ahead of the
+// run caches their results, so that the run does not need to intern
+// them. Prewarm returns a nil flow if no interns would run.
+func (r DryRunReport) Prewarm() (*Flow, []string, error) {
+	var (
+		interns []*Flow
+		images  []string
+		seen    = make(map[string]bool)
+	)
+	for _, x := range r.Execs {
+		switch x.Op {
+		case Intern.String():
+			u, err := url.Parse(x.URL)
+			if err != nil {
+				return nil, nil, errors.E("prewarm", x.Ident, err)
+			}
+			interns = append(interns, &Flow{Op: Intern, Ident: x.Ident, Position: x.Position, URL: u, MustIntern: true})
+		case Exec.String():
+			if x.Image != "" && !seen[x.Image] {
+				seen[x.Image] = true
+				images = append(images, x.Image)
+			}
+		}
+	}
+	if len(interns) == 0 {
+		return nil, images, nil
+	}
+	return &Flow{Op: Merge, Deps: interns}, images, nil
+}
+
+// IsDryRun tells whether err is the error of an evaluation which
+// completed as far as it could in a dry run, i.e., whose root depends
+// on nodes which would run.
+func IsDryRun(err error) bool {
+	if err == nil {
+		return false
+	}
+	return isDryRun(errors.Recover(err))
+}
+
+func isDryRun(err *errors.Error) bool {
+	return err != nil && err.Op == dryRunOp && len(err.Arg) > 0
+}
+
+// wouldRunErr records that node f would run, and returns the dry-run
+// error with which it fails.
+func (e *Eval) wouldRunErr(f *Flow) *errors.Error {
+	e.dryRunMu.Lock()
+	defer e.dryRunMu.Unlock()
+	var reasons []string
+	if reason, ok := e.missReasons[f]; ok {
+		reasons = append(reasons, reason)
+	} else if !e.cacheMode(f).Reading() {
+		reasons = append(reasons, "cache reads are disabled")
+	} else if e.dirty(f) {
+		reasons = append(reasons, "depends on an extern, whose results are not cached")
+	}
+	var (
+		deps []string
+		seen = make(map[string]bool)
+	)
+	for _, dep := range f.Deps {
+		if isDryRun(dep.Err) && !seen[dep.Err.Arg[0]] {
+			seen[dep.Err.Arg[0]] = true
+			deps = append(deps, dep.Err.Arg[0])
+		}
+	}
+	if len(deps) > 0 {
+		reasons = append(reasons, fmt.Sprintf("depends on %s, which would run", strings.Join(deps, ", ")))
+	}
+	if len(reasons) == 0 {
+		reasons = append(reasons, "not looked up in the cache")
+	}
+	reason := strings.Join(reasons, "; ")
+	x := DryRunExec{
+		Ident:     f.Ident,
+		Position:  f.Position,
+		Op:        f.Op.String(),
+		FlowID:    f.Digest(),
+		Resources: f.Resources,
+		Reason:    reason,
+		Image:     f.Image,
+	}
+	if f.URL != nil {
+		x.URL = f.URL.String()
+	}
+	e.wouldRun = append(e.wouldRun, x)
+	name := f.Ident
+	if name == "" {
+		name = f.Op.String()
+	}
+	return errors.Recover(errors.E(dryRunOp, name, errors.New("would run: "+reason)))
+}
@@ -12,8 +12,11 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"math"
+	"math/bits"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"text/tabwriter"
@@ -54,11 +57,11 @@ const (
 	// maxTaskRetries is the maximum number of times a task can be retried due to any retryable errors.
 	maxTaskRetries = 3
 
-	// memMultiplier is the increase in memory that will be allocated to a task which OOMs.
-	memMultiplier = 1.5
+	// DefaultOOMMemMultiplier is the default increase in memory that will be allocated to a task which OOMs.
+	DefaultOOMMemMultiplier = 1.5
 
-	// oomRetryMaxExecMemory is max memory allowed for execs being retried due to OOM.
-	oomRetryMaxExecMemory = 800 << 30
+	// DefaultOOMMaxMemory is the default max memory allowed for execs being retried due to OOM.
+	DefaultOOMMaxMemory = 800 << 30
 
 	// memSuggestThreshold is the minimum fraction of allocated memory an exec can use before a suggestion is
 	// displayed to use less memory.
@@ -119,6 +122,10 @@ type EvalConfig struct {
 	// DotWriter is an (optional) writer where the evaluator will write the flowgraph to in dot format.
 	DotWriter io.Writer
 
+	// DotOptions configures the summarization of the flowgraph written
+	// to DotWriter.
+	DotOptions DotOptions
+
 	// Status gets evaluation status reports.
 	Status *status.Group
 
@@ -128,6 +135,11 @@ type EvalConfig struct {
 	// Repository is the main, shared repository between evaluations.
 	Repository reflow.Repository
 
+	// RepositoryIndex is an (optional) presence index of Repository.
+	// Files known to be present in the index are not checked for when
+	// looking up or writing cached results.
+	RepositoryIndex repository.PresenceIndex
+
 	// Assoc is the main, shared assoc that is used to store cache and
 	// metadata associations.
 	Assoc assoc.Assoc
@@ -166,8 +178,19 @@ type EvalConfig struct {
 	// ImageMap stores the canonical names of the images.
 	// A canonical name has a fully qualified registry host,
 	// and image digest instead of image tag.
+	//
+	// The results of execs whose images are resolved carry assertions
+	// (in the reflow.DockerAssertionsNamespace) of the image digests to
+	// which they resolved, so that cached results are not reused once
+	// a mutable tag (e.g., "latest") resolves to a different image.
+	// AssertionGenerator must then support the namespace.
 	ImageMap map[string]string
 
+	// Arm64ImageMap stores the canonical names of the images for
+	// the arm64 platform, used for execs which require arm64. Images
+	// which have no arm64 variant are absent from the map.
+	Arm64ImageMap map[string]string
+
 	// CacheLookupTimeout is the timeout for cache lookups.
 	// After the timeout expires, a cache lookup is considered
 	// a miss.
@@ -179,6 +202,62 @@ type EvalConfig struct {
 
 	// Labels is the labels for this run.
 	Labels pool.Labels
+
+	// OOMMemMultiplier is the factor by which the memory of an exec
+	// which fails due to an OOM is increased when it is retried.
+	// If zero, DefaultOOMMemMultiplier is used.
+	OOMMemMultiplier float64
+
+	// OOMMaxMemory is the maximum amount of memory (in bytes) to which
+	// the memory of an exec retried due to an OOM is increased.
+	// If zero, DefaultOOMMaxMemory is used.
+	OOMMaxMemory float64
+
+	// MaxCost is the maximum estimated cost (in USD) of the tasks run
+	// by this evaluation. If nonzero, the evaluation is aborted or
+	// paused (as determined by MaxCostPolicy) once the accumulated
+	// cost of its tasks exceeds MaxCost. Task costs are known only if
+	// the scheduler's cluster implements sched.Pricer.
+	MaxCost float64
+
+	// MaxCostPolicy determines what happens when MaxCost is exceeded.
+	MaxCostPolicy MaxCostPolicy
+
+	// CacheNamespace qualifies the cache keys of this evaluation (see
+	// NamespaceKey): evaluations read and write only those cache
+	// entries written in the same namespace. Evaluations (e.g., of
+	// different teams or pipeline versions) may thus share a cluster
+	// and a cache while keeping their results isolated; they share
+	// results explicitly by using the same namespace. If empty, the
+	// default namespace is used.
+	CacheNamespace string
+
+	// Frontier, if non-nil, records the results of the execs, interns
+	// and externs completed by the evaluation, and supplies those
+	// recorded by a previous (interrupted) evaluation of the same
+	// flow, so that it may be resumed without recomputing them.
+	// Results are recorded in Repository.
+	Frontier Frontier
+
+	// Graph, if non-nil, is updated with snapshots of the evaluation
+	// graph while it is evaluated, so that it may be inspected live.
+	Graph *GraphServer
+
+	// MaxFanout is the maximum number of dependencies of a single node
+	// in the flow graph, i.e., the maximum number of tasks that a single
+	// map, comprehension or flatten may generate. Nodes that exceed it
+	// fail with an evaluation error, so that malformed inputs (e.g.,
+	// a sample sheet with millions of rows) do not result in millions
+	// of submitted tasks. If zero, fan-out is not limited.
+	MaxFanout int
+
+	// DryRun, if set, evaluates the flow without running any tasks:
+	// results are looked up in the cache as usual, but the execs (as
+	// well as interns and externs) that miss the cache are not
+	// submitted. Instead, they fail with a dry-run error and are
+	// reported, together with the reason each would run, by
+	// DryRunReport. Nothing is written to the cache in a dry run.
+	DryRun bool
 }
 
 // String returns a human-readable form of the evaluation configuration.
@@ -233,13 +312,31 @@ func (e EvalConfig) String() string {
 	if e.PostUseChecksum {
 		flags = append(flags, "postusechecksum")
 	}
+	if e.DryRun {
+		flags = append(flags, "dryrun")
+	}
 	fmt.Fprintf(&b, " flags %s", strings.Join(flags, ","))
 	fmt.Fprintf(&b, " flowconfig %s", e.Config)
 	fmt.Fprintf(&b, " cachelookuptimeout %s", e.CacheLookupTimeout)
+	if e.CacheNamespace != "" {
+		fmt.Fprintf(&b, " cachenamespace %s", e.CacheNamespace)
+	}
 	fmt.Fprintf(&b, " imagemap %v", e.ImageMap)
+	if len(e.Arm64ImageMap) > 0 {
+		fmt.Fprintf(&b, " arm64imagemap %v", e.Arm64ImageMap)
+	}
+	if e.MaxFanout > 0 {
+		fmt.Fprintf(&b, " maxfanout %d", e.MaxFanout)
+	}
+	if e.MaxCost > 0 {
+		fmt.Fprintf(&b, " maxcost $%.2f(%s)", e.MaxCost, e.MaxCostPolicy)
+	}
 	if e.DotWriter != nil {
 		fmt.Fprintf(&b, " dotwriter(%T)", e.DotWriter)
 	}
+	if e.Graph != nil {
+		b.WriteString(" graph")
+	}
 	return b.String()
 }
 
@@ -278,6 +375,23 @@ type Eval struct {
 	returnch chan *Flow
 
 	flowgraph *simple.DirectedGraph
+
+	// costMu protects costUSD.
+	costMu sync.Mutex
+	// costUSD is the accumulated estimated cost of the evaluation's tasks.
+	costUSD float64
+
+	// resumed contains the nodes whose results were looked up in the
+	// frontier, so that each is looked up at most once.
+	resumed map[*Flow]bool
+
+	// dryRunMu protects missReasons and wouldRun.
+	dryRunMu sync.Mutex
+	// missReasons records, in dry runs, why the cache lookups of nodes
+	// missed.
+	missReasons map[*Flow]string
+	// wouldRun are the nodes which would run, in dry runs.
+	wouldRun []DryRunExec
 }
 
 // NewEval creates and initializes a new evaluator using the provided
@@ -294,6 +408,9 @@ func NewEval(root *Flow, config EvalConfig) *Eval {
 		}
 		config.CacheMode = infra2.CacheOff
 	}
+	if config.DryRun {
+		config.CacheMode &^= infra2.CacheWrite
+	}
 
 	e := &Eval{
 		EvalConfig: config,
@@ -302,6 +419,10 @@ func NewEval(root *Flow, config EvalConfig) *Eval {
 		errors:     make(chan error),
 		returnch:   make(chan *Flow, 1024),
 		pending:    newWorkingset(),
+		resumed:    make(map[*Flow]bool),
+	}
+	if e.DryRun {
+		e.missReasons = make(map[*Flow]string)
 	}
 
 	// We require a snapshotter for delayed loads when using a scheduler.
@@ -311,6 +432,12 @@ func NewEval(root *Flow, config EvalConfig) *Eval {
 	if e.CacheLookupTimeout == time.Duration(0) {
 		e.CacheLookupTimeout = defaultCacheLookupTimeout
 	}
+	if e.OOMMemMultiplier == 0 {
+		e.OOMMemMultiplier = DefaultOOMMemMultiplier
+	}
+	if e.OOMMaxMemory == 0 {
+		e.OOMMaxMemory = DefaultOOMMaxMemory
+	}
 	if e.Log == nil && printAllTasks {
 		e.Log = log.Std
 	}
@@ -392,7 +519,11 @@ func (e *Eval) Err() error {
 func (e *Eval) Do(ctx context.Context) error {
 	defer func() {
 		if e.DotWriter != nil && e.flowgraph != nil {
-			b, err := dot.Marshal(e.flowgraph, fmt.Sprintf("reflow flowgraph %v", e.EvalConfig.RunID.ID()), "", "")
+			g := e.DotOptions.summarize(e.flowgraph)
+			if n, m := e.flowgraph.Nodes().Len(), g.Nodes().Len(); n != m {
+				e.Log.Printf("flowgraph of %d nodes summarized to %d nodes", n, m)
+			}
+			b, err := dot.Marshal(g, fmt.Sprintf("reflow flowgraph %v", e.EvalConfig.RunID.ID()), "", "")
 			if err != nil {
 				e.Log.Debugf("err dot marshal: %v", err)
 				return
@@ -404,6 +535,7 @@ func (e *Eval) Do(ctx context.Context) error {
 		}
 	}()
 	e.Log.Printf("evaluating with configuration: %s", e.EvalConfig)
+	defer e.publishGraph(true)
 	e.begin = time.Now()
 	defer func() {
 		e.totalTime = time.Since(e.begin)
@@ -460,10 +592,36 @@ func (e *Eval) Do(ctx context.Context) error {
 			}
 			if e.ImageMap != nil && f.OriginalImage == "" {
 				f.OriginalImage = f.Image
-				if img, ok := e.ImageMap[f.Image]; ok {
+				// ImageMap resolves images for the default (amd64) platform;
+				// execs which run on arm64 allocs use the arm64 variant.
+				imageMap := e.ImageMap
+				if f.Resources[reflow.Arm64] > 0 {
+					imageMap = e.Arm64ImageMap
+				}
+				if img, ok := imageMap[f.Image]; ok {
 					f.Image = img
 				}
 			}
+			if e.Frontier != nil && f.Op.External() && (f.State == Ready || f.State == NeedLookup) && !e.resumed[f] {
+				e.resumed[f] = true
+				if fsid, ok := e.Frontier.Lookup(f.Digest()); ok {
+					state := f.State
+					e.Mutate(f, Running, NoStatus)
+					e.pending.Add(f)
+					e.step(f, func(f *Flow) error {
+						var fs reflow.Fileset
+						if err := unmarshal(ctx, e.Repository, fsid, &fs, assoc.FilesetV2); err != nil {
+							// Evaluate the node as if it were not in the frontier.
+							e.Log.Printf("resume %v: %v", f, err)
+							e.Mutate(f, state)
+						} else {
+							e.Mutate(f, fs, Propagate, Done)
+						}
+						return nil
+					})
+					continue dequeue
+				}
+			}
 			if e.Snapshotter != nil && f.Op == Intern && f.State == Ready && !f.MustIntern {
 				// In this case we don't display status, since we're not doing
 				// any appreciable work here, and it's confusing to the user.
@@ -511,6 +669,15 @@ func (e *Eval) Do(ctx context.Context) error {
 						break
 					}
 				}
+				// Tasks are not submitted once the evaluation exceeds its budget.
+				if err == nil {
+					err = e.budgetErr()
+				}
+				// Nor are they submitted in dry runs: instead, we record
+				// that they would run.
+				if e.DryRun && (err == nil || isDryRun(err)) {
+					err = e.wouldRunErr(f)
+				}
 				e.pending.Add(f)
 				if err != nil {
 					go func(err *errors.Error) {
@@ -520,6 +687,26 @@ func (e *Eval) Do(ctx context.Context) error {
 					break
 				}
 				e.Mutate(f, Execing, SetReserved(f.Resources))
+				if f.ArraySize > 0 {
+					// Execs which run as task arrays are submitted by
+					// themselves, as a group, and are not subject to
+					// resource prediction or the evaluator's retries.
+					array := sched.NewTaskArray(e.newTask(f), f.ArraySize, f.Gang)
+					e.Scheduler.SubmitGroup(array)
+					e.step(f, func(f *Flow) error {
+						if err := e.arrayWait(ctx, f, array); err != nil {
+							return err
+						}
+						if e.cacheMode(f).Writing() && f.Err == nil {
+							e.cacheWriteAsync(ctx, f)
+						}
+						if e.Frontier != nil && f.Err == nil {
+							e.recordFrontier(ctx, f)
+						}
+						return nil
+					})
+					break
+				}
 				task := e.newTask(f)
 				tasks = append(tasks, task)
 				flows = append(flows, f)
@@ -527,7 +714,13 @@ func (e *Eval) Do(ctx context.Context) error {
 					if err := e.taskWait(ctx, f, task); err != nil {
 						return err
 					}
-					for retries := 0; retries < maxTaskRetries && task.Result.Err != nil; retries++ {
+					// Execs may request more retries than the default (see
+					// reflow.ExecConfig.Retries), of errors of any kind.
+					maxRetries := maxTaskRetries
+					if f.Retries > maxRetries {
+						maxRetries = f.Retries
+					}
+					for retries := 0; retries < maxRetries && task.Result.Err != nil; retries++ {
 						var (
 							retry          bool
 							resources      reflow.Resources
@@ -542,18 +735,26 @@ func (e *Eval) Do(ctx context.Context) error {
 							// that task returns a non-OOM error, the task should be retried with its original resources.
 							retry, resources, retryType = true, f.Resources, "Predictor"
 							msg = fmt.Sprintf("non-OOM error, switch from predicted (%s) to default (%s) memory", data.Size(f.Reserved["mem"]), data.Size(f.Resources["mem"]))
+						case !errors.Is(errors.OOM, task.Result.Err) && (f.Reserved["disk"] < f.Resources["disk"] || f.Reserved["cpu"] < f.Resources["cpu"]):
+							// Similarly, a task whose predicted disk (or cpu) is lower than requested
+							// may fail for lack of scratch space; retry it with its original resources.
+							retry, resources, retryType = true, f.Resources, "Predictor"
+							msg = fmt.Sprintf("non-OOM error, switch from predicted (%s) to default (%s) resources", f.Reserved, f.Resources)
 						case errors.Is(errors.OOM, task.Result.Err):
 							// Retry OOMs with adjusted resources.
-							retry, retryType, resources = true, "OOM", oomAdjust(f.Resources, task.Config.Resources)
+							retry, retryType, resources = true, "OOM", oomAdjust(f.Resources, task.Config.Resources, e.OOMMemMultiplier, e.OOMMaxMemory)
 							msg = fmt.Sprintf("%v of memory", data.Size(resources["mem"]))
 						case errors.Is(errors.Temporary, task.Result.Err):
 							// Retry Temporary.
 							retry, retryType, resources = true, "Temporary", f.Reserved
+						case retries < f.Retries:
+							// Retry other errors as requested by the exec.
+							retry, retryType, resources = true, "Exec", f.Reserved
 						}
 						if retry {
-							msg += fmt.Sprintf("(%v/%v) due to error: %s", retries+1, maxTaskRetries, task.Result.Err)
+							msg += fmt.Sprintf("(%v/%v) due to error: %s", retries+1, maxRetries, task.Result.Err)
 							var err error
-							if task, err = e.retryTask(ctx, f, resources, retryType, msg); err != nil {
+							if task, err = e.retryTask(ctx, f, task, resources, retries+1, retryType, msg); err != nil {
 								return err
 							}
 						} else {
@@ -561,9 +762,15 @@ func (e *Eval) Do(ctx context.Context) error {
 						}
 					}
 					// Write to the cache only if a task was successfully completed.
-					if e.CacheMode.Writing() && task.Err == nil && task.Result.Err == nil {
+					if e.cacheMode(f).Writing() && task.Err == nil && task.Result.Err == nil {
 						e.cacheWriteAsync(ctx, f)
 					}
+					if e.Frontier != nil && task.Err == nil && task.Result.Err == nil {
+						e.recordFrontier(ctx, f)
+					}
+					if e.Predictor != nil {
+						e.Predictor.Observe(task)
+					}
 					return nil
 				})
 			}
@@ -610,6 +817,17 @@ func (e *Eval) Do(ctx context.Context) error {
 	// also perform another collection, so that the executor may be
 	// archived without data.
 	if root.Err != nil {
+		if e.MaxCostPolicy == MaxCostPause && e.budgetErr() != nil {
+			// Allow running tasks to complete (so that their results are
+			// cached) before pausing the evaluation. Tasks which were never
+			// submitted are never returned, so we don't wait for them.
+			e.Log.Printf("pausing evaluation: waiting for running tasks to complete")
+			for e.pending.N() > len(tasks) {
+				if err := e.wait(ctx); err != nil {
+					return err
+				}
+			}
+		}
 		return nil
 	}
 	for e.pending.N() > 0 {
@@ -624,6 +842,94 @@ func (e *Eval) Do(ctx context.Context) error {
 	return nil
 }
 
+// recordFrontier records the result of the completed node f in the
+// evaluation's frontier. Failures are logged: they only cause the
+// node to be recomputed should the evaluation be resumed.
+func (e *Eval) recordFrontier(ctx context.Context, f *Flow) {
+	fs := f.Value.(reflow.Fileset)
+	fsid, err := marshal(ctx, e.Repository, &fs)
+	if err == nil {
+		err = e.Frontier.Record(f.Digest(), fsid)
+	}
+	if err != nil {
+		e.Log.Errorf("record %v in frontier: %v", f, err)
+	}
+}
+
+// CacheStats returns the number of execs (including interns and
+// externs) that were evaluated, and the number of those whose results
+// were retrieved from the cache.
+func (e *Eval) CacheStats() (n, ncache int) {
+	for v := e.root.Visitor(); v.Walk(); v.Visit() {
+		if v.Parent != nil {
+			v.Push(v.Parent)
+		}
+		// Skip nodes that were skipped due to caching.
+		if v.State < Done {
+			continue
+		}
+		switch v.Op {
+		case Exec, Intern, Extern:
+		default:
+			continue
+		}
+		n++
+		if v.Cached {
+			ncache++
+		}
+	}
+	return
+}
+
+// A Failure summarizes a failed exec, intern or extern of an
+// evaluation.
+type Failure struct {
+	// Ident is the identifier of the failed node.
+	Ident string
+	// Position is the source position of the failed node.
+	Position string
+	// Op is the failed node's operation.
+	Op string
+	// FlowID is the digest of the failed node.
+	FlowID digest.Digest
+	// Exec is the URI of the exec which failed, if any.
+	Exec string `json:",omitempty"`
+	// Err is the node's error.
+	Err *errors.Error
+}
+
+// Failures returns summaries of the execs, interns and externs which
+// failed in this evaluation.
+func (e *Eval) Failures() []Failure {
+	var failures []Failure
+	for v := e.root.Visitor(); v.Walk(); v.Visit() {
+		if v.Parent != nil {
+			v.Push(v.Parent)
+		}
+		// Nodes which would run in a dry run did not fail.
+		if v.State != Done || v.Err == nil || isDryRun(v.Err) {
+			continue
+		}
+		switch v.Op {
+		case Exec, Intern, Extern:
+		default:
+			continue
+		}
+		f := Failure{
+			Ident:    v.Ident,
+			Position: v.Position,
+			Op:       v.Op.String(),
+			FlowID:   v.Digest(),
+			Err:      v.Err,
+		}
+		if v.Exec != nil {
+			f.Exec = v.Exec.URI()
+		}
+		failures = append(failures, f)
+	}
+	return failures
+}
+
 // LogSummary prints an execution summary to an io.Writer.
 func (e *Eval) LogSummary(log *log.Logger) {
 	var n int
@@ -755,6 +1061,7 @@ func (e *Eval) wait(ctx context.Context) (err error) {
 			e.LogFlow(ctx, f)
 		}
 		e.needLog = nil
+		e.publishGraph(false)
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
@@ -769,6 +1076,14 @@ func (e *Eval) wait(ctx context.Context) (err error) {
 	}
 }
 
+// publishGraph publishes a snapshot of the evaluation graph to the
+// evaluation's graph server, if any.
+func (e *Eval) publishGraph(force bool) {
+	if e.Graph != nil {
+		e.Graph.Publish(e.root, force)
+	}
+}
+
 func (e *Eval) reportStatus() {
 	if e.Status == nil {
 		return
@@ -857,6 +1172,19 @@ func (e *Eval) dirty(f *Flow) bool {
 	return false
 }
 
+// cacheMode returns the cache mode of node f: the evaluation's cache
+// mode, less the uses of the cache which are disabled for f.
+func (e *Eval) cacheMode(f *Flow) infra2.CacheMode {
+	mode := e.CacheMode
+	if f.NoCacheRead {
+		mode &^= infra2.CacheRead
+	}
+	if f.NoCacheWrite {
+		mode &^= infra2.CacheWrite
+	}
+	return mode
+}
+
 // Valid tells whether f's cached results should be considered valid.
 func (e *Eval) valid(f *Flow) bool {
 	if e.Invalidate == nil {
@@ -884,6 +1212,13 @@ func (e *Eval) todo(f *Flow, visited flowOnce, v *FlowVisitor) {
 	}
 	switch f.State {
 	case Init:
+		if e.exceedsFanout(f) {
+			// The node's dependencies are not traversed, so that none of
+			// them are computed; the node fails when it is evaluated.
+			e.Mutate(f, Ready)
+			v.Push(f)
+			return
+		}
 		f.Pending = make(map[*Flow]bool)
 		for _, dep := range f.Deps {
 			if dep.State != Done {
@@ -893,7 +1228,7 @@ func (e *Eval) todo(f *Flow, visited flowOnce, v *FlowVisitor) {
 		}
 		switch f.Op {
 		case Intern, Exec, Extern:
-			if !e.BottomUp && e.CacheMode.Reading() && !e.dirty(f) {
+			if !e.BottomUp && e.cacheMode(f).Reading() && !e.dirty(f) {
 				v.Push(f)
 				e.Mutate(f, NeedLookup)
 				return
@@ -908,14 +1243,20 @@ func (e *Eval) todo(f *Flow, visited flowOnce, v *FlowVisitor) {
 		// In the case of multiple dependencies, we short-circuit
 		// computation on error. This is because we want to return early,
 		// in case it can be dealt with (e.g., by restarting evaluation).
+		// Dry runs do not short-circuit, so that all of the execs which
+		// would run are found.
+		var depErr bool
 		for _, dep := range f.Deps {
 			if dep == nil {
 				panic(fmt.Sprintf("op %s n %d", f.Op, len(f.Deps)))
 			}
 			if dep.State == Done && dep.Err != nil {
-				e.Mutate(f, Ready)
-				v.Push(f)
-				return
+				if !e.DryRun {
+					e.Mutate(f, Ready)
+					v.Push(f)
+					return
+				}
+				depErr = true
 			}
 		}
 		for _, dep := range f.Deps {
@@ -923,12 +1264,17 @@ func (e *Eval) todo(f *Flow, visited flowOnce, v *FlowVisitor) {
 				return
 			}
 		}
+		if depErr {
+			e.Mutate(f, Ready)
+			v.Push(f)
+			return
+		}
 		// The node is ready to run. This is done according to the evaluator's mode.
 		switch f.Op {
 		case Intern, Exec, Extern:
 			// We're ready to run. If we're in bottom up mode, this means we're ready
 			// for our cache lookup.
-			if e.BottomUp && e.CacheMode.Reading() {
+			if e.BottomUp && e.cacheMode(f).Reading() {
 				e.Mutate(f, NeedLookup)
 			} else {
 				e.Mutate(f, Ready)
@@ -944,15 +1290,95 @@ func (e *Eval) todo(f *Flow, visited flowOnce, v *FlowVisitor) {
 	}
 }
 
+// shardSizeFile is the name of the file, in the fileset recorded in
+// the cache for a continuation, which holds its shard size.
+const shardSizeFile = "shardsize"
+
 type kCtx struct {
 	context.Context
 	repo reflow.Repository
+	// pred, if non-nil, is used to estimate shard sizes.
+	pred *predictor.Predictor
+	// assoc, if non-nil, records shard sizes under key, the
+	// continuation's cache key; read and write tell whether recorded
+	// sizes are used and whether new sizes are recorded.
+	assoc       assoc.Assoc
+	key         digest.Digest
+	read, write bool
 }
 
 func (k kCtx) Repository() reflow.Repository {
 	return k.repo
 }
 
+// ShardSize implements KContext. Estimates are rounded down to a
+// power of two, so that small changes in the execs' history do not
+// change the size.
+func (k kCtx) ShardSize(ident string, target time.Duration, fallback int) int {
+	if k.assoc != nil && k.read {
+		n, err := k.recordedShardSize()
+		if err == nil {
+			return n
+		}
+		if !errors.Is(errors.NotExist, err) {
+			log.Errorf("shardsize %s: %v", ident, err)
+		}
+	}
+	n := fallback
+	if k.pred != nil {
+		if size, err := k.pred.ShardSize(k, ident, target); err == nil {
+			n = 1 << (bits.Len(uint(size)) - 1)
+		} else {
+			log.Debugf("shardsize %s: using size %d: %v", ident, fallback, err)
+		}
+	}
+	if k.assoc != nil && k.write {
+		if err := k.recordShardSize(n); err != nil {
+			log.Errorf("shardsize %s: record: %v", ident, err)
+		}
+	}
+	return n
+}
+
+func (k kCtx) recordedShardSize() (int, error) {
+	_, fsid, err := k.assoc.Get(k, assoc.FilesetV2, k.key)
+	if err != nil {
+		return 0, err
+	}
+	var fs reflow.Fileset
+	if err = unmarshal(k, k.repo, fsid, &fs, assoc.FilesetV2); err != nil {
+		return 0, err
+	}
+	file, ok := fs.Map[shardSizeFile]
+	if !ok {
+		return 0, errors.E("shardsize", k.key, errors.Invalid, errors.New("no recorded size"))
+	}
+	rc, err := k.repo.Get(k, file.ID)
+	if err != nil {
+		return 0, err
+	}
+	defer rc.Close()
+	p, err := ioutil.ReadAll(rc)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(string(p))
+}
+
+func (k kCtx) recordShardSize(n int) error {
+	p := strconv.Itoa(n)
+	id, err := k.repo.Put(k, strings.NewReader(p))
+	if err != nil {
+		return err
+	}
+	fs := reflow.Fileset{Map: map[string]reflow.File{shardSizeFile: {ID: id, Size: int64(len(p))}}}
+	fsid, err := marshal(k, k.repo, &fs)
+	if err != nil {
+		return err
+	}
+	return k.assoc.Store(k, assoc.FilesetV2, k.key, fsid)
+}
+
 // Eval performs a one-step simplification of f. It must be called
 // only after all of f's dependencies are ready.
 //
@@ -965,6 +1391,10 @@ func (e *Eval) eval(ctx context.Context, f *Flow) (err error) {
 			return nil
 		}
 	}
+	if err := e.fanoutErr(f); err != nil {
+		e.Mutate(f, err, Done)
+		return nil
+	}
 
 	// There is a little bit of concurrency trickery here: we must
 	// modify Flow's state only after any modifications have been done,
@@ -1074,7 +1504,18 @@ func (e *Eval) eval(ctx context.Context, f *Flow) (err error) {
 		for i, dep := range f.Deps {
 			vs[i] = dep.Value
 		}
-		ff := f.Kctx(kCtx{ctx, e.Repository}, vs)
+		k := kCtx{
+			Context: ctx,
+			repo:    e.Repository,
+			assoc:   e.Assoc,
+			key:     NamespaceKey(e.CacheNamespace, f.Digest()),
+			read:    e.CacheMode.Reading(),
+			write:   e.CacheMode.Writing(),
+		}
+		if e.Scheduler != nil {
+			k.pred = e.Predictor
+		}
+		ff := f.Kctx(k, vs)
 		e.Mutate(f, Fork(ff), Init)
 		e.Mutate(f.Parent, Done)
 	case Coerce:
@@ -1113,6 +1554,19 @@ func (e *Eval) eval(ctx context.Context, f *Flow) (err error) {
 	return nil
 }
 
+// cacheKeys returns the cache keys of flow f, qualified by the
+// configured cache namespace.
+func (e EvalConfig) cacheKeys(f *Flow) []digest.Digest {
+	keys := f.CacheKeys()
+	if e.CacheNamespace == "" {
+		return keys
+	}
+	for i := range keys {
+		keys[i] = NamespaceKey(e.CacheNamespace, keys[i])
+	}
+	return keys
+}
+
 // CacheWrite writes the cache entry for flow f, with objects in the provided
 // source repository. CacheWrite returns nil on success, or else the first error
 // encountered.
@@ -1140,13 +1594,17 @@ func (e *Eval) CacheWrite(ctx context.Context, f *Flow) error {
 	if e.NoCacheExtern && f.Op == Extern {
 		return nil
 	}
-	keys := f.CacheKeys()
+	keys := e.cacheKeys(f)
 	if len(keys) == 0 {
 		return nil
 	}
 	// We expect the fileset to contain only resolved files and to already exist
 	// in the shared repository (because the scheduler should've transferred them).
-	if missing, err := repository.Missing(ctx, e.Repository, fs.Files()...); err != nil {
+	// The presence index is not consulted here: objects may have been removed
+	// from the repository (e.g., by reflow gc) since they were indexed, and a
+	// cache entry must never refer to missing objects.
+	files := fs.Files()
+	if missing, err := repository.Missing(ctx, e.Repository, files...); err != nil {
 		return err
 	} else if n := len(missing); n > 0 {
 		b := new(bytes.Buffer)
@@ -1159,6 +1617,11 @@ func (e *Eval) CacheWrite(ctx context.Context, f *Flow) error {
 		}
 		return errors.E("CacheWrite", f.Digest(), b.String())
 	}
+	if e.RepositoryIndex != nil {
+		for _, file := range files {
+			e.RepositoryIndex.Add(file.ID)
+		}
+	}
 	id, err := marshal(ctx, e.Repository, &fs)
 	if err != nil {
 		return err
@@ -1196,7 +1659,15 @@ func (e *Eval) cacheWriteAsync(ctx context.Context, f *Flow) {
 // these could directly move to NeedSubmit.
 //
 // In top-down mode, we need to continue traversing the graph, and the node is marked TODO.
-func (e *Eval) lookupFailed(f *Flow) {
+//
+// In dry runs, the reason for the failure is recorded, so that it may
+// be reported should the node run.
+func (e *Eval) lookupFailed(f *Flow, reason string) {
+	if e.DryRun {
+		e.dryRunMu.Lock()
+		e.missReasons[f] = reason
+		e.dryRunMu.Unlock()
+	}
 	if e.BottomUp {
 		e.Mutate(f, Ready)
 	} else {
@@ -1249,15 +1720,22 @@ func (e *Eval) batchLookup(ctx context.Context, flows ...*Flow) {
 
 	batch := make(assoc.Batch)
 	for _, f := range flows {
-		if !e.valid(f) || !e.CacheMode.Reading() || e.NoCacheExtern && (f.Op == Extern || f == e.root) {
-			e.lookupFailed(f)
+		switch {
+		case !e.valid(f):
+			e.lookupFailed(f, "invalidated")
+			continue
+		case !e.cacheMode(f).Reading():
+			e.lookupFailed(f, "cache reads are disabled")
+			continue
+		case e.NoCacheExtern && (f.Op == Extern || f == e.root):
+			e.lookupFailed(f, "extern caching is disabled")
 			continue
 		}
-		keys := f.CacheKeys()
+		keys := e.cacheKeys(f)
 		if len(keys) == 0 {
 			// This can't be true now, but in the future it could be valid for nodes
 			// to present no cache keys.
-			e.lookupFailed(f)
+			e.lookupFailed(f, "no cache keys")
 			continue
 		}
 		for _, key := range keys {
@@ -1283,7 +1761,7 @@ func (e *Eval) batchLookup(ctx context.Context, flows ...*Flow) {
 			e.Log.Errorf("assoc.BatchGet: %v", err)
 			for _, f := range flows {
 				e.step(f, func(f *Flow) error {
-					e.lookupFailed(f)
+					e.lookupFailed(f, fmt.Sprintf("cache lookup failed: %v", err))
 					return nil
 				})
 			}
@@ -1297,7 +1775,7 @@ func (e *Eval) batchLookup(ctx context.Context, flows ...*Flow) {
 		e.step(f, func(f *Flow) error {
 			defer wg.Done()
 			var (
-				keys           = f.CacheKeys()
+				keys           = e.cacheKeys(f)
 				fs             reflow.Fileset
 				fsidV1, fsidV2 digest.Digest
 			)
@@ -1308,13 +1786,13 @@ func (e *Eval) batchLookup(ctx context.Context, flows ...*Flow) {
 				// Nothing was found, so there is no read repair to do.
 				// Fail the lookup early.
 				if fsidV1.IsZero() {
-					e.lookupFailed(f)
+					e.lookupFailed(f, "no cached result")
 					return nil
 				}
 			}
 			// Make sure all of the files are present in the repository.
 			// If they are not, we consider this a cache miss.
-			missing, err := missing(ctx, e.Repository, fs.Files()...)
+			missing, err := missing(ctx, e.Repository, e.RepositoryIndex, fs.Files()...)
 			switch {
 			case err != nil:
 				if err != ctx.Err() {
@@ -1331,7 +1809,7 @@ func (e *Eval) batchLookup(ctx context.Context, flows ...*Flow) {
 				e.Log.Error(err)
 			}
 			if err != nil {
-				e.lookupFailed(f)
+				e.lookupFailed(f, fmt.Sprintf("cached result unavailable: %v", err))
 				return nil
 			}
 			// If the cached fileset has viable non-empty assertions, assert them.
@@ -1339,13 +1817,13 @@ func (e *Eval) batchLookup(ctx context.Context, flows ...*Flow) {
 				// Check if the assertions are internally consistent for the cached fileset.
 				if err = e.assertionsConsistent(f, []*reflow.Assertions{a}); err != nil {
 					e.Log.Debugf("assertions consistent: %v", err)
-					e.lookupFailed(f)
+					e.lookupFailed(f, fmt.Sprintf("cached result has inconsistent assertions: %v", err))
 					return nil
 				}
 				anew, err := e.refreshAssertions(ctx, []*reflow.Assertions{a}, bg)
 				if err != nil {
 					e.Log.Debugf("refresh assertions: %v", err)
-					e.lookupFailed(f)
+					e.lookupFailed(f, fmt.Sprintf("assertions of cached result could not be refreshed: %v", err))
 					return nil
 				}
 				if !e.Assert(ctx, []*reflow.Assertions{a}, anew) {
@@ -1354,18 +1832,20 @@ func (e *Eval) batchLookup(ctx context.Context, flows ...*Flow) {
 							e.Log.Debugf("flow %s assertions diff:\n%s\n", f.Digest().Short(), diff)
 						}
 					}
-					e.lookupFailed(f)
+					e.lookupFailed(f, "inputs of cached result have changed")
 					return nil
 				}
 			}
 			if e.RecomputeEmpty && fs.AnyEmpty() {
 				e.Log.Debugf("recomputing empty value for %v", f)
-				e.lookupFailed(f)
+				e.lookupFailed(f, "cached result is empty")
 				return nil
 			}
 			// Perform read repair: asynchronously write back all non existent keys in v2 format if we have already
-			// marshalled a v2 format fileset.
-			if !fsidV2.IsZero() {
+			// marshalled a v2 format fileset. Dry runs write nothing.
+			switch {
+			case e.DryRun:
+			case !fsidV2.IsZero():
 				writeback := keys[:0]
 				for _, key := range keys {
 					if res, ok := batch[assoc.Key{Kind: assoc.FilesetV2, Digest: key}]; !ok || res.Digest.IsZero() || res.Error != nil {
@@ -1383,7 +1863,7 @@ func (e *Eval) batchLookup(ctx context.Context, flows ...*Flow) {
 					}
 					bgctx.Complete()
 				}()
-			} else {
+			default:
 				// If the V2 fileset does not exist yet in the repository, upgrade with an async cache write once we've
 				// mutated the flow to include the unmarshalled v1 fileset.
 				defer e.cacheWriteAsync(ctx, f)
@@ -1709,7 +2189,7 @@ var statusPrinters = [maxOp]struct {
 			seen := make(map[string]bool)
 			for i, arg := range f.Argstrs {
 				earg := f.ExecArg(i)
-				if earg.Out || seen[arg] {
+				if earg.Out || earg.Scratch || earg.Inline || seen[arg] {
 					continue
 				}
 				fmt.Fprintf(w, "    %s = \n", arg)
@@ -1902,7 +2382,7 @@ func (e *Eval) LogFlow(ctx context.Context, f *Flow) {
 		}
 		fmt.Fprintf(&b, "\tresources: %s\n", logResources)
 	}
-	for _, key := range f.CacheKeys() {
+	for _, key := range e.cacheKeys(f) {
 		fmt.Fprintf(&b, "\t%s\n", key)
 	}
 	if pr.debug != nil {
@@ -1925,7 +2405,7 @@ func (e *Eval) taskWait(ctx context.Context, f *Flow, task *sched.Task) error {
 		f.resolvedFs = make([]*reflow.Fileset, n)
 		for i := 0; i < n; i++ {
 			earg, arg := f.ExecArg(i), task.Config.Args[i]
-			if earg.Out {
+			if earg.Out || earg.Scratch || earg.Inline {
 				continue
 			}
 			f.resolvedFs[earg.Index] = arg.Fileset
@@ -1941,7 +2421,68 @@ func (e *Eval) taskWait(ctx context.Context, f *Flow, task *sched.Task) error {
 	} else {
 		e.Mutate(f, task.Result.Err, task.Result.Fileset, Propagate, Done)
 	}
-	return nil
+	return e.addCost(task)
+}
+
+// arrayWait waits for the tasks of a task array to finish running
+// and updates the flow with their merged results, as taskWait does
+// for single tasks. The flow fails if any of the array's tasks
+// fails, with an error which lists the failed tasks.
+func (e *Eval) arrayWait(ctx context.Context, f *Flow, array *sched.TaskGroup) error {
+	if err := array.Wait(ctx, sched.TaskRunning); err != nil {
+		return err
+	}
+	first := array.Tasks[0]
+	f.Exec = first.Exec
+	if f.Argmap != nil {
+		n := f.NExecArg()
+		f.resolvedFs = make([]*reflow.Fileset, n)
+		for i := 0; i < n; i++ {
+			earg, arg := f.ExecArg(i), first.Config.Args[i]
+			if earg.Out || earg.Scratch || earg.Inline {
+				continue
+			}
+			f.resolvedFs[earg.Index] = arg.Fileset
+		}
+	}
+	e.LogFlow(ctx, f)
+	if err := array.Wait(ctx, sched.TaskDone); err != nil {
+		return err
+	}
+	f.RunInfo = first.RunInfo
+	e.Log.Debugf("flow %s: array %s", f.Digest().Short(), array.Status())
+	var costErr error
+	for _, task := range array.Tasks {
+		if err := e.addCost(task); err != nil && costErr == nil {
+			costErr = err
+		}
+	}
+	err := array.Err()
+	result := reflow.Fileset{List: make([]reflow.Fileset, len(f.OutputIsDir))}
+	for _, task := range array.Tasks {
+		if err != nil {
+			break
+		}
+		list := task.Result.Fileset.List
+		if len(list) != len(result.List) {
+			err = errors.E(errors.Invalid, errors.Errorf("task %d: expected %d outputs, got %d", task.Index(), len(result.List), len(list)))
+			break
+		}
+		for i, fs := range list {
+			if result.List[i].Map == nil {
+				result.List[i].Map = make(map[string]reflow.File)
+			}
+			for path, file := range fs.Map {
+				result.List[i].Map[fmt.Sprintf("%d/%s", task.Index(), path)] = file
+			}
+		}
+	}
+	if err != nil {
+		e.Mutate(f, err, Done)
+	} else {
+		e.Mutate(f, result, Propagate, Done)
+	}
+	return costErr
 }
 
 func (e *Eval) newTask(f *Flow) *sched.Task {
@@ -1949,7 +2490,10 @@ func (e *Eval) newTask(f *Flow) *sched.Task {
 	t := sched.NewTask()
 	t.RunID = e.RunID
 	t.FlowID = f.Digest()
+	t.CacheKeys = f.CacheKeys()
 	t.Config = f.ExecConfig()
+	t.Timeout = f.Timeout
+	t.Critical = f.Critical
 	t.Repository = e.Repository
 	t.PostUseChecksum = e.PostUseChecksum
 	t.Log = e.Log
@@ -1969,9 +2513,21 @@ func (e *Eval) reviseResources(ctx context.Context, tasks []*sched.Task, flows [
 			newReserved := make(reflow.Resources)
 			newReserved.Set(f.Reserved)
 			for k, v := range predicted.Resources {
-				newReserved[k] = v
+				switch k {
+				case "cpu", "disk":
+					// Predicted cpu and disk are only used to shrink the reservation: an exec
+					// is never given more cores or scratch space than it asked for.
+					if v < newReserved[k] {
+						newReserved[k] = v
+					}
+				default:
+					newReserved[k] = v
+				}
 			}
 			newReserved["mem"] = math.Max(newReserved["mem"], minExecMemory)
+			if cpu, ok := newReserved["cpu"]; ok {
+				newReserved["cpu"] = math.Max(cpu, minExecCPU)
+			}
 			e.Mutate(f, SetReserved(newReserved))
 			task.Config = f.ExecConfig()
 			e.Log.Debugf("(flow %s): modifying resources from %s to %s", task.FlowID.Short(), oldResources, task.Config.Resources)
@@ -1981,26 +2537,31 @@ func (e *Eval) reviseResources(ctx context.Context, tasks []*sched.Task, flows [
 	}
 }
 
-// retryTask retries a task with the specified resources and waits for it to complete.
-func (e *Eval) retryTask(ctx context.Context, f *Flow, resources reflow.Resources, retryType, msg string) (*sched.Task, error) {
+// retryTask retries a (failed) task with the specified resources and waits for it to complete.
+// The retry is recorded (separately from the task's attempt) in TaskDB, and is placed outside of
+// the failure domains in which the previous task failed, if possible.
+func (e *Eval) retryTask(ctx context.Context, f *Flow, prev *sched.Task, resources reflow.Resources, retry int, retryType, msg string) (*sched.Task, error) {
 	// Apply ExecReset so that the exec can be resubmitted to the scheduler with the flow's
 	// exec runtime parameters reset.
 	f.ExecReset()
 	e.Mutate(f, SetReserved(resources), Execing)
 	task := e.newTask(f)
+	task.Retry = retry
+	task.SpreadFrom(prev)
 	e.Log.Printf("flow %s: %s: re-submitting task with %s", f.Digest().Short(), retryType, msg)
 	e.Scheduler.Submit(task)
 	return task, e.taskWait(ctx, f, task)
 }
 
-// oomAdjust returns a new set of resources with increased memory.
+// oomAdjust returns a new set of resources with memory increased by
+// the given multiplier, but capped at maxMem.
 // TODO(dnicolaou): Adjust based on actual used memory instead of allocated.
-func oomAdjust(specified, used reflow.Resources) reflow.Resources {
+func oomAdjust(specified, used reflow.Resources, multiplier, maxMem float64) reflow.Resources {
 	newResources := make(reflow.Resources)
 	newResources.Set(used)
 	mem := used["mem"]
-	if mem > oomRetryMaxExecMemory {
-		mem = oomRetryMaxExecMemory
+	if mem > maxMem {
+		mem = maxMem
 	}
 	if mem < specified["mem"] {
 		// If we used lesser than what was specified (eg: predicted usage was lower)
@@ -2008,10 +2569,10 @@ func oomAdjust(specified, used reflow.Resources) reflow.Resources {
 		mem = specified["mem"]
 	} else {
 		// Increase memory
-		mem *= memMultiplier
-		// But cap it to oomRetryMaxExecMemory
-		if mem > oomRetryMaxExecMemory {
-			mem = oomRetryMaxExecMemory
+		mem *= multiplier
+		// But cap it to maxMem
+		if mem > maxMem {
+			mem = maxMem
 		}
 	}
 	newResources["mem"] = mem
@@ -2143,17 +2704,25 @@ func unmarshal(ctx context.Context, repo reflow.Repository, k digest.Digest, fs
 }
 
 // Missing returns the files in files that are missing from
-// repository r. Missing returns an error if any underlying
-// call fails.
-func missing(ctx context.Context, r reflow.Repository, files ...reflow.File) ([]reflow.File, error) {
+// repository r. Files known to be present in the (optional) presence
+// index of r are not checked for; files found in r are added to it.
+// Missing returns an error if any underlying call fails.
+func missing(ctx context.Context, r reflow.Repository, index repository.PresenceIndex, files ...reflow.File) ([]reflow.File, error) {
 	exists := make([]bool, len(files))
 	g, ctx := errgroup.WithContext(ctx)
 	for i, file := range files {
+		if index != nil && index.Contains(file.ID) {
+			exists[i] = true
+			continue
+		}
 		i, file := i, file
 		g.Go(func() error {
 			_, err := r.Stat(ctx, file.ID)
 			if err == nil {
 				exists[i] = true
+				if index != nil {
+					index.Add(file.ID)
+				}
 			} else if errors.Is(errors.NotExist, err) {
 				return nil
 			}
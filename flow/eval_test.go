@@ -234,6 +234,91 @@ func TestGroupbyMapCollect(t *testing.T) {
 	}
 }
 
+func TestMaxFanout(t *testing.T) {
+	intern := op.Intern("internurl")
+	groupby := op.Groupby("^(.)/.*", intern)
+	mapCollect := op.Map(func(f *flow.Flow) *flow.Flow {
+		return op.Collect("^./(.*)", "$1", f)
+	}, groupby)
+
+	e, config, done := newTestScheduler()
+	defer done()
+	config.MaxFanout = 2
+
+	eval := flow.NewEval(mapCollect, config)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	rc := testutil.EvalAsync(ctx, eval)
+
+	e.Ok(ctx, intern, testutil.WriteFiles(e.Repo, "a/one:one", "b/1:four", "c/xxx:six"))
+	r := <-rc
+	if !errors.Is(errors.Eval, r.Err) {
+		t.Fatalf("expected fan-out error, got %v", r.Err)
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	intern := op.Intern("internurl")
+	exec1 := op.Exec("image", "command1", testutil.Resources, intern)
+	exec2 := op.Exec("image", "command2", testutil.Resources, intern)
+	extern := op.Extern("externurl", op.Pullup(exec1, exec2))
+
+	e, config, done := newTestScheduler()
+	defer done()
+	config.CacheMode = infra.CacheRead | infra.CacheWrite
+	config.DryRun = true
+	eval := flow.NewEval(extern, config)
+	testutil.WriteCache(eval, exec2.Digest(), "cached")
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	r := <-testutil.EvalAsync(ctx, eval)
+	if !flow.IsDryRun(r.Err) {
+		t.Fatalf("expected dry-run error, got %v", r.Err)
+	}
+	if !e.Equiv() {
+		t.Error("did not expect any flows to be executed")
+	}
+	report := eval.DryRunReport()
+	reasons := make(map[string]string)
+	for _, x := range report.Execs {
+		reasons[x.Ident] = x.Reason
+	}
+	for _, c := range []struct {
+		f      *flow.Flow
+		reason string
+	}{
+		{intern, "no cached result"},
+		{exec1, "no cached result; depends on " + intern.Ident + ", which would run"},
+		{extern, "no cached result; depends on " + exec1.Ident + ", which would run"},
+	} {
+		if got, want := reasons[c.f.Ident], c.reason; got != want {
+			t.Errorf("%v: got reason %q, want %q", c.f.Op, got, want)
+		}
+	}
+	if got, want := len(report.Execs), 3; got != want {
+		t.Errorf("got %d execs which would run, want %d", got, want)
+	}
+	prewarm, images, err := report.Prewarm()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := images, []string{"image"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got images %v, want %v", got, want)
+	}
+	// The prewarm flow's interns have the same cache keys as the
+	// program's, so that the run finds their results in the cache.
+	if prewarm == nil || len(prewarm.Deps) != 1 {
+		t.Fatalf("got prewarm flow %v, want one intern", prewarm)
+	}
+	if got, want := prewarm.Deps[0].Digest(), intern.Digest(); got != want || !prewarm.Deps[0].MustIntern {
+		t.Errorf("got intern %v (must intern: %v), want %v", got, prewarm.Deps[0].MustIntern, want)
+	}
+	if failures := eval.Failures(); len(failures) != 0 {
+		t.Errorf("unexpected failures %v", failures)
+	}
+}
+
 func TestExecRetry(t *testing.T) {
 	exec := op.Exec("image", "command", testutil.Resources)
 
@@ -254,6 +339,59 @@ func TestExecRetry(t *testing.T) {
 	}
 }
 
+func TestExecRetries(t *testing.T) {
+	exec := op.Exec("image", "command", testutil.Resources)
+	exec.Retries = 2
+
+	e, config, done := newTestScheduler()
+	defer done()
+	eval := flow.NewEval(exec, config)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	rc := testutil.EvalAsync(ctx, eval)
+	// Errors of any kind are retried, as requested by the exec.
+	e.Ok(ctx, exec, errors.New("failed"))
+	e.Ok(ctx, exec, errors.New("failed again"))
+	e.Ok(ctx, exec, testutil.WriteFiles(e.Repo, "execout"))
+	r := <-rc
+	if r.Err != nil {
+		t.Fatal(r.Err)
+	}
+	if got, want := r.Val, testutil.Files("execout"); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestKctxShardSizeRecorded(t *testing.T) {
+	_, config, done := newTestScheduler()
+	defer done()
+	config.CacheMode = infra.CacheRead | infra.CacheWrite
+	// The first evaluation records its fallback; later evaluations
+	// must return the recorded size regardless of their own estimate.
+	for _, fallback := range []int{8, 3} {
+		var size int
+		shards := &flow.Flow{
+			Op:         flow.Kctx,
+			FlowDigest: reflow.Digester.FromString("shards"),
+			Ident:      "shards",
+			Kctx: func(ctx flow.KContext, vs []values.T) *flow.Flow {
+				size = ctx.ShardSize("shards", time.Hour, fallback)
+				return op.Val(reflow.Fileset{})
+			},
+		}
+		eval := flow.NewEval(shards, config)
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		r := <-testutil.EvalAsync(ctx, eval)
+		cancel()
+		if r.Err != nil {
+			t.Fatal(r.Err)
+		}
+		if got, want := size, 8; got != want {
+			t.Errorf("fallback %d: got %v, want %v", fallback, got, want)
+		}
+	}
+}
+
 func TestCacheWrite(t *testing.T) {
 	for _, bottomup := range []bool{false, true} {
 		e, config, done := newTestScheduler()
@@ -292,6 +430,45 @@ func TestCacheWrite(t *testing.T) {
 	}
 }
 
+func TestCacheModeOverride(t *testing.T) {
+	for _, bottomup := range []bool{false, true} {
+		e, config, done := newTestScheduler()
+		defer done()
+		config.CacheMode = infra.CacheRead | infra.CacheWrite
+		config.BottomUp = bottomup
+
+		intern := op.Intern("internurl")
+		exec1 := op.Exec("image", "command1", testutil.Resources, intern)
+		exec1.NoCacheRead = true
+		exec2 := op.Exec("image", "command2", testutil.Resources, intern)
+		exec2.NoCacheWrite = true
+		pullup := op.Pullup(exec1, exec2)
+
+		eval := flow.NewEval(pullup, config)
+		testutil.WriteCache(eval, exec1.Digest(), "stale")
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		rc := testutil.EvalAsync(ctx, eval)
+		var (
+			exec1Value = testutil.WriteFiles(e.Repo, "a")
+			exec2Value = testutil.WriteFiles(e.Repo, "b")
+		)
+		e.Ok(ctx, intern, testutil.WriteFiles(e.Repo, "in"))
+		e.Ok(ctx, exec1, exec1Value)
+		e.Ok(ctx, exec2, exec2Value)
+		r := <-rc
+		cancel()
+		if r.Err != nil {
+			t.Fatal(r.Err)
+		}
+		if got, want := testutil.Value(eval, exec1.Digest()), exec1Value; !got.Equal(want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+		if testutil.Exists(eval, exec2.CacheKeys()...) {
+			t.Errorf("exec %v: unexpectedly cached", exec2)
+		}
+	}
+}
+
 func TestCacheLookupFilesetMigration(t *testing.T) {
 	*debug = true
 	intern := op.Intern("internurl")
@@ -955,6 +1132,71 @@ func TestPropagateAssertions(t *testing.T) {
 	}
 }
 
+func TestPropagateImageAssertions(t *testing.T) {
+	intern := op.Intern("url")
+	intern.Value = testutil.Files("a")
+	ec := op.Exec("image:latest", "cmd", testutil.Resources, intern)
+	ec.OriginalImage, ec.Image = "image:latest", "image@sha256:1"
+	ec.Value = testutil.Files("b")
+
+	_, config, done := newTestScheduler()
+	defer done()
+	eval := flow.NewEval(ec, config)
+	eval.Mutate(ec, flow.Propagate)
+	got := ec.Value.(reflow.Fileset).Assertions()
+	if want := reflow.DockerAssertions("image:latest", "", "image@sha256:1"); !got.Equal(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+// imageGenerator generates the docker assertions of the images to
+// which image references resolve.
+type imageGenerator map[string]string
+
+func (g imageGenerator) Generate(ctx context.Context, key reflow.AssertionKey) (*reflow.Assertions, error) {
+	image, arch := reflow.ParseDockerAssertionSubject(key.Subject)
+	return reflow.DockerAssertions(image, arch, g[image]), nil
+}
+
+func TestCacheLookupWithImageAssertions(t *testing.T) {
+	intern := op.Intern("internurl")
+	execA := op.Exec("a:latest", "command", testutil.Resources, intern)
+	execB := op.Exec("b:latest", "command", testutil.Resources, intern)
+	extern := op.Extern("externurl", op.Merge(execA, execB))
+
+	e, config, done := newTestScheduler()
+	defer done()
+	config.CacheMode = infra.CacheRead | infra.CacheWrite
+	config.CacheLookupTimeout = 100 * time.Millisecond
+	config.ImageMap = map[string]string{"a:latest": "a@sha256:a2", "b:latest": "b@sha256:b1"}
+	config.AssertionGenerator = reflow.AssertionGeneratorMux{reflow.DockerAssertionsNamespace: imageGenerator(config.ImageMap)}
+	config.Assert = reflow.AssertExact
+	eval := flow.NewEval(extern, config)
+
+	testutil.WriteCache(eval, intern.Digest(), "in")
+	// "a:latest" now resolves to a different image, so its cached
+	// result is rejected.
+	fsA := testutil.WriteFiles(eval.Repository, "a")
+	_ = fsA.AddAssertions(reflow.DockerAssertions("a:latest", "", "a@sha256:a1"))
+	testutil.WriteCacheFileset(eval, execA.Digest(), fsA)
+	fsB := testutil.WriteFiles(eval.Repository, "b")
+	_ = fsB.AddAssertions(reflow.DockerAssertions("b:latest", "", "b@sha256:b1"))
+	testutil.WriteCacheFileset(eval, execB.Digest(), fsB)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	rc := testutil.EvalAsync(ctx, eval)
+	e.Ok(ctx, execA, testutil.WriteFiles(e.Repo, "a"))
+	e.Ok(ctx, extern, reflow.Fileset{})
+	r := <-rc
+	if r.Err != nil {
+		t.Fatal(r.Err)
+	}
+	if !e.Equiv(execA, extern) {
+		t.Error("wrong set of expected flows")
+	}
+}
+
 // TestAlloc is used in scheduler tests. As well as implementing
 // alloc, it implements sched.Cluster, handing itself out.
 type testAlloc struct {
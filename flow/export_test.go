@@ -9,11 +9,13 @@ import (
 
 	"github.com/grailbio/base/digest"
 	"github.com/grailbio/reflow"
+	"gonum.org/v1/gonum/graph"
+	"gonum.org/v1/gonum/graph/simple"
 )
 
 var (
-	MemMultiplier         = memMultiplier
-	OomRetryMaxExecMemory = oomRetryMaxExecMemory
+	MemMultiplier         = DefaultOOMMemMultiplier
+	OomRetryMaxExecMemory = DefaultOOMMaxMemory
 )
 
 func PhysicalDigests(f *Flow) []digest.Digest {
@@ -35,7 +37,7 @@ func RefreshAssertions(ctx context.Context, e *Eval, a []*reflow.Assertions, cac
 }
 
 func OomAdjust(specified, used reflow.Resources) reflow.Resources {
-	return oomAdjust(specified, used)
+	return oomAdjust(specified, used, DefaultOOMMemMultiplier, DefaultOOMMaxMemory)
 }
 
 // FindFlowCopy finds the copy of a given flow in the flow graph maintained by the Eval.
@@ -50,3 +52,11 @@ func (e *Eval) FindFlowCopy(f *Flow) *Flow {
 	}
 	return nil
 }
+
+// SummarizeFlowgraph returns the summary, as configured by opts, of
+// the flowgraph of f as it is written in dot format.
+func SummarizeFlowgraph(f *Flow, opts DotOptions) graph.Directed {
+	e := &Eval{flowgraph: simple.NewDirectedGraph()}
+	e.printDeps(f, false)
+	return opts.summarize(e.flowgraph)
+}
@@ -0,0 +1,39 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package flow
+
+import (
+	"fmt"
+
+	"github.com/grailbio/reflow/errors"
+)
+
+// exceedsFanout tells whether flow f fans out to more dependencies
+// than permitted by the evaluation's maximum fan-out. Only the
+// internal nodes produced by maps, comprehensions and flattens have
+// large fan-outs; execs, interns and externs are never subject to
+// the limit.
+func (e *Eval) exceedsFanout(f *Flow) bool {
+	return e.MaxFanout > 0 && len(f.Deps) > e.MaxFanout && !f.Op.External()
+}
+
+// fanoutErr returns an error if flow f exceeds the evaluation's
+// maximum fan-out.
+func (e *Eval) fanoutErr(f *Flow) *errors.Error {
+	if !e.exceedsFanout(f) {
+		return nil
+	}
+	args := []interface{}{"eval", f.Op.String()}
+	if f.Ident != "" {
+		args[1] = f.Ident
+	}
+	if f.Position != "" {
+		args = append(args, f.Position)
+	}
+	args = append(args, errors.Eval,
+		fmt.Errorf("fan-out of %d exceeds the maximum fan-out %d; check the inputs of the map, "+
+			"or raise the limit (e.g., with reflow run -maxfanout) if this is intended", len(f.Deps), e.MaxFanout))
+	return errors.Recover(errors.E(args...))
+}
@@ -17,6 +17,7 @@ import (
 	"math"
 	"net/url"
 	"regexp"
+	"sort"
 	"strings"
 	"sync"
 	"time" // This is imported for the sha256 implementation, which is always required for Reflow.
@@ -208,7 +209,18 @@ type ExecArg struct {
 	// Out tells whether this argument is an output argument.
 	Out bool
 	// Index is the dependency index represented by this argument.
+	// For scratch arguments, Index is the scratch directory's index.
 	Index int
+	// Scratch tells whether this argument is a scratch argument: a
+	// temporary directory that is private to the exec. Scratch
+	// arguments are not part of the exec's digest.
+	Scratch bool
+	// Inline tells whether this argument is an inline input argument:
+	// a (small) file with contents Data, which is materialized directly
+	// into the exec's sandbox.
+	Inline bool
+	// Data is the contents of an inline argument.
+	Data string
 }
 
 // KContext is the context provided to a continuation (Kctx).
@@ -217,6 +229,14 @@ type KContext interface {
 	context.Context
 	// Repository returns the repository.
 	Repository() reflow.Repository
+	// ShardSize returns the number of items which an exec with the
+	// given ident should process so that it takes about the target
+	// duration, based on the historical processing rates of such execs,
+	// or the fallback size if no such estimate can be made. The size is
+	// recorded in the cache when the continuation is first evaluated,
+	// so that subsequent evaluations of the same continuation (including
+	// repairs) return the same size.
+	ShardSize(ident string, target time.Duration, fallback int) int
 }
 
 // Flow defines an AST for data flows. It is a logical union of ops
@@ -337,6 +357,59 @@ type Flow struct {
 	// NonDeterministic, in the case of Execs, denotes if the exec is non-deterministic.
 	NonDeterministic bool
 
+	// Timeout, in the case of Execs, is the maximum duration for which
+	// the exec may run. A zero Timeout means no limit. Like resources,
+	// the timeout does not affect the flow's digest.
+	Timeout time.Duration
+
+	// Retries, in the case of Execs, is the number of times the exec
+	// is retried if it fails, whatever its error (see
+	// reflow.ExecConfig.Retries). It does not affect the flow's digest.
+	Retries int
+
+	// Stdin, in the case of Execs, is (small) inline data provided to
+	// the exec's standard input.
+	Stdin string
+
+	// EnvVars, in the case of Execs, are environment variables defined
+	// in the exec's environment. They are part of the flow's digest.
+	EnvVars map[string]string
+
+	// Secrets, in the case of Execs, are secrets defined in the exec's
+	// environment (see reflow.ExecConfig.Secrets). Like AWS
+	// credentials, secrets are assumed not to affect the exec's
+	// result: neither the secrets' values, which are fetched only by
+	// the executor, nor their references are part of the flow's
+	// digest.
+	Secrets map[string]string
+
+	// Critical, in the case of Execs, indicates that the instance
+	// running the exec should be protected from termination while it
+	// runs (see sched.Task.Critical). It does not affect the flow's
+	// digest.
+	Critical bool
+
+	// NoCacheRead and NoCacheWrite, in the case of Execs, disable
+	// cache lookups and cache writes, respectively, of the exec's
+	// results, whatever the evaluation's cache mode (see
+	// EvalConfig.CacheMode). They are used for execs with side effects
+	// or known nondeterminism. They do not affect the flow's digest.
+	NoCacheRead, NoCacheWrite bool
+
+	// ArraySize, in the case of Execs, is the number of tasks of the
+	// task array as which the exec runs, each told its index in the
+	// array (see reflow.ExecConfig.ArrayIndex); zero if the exec does
+	// not run as an array. The (directory) outputs of the array's tasks
+	// are merged, each task's under the prefix "<index>/". ArraySize is
+	// part of the flow's digest.
+	ArraySize int
+
+	// Gang, in the case of Execs run as task arrays, indicates that
+	// the array's tasks are gang scheduled, so that they start
+	// simultaneously (see sched.TaskGroup). It does not affect the
+	// flow's digest.
+	Gang bool
+
 	// ExecDepIncorrectCacheKeyBug is set for nodes that are known to be impacted by a bug
 	// which causes the cache keys to be incorrectly computed.
 	// See https://github.com/grailbio/reflow/pull/128 or T41260.
@@ -495,6 +568,10 @@ func (f *Flow) DebugString() string {
 			for i, arg := range f.Argmap {
 				if arg.Out {
 					args[i] = fmt.Sprintf("out(%d)", arg.Index)
+				} else if arg.Scratch {
+					args[i] = fmt.Sprintf("scratch(%d)", arg.Index)
+				} else if arg.Inline {
+					args[i] = fmt.Sprintf("inline(%d)", len(arg.Data))
 				} else {
 					args[i] = fmt.Sprintf("in(%d)", arg.Index)
 				}
@@ -571,6 +648,10 @@ func (f *Flow) ExecString(cache bool) string {
 				earg := f.ExecArg(i)
 				if earg.Out {
 					argv[i] = fmt.Sprintf("<out(%d)>", earg.Index)
+				} else if earg.Scratch {
+					argv[i] = fmt.Sprintf("<scratch(%d)>", earg.Index)
+				} else if earg.Inline {
+					argv[i] = fmt.Sprintf("<inline(%d)>", len(earg.Data))
 				} else {
 					argv[i] = "<in(" + f.Deps[earg.Index].Digest().Short() + ")>"
 				}
@@ -615,6 +696,10 @@ func (f *Flow) ExecString(cache bool) string {
 				earg := f.ExecArg(i)
 				if earg.Out {
 					argv[i] = fmt.Sprintf("<out(%d)>", earg.Index)
+				} else if earg.Scratch {
+					argv[i] = fmt.Sprintf("<scratch(%d)>", earg.Index)
+				} else if earg.Inline {
+					argv[i] = fmt.Sprintf("<inline(%d)>", len(earg.Data))
 				} else {
 					if fs, ok := f.Deps[earg.Index].Value.(reflow.Fileset); ok {
 						argv[i] = "<in(" + fs.Short() + ")>"
@@ -755,6 +840,12 @@ func (f *Flow) ExecConfig() reflow.ExecConfig {
 			if earg.Out {
 				args[i].Out = true
 				args[i].Index = earg.Index
+			} else if earg.Scratch {
+				args[i].Scratch = true
+				args[i].Index = earg.Index
+			} else if earg.Inline {
+				args[i].Inline = true
+				args[i].Data = earg.Data
 			} else {
 				fs := f.Deps[earg.Index].Value.(reflow.Fileset)
 				args[i].Fileset = &fs
@@ -770,6 +861,8 @@ func (f *Flow) ExecConfig() reflow.ExecConfig {
 		var (
 			reserved    = make(reflow.Resources)
 			outputIsDir = make([]bool, len(f.OutputIsDir))
+			envVars     = copyStringMap(f.EnvVars)
+			secrets     = copyStringMap(f.Secrets)
 		)
 		reserved.Set(f.Reserved)
 		copy(outputIsDir, f.OutputIsDir)
@@ -784,6 +877,11 @@ func (f *Flow) ExecConfig() reflow.ExecConfig {
 			Args:             args,
 			Resources:        reserved,
 			OutputIsDir:      outputIsDir,
+			Timeout:          f.Timeout,
+			Retries:          f.Retries,
+			Stdin:            f.Stdin,
+			EnvVars:          envVars,
+			Secrets:          secrets,
 		}
 	default:
 		panic("no exec config for op " + f.Op.String())
@@ -792,7 +890,10 @@ func (f *Flow) ExecConfig() reflow.ExecConfig {
 
 // depAssertions returns the assertions of this flow's dependencies.
 // The flows dependencies must already be computed before invoking depAssertions.
-// depAssertions is valid only for Extern, and Exec ops.
+// depAssertions is valid only for Extern, and Exec ops. The image of an
+// exec whose image was resolved (see EvalConfig.ImageMap) is considered
+// one of its dependencies: its assertions include the digest of the
+// image to which the exec's image reference resolved.
 func (f *Flow) depAssertions() []*reflow.Assertions {
 	var depAs []*reflow.Assertions
 	switch f.Op {
@@ -805,13 +906,20 @@ func (f *Flow) depAssertions() []*reflow.Assertions {
 		f.setArgmap()
 		for i := 0; i < f.NExecArg(); i++ {
 			earg := f.ExecArg(i)
-			if earg.Out {
+			if earg.Out || earg.Scratch || earg.Inline {
 				continue
 			}
 			if v := f.Deps[earg.Index].Value; v != nil {
 				depAs = append(depAs, v.(reflow.Fileset).Assertions())
 			}
 		}
+		if f.OriginalImage != "" && f.OriginalImage != f.Image {
+			var arch string
+			if f.Resources[reflow.Arm64] > 0 {
+				arch = reflow.Arm64
+			}
+			depAs = append(depAs, reflow.DockerAssertions(f.OriginalImage, arch, f.Image))
+		}
 	}
 	depAs, _ = reflow.DistinctAssertions(depAs...)
 	return depAs
@@ -876,12 +984,32 @@ func (f *Flow) WriteDigest(w io.Writer) {
 		io.WriteString(w, f.Image)
 		io.WriteString(w, f.Cmd)
 		for _, arg := range f.Argmap {
+			if arg.Scratch {
+				// Scratch directories are private to the exec and
+				// do not affect its result.
+				continue
+			}
+			if arg.Inline {
+				writeN(w, len(arg.Data))
+				io.WriteString(w, arg.Data)
+				continue
+			}
 			if arg.Out {
 				writeN(w, -arg.Index)
 			} else {
 				writeN(w, arg.Index)
 			}
 		}
+		if f.Stdin != "" {
+			writeN(w, len(f.Stdin))
+			io.WriteString(w, f.Stdin)
+		}
+		if len(f.EnvVars) > 0 {
+			writeEnvVars(w, f.EnvVars)
+		}
+		if f.ArraySize > 0 {
+			writeN(w, f.ArraySize)
+		}
 	case Groupby:
 		io.WriteString(w, f.Re.String())
 	case Map:
@@ -938,12 +1066,32 @@ func (f *Flow) physicalDigest(image string) digest.Digest {
 		io.WriteString(w, f.Cmd)
 		f.setArgmap()
 		for _, arg := range f.Argmap {
+			if arg.Scratch {
+				// Scratch directories are private to the exec and
+				// do not affect its result.
+				continue
+			}
+			if arg.Inline {
+				writeN(w, len(arg.Data))
+				io.WriteString(w, arg.Data)
+				continue
+			}
 			if arg.Out {
 				writeN(w, -arg.Index)
 			} else {
 				writeN(w, arg.Index)
 			}
 		}
+		if f.Stdin != "" {
+			writeN(w, len(f.Stdin))
+			io.WriteString(w, f.Stdin)
+		}
+		if len(f.EnvVars) > 0 {
+			writeEnvVars(w, f.EnvVars)
+		}
+		if f.ArraySize > 0 {
+			writeN(w, f.ArraySize)
+		}
 	}
 	if !f.ExtraDigest.IsZero() {
 		digest.WriteDigest(w, f.ExtraDigest)
@@ -995,6 +1143,22 @@ func (f *Flow) CacheKeys() []digest.Digest {
 	return append(f.physicalDigests(), f.Digest())
 }
 
+// NamespaceKey returns the cache key key qualified by the provided
+// cache namespace. Keys in different namespaces are distinct, so that
+// evaluations in one namespace never observe cache entries written in
+// another. The empty namespace is the default (shared) namespace, in
+// which keys are unqualified.
+func NamespaceKey(namespace string, key digest.Digest) digest.Digest {
+	if namespace == "" {
+		return key
+	}
+	w := Digester.NewWriter()
+	io.WriteString(w, "cachenamespace:")
+	io.WriteString(w, namespace)
+	digest.WriteDigest(w, key)
+	return w.Digest()
+}
+
 // Visitor returns a new FlowVisitor rooted at this node.
 func (f *Flow) Visitor() *FlowVisitor {
 	v := &FlowVisitor{}
@@ -1144,6 +1308,35 @@ func writeN(w io.Writer, n int) {
 	w.Write(b[:])
 }
 
+// writeEnvVars writes the environment variables env to w, in order
+// of their names.
+func writeEnvVars(w io.Writer, env map[string]string) {
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	writeN(w, len(names))
+	for _, name := range names {
+		writeN(w, len(name))
+		io.WriteString(w, name)
+		writeN(w, len(env[name]))
+		io.WriteString(w, env[name])
+	}
+}
+
+// copyStringMap returns a copy of the map m, or nil if m is empty.
+func copyStringMap(m map[string]string) map[string]string {
+	if len(m) == 0 {
+		return nil
+	}
+	c := make(map[string]string, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
 // AbbrevCmd returns the abbreviated command line for an exec flow.
 func (f *Flow) AbbrevCmd() string {
 	if f.Op != Exec {
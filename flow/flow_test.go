@@ -94,6 +94,20 @@ func TestPhysicalDigests(t *testing.T) {
 	}
 }
 
+func TestNamespaceKey(t *testing.T) {
+	key := op.Exec("image", "cmd1", reflow.Resources{"mem": 10}).Digest()
+	if got, want := flow.NamespaceKey("", key), key; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	a, b := flow.NamespaceKey("team-a", key), flow.NamespaceKey("team-b", key)
+	if a == key || b == key || a == b {
+		t.Errorf("expected distinct keys, got %v, %v, %v", key, a, b)
+	}
+	if got, want := flow.NamespaceKey("team-a", key), a; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
 func TestVisitor(t *testing.T) {
 	intern1 := op.Intern("url")
 	intern2 := op.Intern("url")
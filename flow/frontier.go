@@ -0,0 +1,107 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package flow
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"sync"
+
+	"github.com/grailbio/base/digest"
+	"github.com/grailbio/reflow/errors"
+)
+
+// A Frontier records the results of the execs, interns and externs
+// completed by an evaluation, so that an evaluation which is
+// interrupted (e.g., because the driver is killed) may be resumed
+// without recomputing them. Unlike the cache, the frontier is
+// maintained regardless of the evaluation's cache mode, and it is
+// keyed by flow digests rather than cache keys.
+type Frontier interface {
+	// Lookup returns the digest of the (marshaled) fileset recorded
+	// for the flow node with the given digest.
+	Lookup(flow digest.Digest) (fileset digest.Digest, ok bool)
+	// Record records that the flow node with the given digest
+	// computed the fileset marshaled in the evaluation's repository
+	// with the given digest.
+	Record(flow, fileset digest.Digest) error
+}
+
+// frontierEntry is the on-disk representation of a frontier entry.
+type frontierEntry struct {
+	Flow    digest.Digest `json:"flow"`
+	Fileset digest.Digest `json:"fileset"`
+}
+
+// FileFrontier is a Frontier that persists its entries to a local
+// file, one JSON entry per line. Entries are appended as they are
+// recorded, so that the file reflects the evaluation's frontier at
+// the time the driver is killed.
+type FileFrontier struct {
+	mu      sync.Mutex
+	file    *os.File
+	entries map[digest.Digest]digest.Digest
+}
+
+// OpenFileFrontier opens (or creates) the frontier stored at the
+// provided path. Entries recorded in the file by previous
+// evaluations are loaded; a truncated last entry (as may be written
+// by a killed driver) is ignored.
+func OpenFileFrontier(path string) (*FileFrontier, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		return nil, errors.E("openfrontier", path, err)
+	}
+	f := &FileFrontier{file: file, entries: make(map[digest.Digest]digest.Digest)}
+	scan := bufio.NewScanner(file)
+	for scan.Scan() {
+		var entry frontierEntry
+		if json.Unmarshal(scan.Bytes(), &entry) != nil {
+			continue
+		}
+		f.entries[entry.Flow] = entry.Fileset
+	}
+	if err := scan.Err(); err != nil {
+		_ = file.Close()
+		return nil, errors.E("openfrontier", path, err)
+	}
+	return f, nil
+}
+
+// Len returns the number of entries in the frontier.
+func (f *FileFrontier) Len() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.entries)
+}
+
+// Lookup implements Frontier.
+func (f *FileFrontier) Lookup(flow digest.Digest) (digest.Digest, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fileset, ok := f.entries[flow]
+	return fileset, ok
+}
+
+// Record implements Frontier.
+func (f *FileFrontier) Record(flow, fileset digest.Digest) error {
+	b, err := json.Marshal(frontierEntry{flow, fileset})
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, err := f.file.Write(append(b, '\n')); err != nil {
+		return errors.E("recordfrontier", f.file.Name(), err)
+	}
+	f.entries[flow] = fileset
+	return nil
+}
+
+// Close closes the frontier's underlying file.
+func (f *FileFrontier) Close() error {
+	return f.file.Close()
+}
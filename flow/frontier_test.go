@@ -0,0 +1,104 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package flow_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/grailbio/reflow"
+	"github.com/grailbio/reflow/flow"
+	op "github.com/grailbio/reflow/test/flow"
+	"github.com/grailbio/reflow/test/testutil"
+)
+
+func TestFileFrontier(t *testing.T) {
+	dir, err := ioutil.TempDir("", "frontier")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "frontier")
+	f, err := flow.OpenFileFrontier(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, b := reflow.Digester.FromString("a"), reflow.Digester.FromString("b")
+	if err = f.Record(a, b); err != nil {
+		t.Fatal(err)
+	}
+	if err = f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// Simulate an entry truncated by a killed driver.
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = file.WriteString(`{"flow":"sha256:`); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	f, err = flow.OpenFileFrontier(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if got, want := f.Len(), 1; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got, ok := f.Lookup(a); !ok || got != b {
+		t.Errorf("got %v, %v, want %v, true", got, ok, b)
+	}
+	if _, ok := f.Lookup(b); ok {
+		t.Errorf("unexpected entry for %v", b)
+	}
+}
+
+func TestEvalFrontier(t *testing.T) {
+	dir, err := ioutil.TempDir("", "frontier")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	frontier, err := flow.OpenFileFrontier(filepath.Join(dir, "frontier"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer frontier.Close()
+
+	e, config, done := newTestScheduler()
+	defer done()
+	config.Frontier = frontier
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	exec := op.Exec("image", "command", testutil.Resources)
+	rc := testutil.EvalAsync(ctx, flow.NewEval(exec, config))
+	e.Ok(ctx, exec, testutil.WriteFiles(e.Repo, "execout"))
+	r := <-rc
+	if r.Err != nil {
+		t.Fatal(r.Err)
+	}
+	if got, want := frontier.Len(), 1; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	// A resumed evaluation uses the recorded result without
+	// submitting the exec.
+	resumed := &flow.Flow{Op: flow.Exec, Ident: exec.Ident, Cmd: exec.Cmd, Image: exec.Image, Resources: exec.Resources}
+	r = <-testutil.EvalAsync(ctx, flow.NewEval(resumed, config))
+	if r.Err != nil {
+		t.Fatal(r.Err)
+	}
+	if got, want := r.Val, testutil.Files("execout"); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
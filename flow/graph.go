@@ -0,0 +1,362 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package flow
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grailbio/reflow"
+)
+
+const (
+	// graphPublishInterval is the minimum interval between two
+	// snapshots of the evaluation graph published by the evaluator.
+	graphPublishInterval = time.Second
+	// graphInspectTimeout is the timeout for inspecting a node's exec.
+	graphInspectTimeout = 30 * time.Second
+	// graphDefaultLimit is the default number of nodes returned by a
+	// graph query.
+	graphDefaultLimit = 1000
+)
+
+// Graph node states, as presented by a GraphServer.
+const (
+	GraphWaiting = "waiting"
+	GraphRunning = "running"
+	GraphCached  = "cached"
+	GraphFailed  = "failed"
+	GraphDone    = "done"
+)
+
+// GraphNode is a node in an evaluation graph served by a GraphServer.
+type GraphNode struct {
+	// ID is the digest of the node's flow.
+	ID string `json:"id"`
+	// Op is the node's operation.
+	Op string `json:"op"`
+	// Ident is the node's identifier, if any.
+	Ident string `json:"ident,omitempty"`
+	// Position is the source position of the node, if any.
+	Position string `json:"position,omitempty"`
+	// State is the node's (simplified) state: one of GraphWaiting,
+	// GraphRunning, GraphCached, GraphFailed or GraphDone.
+	State string `json:"state"`
+	// EvalState is the evaluator's state of the node.
+	EvalState string `json:"evalstate"`
+	// Err is the node's error, if it failed.
+	Err string `json:"err,omitempty"`
+	// Exec is the URI of the node's exec, if any.
+	Exec string `json:"exec,omitempty"`
+	// Deps are the IDs of the node's dependencies.
+	Deps []string `json:"deps,omitempty"`
+
+	exec reflow.Exec
+}
+
+// graphNodeState returns the GraphServer state of flow f.
+func graphNodeState(f *Flow) string {
+	switch f.State {
+	case Done:
+		switch {
+		case f.Err != nil:
+			return GraphFailed
+		case f.Cached:
+			return GraphCached
+		default:
+			return GraphDone
+		}
+	case Running, Execing:
+		return GraphRunning
+	default:
+		return GraphWaiting
+	}
+}
+
+// A GraphServer serves an interactive view of an evaluation graph
+// over HTTP. The view is updated from snapshots of the graph published
+// by the evaluator (see EvalConfig.Graph) while it evaluates, so that
+// the states of nodes (waiting, running, cached, failed or done) are
+// shown live. Nodes may be filtered by state and identifier, and the
+// execs of individual nodes may be inspected.
+//
+// A GraphServer handles the following paths, relative to where it
+// is mounted:
+//
+//	/            an HTML view of the graph
+//	/graph.json  the graph's nodes, filtered by the query parameters
+//	             state, ident (a substring) and limit
+//	/node        the node with the query parameter id, its dependents,
+//	             and, if it has an exec, the exec's inspect
+type GraphServer struct {
+	mu sync.Mutex
+	// nodes stores the nodes of the last published snapshot, by ID.
+	nodes map[string]*GraphNode
+	// order is the order in which nodes were visited in the last
+	// snapshot, from the root.
+	order []string
+	// dependents maps node IDs to the IDs of the nodes that depend on them.
+	dependents map[string][]string
+	updated    time.Time
+	published  time.Time
+}
+
+// NewGraphServer returns a new, empty GraphServer.
+func NewGraphServer() *GraphServer {
+	return &GraphServer{nodes: make(map[string]*GraphNode)}
+}
+
+// Publish publishes a snapshot of the graph rooted at root. Publish
+// is rate limited: snapshots published within graphPublishInterval of
+// the previous one are dropped unless force is true.
+func (g *GraphServer) Publish(root *Flow, force bool) {
+	g.mu.Lock()
+	if !force && time.Since(g.published) < graphPublishInterval {
+		g.mu.Unlock()
+		return
+	}
+	g.published = time.Now()
+	g.mu.Unlock()
+
+	var (
+		nodes      = make(map[string]*GraphNode)
+		order      []string
+		dependents = make(map[string][]string)
+	)
+	for v := root.Visitor(); v.Walk(); v.Visit() {
+		if f := v.Parent; f != nil {
+			for _, dep := range f.Deps {
+				v.Push(dep)
+			}
+		}
+		n := &GraphNode{
+			ID:        v.Digest().String(),
+			Op:        v.Op.String(),
+			Ident:     v.Ident,
+			Position:  v.Position,
+			State:     graphNodeState(v.Flow),
+			EvalState: v.State.Name(),
+			exec:      v.Exec,
+		}
+		if n.State == GraphFailed {
+			n.Err = v.Err.Error()
+		}
+		if v.Exec != nil {
+			n.Exec = v.Exec.URI()
+		}
+		for _, dep := range v.Deps {
+			id := dep.Digest().String()
+			n.Deps = append(n.Deps, id)
+			dependents[id] = append(dependents[id], n.ID)
+		}
+		if _, ok := nodes[n.ID]; !ok {
+			order = append(order, n.ID)
+		}
+		nodes[n.ID] = n
+	}
+	g.mu.Lock()
+	g.nodes, g.order, g.dependents = nodes, order, dependents
+	g.updated = time.Now()
+	g.mu.Unlock()
+}
+
+// GraphResult is the result of a graph query.
+type GraphResult struct {
+	// Updated is the time at which the graph was last published.
+	Updated time.Time `json:"updated"`
+	// Counts is the number of nodes in each state.
+	Counts map[string]int `json:"counts"`
+	// Total is the number of nodes matching the query; at most
+	// the query's limit are returned in Nodes.
+	Total int `json:"total"`
+	// Nodes are the nodes matching the query.
+	Nodes []*GraphNode `json:"nodes"`
+}
+
+// Query returns the nodes in the last published snapshot with the
+// given state and whose identifiers contain ident; empty values match
+// all nodes. At most limit nodes are returned.
+func (g *GraphServer) Query(state, ident string, limit int) GraphResult {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	r := GraphResult{Updated: g.updated, Counts: make(map[string]int), Nodes: []*GraphNode{}}
+	for _, id := range g.order {
+		n := g.nodes[id]
+		r.Counts[n.State]++
+		if (state != "" && n.State != state) || !strings.Contains(n.Ident, ident) {
+			continue
+		}
+		r.Total++
+		if len(r.Nodes) < limit {
+			r.Nodes = append(r.Nodes, n)
+		}
+	}
+	return r
+}
+
+// GraphNodeResult is the result of a node query.
+type GraphNodeResult struct {
+	*GraphNode
+	// Dependents are the IDs of the nodes which depend on this node.
+	Dependents []string `json:"dependents,omitempty"`
+	// Inspect is the inspect of the node's exec, if any.
+	Inspect *reflow.ExecInspect `json:"inspect,omitempty"`
+	// InspectErr is the error, if any, encountered while inspecting
+	// the node's exec.
+	InspectErr string `json:"inspecterr,omitempty"`
+}
+
+// Node returns the node with the provided ID, inspecting its exec
+// if it has one.
+func (g *GraphServer) Node(ctx context.Context, id string) (GraphNodeResult, bool) {
+	g.mu.Lock()
+	n, ok := g.nodes[id]
+	dependents := append([]string(nil), g.dependents[id]...)
+	g.mu.Unlock()
+	if !ok {
+		return GraphNodeResult{}, false
+	}
+	sort.Strings(dependents)
+	r := GraphNodeResult{GraphNode: n, Dependents: dependents}
+	if n.exec != nil {
+		ctx, cancel := context.WithTimeout(ctx, graphInspectTimeout)
+		resp, err := n.exec.Inspect(ctx, nil)
+		cancel()
+		if err != nil {
+			r.InspectErr = err.Error()
+		} else {
+			r.Inspect = resp.Inspect
+		}
+	}
+	return r, true
+}
+
+// ServeHTTP implements http.Handler.
+func (g *GraphServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch path := strings.TrimPrefix(r.URL.Path, "/"); path {
+	case "":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = io.WriteString(w, graphHTML)
+	case "graph.json":
+		q := r.URL.Query()
+		limit := graphDefaultLimit
+		if s := q.Get("limit"); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 0 {
+				http.Error(w, "invalid limit "+s, http.StatusBadRequest)
+				return
+			}
+			limit = n
+		}
+		writeGraphJSON(w, g.Query(q.Get("state"), q.Get("ident"), limit))
+	case "node":
+		n, ok := g.Node(r.Context(), r.URL.Query().Get("id"))
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		writeGraphJSON(w, n)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func writeGraphJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// graphHTML is the interactive view of the graph. It polls graph.json
+// for the (filtered) list of nodes, and fetches individual nodes on
+// demand, so that it remains usable for very large graphs.
+const graphHTML = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>reflow evaluation</title>
+<style>
+body { font-family: monospace; font-size: 13px; margin: 1em; }
+table { border-collapse: collapse; }
+td, th { padding: 2px 8px; text-align: left; vertical-align: top; }
+tr.node { cursor: pointer; }
+tr.node:hover { background: #eee; }
+.waiting { color: #888; }
+.running { color: #06c; font-weight: bold; }
+.cached { color: #690; }
+.failed { color: #c00; font-weight: bold; }
+.done { color: #090; }
+#detail { white-space: pre-wrap; border-left: 2px solid #ccc; padding-left: 1em; }
+a { cursor: pointer; color: #06c; }
+</style>
+</head>
+<body>
+<div>
+state: <select id="state">
+<option value="">all</option><option>waiting</option><option>running</option>
+<option>cached</option><option>failed</option><option>done</option>
+</select>
+ident: <input id="ident" size="30">
+<span id="counts"></span>
+</div>
+<table><tr>
+<td><table id="nodes"></table></td>
+<td id="detail"></td>
+</tr></table>
+<script>
+function esc(s) {
+  return String(s).replace(/[&<>"]/g, function(c) {
+    return {"&": "&amp;", "<": "&lt;", ">": "&gt;", '"': "&quot;"}[c];
+  });
+}
+function refresh() {
+  var q = "graph.json?state=" + encodeURIComponent(document.getElementById("state").value) +
+    "&ident=" + encodeURIComponent(document.getElementById("ident").value);
+  fetch(q).then(function(r) { return r.json(); }).then(function(g) {
+    var counts = [];
+    for (var s in g.counts) counts.push('<span class="' + s + '">' + s + ":" + g.counts[s] + "</span>");
+    document.getElementById("counts").innerHTML = counts.join(" ") +
+      " (showing " + g.nodes.length + "/" + g.total + ", updated " + g.updated + ")";
+    var rows = ["<tr><th>id</th><th>op</th><th>ident</th><th>state</th><th>position</th></tr>"];
+    g.nodes.forEach(function(n) {
+      rows.push('<tr class="node" onclick="show(\'' + n.id + '\')"><td>' + n.id.substr(0, 15) +
+        "</td><td>" + esc(n.op) + "</td><td>" + esc(n.ident || "") + '</td><td class="' + n.state + '">' +
+        n.state + "</td><td>" + esc(n.position || "") + "</td></tr>");
+    });
+    document.getElementById("nodes").innerHTML = rows.join("");
+  });
+}
+function links(ids) {
+  return (ids || []).map(function(id) {
+    return '<a onclick="show(\'' + id + '\')">' + id.substr(0, 15) + "</a>";
+  }).join(" ");
+}
+function show(id) {
+  fetch("node?id=" + encodeURIComponent(id)).then(function(r) { return r.json(); }).then(function(n) {
+    var deps = n.deps, dependents = n.dependents, inspect = n.inspect;
+    delete n.deps; delete n.dependents; delete n.inspect;
+    document.getElementById("detail").innerHTML = esc(JSON.stringify(n, null, 2)) +
+      "\n\ndeps: " + links(deps) + "\ndependents: " + links(dependents) +
+      (inspect ? "\n\ninspect:\n" + esc(JSON.stringify(inspect, null, 2)) : "");
+  });
+}
+document.getElementById("state").onchange = refresh;
+document.getElementById("ident").onchange = refresh;
+refresh();
+setInterval(refresh, 2000);
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,116 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package flow_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/grailbio/reflow/errors"
+	"github.com/grailbio/reflow/flow"
+	op "github.com/grailbio/reflow/test/flow"
+	"github.com/grailbio/reflow/test/testutil"
+)
+
+func getGraphJSON(t *testing.T, url string, v interface{}) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("get %s: %s", url, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGraphServerStates(t *testing.T) {
+	var (
+		cached  = op.Exec("image", "cached", testutil.Resources)
+		failed  = op.Exec("image", "failed", testutil.Resources)
+		running = op.Exec("image", "running", testutil.Resources, cached)
+		root    = op.Merge(running, failed)
+	)
+	cached.State, cached.Cached = flow.Done, true
+	failed.State, failed.Err = flow.Done, errors.Recover(errors.New("failed"))
+	running.State = flow.Execing
+	g := flow.NewGraphServer()
+	g.Publish(root, true)
+
+	r := g.Query("", "", 100)
+	if got, want := len(r.Nodes), 4; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for state, want := range map[string]int{
+		flow.GraphCached:  1,
+		flow.GraphFailed:  1,
+		flow.GraphRunning: 1,
+		flow.GraphWaiting: 1,
+	} {
+		if got := r.Counts[state]; got != want {
+			t.Errorf("%s: got %v, want %v", state, got, want)
+		}
+	}
+	r = g.Query(flow.GraphFailed, "", 100)
+	if len(r.Nodes) != 1 || r.Nodes[0].Err == "" {
+		t.Fatalf("bad failed nodes: %+v", r.Nodes)
+	}
+	if r = g.Query("", "", 2); r.Total != 4 || len(r.Nodes) != 2 {
+		t.Errorf("got %d/%d nodes, want 2/4", len(r.Nodes), r.Total)
+	}
+	n, ok := g.Node(context.Background(), cached.Digest().String())
+	if !ok {
+		t.Fatal("node not found")
+	}
+	if got, want := n.Dependents, []string{running.Digest().String()}; len(got) != 1 || got[0] != want[0] {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestEvalGraph(t *testing.T) {
+	e, config, done := newTestScheduler()
+	defer done()
+	g := flow.NewGraphServer()
+	config.Graph = g
+	srv := httptest.NewServer(g)
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	exec := op.Exec("image", "command", testutil.Resources)
+	rc := testutil.EvalAsync(ctx, flow.NewEval(exec, config))
+	e.Ok(ctx, exec, testutil.WriteFiles(e.Repo, "execout"))
+	if r := <-rc; r.Err != nil {
+		t.Fatal(r.Err)
+	}
+
+	var r flow.GraphResult
+	getGraphJSON(t, srv.URL+"/graph.json?state=done", &r)
+	if got, want := len(r.Nodes), 1; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	var n flow.GraphNodeResult
+	getGraphJSON(t, srv.URL+"/node?id="+r.Nodes[0].ID, &n)
+	if got, want := n.ID, exec.Digest().String(); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if n.Exec == "" {
+		t.Error("missing exec")
+	}
+	resp, err := http.Get(srv.URL + "/node?id=unknown")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got, want := resp.StatusCode, http.StatusNotFound; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package flow
+
+import (
+	"sort"
+
+	"github.com/grailbio/reflow"
+)
+
+// A Plan is a static estimate of the work required to evaluate a
+// flow, computed from the flow graph without evaluating it. Nodes
+// whose expansion depends on values computed during evaluation (maps
+// and continuations) cannot be expanded statically, and so the plan
+// of a flow with such nodes is a lower bound.
+type Plan struct {
+	// Idents summarizes the plan's execs by ident, ordered by ident.
+	Idents []PlanIdent
+	// Execs is the number of execs in the plan.
+	Execs int
+	// Interns and Externs are the number of interns and externs in
+	// the plan.
+	Interns, Externs int
+	// Peak is the estimated peak amount of resources required by
+	// execs which may run concurrently. Execs may run concurrently if
+	// they are at the same depth in the flow graph, i.e., they are
+	// separated from the graph's leaves by the same number of execs.
+	Peak reflow.Resources
+	// Dynamic is the number of nodes which could not be expanded.
+	Dynamic int
+}
+
+// PlanIdent summarizes the execs of a plan with a given ident.
+type PlanIdent struct {
+	// Ident is the execs' ident; Position is the source position of
+	// one of them.
+	Ident, Position string
+	// Execs is the number of execs with the ident.
+	Execs int
+	// Resources are the largest resources declared by the execs.
+	Resources reflow.Resources
+	// Image and Cmd are the image and command of one of the execs;
+	// they may be used to look up profiles of earlier runs.
+	Image, Cmd string
+}
+
+// Static tells whether the plan was fully expanded, so that it is
+// exact rather than a lower bound.
+func (p *Plan) Static() bool {
+	return p.Dynamic == 0
+}
+
+// NewPlan computes the plan of flow f.
+func NewPlan(f *Flow) *Plan {
+	var (
+		p      = &Plan{Peak: make(reflow.Resources)}
+		idents = make(map[string]*PlanIdent)
+		depths = make(map[*Flow]int)
+		levels []reflow.Resources
+		depth  func(f *Flow) int
+	)
+	depth = func(f *Flow) int {
+		if d, ok := depths[f]; ok {
+			return d
+		}
+		// Mark the node visited (at depth 0) so that cycles, which are
+		// not expected, do not recur indefinitely.
+		depths[f] = 0
+		var d int
+		for _, dep := range f.Deps {
+			if dd := depth(dep); dd > d {
+				d = dd
+			}
+		}
+		switch f.Op {
+		case Exec:
+			p.Execs++
+			for len(levels) <= d {
+				levels = append(levels, make(reflow.Resources))
+			}
+			levels[d].Add(levels[d], f.Resources)
+			d++
+			id := idents[f.Ident]
+			if id == nil {
+				id = &PlanIdent{Ident: f.Ident, Position: f.Position, Image: f.Image, Cmd: f.Cmd, Resources: make(reflow.Resources)}
+				idents[f.Ident] = id
+			}
+			id.Execs++
+			id.Resources.Max(id.Resources, f.Resources)
+		case Intern:
+			p.Interns++
+		case Extern:
+			p.Externs++
+		case Map, K, Kctx:
+			p.Dynamic++
+		}
+		depths[f] = d
+		return d
+	}
+	depth(f)
+	for _, level := range levels {
+		p.Peak.Max(p.Peak, level)
+	}
+	for _, id := range idents {
+		p.Idents = append(p.Idents, *id)
+	}
+	sort.Slice(p.Idents, func(i, j int) bool { return p.Idents[i].Ident < p.Idents[j].Ident })
+	return p
+}
@@ -0,0 +1,58 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package flow_test
+
+import (
+	"testing"
+
+	"github.com/grailbio/reflow"
+	"github.com/grailbio/reflow/flow"
+	op "github.com/grailbio/reflow/test/flow"
+	"github.com/grailbio/reflow/values"
+)
+
+func TestPlan(t *testing.T) {
+	// fanout(10) is 10 concurrent aligns, each depending on an intern.
+	align := fanout(10)
+	merge := &flow.Flow{Op: flow.Exec, Ident: "merge", Image: "image", Cmd: "merge", Deps: align.Deps,
+		Resources: reflow.Resources{"mem": 8 << 30, "cpu": 4}}
+	p := flow.NewPlan(op.Extern("s3://bucket/merged", merge))
+	if got, want := p.Execs, 11; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := p.Interns, 10; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := p.Externs, 1; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if !p.Static() {
+		t.Error("expected static plan")
+	}
+	if got, want := len(p.Idents), 2; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got, want := p.Idents[0].Ident, "align"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := p.Idents[0].Execs, 10; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := p.Idents[1].Resources, merge.Resources; !got.Equal(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	// The aligns run concurrently; the merge runs after them.
+	var want reflow.Resources
+	want.Scale(align.Deps[0].Resources, 10)
+	want.Max(want, merge.Resources)
+	if got := p.Peak; !got.Equal(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	k := op.K("k", func(vs []values.T) *flow.Flow { return align }, op.Intern("s3://bucket/samples/"))
+	if p := flow.NewPlan(k); p.Static() || p.Execs != 0 {
+		t.Errorf("got %+v, want dynamic plan without execs", p)
+	}
+}
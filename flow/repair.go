@@ -77,7 +77,7 @@ func (r *Repair) Do(ctx context.Context, f *Flow) {
 		hit  bool
 	)
 	r.Log.Debugf("Repair.Do(%v)", f)
-	keys := f.CacheKeys()
+	keys := r.cacheKeys(f)
 	for _, key := range keys {
 		if r.GetLimit != nil {
 			if err := r.GetLimit.Acquire(ctx, 1); err != nil {
@@ -241,7 +241,15 @@ func (r *Repair) eval(f *Flow) {
 			vs[i] = dep.Value
 		}
 
-		ff := f.Kctx(kCtx{context.Background(), r.Repository}, vs)
+		// Continuations are repaired with the shard sizes recorded
+		// by the evaluation, if any.
+		ff := f.Kctx(kCtx{
+			Context: context.Background(),
+			repo:    r.Repository,
+			assoc:   r.Assoc,
+			key:     NamespaceKey(r.CacheNamespace, f.Digest()),
+			read:    true,
+		}, vs)
 		f.Fork(ff)
 		f.Parent.State = Done
 	case Coerce:
@@ -275,7 +283,7 @@ func (r *Repair) Go(ctx context.Context, concurrency int) {
 		r.g.Go(func() error {
 			for wb := range r.writebacks {
 				r.Log.Printf("write back %s %s %s", wb.Flow.Ident, wb.Flow, wb.Fsid)
-				for _, key := range wb.Flow.CacheKeys() {
+				for _, key := range r.cacheKeys(wb.Flow) {
 					err := r.Assoc.Store(ctx, assoc.FilesetV2, key, wb.Fsid)
 					switch {
 					case errors.Is(errors.Precondition, err):
@@ -0,0 +1,114 @@
+package infra
+
+import (
+	"context"
+	"flag"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/grailbio/infra"
+	"github.com/grailbio/reflow/blob"
+	"github.com/grailbio/reflow/blob/fileblob"
+	"github.com/grailbio/reflow/blob/httpblob"
+	"github.com/grailbio/reflow/errors"
+)
+
+func init() {
+	infra.Register("fileblob", new(FileBlob))
+}
+
+// FileBlob is the infra provider for the options used to access
+// files on (shared) POSIX filesystems through file:// URLs, as in:
+//
+//	fileblob: fileblob,roots=/mnt/filer:/scratch,checksum=true
+//
+// Only files under the configured roots may be accessed. Reflowlets
+// (which run as root on cluster hosts) serve file:// URLs only if
+// explicitly enabled.
+type FileBlob struct {
+	checksum  bool
+	roots     string
+	reflowlet bool
+}
+
+// Help implements infra.Provider.
+func (*FileBlob) Help() string {
+	return "configure access to files on shared filesystems (file:// URLs)"
+}
+
+// Flags implements infra.Provider.
+func (f *FileBlob) Flags(flags *flag.FlagSet) {
+	flags.BoolVar(&f.checksum, "checksum", false, "identify files by the checksums of their contents instead of by their size and modification time")
+	flags.StringVar(&f.roots, "roots", "", "colon-separated list of the (absolute) directories under which files may be accessed")
+	flags.BoolVar(&f.reflowlet, "reflowlet", false, "also serve file URLs from reflowlets, for filesystems which are shared by the cluster's instances")
+}
+
+// Store returns a filesystem blob store with these options.
+func (f *FileBlob) Store() *fileblob.Store {
+	store := &fileblob.Store{Checksum: f.checksum}
+	for _, root := range strings.Split(f.roots, ":") {
+		if root != "" {
+			store.Roots = append(store.Roots, root)
+		}
+	}
+	return store
+}
+
+// FileStore returns a filesystem blob store configured according
+// to the given config, if any.
+func FileStore(config infra.Config) *fileblob.Store {
+	var f *FileBlob
+	if err := config.Instance(&f); err == nil && f != nil {
+		return f.Store()
+	}
+	return new(fileblob.Store)
+}
+
+// BlobMux returns a blob mux for all supported URL schemes: s3 (see
+// S3Store), http, https and ftp (see package httpblob), and file
+// (see FileStore).
+func BlobMux(config infra.Config, sess *session.Session) blob.Mux {
+	mux := blob.Mux{
+		"s3":            S3Store(config, sess),
+		fileblob.Scheme: FileStore(config),
+	}
+	httpblob.Register(mux, nil)
+	return mux
+}
+
+// ReflowletBlobMux returns the blob mux used by reflowlets. It is
+// BlobMux, except that file URLs are served only if the fileblob
+// provider is configured to serve them from reflowlets.
+func ReflowletBlobMux(config infra.Config, sess *session.Session) blob.Mux {
+	mux := BlobMux(config, sess)
+	var f *FileBlob
+	if err := config.Instance(&f); err != nil || f == nil || !f.reflowlet {
+		delete(mux, fileblob.Scheme)
+	}
+	return mux
+}
+
+// OfflineBlobMux returns a blob mux for use without network access:
+// file URLs are accessed through the provided store, while accessing
+// URLs of any of the other schemes supported by BlobMux fails with
+// an errors.NotAllowed error.
+func OfflineBlobMux(file *fileblob.Store) blob.Mux {
+	mux := blob.Mux{
+		"s3":            offlineStore("s3"),
+		fileblob.Scheme: file,
+	}
+	for _, scheme := range httpblob.Schemes {
+		mux[scheme] = offlineStore(scheme)
+	}
+	return mux
+}
+
+// offlineStore is a blob.Store for a URL scheme which requires network
+// access; none of its buckets may be accessed.
+type offlineStore string
+
+// Bucket implements blob.Store.
+func (s offlineStore) Bucket(ctx context.Context, name string) (blob.Bucket, error) {
+	return nil, errors.E("blob.Bucket", string(s)+"://"+name, errors.NotAllowed,
+		errors.New("network access is disabled in offline mode; only local (file://) data may be used"))
+}
@@ -28,6 +28,7 @@ func init() {
 	infra.Register("kv", new(KV))
 	infra.Register("reflowletconfig", new(ReflowletConfig))
 	infra.Register("docker", new(DockerConfig))
+	infra.Register("admin", new(Admin))
 	infra.Register("predictorconfig", new(PredictorConfig))
 	infra.Register("testpredictorconfig", new(PredictorTestConfig))
 }
@@ -56,6 +57,16 @@ const (
 	Docker     = "docker"
 	Predictor  = "predictor"
 	RunID      = "runid"
+	Admins     = "admins"
+	Templates  = "templates"
+	// RunExporter is the (optional) exporter of completed run summaries.
+	RunExporter = "runexporter"
+	// S3Buckets is the per-bucket options of S3 access.
+	S3Buckets = "s3buckets"
+	// FileBlob is the options of filesystem (file://) access.
+	FileBlob = "fileblob"
+	// LabelPolicy is the (optional) policy on the labels of runs.
+	LabelPolicy = "labelpolicy"
 )
 
 // User is the infrastructure provider for username.
@@ -91,6 +102,33 @@ func (u User) User() string {
 	return (string)(u)
 }
 
+// Admin is the infrastructure provider for the set of cluster
+// administrators. Administrators may view and manage the runs and
+// resources of all users on shared infrastructure.
+type Admin struct {
+	users string
+}
+
+// Help implements infra.Provider
+func (a *Admin) Help() string {
+	return "provide a colon-separated list of cluster administrators"
+}
+
+// Flags implements infra.Provider.
+func (a *Admin) Flags(flags *flag.FlagSet) {
+	flags.StringVar(&a.users, "users", "", "colon-separated list of administrator user names")
+}
+
+// IsAdmin tells whether the given user is a cluster administrator.
+func (a *Admin) IsAdmin(user string) bool {
+	for _, u := range strings.Split(a.users, ":") {
+		if u = strings.TrimSpace(u); u != "" && u == user {
+			return true
+		}
+	}
+	return false
+}
+
 // BootstrapImage is the URL of the image used for instance bootstrap.
 type BootstrapImage string
 
@@ -425,6 +463,18 @@ type ReflowletConfig struct {
 	LogStatsDuration time.Duration `yaml:"logmemstatsduration,omitempty"`
 	// VolumeWatcher defines a set of parameters for the volume watcher on the reflowlet.
 	VolumeWatcher VolumeWatcher `yaml:"volumewatcher,omitempty"`
+	// Runtime is the container runtime used by the reflowlet to run execs:
+	// "docker" (the default), "podman" (rootless), or "apptainer", for hosts
+	// on which a root Docker daemon is not available.
+	Runtime string `yaml:"runtime,omitempty"`
+	// StalePolicy is the policy applied to stale allocs, whose owners
+	// stopped maintaining their leases while they were running execs
+	// (see pool.ParseStalePolicy): "keep" (the default) or "teardown".
+	StalePolicy string `yaml:"stalepolicy,omitempty"`
+	// StaleGrace is the duration for which an alloc is stale before the
+	// stale policy is applied to it. Until then, a new owner may adopt
+	// the alloc by resuming its keepalives (see "reflow cleanup-allocs").
+	StaleGrace time.Duration `yaml:"stalegrace,omitempty"`
 }
 
 // MergeReflowletConfig merges/overrides field values into `base` from `other`.
@@ -457,6 +507,15 @@ func MergeReflowletConfig(base, other ReflowletConfig) ReflowletConfig {
 	if other.VolumeWatcher.SlowIncreaseFactor > 0 {
 		rc.VolumeWatcher.SlowIncreaseFactor = other.VolumeWatcher.SlowIncreaseFactor
 	}
+	if other.Runtime != "" {
+		rc.Runtime = other.Runtime
+	}
+	if other.StalePolicy != "" {
+		rc.StalePolicy = other.StalePolicy
+	}
+	if other.StaleGrace != 0 {
+		rc.StaleGrace = other.StaleGrace
+	}
 	return rc
 }
 
@@ -464,6 +523,9 @@ var DefaultReflowletConfig = ReflowletConfig{
 	MaxIdleDuration:  10 * time.Minute,
 	LogStatsDuration: 1 * time.Minute,
 	VolumeWatcher:    DefaultVolumeWatcher,
+	Runtime:          "docker",
+	StalePolicy:      "keep",
+	StaleGrace:       30 * time.Minute,
 }
 
 // DefaultVolumeWatcher are a default set of volume watcher parameters which will double the disk size
@@ -485,6 +547,16 @@ func (rp *ReflowletConfig) Init() error {
 		return nil
 	}
 	*rp = MergeReflowletConfig(DefaultReflowletConfig, *rp)
+	switch rp.Runtime {
+	case "docker", "podman", "apptainer":
+	default:
+		return fmt.Errorf("reflowletconfig: unsupported runtime %q (must be one of docker, podman, apptainer)", rp.Runtime)
+	}
+	switch rp.StalePolicy {
+	case "keep", "teardown":
+	default:
+		return fmt.Errorf("reflowletconfig: unsupported stale policy %q (must be one of keep, teardown)", rp.StalePolicy)
+	}
 	return nil
 }
 
@@ -504,20 +576,26 @@ func (rp *ReflowletConfig) Flags(flags *flag.FlagSet) {
 	flags.DurationVar(&rp.VolumeWatcher.FastThresholdDuration, "fastthresholdduration", 24*time.Hour, "FastThresholdDuration is the duration to use to determine whether disk usage grew fast or slow.")
 	flags.UintVar(&rp.VolumeWatcher.FastIncreaseFactor, "fastincreasefactor", 10, "FastIncreaseFactor is the factor by which to increase disk size if it filled up fast.")
 	flags.UintVar(&rp.VolumeWatcher.SlowIncreaseFactor, "slowincreasefactor", 5, "SlowIncreaseFactor is the factor by which to increase disk size if it filled up slow.")
+	flags.StringVar(&rp.Runtime, "runtime", "docker", "Runtime is the container runtime used to run execs (docker, podman or apptainer).")
+	flags.StringVar(&rp.StalePolicy, "stalepolicy", "keep", "StalePolicy is the policy applied to allocs whose owners stopped their keepalives while they run execs (keep or teardown).")
+	flags.DurationVar(&rp.StaleGrace, "stalegrace", 30*time.Minute, "StaleGrace is the duration for which an alloc is stale before the stale policy is applied.")
 }
 
-// DockerConfig sets the docker memory limit to either be hard or soft.
+// DockerConfig sets the docker resource limits to be soft, hard (memory
+// is limited) or strict (memory, CPU and block I/O are limited).
 type DockerConfig string
 
 // Help implements infra.Provider.
 func (m DockerConfig) Help() string {
-	return "set hard/soft memory limits for docker containers"
+	return "set soft/hard/strict resource limits for docker containers"
 }
 
 // Init implements infra.Provider.
 func (m *DockerConfig) Init() error {
-	if m.Value() != "soft" && m.Value() != "hard" {
-		return fmt.Errorf("invalid memlimit: %v: memlimit is soft or hard only", m.Value())
+	switch m.Value() {
+	case "soft", "hard", "strict":
+	default:
+		return fmt.Errorf("invalid memlimit: %v: memlimit is soft, hard or strict only", m.Value())
 	}
 	return nil
 }
@@ -527,7 +605,7 @@ func (m *DockerConfig) Flags(flags *flag.FlagSet) {
 	flags.StringVar((*string)(m), "memlimit", "soft", "memlimit")
 }
 
-// Value returns the docker memory limit mode.
+// Value returns the docker resource limit mode.
 func (m *DockerConfig) Value() string {
 	return string(*m)
 }
@@ -538,7 +616,9 @@ func (m *DockerConfig) Value() string {
 // at least MinData datapoints--profiles containing the resource to be predicted.
 // No prediction will look at more than MaxInspect ExecInspects to obtain theses
 // profiles. An exec's predicted memory usage is the MemPercentile'th percentile
-// of the maximum memory usage of the exec.
+// of the maximum memory usage of the exec. Similarly, its predicted cpu and
+// scratch disk usage are the CPUPercentile'th and DiskPercentile'th percentiles
+// of its peak load and maximum disk usage, respectively.
 type PredictorConfig struct {
 	// MinData is the minimum number of datapoints
 	// required to make a resource prediction.
@@ -549,6 +629,16 @@ type PredictorConfig struct {
 	// MemPercentile is the percentile that will
 	// be used to predict memory usage for all tasks.
 	MemPercentile float64 `yaml:"mempercentile"`
+	// CPUPercentile is the percentile that will be used to predict
+	// cpu usage for all tasks. If zero, MemPercentile is used.
+	CPUPercentile float64 `yaml:"cpupercentile,omitempty"`
+	// DiskPercentile is the percentile that will be used to predict
+	// scratch disk usage for all tasks. If zero, MemPercentile is used.
+	DiskPercentile float64 `yaml:"diskpercentile,omitempty"`
+	// Online determines whether the profiles of tasks completed during
+	// a run are incorporated into predictions for the run's later tasks
+	// (in addition to the profiles of previous runs found in TaskDB).
+	Online bool `yaml:"online,omitempty"`
 	// NonEC2Ok determines if the predictor should
 	// run on any machine. If set to false, the predictor
 	// will only work when 'reflow run' is invoked from
@@ -579,8 +669,16 @@ func (p *PredictorConfig) Init() error {
 	if p.MaxInspect < p.MinData {
 		return fmt.Errorf("maxinspect is less than mindata")
 	}
-	if p.MemPercentile < 0 || p.MemPercentile > 100 {
-		return fmt.Errorf("percentile %v is outside of range [0, 100]", p.MemPercentile)
+	if p.CPUPercentile == 0 {
+		p.CPUPercentile = p.MemPercentile
+	}
+	if p.DiskPercentile == 0 {
+		p.DiskPercentile = p.MemPercentile
+	}
+	for _, pct := range []float64{p.MemPercentile, p.CPUPercentile, p.DiskPercentile} {
+		if pct < 0 || pct > 100 {
+			return fmt.Errorf("percentile %v is outside of range [0, 100]", pct)
+		}
 	}
 	return nil
 }
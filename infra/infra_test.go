@@ -35,6 +35,14 @@ func TestReflowletConfig(t *testing.T) {
 			MergeReflowletConfig(DefaultReflowletConfig, ReflowletConfig{
 				VolumeWatcher: VolumeWatcher{LowThresholdPct: 25.0, ResizeSleepDuration: 2 * time.Second}}),
 		},
+		{
+			"reflowletconfig,runtime=apptainer",
+			MergeReflowletConfig(DefaultReflowletConfig, ReflowletConfig{Runtime: "apptainer"}),
+		},
+		{
+			"reflowletconfig,stalepolicy=teardown,stalegrace=1h",
+			MergeReflowletConfig(DefaultReflowletConfig, ReflowletConfig{StalePolicy: "teardown", StaleGrace: time.Hour}),
+		},
 	}{
 		config, err := schema.Make(infra.Keys{"reflowlet": tt.keyVal})
 		if err != nil {
@@ -49,3 +57,28 @@ func TestReflowletConfig(t *testing.T) {
 		}
 	}
 }
+
+func TestAdmin(t *testing.T) {
+	var schema = infra.Schema{"admins": new(Admin)}
+	config, err := schema.Make(infra.Keys{"admins": "admin,users=alice@example.com:bob@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var admin *Admin
+	if err = config.Instance(&admin); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		user string
+		want bool
+	}{
+		{"alice@example.com", true},
+		{"bob@example.com", true},
+		{"carol@example.com", false},
+		{"", false},
+	} {
+		if got, want := admin.IsAdmin(tt.user), tt.want; got != want {
+			t.Errorf("IsAdmin(%q): got %v, want %v", tt.user, got, want)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package infra
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/grailbio/infra"
+	"github.com/grailbio/reflow/pool"
+)
+
+func init() {
+	infra.Register("labelpolicy", new(LabelPolicy))
+}
+
+// LabelPolicy is the infra provider for the profile's policy on the
+// labels attached to runs, as in:
+//
+//	labelpolicy: labelpolicy
+//	labelpolicy:
+//	  defaults:
+//	    team: genomics
+//	  required: [costcenter, project]
+//
+// Default labels are attached to every run which does not set them
+// itself (e.g., with reflow -labels 'kv,labels=team=infra'). Runs without all of the required
+// labels are refused before they use any cluster resources, so that,
+// e.g., all costs can be attributed.
+type LabelPolicy struct {
+	// Defaults are the labels attached to runs which do not set them.
+	Defaults map[string]string `yaml:"defaults,omitempty"`
+	// Required are the names of the labels which runs must set,
+	// either explicitly or by default.
+	Required []string `yaml:"required,omitempty"`
+}
+
+// Help implements infra.Provider.
+func (LabelPolicy) Help() string {
+	return "default labels attached to every run, and labels required of runs"
+}
+
+// Init implements infra.Provider.
+func (p *LabelPolicy) Init() error {
+	for _, k := range p.Required {
+		if k == "" {
+			return fmt.Errorf("labelpolicy: empty required label")
+		}
+	}
+	return nil
+}
+
+// InstanceConfig implements infra.Provider.
+func (p *LabelPolicy) InstanceConfig() interface{} {
+	return p
+}
+
+// Apply returns a copy of the given labels with the policy's
+// defaults added. It returns an error naming the required labels
+// which are then missing (or empty), if any.
+func (p *LabelPolicy) Apply(labels pool.Labels) (pool.Labels, error) {
+	applied := labels.Copy()
+	for k, v := range p.Defaults {
+		if applied[k] == "" {
+			applied[k] = v
+		}
+	}
+	var missing []string
+	for _, k := range p.Required {
+		if applied[k] == "" {
+			missing = append(missing, k)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, fmt.Errorf("missing required labels %s (set them with, e.g., reflow -labels 'kv,labels=%s=value' run ...)",
+			strings.Join(missing, ", "), missing[0])
+	}
+	return applied, nil
+}
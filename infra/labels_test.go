@@ -0,0 +1,37 @@
+package infra
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/grailbio/reflow/pool"
+)
+
+func TestLabelPolicyApply(t *testing.T) {
+	policy := LabelPolicy{
+		Defaults: map[string]string{"team": "genomics", "tier": "dev"},
+		Required: []string{"costcenter", "team"},
+	}
+	labels := pool.Labels{"user": "alice", "tier": "prod", "costcenter": "1234"}
+	got, err := policy.Apply(labels)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := pool.Labels{"user": "alice", "tier": "prod", "costcenter": "1234", "team": "genomics"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if _, ok := labels["team"]; ok {
+		t.Error("labels modified")
+	}
+
+	policy.Required = append(policy.Required, "project")
+	_, err = policy.Apply(pool.Labels{"user": "alice", "costcenter": ""})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if got, want := err.Error(), "missing required labels costcenter, project"; !strings.HasPrefix(got, want) {
+		t.Errorf("got %v, want prefix %v", got, want)
+	}
+}
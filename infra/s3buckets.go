@@ -0,0 +1,67 @@
+package infra
+
+import (
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/grailbio/infra"
+	"github.com/grailbio/reflow/blob/s3blob"
+)
+
+func init() {
+	infra.Register("s3bucketoptions", new(S3BucketOptions))
+}
+
+// S3BucketOptions is the infra provider for the options used to
+// access S3 buckets, keyed by bucket name, as in:
+//
+//	s3buckets: s3bucketoptions
+//	s3bucketoptions:
+//	  1000genomes:
+//	    requesterpays: true
+//	  grail-staging:
+//	    accelerate: true
+//	  grail-reflow:
+//	    shards: [grail-reflow-us-east-1, grail-reflow-eu-west-1]
+//	  "*":
+//	    retry:
+//	      maxretries: 5
+//	      maxbackoff: 2m
+//	      admitlimit: 200
+//
+// The retry options (see s3blob.RetryOptions) tune how requests to a
+// bucket are retried, timed out and admitted, e.g., for buckets that
+// are chronically throttled. Buckets without options are accessed
+// with the options keyed by "*", if any, and otherwise with the
+// default options.
+// Buckets with shards (e.g., a repository's bucket) are accessed as a
+// single logical bucket spanning multiple regions; see
+// s3blob.ShardedBucket.
+type S3BucketOptions map[string]s3blob.BucketOptions
+
+// Help implements infra.Provider.
+func (S3BucketOptions) Help() string {
+	return "per-bucket S3 access options (transfer acceleration, requester pays, shards, retries)"
+}
+
+// Init implements infra.Provider.
+func (o *S3BucketOptions) Init() error {
+	if *o == nil {
+		*o = make(S3BucketOptions)
+	}
+	return nil
+}
+
+// InstanceConfig implements infra.Provider.
+func (o *S3BucketOptions) InstanceConfig() interface{} {
+	return o
+}
+
+// S3Store returns an S3 blob store which uses the given session, and
+// the bucket options defined in the given config, if any.
+func S3Store(config infra.Config, sess *session.Session) *s3blob.Store {
+	store := s3blob.New(sess)
+	var opts *S3BucketOptions
+	if err := config.Instance(&opts); err == nil && opts != nil {
+		store.Options = *opts
+	}
+	return store
+}
@@ -0,0 +1,139 @@
+package infra
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/grailbio/infra"
+)
+
+func init() {
+	infra.Register("runtemplates", new(RunTemplates))
+}
+
+// RunTemplate is a named, saved set of arguments to "reflow run":
+// a program together with its parameters, run flags and labels.
+// Templates are used to standardize (production) invocations of
+// programs across users.
+type RunTemplate struct {
+	// Inherits is the name of the template from which this template
+	// inherits its program, params, flags and labels. Values defined
+	// in this template override the inherited ones.
+	Inherits string `yaml:"inherits,omitempty"`
+	// Program is the path of the reflow program (or bundle) to run.
+	Program string `yaml:"program,omitempty"`
+	// Params are the program's parameters, keyed by name.
+	Params map[string]string `yaml:"params,omitempty"`
+	// Flags are the run flags (e.g., "maxcost" or "sched"), keyed by name.
+	Flags map[string]string `yaml:"flags,omitempty"`
+	// Labels are the labels attached to the run.
+	Labels map[string]string `yaml:"labels,omitempty"`
+}
+
+// RunTemplates is the infra provider for the named run templates
+// defined in the profile, as in:
+//
+//	templates: runtemplates
+//	runtemplates:
+//	  wgs:
+//	    program: s3://bucket/pipelines/wgs.rfx
+//	    flags:
+//	      sched: true
+//	    labels:
+//	      team: genomics
+//	  wgs-highmem:
+//	    inherits: wgs
+//	    params:
+//	      mem: 64GiB
+type RunTemplates map[string]*RunTemplate
+
+// Help implements infra.Provider.
+func (RunTemplates) Help() string {
+	return "named run templates (program, params, flags and labels) invocable as reflow run @template"
+}
+
+// Init implements infra.Provider.
+func (t *RunTemplates) Init() error {
+	if *t == nil {
+		*t = make(RunTemplates)
+	}
+	for name := range *t {
+		if _, err := t.Resolve(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// InstanceConfig implements infra.Provider.
+func (t *RunTemplates) InstanceConfig() interface{} {
+	return t
+}
+
+// Names returns the sorted names of the defined templates.
+func (t RunTemplates) Names() []string {
+	names := make([]string, 0, len(t))
+	for name := range t {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Resolve returns the template with the given name, with the values
+// of the templates it (transitively) inherits from merged in.
+func (t RunTemplates) Resolve(name string) (RunTemplate, error) {
+	var (
+		chain []*RunTemplate
+		seen  = make(map[string]bool)
+		path  []string
+	)
+	for n := name; n != ""; {
+		path = append(path, n)
+		if seen[n] {
+			return RunTemplate{}, fmt.Errorf("run template %s: inheritance cycle %s", name, strings.Join(path, " -> "))
+		}
+		seen[n] = true
+		tmpl := t[n]
+		if tmpl == nil {
+			if n == name {
+				return RunTemplate{}, fmt.Errorf("run template %s not defined", name)
+			}
+			return RunTemplate{}, fmt.Errorf("run template %s: inherited template %s not defined", name, n)
+		}
+		chain = append(chain, tmpl)
+		n = tmpl.Inherits
+	}
+	var resolved RunTemplate
+	// Apply the templates from the root of the inheritance chain down,
+	// so that values of descendants override those of their ancestors.
+	for i := len(chain) - 1; i >= 0; i-- {
+		tmpl := chain[i]
+		if tmpl.Program != "" {
+			resolved.Program = tmpl.Program
+		}
+		resolved.Params = mergeStrings(resolved.Params, tmpl.Params)
+		resolved.Flags = mergeStrings(resolved.Flags, tmpl.Flags)
+		resolved.Labels = mergeStrings(resolved.Labels, tmpl.Labels)
+	}
+	if resolved.Program == "" {
+		return RunTemplate{}, fmt.Errorf("run template %s: no program defined", name)
+	}
+	return resolved, nil
+}
+
+// mergeStrings returns a copy of base with the entries of override set.
+func mergeStrings(base, override map[string]string) map[string]string {
+	if len(base) == 0 && len(override) == 0 {
+		return nil
+	}
+	m := make(map[string]string, len(base)+len(override))
+	for k, v := range base {
+		m[k] = v
+	}
+	for k, v := range override {
+		m[k] = v
+	}
+	return m
+}
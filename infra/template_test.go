@@ -0,0 +1,64 @@
+package infra
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRunTemplatesResolve(t *testing.T) {
+	templates := RunTemplates{
+		"wgs": {
+			Program: "wgs.rf",
+			Params:  map[string]string{"ref": "hg38", "mem": "32GiB"},
+			Flags:   map[string]string{"sched": "true"},
+			Labels:  map[string]string{"team": "genomics"},
+		},
+		"wgs-highmem": {
+			Inherits: "wgs",
+			Params:   map[string]string{"mem": "64GiB"},
+			Labels:   map[string]string{"tier": "prod"},
+		},
+		"wgs-v2": {
+			Inherits: "wgs-highmem",
+			Program:  "wgs2.rf",
+		},
+		"a":       {Inherits: "b", Program: "a.rf"},
+		"b":       {Inherits: "a"},
+		"orphan":  {Inherits: "missing", Program: "orphan.rf"},
+		"noprogr": {Params: map[string]string{"x": "y"}},
+	}
+	got, err := templates.Resolve("wgs-v2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := RunTemplate{
+		Program: "wgs2.rf",
+		Params:  map[string]string{"ref": "hg38", "mem": "64GiB"},
+		Flags:   map[string]string{"sched": "true"},
+		Labels:  map[string]string{"team": "genomics", "tier": "prod"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	// Resolving must not modify the inherited templates.
+	if got, want := templates["wgs"].Params["mem"], "32GiB"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	for _, tt := range []struct {
+		name, err string
+	}{
+		{"unknown", "not defined"},
+		{"a", "inheritance cycle a -> b -> a"},
+		{"orphan", "inherited template missing not defined"},
+		{"noprogr", "no program defined"},
+	} {
+		_, err := templates.Resolve(tt.name)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: got error %v, want %q", tt.name, err, tt.err)
+		}
+	}
+	if got, want := (RunTemplates{"b": nil, "a": nil}).Names(), []string{"a", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
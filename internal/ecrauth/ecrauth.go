@@ -3,7 +3,9 @@
 // license that can be found in the LICENSE file.
 
 // Package ecrauth provides an interface and utilities for
-// authenticating AWS EC2 ECR Docker repositories.
+// authenticating Docker registries: AWS EC2 ECR repositories, and
+// other registries (e.g., Docker Hub) configured with credentials
+// (see FromConfig).
 package ecrauth
 
 import (
@@ -13,8 +15,8 @@ import (
 	"docker.io/go-docker/api/types"
 )
 
-// Interface is the interface that is implemented by ECR
-// authentication providers.
+// Interface is the interface that is implemented by registry
+// authentication providers (e.g., ECR).
 type Interface interface {
 	// Authenticates tells whether this authenticator can authenticate the
 	// provided image URI.
@@ -0,0 +1,297 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package ecrauth
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"docker.io/go-docker/api/types"
+	"github.com/grailbio/infra"
+	"github.com/grailbio/reflow"
+	"github.com/grailbio/reflow/errors"
+)
+
+// The kinds of registries with builtin defaults.
+const (
+	// DockerHub is Docker Hub; its password is a personal access token.
+	DockerHub = "dockerhub"
+	// GCR is Google Container Registry and Artifact Registry; its
+	// password is the JSON key of a service account.
+	GCR = "gcr"
+	// Quay is quay.io; its username and password are those of a robot
+	// account.
+	Quay = "quay"
+	// Static is any other registry; its hosts must be given.
+	Static = "static"
+)
+
+// dockerHubServer is the server address of Docker Hub's credentials.
+const dockerHubServer = "https://index.docker.io/v1/"
+
+// registryDefaults are the default hosts and usernames of the kinds of
+// registries.
+var registryDefaults = map[string]struct {
+	hosts    []string
+	username string
+}{
+	DockerHub: {hosts: []string{"docker.io", "index.docker.io", "registry-1.docker.io"}},
+	GCR:       {hosts: []string{"gcr.io", "*.gcr.io", "*.pkg.dev"}, username: "_json_key"},
+	Quay:      {hosts: []string{"quay.io"}},
+	Static:    {},
+}
+
+// ImageAuthenticator is implemented by authenticators whose credentials
+// depend on the image, e.g., because they authenticate several
+// registries.
+type ImageAuthenticator interface {
+	Interface
+	// AuthenticateImage writes the authentication information for the
+	// given image into the provided config struct.
+	AuthenticateImage(ctx context.Context, image string, cfg *types.AuthConfig) error
+}
+
+// AuthConfig returns the authentication information with which the
+// given image is pulled, and whether auth authenticates the image at
+// all. Images which auth does not authenticate are pulled anonymously.
+func AuthConfig(ctx context.Context, auth Interface, image string) (cfg types.AuthConfig, ok bool, err error) {
+	if auth == nil {
+		return cfg, false, nil
+	}
+	if ok, err = auth.Authenticates(ctx, image); err != nil || !ok {
+		return cfg, false, err
+	}
+	if a, isImage := auth.(ImageAuthenticator); isImage {
+		err = a.AuthenticateImage(ctx, image, &cfg)
+	} else {
+		err = auth.Authenticate(ctx, &cfg)
+	}
+	return cfg, err == nil, err
+}
+
+// RegistryHost returns the host of the registry of the given image
+// reference. As with Docker, images whose first path component is not
+// a host (e.g., "ubuntu" or "grail/reflow") are in Docker Hub.
+func RegistryHost(image string) string {
+	parts := strings.SplitN(image, "/", 2)
+	if len(parts) == 1 {
+		return "docker.io"
+	}
+	if host := parts[0]; strings.ContainsAny(host, ".:") || host == "localhost" {
+		return host
+	}
+	return "docker.io"
+}
+
+// SecretFunc returns the value of the referenced secret.
+type SecretFunc func(ctx context.Context, ref reflow.SecretRef) (string, error)
+
+// Registry authenticates the images of a registry with a username and
+// a password (or token), which is stored as a secret.
+type Registry struct {
+	// Kind is the kind of the registry, which determines its default
+	// hosts and username.
+	Kind string
+	// Hosts are the hosts of the registry. A host "*.domain" matches
+	// any subdomain of domain.
+	Hosts []string
+	// Username is the username with which images are pulled.
+	Username string
+	// PasswordSecret references the secret (see reflow.ParseSecretRef)
+	// which stores the password (or token) with which images are pulled.
+	// Passwords are never configured in plaintext, since the
+	// configuration is shipped to cluster instances in their user data.
+	PasswordSecret string
+	// Secret fetches PasswordSecret.
+	Secret SecretFunc
+}
+
+// Authenticates tells whether the image is in one of the registry's hosts.
+func (r *Registry) Authenticates(ctx context.Context, image string) (bool, error) {
+	host := RegistryHost(image)
+	for _, h := range r.Hosts {
+		if h == host || strings.HasPrefix(h, "*.") && strings.HasSuffix(host, h[1:]) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Authenticate writes the registry's credentials for its first host
+// into the provided config struct.
+func (r *Registry) Authenticate(ctx context.Context, cfg *types.AuthConfig) error {
+	return r.authenticate(ctx, strings.TrimPrefix(r.Hosts[0], "*."), cfg)
+}
+
+// AuthenticateImage writes the registry's credentials for the image's
+// host into the provided config struct.
+func (r *Registry) AuthenticateImage(ctx context.Context, image string, cfg *types.AuthConfig) error {
+	return r.authenticate(ctx, RegistryHost(image), cfg)
+}
+
+func (r *Registry) authenticate(ctx context.Context, host string, cfg *types.AuthConfig) error {
+	ref, err := reflow.ParseSecretRef(r.PasswordSecret)
+	if err != nil {
+		return errors.E("authenticate", host, err)
+	}
+	if r.Secret == nil {
+		return errors.E("authenticate", host, errors.NotSupported, errors.New("cannot fetch secrets"))
+	}
+	password, err := r.Secret(ctx, ref)
+	if err != nil {
+		return errors.E("authenticate", host, err)
+	}
+	cfg.Username, cfg.Password = r.Username, password
+	cfg.ServerAddress = host
+	if r.Kind == DockerHub {
+		cfg.ServerAddress = dockerHubServer
+	}
+	return nil
+}
+
+// Chain authenticates images with the first of its authenticators
+// which authenticates them, so that images may be pulled from several
+// registries.
+type Chain []Interface
+
+// Authenticates tells whether any of the chain's authenticators
+// authenticates the image.
+func (c Chain) Authenticates(ctx context.Context, image string) (bool, error) {
+	for _, auth := range c {
+		if ok, err := auth.Authenticates(ctx, image); err != nil || ok {
+			return ok, err
+		}
+	}
+	return false, nil
+}
+
+// Authenticate authenticates with the chain's first (primary)
+// authenticator.
+func (c Chain) Authenticate(ctx context.Context, cfg *types.AuthConfig) error {
+	if len(c) == 0 {
+		return errors.New("no authenticators")
+	}
+	return c[0].Authenticate(ctx, cfg)
+}
+
+// AuthenticateImage authenticates the image with the first of the
+// chain's authenticators which authenticates it.
+func (c Chain) AuthenticateImage(ctx context.Context, image string, cfg *types.AuthConfig) error {
+	for _, auth := range c {
+		cred, ok, err := AuthConfig(ctx, auth, image)
+		if err != nil {
+			return err
+		}
+		if ok {
+			*cfg = cred
+			return nil
+		}
+	}
+	return errors.E("authenticate", image, errors.NotExist, errors.New("no authenticator for image"))
+}
+
+// FromConfig returns an authenticator for the primary authenticator
+// (e.g., ECR) and the registries configured by the "registries" key of
+// config, which are tried in order after the primary. The registries'
+// passwords are stored as secrets, which are fetched with secret. If no
+// registries are configured, FromConfig returns primary. For example:
+//
+//	registries:
+//	- kind: dockerhub
+//	  username: grail
+//	  passwordsecret: ssm:/dockerhub/token
+//	- kind: static
+//	  hosts: [registry.example.com]
+//	  username: reflow
+//	  passwordsecret: ssm:/registry.example.com/password
+//
+// Plaintext passwords are rejected: the configuration is marshaled
+// into the user data of cluster instances, where anyone permitted to
+// describe the instances could read them.
+func FromConfig(config infra.Config, primary Interface, secret SecretFunc) (Interface, error) {
+	registries, err := ParseRegistries(config.Value("registries"), secret)
+	if err != nil || len(registries) == 0 {
+		return primary, err
+	}
+	chain := Chain{primary}
+	for _, r := range registries {
+		chain = append(chain, r)
+	}
+	return chain, nil
+}
+
+// ParseRegistries parses the registries configured by the value v of
+// the "registries" configuration key (see FromConfig).
+func ParseRegistries(v interface{}, secret SecretFunc) ([]*Registry, error) {
+	if v == nil {
+		return nil, nil
+	}
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil, errors.E(errors.Invalid, errors.Errorf("registries: expected a list, got %v", v))
+	}
+	registries := make([]*Registry, len(list))
+	for i, item := range list {
+		m, ok := item.(map[interface{}]interface{})
+		if !ok {
+			return nil, errors.E(errors.Invalid, errors.Errorf("registries: expected a map, got %v", item))
+		}
+		r := &Registry{Secret: secret}
+		for k, v := range m {
+			key := fmt.Sprint(k)
+			switch key {
+			case "hosts":
+				hosts, ok := v.([]interface{})
+				if !ok {
+					return nil, errors.E(errors.Invalid, errors.Errorf("registries: hosts: expected a list, got %v", v))
+				}
+				for _, h := range hosts {
+					r.Hosts = append(r.Hosts, fmt.Sprint(h))
+				}
+			case "password":
+				return nil, errors.E(errors.Invalid, errors.New("registries: plaintext passwords are not permitted, since the configuration is shipped to cluster instances; store the password as a secret and reference it with passwordsecret"))
+			case "kind", "username", "passwordsecret":
+				s, ok := v.(string)
+				if !ok {
+					return nil, errors.E(errors.Invalid, errors.Errorf("registries: %s: expected a string, got %v", key, v))
+				}
+				switch key {
+				case "kind":
+					r.Kind = s
+				case "username":
+					r.Username = s
+				case "passwordsecret":
+					r.PasswordSecret = s
+				}
+			default:
+				return nil, errors.E(errors.Invalid, errors.Errorf("registries: unknown key %s", key))
+			}
+		}
+		def, ok := registryDefaults[r.Kind]
+		if !ok {
+			return nil, errors.E(errors.Invalid, errors.Errorf("registries: unknown kind %q", r.Kind))
+		}
+		if len(r.Hosts) == 0 {
+			r.Hosts = def.hosts
+		}
+		if r.Username == "" {
+			r.Username = def.username
+		}
+		switch {
+		case len(r.Hosts) == 0:
+			return nil, errors.E(errors.Invalid, errors.Errorf("registries: %s registry has no hosts", r.Kind))
+		case r.Username == "":
+			return nil, errors.E(errors.Invalid, errors.Errorf("registries: %s registry has no username", r.Kind))
+		case r.PasswordSecret == "":
+			return nil, errors.E(errors.Invalid, errors.Errorf("registries: %s registry has no password secret", r.Kind))
+		}
+		if _, err := reflow.ParseSecretRef(r.PasswordSecret); err != nil {
+			return nil, errors.E(errors.Invalid, "registries", err)
+		}
+		registries[i] = r
+	}
+	return registries, nil
+}
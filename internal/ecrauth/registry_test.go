@@ -0,0 +1,147 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package ecrauth
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"docker.io/go-docker/api/types"
+	"github.com/grailbio/reflow"
+	"github.com/grailbio/reflow/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// testECR authenticates ECR images with fixed credentials.
+type testECR struct{}
+
+func (testECR) Authenticates(ctx context.Context, image string) (bool, error) {
+	return strings.Contains(image, ".dkr.ecr."), nil
+}
+
+func (testECR) Authenticate(ctx context.Context, cfg *types.AuthConfig) error {
+	cfg.Username, cfg.Password, cfg.ServerAddress = "AWS", "ecr-token", "123.dkr.ecr.us-west-2.amazonaws.com"
+	return nil
+}
+
+func TestRegistryHost(t *testing.T) {
+	for image, want := range map[string]string{
+		"ubuntu":                                    "docker.io",
+		"grail/reflow:latest":                       "docker.io",
+		"docker.io/library/ubuntu":                  "docker.io",
+		"gcr.io/project/image":                      "gcr.io",
+		"us-west1-docker.pkg.dev/project/repo/img":  "us-west1-docker.pkg.dev",
+		"localhost/image":                           "localhost",
+		"registry.example.com:5000/team/image@sha1": "registry.example.com:5000",
+	} {
+		if got := RegistryHost(image); got != want {
+			t.Errorf("%s: got %v, want %v", image, got, want)
+		}
+	}
+}
+
+func TestChain(t *testing.T) {
+	var config interface{}
+	if err := yaml.Unmarshal([]byte(`
+- kind: dockerhub
+  username: grail
+  passwordsecret: ssm:/dockerhub/token
+- kind: gcr
+  passwordsecret: ssm:/gcr/key
+- kind: quay
+  username: grail+robot
+  passwordsecret: ssm:/quay/token
+- kind: static
+  hosts: [registry.example.com]
+  username: reflow
+  passwordsecret: ssm:/registry/password
+`), &config); err != nil {
+		t.Fatal(err)
+	}
+	secrets := map[string]string{
+		"ssm:/dockerhub/token":   "dockerhub-token",
+		"ssm:/gcr/key":           `{"type": "service_account"}`,
+		"ssm:/quay/token":        "quay-token",
+		"ssm:/registry/password": "hunter2",
+	}
+	secret := func(ctx context.Context, ref reflow.SecretRef) (string, error) {
+		s, ok := secrets[ref.String()]
+		if !ok {
+			return "", errors.E(errors.NotExist, errors.New(ref.String()))
+		}
+		return s, nil
+	}
+	registries, err := ParseRegistries(config, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain := Chain{testECR{}}
+	for _, r := range registries {
+		chain = append(chain, r)
+	}
+	ctx := context.Background()
+	for _, tc := range []struct {
+		image                      string
+		username, password, server string
+	}{
+		{"123.dkr.ecr.us-west-2.amazonaws.com/reflow", "AWS", "ecr-token", "123.dkr.ecr.us-west-2.amazonaws.com"},
+		{"grail/private", "grail", "dockerhub-token", dockerHubServer},
+		{"eu.gcr.io/project/image", "_json_key", `{"type": "service_account"}`, "eu.gcr.io"},
+		{"us-docker.pkg.dev/project/repo/image", "_json_key", `{"type": "service_account"}`, "us-docker.pkg.dev"},
+		{"quay.io/grail/image", "grail+robot", "quay-token", "quay.io"},
+		{"registry.example.com/image", "reflow", "hunter2", "registry.example.com"},
+	} {
+		cfg, ok, err := AuthConfig(ctx, chain, tc.image)
+		if err != nil {
+			t.Errorf("%s: %v", tc.image, err)
+			continue
+		}
+		if !ok {
+			t.Errorf("%s: not authenticated", tc.image)
+			continue
+		}
+		if got, want := cfg, (types.AuthConfig{Username: tc.username, Password: tc.password, ServerAddress: tc.server}); got != want {
+			t.Errorf("%s: got %+v, want %+v", tc.image, got, want)
+		}
+	}
+	if _, ok, err := AuthConfig(ctx, chain, "other.example.com/image"); ok || err != nil {
+		t.Errorf("got %v, %v, want false, nil", ok, err)
+	}
+	// The chain's primary authenticator is used when no image is given.
+	var cfg types.AuthConfig
+	if err := chain.Authenticate(ctx, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := cfg.Username, "AWS"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if _, _, err := AuthConfig(ctx, nil, "ubuntu"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestParseRegistriesInvalid(t *testing.T) {
+	for _, config := range []string{
+		`kind: dockerhub`,
+		`[{kind: nexus, hosts: [nexus.example.com], username: x}]`,
+		`[{kind: static, username: x, passwordsecret: "ssm:/y"}]`,
+		`[{kind: quay, passwordsecret: "ssm:/y"}]`,
+		`[{kind: dockerhub, username: x, password: y, passwordsecret: "ssm:/y"}]`,
+		// Plaintext passwords are rejected, as are registries without secrets.
+		`[{kind: dockerhub, username: x, password: y}]`,
+		`[{kind: dockerhub, username: x}]`,
+		`[{kind: dockerhub, username: x, passwordsecret: "vault:y"}]`,
+		`[{kind: dockerhub, username: x, token: y}]`,
+	} {
+		var v interface{}
+		if err := yaml.Unmarshal([]byte(config), &v); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ParseRegistries(v, nil); !errors.Is(errors.Invalid, err) {
+			t.Errorf("%s: got %v, want Invalid", config, err)
+		}
+	}
+}
@@ -523,19 +523,45 @@ func (s *Scanner) scanRawString() {
 	}
 }
 
-func (s *Scanner) scanTemplate() bool {
+// scanTemplate scans the remainder of a template literal, beginning
+// with its first character ch, through its closing '"}'. Template
+// interpolations ("{{...}}") may contain arbitrary expressions: the
+// template is not terminated within an interpolation, and an
+// interpolation is not terminated within the string literals or
+// braces that it contains.
+func (s *Scanner) scanTemplate(ch rune) bool {
 	var (
 		prev rune
-		next = s.next()
+		// depth is the depth of braces within an interpolation,
+		// or -1 outside of interpolations.
+		depth = -1
 	)
-	for prev != '"' || next != '}' {
-		if next < 0 {
+	for {
+		if ch < 0 {
 			s.error("template literal not terminated")
 			return false
 		}
-		prev, next = next, s.next()
+		switch {
+		case depth < 0 && prev == '"' && ch == '}':
+			return true
+		case depth < 0 && prev == '{' && ch == '{':
+			depth, ch = 0, 0
+		case depth < 0:
+		case ch == '"':
+			s.scanString('"')
+			ch = 0
+		case ch == '`':
+			s.scanRawString()
+			ch = 0
+		case ch == '{':
+			depth++
+		case ch == '}' && depth > 0:
+			depth, ch = depth-1, 0
+		case ch == '}' && prev == '}':
+			depth, ch = -1, 0
+		}
+		prev, ch = ch, s.next()
 	}
-	return true
 }
 
 func (s *Scanner) scanChar() {
@@ -733,8 +759,7 @@ redo:
 		case '{':
 			ch = s.next()
 			if ch == '"' {
-				s.next()
-				if s.scanTemplate() {
+				if s.scanTemplate(s.next()) {
 					tok = Template
 					ch = s.next()
 				}
@@ -414,6 +414,17 @@ func TestScanTemplate(t *testing.T) {
 	}
 }
 
+func TestScanTemplateInterpolation(t *testing.T) {
+	s := new(Scanner).Init(strings.NewReader(`foo {"cat {{x + ".bam"}} {{ {a: "}"}.a}}"} bar`))
+	checkTok(t, s, 1, s.Scan(), Ident, "foo")
+	checkTok(t, s, 1, s.Scan(), Template, `{"cat {{x + ".bam"}} {{ {a: "}"}.a}}"}`)
+	checkTok(t, s, 1, s.Scan(), Ident, "bar")
+	checkTok(t, s, 1, s.Scan(), -1, "")
+	if s.ErrorCount != 0 {
+		t.Errorf("%d errors", s.ErrorCount)
+	}
+}
+
 func TestScanWhitespace(t *testing.T) {
 	var buf bytes.Buffer
 	var ws uint64
@@ -0,0 +1,682 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package local
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	osexec "os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/grailbio/base/digest"
+	"github.com/grailbio/base/sync/once"
+	"github.com/grailbio/reflow"
+	"github.com/grailbio/reflow/errors"
+	"github.com/grailbio/reflow/internal/ecrauth"
+	"github.com/grailbio/reflow/log"
+	"github.com/grailbio/reflow/repository/filerepo"
+)
+
+// apptainerBinary is the name of the Apptainer binary used to run execs.
+const apptainerBinary = "apptainer"
+
+// apptainerExec is a (local) exec which runs its command in an
+// Apptainer container. It implements the same state machine and
+// disk layout as dockerExec, but Apptainer containers are not managed
+// by a daemon: they run as children of the executor's process.
+// Thus, unlike Docker containers, they cannot be reattached to after
+// the executor restarts; such execs fail with a temporary error.
+type apptainerExec struct {
+	// The Executor that owns this exec.
+	Executor *Executor
+	// The (possibly nil) Logger that logs exec's actions, for external consumption.
+	Log *log.Logger
+
+	id      digest.Digest
+	repo    *filerepo.Repository
+	staging filerepo.Repository
+	stdout  remoteLogsOutputter
+	stderr  remoteLogsOutputter
+
+	mu   sync.Mutex
+	cond *sync.Cond
+	// cmd is the running apptainer process; it is nil
+	// if the exec was recovered from disk.
+	cmd *osexec.Cmd
+
+	// Manifest stores the serializable state of the exec.
+	Manifest
+	err         error
+	promoteOnce once.Task
+}
+
+// newApptainerExec creates a new Apptainer exec with parent executor x.
+func newApptainerExec(id digest.Digest, x *Executor, cfg reflow.ExecConfig, stdout, stderr remoteLogsOutputter) *apptainerExec {
+	e := &apptainerExec{
+		Executor: x,
+		Log:      x.Log.Tee(nil, fmt.Sprintf("%s: ", id)),
+		repo:     x.FileRepository,
+		id:       id,
+		stdout:   stdout,
+		stderr:   stderr,
+	}
+	e.staging.Root = e.path(objectsDir)
+	e.staging.Log = e.Log
+	e.Config = cfg
+	e.Manifest.Type = execApptainer
+	e.Manifest.Created = time.Now()
+	e.cond = sync.NewCond(&e.mu)
+	return e
+}
+
+func (e *apptainerExec) save(state execState) error {
+	if err := os.MkdirAll(e.path(), 0777); err != nil {
+		return err
+	}
+	path := e.path(manifestPath)
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	manifest := e.Manifest
+	manifest.State = state
+	if err := json.NewEncoder(f).Encode(manifest); err != nil {
+		os.Remove(path)
+		f.Close()
+		return err
+	}
+	f.Close()
+	return nil
+}
+
+// apptainerImage returns the Apptainer image URI for the given
+// (Docker) image reference. References that already name an
+// Apptainer transport, or a local image file, are returned as is.
+func apptainerImage(image string) string {
+	for _, prefix := range []string{"docker://", "library://", "oras://", "shub://", "docker-archive:", "/"} {
+		if strings.HasPrefix(image, prefix) {
+			return image
+		}
+	}
+	return "docker://" + image
+}
+
+// apptainerArgs returns the arguments to the apptainer binary that
+// run the shell command cmd in image. The exec's 'arg', 'tmp', and
+// 'return' directories (rooted at dir) are bound into the container
+// in the same way as for Docker execs.
+func apptainerArgs(dir, image, cmd string, resources reflow.Resources, hardMemLimit bool, cpus float64) []string {
+	args := []string{
+		"exec",
+		// Isolate the container's file system, PID namespace and
+		// environment from the host.
+		"--containall",
+		"--cleanenv",
+		"--bind", strings.Join([]string{
+			dir + "/arg:/arg",
+			dir + "/tmp:/tmp",
+			dir + "/return:/return",
+		}, ","),
+		"--pwd", "/tmp",
+	}
+	if mem := resources["mem"]; mem > 0 && hardMemLimit {
+		args = append(args,
+			"--memory", strconv.FormatInt(int64(mem), 10),
+			"--memory-swap", strconv.FormatInt(int64(mem)+int64(hardLimitSwapMem), 10))
+	}
+	if cpus > 0 {
+		args = append(args, "--cpus", strconv.FormatFloat(cpus, 'f', -1, 64))
+	}
+	// We use a login shell here as many Docker images are configured
+	// with /root/.profile, etc.
+	return append(args, apptainerImage(image), "/bin/bash", "-e", "-l", "-o", "pipefail", "-c", cmd)
+}
+
+// create sets up the exec's temporary and return directories.
+// As with Docker execs, results are returned in the 'return'
+// directory of the exec's run directory.
+func (e *apptainerExec) create(ctx context.Context) (execState, error) {
+	os.MkdirAll(e.path("tmp"), 0777)
+	os.MkdirAll(e.path("return"), 0777)
+	if outputs := e.Config.OutputIsDir; outputs != nil {
+		for i, isdir := range outputs {
+			if isdir {
+				os.MkdirAll(e.path("return", strconv.Itoa(i)), 0777)
+			}
+		}
+	}
+	return execCreated, nil
+}
+
+// env returns the environment of the apptainer process. Variables
+// meant for the exec are passed with the APPTAINERENV_ prefix, so
+// that they are not exposed on the command line.
+func (e *apptainerExec) env(ctx context.Context) ([]string, error) {
+	env := []string{
+		"APPTAINERENV_tmp=/tmp",
+		"APPTAINERENV_TMPDIR=/tmp",
+		"APPTAINERENV_HOME=/tmp",
+	}
+	secrets, err := secretEnv(ctx, e.Executor.Secrets, e.Config)
+	if err != nil {
+		return nil, errors.E("run", e.id, err)
+	}
+	for _, kv := range append(append(e.Config.Env(), userEnv(e.Config)...), secrets...) {
+		env = append(env, "APPTAINERENV_"+kv)
+	}
+	if e.Config.OutputIsDir == nil {
+		env = append(env, "APPTAINERENV_out=/return/default")
+	}
+	if e.Config.NeedAWSCreds {
+		creds, err := e.Executor.AWSCreds.Get()
+		if err != nil {
+			// We mark this as temporary, because most of the time it is.
+			return nil, errors.E("run", e.id, errors.Temporary, err)
+		}
+		env = append(env,
+			"APPTAINERENV_AWS_ACCESS_KEY_ID="+creds.AccessKeyID,
+			"APPTAINERENV_AWS_SECRET_ACCESS_KEY="+creds.SecretAccessKey,
+			"APPTAINERENV_AWS_SESSION_TOKEN="+creds.SessionToken)
+	}
+	// Authenticate image pulls from registries (e.g., ECR) which
+	// require it.
+	cfg, ok, err := ecrauth.AuthConfig(ctx, e.Executor.Authenticator, e.Config.Image)
+	if err != nil {
+		return nil, errors.E("authenticate", e.Config.Image, err)
+	}
+	if ok {
+		env = append(env,
+			"APPTAINER_DOCKER_USERNAME="+cfg.Username,
+			"APPTAINER_DOCKER_PASSWORD="+cfg.Password)
+	}
+	return append(os.Environ(), env...), nil
+}
+
+// start materializes the exec's arguments to the 'arg' directory
+// in the exec's run directory and starts the apptainer process.
+// The process's standard output and error are saved to the exec
+// directory and forwarded to the exec's remote log streams.
+func (e *apptainerExec) start(ctx context.Context) (execState, error) {
+	args, err := materializeArgs(e.Config, e.repo, e.path)
+	if err != nil {
+		return execCreated, err
+	}
+	command, err := execCommand(e.Config, args, e.path)
+	if err != nil {
+		return execCreated, err
+	}
+	env, err := e.env(ctx)
+	if err != nil {
+		return execCreated, err
+	}
+	var cpus float64
+	if e.Executor.StrictLimits {
+		cpus = e.Config.Resources["cpu"]
+	}
+	name, cmdArgs := priorityCommand(e.Config.Priority, apptainerBinary,
+		apptainerArgs(e.path(), e.Config.Image, command, e.Config.Resources, e.Executor.HardMemLimit, cpus)...)
+	cmd := osexec.Command(name, cmdArgs...)
+	cmd.Env = env
+	// Run the exec in its own process group, so that the apptainer
+	// process and everything it starts can be killed together.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	stdout, err := os.Create(e.path("stdout"))
+	if err != nil {
+		return execCreated, errors.E("create", e.path("stdout"), err)
+	}
+	stderr, err := os.Create(e.path("stderr"))
+	if err != nil {
+		stdout.Close()
+		return execCreated, errors.E("create", e.path("stderr"), err)
+	}
+	cmd.Stdout = e.output(stdout, e.stdout)
+	cmd.Stderr = e.output(stderr, e.stderr)
+	if err := cmd.Start(); err != nil {
+		stdout.Close()
+		stderr.Close()
+		return execCreated, errors.E("exec", e.id, apptainerBinary, err)
+	}
+	e.mu.Lock()
+	e.cmd = cmd
+	e.mu.Unlock()
+	e.Manifest.PID = cmd.Process.Pid
+	// As with Docker execs, try to ensure that the exec is killed before
+	// the reflowlet (and lower priority execs before higher priority ones).
+	// Processes started by the exec inherit the adjustment.
+	adj := strconv.Itoa(oomScoreAdj(e.Config.Priority))
+	if err := ioutil.WriteFile(fmt.Sprintf("/proc/%d/oom_score_adj", cmd.Process.Pid), []byte(adj), 0); err != nil {
+		e.Log.Errorf("set oom_score_adj: %v", err)
+	}
+	return execRunning, nil
+}
+
+// output returns a writer that writes to the file f and, if it is
+// non-nil, forwards lines to the remote log stream out.
+func (e *apptainerExec) output(f *os.File, out remoteLogsOutputter) io.WriteCloser {
+	if out == nil {
+		return f
+	}
+	r, w := io.Pipe()
+	go func() {
+		s := bufio.NewScanner(r)
+		logger := log.New(out, log.InfoLevel)
+		for s.Scan() {
+			logger.Print(s.Text())
+		}
+		if err := s.Err(); err != nil {
+			e.Log.Errorf("scanlines: %v", err)
+		}
+		// Drain the pipe so that the process is never blocked.
+		io.Copy(ioutil.Discard, r)
+	}()
+	return &writeCloser{io.MultiWriter(f, w), []io.Closer{f, w}}
+}
+
+// writeCloser defines an io.WriteCloser over a writer and
+// multiple closers.
+type writeCloser struct {
+	io.Writer
+	closers []io.Closer
+}
+
+func (c *writeCloser) Close() error {
+	var err error
+	for _, c := range c.closers {
+		if e := c.Close(); e != nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// wait waits for the apptainer process to complete and performs
+// teardown: it installs the results into the repository and removes
+// the argument and temporary directories.
+func (e *apptainerExec) wait(ctx context.Context) (execState, error) {
+	e.mu.Lock()
+	cmd := e.cmd
+	e.mu.Unlock()
+	if cmd == nil {
+		return execInit, errors.E("exec", e.id, errors.Temporary,
+			errors.New("apptainer process lost; the executor was likely restarted"))
+	}
+	profc := make(chan stats)
+	profctx, cancelprof := context.WithCancel(ctx)
+	go func() {
+		profc <- e.profile(profctx)
+	}()
+	startedAt := time.Now()
+	timer := startExecTimer(startedAt, e.Config.Timeout, func() {
+		e.Log.Printf("killing process after exceeding timeout %s", e.Config.Timeout)
+		killProcessGroup(cmd)
+	})
+	// The process is killed if the exec is abandoned, e.g., because its
+	// executor is killed.
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			killProcessGroup(cmd)
+		case <-done:
+		}
+	}()
+	err := cmd.Wait()
+	close(done)
+	timedOut := timer.Stop()
+	finishedAt := time.Now()
+	for _, w := range []io.Writer{cmd.Stdout, cmd.Stderr} {
+		if c, ok := w.(io.Closer); ok {
+			c.Close()
+		}
+	}
+	cancelprof()
+	e.Manifest.Stats = <-profc
+	if ctx.Err() != nil {
+		return execInit, errors.E("exec", e.id, apptainerBinary, ctx.Err())
+	}
+
+	code := 0
+	if err != nil {
+		exitErr, ok := err.(*osexec.ExitError)
+		if !ok {
+			return execInit, errors.E("exec", e.id, apptainerBinary, err)
+		}
+		code = exitErr.ExitCode()
+		// Report processes that were killed by a signal with the
+		// same exit code as the shell (and Docker) would.
+		if ws, ok := exitErr.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+			code = 128 + int(ws.Signal())
+		}
+	}
+	oomSys, oomSysReason := e.isOOMSystem(startedAt, finishedAt)
+	switch {
+	case code == 0:
+		if err := e.install(ctx); err != nil {
+			return execInit, err
+		}
+	case timedOut:
+		e.Manifest.Result.Err = errors.Recover(errors.E("exec", e.id, errors.ExecTimeout,
+			errors.Errorf("killed after exceeding timeout %s", e.Config.Timeout)))
+	case code == temporaryExecErrorExitCode:
+		e.Manifest.Result.Err = errors.Recover(errors.E("exec", e.id, errors.Temporary,
+			errors.Errorf("exec returned exit code %d (considered temporary)", temporaryExecErrorExitCode)))
+	case oomSys:
+		e.Manifest.Result.Err = errors.Recover(errors.E("exec", e.id, errors.OOM, errors.Errorf("killed by OOM killer: %s", oomSysReason)))
+	case logsContain(ctx, e, "runtime: out of memory", "runtime: cannot allocate memory"):
+		e.Manifest.Result.Err = errors.Recover(errors.E("exec", e.id, errors.OOM, errors.New("detected golang OOM error")))
+	case code == possibleOOMExitCode:
+		e.Manifest.Result.Err = errors.Recover(errors.E("exec", e.id, errors.OOM,
+			errors.Errorf("apptainer returned possible OOM exit code %d", possibleOOMExitCode)))
+	case logTailsContain(outOfDiskLogTailBytes, []string{e.path("stdout"), e.path("stderr")}, enospcErrStr):
+		e.Manifest.Result.Err = errors.Recover(errors.E("exec", e.id, errors.OutOfDisk,
+			errors.Errorf("exited with code %d after running out of disk space", code)))
+	default:
+		e.Manifest.Result.Err = errors.Recover(errors.E("exec", e.id, errors.DockerExec, errors.Errorf("exited with code %d", code)))
+	}
+	if e.Manifest.Result.Err != nil {
+		postMortem{ExitCode: int64(code), Kernel: oomSysReason, Stderr: tailLines(e.path("stderr"), postMortemLines)}.
+			Annotate(e.Manifest.Result.Err)
+	}
+	if err := os.RemoveAll(e.path("arg")); err != nil {
+		e.Log.Errorf("failed to remove arg path: %v", err)
+	}
+	if err := os.RemoveAll(e.path("tmp")); err != nil {
+		e.Log.Errorf("failed to remove tmpdir: %v", err)
+	}
+	return execComplete, nil
+}
+
+// isOOMSystem checks to see if the exec was killed by the
+// system's OOM killer.
+func (e *apptainerExec) isOOMSystem(start, end time.Time) (ok bool, s string) {
+	if bootTime.IsZero() {
+		return
+	}
+	// See dockerExec.isOOMSystem.
+	time.Sleep(100 * time.Millisecond)
+	return e.Executor.oomTracker.Oom(e.Manifest.PID, start, end)
+}
+
+// profile profiles the disk usage and reported progress of the exec
+// until ctx is cancelled. Apptainer does not provide container
+// statistics, so CPU and memory are not profiled.
+func (e *apptainerExec) profile(ctx context.Context) stats {
+	var (
+		stats  = make(stats)
+		gauges = make(reflow.Gauges)
+		paths  = map[string]string{"tmp": e.path("tmp"), "disk": e.path("return")}
+		ticker = time.NewTicker(time.Minute)
+	)
+	defer ticker.Stop()
+	for ctx.Err() == nil {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+		}
+		for k, v := range paths {
+			n, err := du(v)
+			if err != nil {
+				e.Log.Errorf("du %s: %v", v, err)
+				continue
+			}
+			stats.Observe(time.Now(), k, float64(n))
+			gauges[k] = float64(n)
+		}
+		if p, ok, err := readProgress(progressPath(e.path("tmp"))); err != nil {
+			e.Log.Debugf("progress: %v", err)
+		} else if ok {
+			gauges[reflow.ProgressGauge] = p
+		}
+		e.mu.Lock()
+		e.Manifest.Gauges = gauges.Snapshot()
+		e.mu.Unlock()
+	}
+	return stats
+}
+
+// Go runs the exec's state machine. It resumes from the saved state
+// when possible; if no state exists, it begins from execUnstarted,
+// and immediately transitions to execInit.
+func (e *apptainerExec) Go(ctx context.Context) {
+	os.MkdirAll(e.path(), 0777)
+	for state, err := e.getState(); err == nil && state != execComplete; e.setState(state, err) {
+		switch state {
+		case execUnstarted:
+			state = execInit
+		case execInit:
+			state, err = e.create(ctx)
+		case execCreated:
+			state, err = e.start(ctx)
+		case execRunning:
+			state, err = e.wait(ctx)
+		default:
+			panic("bug")
+		}
+		if err == nil {
+			err = e.save(state)
+		}
+		if state == execComplete {
+			if e.stdout != nil {
+				e.stdout.Close()
+			}
+			if e.stderr != nil {
+				e.stderr.Close()
+			}
+		}
+	}
+}
+
+// Logs returns the stdout and/or stderr log files of the exec.
+// Since the apptainer process writes directly to these files, they
+// are also available while the exec is running; follow is not
+// supported.
+func (e *apptainerExec) Logs(ctx context.Context, stdout, stderr, follow bool) (io.ReadCloser, error) {
+	state, err := e.getState()
+	if err != nil {
+		return nil, err
+	}
+	if !stdout && !stderr {
+		return nil, errors.Errorf("logs %v %v %v: must specify at least one of stdout, stderr", e.id, stdout, stderr)
+	}
+	switch state {
+	case execUnstarted, execInit, execCreated:
+		return nil, errors.Errorf("logs %v %v %v: exec not yet started", e.id, stdout, stderr)
+	}
+	var (
+		readers []io.Reader
+		closers []io.Closer
+	)
+	for _, name := range []string{"stdout", "stderr"} {
+		if (name == "stdout" && !stdout) || (name == "stderr" && !stderr) {
+			continue
+		}
+		file, err := os.Open(e.path(name))
+		if err != nil {
+			for _, c := range closers {
+				c.Close()
+			}
+			return nil, err
+		}
+		readers = append(readers, file)
+		closers = append(closers, file)
+	}
+	return newAllCloser(io.MultiReader(readers...), closers...), nil
+}
+
+func (e *apptainerExec) RemoteLogs(_ context.Context, stdout bool) (reflow.RemoteLogs, error) {
+	if stdout {
+		return e.stdout.RemoteLogs(), nil
+	}
+	return e.stderr.RemoteLogs(), nil
+}
+
+// Shell is not supported for Apptainer execs.
+func (e *apptainerExec) Shell(ctx context.Context) (io.ReadWriteCloser, error) {
+	return nil, errors.E("shell", e.id, errors.NotSupported, errors.New("shell is not supported by the apptainer runtime"))
+}
+
+// Inspect returns the current state of the exec.
+func (e *apptainerExec) Inspect(ctx context.Context, repo *url.URL) (resp reflow.InspectResponse, err error) {
+	e.mu.Lock()
+	gauges := e.Manifest.Gauges
+	e.mu.Unlock()
+	inspect := reflow.ExecInspect{
+		Created: e.Manifest.Created,
+		Config:  e.Config,
+		Profile: e.Manifest.Stats.Profile(),
+		Gauges:  gauges,
+	}
+	state, err := e.getState()
+	if err != nil {
+		inspect.Error = errors.Recover(err)
+	}
+	inspect.ExecError = e.Manifest.Result.Err
+	switch state {
+	case execUnstarted, execInit:
+		inspect.State = "initializing"
+		inspect.Status = "the exec is still initializing"
+	case execCreated:
+		inspect.State = "created"
+		inspect.Status = "the exec container was created"
+	case execRunning:
+		inspect.State = "running"
+		inspect.Status = "the exec container is running"
+	case execComplete:
+		inspect.State = "complete"
+		inspect.Status = "the exec container has completed"
+		if repo != nil {
+			var runInfo reflow.ExecRunInfo
+			runInfo, err = saveExecInfo(ctx, state, e, inspect, repo, e.Executor.SaveLogsToRepo)
+			resp.RunInfo = &runInfo
+		}
+	}
+	if repo == nil {
+		resp.Inspect = &inspect
+	}
+	return
+}
+
+// Result returns the value computed by the exec.
+func (e *apptainerExec) Result(ctx context.Context) (reflow.Result, error) {
+	state, err := e.getState()
+	if err != nil {
+		return reflow.Result{}, err
+	}
+	if state != execComplete {
+		return reflow.Result{}, errors.Errorf("result %v: %s", e.id, errExecNotComplete)
+	}
+	return e.Manifest.Result, nil
+}
+
+// Promote promotes the objects in the exec's repository to the alloc repository.
+func (e *apptainerExec) Promote(ctx context.Context) error {
+	return e.promoteOnce.Do(func() error {
+		res, err := e.Result(ctx)
+		if err != nil {
+			return err
+		}
+		return e.Executor.promote(ctx, res.Fileset, &e.staging)
+	})
+}
+
+// Kill kills the exec's apptainer process and removes the exec entirely.
+func (e *apptainerExec) Kill(ctx context.Context) error {
+	e.mu.Lock()
+	cmd := e.cmd
+	e.mu.Unlock()
+	if cmd != nil {
+		killProcessGroup(cmd)
+	}
+	if err := e.Wait(ctx); err != nil {
+		return err
+	}
+	return os.RemoveAll(e.path())
+}
+
+// killProcessGroup kills the process group of the exec's apptainer
+// process, i.e., the process and all of its descendants.
+func killProcessGroup(cmd *osexec.Cmd) {
+	if cmd.Process == nil {
+		return
+	}
+	// A negative pid signals the whole process group.
+	_ = syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
+
+// WaitUntil returns when the object state reaches at least min, or
+// an error occurs.
+func (e *apptainerExec) WaitUntil(min execState) error {
+	e.mu.Lock()
+	for e.State < min && e.err == nil {
+		e.cond.Wait()
+	}
+	e.mu.Unlock()
+	return e.err
+}
+
+// Wait waits until the exec reaches completion.
+func (e *apptainerExec) Wait(ctx context.Context) error {
+	return e.WaitUntil(execComplete)
+}
+
+// URI returns a URI For this exec based on its executor's URI.
+func (e *apptainerExec) URI() string { return e.Executor.URI() + "/" + e.id.Hex() }
+
+// ID returns this exec's ID.
+func (e *apptainerExec) ID() digest.Digest { return e.id }
+
+// path constructs a path in the exec's directory.
+func (e *apptainerExec) path(elems ...string) string {
+	return e.Executor.execPath(e.id, elems...)
+}
+
+// setState sets the current state and error. It broadcasts
+// on the exec's condition variable to wake up all waiters.
+func (e *apptainerExec) setState(state execState, err error) {
+	e.mu.Lock()
+	e.State = state
+	e.err = err
+	e.cond.Broadcast()
+	e.mu.Unlock()
+}
+
+// getState returns the current state of the exec.
+func (e *apptainerExec) getState() (execState, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.State, e.err
+}
+
+// install installs the exec's result object into the repository.
+func (e *apptainerExec) install(ctx context.Context) error {
+	if e.Manifest.Result.Fileset.Map != nil || e.Manifest.Result.Fileset.List != nil {
+		return nil
+	}
+	if outputs := e.Config.OutputIsDir; outputs != nil {
+		e.Manifest.Result.Fileset.List = make([]reflow.Fileset, len(outputs))
+		for i := range outputs {
+			var err error
+			e.Manifest.Result.Fileset.List[i], err =
+				e.Executor.install(ctx, e.path("return", strconv.Itoa(i)), true, &e.staging)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	var err error
+	e.Manifest.Result.Fileset, err = e.Executor.install(ctx, e.path("return", "default"), true, &e.staging)
+	return err
+}
@@ -0,0 +1,59 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package local
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/grailbio/reflow"
+)
+
+func TestApptainerImage(t *testing.T) {
+	for _, tc := range []struct {
+		image, want string
+	}{
+		{"ubuntu", "docker://ubuntu"},
+		{"123456789.dkr.ecr.us-west-2.amazonaws.com/tool:v1", "docker://123456789.dkr.ecr.us-west-2.amazonaws.com/tool:v1"},
+		{"docker://ubuntu", "docker://ubuntu"},
+		{"library://alpine", "library://alpine"},
+		{"/images/tool.sif", "/images/tool.sif"},
+	} {
+		if got, want := apptainerImage(tc.image), tc.want; got != want {
+			t.Errorf("apptainerImage(%q): got %v, want %v", tc.image, got, want)
+		}
+	}
+}
+
+func TestApptainerArgs(t *testing.T) {
+	prefix := []string{
+		"exec", "--containall", "--cleanenv",
+		"--bind", "/x/arg:/arg,/x/tmp:/tmp,/x/return:/return",
+		"--pwd", "/tmp",
+	}
+	suffix := []string{"docker://ubuntu", "/bin/bash", "-e", "-l", "-o", "pipefail", "-c", "echo hello"}
+	join := func(elems ...[]string) []string {
+		var args []string
+		for _, e := range elems {
+			args = append(args, e...)
+		}
+		return args
+	}
+	r := reflow.Resources{"mem": 1 << 30, "cpu": 2}
+	for _, tc := range []struct {
+		hardMemLimit bool
+		cpus         float64
+		want         []string
+	}{
+		{false, 0, join(prefix, suffix)},
+		{true, 0, join(prefix, []string{"--memory", "1073741824", "--memory-swap", "1178599424"}, suffix)},
+		{true, 2, join(prefix, []string{"--memory", "1073741824", "--memory-swap", "1178599424", "--cpus", "2"}, suffix)},
+		{false, 0.5, join(prefix, []string{"--cpus", "0.5"}, suffix)},
+	} {
+		if got, want := apptainerArgs("/x", "ubuntu", "echo hello", r, tc.hardMemLimit, tc.cpus), tc.want; !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	}
+}
@@ -735,13 +735,17 @@ type upload struct {
 }
 
 func (u *upload) Do(ctx context.Context) error {
-	f := newLazyReadCloser(func() (io.ReadCloser, error) {
+	f := newLazyReadCloser(func() (readAtCloser, error) {
 		uploadingFiles.Add(1)
-		file, err := u.Repository.Get(ctx, u.ID)
-		if err == nil {
-			u.Log.Printf("upload %s (%s) to %s%s", u.Key, data.Size(u.Size), u.Bucket.Location(), u.Key)
+		// The file is opened directly (rather than through Repository.Get)
+		// since resumable uploads read it at arbitrary offsets.
+		_, path := u.Repository.Path(u.ID)
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, errors.E("get", u.Repository.Root, u.ID, err)
 		}
-		return file, err
+		u.Log.Printf("upload %s (%s) to %s%s", u.Key, data.Size(u.Size), u.Bucket.Location(), u.Key)
+		return file, nil
 	})
 	defer func() {
 		if err := f.Close(); err != nil {
@@ -766,29 +770,47 @@ func (u *upload) Do(ctx context.Context) error {
 // This is to avoid having too many file descriptors open when we intend to read from and upload a lot of files,
 // while doing so with various concurrency limits limiting the number of files being processed concurrently.
 type lazyReadCloser struct {
-	io.ReadCloser
-	opener   func() (io.ReadCloser, error)
+	readAtCloser
+	opener   func() (readAtCloser, error)
 	openErr  error
 	openOnce sync.Once
 }
 
-func newLazyReadCloser(opener func() (io.ReadCloser, error)) io.ReadCloser {
+// readAtCloser combines different interfaces for use by lazyReadCloser.
+type readAtCloser interface {
+	io.ReadCloser
+	io.ReaderAt
+}
+
+func newLazyReadCloser(opener func() (readAtCloser, error)) io.ReadCloser {
 	return &lazyReadCloser{opener: opener}
 }
 
 func (r *lazyReadCloser) Read(p []byte) (n int, err error) {
 	r.openOnce.Do(func() {
-		r.ReadCloser, r.openErr = r.opener()
+		r.readAtCloser, r.openErr = r.opener()
+	})
+	if r.openErr != nil {
+		return 0, r.openErr
+	}
+	return r.readAtCloser.Read(p)
+}
+
+// ReadAt implements io.ReaderAt, so that uploads of large files can
+// be resumed.
+func (r *lazyReadCloser) ReadAt(p []byte, off int64) (n int, err error) {
+	r.openOnce.Do(func() {
+		r.readAtCloser, r.openErr = r.opener()
 	})
 	if r.openErr != nil {
 		return 0, r.openErr
 	}
-	return r.ReadCloser.Read(p)
+	return r.readAtCloser.ReadAt(p, off)
 }
 
 func (r *lazyReadCloser) Close() error {
-	if r.ReadCloser != nil {
-		return r.ReadCloser.Close()
+	if r.readAtCloser != nil {
+		return r.readAtCloser.Close()
 	}
 	return nil
 }
@@ -0,0 +1,195 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package local
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/grailbio/reflow"
+	"github.com/grailbio/reflow/blob"
+	"github.com/grailbio/reflow/errors"
+	"github.com/grailbio/reflow/log"
+)
+
+const (
+	// blobLogsFlushInterval is the interval at which blob log streams
+	// are written to their objects, if they have changed.
+	blobLogsFlushInterval = 10 * time.Second
+	// blobLogsMaxBytes is the maximum size of a blob log stream.
+	// Objects cannot be appended to, and so each stream is rewritten
+	// in full whenever it is flushed; logs beyond this size are dropped.
+	blobLogsMaxBytes = 64 << 20
+	// blobLogsTimeout is the timeout for writing a blob log stream.
+	blobLogsTimeout = time.Minute
+)
+
+// blobLogs implements a remoteStream which ships logs to objects in
+// a blob store (e.g., S3) under a prefix. Streams are written to
+// their objects periodically while they are written to, so that logs
+// survive the termination of the instance on which they were written.
+type blobLogs struct {
+	bucket blob.Bucket
+	prefix string
+
+	streamsMu sync.Mutex
+	streams   []*blobLogsStream
+	closed    bool
+}
+
+// newBlobLogs returns a new remote logger client which writes streams
+// to objects in the provided bucket under the provided prefix.
+func newBlobLogs(bucket blob.Bucket, prefix string) *blobLogs {
+	return &blobLogs{bucket: bucket, prefix: prefix}
+}
+
+// NewStream creates a new stream with the given stream prefix and type.
+// The stream is written to the object prefix/sprefix/sType.
+func (b *blobLogs) NewStream(sprefix string, sType streamType) remoteLogsOutputter {
+	b.streamsMu.Lock()
+	defer b.streamsMu.Unlock()
+	if b.closed {
+		panic("calling NewStream after closing")
+	}
+	stream := newBlobLogsStream(b.bucket, path.Join(b.prefix, sprefix, string(sType)))
+	b.streams = append(b.streams, stream)
+	return stream
+}
+
+func (b *blobLogs) Close() error {
+	b.streamsMu.Lock()
+	defer b.streamsMu.Unlock()
+	for _, stream := range b.streams {
+		stream.Close()
+	}
+	b.closed = true
+	return nil
+}
+
+// blobLogsStream is a log stream which is written to a blob object.
+type blobLogsStream struct {
+	bucket blob.Bucket
+	key    string
+
+	mu        sync.Mutex
+	buf       bytes.Buffer
+	dirty     bool
+	truncated bool
+	closed    bool
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newBlobLogsStream(bucket blob.Bucket, key string) *blobLogsStream {
+	s := &blobLogsStream{bucket: bucket, key: key, quit: make(chan struct{})}
+	s.wg.Add(1)
+	go s.loop()
+	return s
+}
+
+// loop writes the stream to its object every blobLogsFlushInterval
+// until the stream is closed, and then one last time.
+func (s *blobLogsStream) loop() {
+	defer s.wg.Done()
+	tick := time.NewTicker(blobLogsFlushInterval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-tick.C:
+			s.flush()
+		case <-s.quit:
+			s.flush()
+			return
+		}
+	}
+}
+
+// flush writes the stream to its object if it has changed since it
+// was last written. Failures are logged: they are retried at the next
+// flush.
+func (s *blobLogsStream) flush() {
+	s.mu.Lock()
+	if !s.dirty {
+		s.mu.Unlock()
+		return
+	}
+	b := append([]byte{}, s.buf.Bytes()...)
+	s.dirty = false
+	s.mu.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), blobLogsTimeout)
+	defer cancel()
+	if err := s.bucket.Put(ctx, s.key, int64(len(b)), bytes.NewReader(b), ""); err != nil {
+		log.Errorf("write logs %s%s: %v", s.bucket.Location(), s.key, err)
+		s.mu.Lock()
+		s.dirty = true
+		s.mu.Unlock()
+	}
+}
+
+// Write appends p to the stream.
+func (s *blobLogsStream) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return 0, errors.New("cannot write to a closed stream")
+	}
+	if s.truncated {
+		return len(p), nil
+	}
+	if s.buf.Len()+len(p) > blobLogsMaxBytes {
+		s.buf.WriteString("[log truncated]\n")
+		s.truncated, s.dirty = true, true
+		return len(p), nil
+	}
+	s.buf.Write(p)
+	s.dirty = true
+	return len(p), nil
+}
+
+// Output writes the message msg as a line to the stream.
+func (s *blobLogsStream) Output(calldepth int, msg string) error {
+	if len(msg) == 0 || msg[len(msg)-1] != '\n' {
+		msg += "\n"
+	}
+	_, err := s.Write([]byte(msg))
+	return err
+}
+
+func (s *blobLogsStream) RemoteLogs() reflow.RemoteLogs {
+	return reflow.RemoteLogs{
+		Type: reflow.RemoteLogsTypeBlob,
+		URL:  s.bucket.Location() + s.key,
+	}
+}
+
+// Close flushes the stream and closes it.
+func (s *blobLogsStream) Close() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	s.mu.Unlock()
+	close(s.quit)
+	s.wg.Wait()
+}
+
+// NewLogStream returns a writer to a new log stream which is written
+// to the object named name under the pool's Logs prefix, together
+// with a function done that flushes and closes the stream. It returns a
+// nil writer if the pool's Logs is not set. The pool must be started.
+func (p *Pool) NewLogStream(name string) (w io.Writer, done func()) {
+	if p.logsBucket == nil {
+		return nil, func() {}
+	}
+	s := newBlobLogsStream(p.logsBucket, path.Join(p.logsPrefix, name))
+	return s, s.Close
+}
@@ -0,0 +1,52 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package local
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/grailbio/reflow"
+	"github.com/grailbio/reflow/blob/testblob"
+)
+
+func TestBlobLogs(t *testing.T) {
+	ctx := context.Background()
+	bucket, err := testblob.New("s3").Bucket(ctx, "bucket")
+	if err != nil {
+		t.Fatal(err)
+	}
+	logs := newBlobLogs(bucket, "logs")
+	so := logs.NewStream("run/alloc/exec", stdout)
+	if err := so.Output(0, "hello"); err != nil {
+		t.Fatal(err)
+	}
+	if err := so.Output(0, "world\n"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := so.RemoteLogs(), (reflow.RemoteLogs{Type: reflow.RemoteLogsTypeBlob, URL: "s3://bucket/logs/run/alloc/exec/stdout"}); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	// Streams are flushed when they are closed.
+	if err := logs.Close(); err != nil {
+		t.Fatal(err)
+	}
+	rc, _, err := bucket.Get(ctx, "logs/run/alloc/exec/stdout", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	b, err := ioutil.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), "hello\nworld\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if err := so.Output(0, "closed"); err == nil {
+		t.Error("expected error writing to a closed stream")
+	}
+}
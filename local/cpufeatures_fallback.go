@@ -10,6 +10,15 @@
 
 package local
 
+import (
+	"runtime"
+
+	"github.com/grailbio/reflow"
+)
+
 func cpuFeatures() ([]string, error) {
+	if runtime.GOARCH == "arm64" {
+		return []string{reflow.Arm64}, nil
+	}
 	return nil, nil
 }
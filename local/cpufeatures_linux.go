@@ -14,10 +14,18 @@ import (
 	"bufio"
 	"errors"
 	"os"
+	"runtime"
 	"strings"
+
+	"github.com/grailbio/reflow"
 )
 
 func cpuFeatures() ([]string, error) {
+	// arm64 machines do not report x86 flags; the architecture
+	// itself is the only feature we expose for them.
+	if runtime.GOARCH == "arm64" {
+		return []string{reflow.Arm64}, nil
+	}
 	f, err := os.Open("/proc/cpuinfo")
 	if err != nil {
 		return nil, err
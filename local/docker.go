@@ -8,13 +8,16 @@ package local
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/url"
 	"os"
 	"path"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
@@ -53,10 +56,37 @@ const (
 	// - From `/usr/include/sysexits.h` in linux:
 	// #define EX_TEMPFAIL	75	/* temp failure; user is invited to retry */
 	temporaryExecErrorExitCode = 75
+	// minBlkioWeight and maxBlkioWeight are the bounds of the
+	// block I/O weights accepted by docker.
+	minBlkioWeight = 10
+	maxBlkioWeight = 1000
+	// enospcErrStr is the message with which a failed exec reports
+	// that it ran out of disk space.
+	enospcErrStr = "No space left on device"
+	// outOfDiskLogTailBytes bounds the number of trailing bytes of each
+	// of a failed exec's logs in which enospcErrStr is looked for.
+	outOfDiskLogTailBytes = 64 << 10
 )
 
 var dockerUser = fmt.Sprintf("%d:%d", os.Getuid(), os.Getgid())
 
+// blkioWeight returns the block I/O weight of an exec with resources r
+// running in an executor with the total resources total. The weight
+// is proportional to the exec's share of the executor's CPUs.
+func blkioWeight(r, total reflow.Resources) uint16 {
+	if total["cpu"] <= 0 {
+		return maxBlkioWeight
+	}
+	w := maxBlkioWeight * r["cpu"] / total["cpu"]
+	switch {
+	case w < minBlkioWeight:
+		w = minBlkioWeight
+	case w > maxBlkioWeight:
+		w = maxBlkioWeight
+	}
+	return uint16(w)
+}
+
 // dockerExec is a (local) exec attached to a local executor, from which it
 // is given its own subdirectory to operate. exec is responsible for
 // the lifecycle of an exec through an executor. It maintains a state
@@ -140,6 +170,14 @@ func (e *dockerExec) containerName() string {
 	return fmt.Sprintf("reflow-%s-%s-%s", e.Executor.ID, e.id.Hex(), pathHex)
 }
 
+// redactDocker redacts the exec's secrets from its Docker inspect
+// output, which is saved in the exec's manifest.
+func (e *dockerExec) redactDocker() {
+	if e.Docker.Config != nil {
+		redactEnv(e.Docker.Config.Env, e.Config)
+	}
+}
+
 // create sets up the exec's filesystem layout environment and
 // instantiates its container. It is not run. The arguments are
 // materialized to a the 'arg' directory in the exec's run directory,
@@ -157,35 +195,19 @@ func (e *dockerExec) create(ctx context.Context) (execState, error) {
 	} else if !docker.IsErrNotFound(err) {
 		return execInit, errors.E("ContainerInspect", e.containerName(), kind(err), err)
 	}
+	e.Manifest.PullStart = time.Now()
 	if err := e.Executor.ensureImage(ctx, e.Config.Image); err != nil {
 		e.Log.Errorf("error ensuring image %s: %v", e.Config.Image, err)
 		return execInit, errors.E("ensureimage", e.Config.Image, err)
 	}
-	// Map the products to input arguments and volume bindings for
-	// the container. Currently we map the whole repository (named by
-	// the digest) and then include the cut in the arguments passed to
-	// the job.
-	args := make([]interface{}, len(e.Config.Args))
-	for i, iv := range e.Config.Args {
-		if iv.Out {
-			which := strconv.Itoa(iv.Index)
-			args[i] = path.Join("/return", which)
-		} else {
-			flat := iv.Fileset.Flatten()
-			argv := make([]string, len(flat))
-			for j, jv := range flat {
-				argPath := fmt.Sprintf("arg/%d/%d", i, j)
-				binds := map[string]digest.Digest{}
-				for path, file := range jv.Map {
-					binds[path] = file.ID
-				}
-				if err := e.repo.Materialize(e.path(argPath), binds); err != nil {
-					return execInit, err
-				}
-				argv[j] = "/" + argPath
-			}
-			args[i] = strings.Join(argv, " ")
-		}
+	e.Manifest.PullEnd = time.Now()
+	args, err := materializeArgs(e.Config, e.repo, e.path)
+	if err != nil {
+		return execInit, err
+	}
+	cmd, err := execCommand(e.Config, args, e.path)
+	if err != nil {
+		return execInit, err
 	}
 	// Set up temporary directory.
 	os.MkdirAll(e.path("tmp"), 0777)
@@ -197,13 +219,16 @@ func (e *dockerExec) create(ctx context.Context) (execState, error) {
 			e.hostPath("return") + ":/return",
 		},
 		NetworkMode: container.NetworkMode("host"),
-		// Try to ensure that jobs we control get killed before the reflowlet,
-		// so that we don't lose adjacent tasks unnecessarily and so that
-		// errors are more sensible to the user.
-		OomScoreAdj: 1000,
+		// Try to ensure that jobs we control get killed before the reflowlet
+		// (and lower priority jobs before higher priority ones).
+		OomScoreAdj: oomScoreAdj(e.Config.Priority),
 	}
 	if e.Config.NeedDockerAccess {
-		hostConfig.Binds = append(hostConfig.Binds, "/var/run/docker.sock:/var/run/docker.sock")
+		socket := e.Executor.DockerSocket
+		if socket == "" {
+			socket = defaultDockerSocket
+		}
+		hostConfig.Binds = append(hostConfig.Binds, socket+":"+defaultDockerSocket)
 	}
 
 	// Restrict docker memory usage if specified by the user.
@@ -215,12 +240,37 @@ func (e *dockerExec) create(ctx context.Context) (execState, error) {
 		hostConfig.Resources.Memory = int64(mem)
 		hostConfig.Resources.MemorySwap = int64(mem) + int64(hardLimitSwapMem)
 	}
+	// Restrict CPU usage and block I/O share so that a single exec
+	// cannot starve others running in the same alloc.
+	if e.Executor.StrictLimits {
+		if cpu := e.Config.Resources["cpu"]; cpu > 0 {
+			hostConfig.Resources.NanoCPUs = int64(cpu * 1e9)
+		}
+		hostConfig.Resources.BlkioWeight = blkioWeight(e.Config.Resources, e.Executor.Resources())
+	}
+	// Apportion CPU and block I/O according to the exec's priority
+	// relative to others running on the same host.
+	if p := e.Config.Priority; p != 0 {
+		hostConfig.Resources.CPUShares = cpuShares(p)
+		w := hostConfig.Resources.BlkioWeight
+		if w == 0 {
+			w = defaultBlkioWeight
+		}
+		hostConfig.Resources.BlkioWeight = priorityBlkioWeight(w, p)
+	}
 
 	env := []string{
 		"tmp=/tmp",
 		"TMPDIR=/tmp",
 		"HOME=/tmp",
 	}
+	env = append(env, e.Config.Env()...)
+	env = append(env, userEnv(e.Config)...)
+	secrets, err := secretEnv(ctx, e.Executor.Secrets, e.Config)
+	if err != nil {
+		return execInit, errors.E("run", e.id, err)
+	}
+	env = append(env, secrets...)
 	if outputs := e.Config.OutputIsDir; outputs != nil {
 		for i, isdir := range outputs {
 			if isdir {
@@ -248,25 +298,108 @@ func (e *dockerExec) create(ctx context.Context) (execState, error) {
 		Image: e.Config.Image,
 		// We use a login shell here as many Docker images are configured
 		// with /root/.profile, etc.
-		Entrypoint: []string{"/bin/bash", "-e", "-l", "-o", "pipefail", "-c", fmt.Sprintf(e.Config.Cmd, args...)},
+		Entrypoint: []string{"/bin/bash", "-e", "-l", "-o", "pipefail", "-c", cmd},
 		Cmd:        []string{},
 		Env:        env,
 		Labels:     map[string]string{"reflow-id": e.id.Hex()},
 		User:       dockerUser,
 	}
+	// In rootless Podman, the container's root user is mapped to the user
+	// running Podman, which owns the exec's directories; other users are
+	// mapped to subordinate IDs which cannot access them.
+	if e.Executor.Runtime == RuntimePodman {
+		config.User = "0:0"
+	}
 	networkingConfig := &network.NetworkingConfig{}
 	if _, err := e.client.ContainerCreate(ctx, config, hostConfig, networkingConfig, e.containerName()); err != nil {
+		// Secrets must not appear in errors.
+		logged := *config
+		logged.Env = append([]string(nil), config.Env...)
+		redactEnv(logged.Env, e.Config)
 		return execInit, errors.E(
 			"ContainerCreate",
 			kind(err),
 			e.containerName(),
-			fmt.Sprint(config), fmt.Sprint(hostConfig), fmt.Sprint(networkingConfig),
+			fmt.Sprint(logged), fmt.Sprint(hostConfig), fmt.Sprint(networkingConfig),
 			err,
 		)
 	}
 	return execCreated, nil
 }
 
+// materializeArgs maps the products to input arguments and volume
+// bindings for the container. Currently we map the whole repository
+// (named by the digest) and then include the cut in the arguments
+// passed to the job. Arguments are materialized under the 'arg'
+// directory of the exec, as given by execPath. Inline arguments are
+// written to files under the 'arg/inline' directory. Scratch directories
+// are created under the exec's 'tmp' directory, so that they are
+// removed (and never returned) together with it.
+func materializeArgs(cfg reflow.ExecConfig, repo *filerepo.Repository, execPath func(...string) string) ([]interface{}, error) {
+	args := make([]interface{}, len(cfg.Args))
+	for i, iv := range cfg.Args {
+		if iv.Out {
+			which := strconv.Itoa(iv.Index)
+			args[i] = path.Join("/return", which)
+		} else if iv.Scratch {
+			which := strconv.Itoa(iv.Index)
+			if err := os.MkdirAll(execPath("tmp", "scratch", which), 0777); err != nil {
+				return nil, err
+			}
+			args[i] = path.Join("/tmp", "scratch", which)
+		} else if iv.Inline {
+			argPath := fmt.Sprintf("arg/inline/%d", i)
+			if err := writeInline(execPath(argPath), iv.Data); err != nil {
+				return nil, err
+			}
+			args[i] = "/" + argPath
+		} else {
+			flat := iv.Fileset.Flatten()
+			argv := make([]string, len(flat))
+			for j, jv := range flat {
+				argPath := fmt.Sprintf("arg/%d/%d", i, j)
+				binds := map[string]digest.Digest{}
+				for path, file := range jv.Map {
+					binds[path] = file.ID
+				}
+				if err := repo.Materialize(execPath(argPath), binds); err != nil {
+					return nil, err
+				}
+				argv[j] = "/" + argPath
+			}
+			args[i] = strings.Join(argv, " ")
+		}
+	}
+	return args, nil
+}
+
+// execCommand returns the exec's command, rendered with the provided
+// (materialized) arguments. If the exec has inline standard input, it
+// is written to the exec's 'arg' directory and redirected to the
+// command's standard input.
+func execCommand(cfg reflow.ExecConfig, args []interface{}, execPath func(...string) string) (string, error) {
+	cmd := fmt.Sprintf(cfg.Cmd, args...)
+	if cfg.Stdin == "" {
+		return cmd, nil
+	}
+	if err := writeInline(execPath("arg", "stdin"), cfg.Stdin); err != nil {
+		return "", err
+	}
+	return "exec 0</arg/stdin\n" + cmd, nil
+}
+
+// writeInline writes the inline data to the given path, creating
+// its parent directory if needed.
+func writeInline(file, data string) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0777); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(file, []byte(data), 0644); err != nil {
+		return errors.E("write inline", file, err)
+	}
+	return nil
+}
+
 func scanLines(input io.ReadCloser, output *log.Logger) error {
 	r, w := io.Pipe()
 	go func() {
@@ -287,6 +420,7 @@ func (e *dockerExec) start(ctx context.Context) (execState, error) {
 	}
 	var err error
 	e.Docker, err = e.client.ContainerInspect(ctx, e.containerName())
+	e.redactDocker()
 	e.Manifest.PID = e.Docker.State.Pid
 	if err != nil {
 		e.Log.Errorf("error inspecting container %q: %v", e.containerName(), err)
@@ -341,15 +475,18 @@ func (e *dockerExec) wait(ctx context.Context) (state execState, err error) {
 
 	// The documentation for ContainerWait seems to imply that both channels will
 	// be sent. In practice it's one or the other, and it's also not buffered. Cool API.
+	timer := e.startTimer(ctx)
 	respc, errc := e.client.ContainerWait(ctx, e.containerName(), container.WaitConditionNotRunning)
 	var code int64
 	select {
 	case err := <-errc:
 		cancelprof()
+		timer.Stop()
 		return execInit, errors.E("ContainerWait", e.containerName(), kind(err), err)
 	case resp := <-respc:
 		code = resp.StatusCode
 	}
+	timedOut := timer.Stop()
 	// Best-effort writing of log files.
 	rc, err := e.client.ContainerLogs(
 		ctx, e.containerName(),
@@ -379,6 +516,7 @@ func (e *dockerExec) wait(ctx context.Context) (state execState, err error) {
 		}
 	}
 	e.Docker, err = e.client.ContainerInspect(ctx, e.containerName())
+	e.redactDocker()
 
 	// Retrieve the profile before we clean up the results.
 	cancelprof()
@@ -447,6 +585,9 @@ func (e *dockerExec) wait(ctx context.Context) (state execState, err error) {
 		if err := e.install(ctx); err != nil {
 			return execInit, err
 		}
+	case timedOut:
+		e.Manifest.Result.Err = errors.Recover(errors.E("exec", e.id, errors.ExecTimeout,
+			errors.Errorf("killed after exceeding timeout %s", e.Config.Timeout)))
 	case code == temporaryExecErrorExitCode:
 		e.Manifest.Result.Err = errors.Recover(errors.E("exec", e.id, errors.Temporary,
 			errors.Errorf("exec returned exit code %d (considered temporary)", temporaryExecErrorExitCode)))
@@ -461,9 +602,16 @@ func (e *dockerExec) wait(ctx context.Context) (state execState, err error) {
 			errors.Errorf("docker returned possible OOM exit code %d", possibleOOMExitCode)))
 	case oomNode:
 		e.Manifest.Result.Err = errors.Recover(errors.E("exec", e.id, errors.OOM, oomNodeReason))
+	case e.isOutOfDisk():
+		e.Manifest.Result.Err = errors.Recover(errors.E("exec", e.id, errors.OutOfDisk,
+			errors.Errorf("exited with code %d after running out of disk space", code)))
 	default:
 		e.Manifest.Result.Err = errors.Recover(errors.E("exec", e.id, errors.DockerExec, errors.Errorf("exited with code %d", code)))
 	}
+	if e.Manifest.Result.Err != nil {
+		postMortem{ExitCode: code, Kernel: oomSysReason, Stderr: tailLines(e.path("stderr"), postMortemLines)}.
+			Annotate(e.Manifest.Result.Err)
+	}
 
 	// Clean up args. TODO(marius): replace these with symlinks to sha256s also?
 	if err := os.RemoveAll(e.path("arg")); err != nil {
@@ -475,6 +623,27 @@ func (e *dockerExec) wait(ctx context.Context) (state execState, err error) {
 	return execComplete, nil
 }
 
+// startTimer starts the timer which enforces the exec's timeout, if
+// any. The timeout is measured from the container's start time, so
+// that it continues to be enforced if the executor is restarted.
+func (e *dockerExec) startTimer(ctx context.Context) *execTimer {
+	if e.Config.Timeout <= 0 {
+		return nil
+	}
+	start := time.Now()
+	if info, err := e.client.ContainerInspect(ctx, e.containerName()); err == nil {
+		if t, err := time.Parse(reflow.DockerInspectTimeFormat, info.State.StartedAt); err == nil {
+			start = t
+		}
+	}
+	return startExecTimer(start, e.Config.Timeout, func() {
+		e.Log.Printf("killing container after exceeding timeout %s", e.Config.Timeout)
+		if err := e.client.ContainerKill(context.Background(), e.containerName(), "KILL"); err != nil {
+			e.Log.Errorf("kill container %s: %v", e.containerName(), err)
+		}
+	})
+}
+
 // profile profiles the container and returns a profile when its
 // context is cancelled or when the container stops. profile profiles
 // the following resources:
@@ -482,6 +651,8 @@ func (e *dockerExec) wait(ctx context.Context) (state execState, err error) {
 // mem: Memory usage in bytes.
 // tmp: Disk usage in the tmp directory in bytes.
 // disk: Total disk usage of the return directory in bytes.
+// It also gauges the progress reported by the exec (see
+// reflow.ExecProgressPath), if any.
 // Note that profile logs all its errors to e.Log.Error
 // and does not return an error. It simply attempts
 // to profile resources until ctx is cancelled.
@@ -520,8 +691,16 @@ func (e *dockerExec) profile(ctx context.Context) stats {
 				gauges[k] = float64(n)
 				mu.Unlock()
 			}
+			// Read the progress reported by the exec, if any.
+			p, ok, err := readProgress(progressPath(e.path("tmp")))
+			if err != nil {
+				e.Log.Debugf("progress: %v", err)
+			}
 
 			mu.Lock()
+			if ok {
+				gauges[reflow.ProgressGauge] = p
+			}
 			e.Manifest.Gauges = gauges.Snapshot()
 			mu.Unlock()
 		}
@@ -742,6 +921,7 @@ func (e *dockerExec) Inspect(ctx context.Context, repo *url.URL) (resp reflow.In
 		Profile: e.Manifest.Stats.Profile(),
 		Gauges:  e.Manifest.Gauges,
 	}
+	addImagePull(inspect.Profile, e.Manifest.PullStart, e.Manifest.PullEnd)
 	state, err := e.getState()
 	if err != nil {
 		inspect.Error = errors.Recover(err)
@@ -961,12 +1141,46 @@ func (e *dockerExec) isOOMGolang(ctx context.Context) bool {
 		oomErrStr1 = "runtime: out of memory"
 		oomErrStr2 = "runtime: cannot allocate memory"
 	)
-	rc, err := e.Logs(ctx, true, true, false)
-	if err == nil {
-		defer func() { _ = rc.Close() }()
-		scanner := bufio.NewScanner(rc)
-		for scanner.Scan() {
-			if strings.Contains(scanner.Text(), oomErrStr1) || strings.Contains(scanner.Text(), oomErrStr2) {
+	return logsContain(ctx, e, oomErrStr1, oomErrStr2)
+}
+
+// isOutOfDisk checks to see if the docker exec failed because it ran out of
+// disk space, by looking for the ENOSPC error message in the tails of its
+// (saved) logs.
+func (e *dockerExec) isOutOfDisk() bool {
+	return logTailsContain(outOfDiskLogTailBytes, []string{e.path("stdout"), e.path("stderr")}, enospcErrStr)
+}
+
+// logTailsContain tells whether the last (up to) max bytes of any of
+// the given log files contain one of the given strings. Unlike
+// logsContain, it does not read the logs in full, which can be large.
+func logTailsContain(max int64, paths []string, strs ...string) bool {
+	for _, path := range paths {
+		tail, _, err := readTail(path, max)
+		if err != nil {
+			continue
+		}
+		for _, str := range strs {
+			if bytes.Contains(tail, []byte(str)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// logsContain tells whether any line of the exec's stdout/stderr
+// logs contains one of the given strings.
+func logsContain(ctx context.Context, x reflow.Exec, strs ...string) bool {
+	rc, err := x.Logs(ctx, true, true, false)
+	if err != nil {
+		return false
+	}
+	defer func() { _ = rc.Close() }()
+	scanner := bufio.NewScanner(rc)
+	for scanner.Scan() {
+		for _, str := range strs {
+			if strings.Contains(scanner.Text(), str) {
 				return true
 			}
 		}
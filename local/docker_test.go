@@ -0,0 +1,92 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package local
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/grailbio/reflow"
+)
+
+func TestBlkioWeight(t *testing.T) {
+	total := reflow.Resources{"cpu": 16, "mem": 64 << 30}
+	for _, tc := range []struct {
+		r, total reflow.Resources
+		want     uint16
+	}{
+		{reflow.Resources{"cpu": 16}, total, 1000},
+		{reflow.Resources{"cpu": 4}, total, 250},
+		{reflow.Resources{"cpu": 0.01}, total, 10},
+		{reflow.Resources{"cpu": 32}, total, 1000},
+		{reflow.Resources{"cpu": 1}, nil, 1000},
+	} {
+		if got, want := blkioWeight(tc.r, tc.total), tc.want; got != want {
+			t.Errorf("blkioWeight(%v, %v): got %v, want %v", tc.r, tc.total, got, want)
+		}
+	}
+}
+
+func TestNormalizeRef(t *testing.T) {
+	for _, tc := range []struct {
+		ref, want string
+	}{
+		{"ubuntu:latest", "docker.io/library/ubuntu:latest"},
+		{"docker.io/library/ubuntu:latest", "docker.io/library/ubuntu:latest"},
+		{"grailbio/awstool:latest", "docker.io/grailbio/awstool:latest"},
+		{"123456789.dkr.ecr.us-west-2.amazonaws.com/tool:v1", "123456789.dkr.ecr.us-west-2.amazonaws.com/tool:v1"},
+		{"<none>:<none>", "<none>:<none>"},
+	} {
+		if got, want := normalizeRef(tc.ref), tc.want; got != want {
+			t.Errorf("normalizeRef(%q): got %v, want %v", tc.ref, got, want)
+		}
+	}
+}
+
+func TestMaterializeInline(t *testing.T) {
+	dir := t.TempDir()
+	execPath := func(elems ...string) string {
+		return filepath.Join(append([]string{dir}, elems...)...)
+	}
+	cfg := reflow.ExecConfig{
+		Cmd: "tool -config %s -tmp %s > %s",
+		Args: []reflow.Arg{
+			{Inline: true, Data: "key: value\n"},
+			{Scratch: true, Index: 0},
+			{Out: true, Index: 0},
+		},
+		Stdin: "input\n",
+	}
+	args, err := materializeArgs(cfg, nil, execPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := args, []interface{}{"/arg/inline/0", "/tmp/scratch/0", "/return/0"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	b, err := ioutil.ReadFile(execPath("arg", "inline", "0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), "key: value\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	cmd, err := execCommand(cfg, args, execPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := cmd, "exec 0</arg/stdin\ntool -config /arg/inline/0 -tmp /tmp/scratch/0 > /return/0"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	b, err = ioutil.ReadFile(execPath("arg", "stdin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), "input\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -48,17 +48,19 @@ func imageExists(ctx context.Context, client *docker.Client, id string) (bool, e
 		}
 	}
 
-	refStr := ref.String()
+	// Compare normalized references, since some daemons (e.g., Podman)
+	// report fully qualified image names (docker.io/library/ubuntu:latest).
+	refStr := normalizeRef(ref.String())
 	for _, image := range images {
 		if useDigest {
 			for _, digest := range image.RepoDigests {
-				if digest == refStr {
+				if normalizeRef(digest) == refStr {
 					return true, nil
 				}
 			}
 		} else {
 			for _, tag := range image.RepoTags {
-				if tag == refStr {
+				if normalizeRef(tag) == refStr {
 					return true, nil
 				}
 			}
@@ -67,23 +69,27 @@ func imageExists(ctx context.Context, client *docker.Client, id string) (bool, e
 	return false, nil
 }
 
+// normalizeRef returns the fully qualified form of the image
+// reference ref, or ref itself if it cannot be parsed.
+func normalizeRef(ref string) string {
+	named, err := reference.ParseNormalizedNamed(ref)
+	if err != nil {
+		return ref
+	}
+	return named.String()
+}
+
 // pullImage pulls an image (by reference) to a Docker client using an authenticator.
 func pullImage(ctx context.Context, client docker.APIClient, authenticator ecrauth.Interface, ref string, log *log.Logger) error {
 	var options types.ImagePullOptions
-	if authenticator != nil {
-		if ok, err := authenticator.Authenticates(ctx, ref); ok && err == nil {
-			var auth types.AuthConfig
-			if err := authenticator.Authenticate(ctx, &auth); err != nil {
-				return err
-			}
-			b, err := json.Marshal(auth)
-			if err != nil {
-				return err
-			}
-			options.RegistryAuth = base64.URLEncoding.EncodeToString(b)
-		} else if err != nil {
+	if auth, ok, err := ecrauth.AuthConfig(ctx, authenticator, ref); err != nil {
+		return err
+	} else if ok {
+		b, err := json.Marshal(auth)
+		if err != nil {
 			return err
 		}
+		options.RegistryAuth = base64.URLEncoding.EncodeToString(b)
 	}
 	var (
 		resp     io.ReadCloser
@@ -113,7 +119,27 @@ func pullImage(ctx context.Context, client docker.APIClient, authenticator ecrau
 		}
 	}
 	defer resp.Close()
-	decoder := json.NewDecoder(resp)
+	return consumeMessages(resp)
+}
+
+// LoadImages loads the images in the tarball r, as produced by "docker
+// save", into the Docker daemon of client.
+func LoadImages(ctx context.Context, client docker.APIClient, r io.Reader) error {
+	resp, err := client.ImageLoad(ctx, r, true)
+	if err != nil {
+		return errors.E("load images", err)
+	}
+	defer resp.Body.Close()
+	if err := consumeMessages(resp.Body); err != nil {
+		return errors.E("load images", err)
+	}
+	return nil
+}
+
+// consumeMessages consumes the JSON messages sent by Docker in r,
+// returning the first error reported.
+func consumeMessages(r io.Reader) error {
+	decoder := json.NewDecoder(r)
 	// Docker sends status messages (e.g., "x% downloaded").
 	// We don't currently display these, but nonetheless have to
 	// consume them.
@@ -52,6 +52,24 @@ const defaultRegion = "us-west-2"
 
 var errDead = errors.New("executor is dead")
 
+// Container runtimes supported by the executor.
+const (
+	// RuntimeDocker runs execs in Docker containers managed by
+	// the local Docker daemon.
+	RuntimeDocker = "docker"
+	// RuntimePodman runs execs in rootless Podman containers, managed
+	// through Podman's Docker-compatible API. Containers run as root
+	// in the container's user namespace, which maps to the (unprivileged)
+	// user running Podman.
+	RuntimePodman = "podman"
+	// RuntimeApptainer runs execs in Apptainer (formerly Singularity)
+	// containers. It does not require a Docker daemon.
+	RuntimeApptainer = "apptainer"
+)
+
+// defaultDockerSocket is the default path of the Docker API socket.
+const defaultDockerSocket = "/var/run/docker.sock"
+
 // Executor is a small management layer on top of exec. It implements
 // reflow.Executor. Executor assumes that it has local access to the
 // file system (perhaps with a prefix).
@@ -74,11 +92,22 @@ type Executor struct {
 	Dir string
 	// Client is the Docker client used by this executor.
 	Client *docker.Client
+	// Runtime is the container runtime used to run execs: one of
+	// RuntimeDocker (the default, if empty), RuntimePodman or
+	// RuntimeApptainer.
+	Runtime string
+	// DockerSocket is the host path of the API socket of the Docker
+	// (or Podman) daemon. It is bound into the containers of execs
+	// which need Docker access. If empty, /var/run/docker.sock is used.
+	DockerSocket string
 	// Authenticator is used to pull images that are stored on Amazon's ECR
 	// service.
 	Authenticator ecrauth.Interface
 	// AWSCreds is an AWS credentials provider, used for "$aws" passthroughs.
 	AWSCreds *credentials.Credentials
+	// Secrets fetches the values of the secrets referenced by execs.
+	// If nil, execs which reference secrets fail.
+	Secrets Secrets
 	// Log is this executor's logger where operational status is printed.
 	Log *log.Logger
 
@@ -89,6 +118,13 @@ type Executor struct {
 
 	// HardMemLimit restricts an exec's memory limit to the exec's resource requirements
 	HardMemLimit bool
+	// StrictLimits additionally restricts an exec's CPU usage and block I/O
+	// share according to the exec's resource requirements.
+	StrictLimits bool
+	// Offline disables network access: images are never pulled, and
+	// execs whose images are not present locally fail. (Access to
+	// remote data is determined by Blob.)
+	Offline bool
 
 	Blob blob.Mux
 
@@ -210,6 +246,11 @@ func (e *Executor) Start() error {
 			dx := newDockerExec(id, e, reflow.ExecConfig{}, stdout, stderr)
 			dx.Manifest = m
 			x = dx
+		case execApptainer:
+			stdout, stderr := e.getRemoteStreams(id, true, true)
+			ax := newApptainerExec(id, e, reflow.ExecConfig{}, stdout, stderr)
+			ax.Manifest = m
+			x = ax
 		case execBlob:
 			_, stderr := e.getRemoteStreams(id, false, true)
 			blobx := &blobExec{
@@ -236,9 +277,43 @@ func (e *Executor) Start() error {
 // at the local Docker client.
 // TODO(marius): image pulling may be(?) better off as part of the executor interface
 func (e *Executor) ensureImage(ctx context.Context, ref string) error {
+	if e.Offline {
+		if ok, err := imageExists(ctx, e.Client, ref); err != nil {
+			return errors.E("ensure image", ref, err)
+		} else if !ok {
+			return errors.E("ensure image", ref, errors.NotAllowed,
+				errors.New("image is not present locally and cannot be pulled in offline mode; pull it before going offline"))
+		}
+		return nil
+	}
 	return ensureImage(ctx, e.Client, e.Authenticator, ref, e.Log)
 }
 
+// PrefetchImages begins pulling the given images in the background, so
+// that the execs which need them need not wait for the pulls. Execs
+// whose image is being prefetched wait for the prefetch instead of
+// pulling the image again. It implements reflow.ImagePrefetcher.
+func (e *Executor) PrefetchImages(ctx context.Context, images []string) error {
+	if e.Runtime == RuntimeApptainer {
+		return errors.E("prefetchimages", errors.NotSupported, errors.New("images are not prefetched by the apptainer runtime"))
+	}
+	if e.Offline {
+		return nil
+	}
+	for _, ref := range images {
+		ref := ref
+		go func() {
+			start := time.Now()
+			if err := e.ensureImage(e.ctx, ref); err != nil {
+				e.Log.Errorf("prefetch image %s: %v", ref, err)
+				return
+			}
+			e.Log.Debugf("prefetched image %s in %s", ref, time.Since(start))
+		}()
+	}
+	return nil
+}
+
 // execPath constructs a path for the exec with the given id.
 func (e *Executor) execPath(id digest.Digest, elem ...string) string {
 	elem = append([]string{e.Prefix, e.Dir, execsDir, id.Hex()}, elem...)
@@ -302,6 +377,11 @@ func (e *Executor) Put(ctx context.Context, id digest.Digest, cfg reflow.ExecCon
 
 	switch cfg.Type {
 	case intern, extern:
+		// Scoped credentials are used only by this process's transfer;
+		// they are not persisted with the exec's manifest. Execs
+		// restored after a restart use the executor's credentials.
+		creds := cfg.Credentials
+		cfg.Credentials = nil
 		u, err := url.Parse(cfg.URL)
 		if err != nil {
 			e.mu.Unlock()
@@ -321,11 +401,16 @@ func (e *Executor) Put(ctx context.Context, id digest.Digest, cfg reflow.ExecCon
 			}
 			blob.Config = cfg
 			blob.Init(e)
+			blob.Blob = blob.Blob.WithCredentials(creds)
 			x = blob
 		}
 	default:
 		stdout, stderr := e.getRemoteStreams(id, true, true)
-		x = newDockerExec(id, e, cfg, stdout, stderr)
+		if e.Runtime == RuntimeApptainer {
+			x = newApptainerExec(id, e, cfg, stdout, stderr)
+		} else {
+			x = newDockerExec(id, e, cfg, stdout, stderr)
+		}
 	}
 	e.execs[id] = x
 	e.mu.Unlock()
@@ -618,7 +703,13 @@ func (e *Executor) Kill(ctx context.Context) error {
 	for _, x := range e.execs {
 		x.Wait(ctx)
 	}
-	// Now try to collect any vestigial containers.
+	// Now try to collect any vestigial containers. The process
+	// groups of apptainer execs are killed when the executor's
+	// context is cancelled, and have exited by the time their
+	// execs complete.
+	if e.Runtime == RuntimeApptainer {
+		return e.FileRepository.Collect(ctx, nil)
+	}
 	cs, err := e.Client.ContainerList(ctx, types.ContainerListOptions{All: true})
 	if err != nil {
 		return errors.E("kill", e.ID, err)
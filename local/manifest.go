@@ -18,6 +18,7 @@ type execType int
 const (
 	execDocker execType = iota
 	execBlob
+	execApptainer
 )
 
 // Manifest stores the state of an exec. It is serialized to JSON and
@@ -36,4 +37,8 @@ type Manifest struct {
 	Resources reflow.Resources
 	Stats     stats
 	Gauges    reflow.Gauges
+
+	// PullStart and PullEnd bound the time the exec spent ensuring
+	// that its image was present (see reflow.ImagePullProfile).
+	PullStart, PullEnd time.Time
 }
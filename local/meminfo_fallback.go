@@ -0,0 +1,15 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+//go:build !linux
+// +build !linux
+
+package local
+
+import "errors"
+
+// hostMemory returns the total amount of memory of the host, in bytes.
+func hostMemory() (int64, error) {
+	return 0, errors.New("host memory is not available on this platform")
+}
@@ -11,6 +11,8 @@ import (
 	"math"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
 	"sync"
 	"time"
 
@@ -73,6 +75,13 @@ type Pool struct {
 	// Client is the Docker client. We assume that the Docker daemon
 	// runs on the same host from which the pool is managed.
 	Client *docker.Client
+	// Runtime is the container runtime used to run execs
+	// (see Executor.Runtime). When it is RuntimeApptainer, the
+	// Docker client is not used.
+	Runtime string
+	// DockerSocket is the host path of the API socket of the
+	// Docker (or Podman) daemon (see Executor.DockerSocket).
+	DockerSocket string
 	// Authenticator is used to authenticate ECR image pulls.
 	Authenticator interface {
 		Authenticates(ctx context.Context, image string) (bool, error)
@@ -83,8 +92,17 @@ type Pool struct {
 	AWSCreds *credentials.Credentials
 	// Session is the AWS session to use for AWS API calls.
 	Session *session.Session
+	// Secrets fetches the values of the secrets referenced by execs.
+	// If nil, secrets are fetched from AWS using Session, if set.
+	Secrets Secrets
 	// Blob is the blob store implementation used to fetch data from interns.
 	Blob blob.Mux
+	// Logs is the destination of exec logs. If empty, exec logs are
+	// shipped to CloudWatch Logs (if Session is set). Otherwise it is
+	// the URL of a blob store prefix (e.g., s3://bucket/logs) under
+	// which exec logs are written, in objects named by run, alloc and
+	// exec ID.
+	Logs string
 
 	// TaskDBPoolId is the identifier of this Pool in TaskDB
 	TaskDBPoolId reflow.StringDigest
@@ -94,10 +112,26 @@ type Pool struct {
 	Log *log.Logger
 
 	HardMemLimit bool
+	// StrictLimits restricts CPU usage and block I/O share of execs
+	// (see Executor.StrictLimits).
+	StrictLimits bool
+	// Offline disables network access by the pool's executors
+	// (see Executor.Offline).
+	Offline bool
 
 	// NodeOomDetector is an oom detector based node metrics
 	NodeOomDetector OomDetector
 
+	// logsBucket and logsPrefix are the bucket and prefix to which
+	// exec logs are written if Logs is set.
+	logsBucket blob.Bucket
+	logsPrefix string
+
+	// Features are additional resource labels (e.g., reflow.OnDemand)
+	// presented by the pool, one unit per CPU, alongside its CPU
+	// features.
+	Features []string
+
 	mu sync.Mutex
 }
 
@@ -148,23 +182,46 @@ func (p *Pool) Start(expectedUsableMemBytes int64) error {
 	defer p.mu.Unlock()
 	ctx := context.Background()
 	p.ResourcePool = pool.NewResourcePool(p, p.Log)
-	info, err := p.Client.Info(ctx)
-	if err != nil {
-		return err
+	if p.Logs != "" {
+		bucket, prefix, err := p.Blob.Bucket(ctx, p.Logs)
+		if err != nil {
+			return errors.E("logs", p.Logs, err)
+		}
+		p.logsBucket, p.logsPrefix = bucket, strings.Trim(prefix, "/")
+	}
+	if p.Secrets == nil && p.Session != nil {
+		p.Secrets = NewAWSSecrets(p.Session)
+	}
+	var (
+		memTotal int64
+		ncpu     int
+	)
+	if p.Runtime == RuntimeApptainer {
+		var err error
+		if memTotal, err = hostMemory(); err != nil {
+			return err
+		}
+		ncpu = runtime.NumCPU()
+	} else {
+		info, err := p.Client.Info(ctx)
+		if err != nil {
+			return err
+		}
+		memTotal, ncpu = info.MemTotal, info.NCPU
 	}
-	availableMem := math.Floor(float64(info.MemTotal) * (1 - reflowletProcessMemoryReservationPct))
+	availableMem := math.Floor(float64(memTotal) * (1 - reflowletProcessMemoryReservationPct))
 	if got, want := int64(availableMem), expectedUsableMemBytes; got < want {
 		return errors.Errorf("unviable pool, available mem (%d) < expected mem (%d)", got, want)
 	}
 	resources := reflow.Resources{
 		"mem": availableMem,
-		"cpu": float64(info.NCPU),
+		"cpu": float64(ncpu),
 	}
 	features, err := cpuFeatures()
 	if err != nil {
 		return err
 	}
-	for _, feature := range features {
+	for _, feature := range append(features, p.Features...) {
 		// Add one feature per CPU.
 		resources[feature] = resources["cpu"]
 	}
@@ -232,6 +289,9 @@ func (p *Pool) Start(expectedUsableMemBytes int64) error {
 		p.Log.Printf("orphaned alloc %s", id)
 	}
 	p.ResourcePool.Init(resources, allocs)
+	if p.logsBucket != nil {
+		return nil
+	}
 	return p.createCwLogGroup()
 }
 
@@ -371,17 +431,25 @@ func (p *Pool) newAlloc(id string, keepalive time.Duration) *alloc {
 	e := &Executor{
 		ID:              id,
 		Client:          p.Client,
+		Runtime:         p.Runtime,
+		DockerSocket:    p.DockerSocket,
 		Dir:             filepath.Join(p.Dir, allocsPath, id),
 		Prefix:          p.Prefix,
 		Authenticator:   p.Authenticator,
 		AWSCreds:        p.AWSCreds,
+		Secrets:         p.Secrets,
 		Blob:            p.Blob,
 		Log:             p.Log.Tee(nil, id+": "),
 		HardMemLimit:    p.HardMemLimit,
+		StrictLimits:    p.StrictLimits,
+		Offline:         p.Offline,
 		NodeOomDetector: p.NodeOomDetector,
 		SaveLogsToRepo:  isNoop,
 	}
-	if p.Session != nil {
+	switch {
+	case p.logsBucket != nil:
+		e.remoteStream = newBlobLogs(p.logsBucket, p.logsPrefix)
+	case p.Session != nil:
 		cwlclient := cloudwatchlogs.New(p.Session)
 		e.remoteStream = newCloudWatchLogs(cwlclient, remoteStreamCWLogGroupName)
 	}
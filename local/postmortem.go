@@ -0,0 +1,121 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package local
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/grailbio/reflow/errors"
+)
+
+const (
+	// postMortemLines is the number of trailing lines of a failed
+	// exec's standard error which are included in its post-mortem.
+	postMortemLines = 20
+	// postMortemMaxBytes bounds the number of trailing bytes of a
+	// failed exec's standard error which are read for its post-mortem.
+	postMortemMaxBytes = 16 << 10
+)
+
+// postMortem describes the proximate cause of an exec's failure so
+// that it can be reported alongside the exec's error, without having
+// to separately fetch the exec's logs.
+type postMortem struct {
+	// ExitCode is the exit code of the exec.
+	ExitCode int64
+	// Kernel is the kernel's (dmesg) OOM record for the exec, if any.
+	Kernel string
+	// Stderr holds the last lines of the exec's standard error.
+	Stderr []string
+}
+
+// String renders the post-mortem as an indented, multi-line string.
+func (p postMortem) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "post-mortem:\n\texit code: %d", p.ExitCode)
+	if p.Kernel != "" {
+		fmt.Fprintf(&b, "\n\tkernel: %s", p.Kernel)
+	}
+	if len(p.Stderr) > 0 {
+		fmt.Fprintf(&b, "\n\tstderr (last %d lines):", len(p.Stderr))
+		for _, line := range p.Stderr {
+			b.WriteString("\n\t\t")
+			b.WriteString(line)
+		}
+	}
+	return b.String()
+}
+
+// Annotate appends the post-mortem to the (innermost) message of the
+// exec error err, retaining its kind, so that it is propagated to the
+// flow's error and to the task's record in TaskDB.
+func (p postMortem) Annotate(err *errors.Error) {
+	for err.Err != nil {
+		next, ok := err.Err.(*errors.Error)
+		if !ok {
+			break
+		}
+		err = next
+	}
+	msg := p.String()
+	if err.Err != nil {
+		msg = err.Err.Error() + "\n" + msg
+	}
+	err.Err = errors.New(msg)
+}
+
+// tailLines returns (up to) the last n non-empty lines of the file at
+// path. Errors are ignored: post-mortems are best-effort.
+func tailLines(path string, n int) []string {
+	b, partial, err := readTail(path, postMortemMaxBytes)
+	if err != nil {
+		return nil
+	}
+	lines := strings.Split(strings.TrimRight(string(b), "\n"), "\n")
+	if partial && len(lines) > 0 {
+		// The first line is likely partial.
+		lines = lines[1:]
+	}
+	var tail []string
+	for i := len(lines) - 1; i >= 0 && len(tail) < n; i-- {
+		if line := strings.TrimRight(lines[i], "\r"); strings.TrimSpace(line) != "" {
+			tail = append(tail, line)
+		}
+	}
+	for i, j := 0, len(tail)-1; i < j; i, j = i+1, j-1 {
+		tail[i], tail[j] = tail[j], tail[i]
+	}
+	return tail
+}
+
+// readTail returns the last (up to) max bytes of the file at path,
+// and whether the file is longer, so that the returned tail likely
+// begins with a partial line.
+func readTail(path string, max int64) (tail []byte, partial bool, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, false, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, false, err
+	}
+	off := info.Size() - max
+	if off < 0 {
+		off = 0
+	}
+	if _, err = f.Seek(off, io.SeekStart); err != nil {
+		return nil, false, err
+	}
+	tail = make([]byte, info.Size()-off)
+	if _, err = io.ReadFull(f, tail); err != nil {
+		return nil, false, err
+	}
+	return tail, off > 0, nil
+}
@@ -0,0 +1,84 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package local
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/grailbio/reflow/errors"
+)
+
+func TestTailLines(t *testing.T) {
+	dir, err := ioutil.TempDir("", "postmortem")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "stderr")
+	if got := tailLines(path, 10); got != nil {
+		t.Errorf("got %v, want nil", got)
+	}
+	var lines []string
+	for i := 0; i < 5000; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	if err := ioutil.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := tailLines(path, 3), []string{"line 4997", "line 4998", "line 4999"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if err := ioutil.WriteFile(path, []byte("a\n\nb"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := tailLines(path, 10), []string{"a", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestLogTailsContain(t *testing.T) {
+	dir, err := ioutil.TempDir("", "postmortem")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	stdout, stderr := filepath.Join(dir, "stdout"), filepath.Join(dir, "stderr")
+	head := "write /tmp/x: " + enospcErrStr + "\n"
+	if err := ioutil.WriteFile(stdout, []byte(head+strings.Repeat("x", 1<<10)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	paths := []string{stdout, stderr}
+	// Only the tails of the logs are examined.
+	if logTailsContain(1<<10, paths, enospcErrStr) {
+		t.Error("message before the tail was found")
+	}
+	if !logTailsContain(2<<10, paths, enospcErrStr) {
+		t.Error("message in the tail was not found")
+	}
+}
+
+func TestPostMortemAnnotate(t *testing.T) {
+	err := errors.Recover(errors.E("exec", "id", errors.OOM, errors.New("killed by OOM killer")))
+	postMortem{ExitCode: 137, Kernel: "oom-kill: task bwa", Stderr: []string{"a", "b"}}.Annotate(err)
+	if got, want := err.Kind, errors.OOM; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	want := `exec id: OOM error: killed by OOM killer
+post-mortem:
+	exit code: 137
+	kernel: oom-kill: task bwa
+	stderr (last 2 lines):
+		a
+		b`
+	if got := err.Error(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package local
+
+import (
+	"math"
+	"strconv"
+)
+
+// Execs are given operating system scheduling settings according to
+// their priority (see reflow.ExecConfig.Priority), so that low
+// priority execs which share a host with high priority ones (as
+// arranged by the scheduler's packing) do not degrade them. Each
+// level of priority doubles (or halves) an exec's share of CPU and
+// block I/O relative to an exec of the default priority.
+const (
+	// maxPriorityLevels bounds the number of priority levels (in
+	// either direction) which are distinguished.
+	maxPriorityLevels = 8
+
+	// defaultCPUShares is the CPU share of an exec of default
+	// priority; it is also Docker's (and the kernel's) default.
+	defaultCPUShares = 1024
+	// defaultBlkioWeight is the block I/O weight of an exec of
+	// default priority, when block I/O weights are not otherwise
+	// restricted.
+	defaultBlkioWeight = 500
+
+	// defaultOOMScoreAdj is the OOM score adjustment of an exec of
+	// default priority. Execs are always more likely to be killed by
+	// the OOM killer than the reflowlet, so that we don't lose
+	// adjacent tasks unnecessarily and so that errors are more
+	// sensible to the user.
+	defaultOOMScoreAdj = 900
+	// oomScoreAdjStep is the difference in OOM score adjustments
+	// between adjacent priority levels.
+	oomScoreAdjStep = 100
+	// maxOOMScoreAdj is the largest OOM score adjustment.
+	maxOOMScoreAdj = 1000
+
+	// defaultIOLevel is the default best-effort I/O scheduling level;
+	// levels range from 0 (highest) to 7 (lowest).
+	defaultIOLevel = 4
+	// maxNiceness is the largest niceness accepted by the kernel.
+	maxNiceness = 19
+)
+
+// priorityLevel clamps the provided priority to the range of
+// distinguished levels.
+func priorityLevel(priority int) int {
+	switch {
+	case priority < -maxPriorityLevels:
+		return -maxPriorityLevels
+	case priority > maxPriorityLevels:
+		return maxPriorityLevels
+	}
+	return priority
+}
+
+// priorityScale returns the factor by which the CPU and block I/O
+// shares of an exec with the provided priority are scaled.
+func priorityScale(priority int) float64 {
+	return math.Exp2(float64(-priorityLevel(priority)))
+}
+
+// cpuShares returns the CPU shares of an exec with the provided
+// priority.
+func cpuShares(priority int) int64 {
+	return int64(defaultCPUShares * priorityScale(priority))
+}
+
+// priorityBlkioWeight scales the block I/O weight w according to the
+// provided priority, within the bounds accepted by Docker.
+func priorityBlkioWeight(w uint16, priority int) uint16 {
+	scaled := math.Round(float64(w) * priorityScale(priority))
+	switch {
+	case scaled < minBlkioWeight:
+		scaled = minBlkioWeight
+	case scaled > maxBlkioWeight:
+		scaled = maxBlkioWeight
+	}
+	return uint16(scaled)
+}
+
+// oomScoreAdj returns the OOM score adjustment of an exec with the
+// provided priority: lower priority execs are killed first.
+func oomScoreAdj(priority int) int {
+	adj := defaultOOMScoreAdj + oomScoreAdjStep*priorityLevel(priority)
+	switch {
+	case adj < oomScoreAdjStep:
+		adj = oomScoreAdjStep
+	case adj > maxOOMScoreAdj:
+		adj = maxOOMScoreAdj
+	}
+	return adj
+}
+
+// priorityCommand returns the command (name and arguments) which
+// runs the provided command with the CPU and I/O scheduling priority
+// of an exec with the provided priority, using nice(1) and ionice(1).
+// Since raising the CPU scheduling priority of a process requires
+// privileges, execs of higher than default priority are instead given
+// only a higher I/O scheduling priority.
+func priorityCommand(priority int, name string, args ...string) (string, []string) {
+	if priority == 0 {
+		return name, args
+	}
+	level := defaultIOLevel + priorityLevel(priority)
+	switch {
+	case level < 0:
+		level = 0
+	case level > 7:
+		level = 7
+	}
+	cmd := []string{"ionice", "-c", "2", "-n", strconv.Itoa(level)}
+	if priority > 0 {
+		nice := 2 * priorityLevel(priority)
+		if nice > maxNiceness {
+			nice = maxNiceness
+		}
+		cmd = append([]string{"nice", "-n", strconv.Itoa(nice)}, cmd...)
+	}
+	cmd = append(cmd, name)
+	return cmd[0], append(cmd[1:], args...)
+}
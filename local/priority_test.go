@@ -0,0 +1,53 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package local
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPrioritySettings(t *testing.T) {
+	for _, tc := range []struct {
+		priority int
+		shares   int64
+		weight   uint16
+		oomAdj   int
+	}{
+		{0, 1024, 500, 900},
+		{1, 512, 250, 1000},
+		{2, 256, 125, 1000},
+		{-1, 2048, 1000, 800},
+		{-20, 262144, 1000, 100},
+		{20, 4, 10, 1000},
+	} {
+		if got, want := cpuShares(tc.priority), tc.shares; got != want {
+			t.Errorf("cpuShares(%d): got %v, want %v", tc.priority, got, want)
+		}
+		if got, want := priorityBlkioWeight(defaultBlkioWeight, tc.priority), tc.weight; got != want {
+			t.Errorf("priorityBlkioWeight(%d): got %v, want %v", tc.priority, got, want)
+		}
+		if got, want := oomScoreAdj(tc.priority), tc.oomAdj; got != want {
+			t.Errorf("oomScoreAdj(%d): got %v, want %v", tc.priority, got, want)
+		}
+	}
+}
+
+func TestPriorityCommand(t *testing.T) {
+	for _, tc := range []struct {
+		priority int
+		want     []string
+	}{
+		{0, []string{"apptainer", "exec"}},
+		{1, []string{"nice", "-n", "2", "ionice", "-c", "2", "-n", "5", "apptainer", "exec"}},
+		{-2, []string{"ionice", "-c", "2", "-n", "2", "apptainer", "exec"}},
+		{20, []string{"nice", "-n", "16", "ionice", "-c", "2", "-n", "7", "apptainer", "exec"}},
+	} {
+		name, args := priorityCommand(tc.priority, "apptainer", "exec")
+		if got, want := append([]string{name}, args...), tc.want; !reflect.DeepEqual(got, want) {
+			t.Errorf("priorityCommand(%d): got %v, want %v", tc.priority, got, want)
+		}
+	}
+}
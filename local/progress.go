@@ -0,0 +1,77 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package local
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/grailbio/reflow"
+	"github.com/grailbio/reflow/errors"
+)
+
+// progressPath returns the local path of the progress file of an exec
+// whose sandbox /tmp is the provided directory.
+func progressPath(tmp string) string {
+	return filepath.Join(tmp, strings.TrimPrefix(reflow.ExecProgressPath, "/tmp/"))
+}
+
+// readProgress reads the progress, in percent, reported by an exec in
+// the file at the provided path (see reflow.ExecProgressPath). It
+// returns false if no progress has been reported.
+func readProgress(path string) (float64, bool, error) {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	// Use the last value written, in case the exec appends to the file.
+	lines := strings.Fields(string(b))
+	if len(lines) == 0 {
+		return 0, false, nil
+	}
+	p, err := parseProgress(lines[len(lines)-1])
+	if err != nil {
+		return 0, false, err
+	}
+	return p, true, nil
+}
+
+// parseProgress parses a progress report, either a percentage (e.g.,
+// "42" or "42%") or a fraction (e.g., "21/50"). The returned progress
+// is clamped to [0, 100].
+func parseProgress(s string) (float64, error) {
+	var (
+		p   float64
+		err error
+	)
+	if i := strings.Index(s, "/"); i >= 0 {
+		var n, d float64
+		if n, err = strconv.ParseFloat(s[:i], 64); err == nil {
+			d, err = strconv.ParseFloat(s[i+1:], 64)
+		}
+		if err == nil && d <= 0 {
+			err = errors.New("non-positive denominator")
+		}
+		p = 100 * n / d
+	} else {
+		p, err = strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+	}
+	if err != nil {
+		return 0, errors.E("parse progress", s, errors.Invalid, err)
+	}
+	switch {
+	case p < 0:
+		p = 0
+	case p > 100:
+		p = 100
+	}
+	return p, nil
+}
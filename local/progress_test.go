@@ -0,0 +1,58 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package local
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseProgress(t *testing.T) {
+	for _, tc := range []struct {
+		s    string
+		want float64
+		ok   bool
+	}{
+		{"42", 42, true},
+		{"42.5%", 42.5, true},
+		{"21/50", 42, true},
+		{"150", 100, true},
+		{"-3", 0, true},
+		{"1/0", 0, false},
+		{"half", 0, false},
+	} {
+		got, err := parseProgress(tc.s)
+		if tc.ok != (err == nil) {
+			t.Errorf("%q: unexpected error %v", tc.s, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("%q: got %v, want %v", tc.s, got, tc.want)
+		}
+	}
+}
+
+func TestReadProgress(t *testing.T) {
+	dir, err := ioutil.TempDir("", "progress")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := progressPath(dir)
+	if got, want := path, filepath.Join(dir, ".reflow_progress"); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if _, ok, err := readProgress(path); ok || err != nil {
+		t.Errorf("got %v, %v, want no progress", ok, err)
+	}
+	if err := ioutil.WriteFile(path, []byte("10\n20\n30\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if p, ok, err := readProgress(path); !ok || err != nil || p != 30 {
+		t.Errorf("got %v, %v, %v, want 30", p, ok, err)
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package local
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/secretsmanager/secretsmanageriface"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+	"github.com/grailbio/reflow"
+	"github.com/grailbio/reflow/errors"
+)
+
+// Secrets fetches the values of the secrets referenced by execs
+// (see reflow.ExecConfig.Secrets).
+type Secrets interface {
+	// Secret returns the value of the referenced secret.
+	Secret(ctx context.Context, ref reflow.SecretRef) (string, error)
+}
+
+// awsSecrets fetches secrets from AWS Secrets Manager and the AWS
+// Systems Manager Parameter Store.
+type awsSecrets struct {
+	secretsManager secretsmanageriface.SecretsManagerAPI
+	ssm            ssmiface.SSMAPI
+}
+
+// NewAWSSecrets returns a Secrets which fetches secrets from AWS
+// Secrets Manager and the AWS Systems Manager Parameter Store using
+// the provided session.
+func NewAWSSecrets(sess *session.Session) Secrets {
+	return &awsSecrets{secretsmanager.New(sess), ssm.New(sess)}
+}
+
+// Secret implements Secrets.
+func (s *awsSecrets) Secret(ctx context.Context, ref reflow.SecretRef) (string, error) {
+	switch ref.Service {
+	case reflow.SecretsManager:
+		out, err := s.secretsManager.GetSecretValueWithContext(ctx, &secretsmanager.GetSecretValueInput{
+			SecretId: aws.String(ref.Name),
+		})
+		if err != nil {
+			return "", errors.E("secret", ref.String(), secretKind(err), err)
+		}
+		value := aws.StringValue(out.SecretString)
+		if ref.Key == "" {
+			return value, nil
+		}
+		var fields map[string]interface{}
+		if err := json.Unmarshal([]byte(value), &fields); err != nil {
+			return "", errors.E("secret", ref.String(), errors.Invalid, errors.New("secret value is not a JSON object"))
+		}
+		v, ok := fields[ref.Key]
+		if !ok {
+			return "", errors.E("secret", ref.String(), errors.NotExist, errors.Errorf("secret has no key %s", ref.Key))
+		}
+		if str, ok := v.(string); ok {
+			return str, nil
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return "", errors.E("secret", ref.String(), err)
+		}
+		return string(b), nil
+	case reflow.SSM:
+		out, err := s.ssm.GetParameterWithContext(ctx, &ssm.GetParameterInput{
+			Name:           aws.String(ref.Name),
+			WithDecryption: aws.Bool(true),
+		})
+		if err != nil {
+			return "", errors.E("secret", ref.String(), secretKind(err), err)
+		}
+		return aws.StringValue(out.Parameter.Value), nil
+	default:
+		return "", errors.E("secret", ref.String(), errors.NotSupported)
+	}
+}
+
+// secretKind returns the kind of errors returned by the AWS secret
+// services. AWS errors with unknown codes are presumed to be
+// transient.
+func secretKind(err error) errors.Kind {
+	aerr, ok := err.(awserr.Error)
+	if !ok {
+		return errors.Other
+	}
+	switch aerr.Code() {
+	case secretsmanager.ErrCodeResourceNotFoundException, ssm.ErrCodeParameterNotFound, ssm.ErrCodeParameterVersionNotFound:
+		return errors.NotExist
+	case "AccessDeniedException", "UnrecognizedClientException":
+		return errors.NotAllowed
+	case secretsmanager.ErrCodeInvalidParameterException, secretsmanager.ErrCodeInvalidRequestException, secretsmanager.ErrCodeDecryptionFailure, ssm.ErrCodeInvalidKeyId:
+		return errors.Invalid
+	default:
+		return errors.Temporary
+	}
+}
+
+// secretEnv fetches the secrets of the exec config cfg, and returns
+// them as "name=value" environment variables, ordered by name.
+func secretEnv(ctx context.Context, secrets Secrets, cfg reflow.ExecConfig) ([]string, error) {
+	if len(cfg.Secrets) == 0 {
+		return nil, nil
+	}
+	if secrets == nil {
+		return nil, errors.E("secrets", errors.NotSupported, errors.New("executor cannot fetch secrets"))
+	}
+	env := make([]string, 0, len(cfg.Secrets))
+	for name, s := range cfg.Secrets {
+		ref, err := reflow.ParseSecretRef(s)
+		if err != nil {
+			return nil, errors.E("secret", name, err)
+		}
+		value, err := secrets.Secret(ctx, ref)
+		if err != nil {
+			return nil, errors.E("secret", name, err)
+		}
+		env = append(env, name+"="+value)
+	}
+	sort.Strings(env)
+	return env, nil
+}
+
+// userEnv returns the environment variables defined by the exec
+// config cfg (see reflow.ExecConfig.EnvVars) as "name=value"
+// strings, ordered by name.
+func userEnv(cfg reflow.ExecConfig) []string {
+	env := make([]string, 0, len(cfg.EnvVars))
+	for name, value := range cfg.EnvVars {
+		env = append(env, name+"="+value)
+	}
+	sort.Strings(env)
+	return env
+}
+
+// redactEnv replaces, in place, the values of the variables in env
+// which are defined by the exec config's secrets.
+func redactEnv(env []string, cfg reflow.ExecConfig) {
+	if len(cfg.Secrets) == 0 {
+		return
+	}
+	for i, kv := range env {
+		for name := range cfg.Secrets {
+			if strings.HasPrefix(kv, name+"=") {
+				env[i] = name + "=<redacted>"
+			}
+		}
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package local
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/secretsmanager/secretsmanageriface"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+	"github.com/grailbio/reflow"
+	"github.com/grailbio/reflow/errors"
+)
+
+type mockSecretsManager struct {
+	secretsmanageriface.SecretsManagerAPI
+	secrets map[string]string
+}
+
+func (m *mockSecretsManager) GetSecretValueWithContext(ctx aws.Context, input *secretsmanager.GetSecretValueInput, opts ...request.Option) (*secretsmanager.GetSecretValueOutput, error) {
+	v, ok := m.secrets[aws.StringValue(input.SecretId)]
+	if !ok {
+		return nil, awserr.New(secretsmanager.ErrCodeResourceNotFoundException, "not found", nil)
+	}
+	return &secretsmanager.GetSecretValueOutput{SecretString: aws.String(v)}, nil
+}
+
+type mockSSM struct {
+	ssmiface.SSMAPI
+	params map[string]string
+}
+
+func (m *mockSSM) GetParameterWithContext(ctx aws.Context, input *ssm.GetParameterInput, opts ...request.Option) (*ssm.GetParameterOutput, error) {
+	if !aws.BoolValue(input.WithDecryption) {
+		return nil, awserr.New("ValidationException", "parameter must be decrypted", nil)
+	}
+	v, ok := m.params[aws.StringValue(input.Name)]
+	if !ok {
+		return nil, awserr.New(ssm.ErrCodeParameterNotFound, "not found", nil)
+	}
+	return &ssm.GetParameterOutput{Parameter: &ssm.Parameter{Value: aws.String(v)}}, nil
+}
+
+func TestSecretEnv(t *testing.T) {
+	secrets := &awsSecrets{
+		secretsManager: &mockSecretsManager{secrets: map[string]string{
+			"db":    `{"user": "reflow", "port": 5432}`,
+			"token": "abc",
+		}},
+		ssm: &mockSSM{params: map[string]string{"/prod/key": "xyz"}},
+	}
+	cfg := reflow.ExecConfig{Secrets: map[string]string{
+		"DB_USER": "secretsmanager:db#user",
+		"DB_PORT": "secretsmanager:db#port",
+		"TOKEN":   "secretsmanager:token",
+		"KEY":     "ssm:/prod/key",
+	}}
+	ctx := context.Background()
+	env, err := secretEnv(ctx, secrets, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := env, []string{"DB_PORT=5432", "DB_USER=reflow", "KEY=xyz", "TOKEN=abc"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	redactEnv(env, cfg)
+	for _, kv := range env {
+		if got, want := kv[len(kv)-len("<redacted>"):], "<redacted>"; got != want {
+			t.Errorf("%s: not redacted", kv)
+		}
+	}
+
+	for _, ref := range []string{"secretsmanager:missing", "secretsmanager:db#password", "ssm:/missing"} {
+		_, err := secretEnv(ctx, secrets, reflow.ExecConfig{Secrets: map[string]string{"X": ref}})
+		if !errors.Is(errors.NotExist, err) {
+			t.Errorf("%s: got %v, want NotExist", ref, err)
+		}
+	}
+	if _, err := secretEnv(ctx, nil, cfg); !errors.Is(errors.NotSupported, err) {
+		t.Errorf("got %v, want NotSupported", err)
+	}
+	if env, err := secretEnv(ctx, nil, reflow.ExecConfig{}); err != nil || env != nil {
+		t.Errorf("got %v, %v, want nil, nil", env, err)
+	}
+}
+
+func TestUserEnv(t *testing.T) {
+	cfg := reflow.ExecConfig{EnvVars: map[string]string{"B": "2", "A": "1=1"}}
+	if got, want := userEnv(cfg), []string{"A=1=1", "B=2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
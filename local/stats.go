@@ -94,6 +94,20 @@ func (s stats) Profile() reflow.Profile {
 	return prof
 }
 
+// addImagePull adds to the profile prof the image pull which took
+// place between start and end, if any (see reflow.ImagePullProfile).
+func addImagePull(prof reflow.Profile, start, end time.Time) {
+	if start.IsZero() || end.Before(start) {
+		return
+	}
+	d := end.Sub(start).Seconds()
+	prof[reflow.ImagePullProfile] = struct {
+		Max, Mean, Var float64
+		N              int64
+		First, Last    time.Time
+	}{Max: d, Mean: d, N: 1, First: start, Last: end}
+}
+
 func du(path string) (uint64, error) {
 	var (
 		w walker.Walker
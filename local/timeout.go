@@ -0,0 +1,44 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package local
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// execTimer enforces an exec's (wallclock) timeout by killing the exec
+// once the timeout, measured from the exec's start, has elapsed.
+// A nil *execTimer never expires.
+type execTimer struct {
+	timer   *time.Timer
+	expired int32
+}
+
+// startExecTimer starts a timer which calls kill once timeout has
+// elapsed since start. If the deadline has already passed (e.g., the
+// executor was restarted and reattached to a long-running exec), kill
+// is called immediately. startExecTimer returns nil if timeout is zero.
+func startExecTimer(start time.Time, timeout time.Duration, kill func()) *execTimer {
+	if timeout <= 0 {
+		return nil
+	}
+	t := new(execTimer)
+	t.timer = time.AfterFunc(time.Until(start.Add(timeout)), func() {
+		atomic.StoreInt32(&t.expired, 1)
+		kill()
+	})
+	return t
+}
+
+// Stop stops the timer and reports whether it had expired, i.e.,
+// whether the exec was killed for exceeding its timeout.
+func (t *execTimer) Stop() bool {
+	if t == nil {
+		return false
+	}
+	t.timer.Stop()
+	return atomic.LoadInt32(&t.expired) == 1
+}
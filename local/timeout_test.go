@@ -0,0 +1,38 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package local
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExecTimer(t *testing.T) {
+	if timer := startExecTimer(time.Now(), 0, func() { t.Error("unexpected kill") }); timer != nil {
+		t.Errorf("got %v, want nil", timer)
+	}
+	var timer *execTimer
+	if timer.Stop() {
+		t.Error("nil timer expired")
+	}
+
+	killed := make(chan bool, 1)
+	timer = startExecTimer(time.Now(), time.Hour, func() { killed <- true })
+	if timer.Stop() {
+		t.Error("timer expired early")
+	}
+
+	// A deadline which has passed (e.g., on reattaching to an exec)
+	// kills the exec immediately.
+	timer = startExecTimer(time.Now().Add(-2*time.Hour), time.Hour, func() { killed <- true })
+	select {
+	case <-killed:
+	case <-time.After(10 * time.Second):
+		t.Fatal("exec was not killed")
+	}
+	if !timer.Stop() {
+		t.Error("timer did not expire")
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"math"
 	"net/http"
 	"os"
+	"path/filepath"
 	"time"
 
 	"docker.io/go-docker"
@@ -21,9 +22,12 @@ import (
 	"github.com/grailbio/infra/tls"
 	"github.com/grailbio/reflow"
 	"github.com/grailbio/reflow/blob"
+	"github.com/grailbio/reflow/blob/fileblob"
+	"github.com/grailbio/reflow/blob/httpblob"
 	"github.com/grailbio/reflow/blob/s3blob"
 	"github.com/grailbio/reflow/ec2authenticator"
 	"github.com/grailbio/reflow/errors"
+	infra2 "github.com/grailbio/reflow/infra"
 	"github.com/grailbio/reflow/local"
 	"github.com/grailbio/reflow/log"
 	"github.com/grailbio/reflow/pool"
@@ -46,12 +50,13 @@ type Cluster struct {
 	// Session is the aws session.
 	Session *session.Session
 
-	total     reflow.Resources
-	dir       string
+	total   reflow.Resources
+	dir     string
+	offline bool
 }
 
 // Init implements infra.Provider
-func (c *Cluster) Init(tls tls.Certs, session *session.Session, logger *log.Logger, creds *credentials.Credentials, tdb taskdb.TaskDB) error {
+func (c *Cluster) Init(tls tls.Certs, session *session.Session, logger *log.Logger, creds *credentials.Credentials, tdb taskdb.TaskDB, s3opts *infra2.S3BucketOptions, fileopts *infra2.FileBlob) error {
 	var err error
 	if c.Client, c.total, err = dockerClient(); err != nil {
 		return err
@@ -66,18 +71,33 @@ func (c *Cluster) Init(tls tls.Certs, session *session.Session, logger *log.Logg
 	}
 	c.Session = session
 	c.Log = logger.Tee(nil, "localcluster: ")
+	var mux blob.Mux
+	if c.offline {
+		mux = infra2.OfflineBlobMux(fileopts.Store())
+		// Offline executors do not stream their logs to CloudWatch.
+		session = nil
+	} else {
+		// Since local executors run in this process, checkpoint large
+		// transfers so that they are resumed if the process is restarted.
+		store := s3blob.New(session)
+		store.Options = *s3opts
+		store.CheckpointDir = filepath.Join(c.dir, "checkpoints")
+		mux = blob.Mux{"s3": store, fileblob.Scheme: fileopts.Store()}
+		httpblob.Register(mux, nil)
+	}
 	pool := &local.Pool{
 		Dir:           c.dir,
 		Client:        c.Client,
 		Authenticator: ec2authenticator.New(session),
 		AWSCreds:      creds,
 		Session:       session,
-		Blob: blob.Mux{
-			"s3": s3blob.New(session),
-		},
-		Log:          logger.Tee(nil, "executor: "),
-		HardMemLimit: false,
-		TaskDB:       tdb,
+		Blob:          mux,
+		Log:           logger.Tee(nil, "executor: "),
+		HardMemLimit:  false,
+		TaskDB:        tdb,
+		Offline:       c.offline,
+		// The local machine is never preempted.
+		Features: []string{reflow.OnDemand},
 	}
 	if err = pool.Start(0); err != nil {
 		return err
@@ -89,6 +109,7 @@ func (c *Cluster) Init(tls tls.Certs, session *session.Session, logger *log.Logg
 // Flags implements infra.Provider
 func (c *Cluster) Flags(flags *flag.FlagSet) {
 	flags.StringVar(&c.dir, "dir", "/tmp/flow", "directory to store local state")
+	flags.BoolVar(&c.offline, "offline", false, "run without network access: only local images and file:// data may be used")
 }
 
 // Help implements infra.Provider
@@ -157,5 +178,6 @@ func dockerClient() (*docker.Client, reflow.Resources, error) {
 		"cpu":  float64(info.NCPU),
 		"disk": 1e13, // Assume 10TB.
 	}
+	resources[reflow.OnDemand] = resources["cpu"]
 	return client, resources, nil
 }
@@ -37,6 +37,8 @@ func getTestReflowConfig() infra.Config {
 		infra2.TaskDB:     new(taskdb.TaskDB),
 		infra2.TLS:        new(tls.Certs),
 		infra2.Username:   new(infra2.User),
+		infra2.S3Buckets:  new(infra2.S3BucketOptions),
+		infra2.FileBlob:   new(infra2.FileBlob),
 	}
 	keys := getTestReflowConfigKeys()
 	cfg, err := schema.Make(keys)
@@ -60,6 +62,8 @@ func getTestReflowConfigKeys() infra.Keys {
 		infra2.TaskDB:     "noptaskdb",
 		infra2.TLS:        "tls,file=/tmp/ca.reflow",
 		infra2.Username:   "user",
+		infra2.S3Buckets:  "s3bucketoptions",
+		infra2.FileBlob:   "fileblob",
 	}
 }
 
@@ -0,0 +1,127 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package log
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Keys of fields which identify the entities to which log messages
+// pertain. JSON records include them as top-level attributes so that
+// they can be correlated with TaskDB.
+const (
+	// RunIDKey is the key of the field identifying a run.
+	RunIDKey = "runid"
+	// TaskIDKey is the key of the field identifying a task.
+	TaskIDKey = "taskid"
+	// AllocIDKey is the key of the field identifying an alloc.
+	AllocIDKey = "allocid"
+)
+
+// A Field is a key-value pair attached to log messages (see Logger.With).
+type Field struct {
+	Key, Value string
+}
+
+// A Record is a structured log message.
+type Record struct {
+	// Level is the level at which the message was published.
+	Level Level
+	// Module is the (prefix) of the logger through which the message
+	// was published, e.g., "scheduler".
+	Module string
+	// Message is the log message.
+	Message string
+	// Fields are the fields attached to the message, outermost first.
+	Fields []Field
+}
+
+// A RecordOutputter is an Outputter which receives log messages as
+// structured records. Loggers publish messages to RecordOutputters
+// with OutputRecord in place of Output.
+type RecordOutputter interface {
+	Outputter
+	OutputRecord(calldepth int, r Record) error
+}
+
+// JSONOutputter is a RecordOutputter which writes log messages as
+// JSON objects, one per line, so that they may be ingested by log
+// aggregators. Each object contains the attributes "time", "level",
+// "module", "msg", the identifiers "runid", "taskid" and "allocid"
+// (if present), and any other fields in "fields". Messages output
+// directly (e.g., by Fatal) are written at error level.
+type JSONOutputter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewJSONOutputter returns a new JSONOutputter which writes to w.
+func NewJSONOutputter(w io.Writer) *JSONOutputter {
+	return &JSONOutputter{w: w}
+}
+
+// SetOutput sets the writer to which the outputter writes.
+func (o *JSONOutputter) SetOutput(w io.Writer) {
+	o.mu.Lock()
+	o.w = w
+	o.mu.Unlock()
+}
+
+type jsonRecord struct {
+	Time    time.Time         `json:"time"`
+	Level   string            `json:"level"`
+	Module  string            `json:"module,omitempty"`
+	RunID   string            `json:"runid,omitempty"`
+	TaskID  string            `json:"taskid,omitempty"`
+	AllocID string            `json:"allocid,omitempty"`
+	Message string            `json:"msg"`
+	Fields  map[string]string `json:"fields,omitempty"`
+}
+
+// Output implements Outputter.
+func (o *JSONOutputter) Output(calldepth int, s string) error {
+	return o.OutputRecord(calldepth+1, Record{Level: ErrorLevel, Message: s})
+}
+
+// OutputRecord implements RecordOutputter.
+func (o *JSONOutputter) OutputRecord(calldepth int, r Record) error {
+	rec := jsonRecord{
+		Time:    time.Now().UTC(),
+		Level:   strings.ToLower(r.Level.String()),
+		Module:  r.Module,
+		Message: strings.TrimSuffix(r.Message, "\n"),
+	}
+	for _, f := range r.Fields {
+		switch f.Key {
+		case RunIDKey:
+			rec.RunID = f.Value
+		case TaskIDKey:
+			rec.TaskID = f.Value
+		case AllocIDKey:
+			rec.AllocID = f.Value
+		default:
+			if rec.Fields == nil {
+				rec.Fields = make(map[string]string)
+			}
+			rec.Fields[f.Key] = f.Value
+		}
+	}
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.w == nil {
+		return nil
+	}
+	_, err = o.w.Write(b)
+	return err
+}
@@ -98,6 +98,9 @@ type Logger struct {
 	Parent   *Logger
 	prefix   string
 	addLevel bool
+	// fields are attached to every message published through this
+	// Logger (see With).
+	fields []Field
 }
 
 // New creates a new Logger that publishes messsages at or below the
@@ -164,26 +167,53 @@ func (l *Logger) At(level Level) bool {
 }
 
 func (l *Logger) print(calldepth int, level Level, prefix string, v ...interface{}) {
-	if l == nil {
+	if !l.publishes(level) {
 		return
 	}
-	if l.Outputter != nil && level <= l.Level {
-		_ = l.Output(calldepth+1, l.getPrefix(level, prefix)+fmt.Sprint(v...))
-	}
-	if l.Parent != nil {
-		l.Parent.print(calldepth+1, level, l.prefix+prefix, v...)
-	}
+	l.output(calldepth+1, level, prefix, nil, fmt.Sprint(v...))
 }
 
 func (l *Logger) printf(calldepth int, level Level, prefix, format string, args ...interface{}) {
-	if l == nil {
+	if !l.publishes(level) {
 		return
 	}
+	l.output(calldepth+1, level, prefix, nil, fmt.Sprintf(format, args...))
+}
+
+// publishes tells whether a message at the provided level is
+// published by the logger or any of its ancestors, so that messages
+// are formatted only when they are needed.
+func (l *Logger) publishes(level Level) bool {
+	for ; l != nil; l = l.Parent {
+		if l.Outputter != nil && level <= l.Level {
+			return true
+		}
+	}
+	return false
+}
+
+// output publishes the message msg to the logger and its ancestors.
+// Structured outputters (see RecordOutputter) receive the message
+// as a record, with the fields of the loggers through which it was
+// published; others receive it as a prefixed string.
+func (l *Logger) output(calldepth int, level Level, prefix string, fields []Field, msg string) {
+	if len(l.fields) > 0 {
+		fields = append(append([]Field(nil), l.fields...), fields...)
+	}
 	if l.Outputter != nil && level <= l.Level {
-		_ = l.Output(calldepth+1, l.getPrefix(level, prefix)+fmt.Sprintf(format, args...))
+		if out, ok := l.Outputter.(RecordOutputter); ok {
+			_ = out.OutputRecord(calldepth+1, Record{
+				Level:   level,
+				Module:  strings.TrimRight(prefix, ": "),
+				Message: msg,
+				Fields:  fields,
+			})
+		} else {
+			_ = l.Output(calldepth+1, l.getPrefix(level, prefix)+msg)
+		}
 	}
 	if l.Parent != nil {
-		l.Parent.printf(calldepth+1, level, l.prefix+prefix, format, args...)
+		l.Parent.output(calldepth+1, level, l.prefix+prefix, fields, msg)
 	}
 }
 
@@ -210,6 +240,22 @@ func (l *Logger) Tee(out Outputter, prefix string) *Logger {
 	}
 }
 
+// With constructs a new logger which publishes messages to the
+// receiver, attaching to each the field with the provided key and
+// value. Fields are included in records published to structured
+// outputters (see RecordOutputter); they are omitted from plain
+// messages.
+func (l *Logger) With(key, value string) *Logger {
+	if l == nil {
+		return nil
+	}
+	return &Logger{
+		Level:  l.Level,
+		Parent: l,
+		fields: []Field{{key, value}},
+	}
+}
+
 // Std is the standard global logger.
 // It is used by the package level logging functions.
 var Std = New(log.New(os.Stderr, "", log.LstdFlags), InfoLevel)
@@ -5,6 +5,8 @@
 package log_test
 
 import (
+	"bytes"
+	"encoding/json"
 	"reflect"
 	"testing"
 
@@ -99,3 +101,48 @@ func TestMultiOutputter(t *testing.T) {
 		t.Errorf("got %v, want %v", got, want)
 	}
 }
+
+func TestJSONOutputter(t *testing.T) {
+	var b bytes.Buffer
+	l := log.New(log.NewJSONOutputter(&b), log.DebugLevel)
+	run := l.Tee(nil, "scheduler: ").With(log.RunIDKey, "r1")
+	task := run.With(log.TaskIDKey, "t1").With("attempt", "2")
+	l.Printf("hello")
+	task.Errorf("task %s failed", "t1")
+	run.Debug("debug")
+
+	type record struct {
+		Level   string            `json:"level"`
+		Module  string            `json:"module"`
+		RunID   string            `json:"runid"`
+		TaskID  string            `json:"taskid"`
+		Message string            `json:"msg"`
+		Fields  map[string]string `json:"fields"`
+	}
+	var got []record
+	dec := json.NewDecoder(&b)
+	for dec.More() {
+		var r record
+		if err := dec.Decode(&r); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, r)
+	}
+	want := []record{
+		{Level: "info", Message: "hello"},
+		{Level: "error", Module: "scheduler", RunID: "r1", TaskID: "t1", Message: "task t1 failed", Fields: map[string]string{"attempt": "2"}},
+		{Level: "debug", Module: "scheduler", RunID: "r1", Message: "debug"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestWithPlain(t *testing.T) {
+	var b outputBuffer
+	l := log.New(&b, log.InfoLevel)
+	l.Tee(nil, "prefix: ").With(log.RunIDKey, "r1").Printf("hello")
+	if got, want := b.messages, []string{"prefix: hello"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
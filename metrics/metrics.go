@@ -24,6 +24,13 @@ var (
 		"allocs_started_size": {
 			Help: "Size of started allocs.",
 		},
+		"direct_transfer_bytes": {
+			Help: "Bytes of files copied by direct transfers.",
+		},
+		"taskdb_errors_count": {
+			Help:   "Count of TaskDB operation errors.",
+			Labels: []string{"operation"},
+		},
 		"tasks_completed_count": {
 			Help: "Count of completed tasks.",
 		},
@@ -44,6 +51,16 @@ var (
 		},
 	}
 	Gauges = map[string]gaugeOpts{
+		"allocs_utilization": {
+			Help:   "Fraction of alloc resources assigned to tasks.",
+			Labels: []string{"alloc", "resource"},
+		},
+		"cluster_cost_usd": {
+			Help: "Cumulative cost in USD of cluster instances since the cluster manager started.",
+		},
+		"cluster_hourly_cost_usd": {
+			Help: "Hourly cost in USD of active cluster instances.",
+		},
 		"memstats_heap_inuse_bytes": {
 			Help: "Bytes of memory used by in use heap spans.",
 		},
@@ -59,6 +76,14 @@ var (
 		"memstats_stack_sys_bytes": {
 			Help: "Bytes of stack memory obtained from the OS.",
 		},
+		"scheduler_allocs": {
+			Help:   "Number of scheduler allocs in each state.",
+			Labels: []string{"state"},
+		},
+		"scheduler_tasks": {
+			Help:   "Number of scheduler tasks in each state.",
+			Labels: []string{"state"},
+		},
 	}
 	Histograms = map[string]histogramOpts{
 		"dydbassoc_op_latency_seconds": {
@@ -66,6 +91,14 @@ var (
 			Labels:  []string{"operation"},
 			Buckets: []float64{0.001, 0.01, 0.1, 1, 10},
 		},
+		"tasks_attempts": {
+			Help:    "Number of attempts of completed tasks.",
+			Buckets: []float64{1, 2, 3, 5, 10},
+		},
+		"tasks_placement_latency_seconds": {
+			Help:    "Latency in seconds from task submission to placement on an alloc.",
+			Buckets: []float64{1, 10, 60, 300, 1800},
+		},
 	}
 )
 
@@ -89,6 +122,16 @@ func GetAllocsStartedSizeCounter(ctx context.Context) Counter {
 	return getCounter(ctx, "allocs_started_size", nil)
 }
 
+// GetDirectTransferBytesCounter returns a Counter to set metric direct_transfer_bytes (bytes of files copied by direct transfers).
+func GetDirectTransferBytesCounter(ctx context.Context) Counter {
+	return getCounter(ctx, "direct_transfer_bytes", nil)
+}
+
+// GetTaskdbErrorsCountCounter returns a Counter to set metric taskdb_errors_count (count of TaskDB operation errors).
+func GetTaskdbErrorsCountCounter(ctx context.Context, operation string) Counter {
+	return getCounter(ctx, "taskdb_errors_count", map[string]string{"operation": operation})
+}
+
 // GetTasksCompletedCountCounter returns a Counter to set metric tasks_completed_count (count of completed tasks).
 func GetTasksCompletedCountCounter(ctx context.Context) Counter {
 	return getCounter(ctx, "tasks_completed_count", nil)
@@ -119,6 +162,21 @@ func GetTasksSubmittedSizeCounter(ctx context.Context) Counter {
 	return getCounter(ctx, "tasks_submitted_size", nil)
 }
 
+// GetAllocsUtilizationGauge returns a Gauge to set metric allocs_utilization (fraction of alloc resources assigned to tasks).
+func GetAllocsUtilizationGauge(ctx context.Context, alloc, resource string) Gauge {
+	return getGauge(ctx, "allocs_utilization", map[string]string{"alloc": alloc, "resource": resource})
+}
+
+// GetClusterCostUsdGauge returns a Gauge to set metric cluster_cost_usd (cumulative cost in USD of cluster instances since the cluster manager started).
+func GetClusterCostUsdGauge(ctx context.Context) Gauge {
+	return getGauge(ctx, "cluster_cost_usd", nil)
+}
+
+// GetClusterHourlyCostUsdGauge returns a Gauge to set metric cluster_hourly_cost_usd (hourly cost in USD of active cluster instances).
+func GetClusterHourlyCostUsdGauge(ctx context.Context) Gauge {
+	return getGauge(ctx, "cluster_hourly_cost_usd", nil)
+}
+
 // GetMemstatsHeapInuseBytesGauge returns a Gauge to set metric memstats_heap_inuse_bytes (bytes of memory used by in use heap spans).
 func GetMemstatsHeapInuseBytesGauge(ctx context.Context) Gauge {
 	return getGauge(ctx, "memstats_heap_inuse_bytes", nil)
@@ -144,7 +202,27 @@ func GetMemstatsStackSysBytesGauge(ctx context.Context) Gauge {
 	return getGauge(ctx, "memstats_stack_sys_bytes", nil)
 }
 
+// GetSchedulerAllocsGauge returns a Gauge to set metric scheduler_allocs (number of scheduler allocs in each state).
+func GetSchedulerAllocsGauge(ctx context.Context, state string) Gauge {
+	return getGauge(ctx, "scheduler_allocs", map[string]string{"state": state})
+}
+
+// GetSchedulerTasksGauge returns a Gauge to set metric scheduler_tasks (number of scheduler tasks in each state).
+func GetSchedulerTasksGauge(ctx context.Context, state string) Gauge {
+	return getGauge(ctx, "scheduler_tasks", map[string]string{"state": state})
+}
+
 // GetDydbassocOpLatencySecondsHistogram returns a Histogram to set metric dydbassoc_op_latency_seconds (dydbassoc operation latency in seconds).
 func GetDydbassocOpLatencySecondsHistogram(ctx context.Context, operation string) Histogram {
 	return getHistogram(ctx, "dydbassoc_op_latency_seconds", map[string]string{"operation": operation})
 }
+
+// GetTasksAttemptsHistogram returns a Histogram to set metric tasks_attempts (number of attempts of completed tasks).
+func GetTasksAttemptsHistogram(ctx context.Context) Histogram {
+	return getHistogram(ctx, "tasks_attempts", nil)
+}
+
+// GetTasksPlacementLatencySecondsHistogram returns a Histogram to set metric tasks_placement_latency_seconds (latency in seconds from task submission to placement on an alloc).
+func GetTasksPlacementLatencySecondsHistogram(ctx context.Context) Histogram {
+	return getHistogram(ctx, "tasks_placement_latency_seconds", nil)
+}
@@ -198,25 +198,31 @@ type urlFetcher struct {
 // fetchUrl retrieves metrics from the provided URL and returns MetricFamily proto messages.
 func (f *urlFetcher) fetchUrl(ctx context.Context) (time.Time, []dto.MetricFamily, error) {
 	start := time.Now()
-	req, err := http.NewRequestWithContext(ctx, "GET", f.url, nil)
+	mfs, err := FetchMetrics(ctx, &http.Client{}, f.url)
+	return start, mfs, err
+}
+
+// FetchMetrics retrieves prometheus metrics from the provided URL using
+// the given HTTP client, and returns them as MetricFamily proto messages.
+func FetchMetrics(ctx context.Context, client *http.Client, url string) ([]dto.MetricFamily, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return start, nil, errors.E("GET", f.url, err)
+		return nil, errors.E("GET", url, err)
 	}
 	req.Header.Add("Accept", string(expfmt.FmtProtoDelim))
-	client := http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
-		return start, nil, errors.E("GET", f.url, err)
+		return nil, errors.E("GET", url, err)
 	}
+	defer func() { _ = resp.Body.Close() }()
 	if resp.StatusCode != http.StatusOK {
-		return start, nil, errors.E("GET", f.url, resp.Status)
+		return nil, errors.E("GET", url, resp.Status)
 	}
 	var (
 		dec  = expfmt.NewDecoder(resp.Body, expfmt.FmtProtoDelim)
 		mfs  []dto.MetricFamily
 		done bool
 	)
-	defer func() { _ = resp.Body.Close() }()
 	for !done {
 		var mf dto.MetricFamily
 		err := dec.Decode(&mf)
@@ -226,10 +232,27 @@ func (f *urlFetcher) fetchUrl(ctx context.Context) (time.Time, []dto.MetricFamil
 		case err == io.EOF:
 			done = true
 		default:
-			return start, mfs, errors.E("parse", "pbutil.ReadDelimited", err)
+			return mfs, errors.E("parse", "pbutil.ReadDelimited", err)
 		}
 	}
-	return start, mfs, nil
+	return mfs, nil
+}
+
+// Value returns the value of the (gauge or counter) metric with the
+// given name, summed across all of its label values. Value returns
+// false if there is no such metric.
+func Value(mfs []dto.MetricFamily, name string) (v float64, ok bool) {
+	for _, mf := range mfs {
+		if mf.GetName() != name {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			if mv, mok := singleValue(mf.GetType(), m); mok {
+				v, ok = v+mv, true
+			}
+		}
+	}
+	return
 }
 
 func toString(mf dto.MetricFamily) string {
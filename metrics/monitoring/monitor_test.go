@@ -110,3 +110,25 @@ func TestTimeSeries(t *testing.T) {
 		}
 	}
 }
+
+func TestValue(t *testing.T) {
+	var (
+		name, other = "node_disk_read_bytes_total", "node_load1"
+		typ         = dto.MetricType_COUNTER
+		gauge       = dto.MetricType_GAUGE
+		v1, v2, v3  = 1.0, 2.0, 0.5
+	)
+	mfs := []dto.MetricFamily{
+		{Name: &name, Type: &typ, Metric: []*dto.Metric{{Counter: &dto.Counter{Value: &v1}}, {Counter: &dto.Counter{Value: &v2}}}},
+		{Name: &other, Type: &gauge, Metric: []*dto.Metric{{Gauge: &dto.Gauge{Value: &v3}}}},
+	}
+	if v, ok := Value(mfs, name); !ok || v != 3 {
+		t.Errorf("got %v, %v, want 3, true", v, ok)
+	}
+	if v, ok := Value(mfs, other); !ok || v != 0.5 {
+		t.Errorf("got %v, %v, want 0.5, true", v, ok)
+	}
+	if _, ok := Value(mfs, "node_memory_MemTotal_bytes"); ok {
+		t.Error("expected no value")
+	}
+}
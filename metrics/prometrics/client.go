@@ -63,9 +63,13 @@ func (r *Client) Init() error {
 
 	if r.Port != 0 {
 		go func() {
-			log.Printf("hosting prometheus at %d", r.Port)
+			log.Printf("hosting prometheus at :%d/metrics", r.Port)
 			handler := promhttp.HandlerFor(r.gath, promhttp.HandlerOpts{})
-			log.Fatal(http.ListenAndServe(fmt.Sprintf(":%d", r.Port), handler))
+			mux := http.NewServeMux()
+			mux.Handle("/metrics", handler)
+			// Metrics were historically served at all paths; keep doing so.
+			mux.Handle("/", handler)
+			log.Fatal(http.ListenAndServe(fmt.Sprintf(":%d", r.Port), mux))
 		}()
 	}
 	return nil
@@ -0,0 +1,276 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+// Package statsd implements a metrics client which pushes metrics
+// to a StatsD (or Datadog DogStatsD) agent over UDP.
+package statsd
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grailbio/infra"
+	"github.com/grailbio/reflow/log"
+	"github.com/grailbio/reflow/metrics"
+)
+
+func init() {
+	infra.Register("statsd", new(Client))
+}
+
+const (
+	// datadog is the flavor for DogStatsD agents, which support tags
+	// and histograms.
+	datadog = "datadog"
+	// statsd is the flavor for plain StatsD agents; labels are appended
+	// to metric names and histograms are reported as timers.
+	statsd = "statsd"
+
+	// maxPacketSize is the maximum size of a UDP packet sent to the
+	// agent, chosen to avoid fragmentation on typical networks.
+	maxPacketSize = 1432
+)
+
+// Client is a metrics client which buffers metrics and sends them
+// to a StatsD agent periodically (and whenever a packet fills up).
+type Client struct {
+	// Addr is the (UDP) address of the agent.
+	Addr string
+	// Namespace is given as a prefix to all metrics.
+	Namespace string
+	// Flavor is the flavor of the agent: "datadog" or "statsd".
+	Flavor string
+	// Tags are additional (comma-separated key:value) tags which are
+	// added to all metrics (datadog only).
+	Tags string
+	// FlushInterval is the interval at which buffered metrics are sent.
+	FlushInterval time.Duration
+
+	conn net.Conn
+
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+// String implements infra.Provider.
+func (c *Client) String() string {
+	return fmt.Sprintf("%T,Addr=%s", c, c.Addr)
+}
+
+// Help implements infra.Provider.
+func (c *Client) Help() string {
+	return "configure a metrics client which pushes metrics to a StatsD or Datadog agent"
+}
+
+// Flags implements infra.Provider.
+func (c *Client) Flags(flags *flag.FlagSet) {
+	flags.StringVar(&c.Addr, "addr", "127.0.0.1:8125", "UDP address of the StatsD/Datadog agent")
+	flags.StringVar(&c.Namespace, "namespace", "reflow", "namespace to prepend to metrics")
+	flags.StringVar(&c.Flavor, "flavor", datadog, "flavor of the agent (one of datadog, statsd)")
+	flags.StringVar(&c.Tags, "tags", "", "comma-separated key:value tags added to all metrics (datadog only)")
+	flags.DurationVar(&c.FlushInterval, "flushinterval", time.Second, "interval at which buffered metrics are sent")
+}
+
+// Init implements infra.Provider.
+func (c *Client) Init() error {
+	switch c.Flavor {
+	case "":
+		c.Flavor = datadog
+	case datadog, statsd:
+	default:
+		return fmt.Errorf("statsd: invalid flavor %q (must be one of %s, %s)", c.Flavor, datadog, statsd)
+	}
+	if c.FlushInterval <= 0 {
+		c.FlushInterval = time.Second
+	}
+	conn, err := net.Dial("udp", c.Addr)
+	if err != nil {
+		return fmt.Errorf("statsd: dial %s: %v", c.Addr, err)
+	}
+	c.conn = conn
+	go func() {
+		for range time.Tick(c.FlushInterval) {
+			c.Flush()
+		}
+	}()
+	return nil
+}
+
+// Flush sends all buffered metrics to the agent.
+func (c *Client) Flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.flush()
+}
+
+// flush sends buffered metrics; c.mu must be held.
+func (c *Client) flush() {
+	if c.buf.Len() == 0 {
+		return
+	}
+	// Metrics are best-effort: errors (eg, no agent listening) are
+	// logged and the buffered metrics are dropped.
+	if _, err := c.conn.Write(c.buf.Bytes()); err != nil {
+		log.Debugf("statsd: write %s: %v", c.Addr, err)
+	}
+	c.buf.Reset()
+}
+
+// send buffers the given metric line, flushing the buffer first if
+// the line would not fit into the current packet.
+func (c *Client) send(line string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.buf.Len() > 0 && c.buf.Len()+1+len(line) > maxPacketSize {
+		c.flush()
+	}
+	if c.buf.Len() > 0 {
+		c.buf.WriteByte('\n')
+	}
+	c.buf.WriteString(line)
+}
+
+// metric is a named metric with a set of labels.
+type metric struct {
+	client *Client
+	name   string
+	tags   string
+}
+
+func (c *Client) metric(name string, labels map[string]string) metric {
+	var (
+		keys = make([]string, 0, len(labels))
+		tags []string
+	)
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	if c.Namespace != "" {
+		name = c.Namespace + "." + name
+	}
+	switch c.Flavor {
+	case statsd:
+		for _, k := range keys {
+			name += "." + sanitize(labels[k])
+		}
+	default:
+		for _, k := range keys {
+			tags = append(tags, k+":"+sanitize(labels[k]))
+		}
+		if c.Tags != "" {
+			tags = append(tags, c.Tags)
+		}
+	}
+	m := metric{client: c, name: name}
+	if len(tags) > 0 {
+		m.tags = "|#" + strings.Join(tags, ",")
+	}
+	return m
+}
+
+// emit sends the given value of the metric, with the given type.
+func (m metric) emit(value string, typ string) {
+	m.client.send(m.name + ":" + value + "|" + typ + m.tags)
+}
+
+func format(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// sanitize replaces characters reserved by the statsd protocol.
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ':', '|', '@', ',', '#', '\n':
+			return '_'
+		}
+		return r
+	}, s)
+}
+
+type gauge struct{ metric }
+
+// Set implements metrics.Gauge.
+func (g gauge) Set(v float64) {
+	// Negative values are interpreted as decrements by statsd,
+	// so the gauge must first be reset.
+	if v < 0 {
+		g.emit("0", "g")
+	}
+	g.emit(format(v), "g")
+}
+
+// Inc implements metrics.Gauge.
+func (g gauge) Inc() { g.Add(1) }
+
+// Dec implements metrics.Gauge.
+func (g gauge) Dec() { g.Sub(1) }
+
+// Add implements metrics.Gauge.
+func (g gauge) Add(v float64) {
+	if v < 0 {
+		g.Sub(-v)
+		return
+	}
+	g.emit("+"+format(v), "g")
+}
+
+// Sub implements metrics.Gauge.
+func (g gauge) Sub(v float64) {
+	if v < 0 {
+		g.Add(-v)
+		return
+	}
+	g.emit("-"+format(v), "g")
+}
+
+type counter struct{ metric }
+
+// Inc implements metrics.Counter.
+func (c counter) Inc() { c.Add(1) }
+
+// Add implements metrics.Counter.
+func (c counter) Add(v float64) {
+	if v < 0 {
+		panic("statsd: counter cannot decrease")
+	}
+	c.emit(format(v), "c")
+}
+
+type histogram struct {
+	metric
+	typ string
+}
+
+// Observe implements metrics.Histogram.
+func (h histogram) Observe(v float64) {
+	h.emit(format(v), h.typ)
+}
+
+// GetGauge implements metrics.Client.
+func (c *Client) GetGauge(name string, labels map[string]string) metrics.Gauge {
+	return gauge{c.metric(name, labels)}
+}
+
+// GetCounter implements metrics.Client.
+func (c *Client) GetCounter(name string, labels map[string]string) metrics.Counter {
+	return counter{c.metric(name, labels)}
+}
+
+// GetHistogram implements metrics.Client.
+func (c *Client) GetHistogram(name string, labels map[string]string) metrics.Histogram {
+	typ := "h"
+	if c.Flavor == statsd {
+		typ = "ms"
+	}
+	return histogram{c.metric(name, labels), typ}
+}
@@ -0,0 +1,115 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package statsd
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func listen(t *testing.T) net.PacketConn {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+func receive(t *testing.T, conn net.PacketConn) []string {
+	t.Helper()
+	if err := conn.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, 2*maxPacketSize)
+	n, _, err := conn.ReadFrom(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(string(b[:n]), "\n")
+}
+
+func TestClient(t *testing.T) {
+	for _, tc := range []struct {
+		flavor string
+		want   []string
+	}{
+		{datadog, []string{
+			"reflow.tasks:+1|g|#state:running,env:test",
+			"reflow.tasks:-2|g|#state:running,env:test",
+			"reflow.tasks:0|g|#state:running,env:test",
+			"reflow.tasks:-1|g|#state:running,env:test",
+			"reflow.bytes:1024|c|#env:test",
+			"reflow.latency:0.5|h|#alloc:a_b,op:x,env:test",
+		}},
+		{statsd, []string{
+			"reflow.tasks.running:+1|g",
+			"reflow.tasks.running:-2|g",
+			"reflow.tasks.running:0|g",
+			"reflow.tasks.running:-1|g",
+			"reflow.bytes:1024|c",
+			"reflow.latency.a_b.x:0.5|ms",
+		}},
+	} {
+		conn := listen(t)
+		c := &Client{
+			Addr:          conn.LocalAddr().String(),
+			Namespace:     "reflow",
+			Flavor:        tc.flavor,
+			Tags:          "env:test",
+			FlushInterval: time.Hour,
+		}
+		if err := c.Init(); err != nil {
+			t.Fatal(err)
+		}
+		g := c.GetGauge("tasks", map[string]string{"state": "running"})
+		g.Inc()
+		g.Sub(2)
+		g.Set(-1)
+		c.GetCounter("bytes", nil).Add(1024)
+		c.GetHistogram("latency", map[string]string{"op": "x", "alloc": "a:b"}).Observe(0.5)
+		c.Flush()
+		got := receive(t, conn)
+		if len(got) != len(tc.want) {
+			t.Fatalf("%s: got %v, want %v", tc.flavor, got, tc.want)
+		}
+		for i := range got {
+			if got[i] != tc.want[i] {
+				t.Errorf("%s: got %v, want %v", tc.flavor, got[i], tc.want[i])
+			}
+		}
+		conn.Close()
+	}
+}
+
+func TestClientPacketSize(t *testing.T) {
+	conn := listen(t)
+	defer conn.Close()
+	c := &Client{Addr: conn.LocalAddr().String(), FlushInterval: time.Hour}
+	if err := c.Init(); err != nil {
+		t.Fatal(err)
+	}
+	counter := c.GetCounter(strings.Repeat("x", 500), nil)
+	for i := 0; i < 3; i++ {
+		counter.Inc()
+	}
+	// The third metric does not fit into the first packet.
+	if got, want := len(receive(t, conn)), 2; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	c.Flush()
+	if got, want := len(receive(t, conn)), 1; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestClientInvalidFlavor(t *testing.T) {
+	c := &Client{Addr: "127.0.0.1:8125", Flavor: "graphite"}
+	if err := c.Init(); err == nil {
+		t.Error("expected error")
+	}
+}
@@ -30,6 +30,18 @@ import (
 // implementation.
 type Client struct {
 	*rest.Client
+
+	// Resolve, if set, returns the current host (and port) of the
+	// remote pool. It is used to follow a remote pool whose address
+	// changes (e.g., an EC2 instance whose public DNS name changes
+	// after a stop/start), and is invoked when a call fails due to a
+	// network error.
+	Resolve func(ctx context.Context) (string, error)
+	// Identity is the identity (e.g., EC2 instance ID) of the remote
+	// pool. If set, a re-resolved address is used only after verifying
+	// that the pool serving it has this identity.
+	Identity string
+
 	host  string
 	group singleflight.Group
 }
@@ -49,6 +61,62 @@ func New(baseurl string, client *http.Client, log *log.Logger) (*Client, error)
 // ID returns the client's host name.
 func (c *Client) ID() string { return c.host }
 
+// InstanceID retrieves the identity (EC2 instance ID) of the remote
+// reflowlet instance.
+func (c *Client) InstanceID(ctx context.Context) (string, error) {
+	call := c.Call("GET", "identity")
+	defer call.Close()
+	code, err := call.Do(ctx, nil)
+	if err != nil {
+		return "", errors.E("identity", c.ID(), err)
+	}
+	if code != http.StatusOK {
+		return "", call.Error()
+	}
+	var id string
+	if err := call.Unmarshal(&id); err != nil {
+		return "", errors.E("identity", c.ID(), err)
+	}
+	return id, nil
+}
+
+// reresolve re-resolves the address of the remote pool after a call
+// failed with the error err. It reports whether the address changed
+// (and its identity was verified), in which case the call should be
+// retried.
+func (c *Client) reresolve(ctx context.Context, err error) bool {
+	if c.Resolve == nil || !errors.Is(errors.Net, err) {
+		return false
+	}
+	v, rerr, _ := c.group.Do("resolve", func() (interface{}, error) {
+		host, err := c.Resolve(ctx)
+		if err != nil {
+			return false, err
+		}
+		if host == c.Host() {
+			return false, nil
+		}
+		if c.Identity != "" {
+			probe := &Client{Client: c.Client.WithHost(host), host: c.host}
+			id, err := probe.InstanceID(ctx)
+			if err != nil {
+				return false, err
+			}
+			if id != c.Identity {
+				return false, errors.E(errors.Invalid,
+					errors.Errorf("pool at %s has identity %s, expected %s", host, id, c.Identity))
+			}
+		}
+		c.SetHost(host)
+		return true, nil
+	})
+	if rerr != nil {
+		log.Debugf("pool %s: re-resolve: %v", c.ID(), rerr)
+		return false
+	}
+	return v.(bool)
+}
+
 // Alloc looks up an alloc by name.
 func (c *Client) Alloc(ctx context.Context, id string) (pool.Alloc, error) {
 	call := c.Call("GET", "allocs/%s", id)
@@ -180,8 +248,18 @@ func (a *clientAlloc) Repository() reflow.Repository {
 	}
 }
 
-// Keepalive issues a keepalive request to a remote alloc.
+// Keepalive issues a keepalive request to a remote alloc. If the
+// request fails due to a network error, the remote pool's address
+// is re-resolved and the request is retried.
 func (a *clientAlloc) Keepalive(ctx context.Context, interval time.Duration) (time.Duration, error) {
+	iv, err := a.keepalive(ctx, interval)
+	if err != nil && a.reresolve(ctx, err) {
+		iv, err = a.keepalive(ctx, interval)
+	}
+	return iv, err
+}
+
+func (a *clientAlloc) keepalive(ctx context.Context, interval time.Duration) (time.Duration, error) {
 	call := a.Call("POST", "allocs/%s/keepalive", a.id)
 	defer call.Close()
 	arg := struct {
@@ -342,6 +420,21 @@ func (a *clientAlloc) Unload(ctx context.Context, fs reflow.Fileset) error {
 	return nil
 }
 
+// PrefetchImages asks the alloc to begin fetching the given images.
+// It implements reflow.ImagePrefetcher.
+func (a *clientAlloc) PrefetchImages(ctx context.Context, images []string) error {
+	call := a.Call("POST", "allocs/%s/prefetch", a.id)
+	defer call.Close()
+	code, err := call.DoJSON(ctx, images)
+	if err != nil {
+		return errors.E("prefetch", a.ID(), err)
+	}
+	if code != http.StatusOK {
+		return call.Error()
+	}
+	return nil
+}
+
 type clientExec struct {
 	*Client
 	allocID string
@@ -527,8 +620,18 @@ func (c *Client) Offer(ctx context.Context, id string) (pool.Offer, error) {
 	return &clientOffer{c, id, json.Available}, nil
 }
 
-// Offers enumerates all available offers in this pool.
+// Offers enumerates all available offers in this pool. If the
+// request fails due to a network error, the remote pool's address
+// is re-resolved and the request is retried.
 func (c *Client) Offers(ctx context.Context) ([]pool.Offer, error) {
+	offers, err := c.offers(ctx)
+	if err != nil && c.reresolve(ctx, err) {
+		offers, err = c.offers(ctx)
+	}
+	return offers, err
+}
+
+func (c *Client) offers(ctx context.Context) ([]pool.Offer, error) {
 	v, err, _ := c.group.Do("offers", func() (interface{}, error) {
 		call := c.Call("GET", "offers/")
 		defer call.Close()
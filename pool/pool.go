@@ -188,11 +188,11 @@ func Allocs(ctx context.Context, pool Pool, log *log.Logger) []Alloc {
 // pickN returns upto n offers in decreasing order of "best match" defined as follows:
 // - all offers >= max appear first, in increasing order of distance from max.
 // - offers less than max appear next, again in increasing order of distance from max.
-// - offers less than min are omitted.
+// - offers less than min, or of a different architecture, are omitted.
 func pickN(offers []Offer, n int, min, max reflow.Resources) []Offer {
 	q := &offerq{max: max}
 	for _, offer := range offers {
-		if !offer.Available().Available(min) {
+		if avail := offer.Available(); !avail.Available(min) || !avail.ArchCompatible(min) {
 			continue
 		}
 		heap.Push(q, offer)
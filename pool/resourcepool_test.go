@@ -2,9 +2,11 @@ package pool
 
 import (
 	"context"
+	"net/url"
 	"testing"
 	"time"
 
+	"github.com/grailbio/reflow"
 	"github.com/grailbio/reflow/log"
 )
 
@@ -53,3 +55,70 @@ func TestStopIfIdleFor(t *testing.T) {
 		t.Fatal("idle pool must be stopped")
 	}
 }
+
+type stateExec struct {
+	reflow.Exec
+	state string
+}
+
+func (x stateExec) Inspect(ctx context.Context, repo *url.URL) (reflow.InspectResponse, error) {
+	return reflow.InspectResponse{Inspect: &reflow.ExecInspect{State: x.state}}, nil
+}
+
+type execsAlloc struct {
+	*inspectAlloc
+	states []string
+}
+
+func (a *execsAlloc) Execs(ctx context.Context) ([]reflow.Exec, error) {
+	execs := make([]reflow.Exec, len(a.states))
+	for i, state := range a.states {
+		execs[i] = stateExec{state: state}
+	}
+	return execs, nil
+}
+
+type killManager struct {
+	AllocManager
+	killed []string
+}
+
+func (m *killManager) Kill(a Alloc) error {
+	m.killed = append(m.killed, a.ID())
+	return nil
+}
+
+func TestReapStale(t *testing.T) {
+	m := new(killManager)
+	p := ResourcePool{manager: m, log: log.Std, allocs: map[string]Alloc{}}
+	for _, a := range []struct {
+		id     string
+		ka     time.Duration
+		states []string
+	}{
+		{"live", time.Minute, []string{"running"}},
+		{"stale", -time.Hour, []string{"complete", "running"}},
+		{"recent", -time.Minute, []string{"running"}},
+		{"done", -time.Hour, []string{"complete"}},
+	} {
+		p.allocs[a.id] = &execsAlloc{newInspectAlloc(&p, a.id, a.ka), a.states}
+	}
+	ctx := context.Background()
+	stale := p.ReapStale(ctx, StaleKeep, 10*time.Minute)
+	if got, want := len(stale), 1; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got, want := stale[0].ID(), "stale"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if len(m.killed) != 0 {
+		t.Errorf("killed %v under policy keep", m.killed)
+	}
+	p.ReapStale(ctx, StaleTeardown, 10*time.Minute)
+	if got, want := m.killed, []string{"stale"}; len(got) != 1 || got[0] != want[0] {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if _, err := p.Alloc(ctx, "stale"); err == nil {
+		t.Error("stale alloc was not freed")
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/grailbio/reflow/errors"
+	"github.com/grailbio/reflow/log"
+	"golang.org/x/time/rate"
+)
+
+// maxGuardClients is the number of clients whose rate limiters are
+// retained; beyond it, the limiters of clients which have been idle
+// for longer than guardClientIdle are discarded.
+const (
+	maxGuardClients = 1024
+	guardClientIdle = 10 * time.Minute
+)
+
+// A Guard is an http.Handler which protects the API served by its
+// handler (e.g., a reflowlet's) from buggy or runaway clients, and
+// provides forensic data after incidents: it limits the rate of each
+// client's calls, and records an audit log of the calls: who made
+// them, when, what they called, and with what outcome.
+//
+// Clients are identified by the common name of their TLS certificate,
+// if any, and by their address, e.g., "reflow@10.1.2.3".
+type Guard struct {
+	// Handler serves the calls which are admitted.
+	Handler http.Handler
+	// Limit is the rate (in calls per second) at which each client
+	// may call, with bursts of up to Burst calls. Calls in excess of
+	// the limit fail with http.StatusTooManyRequests and an error of
+	// kind errors.Temporary, so that clients retry them. If Limit is
+	// zero, calls are not limited.
+	Limit rate.Limit
+	Burst int
+	// Audit, if not nil, receives a record of each call.
+	Audit *log.Logger
+
+	mu      sync.Mutex
+	clients map[string]*guardClient
+}
+
+type guardClient struct {
+	limiter *rate.Limiter
+	last    time.Time
+}
+
+// NewGuard returns a new Guard which serves calls with the given
+// handler, limiting each client to limit calls per second (with the
+// given burst), and recording the calls in audit, if it is not nil.
+func NewGuard(h http.Handler, limit rate.Limit, burst int, audit *log.Logger) *Guard {
+	return &Guard{Handler: h, Limit: limit, Burst: burst, Audit: audit}
+}
+
+// ServeHTTP implements http.Handler.
+func (g *Guard) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var (
+		start  = time.Now()
+		client = clientID(r)
+		rw     = &statusWriter{ResponseWriter: w}
+	)
+	if g.allow(client, start) {
+		g.Handler.ServeHTTP(rw, r)
+	} else {
+		err := errors.E(r.Method, r.URL.Path, errors.Temporary, errors.Errorf("client %s exceeded the rate limit of %v calls per second", client, float64(g.Limit)))
+		rw.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		rw.Header().Set("Retry-After", "1")
+		rw.WriteHeader(http.StatusTooManyRequests)
+		_ = json.NewEncoder(rw).Encode(err)
+	}
+	if g.Audit != nil {
+		g.Audit.Printf("client %s %s %s status %d duration %s", client, r.Method, r.URL.Path, rw.status(), time.Since(start).Round(time.Millisecond))
+	}
+}
+
+// allow tells whether the given client may call now.
+func (g *Guard) allow(client string, now time.Time) bool {
+	if g.Limit == 0 || g.Limit == rate.Inf {
+		return true
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.clients == nil {
+		g.clients = make(map[string]*guardClient)
+	}
+	c := g.clients[client]
+	if c == nil {
+		if len(g.clients) >= maxGuardClients {
+			for id, c := range g.clients {
+				if now.Sub(c.last) > guardClientIdle {
+					delete(g.clients, id)
+				}
+			}
+		}
+		burst := g.Burst
+		if burst < 1 {
+			burst = int(math.Ceil(float64(g.Limit)))
+		}
+		c = &guardClient{limiter: rate.NewLimiter(g.Limit, burst)}
+		g.clients[client] = c
+	}
+	c.last = now
+	return c.limiter.AllowN(now, 1)
+}
+
+// clientID returns the identity of the client of the given request:
+// the common name of its TLS certificate, if any, and its address.
+func clientID(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+		if cn := r.TLS.PeerCertificates[0].Subject.CommonName; cn != "" {
+			return fmt.Sprintf("%s@%s", cn, host)
+		}
+	}
+	return host
+}
+
+// statusWriter is an http.ResponseWriter which records the status
+// of the reply.
+type statusWriter struct {
+	http.ResponseWriter
+	code int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if w.code == 0 {
+		w.code = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(p []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush implements http.Flusher, so that streaming replies (e.g., of
+// exec logs) are flushed to the client.
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// status returns the status of the reply.
+func (w *statusWriter) status() int {
+	if w.code == 0 {
+		return http.StatusOK
+	}
+	return w.code
+}
@@ -0,0 +1,72 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	golog "log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/grailbio/reflow/errors"
+	"github.com/grailbio/reflow/log"
+)
+
+func TestGuard(t *testing.T) {
+	var b bytes.Buffer
+	audit := log.New(golog.New(&b, "", 0), log.InfoLevel)
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	g := NewGuard(h, 1, 2, audit)
+	call := func(addr string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/v1/allocs/x/execs/y", nil)
+		r.RemoteAddr = addr
+		w := httptest.NewRecorder()
+		g.ServeHTTP(w, r)
+		return w
+	}
+	for i := 0; i < 2; i++ {
+		if got, want := call("10.0.0.1:1234").Code, http.StatusNoContent; got != want {
+			t.Fatalf("call %d: got %v, want %v", i, got, want)
+		}
+	}
+	w := call("10.0.0.1:1235")
+	if got, want := w.Code, http.StatusTooManyRequests; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("missing Retry-After header")
+	}
+	var err errors.Error
+	if e := json.NewDecoder(w.Body).Decode(&err); e != nil {
+		t.Fatal(e)
+	}
+	if !errors.Is(errors.Temporary, &err) {
+		t.Errorf("expected temporary error, got %v", &err)
+	}
+	// Other clients are limited independently.
+	if got, want := call("10.0.0.2:1234").Code, http.StatusNoContent; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if got, want := len(lines), 4; got != want {
+		t.Fatalf("got %v audit records, want %v:\n%s", got, want, b.String())
+	}
+	for i, want := range []string{
+		"client 10.0.0.1 POST /v1/allocs/x/execs/y status 204",
+		"client 10.0.0.1 POST /v1/allocs/x/execs/y status 204",
+		"client 10.0.0.1 POST /v1/allocs/x/execs/y status 429",
+		"client 10.0.0.2 POST /v1/allocs/x/execs/y status 204",
+	} {
+		if !strings.HasPrefix(lines[i], want) {
+			t.Errorf("got %q, want prefix %q", lines[i], want)
+		}
+	}
+}
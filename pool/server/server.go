@@ -212,6 +212,26 @@ func (n allocNode) Walk(ctx context.Context, call *rest.Call, path string) rest.
 			}
 			call.Reply(http.StatusOK, "verified")
 		})
+	case "prefetch":
+		return rest.DoFunc(func(ctx context.Context, call *rest.Call) {
+			if !call.Allow("POST") {
+				return
+			}
+			p, ok := n.a.(reflow.ImagePrefetcher)
+			if !ok {
+				call.Error(errors.E("prefetch", errors.NotSupported, errors.New("alloc does not prefetch images")))
+				return
+			}
+			var images []string
+			if call.Unmarshal(&images) != nil {
+				return
+			}
+			if err := p.PrefetchImages(ctx, images); err != nil {
+				call.Error(err)
+				return
+			}
+			call.Reply(http.StatusOK, "prefetching")
+		})
 	default:
 		return nil
 	}
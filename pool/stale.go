@@ -0,0 +1,126 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package pool
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// StalePolicy determines how a pool handles stale allocs: allocs
+// whose owner (e.g., the process of a reflow run which has died) has
+// stopped maintaining their lease, but which are still busy running
+// execs. Until it is torn down, a stale alloc may be adopted by a new
+// owner, which need only resume its keepalives.
+type StalePolicy int
+
+const (
+	// StaleKeep leaves stale allocs running until their resources are
+	// needed by new allocs, or until the pool is stopped.
+	StaleKeep StalePolicy = iota
+	// StaleTeardown kills the execs of, and frees, allocs which have
+	// been stale for longer than a grace period. The allocs' metadata
+	// and logs are kept so that they may be examined posthumously.
+	StaleTeardown
+)
+
+// String returns the name of the policy, as accepted by ParseStalePolicy.
+func (p StalePolicy) String() string {
+	switch p {
+	case StaleKeep:
+		return "keep"
+	case StaleTeardown:
+		return "teardown"
+	default:
+		return fmt.Sprintf("StalePolicy(%d)", p)
+	}
+}
+
+// ParseStalePolicy parses a StalePolicy from its name. The empty
+// string parses as StaleKeep.
+func ParseStalePolicy(s string) (StalePolicy, error) {
+	switch s {
+	case "", "keep":
+		return StaleKeep, nil
+	case "teardown":
+		return StaleTeardown, nil
+	default:
+		return StaleKeep, fmt.Errorf("unknown stale alloc policy %q (must be one of \"keep\", \"teardown\")", s)
+	}
+}
+
+// AllocBusy tells whether any of the alloc's execs have yet to
+// complete.
+func AllocBusy(ctx context.Context, a Alloc) (bool, error) {
+	execs, err := a.Execs(ctx)
+	if err != nil {
+		return false, err
+	}
+	for _, x := range execs {
+		resp, err := x.Inspect(ctx, nil)
+		if err != nil {
+			return false, err
+		}
+		if resp.Inspect != nil && resp.Inspect.State != "complete" && resp.Inspect.State != "zombie" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// StaleAllocs returns the allocs among the provided ones which are
+// stale: their leases expired at least grace ago, and they are busy.
+// Allocs which cannot be inspected are assumed not to be stale.
+func StaleAllocs(ctx context.Context, allocs []Alloc, grace time.Duration) []Alloc {
+	var (
+		g     errgroup.Group
+		stale = make([]bool, len(allocs))
+	)
+	for i := range allocs {
+		i := i
+		g.Go(func() error {
+			if AllocExpiredBy(allocs[i]) < grace {
+				return nil
+			}
+			busy, err := AllocBusy(ctx, allocs[i])
+			stale[i] = err == nil && busy
+			return nil
+		})
+	}
+	_ = g.Wait()
+	var staleAllocs []Alloc
+	for i, a := range allocs {
+		if stale[i] {
+			staleAllocs = append(staleAllocs, a)
+		}
+	}
+	return staleAllocs
+}
+
+// ReapStale applies the given policy to the allocs of the pool which
+// have been stale for at least grace, and returns them. Allocs which
+// are adopted (their leases renewed) while they are examined are not
+// torn down.
+func (p *ResourcePool) ReapStale(ctx context.Context, policy StalePolicy, grace time.Duration) []Alloc {
+	allocs, _ := p.Allocs(ctx)
+	stale := StaleAllocs(ctx, allocs, grace)
+	if policy != StaleTeardown {
+		return stale
+	}
+	for _, a := range stale {
+		p.mu.Lock()
+		if by := AllocExpiredBy(a); by >= grace {
+			p.log.Printf("alloc %s stale for %s; tearing down", a.ID(), by.Round(time.Second))
+			if err := p.doFree(a); err != nil {
+				p.log.Errorf("error tearing down alloc %s: %v", a.ID(), err)
+			}
+		}
+		p.mu.Unlock()
+	}
+	return stale
+}
@@ -0,0 +1,71 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package predictor
+
+import (
+	"sync"
+
+	"github.com/grailbio/reflow"
+	"github.com/grailbio/reflow/sched"
+	"github.com/grailbio/reflow/taskdb"
+)
+
+// observation is the profile of a task which was observed to
+// complete (successfully) during the current run.
+type observation struct {
+	id      taskdb.TaskID
+	profile reflow.Profile
+}
+
+// observations holds the observed profiles of each taskGroup,
+// in reverse chronological order.
+type observations struct {
+	mu sync.Mutex
+	m  map[taskGroup][]observation
+}
+
+// add adds the observation o to the given group, retaining at most
+// max (most recent) observations for the group.
+func (o *observations) add(group taskGroup, obs observation, max int) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.m == nil {
+		o.m = make(map[taskGroup][]observation)
+	}
+	list := append([]observation{obs}, o.m[group]...)
+	if len(list) > max {
+		list = list[:max]
+	}
+	o.m[group] = list
+}
+
+// get returns the observations of the given group.
+func (o *observations) get(group taskGroup) []observation {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]observation(nil), o.m[group]...)
+}
+
+// Observe incorporates the profile of the given (completed) task
+// into the predictor's model, so that subsequent predictions for
+// tasks of the same taskGroups reflect it immediately, without
+// waiting for the task's profile to become available in TaskDB.
+// Observe is a no-op unless the predictor is Online, or if the task
+// is not a successfully completed exec with memory profiling data.
+func (p *Predictor) Observe(task *sched.Task) {
+	if !p.Online || task.Config.Type != "exec" || task.Err != nil || task.Result.Err != nil {
+		return
+	}
+	profile := task.RunInfo.Profile
+	if _, ok := profile["mem"]; !ok {
+		return
+	}
+	obs := observation{id: task.ID(), profile: profile}
+	for _, group := range getTaskGroups(task) {
+		p.observed.add(group, obs, p.maxInspect)
+		// Invalidate cached predictions, which do not account for obs.
+		p.cache.Delete(group)
+	}
+}
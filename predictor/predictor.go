@@ -58,6 +58,11 @@ var defaultInspectLimiterTokens = runtime.NumCPU()
 // Predictor predicts tasks' resource usage. All predictions
 // are performed online using cached profiling data.
 type Predictor struct {
+	// Online determines whether the profiles of tasks completed
+	// during the current run (as reported to Observe) are incorporated
+	// into predictions, in addition to the profiles found in TaskDB.
+	Online bool
+
 	// taskDB is the task reporting db.
 	taskDB taskdb.TaskDB
 	// log is used to log.
@@ -72,6 +77,12 @@ type Predictor struct {
 	// memPercentile is the percentile that will
 	// be used to predict memory usage for all tasks.
 	memPercentile float64
+	// cpuPercentile is the percentile that will
+	// be used to predict cpu usage for all tasks.
+	cpuPercentile float64
+	// diskPercentile is the percentile that will be
+	// used to predict scratch disk usage for all tasks.
+	diskPercentile float64
 	// inspectLimiter limits the number of concurrent
 	// ExecInspect Profile unmarshal operations.
 	inspectLimiter *limiter.Limiter
@@ -79,6 +90,9 @@ type Predictor struct {
 	// cache is used for caching predicted results.
 	cache    sync.Map // map[taskGroup]*predCacheEntry
 	cacheTtl time.Duration
+
+	// observed holds the profiles of tasks observed (in Online mode).
+	observed observations
 }
 
 // Prediction consists of various predicted attributes.
@@ -94,7 +108,7 @@ type Prediction struct {
 // New returns a new Predictor instance. New will panic if either repo or tdb is nil because
 // a Predictor requires both a taskdb and a repository to function. NewPred will also panic if
 // minData <= 0 because a prediction requires at least one data point.
-func New(tdb taskdb.TaskDB, log *log.Logger, minData, maxInspect int, memPercentile float64) *Predictor {
+func New(tdb taskdb.TaskDB, log *log.Logger, minData, maxInspect int, memPercentile, cpuPercentile, diskPercentile float64) *Predictor {
 	if tdb == nil {
 		panic("predictor requires a taskdb")
 	}
@@ -122,6 +136,8 @@ func New(tdb taskdb.TaskDB, log *log.Logger, minData, maxInspect int, memPercent
 		minData:        minData,
 		maxInspect:     maxInspect,
 		memPercentile:  memPercentile,
+		cpuPercentile:  cpuPercentile,
+		diskPercentile: diskPercentile,
 		inspectLimiter: inspectLimiter,
 		cacheTtl:       defaultCacheTtl,
 	}
@@ -206,11 +222,19 @@ func (p *Predictor) Predict(ctx context.Context, tasks ...*sched.Task) map[*sche
 				p.log.Debugf("getting predictions for group: %v", preds.err)
 				return nil
 			}
-			p.log.Debugf("successfully modeled memory usage: %s", groups[i].Name())
+			p.log.Debugf("successfully modeled resource usage: %s", groups[i].Name())
 			doneTasks := groupMap[groups[i]]
 			for j := 0; j < len(doneTasks); j++ {
 				predictedResources := make(reflow.Resources)
 				predictedResources["mem"] = preds.mem
+				// CPU and disk predictions are made only if there is sufficient
+				// profiling data for them; otherwise the task's reservation is kept.
+				if preds.cpu > 0 {
+					predictedResources["cpu"] = preds.cpu
+				}
+				if preds.disk > 0 {
+					predictedResources["disk"] = preds.disk
+				}
 
 				mu.Lock()
 				todo.RemoveAll(doneTasks[j])
@@ -224,7 +248,7 @@ func (p *Predictor) Predict(ctx context.Context, tasks ...*sched.Task) map[*sche
 			}
 			return nil
 		})
-		p.log.Debugf("successfully modeled memory usage for %d/%d tasks", len(tasks)-todo.Len(), len(tasks))
+		p.log.Debugf("successfully modeled resource usage for %d/%d tasks", len(tasks)-todo.Len(), len(tasks))
 	}
 
 	return predMap
@@ -232,8 +256,8 @@ func (p *Predictor) Predict(ctx context.Context, tasks ...*sched.Task) map[*sche
 
 // prediction holds all the predictions based on a set of profiles.
 type prediction struct {
-	mem, durNanos float64
-	err           error
+	mem, cpu, disk, durNanos float64
+	err                      error
 }
 
 // predCacheEntry is an entry in the predictions cache.
@@ -260,6 +284,8 @@ func (p *Predictor) getPrediction(ctx context.Context, group taskGroup) predicti
 		entry.p.err = err
 	} else {
 		entry.p.durNanos, _ = p.durationNanos(profiles)
+		entry.p.cpu, _ = p.cpuUsage(profiles)
+		entry.p.disk, _ = p.diskUsage(profiles)
 		entry.p.mem, entry.p.err = p.memUsage(profiles)
 	}
 	entry.expiration = time.Now().Add(p.cacheTtl)
@@ -267,24 +293,59 @@ func (p *Predictor) getPrediction(ctx context.Context, group taskGroup) predicti
 }
 
 // getProfiles returns a list of profiles the predicted memory usage of a task in group.
+// Profiles of tasks observed during the current run (if any) are included first.
 func (p *Predictor) getProfiles(ctx context.Context, group taskGroup) ([]reflow.Profile, error) {
+	observed := p.observed.get(group)
+	observedIDs := make(map[taskdb.TaskID]bool, len(observed))
+	for _, obs := range observed {
+		observedIDs[obs.id] = true
+	}
 	// Query taskdb for all tasks in the taskGroup.
 	tasks, err := p.taskDB.Tasks(ctx, group.Query())
 	if err != nil {
 		return nil, errors.E(group.Name(), "taskdb query", err)
 	}
-	if len(tasks) < p.minData {
-		return nil, errors.E(group.Name(), fmt.Errorf("insufficient tasks (%d < %d)", len(tasks), p.minData))
+	if len(tasks)+len(observed) < p.minData {
+		return nil, errors.E(group.Name(), fmt.Errorf("insufficient tasks (%d < %d)", len(tasks)+len(observed), p.minData))
 	}
 	ended := tasks[:0]
 	for _, task := range tasks {
-		if task.End.IsZero() {
+		// Observed tasks are also recorded in taskdb; don't count them twice.
+		if task.End.IsZero() || observedIDs[task.ID] {
 			continue
 		}
 		ended = append(ended, task)
 	}
 	tasks = ended
 
+	inspectDigests := p.recentInspects(ctx, tasks, p.maxInspect-len(observed))
+	// Get all profiles for all tasks in the taskGroup.
+	var (
+		mu       sync.Mutex
+		profiles = make([]reflow.Profile, 0, len(observed)+len(inspectDigests))
+	)
+	for _, obs := range observed {
+		profiles = append(profiles, obs.profile)
+	}
+	_ = traverse.Each(len(inspectDigests), func(i int) error {
+		var si smallInspect
+		if err := p.decodeInspect(ctx, inspectDigests[i], &si); err != nil {
+			return nil
+		}
+		// Only use profiles with memory data to make memory predictions.
+		if _, ok := si.Profile["mem"]; ok && si.Error == nil && si.ExecError == nil {
+			mu.Lock()
+			profiles = append(profiles, si.Profile)
+			mu.Unlock()
+		}
+		return nil
+	})
+	return profiles, nil
+}
+
+// recentInspects returns the digests of the valid inspects of (at most max of)
+// the given tasks, in reverse chronological order of the tasks' end times.
+func (p *Predictor) recentInspects(ctx context.Context, tasks []taskdb.Task, max int) []digest.Digest {
 	// sort tasks in descending order of end time (ie, reverse chronological order)
 	// TODO(swami): Remove sorting once we've fully migrated to indices which already do the sorting.
 	sort.Slice(tasks, func(i, j int) bool {
@@ -292,11 +353,10 @@ func (p *Predictor) getProfiles(ctx context.Context, group taskGroup) ([]reflow.
 	})
 
 	repo := p.taskDB.Repository()
-
-	inspectDigests := make([]digest.Digest, 0, p.maxInspect)
+	inspectDigests := make([]digest.Digest, 0, max)
 	for _, task := range tasks {
-		// Limit the number of inspects we will use to maxInspect.
-		if len(inspectDigests) == p.maxInspect {
+		// Limit the number of inspects (and observed profiles) we will use to maxInspect.
+		if len(inspectDigests) >= max {
 			break
 		}
 		ins := task.Inspect
@@ -309,46 +369,37 @@ func (p *Predictor) getProfiles(ctx context.Context, group taskGroup) ([]reflow.
 		}
 		inspectDigests = append(inspectDigests, ins)
 	}
-	// Get all profiles for all tasks in the taskGroup.
-	var (
-		mu       sync.Mutex
-		profiles = make([]reflow.Profile, 0, len(inspectDigests))
-	)
-	_ = traverse.Each(len(inspectDigests), func(i int) error {
-		if inspectDigests[i].IsZero() {
-			panic(fmt.Sprintf("unexpectedly got nil digest"))
-		}
-		rc, err := repo.Get(ctx, inspectDigests[i])
-		if err != nil {
-			return nil
-		}
-		defer rc.Close()
+	return inspectDigests
+}
 
-		if err := p.inspectLimiter.Acquire(ctx, 1); err != nil {
-			return nil
-		}
-		var si smallInspect
-		err = json.NewDecoder(rc).Decode(&si)
-		p.inspectLimiter.Release(1)
-		if err != nil {
-			return nil
-		}
+// decodeInspect decodes the ExecInspect with the given digest into v,
+// subject to the predictor's inspect limiter.
+func (p *Predictor) decodeInspect(ctx context.Context, d digest.Digest, v interface{}) error {
+	if d.IsZero() {
+		panic(fmt.Sprintf("unexpectedly got nil digest"))
+	}
+	rc, err := p.taskDB.Repository().Get(ctx, d)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
 
-		// Only use profiles with memory data to make memory predictions.
-		if _, ok := si.Profile["mem"]; ok && si.Error == nil && si.ExecError == nil {
-			mu.Lock()
-			profiles = append(profiles, si.Profile)
-			mu.Unlock()
-		}
-		return nil
-	})
-	return profiles, nil
+	if err := p.inspectLimiter.Acquire(ctx, 1); err != nil {
+		return err
+	}
+	defer p.inspectLimiter.Release(1)
+	return json.NewDecoder(rc).Decode(v)
 }
 
 type extractFunc func(reflow.Profile) (float64, bool)
 
 var (
-	extractFuncs = map[string]extractFunc{"mem": memMaxGetter, "duration": maxDurationGetter}
+	extractFuncs = map[string]extractFunc{
+		"mem":      memMaxGetter,
+		"cpu":      cpuMaxGetter,
+		"disk":     diskMaxGetter,
+		"duration": maxDurationGetter,
+	}
 	// memMaxGetter gets the max value of "mem" resource from the given profile.
 	memMaxGetter = func(rp reflow.Profile) (float64, bool) {
 		if v, ok := rp["mem"]; !ok || v.Max < 0 || v.Max > float64(maxMemThreshold) {
@@ -358,14 +409,35 @@ var (
 		}
 	}
 
+	// cpuMaxGetter gets the max value of "cpu" resource (ie, the peak load) from the given profile.
+	cpuMaxGetter = func(rp reflow.Profile) (float64, bool) {
+		if v, ok := rp["cpu"]; !ok || v.Max <= 0 {
+			return 0.0, false
+		} else {
+			return v.Max, true
+		}
+	}
+
+	// diskMaxGetter gets the max scratch disk usage from the given profile, which is the
+	// sum of the max values of the "disk" (exec's outputs) and "tmp" resources.
+	diskMaxGetter = func(rp reflow.Profile) (float64, bool) {
+		disk, dok := rp["disk"]
+		tmp, tok := rp["tmp"]
+		if !dok && !tok || disk.Max < 0 || tmp.Max < 0 {
+			return 0.0, false
+		}
+		return disk.Max + tmp.Max, true
+	}
+
 	// maxDurationGetter gets the max duration (in nanoseconds) across all resources from the given profile.
+	// The image pull, which precedes the exec's run, is not counted.
 	maxDurationGetter = func(rp reflow.Profile) (float64, bool) {
 		var (
 			dur   time.Duration
 			valid bool
 		)
-		for _, v := range rp {
-			if v.First.IsZero() || v.Last.IsZero() {
+		for k, v := range rp {
+			if k == reflow.ImagePullProfile || v.First.IsZero() || v.Last.IsZero() {
 				continue
 			}
 			d := v.Last.Sub(v.First)
@@ -398,6 +470,24 @@ func (p *Predictor) memUsage(profiles []reflow.Profile) (float64, error) {
 	return pv, nil
 }
 
+// cpuUsage returns the predicted cpu usage (in number of cores, rounded up) from the given profiles.
+func (p *Predictor) cpuUsage(profiles []reflow.Profile) (float64, error) {
+	pv, n := valuePercentile(profiles, p.cpuPercentile, cpuMaxGetter)
+	if n < p.minData {
+		return 0, fmt.Errorf("insufficient profiles (%d < %d)", n, p.minData)
+	}
+	return math.Ceil(pv), nil
+}
+
+// diskUsage returns the predicted scratch disk usage (in bytes) from the given profiles.
+func (p *Predictor) diskUsage(profiles []reflow.Profile) (float64, error) {
+	pv, n := valuePercentile(profiles, p.diskPercentile, diskMaxGetter)
+	if n < p.minData {
+		return 0, fmt.Errorf("insufficient profiles (%d < %d)", n, p.minData)
+	}
+	return pv, nil
+}
+
 // groupByLevel maps all tasks by their respective taskGroups at the specified level.
 // groupByLevel assumes all tasks have the same number of taskGroups.
 func groupByLevel(tasks []*sched.Task, level int) map[taskGroup][]*sched.Task {
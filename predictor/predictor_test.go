@@ -29,11 +29,13 @@ import (
 )
 
 const (
-	defaultMinData       = 20
-	defaultMaxInspect    = 50
-	defaultMemPercentile = 95
-	numTasks             = 20
-	nPredictCalls        = 10
+	defaultMinData        = 20
+	defaultMaxInspect     = 50
+	defaultMemPercentile  = 95
+	defaultCPUPercentile  = 95
+	defaultDiskPercentile = 95
+	numTasks              = 20
+	nPredictCalls         = 10
 )
 
 type mockdb struct {
@@ -140,6 +142,15 @@ func generateTasks(image, cmd, ident string, usedResource float64) (taskdb.Task,
 			"mem": {
 				Max: usedResource,
 			},
+			"cpu": {
+				Max: usedResource - 0.5,
+			},
+			"disk": {
+				Max: usedResource,
+			},
+			"tmp": {
+				Max: usedResource,
+			},
 		},
 	}
 	b, _ := json.Marshal(execInspect)
@@ -222,7 +233,7 @@ func TestNewPred(t *testing.T) {
 		{newMockdb(newMockRepo()), logger, 1, 2, -1},
 	} {
 		defer r(tt.tdb, tt.minData, tt.maxInspect, tt.memPercentile)
-		_ = New(tt.tdb, tt.log, tt.minData, tt.maxInspect, tt.memPercentile)
+		_ = New(tt.tdb, tt.log, tt.minData, tt.maxInspect, tt.memPercentile, defaultCPUPercentile, defaultDiskPercentile)
 	}
 }
 
@@ -235,7 +246,7 @@ func TestPredictImgCmdID(t *testing.T) {
 	)
 	tasks, _ := generateData(t, ctx, repo, tdb, 0)
 
-	pred := New(tdb, nil, defaultMinData, defaultMaxInspect, defaultMemPercentile)
+	pred := New(tdb, nil, defaultMinData, defaultMaxInspect, defaultMemPercentile, defaultCPUPercentile, defaultDiskPercentile)
 	pred.cacheTtl = cacheTtl
 
 	for i := 0; i < numTasks; i++ {
@@ -257,6 +268,13 @@ func TestPredictImgCmdID(t *testing.T) {
 			if got, want := p.Resources["mem"], float64(19); got != want {
 				t.Errorf("mem: got %v, want %v", got, want)
 			}
+			// cpu predictions are rounded up to whole cores.
+			if got, want := p.Resources["cpu"], float64(19); got != want {
+				t.Errorf("cpu: got %v, want %v", got, want)
+			}
+			if got, want := p.Resources["disk"], float64(38); got != want {
+				t.Errorf("disk: got %v, want %v", got, want)
+			}
 		}
 		return nil
 	})
@@ -276,7 +294,7 @@ func TestPredictIdent(t *testing.T) {
 	)
 	tasks, _ := generateData(t, ctx, repo, tdb, 1)
 
-	pred := New(tdb, nil, defaultMinData, defaultMaxInspect, defaultMemPercentile)
+	pred := New(tdb, nil, defaultMinData, defaultMaxInspect, defaultMemPercentile, defaultCPUPercentile, defaultDiskPercentile)
 
 	for i := 0; i < numTasks; i++ {
 		if got, want := tasks[i].Config.Resources["mem"], float64(20); got != want {
@@ -294,6 +312,13 @@ func TestPredictIdent(t *testing.T) {
 			if got, want := p.Resources["mem"], float64(19); got != want {
 				t.Errorf("mem: got %v, want %v", got, want)
 			}
+			// cpu predictions are rounded up to whole cores.
+			if got, want := p.Resources["cpu"], float64(19); got != want {
+				t.Errorf("cpu: got %v, want %v", got, want)
+			}
+			if got, want := p.Resources["disk"], float64(38); got != want {
+				t.Errorf("disk: got %v, want %v", got, want)
+			}
 		}
 		return nil
 	})
@@ -328,7 +353,7 @@ func TestPredictMultiGroup(t *testing.T) {
 
 	// Since minData is set to 1 and each task has a unique imgCmdID, there will be 20
 	// imgCmdIDs and 20 unique predictions (1, 2, 3..., 20).
-	pred := New(tdb, nil, 1, defaultMaxInspect, defaultMemPercentile)
+	pred := New(tdb, nil, 1, defaultMaxInspect, defaultMemPercentile, defaultCPUPercentile, defaultDiskPercentile)
 
 	for i := 0; i < numTasks; i++ {
 		if got, want := tasks[i].Config.Resources["mem"], float64(20); got != want {
@@ -369,6 +394,57 @@ func TestPredictMultiGroup(t *testing.T) {
 	}
 }
 
+func TestPredictOnline(t *testing.T) {
+	var (
+		repo  = newMockRepo()
+		tdb   = newMockdb(repo)
+		ctx   = context.Background()
+		tasks []*sched.Task
+	)
+	for i := 0; i < 4; i++ {
+		tdbTask, task, inspect := generateTasks("img", "cmd", "ident", float64(i+1))
+		task.RunInfo = inspect.RunInfo()
+		tasks = append(tasks, task)
+		if i > 0 {
+			continue
+		}
+		// Only the first task is (already) recorded in taskdb.
+		tdb.Add(tdbTask.ImgCmdID.ID(), tdbTask)
+		tdb.Add(tdbTask.Ident, tdbTask)
+		b, err := json.Marshal(inspect)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := repo.Put(ctx, bytes.NewReader(b)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pred := New(tdb, nil, 3, defaultMaxInspect, 100, 100, 100)
+
+	// Observations are ignored unless the predictor is online.
+	pred.Observe(tasks[1])
+	pred.Observe(tasks[2])
+	if got, want := len(pred.Predict(ctx, tasks[3])), 0; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	pred.Online = true
+	pred.Observe(tasks[0])
+	pred.Observe(tasks[1])
+	// The first task is both in taskdb and observed, and is counted only once.
+	if got, want := len(pred.Predict(ctx, tasks[3])), 0; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	pred.Observe(tasks[2])
+	predictions := pred.Predict(ctx, tasks[3])
+	if got, want := len(predictions), 1; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got, want := predictions[tasks[3]].Resources["mem"], float64(3); got != want {
+		t.Errorf("mem: got %v, want %v", got, want)
+	}
+}
+
 func generateProfiles(memVals []float64) []reflow.Profile {
 	profiles := make([]reflow.Profile, numTasks)
 	for i := 0; i < len(memVals); i++ {
@@ -390,7 +466,7 @@ func TestMemUsage(t *testing.T) {
 		{5, 50, generateProfiles([]float64{1, 2, 3, 4}), 0.0, true},
 		{5, 50, generateProfiles([]float64{1, 2, 3, 4, 5}), 3.0, false},
 	} {
-		pred := New(newMockdb(newMockRepo()), nil, tc.minData, defaultMaxInspect, float64(tc.memPct))
+		pred := New(newMockdb(newMockRepo()), nil, tc.minData, defaultMaxInspect, float64(tc.memPct), defaultCPUPercentile, defaultDiskPercentile)
 		mem, err := pred.memUsage(tc.profiles)
 		if (err != nil) != tc.wantErr {
 			t.Errorf("got %v, want error %v", err, tc.wantErr)
@@ -404,6 +480,52 @@ func TestMemUsage(t *testing.T) {
 	}
 }
 
+func TestCPUDiskUsage(t *testing.T) {
+	profiles := make([]reflow.Profile, 0, numTasks)
+	for i := 0; i < numTasks; i++ {
+		profiles = append(profiles, reflow.Profile{
+			"mem":  {Max: 10},
+			"cpu":  {Max: float64(i+1) / 10},
+			"disk": {Max: float64(i + 1)},
+			"tmp":  {Max: 100},
+		})
+	}
+	pred := New(newMockdb(newMockRepo()), nil, 5, defaultMaxInspect, defaultMemPercentile, 50, 100)
+	// 50th percentile of 0.1, 0.2, ..., 2.0 is 1.0.
+	if cpu, err := pred.cpuUsage(profiles); err != nil {
+		t.Error(err)
+	} else if got, want := cpu, 1.0; got != want {
+		t.Errorf("cpu: got %v, want %v", got, want)
+	}
+	if disk, err := pred.diskUsage(profiles); err != nil {
+		t.Error(err)
+	} else if got, want := disk, 120.0; got != want {
+		t.Errorf("disk: got %v, want %v", got, want)
+	}
+	// Profiles without cpu or disk data are ignored.
+	if _, err := pred.cpuUsage(generateProfiles([]float64{1, 2, 3, 4, 5})); err == nil {
+		t.Error("expected error")
+	}
+	if _, err := pred.diskUsage(generateProfiles([]float64{1, 2, 3, 4, 5})); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestDurationExcludesImagePull(t *testing.T) {
+	start := time.Now()
+	profile := reflow.Profile{
+		"mem":                   {Max: 10, First: start.Add(10 * time.Minute), Last: start.Add(12 * time.Minute)},
+		reflow.ImagePullProfile: {Max: 600, Mean: 600, N: 1, First: start, Last: start.Add(10 * time.Minute)},
+	}
+	dur, ok := maxDurationGetter(profile)
+	if !ok {
+		t.Fatal("expected a duration")
+	}
+	if got, want := time.Duration(dur), 2*time.Minute; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
 func TestGetProfilesInspectErrors(t *testing.T) {
 	var (
 		repo = newMockRepo()
@@ -411,7 +533,7 @@ func TestGetProfilesInspectErrors(t *testing.T) {
 		ctx  = context.Background()
 	)
 
-	pred := New(tdb, nil, defaultMinData, defaultMaxInspect, defaultMemPercentile)
+	pred := New(tdb, nil, defaultMinData, defaultMaxInspect, defaultMemPercentile, defaultCPUPercentile, defaultDiskPercentile)
 
 	tasks, group := generateData(t, ctx, repo, tdb, 0)
 	for i := 0; i < numTasks; i++ {
@@ -607,3 +729,50 @@ func TestValuePercentile(t *testing.T) {
 		_, _ = valuePercentile(testData, p, memMaxGetter)
 	}
 }
+
+func TestShardSize(t *testing.T) {
+	var (
+		ctx  = context.Background()
+		repo = newMockRepo()
+		tdb  = newMockdb(repo)
+		pred = New(tdb, nil, 5, defaultMaxInspect, defaultMemPercentile, defaultCPUPercentile, defaultDiskPercentile)
+	)
+	if _, err := pred.ShardSize(ctx, "shard", time.Hour); err == nil {
+		t.Error("expected error")
+	}
+	// Task i processes 10 files in i minutes.
+	start := time.Now().Add(-time.Hour)
+	for i := 1; i <= 10; i++ {
+		tdbTask, _, inspect := generateTasks("img", "cmd", "shard", float64(i))
+		fs := reflow.Fileset{Map: make(map[string]reflow.File)}
+		for j := 0; j < 10; j++ {
+			fs.Map[strconv.Itoa(j)] = reflow.File{ID: reflow.Digester.Rand(nil)}
+		}
+		inspect.Config.Args = []reflow.Arg{{Fileset: &fs}}
+		mem := inspect.Profile["mem"]
+		mem.First, mem.Last = start, start.Add(time.Duration(i)*time.Minute)
+		inspect.Profile["mem"] = mem
+		b, err := json.Marshal(inspect)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tdbTask.Inspect, err = repo.Put(ctx, bytes.NewReader(b)); err != nil {
+			t.Fatal(err)
+		}
+		tdb.Add(tdbTask.Ident, tdbTask)
+	}
+	// The 90th percentile of per-file processing times is 54s.
+	size, err := pred.ShardSize(ctx, "shard", 30*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := size, 33; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if size, err = pred.ShardSize(ctx, "shard", time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := size, 1; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package predictor
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/grailbio/base/traverse"
+	"github.com/grailbio/reflow"
+	"github.com/grailbio/reflow/errors"
+)
+
+// shardPercentile is the percentile of the historical per-item
+// processing times used to size shards. A high percentile is used
+// so that shards rarely exceed the target duration.
+const shardPercentile = 90
+
+// shardInspect is used to exclusively unmarshal
+// the Profile and the arguments from an ExecInspect.
+type shardInspect struct {
+	Config struct {
+		Args []reflow.Arg
+	}
+	Profile   reflow.Profile
+	Error     *errors.Error
+	ExecError *errors.Error
+}
+
+// items returns the number of input files of the inspected exec.
+func (si shardInspect) items() int {
+	var n int
+	for _, arg := range si.Config.Args {
+		if arg.Out || arg.Fileset == nil {
+			continue
+		}
+		n += arg.Fileset.N()
+	}
+	return n
+}
+
+// ShardSize returns the number of items (input files) which an exec
+// with the given ident should process, so that it is expected to take
+// about the target duration. The per-item processing time is estimated
+// from the profiles and inputs of previously completed execs with the
+// same ident. ShardSize returns an error if there is insufficient data
+// to make such an estimate.
+func (p *Predictor) ShardSize(ctx context.Context, ident string, target time.Duration) (int, error) {
+	if target <= 0 {
+		return 0, errors.E("shardsize", ident, errors.Invalid, fmt.Errorf("invalid target duration %s", target))
+	}
+	group := identGroup{ident: ident}
+	tasks, err := p.taskDB.Tasks(ctx, group.Query())
+	if err != nil {
+		return 0, errors.E("shardsize", group.Name(), "taskdb query", err)
+	}
+	ended := tasks[:0]
+	for _, task := range tasks {
+		if !task.End.IsZero() {
+			ended = append(ended, task)
+		}
+	}
+	inspectDigests := p.recentInspects(ctx, ended, p.maxInspect)
+	var (
+		mu       sync.Mutex
+		perItems = make([]float64, 0, len(inspectDigests))
+	)
+	_ = traverse.Each(len(inspectDigests), func(i int) error {
+		var si shardInspect
+		if err := p.decodeInspect(ctx, inspectDigests[i], &si); err != nil {
+			return nil
+		}
+		if si.Error != nil || si.ExecError != nil {
+			return nil
+		}
+		n := si.items()
+		dur, ok := maxDurationGetter(si.Profile)
+		if n == 0 || !ok || dur <= 0 {
+			return nil
+		}
+		mu.Lock()
+		perItems = append(perItems, dur/float64(n))
+		mu.Unlock()
+		return nil
+	})
+	if len(perItems) < p.minData {
+		return 0, errors.E("shardsize", group.Name(), fmt.Errorf("insufficient profiles (%d < %d)", len(perItems), p.minData))
+	}
+	sort.Float64s(perItems)
+	idx := int(math.Ceil(float64(len(perItems))*shardPercentile/100 - 1))
+	size := int(float64(target.Nanoseconds()) / perItems[idx])
+	if size < 1 {
+		size = 1
+	}
+	p.log.Debugf("%s: shard size %d for target duration %s (%s per item)", group.Name(), size, target, time.Duration(perItems[idx]))
+	return size, nil
+}
@@ -9,11 +9,13 @@ import (
 	"crypto/tls"
 	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"math"
 	"net/http"
 	"os"
 	"os/signal"
+	"path"
 	"path/filepath"
 	"strings"
 	"sync"
@@ -31,23 +33,25 @@ import (
 	infratls "github.com/grailbio/infra/tls"
 	"github.com/grailbio/reflow"
 	"github.com/grailbio/reflow/blob"
-	"github.com/grailbio/reflow/blob/s3blob"
 	"github.com/grailbio/reflow/ec2authenticator"
 	"github.com/grailbio/reflow/ec2cluster"
 	"github.com/grailbio/reflow/ec2cluster/instances"
 	"github.com/grailbio/reflow/ec2cluster/volume"
 	infra2 "github.com/grailbio/reflow/infra"
+	"github.com/grailbio/reflow/internal/ecrauth"
 	"github.com/grailbio/reflow/local"
 	"github.com/grailbio/reflow/log"
 	"github.com/grailbio/reflow/metrics"
 	"github.com/grailbio/reflow/metrics/monitoring"
 	"github.com/grailbio/reflow/metrics/prometrics"
+	"github.com/grailbio/reflow/pool"
 	"github.com/grailbio/reflow/pool/server"
 	"github.com/grailbio/reflow/repository/blobrepo"
 	repositoryhttp "github.com/grailbio/reflow/repository/http"
 	"github.com/grailbio/reflow/rest"
 	"github.com/grailbio/reflow/taskdb"
 	"golang.org/x/net/http2"
+	"golang.org/x/time/rate"
 )
 
 // maxConcurrentStreams is the number of concurrent http/2 streams we
@@ -76,6 +80,27 @@ type Server struct {
 	// HTTPDebug determines whether HTTP debug logging is turned on.
 	HTTPDebug bool
 
+	// Logs is the URL of a blob store prefix (e.g., s3://bucket/logs)
+	// to which the reflowlet ships its own logs and those of its execs,
+	// so that they survive the termination of its instance. If empty,
+	// exec logs are shipped to CloudWatch Logs.
+	Logs string
+
+	// DockerCacheSeed is the URL of a blob (e.g., s3://bucket/images.tar)
+	// holding a tarball of images, as produced by "docker save", with
+	// which the reflowlet seeds the docker layer cache of its instance.
+	DockerCacheSeed string
+
+	// RateLimit is the rate (in calls per second) at which each client
+	// may call the reflowlet's API, with bursts of up to RateLimitBurst
+	// calls, so that buggy clients cannot overwhelm a shared cluster.
+	// If zero, calls are not limited.
+	RateLimit      float64
+	RateLimitBurst int
+	// Audit tells whether to log each call to the reflowlet's API:
+	// the client which made it, what it called, and its outcome.
+	Audit bool
+
 	// NodeExporterMetricsPort determines whether to run a prometheus node_exporter daemon
 	// on each Reflowlet. Setting a value runs the node_exporter daemon and configures it to
 	// output prometheus metrics on the given port. Passing a non-zero value also adds an
@@ -116,6 +141,11 @@ func (s *Server) AddFlags(flags *flag.FlagSet) {
 	flags.StringVar(&s.Dir, "dir", "/mnt/data/reflow", "runtime data directory")
 	flags.BoolVar(&s.EC2Cluster, "ec2cluster", false, "this reflowlet is part of an ec2cluster")
 	flags.BoolVar(&s.HTTPDebug, "httpdebug", false, "turn on HTTP debug logging")
+	flags.StringVar(&s.Logs, "logs", "", "ship reflowlet and exec logs to this blob store prefix (e.g., s3://bucket/logs)")
+	flags.StringVar(&s.DockerCacheSeed, "dockercacheseed", "", "seed the docker layer cache with the images in this tarball (e.g., s3://bucket/images.tar)")
+	flags.Float64Var(&s.RateLimit, "ratelimit", 500, "limit each client to this many API calls per second (0: unlimited)")
+	flags.IntVar(&s.RateLimitBurst, "ratelimitburst", 1000, "allow each client bursts of up to this many API calls")
+	flags.BoolVar(&s.Audit, "audit", true, "log each API call, with its client and outcome")
 }
 
 // spotNoticeWatcher watches for a spot termination notice and logs if found.
@@ -150,6 +180,20 @@ func (s *Server) spotNoticeWatcher(ctx context.Context) {
 	}
 }
 
+// dockerSocket returns the default path of the API socket of the
+// daemon for the given container runtime. Rootless Podman serves
+// its (Docker-compatible) API from the user's runtime directory.
+func dockerSocket(runtime string) string {
+	if runtime != local.RuntimePodman {
+		return "/var/run/docker.sock"
+	}
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		dir = fmt.Sprintf("/run/user/%d", os.Getuid())
+	}
+	return filepath.Join(dir, "podman", "podman.sock")
+}
+
 // setTags sets the reflowlet version tag on the EC2 instance (if running on one).
 func (s *Server) setTags(sess *session.Session) error {
 	if !s.EC2Cluster {
@@ -183,6 +227,55 @@ func (s *Server) getVolumeWatcher(sess *session.Session, path string, vw infra2.
 	return volume.NewWatcher(v, vw, logger)
 }
 
+// shipLogs tees the reflowlet's logs to a log stream in the pool's
+// logs destination, named by the reflowlet's instance ID (or host
+// name), if one is configured. It returns a function which flushes
+// and closes the stream.
+func (s *Server) shipLogs(p *local.Pool) (done func()) {
+	name := s.ec2Identity.InstanceID
+	if !s.EC2Cluster {
+		name, _ = os.Hostname()
+	}
+	w, done := p.NewLogStream(path.Join("reflowlet", name, "stderr"))
+	if w == nil {
+		return done
+	}
+	out, ok := log.Std.Outputter.(interface{ SetOutput(io.Writer) })
+	if !ok {
+		log.Errorf("cannot ship logs to %s: unsupported log outputter %T", s.Logs, log.Std.Outputter)
+		return done
+	}
+	out.SetOutput(io.MultiWriter(os.Stderr, w))
+	return done
+}
+
+// dockerCacheSeedMarker is the name of the file, in the reflowlet's
+// data directory, which records the docker cache seed loaded on the
+// instance.
+const dockerCacheSeedMarker = "dockercacheseed"
+
+// seedDockerCache loads the images in the tarball s.DockerCacheSeed
+// into the docker daemon of client, unless they were already loaded on
+// the instance (e.g., by a previous reflowlet), as recorded by a
+// marker file in the reflowlet's data directory.
+func (s *Server) seedDockerCache(ctx context.Context, client *docker.Client, mux blob.Mux, logger *log.Logger) error {
+	marker := filepath.Join(s.Prefix, s.Dir, dockerCacheSeedMarker)
+	if b, err := ioutil.ReadFile(marker); err == nil && string(b) == s.DockerCacheSeed {
+		return nil
+	}
+	rc, _, err := mux.Get(ctx, s.DockerCacheSeed, "")
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	start := time.Now()
+	if err = local.LoadImages(ctx, client, rc); err != nil {
+		return err
+	}
+	logger.Printf("loaded %s in %s", s.DockerCacheSeed, time.Since(start))
+	return ioutil.WriteFile(marker, []byte(s.DockerCacheSeed), 0644)
+}
+
 // loopUtilIdle loops forever while the given pool is in use; if the pool is idle for long enough it returns.
 func (s *Server) loopUntilIdle(p *local.Pool, rc *infra2.ReflowletConfig, logger *log.Logger) {
 	// Always give the instance an expiry period to receive work,
@@ -209,9 +302,16 @@ func (s *Server) ListenAndServe() error {
 		time.Sleep(time.Second + ec2cluster.ReflowletCloudwatchFlushMs * time.Millisecond)
 	}()
 
+	var rc *infra2.ReflowletConfig
+	if err := s.Config.Instance(&rc); err != nil {
+		return err
+	}
+	socket := dockerSocket(rc.Runtime)
 	addr := os.Getenv("DOCKER_HOST")
 	if addr == "" {
-		addr = "unix:///var/run/docker.sock"
+		addr = "unix://" + socket
+	} else if strings.HasPrefix(addr, "unix://") {
+		socket = strings.TrimPrefix(addr, "unix://")
 	}
 	client, err := docker.NewClient(
 		addr, "1.22",
@@ -219,10 +319,6 @@ func (s *Server) ListenAndServe() error {
 	if err != nil {
 		return err
 	}
-	var rc *infra2.ReflowletConfig
-	if err = s.Config.Instance(&rc); err != nil {
-		return err
-	}
 	var sess *session.Session
 	if err = s.Config.Instance(&sess); err != nil {
 		return err
@@ -252,11 +348,17 @@ func (s *Server) ListenAndServe() error {
 	var (
 		dockerconfig *infra2.DockerConfig
 		hardMemLimit bool
+		strictLimits bool
 	)
 	if err = s.Config.Instance(&dockerconfig); err != nil {
 		return err
-	} else if dockerconfig.Value() == "hard" {
+	}
+	switch dockerconfig.Value() {
+	case "hard":
+		hardMemLimit = true
+	case "strict":
 		hardMemLimit = true
+		strictLimits = true
 	}
 
 	if err = s.setTags(sess); err != nil {
@@ -267,6 +369,7 @@ func (s *Server) ListenAndServe() error {
 		tdb    taskdb.TaskDB
 		poolId reflow.StringDigest
 		expectedUsableMemBytes int64
+		features               []string
 	)
 	if s.EC2Cluster {
 		if err = s.Config.Instance(&tdb); err != nil {
@@ -278,33 +381,70 @@ func (s *Server) ListenAndServe() error {
 			log.Debugf("WARNING: using an unverified instance type: %s", s.ec2Identity.InstanceType)
 		}
 		expectedUsableMemBytes = verifiedStatus.ExpectedMemoryBytes()
+		// Instances which cannot be reclaimed present on-demand capacity.
+		// If the lifecycle cannot be determined, we conservatively assume
+		// that the instance is preemptible.
+		md := ec2metadata.New(sess, &aws.Config{MaxRetries: aws.Int(3)})
+		switch lifecycle, lerr := md.GetMetadata("instance-life-cycle"); {
+		case lerr != nil:
+			log.Printf("instance lifecycle: %v", lerr)
+		case lifecycle != "spot":
+			features = append(features, reflow.OnDemand)
+		}
 	}
 
 	// Default HTTPS and s3 clients for repository dialers.
 	// TODO(marius): handle this more elegantly, perhaps by
 	// avoiding global registration altogether.
-	blobrepo.Register("s3", s3blob.New(sess))
+	// Sharded buckets are routed to the shard in the instance's region.
+	s3store := infra2.S3Store(s.Config, sess)
+	if s.EC2Cluster {
+		s3store.Region = s.ec2Identity.Region
+	}
+	// Checkpoint large transfers (including those between repositories),
+	// so that they resume if the reflowlet is restarted, eg. when it is
+	// upgraded.
+	s3store.CheckpointDir = filepath.Join(s.Prefix, s.Dir, "checkpoints")
+	blobrepo.Register("s3", s3store)
 	transport := &http.Transport{TLSClientConfig: clientConfig}
 	if err = http2.ConfigureTransport(transport); err != nil {
 		return err
 	}
 	repositoryhttp.HTTPClient = &http.Client{Transport: transport}
+	blobMux := infra2.ReflowletBlobMux(s.Config, sess)
+	blobMux["s3"] = s3store
+	// Images are pulled from ECR and from the registries configured
+	// with credentials, so that a run may use images from several
+	// registries.
+	secrets := local.NewAWSSecrets(sess)
+	auth, err := ecrauth.FromConfig(s.Config, ec2authenticator.New(sess), secrets.Secret)
+	if err != nil {
+		return err
+	}
 	p := &local.Pool{
 		Client:        client,
+		Runtime:       rc.Runtime,
+		DockerSocket:  socket,
 		Dir:           s.Dir,
 		Prefix:        s.Prefix,
-		Authenticator: ec2authenticator.New(sess),
+		Authenticator: auth,
 		AWSCreds:      creds,
 		Session:       sess,
-		Blob:          blob.Mux{"s3": s3blob.New(sess)},
+		Secrets:       secrets,
+		Blob:          blobMux,
+		Logs:          s.Logs,
 		TaskDBPoolId:  poolId,
 		TaskDB:        tdb,
 		Log:           log.Std.Tee(nil, "executor: "),
 		HardMemLimit:  hardMemLimit,
+		StrictLimits:  strictLimits,
+		Features:      features,
 	}
 	if err = p.Start(expectedUsableMemBytes); err != nil {
 		return err
 	}
+	closeLogs := s.shipLogs(p)
+	defer closeLogs()
 
 	var (
 		logType = "local"
@@ -346,6 +486,17 @@ func (s *Server) ListenAndServe() error {
 		}()
 	}
 
+	if s.DockerCacheSeed != "" {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			logger := reflowletLog.Tee(nil, "docker cache: ")
+			if err := s.seedDockerCache(ctx, client, blobMux, logger); err != nil {
+				logger.Errorf("seed %s: %v", s.DockerCacheSeed, err)
+			}
+		}()
+	}
+
 	// Start periodic stats logging
 	wg.Add(1)
 	go func() {
@@ -353,12 +504,25 @@ func (s *Server) ListenAndServe() error {
 		logStats(ctx, p, reflowletLog.Tee(nil, "stats: "), rc.LogStatsDuration)
 	}()
 
+	// Apply the stale alloc policy.
+	stalePolicy, err := pool.ParseStalePolicy(rc.StalePolicy)
+	if err != nil {
+		cancel()
+		return err
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		reapStale(ctx, p, stalePolicy, rc.StaleGrace, reflowletLog.Tee(nil, "stale allocs: "))
+	}()
+
 	// Start the loop to exit reflowlet if idle.
 	go func() {
 		s.loopUntilIdle(p, rc, reflowletLog)
 		// Cancel and wait for other goroutines
 		cancel()
 		wg.Wait()
+		closeLogs()
 		// Exit normally
 		os.Exit(0)
 	}()
@@ -377,6 +541,11 @@ func (s *Server) ListenAndServe() error {
 		return fmt.Errorf("read config: %v", err)
 	}
 	http.Handle("/v1/config", rest.DoFuncHandler(cfgNode, httpLog))
+	if s.EC2Cluster {
+		// Serve the instance's identity, so that clients can verify it
+		// after re-resolving the instance's (changed) address.
+		http.Handle("/v1/identity", rest.DoFuncHandler(newIdentityNode(s.ec2Identity.InstanceID), httpLog))
+	}
 	if s.NodeExporterMetricsPort != 0 {
 		url, proxyPath := fmt.Sprintf("http://localhost:%d/metrics", s.NodeExporterMetricsPort), "/v1/node/metrics"
 		http.Handle(proxyPath, rest.DoProxyHandler(url, httpLog))
@@ -410,7 +579,12 @@ func (s *Server) ListenAndServe() error {
 		}()
 	}
 
-	s.server = &http.Server{Addr: s.Addr}
+	var auditLog *log.Logger
+	if s.Audit {
+		auditLog = reflowletLog.Tee(nil, "audit: ")
+	}
+	guard := server.NewGuard(http.DefaultServeMux, rate.Limit(s.RateLimit), s.RateLimitBurst, auditLog)
+	s.server = &http.Server{Addr: s.Addr, Handler: guard}
 	if s.Insecure {
 		return s.server.ListenAndServe()
 	}
@@ -458,6 +632,44 @@ func newConfigNode(cfg infra.Config) (rest.DoFunc, error) {
 	}, nil
 }
 
+// newIdentityNode returns a servlet node which serves the given
+// instance ID.
+func newIdentityNode(instanceID string) rest.DoFunc {
+	return func(ctx context.Context, call *rest.Call) {
+		if !call.Allow("GET") {
+			return
+		}
+		call.Reply(http.StatusOK, instanceID)
+	}
+}
+
+// reapStale periodically applies the given policy to the pool's
+// stale allocs (see pool.StalePolicy), and logs them, until ctx is done.
+func reapStale(ctx context.Context, p *local.Pool, policy pool.StalePolicy, grace time.Duration, log *log.Logger) {
+	iter := time.NewTicker(time.Minute)
+	defer iter.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-iter.C:
+		}
+		for _, a := range p.ReapStale(ctx, policy, grace) {
+			log.Printf("alloc %s (owner %s) is stale (policy %s)", a.ID(), allocOwner(ctx, a), policy)
+		}
+	}
+}
+
+// allocOwner returns the owner of the given alloc, or "unknown" if it
+// cannot be inspected.
+func allocOwner(ctx context.Context, a pool.Alloc) string {
+	inspect, err := a.Inspect(ctx)
+	if err != nil || inspect.Meta.Owner == "" {
+		return "unknown"
+	}
+	return inspect.Meta.Owner
+}
+
 // logStats logs various stats to the given logger every d duration.
 func logStats(ctx context.Context, p *local.Pool, log *log.Logger, d time.Duration) {
 	iter := time.NewTicker(d)
@@ -11,6 +11,7 @@ import (
 	"crypto/rand"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/url"
 	"path"
 	"strings"
@@ -87,6 +88,33 @@ func (r *Repository) Get(ctx context.Context, id digest.Digest) (io.ReadCloser,
 	return rc, err
 }
 
+// GetRange retrieves length bytes of the object named by a digest,
+// starting at the given offset. It implements repository.RangeGetter.
+// If the repository's bucket does not support range reads, the object
+// is read from its start, skipping the bytes before the range.
+func (r *Repository) GetRange(ctx context.Context, id digest.Digest, offset, length int64) (io.ReadCloser, error) {
+	id, err := r.resolve(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	key := path.Join(r.Prefix, objectsPath, id.String())
+	if rg, ok := r.Bucket.(blob.RangeGetter); ok {
+		return rg.GetRange(ctx, key, "", offset, length)
+	}
+	rc, _, err := r.Bucket.Get(ctx, key, "")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.CopyN(ioutil.Discard, rc, offset); err != nil {
+		rc.Close()
+		return nil, errors.E("getrange", r.URL().String(), id, err)
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(rc, length), rc}, nil
+}
+
 // GetFile retrieves an object from the repository directly to the a io.WriterAt.
 // This uses the S3 download manager to download chunks concurrently.
 func (r *Repository) GetFile(ctx context.Context, id digest.Digest, w io.WriterAt) (int64, error) {
@@ -7,11 +7,15 @@ package blobrepo
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"io/ioutil"
 	"os"
+	"sync/atomic"
 	"testing"
 
+	"github.com/grailbio/base/digest"
 	"github.com/grailbio/reflow"
+	"github.com/grailbio/reflow/blob"
 	"github.com/grailbio/reflow/blob/testblob"
 )
 
@@ -116,3 +120,88 @@ func TestAbbrevGet(t *testing.T) {
 		t.Errorf("got %v, want %v", got, want)
 	}
 }
+
+func TestGetRange(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepository(t)
+	const content = "hello, world"
+	id, err := r.Put(ctx, bytes.NewReader([]byte(content)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Both ranged buckets and those which must be read from the start.
+	for _, r := range []*Repository{r, {Bucket: struct{ blob.Bucket }{r.Bucket}}} {
+		rc, err := r.GetRange(ctx, id, 7, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := string(b), "wor"; got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	}
+}
+
+// countingBucket counts the stats and scans of a bucket.
+type countingBucket struct {
+	blob.Bucket
+	files, scans int32
+}
+
+func (b *countingBucket) File(ctx context.Context, key string) (reflow.File, error) {
+	atomic.AddInt32(&b.files, 1)
+	return b.Bucket.File(ctx, key)
+}
+
+func (b *countingBucket) Scan(prefix string) blob.Scanner {
+	atomic.AddInt32(&b.scans, 1)
+	return b.Bucket.Scan(prefix)
+}
+
+func TestExist(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepository(t)
+	var ids []digest.Digest
+	for i := 0; i < 700; i++ {
+		content := fmt.Sprint(i)
+		ids = append(ids, reflow.Digester.FromString(content))
+		if i%7 == 0 {
+			continue
+		}
+		if _, err := r.Put(ctx, bytes.NewReader([]byte(content))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	bucket := &countingBucket{Bucket: r.Bucket}
+	r.Bucket = bucket
+	exists, err := r.Exist(ctx, ids)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range ids {
+		if got, want := exists[i], i%7 != 0; got != want {
+			t.Errorf("%v: got %v, want %v", ids[i], got, want)
+		}
+	}
+	if bucket.scans == 0 {
+		t.Error("no prefixes were listed")
+	}
+	if got, max := int(bucket.files+bucket.scans), len(ids)/2; got > max {
+		t.Errorf("got %v requests, want at most %v", got, max)
+	}
+	// Few objects are statted individually.
+	bucket.files, bucket.scans = 0, 0
+	if exists, err = r.Exist(ctx, ids[:10]); err != nil {
+		t.Fatal(err)
+	}
+	if exists[0] || !exists[1] {
+		t.Errorf("got %v", exists)
+	}
+	if got, want := bucket.files, int32(10); got != want {
+		t.Errorf("got %v stats, want %v", got, want)
+	}
+}
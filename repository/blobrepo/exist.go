@@ -0,0 +1,144 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package blobrepo
+
+import (
+	"context"
+	"path"
+	"sync"
+
+	"github.com/grailbio/base/digest"
+	"github.com/grailbio/base/traverse"
+	"github.com/grailbio/reflow/errors"
+)
+
+const (
+	// existMinGroup is the minimum number of objects sharing a key
+	// prefix whose existence is checked by listing the prefix.
+	existMinGroup = 32
+	// existMaxPrefix is the maximum number of hex digits of the key
+	// prefixes by which objects are grouped.
+	existMaxPrefix = 4
+	// listPageSize is the number of keys returned by one list request
+	// (e.g., S3's ListObjectsV2). Listing a prefix is beneficial as
+	// long as it takes fewer requests than statting its objects.
+	listPageSize = 1000
+	// existConcurrency is the number of concurrent list or stat
+	// requests made by Exist.
+	existConcurrency = 64
+)
+
+// Exist tells which of the objects with the given IDs exist in the
+// repository. The objects are grouped by the prefixes of their keys:
+// the objects of large groups are checked by listing their prefix,
+// which takes far fewer requests than statting each of them; the
+// others are statted. A listing is abandoned, and the objects it has
+// yet to find statted instead, once it has taken as many requests as
+// statting them would have, e.g., because the repository is much
+// larger than the set of objects. Exist implements
+// repository.BatchExister.
+func (r *Repository) Exist(ctx context.Context, ids []digest.Digest) ([]bool, error) {
+	var (
+		exists = make([]bool, len(ids))
+		k      = existPrefixLen(len(ids))
+		groups = make(map[string][]int)
+		stat   []int
+	)
+	for i, id := range ids {
+		if k == 0 || id.IsAbbrev() {
+			stat = append(stat, i)
+			continue
+		}
+		prefix := id.ShortString(k)
+		groups[prefix] = append(groups[prefix], i)
+	}
+	var prefixes []string
+	for prefix, group := range groups {
+		if len(group) < existMinGroup {
+			stat = append(stat, group...)
+			continue
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	var mu sync.Mutex
+	err := traverse.Limit(existConcurrency).Each(len(prefixes), func(j int) error {
+		rest, err := r.listExist(ctx, prefixes[j], ids, groups[prefixes[j]], exists)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		stat = append(stat, rest...)
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = traverse.Limit(existConcurrency).Each(len(stat), func(j int) error {
+		i := stat[j]
+		_, err := r.Stat(ctx, ids[i])
+		switch {
+		case err == nil:
+			exists[i] = true
+		case !errors.Is(errors.NotExist, err):
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return exists, nil
+}
+
+// existPrefixLen returns the number of hex digits of the key prefixes
+// by which n objects are grouped, so that the groups have at least
+// existMinGroup objects on average, or 0 if there are too few objects
+// to group.
+func existPrefixLen(n int) int {
+	var k int
+	for k < existMaxPrefix && n>>(4*(k+1)) >= existMinGroup {
+		k++
+	}
+	return k
+}
+
+// listExist lists the objects whose IDs have the given prefix, marking
+// the objects in group (indices into ids) which exist. It gives up
+// once it has listed more keys than the number of requests it would
+// take to stat the group's objects, and returns the objects of the
+// group which it has yet to find.
+func (r *Repository) listExist(ctx context.Context, prefix string, ids []digest.Digest, group []int, exists []bool) ([]int, error) {
+	want := make(map[string][]int, len(group))
+	for _, i := range group {
+		key := path.Join(r.Prefix, objectsPath, ids[i].String())
+		want[key] = append(want[key], i)
+	}
+	var (
+		scan = r.Bucket.Scan(path.Join(r.Prefix, objectsPath, prefix))
+		max  = len(group) * listPageSize
+		n    int
+	)
+	for len(want) > 0 && n < max && scan.Scan(ctx) {
+		n++
+		key := scan.Key()
+		for _, i := range want[key] {
+			exists[i] = true
+		}
+		delete(want, key)
+	}
+	if err := scan.Err(); err != nil {
+		return nil, errors.E("exist", prefix, err)
+	}
+	if n < max {
+		// The listing is complete: the remaining objects are missing.
+		return nil, nil
+	}
+	var rest []int
+	for _, is := range want {
+		rest = append(rest, is...)
+	}
+	return rest, nil
+}
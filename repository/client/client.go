@@ -7,6 +7,7 @@ package client
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"net/http"
 	"net/url"
@@ -71,6 +72,28 @@ func (c *Client) Get(ctx context.Context, id digest.Digest) (io.ReadCloser, erro
 	return call, nil
 }
 
+// GetRange retrieves length bytes of the object with digest id,
+// starting at the given offset. It implements repository.RangeGetter.
+func (c *Client) GetRange(ctx context.Context, id digest.Digest, offset, length int64) (io.ReadCloser, error) {
+	call := c.Call("GET", "%s", url.PathEscape(id.String()))
+	call.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+	code, err := call.Do(ctx, nil)
+	if err != nil {
+		return nil, errors.E("getrange", id, err)
+	}
+	switch code {
+	case http.StatusPartialContent:
+		return call, nil
+	case http.StatusOK:
+		// The server ignored the range.
+		call.Close()
+		return nil, errors.E("getrange", id, errors.NotSupported, errors.New("server does not support ranges"))
+	default:
+		defer call.Close()
+		return nil, call.Error()
+	}
+}
+
 // Put writes the object in body to the repository.
 func (c *Client) Put(ctx context.Context, body io.Reader) (digest.Digest, error) {
 	call := c.Call("POST", "")
@@ -0,0 +1,14 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package repository
+
+// PartBuffersIdle tells whether none of the shared part buffers are held.
+func PartBuffersIdle() bool {
+	if !partBuffers.TryAcquire(maxPartBuffers) {
+		return false
+	}
+	partBuffers.Release(maxPartBuffers)
+	return true
+}
@@ -0,0 +1,42 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package filerepo
+
+import (
+	"flag"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/grailbio/infra"
+)
+
+func init() {
+	infra.Register("filerepo", new(Repository))
+}
+
+// Help implements infra.Provider.
+func (*Repository) Help() string {
+	return "configure a repository using a directory on the local filesystem"
+}
+
+// Flags implements infra.Provider.
+func (r *Repository) Flags(flags *flag.FlagSet) {
+	flags.StringVar(&r.Root, "dir", "/tmp/flow/repository", "directory in which objects are stored")
+}
+
+// Init implements infra.Provider. The repository's URL is a file URL
+// of its root directory.
+func (r *Repository) Init() error {
+	root, err := filepath.Abs(r.Root)
+	if err != nil {
+		return err
+	}
+	r.Root = root
+	if r.RepoURL == nil {
+		r.RepoURL = &url.URL{Scheme: "file", Path: root}
+	}
+	return os.MkdirAll(r.Root, 0777)
+}
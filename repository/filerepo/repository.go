@@ -113,6 +113,20 @@ func (r *Repository) Get(ctx context.Context, id digest.Digest) (io.ReadCloser,
 	return rc, nil
 }
 
+// GetRange retrieves length bytes of the object named by a digest,
+// starting at the given offset. It implements repository.RangeGetter.
+func (r *Repository) GetRange(ctx context.Context, id digest.Digest, offset, length int64) (io.ReadCloser, error) {
+	_, path := r.Path(id)
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.E("getrange", r.Root, id, err)
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.NewSectionReader(f, offset, length), f}, nil
+}
+
 // Remove removes an object from the repository.
 func (r *Repository) Remove(id digest.Digest) error {
 	_, path := r.Path(id)
@@ -130,6 +144,10 @@ func (r *Repository) Remove(id digest.Digest) error {
 // chunks needn't be buffered in memory until the download is
 // contiguous.
 //
+// Otherwise, the object is retrieved with repository.GetParallel, so
+// that large objects are fetched in concurrent parts from
+// repositories which implement repository.RangeGetter.
+//
 // ReadFrom singleflights concurrent downloads from the same key
 // regardless of repository origin.
 func (r *Repository) ReadFrom(ctx context.Context, id digest.Digest, u *url.URL) error {
@@ -145,14 +163,22 @@ func (r *Repository) ReadFrom(ctx context.Context, id digest.Digest, u *url.URL)
 			GetFile(ctx context.Context, id digest.Digest, w io.WriterAt) (int64, error)
 		}
 		if gf, ok := repo.(getFiler); ok {
-			temp, err := r.TempFile("getfile-")
+			temp, err := r.partialFile(id)
 			if err != nil {
 				return nil, err
 			}
-			defer os.Remove(temp.Name())
+			keep := false
+			defer func() {
+				if !keep {
+					_ = os.Remove(temp.Name())
+				}
+			}()
 			_, err = gf.GetFile(ctx, id, temp)
 			if err != nil {
 				_ = temp.Close()
+				// Keep the partially downloaded file, so that a subsequent
+				// (checkpointed) download may resume from it.
+				keep = true
 				return nil, err
 			}
 			err = temp.Close()
@@ -177,7 +203,7 @@ func (r *Repository) ReadFrom(ctx context.Context, id digest.Digest, u *url.URL)
 			return nil, nil
 		}
 
-		rc, err := repo.Get(ctx, id)
+		rc, err := repository.GetParallel(ctx, repo, id)
 		if err != nil {
 			return nil, err
 		}
@@ -414,3 +440,13 @@ func (r *Repository) TempFile(prefix string) (*os.File, error) {
 	os.MkdirAll(dir, 0777)
 	return ioutil.TempFile(dir, prefix)
 }
+
+// partialFile opens the temporary file to which the object with the
+// given digest is downloaded. Unlike TempFile, the file is named by
+// the digest and is not truncated, so that an interrupted download
+// may be resumed from it, even across process restarts.
+func (r *Repository) partialFile(id digest.Digest) (*os.File, error) {
+	dir := filepath.Join(r.Root, "tmp")
+	os.MkdirAll(dir, 0777)
+	return os.OpenFile(filepath.Join(dir, "getfile-"+id.Hex()), os.O_CREATE|os.O_WRONLY, 0666)
+}
@@ -136,7 +136,9 @@ type transferKey struct {
 }
 
 // A Manager is used to transfer objects between repositories while
-// enforcing transfer policies.
+// enforcing transfer policies. Objects are transferred by Transfer:
+// large objects read from repositories which implement RangeGetter are
+// fetched in concurrent, individually retried parts (see GetParts).
 //
 // BUG(marius): Manager does not release references to repositories;
 // in long-term processes, this could cause space leaks.
@@ -158,6 +160,12 @@ type Manager struct {
 	// Status is used to report active transfers to.
 	Status *status.Group
 
+	// Index defines the presence indexes of repositories (keyed by URL).
+	// Objects which are known to be present in an indexed repository
+	// are neither checked for nor transferred. Objects found in, or
+	// transferred to, an indexed repository are added to its index.
+	Index map[string]PresenceIndex
+
 	mu sync.Mutex
 
 	src, dst, stat map[string]*limiter.Limiter
@@ -197,6 +205,7 @@ func (m *Manager) Transfer(ctx context.Context, dst, src reflow.Repository, file
 func (m *Manager) NeedTransfer(ctx context.Context, dst reflow.Repository, files ...reflow.File) ([]reflow.File, error) {
 	exists := make([]bool, len(files))
 	lstat := m.limiter(dst, &m.stat, m.Stat)
+	index := m.Index[key(dst)]
 	g, gctx := errgroup.WithContext(ctx)
 	for _, file := range files {
 		if file.IsRef() {
@@ -204,12 +213,31 @@ func (m *Manager) NeedTransfer(ctx context.Context, dst reflow.Repository, files
 		}
 	}
 	for i, file := range files {
+		if index != nil && index.Contains(file.ID) {
+			exists[i] = true
+		}
+	}
+	var batched bool
+	if _, ok := dst.(BatchExister); ok {
+		if err := lstat.Acquire(ctx, 1); err != nil {
+			return nil, err
+		}
+		var err error
+		batched, err = batchExist(ctx, dst, index, files, exists)
+		lstat.Release(1)
+		if err != nil {
+			// Fall back to statting each file.
+			m.Log.Printf("exist %v: %v", dst, err)
+		}
+	}
+	for i, file := range files {
+		if batched || exists[i] {
+			continue
+		}
 		if err := lstat.Acquire(gctx, 1); err != nil {
 			return nil, err
 		}
 		i, file := i, file
-		// TODO(marius): implement a batch stat call.
-		// It will be more efficient in most cases.
 		g.Go(func() error {
 			_, err := dst.Stat(ctx, file.ID)
 			lstat.Release(1)
@@ -217,6 +245,9 @@ func (m *Manager) NeedTransfer(ctx context.Context, dst reflow.Repository, files
 			if err != nil && !errors.Is(errors.NotExist, err) {
 				m.Log.Printf("stat %v %v: %v", dst, file.ID, err)
 			}
+			if err == nil && index != nil {
+				index.Add(file.ID)
+			}
 			return nil
 		})
 	}
@@ -238,10 +269,11 @@ func (m *Manager) NeedTransfer(ctx context.Context, dst reflow.Repository, files
 
 func (m *Manager) transfer(ctx context.Context, dst, src reflow.Repository, files ...reflow.File) error {
 	var (
-		lx = m.limiter(dst, &m.dst, m.PendingTransfers)
-		ly = m.limiter(src, &m.src, m.PendingTransfers)
-		ux = key(dst)
-		uy = key(src)
+		lx    = m.limiter(dst, &m.dst, m.PendingTransfers)
+		ly    = m.limiter(src, &m.src, m.PendingTransfers)
+		ux    = key(dst)
+		uy    = key(src)
+		index = m.Index[ux]
 	)
 	if uy < ux {
 		ux, uy = uy, ux
@@ -295,6 +327,8 @@ func (m *Manager) transfer(ctx context.Context, dst, src reflow.Repository, file
 			err := Transfer(g1ctx, dst, src, file.ID)
 			if err != nil {
 				err = errors.E("transfer", file.ID, err)
+			} else if index != nil {
+				index.Add(file.ID)
 			}
 			m.updateStats(src, dst, done, stat)
 			ly.Release(1)
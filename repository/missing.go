@@ -7,15 +7,77 @@ package repository
 import (
 	"context"
 
+	"github.com/grailbio/base/digest"
 	"github.com/grailbio/reflow"
 	"github.com/grailbio/reflow/errors"
 	"golang.org/x/sync/errgroup"
 )
 
+// minBatchExist is the minimum number of files whose existence is
+// checked with a single call to BatchExister.Exist, rather than by
+// statting each of them.
+const minBatchExist = 512
+
+// BatchExister is implemented by repositories which can check for the
+// existence of many objects at once with fewer requests than it takes
+// to stat each of them (e.g., by listing the prefixes of their keys).
+type BatchExister interface {
+	// Exist tells which of the objects with the given IDs exist in
+	// the repository.
+	Exist(ctx context.Context, ids []digest.Digest) ([]bool, error)
+}
+
+// batchExist checks for the existence of the files in files which are
+// not yet known to exist, with a single call to r's Exist, if r is a
+// BatchExister and there are enough such files. Files found in r are
+// added to the (optional) presence index. It tells whether the files'
+// existence was checked.
+func batchExist(ctx context.Context, r reflow.Repository, index PresenceIndex, files []reflow.File, exists []bool) (bool, error) {
+	b, ok := r.(BatchExister)
+	if !ok {
+		return false, nil
+	}
+	var (
+		ids  []digest.Digest
+		todo []int
+	)
+	for i, file := range files {
+		if !exists[i] {
+			ids = append(ids, file.ID)
+			todo = append(todo, i)
+		}
+	}
+	if len(ids) < minBatchExist {
+		return false, nil
+	}
+	found, err := b.Exist(ctx, ids)
+	if err != nil {
+		return false, err
+	}
+	for j, i := range todo {
+		if found[j] {
+			exists[i] = true
+			if index != nil {
+				index.Add(files[i].ID)
+			}
+		}
+	}
+	return true, nil
+}
+
 // Missing returns the files in files that are missing from
 // repository r. Missing returns an error if any underlying
 // call fails.
 func Missing(ctx context.Context, r reflow.Repository, files ...reflow.File) ([]reflow.File, error) {
+	return MissingIndex(ctx, r, nil, files...)
+}
+
+// MissingIndex is like Missing, but consults the (optional) presence
+// index of repository r: files which are known to be present in r
+// are not checked for, and files found in r are added to the index.
+// If r is a BatchExister, the existence of many files is checked at
+// once.
+func MissingIndex(ctx context.Context, r reflow.Repository, index PresenceIndex, files ...reflow.File) ([]reflow.File, error) {
 	exists := make([]bool, len(files))
 	g, _ := errgroup.WithContext(ctx)
 	for _, file := range files {
@@ -24,10 +86,25 @@ func Missing(ctx context.Context, r reflow.Repository, files ...reflow.File) ([]
 		}
 	}
 	for i, file := range files {
+		if index != nil && index.Contains(file.ID) {
+			exists[i] = true
+		}
+	}
+	batched, err := batchExist(ctx, r, index, files, exists)
+	if err != nil {
+		return nil, errors.E("missing", err)
+	}
+	for i, file := range files {
+		if batched || exists[i] {
+			continue
+		}
 		i, file := i, file
 		g.Go(func() (err error) {
 			if _, err = r.Stat(ctx, file.ID); err == nil {
 				exists[i] = true
+				if index != nil {
+					index.Add(file.ID)
+				}
 			} else if errors.Is(errors.NotExist, err) {
 				err = nil
 			}
@@ -0,0 +1,336 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package repository
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/grailbio/base/digest"
+	"github.com/grailbio/base/retry"
+	"github.com/grailbio/reflow"
+	"github.com/grailbio/reflow/errors"
+	"github.com/grailbio/reflow/log"
+	"golang.org/x/sync/semaphore"
+)
+
+// A RangeGetter is a repository which can retrieve byte ranges of
+// the objects it stores. Large objects are read from RangeGetters in
+// concurrently fetched parts.
+type RangeGetter interface {
+	// GetRange returns a reader of length bytes of the object named
+	// by id, starting at the given offset.
+	GetRange(ctx context.Context, id digest.Digest, offset, length int64) (io.ReadCloser, error)
+}
+
+// PartOptions configures how objects are read in parts.
+type PartOptions struct {
+	// Threshold is the minimum size of objects which are read in parts;
+	// smaller objects are read in a single stream.
+	Threshold int64
+	// PartSize is the size of each part.
+	PartSize int64
+	// MaxConcurrency is the maximum number of parts which are fetched
+	// (or buffered) concurrently. The concurrency is adapted between 1
+	// and MaxConcurrency: it is increased as parts are fetched
+	// successfully and halved when a part fetch fails.
+	MaxConcurrency int
+	// MaxRetries is the number of times the fetch of a single part is
+	// retried before the read fails.
+	MaxRetries int
+}
+
+// DefaultPartOptions are the PartOptions used by GetParallel and, thus,
+// by Transfer.
+var DefaultPartOptions = PartOptions{
+	Threshold:      256 << 20,
+	PartSize:       32 << 20,
+	MaxConcurrency: 8,
+	MaxRetries:     5,
+}
+
+// maxPartBuffers is the maximum number of bytes of parts which are
+// buffered at any time, across all readers of objects in parts.
+const maxPartBuffers = 1 << 30
+
+// partBuffers bounds the memory used by all readers of objects in
+// parts: a part's length is acquired before it is fetched, and
+// released once it has been read.
+var partBuffers = semaphore.NewWeighted(maxPartBuffers)
+
+// GetParallel returns a reader of the object named by id in
+// repository r. If r is a RangeGetter and the object is at least
+// DefaultPartOptions.Threshold bytes, it is fetched in concurrent
+// parts; otherwise it is read as by r.Get.
+func GetParallel(ctx context.Context, r reflow.Repository, id digest.Digest) (io.ReadCloser, error) {
+	rg, ok := r.(RangeGetter)
+	if !ok {
+		return r.Get(ctx, id)
+	}
+	file, err := r.Stat(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if file.Size < DefaultPartOptions.Threshold {
+		return r.Get(ctx, id)
+	}
+	return GetParts(ctx, rg, id, file.Size, DefaultPartOptions), nil
+}
+
+// GetParts returns a reader of the object named by id, of the given
+// size, which is fetched from r in concurrent parts as configured by
+// opts. Parts are fetched ahead of the reader, at most
+// opts.MaxConcurrency at a time (and within a memory budget shared by
+// all such readers), and are returned in order. Failed
+// part fetches are retried individually. The returned reader must be
+// closed to release its resources.
+func GetParts(ctx context.Context, r RangeGetter, id digest.Digest, size int64, opts PartOptions) io.ReadCloser {
+	ctx, cancel := context.WithCancel(ctx)
+	nparts := int((size + opts.PartSize - 1) / opts.PartSize)
+	p := &partReader{
+		ctx:     ctx,
+		cancel:  cancel,
+		parts:   make([]chan part, nparts),
+		limit:   newAIMD(opts.MaxConcurrency),
+		started: make(chan struct{}),
+	}
+	for i := range p.parts {
+		p.parts[i] = make(chan part, 1)
+	}
+	go func() {
+		defer close(p.started)
+		for i := range p.parts {
+			if !p.limit.Acquire() {
+				return
+			}
+			offset := int64(i) * opts.PartSize
+			length := opts.PartSize
+			if offset+length > size {
+				length = size - offset
+			}
+			n := length
+			if n > maxPartBuffers {
+				n = maxPartBuffers
+			}
+			if err := partBuffers.Acquire(ctx, n); err != nil {
+				p.limit.Release()
+				return
+			}
+			p.nstarted++
+			go func(i int) {
+				buf, err := getPart(ctx, r, id, offset, length, opts.MaxRetries, p.limit)
+				p.parts[i] <- part{buf, n, err}
+			}(i)
+		}
+	}()
+	return p
+}
+
+// getPart fetches a single part, retrying failed fetches up to
+// maxRetries times. The outcome of each fetch is reported to the
+// concurrency limit.
+func getPart(ctx context.Context, r RangeGetter, id digest.Digest, offset, length int64, maxRetries int, limit *aimd) ([]byte, error) {
+	for retries := 0; ; retries++ {
+		buf, err := readRange(ctx, r, id, offset, length)
+		if err == nil {
+			limit.Increase()
+			return buf, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		limit.Decrease()
+		if retries >= maxRetries {
+			return nil, errors.E("getpart", id, fmt.Sprintf("range %d-%d", offset, offset+length-1), err)
+		}
+		log.Debugf("getpart %v range %d-%d: %v (retry %d)", id, offset, offset+length-1, err, retries+1)
+		if err := retry.Wait(ctx, retrier, retries); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func readRange(ctx context.Context, r RangeGetter, id digest.Digest, offset, length int64) ([]byte, error) {
+	rc, err := r.GetRange(ctx, id, offset, length)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	buf := make([]byte, length)
+	if _, err := io.ReadFull(rc, buf); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+type part struct {
+	buf []byte
+	// n is the number of bytes of partBuffers held by the part.
+	n   int64
+	err error
+}
+
+// partReader reads the parts of an object, in order, as they are
+// fetched. Each part holds a slot of the concurrency limit, and its
+// length of the shared part buffers, from the time its fetch starts
+// until it has been consumed by the reader, bounding the memory used
+// by buffered parts.
+type partReader struct {
+	ctx    context.Context
+	cancel func()
+	parts  []chan part
+	limit  *aimd
+
+	// started is closed once no more part fetches are started;
+	// nstarted is then the number of parts whose fetches were started.
+	started  chan struct{}
+	nstarted int
+
+	i      int
+	buf    []byte
+	held   int64
+	err    error
+	closed bool
+}
+
+// Read implements io.Reader.
+func (p *partReader) Read(b []byte) (int, error) {
+	for len(p.buf) == 0 {
+		p.release()
+		if p.err != nil {
+			return 0, p.err
+		}
+		if p.i == len(p.parts) {
+			return 0, io.EOF
+		}
+		var pt part
+		select {
+		case pt = <-p.parts[p.i]:
+		case <-p.ctx.Done():
+			p.err = p.ctx.Err()
+			continue
+		}
+		p.i++
+		p.held = pt.n
+		if pt.err != nil {
+			p.err = pt.err
+			p.cancel()
+			continue
+		}
+		p.buf = pt.buf
+	}
+	n := copy(b, p.buf)
+	p.buf = p.buf[n:]
+	return n, nil
+}
+
+// release releases the resources held by the part last read.
+func (p *partReader) release() {
+	if p.held > 0 {
+		p.limit.Release()
+		partBuffers.Release(p.held)
+		p.held = 0
+	}
+}
+
+// Close implements io.Closer. It aborts any outstanding part fetches
+// and releases the buffers of the parts which were not read.
+func (p *partReader) Close() error {
+	if p.closed {
+		return nil
+	}
+	p.closed = true
+	p.cancel()
+	p.limit.Close()
+	p.release()
+	go func(i int) {
+		<-p.started
+		for ; i < p.nstarted; i++ {
+			partBuffers.Release((<-p.parts[i]).n)
+		}
+	}(p.i)
+	if p.err == nil {
+		p.err = errors.E("getparts", errors.Canceled, errors.Errorf("reader closed"))
+	}
+	return nil
+}
+
+// aimd is a concurrency limit which is adapted by additive increase
+// and multiplicative decrease, between 1 and a maximum.
+type aimd struct {
+	mu       sync.Mutex
+	cond     *sync.Cond
+	n, limit int
+	max      int
+	closed   bool
+}
+
+func newAIMD(max int) *aimd {
+	if max < 1 {
+		max = 1
+	}
+	a := &aimd{max: max, limit: (max + 1) / 2}
+	a.cond = sync.NewCond(&a.mu)
+	return a
+}
+
+// Acquire waits for, and acquires, a slot. It returns false if the
+// limit was closed.
+func (a *aimd) Acquire() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for !a.closed && a.n >= a.limit {
+		a.cond.Wait()
+	}
+	if a.closed {
+		return false
+	}
+	a.n++
+	return true
+}
+
+// Release releases a slot acquired by Acquire.
+func (a *aimd) Release() {
+	a.mu.Lock()
+	a.n--
+	a.cond.Broadcast()
+	a.mu.Unlock()
+}
+
+// Increase increases the limit by one, up to the maximum.
+func (a *aimd) Increase() {
+	a.mu.Lock()
+	if a.limit < a.max {
+		a.limit++
+		a.cond.Broadcast()
+	}
+	a.mu.Unlock()
+}
+
+// Decrease halves the limit, down to one.
+func (a *aimd) Decrease() {
+	a.mu.Lock()
+	if a.limit /= 2; a.limit < 1 {
+		a.limit = 1
+	}
+	a.mu.Unlock()
+}
+
+// Limit returns the current limit.
+func (a *aimd) Limit() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.limit
+}
+
+// Close closes the limit, failing pending and future Acquires.
+func (a *aimd) Close() {
+	a.mu.Lock()
+	a.closed = true
+	a.cond.Broadcast()
+	a.mu.Unlock()
+}
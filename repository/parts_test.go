@@ -0,0 +1,94 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package repository_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grailbio/base/digest"
+	"github.com/grailbio/reflow"
+	"github.com/grailbio/reflow/errors"
+	"github.com/grailbio/reflow/repository"
+)
+
+// flakyRanges serves ranges of a single object, failing the first
+// fetch of every failEvery-th offset.
+type flakyRanges struct {
+	p         []byte
+	failEvery int64
+
+	mu     sync.Mutex
+	failed map[int64]bool
+	calls  int
+}
+
+func (f *flakyRanges) GetRange(ctx context.Context, id digest.Digest, offset, length int64) (io.ReadCloser, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	if f.failEvery > 0 && (offset/length)%f.failEvery == 0 && !f.failed[offset] {
+		f.failed[offset] = true
+		return nil, errors.E(errors.Temporary, errors.New("injected failure"))
+	}
+	return ioutil.NopCloser(bytes.NewReader(f.p[offset : offset+length])), nil
+}
+
+func TestGetParts(t *testing.T) {
+	p := make([]byte, 1<<20+123)
+	rand.Read(p)
+	id := reflow.Digester.FromBytes(p)
+	opts := repository.PartOptions{PartSize: 64 << 10, MaxConcurrency: 4, MaxRetries: 2}
+	for _, failEvery := range []int64{0, 3} {
+		r := &flakyRanges{p: p, failEvery: failEvery, failed: make(map[int64]bool)}
+		rc := repository.GetParts(context.Background(), r, id, int64(len(p)), opts)
+		got, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, p) {
+			t.Errorf("failEvery %d: got %d bytes, want %d bytes (contents differ)", failEvery, len(got), len(p))
+		}
+		if got, want := r.calls, 17+len(r.failed); got != want {
+			t.Errorf("failEvery %d: got %d calls, want %d", failEvery, got, want)
+		}
+	}
+}
+
+func TestGetPartsError(t *testing.T) {
+	p := make([]byte, 1<<16)
+	r := &flakyRanges{p: p, failEvery: 1, failed: make(map[int64]bool)}
+	opts := repository.PartOptions{PartSize: 1 << 12, MaxConcurrency: 4, MaxRetries: 0}
+	rc := repository.GetParts(context.Background(), r, digest.Digest{}, int64(len(p)), opts)
+	defer rc.Close()
+	if _, err := ioutil.ReadAll(rc); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestGetPartsCloseReleasesBuffers(t *testing.T) {
+	p := make([]byte, 1<<20)
+	r := &flakyRanges{p: p, failed: make(map[int64]bool)}
+	opts := repository.PartOptions{PartSize: 64 << 10, MaxConcurrency: 4, MaxRetries: 0}
+	rc := repository.GetParts(context.Background(), r, digest.Digest{}, int64(len(p)), opts)
+	if _, err := io.ReadFull(rc, make([]byte, 100<<10)); err != nil {
+		t.Fatal(err)
+	}
+	rc.Close()
+	// Buffers of unread parts are released asynchronously.
+	for deadline := time.Now().Add(10 * time.Second); !repository.PartBuffersIdle(); {
+		if time.Now().After(deadline) {
+			t.Fatal("part buffers were not released")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
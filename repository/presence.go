@@ -0,0 +1,227 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package repository
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/grailbio/base/digest"
+	"github.com/grailbio/reflow/errors"
+	"github.com/willf/bloom"
+)
+
+// A PresenceIndex records the objects which are known to be present
+// in a repository. The Manager consults a repository's index before
+// checking for (and transferring) objects to it, so that objects
+// which were previously found in, or transferred to, the repository
+// are skipped.
+//
+// Since a PresenceIndex is trusted over the repository itself, it
+// should only be used for repositories from which objects are not
+// removed while the index is in use; indexes must be discarded when
+// objects are removed (as reflow gc does for saved indexes). PresenceIndex implementations
+// must be safe for concurrent use.
+type PresenceIndex interface {
+	// Contains tells whether the object with the given id is known
+	// to be present in the repository.
+	Contains(id digest.Digest) bool
+	// Add records the object with the given id as present in the
+	// repository.
+	Add(id digest.Digest)
+}
+
+// BloomIndex is a PresenceIndex backed by bloom filters. A BloomIndex
+// may be saved to, and loaded from, a file, so that it can be shared
+// across runs. Since a bloom filter may report false positives, the
+// filter's false positive rate should be chosen to be negligible.
+//
+// So that the false positive rate holds however many objects are
+// added, the index keeps two generations of filters, each sized for
+// the index's capacity: once the current generation is full, it
+// replaces the previous one, and a new, empty, generation is started.
+// Objects found in the previous generation are added to the current
+// one, so that the index retains the most recently used objects.
+type BloomIndex struct {
+	// Created is the time at which the index was created.
+	Created time.Time
+
+	path     string
+	mu       sync.Mutex
+	capacity uint
+	// n is the number of objects in the current generation.
+	n                uint
+	filter, previous *bloom.BloomFilter
+	buf              bytes.Buffer
+}
+
+// NewBloomIndex returns a new, empty, BloomIndex sized for n objects
+// (per generation) with the given false positive rate.
+func NewBloomIndex(n uint, fp float64) *BloomIndex {
+	return &BloomIndex{
+		Created:  time.Now(),
+		capacity: n,
+		filter:   bloom.NewWithEstimates(n, fp),
+	}
+}
+
+// LoadBloomIndex loads the BloomIndex saved at the given path. If no
+// index was saved at the path, or if the saved index was created more
+// than maxAge ago (bounding the staleness of the index), a new index
+// is returned as by NewBloomIndex(n, fp). The returned index is saved
+// to path by Save.
+func LoadBloomIndex(path string, maxAge time.Duration, n uint, fp float64) (*BloomIndex, error) {
+	b, err := loadBloomIndex(path)
+	switch {
+	case err == nil && time.Since(b.Created) <= maxAge:
+	case err == nil || os.IsNotExist(err):
+		b = NewBloomIndex(n, fp)
+	default:
+		return nil, errors.E("loadbloomindex", path, err)
+	}
+	if b.capacity == 0 {
+		// Indexes saved by earlier versions do not record their capacity.
+		b.capacity = n
+	}
+	b.path = path
+	return b, nil
+}
+
+func loadBloomIndex(path string) (*BloomIndex, error) {
+	p, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	b := new(BloomIndex)
+	if err := json.Unmarshal(p, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Contains implements PresenceIndex.
+func (b *BloomIndex) Contains(id digest.Digest) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	key := b.key(id)
+	if b.filter.Test(key) {
+		return true
+	}
+	if b.previous == nil || !b.previous.Test(key) {
+		return false
+	}
+	b.add(key)
+	return true
+}
+
+// Add implements PresenceIndex.
+func (b *BloomIndex) Add(id digest.Digest) {
+	b.mu.Lock()
+	b.add(b.key(id))
+	b.mu.Unlock()
+}
+
+// add adds the given key to the current generation, starting a new
+// generation if the current one is full. It must be called with b.mu
+// held.
+func (b *BloomIndex) add(key []byte) {
+	if b.filter.TestAndAdd(key) {
+		return
+	}
+	b.n++
+	if b.capacity > 0 && b.n >= b.capacity {
+		b.previous = b.filter
+		b.filter = bloom.New(b.filter.Cap(), b.filter.K())
+		b.n = 0
+	}
+}
+
+// Len returns the (approximate) number of objects in the index.
+func (b *BloomIndex) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	n := b.n
+	if b.previous != nil {
+		n += b.capacity
+	}
+	return int(n)
+}
+
+// key returns the bloom filter key of the given id. It must be called
+// with b.mu held; the returned slice is valid until the next call.
+func (b *BloomIndex) key(id digest.Digest) []byte {
+	b.buf.Reset()
+	if _, err := digest.WriteDigest(&b.buf, id); err != nil {
+		panic("failed to write digest " + id.String() + ": " + err.Error())
+	}
+	return b.buf.Bytes()
+}
+
+// Save saves the index to the path from which it was loaded. Save
+// is a no-op for indexes which were not loaded by LoadBloomIndex.
+// The index is written atomically; when multiple processes save the
+// same index, the last one wins.
+func (b *BloomIndex) Save() error {
+	if b.path == "" {
+		return nil
+	}
+	b.mu.Lock()
+	p, err := json.Marshal(b)
+	b.mu.Unlock()
+	if err != nil {
+		return errors.E("savebloomindex", b.path, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(b.path), 0777); err != nil {
+		return errors.E("savebloomindex", b.path, err)
+	}
+	f, err := ioutil.TempFile(filepath.Dir(b.path), filepath.Base(b.path)+".tmp")
+	if err != nil {
+		return errors.E("savebloomindex", b.path, err)
+	}
+	_, err = f.Write(p)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), b.path)
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return errors.E("savebloomindex", b.path, err)
+	}
+	return nil
+}
+
+type bloomIndexJSON struct {
+	Created  time.Time
+	Capacity uint `json:",omitempty"`
+	N        uint `json:",omitempty"`
+	Filter   *bloom.BloomFilter
+	Previous *bloom.BloomFilter `json:",omitempty"`
+}
+
+// MarshalJSON serializes the index into JSON.
+func (b *BloomIndex) MarshalJSON() ([]byte, error) {
+	return json.Marshal(bloomIndexJSON{b.Created, b.capacity, b.n, b.filter, b.previous})
+}
+
+// UnmarshalJSON deserializes the index from JSON.
+func (b *BloomIndex) UnmarshalJSON(p []byte) error {
+	var v bloomIndexJSON
+	if err := json.Unmarshal(p, &v); err != nil {
+		return err
+	}
+	if v.Filter == nil {
+		return errors.E("bloomindex", errors.Invalid, errors.Errorf("missing filter"))
+	}
+	b.Created, b.capacity, b.n = v.Created, v.Capacity, v.N
+	b.filter, b.previous = v.Filter, v.Previous
+	return nil
+}
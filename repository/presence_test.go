@@ -0,0 +1,252 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package repository_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/grailbio/base/digest"
+	"github.com/grailbio/reflow"
+	"github.com/grailbio/reflow/repository"
+	"github.com/grailbio/reflow/test/testutil"
+)
+
+func TestBloomIndex(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bloomindex")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "presence", "index")
+	x, y := file("x"), file("y")
+
+	index, err := repository.LoadBloomIndex(path, time.Hour, 1000, 1e-9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	index.Add(x.ID)
+	if !index.Contains(x.ID) {
+		t.Error("index does not contain x")
+	}
+	if index.Contains(y.ID) {
+		t.Error("index contains y")
+	}
+	if err = index.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := repository.LoadBloomIndex(path, time.Hour, 1000, 1e-9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.Contains(x.ID) {
+		t.Error("loaded index does not contain x")
+	}
+	if got, want := loaded.Created, index.Created; !got.Equal(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	// Expired indexes are discarded.
+	expired, err := repository.LoadBloomIndex(path, time.Nanosecond, 1000, 1e-9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expired.Contains(x.ID) {
+		t.Error("expired index contains x")
+	}
+
+	if err = ioutil.WriteFile(path, []byte("{}"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = repository.LoadBloomIndex(path, time.Hour, 1000, 1e-9); err == nil || !strings.Contains(err.Error(), "missing filter") {
+		t.Errorf("expected missing filter error, got %v", err)
+	}
+}
+
+func TestManagerIndex(t *testing.T) {
+	ctx := context.Background()
+	var (
+		src   = testutil.NewInmemoryRepository("")
+		dst   = testutil.NewInmemoryRepository("")
+		index = repository.NewBloomIndex(1000, 1e-9)
+		x, y  = file("x"), file("y")
+	)
+	for _, contents := range []string{"x", "y"} {
+		if _, err := src.Put(ctx, readcloser(contents)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m := &repository.Manager{
+		PendingTransfers: repository.NewLimits(10),
+		Stat:             repository.NewLimits(10),
+		Index:            map[string]repository.PresenceIndex{dst.URL().String(): index},
+	}
+	if err := m.Transfer(ctx, dst, src, x); err != nil {
+		t.Fatal(err)
+	}
+	if !index.Contains(x.ID) {
+		t.Error("transferred file x was not indexed")
+	}
+	// Indexed files are trusted to be present in the repository.
+	dst.Delete(ctx, x.ID)
+	files, err := m.NeedTransfer(ctx, dst, x, y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := files, []reflow.File{y}; len(got) != 1 || got[0].ID != want[0].ID {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	// Files found in the repository are indexed.
+	if err := m.Transfer(ctx, dst, src, y); err != nil {
+		t.Fatal(err)
+	}
+	index = repository.NewBloomIndex(1000, 1e-9)
+	m.Index[dst.URL().String()] = index
+	if files, err = m.NeedTransfer(ctx, dst, y); err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 0 {
+		t.Errorf("expected no files to transfer, got %v", files)
+	}
+	if !index.Contains(y.ID) {
+		t.Error("file y was not indexed")
+	}
+}
+
+func TestBloomIndexGenerations(t *testing.T) {
+	const n = 10
+	index := repository.NewBloomIndex(n, 1e-9)
+	var files []reflow.File
+	for i := 0; i < 2*n; i++ {
+		f := file(fmt.Sprint(i))
+		files = append(files, f)
+		// Keep the first file in use.
+		if !index.Contains(files[0].ID) && i > 0 {
+			t.Fatalf("%d: index does not contain the first file", i)
+		}
+		index.Add(f.ID)
+	}
+	if got, want := index.Len(), 2*n; got > want {
+		t.Errorf("got %v, want <= %v", got, want)
+	}
+	// The index retains the most recently added files, and those in use.
+	for _, f := range append(files[len(files)-n+1:], files[0]) {
+		if !index.Contains(f.ID) {
+			t.Errorf("index does not contain %v", f)
+		}
+	}
+	index.Add(file("x").ID)
+	for i := 0; i < 2*n; i++ {
+		index.Add(file(fmt.Sprint("y", i)).ID)
+	}
+	if index.Contains(files[1].ID) {
+		t.Error("index contains evicted file")
+	}
+}
+
+func TestMissingIndex(t *testing.T) {
+	ctx := context.Background()
+	var (
+		repo  = testutil.NewInmemoryRepository("")
+		index = repository.NewBloomIndex(1000, 1e-9)
+		x, y  = file("x"), file("y")
+	)
+	if _, err := repo.Put(ctx, readcloser("x")); err != nil {
+		t.Fatal(err)
+	}
+	missing, err := repository.MissingIndex(ctx, repo, index, x, y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(missing) != 1 || missing[0].ID != y.ID {
+		t.Errorf("got %v, want %v", missing, []reflow.File{y})
+	}
+	if !index.Contains(x.ID) {
+		t.Error("file x was not indexed")
+	}
+	// Indexed files are not checked for.
+	repo.Delete(ctx, x.ID)
+	if missing, err = repository.MissingIndex(ctx, repo, index, x); err != nil {
+		t.Fatal(err)
+	}
+	if len(missing) != 0 {
+		t.Errorf("expected no missing files, got %v", missing)
+	}
+}
+
+// batchRepo is a repository which checks for the existence of objects
+// in batches, and which counts its stats.
+type batchRepo struct {
+	*testutil.InmemoryRepository
+	batches int
+	stats   int32
+}
+
+func (r *batchRepo) Stat(ctx context.Context, id digest.Digest) (reflow.File, error) {
+	atomic.AddInt32(&r.stats, 1)
+	return r.InmemoryRepository.Stat(ctx, id)
+}
+
+func (r *batchRepo) Exist(ctx context.Context, ids []digest.Digest) ([]bool, error) {
+	r.batches++
+	exists := make([]bool, len(ids))
+	for i, id := range ids {
+		_, err := r.InmemoryRepository.Stat(ctx, id)
+		exists[i] = err == nil
+	}
+	return exists, nil
+}
+
+func TestMissingBatch(t *testing.T) {
+	ctx := context.Background()
+	repo := &batchRepo{InmemoryRepository: testutil.NewInmemoryRepository("")}
+	var files, want []reflow.File
+	for i := 0; i < 1000; i++ {
+		contents := fmt.Sprint(i)
+		files = append(files, file(contents))
+		if i%3 == 0 {
+			want = append(want, file(contents))
+			continue
+		}
+		if _, err := repo.Put(ctx, readcloser(contents)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	missing, err := repository.Missing(ctx, repo, files...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(missing), len(want); got != want {
+		t.Fatalf("got %v missing files, want %v", got, want)
+	}
+	for i := range missing {
+		if missing[i].ID != want[i].ID {
+			t.Errorf("got %v, want %v", missing[i], want[i])
+		}
+	}
+	if got, want := repo.batches, 1; got != want {
+		t.Errorf("got %v batches, want %v", got, want)
+	}
+	if got, want := atomic.LoadInt32(&repo.stats), int32(0); got != want {
+		t.Errorf("got %v stats, want %v", got, want)
+	}
+	// Few files are statted individually.
+	if _, err := repository.Missing(ctx, repo, files[:10]...); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := repo.batches, 1; got != want {
+		t.Errorf("got %v batches, want %v", got, want)
+	}
+	if got, want := atomic.LoadInt32(&repo.stats), int32(10); got != want {
+		t.Errorf("got %v stats, want %v", got, want)
+	}
+}
@@ -127,8 +127,11 @@ func doTransfer(ctx context.Context, dst, src reflow.Repository, id digest.Diges
 	return transferLocal(ctx, dst, src, id)
 }
 
+// transferLocal copies an object from src to dst through the local
+// process. Large objects are read from src in concurrent parts when
+// supported; see GetParallel.
 func transferLocal(ctx context.Context, dst, src reflow.Repository, id digest.Digest) error {
-	rc, err := src.Get(ctx, id)
+	rc, err := GetParallel(ctx, src, id)
 	if err != nil {
 		return err
 	}
@@ -32,14 +32,21 @@ func (Repository) Help() string {
 	return "configure a repository using a S3 bucket"
 }
 
-// Init implements infra.Provider
-func (r *Repository) Init(sess *session.Session) (err error) {
-	r.Repository, err = InitRepo(sess, r.BucketName)
+// Init implements infra.Provider. The repository's bucket is accessed
+// with its configured options; in particular, a bucket with shards
+// in multiple regions makes up a single multi-region repository.
+func (r *Repository) Init(sess *session.Session, opts *infra2.S3BucketOptions) (err error) {
+	blob := s3blob.New(sess)
+	blob.Options = *opts
+	r.Repository, err = initRepo(blob, r.BucketName)
 	return
 }
 
 func InitRepo(sess *session.Session, bucketName string) (*blobrepo.Repository, error) {
-	blob := s3blob.New(sess)
+	return initRepo(s3blob.New(sess), bucketName)
+}
+
+func initRepo(blob *s3blob.Store, bucketName string) (*blobrepo.Repository, error) {
 	blobrepo.Register("s3", blob)
 	ctx := context.Background()
 	bucket, err := blob.Bucket(ctx, bucketName)
@@ -7,14 +7,17 @@ package server
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 
 	"github.com/grailbio/base/digest"
 	"github.com/grailbio/reflow"
 	"github.com/grailbio/reflow/errors"
 	"github.com/grailbio/reflow/liveset/bloomlive"
+	"github.com/grailbio/reflow/repository"
 	"github.com/grailbio/reflow/rest"
 )
 
@@ -100,6 +103,22 @@ func (n fileNode) Do(ctx context.Context, call *rest.Call) {
 		call.ReplyHeader().Add("Content-Length", strconv.FormatInt(file.Size, 10))
 		call.Reply(http.StatusOK, nil)
 	case "GET":
+		if rg, ok := n.r.(repository.RangeGetter); ok && call.Header().Get("Range") != "" {
+			offset, length, err := parseRange(call.Header().Get("Range"))
+			if err != nil {
+				call.Error(err)
+				return
+			}
+			rc, err := rg.GetRange(ctx, n.id, offset, length)
+			if err != nil {
+				call.Error(err)
+				return
+			}
+			call.ReplyHeader().Set("Content-Range", fmt.Sprintf("bytes %d-%d/*", offset, offset+length-1))
+			call.Write(http.StatusPartialContent, rc)
+			rc.Close()
+			return
+		}
 		rc, err := n.r.Get(ctx, n.id)
 		if err != nil {
 			call.Error(err)
@@ -133,6 +152,31 @@ func (n fileNode) Do(ctx context.Context, call *rest.Call) {
 	}
 }
 
+// parseRange parses a single byte range of the form "bytes=first-last",
+// as sent by the repository client, returning its offset and length.
+func parseRange(s string) (offset, length int64, err error) {
+	const prefix = "bytes="
+	if !strings.HasPrefix(s, prefix) {
+		return 0, 0, errors.E("parserange", s, errors.Invalid, errors.New("unsupported range unit"))
+	}
+	parts := strings.SplitN(strings.TrimPrefix(s, prefix), "-", 2)
+	if len(parts) != 2 {
+		return 0, 0, errors.E("parserange", s, errors.Invalid, errors.New("malformed range"))
+	}
+	first, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, 0, errors.E("parserange", s, errors.Invalid, err)
+	}
+	last, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, 0, errors.E("parserange", s, errors.Invalid, err)
+	}
+	if first < 0 || last < first {
+		return 0, 0, errors.E("parserange", s, errors.Invalid, errors.New("invalid range"))
+	}
+	return first, last - first + 1, nil
+}
+
 type transfersNode struct {
 	r  reflow.Repository
 	id digest.Digest
@@ -121,6 +121,21 @@ func TestClientServerFile(t *testing.T) {
 	if got, want := b, b1; !reflect.DeepEqual(got, want) {
 		t.Fatalf("got %v, want %v", got, want)
 	}
+	if len(b1) > 2 {
+		offset, length := int64(1), int64(len(b1)-2)
+		rc, err = repo.GetRange(ctx, id, offset, length)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err = ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := b, b1[offset:offset+length]; !bytes.Equal(got, want) {
+			t.Fatalf("got %d bytes, want %d bytes", len(got), len(want))
+		}
+	}
 
 	id2 := reflow.Digester.FromString("hello, world")
 	_, err = repo.Get(ctx, id2)
@@ -19,6 +19,20 @@ const (
 	disk = "disk"
 )
 
+// Arm64 is the resource label (and CPU feature) which denotes CPUs of
+// the arm64 architecture. Machines of this architecture present one
+// unit per CPU; execs that must run on arm64 request it through
+// their CPU features.
+const Arm64 = "arm64"
+
+// OnDemand is the resource label which denotes on-demand (i.e.,
+// non-preemptible) capacity. Machines which cannot be reclaimed by
+// their provider present one unit per CPU; execs that must not be
+// preempted (e.g., because they are very long-running or have
+// irreproducible side effects) request it through the exec's
+// ondemand parameter.
+const OnDemand = "ondemand"
+
 // Resources describes a set of labeled resources. Each resource is
 // described by a string label and assigned a value. The zero value
 // of Resources represents the resources with zeros for all labels.
@@ -65,6 +79,14 @@ func (r Resources) Available(s Resources) bool {
 	return true
 }
 
+// ArchCompatible tells whether resources r (for example, those of an
+// alloc or an instance type) are of the same architecture as required
+// by s. Unlike other resources, an arm64 machine may not be used to
+// satisfy requirements that do not explicitly ask for arm64.
+func (r Resources) ArchCompatible(s Resources) bool {
+	return (r[Arm64] > 0) == (s[Arm64] > 0)
+}
+
 // Sub sets r to the difference x[key]-y[key] for all keys and returns r.
 func (r *Resources) Sub(x, y Resources) *Resources {
 	r.Set(x)
@@ -67,6 +67,26 @@ func TestResourcesEqual(t *testing.T) {
 	}
 }
 
+func TestResourcesArchCompatible(t *testing.T) {
+	var (
+		x86 = reflow.Resources{"mem": 10, "cpu": 4, "intel_avx": 4}
+		arm = reflow.Resources{"mem": 10, "cpu": 4, reflow.Arm64: 4}
+	)
+	for _, tt := range []struct {
+		r, s reflow.Resources
+		want bool
+	}{
+		{x86, reflow.Resources{"cpu": 1}, true},
+		{arm, reflow.Resources{"cpu": 1}, false},
+		{arm, reflow.Resources{"cpu": 1, reflow.Arm64: 1}, true},
+		{x86, reflow.Resources{"cpu": 1, reflow.Arm64: 1}, false},
+	} {
+		if got, want := tt.r.ArchCompatible(tt.s), tt.want; got != want {
+			t.Errorf("%s.ArchCompatible(%s): got %v, want %v", tt.r, tt.s, got, want)
+		}
+	}
+}
+
 func assertRequirements(t *testing.T, req reflow.Requirements, min, max reflow.Resources) {
 	t.Helper()
 	if got, want := req.Min, min; !got.Equal(want) {
@@ -14,6 +14,7 @@ import (
 	"net/http/httputil"
 	"net/url"
 	"strings"
+	"sync/atomic"
 
 	"github.com/grailbio/reflow"
 	"github.com/grailbio/reflow/assoc"
@@ -27,11 +28,40 @@ type Client struct {
 	url    *url.URL
 	client *http.Client
 	log    *log.Logger
+	// host stores the (string) host which overrides that of url. It is
+	// shared by all clients derived (through Walk) from the same root.
+	host *atomic.Value
 }
 
 // NewClient returns a new REST client given an HTTP client and root URL.
 func NewClient(client *http.Client, u *url.URL, log *log.Logger) *Client {
-	return &Client{client: client, url: u, log: log}
+	return &Client{client: client, url: u, log: log, host: new(atomic.Value)}
+}
+
+// SetHost overrides the host (and port) to which the client, and all
+// clients derived from the same root client, issue requests. SetHost
+// is used to follow a server whose address has changed.
+func (c *Client) SetHost(host string) {
+	c.host.Store(host)
+}
+
+// WithHost returns a copy of client c which issues requests to the
+// given host (and port). Unlike SetHost, WithHost does not affect c.
+func (c *Client) WithHost(host string) *Client {
+	d := &Client{url: c.url, client: c.client, log: c.log, host: new(atomic.Value)}
+	d.host.Store(host)
+	return d
+}
+
+// Host returns the host (and port) to which the client issues requests.
+func (c *Client) Host() string {
+	if c.host == nil {
+		return c.url.Host
+	}
+	if h, ok := c.host.Load().(string); ok && h != "" {
+		return h
+	}
+	return c.url.Host
 }
 
 // Walk constructs a new client based on client c with a root URL based
@@ -45,6 +75,7 @@ func (c *Client) Walk(format string, args ...interface{}) (*Client, error) {
 		url:    c.url.ResolveReference(u),
 		client: c.client,
 		log:    c.log,
+		host:   c.host,
 	}, nil
 }
 
@@ -128,6 +159,7 @@ func (c *ClientCall) Do(ctx context.Context, body io.Reader) (int, error) {
 		query = parts[1]
 	}
 	r.URL = c.url.ResolveReference(&url.URL{Path: path, RawQuery: query})
+	r.URL.Host = c.Host()
 	// add query parameters
 	q := r.URL.Query()
 	for k, v := range c.queryParams {
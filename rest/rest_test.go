@@ -112,3 +112,58 @@ func TestEndToEnd(t *testing.T) {
 	call.Close()
 
 }
+
+func TestSetHost(t *testing.T) {
+	newServer := func(name string) *httptest.Server {
+		return httptest.NewServer(Handler(Mux{"v1": Mux{"name": DoFunc(func(ctx context.Context, call *Call) {
+			call.Reply(http.StatusOK, name)
+		})}}, nil))
+	}
+	srv1, srv2 := newServer("srv1"), newServer("srv2")
+	defer srv1.Close()
+	defer srv2.Close()
+	u, err := url.Parse(srv1.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	name := func(c *Client) string {
+		t.Helper()
+		call := c.Call("GET", "name")
+		defer call.Close()
+		if _, err := call.Do(ctx, nil); err != nil {
+			t.Fatal(err)
+		}
+		m, err := call.Message()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+	root := NewClient(nil, u, nil)
+	v1, err := root.Walk("v1/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := name(v1), "srv1"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	u2, err := url.Parse(srv2.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := name(v1.WithHost(u2.Host)), "srv2"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := name(v1), "srv1"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	// Clients derived from the same root follow a changed host.
+	root.SetHost(u2.Host)
+	if got, want := name(v1), "srv2"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := root.Host(), u2.Host; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -7,12 +7,14 @@ package runner
 import (
 	"context"
 	"fmt"
+	"path/filepath"
 	"sync"
 	"time"
 
 	"github.com/grailbio/reflow"
 	"github.com/grailbio/reflow/errors"
 	"github.com/grailbio/reflow/flow"
+	"github.com/grailbio/reflow/log"
 	"github.com/grailbio/reflow/pool"
 	"github.com/grailbio/reflow/taskdb"
 	"github.com/grailbio/reflow/trace"
@@ -72,6 +74,18 @@ type State struct {
 	// TotalResources stores the total amount of resources used
 	// by this run. Note that the resources are in resource-minutes.
 	TotalResources reflow.Resources
+
+	// Execs is the number of execs evaluated in the last evaluation
+	// attempt, and CachedExecs the number of those that were cache hits.
+	Execs, CachedExecs int
+	// CostUSD is the estimated cost (in USD) of the tasks of all
+	// evaluation attempts.
+	CostUSD float64
+	// Failures summarizes the execs, interns and externs which failed
+	// in the last evaluation attempt.
+	Failures []flow.Failure `json:",omitempty"`
+	// DryRun reports the execs which would run, in dry runs.
+	DryRun *flow.DryRunReport `json:",omitempty"`
 }
 
 // Reset resets the state so that it will reinitialize if run.
@@ -84,6 +98,10 @@ func (s *State) Reset() {
 	s.LastTry = time.Time{}
 	s.Created = time.Time{}
 	s.Completion = time.Time{}
+	s.Execs, s.CachedExecs = 0, 0
+	s.CostUSD = 0
+	s.Failures = nil
+	s.DryRun = nil
 }
 
 // String returns a string representation of the state.
@@ -201,7 +219,13 @@ func (r *Runner) Eval(ctx context.Context) (string, error) {
 	config := r.EvalConfig
 	eval := flow.NewEval(r.Flow, config)
 
-	ctx, done := trace.Start(ctx, trace.Run, r.Flow.Digest(), r.Cmdline)
+	// The run's logical ID is derived from its program (but not its
+	// arguments), so that spans may be compared across runs and retries.
+	var logicalID string
+	if r.Program != "" {
+		logicalID = filepath.Base(r.Program)
+	}
+	ctx, done := trace.StartNode(ctx, trace.Run, r.Flow.Digest(), logicalID, r.Cmdline)
 	traceURL := trace.URL(ctx)
 	if traceURL != "" {
 		r.Log.Printf("Trace: %v", traceURL)
@@ -210,6 +234,14 @@ func (r *Runner) Eval(ctx context.Context) (string, error) {
 
 	err := eval.Do(ctx)
 	done()
+	r.Execs, r.CachedExecs = eval.CacheStats()
+	r.CostUSD += eval.CostUSD()
+	r.Failures = eval.Failures()
+	if config.DryRun {
+		report := eval.DryRunReport()
+		r.DryRun = &report
+		logDryRun(r.Log, report)
+	}
 	if err == nil {
 		// TODO(marius): use logger for this.
 		eval.LogSummary(r.Log)
@@ -221,6 +253,9 @@ func (r *Runner) Eval(ctx context.Context) (string, error) {
 		return "", err
 	}
 	if err := eval.Err(); err != nil {
+	   if config.DryRun && flow.IsDryRun(err) {
+		   return fmt.Sprintf("dry run: %d execs would run", len(r.DryRun.Execs)), nil
+	   }
 	   if re, ok := errors.RecoverError(err); !ok || re.Kind == errors.Other {
 		   err = errors.E(errors.Eval, err)
 	   }
@@ -232,6 +267,19 @@ func (r *Runner) Eval(ctx context.Context) (string, error) {
 	return values.Sprint(eval.Value(), r.Type), nil
 }
 
+// logDryRun logs the report of a dry run.
+func logDryRun(log *log.Logger, report flow.DryRunReport) {
+	if len(report.Execs) == 0 {
+		log.Printf("dry run: no execs would run")
+	}
+	for _, x := range report.Execs {
+		log.Printf("dry run: %s %s (%s) would run: %s", x.Op, x.Ident, x.Position, x.Reason)
+	}
+	if report.Unexpanded > 0 {
+		log.Printf("dry run: %d maps or continuations could not be expanded: they depend on execs which would run", report.Unexpanded)
+	}
+}
+
 func (r Runner) labels() pool.Labels {
 	labels := r.Labels.Copy()
 	labels["ID"] = r.ID.IDShort()
@@ -13,10 +13,10 @@ import (
 	"path/filepath"
 	"strings"
 
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/grailbio/reflow/ec2authenticator"
+	"github.com/grailbio/reflow"
 	"github.com/grailbio/reflow/errors"
 	"github.com/grailbio/reflow/flow"
+	"github.com/grailbio/reflow/internal/ecrauth"
 	"github.com/grailbio/reflow/lang"
 	"github.com/grailbio/reflow/syntax"
 	"github.com/grailbio/reflow/types"
@@ -42,6 +42,9 @@ type Eval struct {
 	// ImageMap stores a mapping between image names and resolved
 	// image names, to be used in evaluation.
 	ImageMap map[string]string
+	// Arm64ImageMap stores a mapping between image names and the
+	// resolved names of their arm64 variants.
+	Arm64ImageMap map[string]string
 	// Type is the module type of the toplevel module that has been
 	// evaluated.
 	Type *types.T
@@ -199,13 +202,17 @@ func (e *Eval) evalV1(sess *syntax.Session) error {
 	return err
 }
 
-// Resolve images resolves the images in an evaluated program.
-func (e *Eval) ResolveImages(sess *session.Session) (err error) {
+// Resolve images resolves the images in an evaluated program, using
+// the given authenticator (see ImageAuthenticator).
+func (e *Eval) ResolveImages(auth ecrauth.Interface) (err error) {
 	// resolve images is only supported for v1 flows as of this writing.
 	if !e.V1 {
 		return
 	}
-	r := ImageResolver{Authenticator: ec2authenticator.New(sess)}
-	e.ImageMap, err = r.ResolveImages(context.Background(), e.Images)
+	r := ImageResolver{Authenticator: auth}
+	if e.ImageMap, err = r.ResolveImages(context.Background(), e.Images); err != nil {
+		return
+	}
+	e.Arm64ImageMap, err = r.ResolveArchImages(context.Background(), e.Images, reflow.Arm64)
 	return
 }
@@ -2,22 +2,27 @@ package runtime
 
 import (
 	"context"
+	"fmt"
 	"sync"
 	"time"
 
-	"docker.io/go-docker/api/types"
+	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/google/go-containerregistry/pkg/authn"
 	imgname "github.com/google/go-containerregistry/pkg/name"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
 	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
 	"github.com/grailbio/base/retry"
-	"github.com/grailbio/base/sync/once"
 	"github.com/grailbio/base/traverse"
+	"github.com/grailbio/infra"
+	"github.com/grailbio/reflow"
+	"github.com/grailbio/reflow/ec2authenticator"
 	"github.com/grailbio/reflow/errors"
 	"github.com/grailbio/reflow/flow"
 	"github.com/grailbio/reflow/internal/ecrauth"
+	"github.com/grailbio/reflow/local"
 	"github.com/grailbio/reflow/log"
+	"golang.org/x/sync/singleflight"
 )
 
 // ImageResolver maintains maps of image descriptions to their canonical values
@@ -25,17 +30,35 @@ import (
 type ImageResolver struct {
 	// Authenticator will have a nil AWS session if the config does not have
 	// AWS credentials, in which case it is expected that none of the images are
-	// ECR images.
+	// ECR images. It may also authenticate other registries (see
+	// ImageAuthenticator).
 	Authenticator ecrauth.Interface
 
-	// Cached ECR credentials from the Authenticator, populated only if needed.
-	ecrCreds *types.AuthConfig
-	authOnce once.Task
+	// Cached credentials from the Authenticator, keyed by registry host,
+	// populated only if needed. Credentials are fetched once per host
+	// (through fetch), without holding mu, so that the resolution of
+	// images of other registries does not wait for a slow registry.
+	mu    sync.Mutex
+	creds map[string]*authn.Basic
+	fetch singleflight.Group
 
 	// TODO(sbagaria): When using this object for batch reflow runs, memoize
 	// the calls to resolveImage.
 }
 
+// ImageAuthenticator returns the authenticator with which images are
+// resolved and pulled: ECR repositories are authenticated with the
+// session sess, and other registries with the credentials configured
+// by the "registries" key of config (see ecrauth.FromConfig), whose
+// secrets are fetched with sess.
+func ImageAuthenticator(config infra.Config, sess *session.Session) (ecrauth.Interface, error) {
+	var secret ecrauth.SecretFunc
+	if sess != nil {
+		secret = local.NewAWSSecrets(sess).Secret
+	}
+	return ecrauth.FromConfig(config, ec2authenticator.New(sess), secret)
+}
+
 func (r *ImageResolver) ResolveImages(ctx context.Context, images []string) (map[string]string, error) {
 	var mu sync.Mutex
 	imageMap := make(map[string]string)
@@ -56,20 +79,39 @@ func (r *ImageResolver) ResolveImages(ctx context.Context, images []string) (map
 	return imageMap, nil
 }
 
+// ResolveArchImages resolves the given images to the digest references
+// of their variants for the given (linux) CPU architecture. Images
+// which have no such variant (including those which are not
+// multi-platform) are omitted from the returned map.
+func (r *ImageResolver) ResolveArchImages(ctx context.Context, images []string, arch string) (map[string]string, error) {
+	var mu sync.Mutex
+	imageMap := make(map[string]string)
+	err := traverse.Each(len(images), func(i int) error {
+		image := images[i]
+		auth, err := r.auth(ctx, image)
+		if err != nil {
+			return err
+		}
+		resolved, ok, err := imageArchDigestReference(ctx, image, arch, auth)
+		if err != nil || !ok {
+			return err
+		}
+		mu.Lock()
+		imageMap[image] = resolved
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return imageMap, nil
+}
+
 func (r *ImageResolver) resolveImage(ctx context.Context, image string) (string, error) {
-	ecrImage, err := r.Authenticator.Authenticates(ctx, image)
+	auth, err := r.auth(ctx, image)
 	if err != nil {
 		return "", err
 	}
-	var auth authn.Authenticator
-	if ecrImage {
-		if err = r.authenticate(ctx); err != nil {
-			return "", err
-		}
-		auth = &authn.Basic{Username: r.ecrCreds.Username, Password: r.ecrCreds.Password}
-	} else {
-		auth = authn.Anonymous
-	}
 
 	ref, err := imageDigestReference(ctx, image, auth)
 	if err != nil {
@@ -79,14 +121,94 @@ func (r *ImageResolver) resolveImage(ctx context.Context, image string) (string,
 	return ref, nil
 }
 
-func (r *ImageResolver) authenticate(ctx context.Context) error {
-	return r.authOnce.Do(func() error {
-		if r.ecrCreds != nil {
-			return nil
+// ImageAssertions is an AssertionGenerator for the docker namespace
+// (see reflow.DockerAssertionsNamespace): it generates assertions of
+// the digests to which image references currently resolve.
+type ImageAssertions struct {
+	// Resolver resolves image references. If nil, images are not
+	// resolved: empty assertions are generated for images absent from
+	// ImageMap and Arm64ImageMap, so that the image assertions of
+	// cached results are not checked.
+	Resolver *ImageResolver
+	// ImageMap and Arm64ImageMap are images which have already been
+	// resolved (e.g., by the run), for the default and the arm64
+	// platforms; they are not resolved again.
+	ImageMap, Arm64ImageMap map[string]string
+}
+
+// Generate implements the AssertionGenerator interface for the docker namespace.
+func (g *ImageAssertions) Generate(ctx context.Context, key reflow.AssertionKey) (*reflow.Assertions, error) {
+	if key.Namespace != reflow.DockerAssertionsNamespace {
+		return nil, fmt.Errorf("unsupported namespace: %v", key.Namespace)
+	}
+	image, arch := reflow.ParseDockerAssertionSubject(key.Subject)
+	imageMap := g.ImageMap
+	if arch == reflow.Arm64 {
+		imageMap = g.Arm64ImageMap
+	}
+	if ref, ok := imageMap[image]; ok {
+		return reflow.DockerAssertions(image, arch, ref), nil
+	}
+	if g.Resolver == nil {
+		return reflow.NewAssertions(), nil
+	}
+	var (
+		ref string
+		err error
+	)
+	if arch == "" {
+		ref, err = g.Resolver.resolveImage(ctx, image)
+	} else {
+		var (
+			auth authn.Authenticator
+			ok   bool
+		)
+		if auth, err = g.Resolver.auth(ctx, image); err == nil {
+			ref, ok, err = imageArchDigestReference(ctx, image, arch, auth)
+			if err == nil && !ok {
+				err = errors.E(errors.NotExist, "runtime.ImageAssertions", image, errors.Errorf("no %s variant", arch))
+			}
 		}
-		r.ecrCreds = &types.AuthConfig{}
-		return r.Authenticator.Authenticate(ctx, r.ecrCreds)
-	})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return reflow.DockerAssertions(image, arch, ref), nil
+}
+
+// auth returns the authenticator to use for the registry of the given image.
+func (r *ImageResolver) auth(ctx context.Context, image string) (authn.Authenticator, error) {
+	host := ecrauth.RegistryHost(image)
+	r.mu.Lock()
+	creds, ok := r.creds[host]
+	r.mu.Unlock()
+	if !ok {
+		v, err, _ := r.fetch.Do(host, func() (interface{}, error) {
+			cfg, ok, err := ecrauth.AuthConfig(ctx, r.Authenticator, image)
+			if err != nil {
+				return nil, err
+			}
+			var creds *authn.Basic
+			if ok {
+				creds = &authn.Basic{Username: cfg.Username, Password: cfg.Password}
+			}
+			r.mu.Lock()
+			if r.creds == nil {
+				r.creds = make(map[string]*authn.Basic)
+			}
+			r.creds[host] = creds
+			r.mu.Unlock()
+			return creds, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		creds = v.(*authn.Basic)
+	}
+	if creds == nil {
+		return authn.Anonymous, nil
+	}
+	return creds, nil
 }
 
 func imageDigestReference(ctx context.Context, image string, auth authn.Authenticator) (string, error) {
@@ -138,3 +260,45 @@ func imageDigestReference(ctx context.Context, image string, auth authn.Authenti
 		}
 	}
 }
+
+// imageArchDigestReference returns the digest reference of the variant
+// of the given image for the given linux CPU architecture, as listed
+// in the image's manifest list. It returns false if the image has no
+// such variant.
+func imageArchDigestReference(ctx context.Context, image, arch string, auth authn.Authenticator) (string, bool, error) {
+	image, _, _ = flow.ImageQualifiers(image)
+	ref, err := imgname.ParseReference(image, imgname.WeakValidation)
+	if err != nil {
+		return "", false, errors.E(err, "tool.imageArchDigestReference", "parse", image)
+	}
+	var manifest *v1.IndexManifest
+	retryPolicy := retry.MaxRetries(retry.Backoff(time.Second, 10*time.Second, 1.5), 5)
+	for retries := 0; ; retries++ {
+		var idx v1.ImageIndex
+		if idx, err = remote.Index(ref, remote.WithAuth(auth)); err == nil {
+			if manifest, err = idx.IndexManifest(); err == nil {
+				break
+			}
+		}
+		// Registries may refuse to serve single-platform images as
+		// manifest lists.
+		if e, ok := err.(*transport.Error); ok {
+			for _, d := range e.Errors {
+				switch d.Code {
+				case transport.ManifestUnknownErrorCode, transport.UnsupportedErrorCode:
+					return "", false, nil
+				}
+			}
+		}
+		log.Printf("retrying getting image manifest list for %q: %v", ref.Name(), err)
+		if err := retry.Wait(ctx, retryPolicy, retries); err != nil {
+			return "", false, errors.E(errors.Unavailable, err, "tool.imageArchDigestReference", ref.Name())
+		}
+	}
+	for _, desc := range manifest.Manifests {
+		if desc.Platform != nil && desc.Platform.OS == "linux" && desc.Platform.Architecture == arch {
+			return ref.Context().Name() + "@" + desc.Digest.String(), true, nil
+		}
+	}
+	return "", false, nil
+}
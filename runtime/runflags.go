@@ -19,14 +19,26 @@ const (
 	Unknown FlagName = "unknown"
 	// CommonRunFlags flag names
 	FlagNameAssert          FlagName = "assert"
+	FlagNameCacheNamespace  FlagName = "cachenamespace"
+	FlagNameDryRun          FlagName = "dryrun"
 	FlagNameEvalStrategy    FlagName = "eval"
 	FlagNameInvalidate      FlagName = "invalidate"
+	FlagNameMaxCost         FlagName = "maxcost"
+	FlagNameMaxCostPolicy   FlagName = "maxcostpolicy"
+	FlagNameMaxFanout       FlagName = "maxfanout"
 	FlagNameNoCacheExtern   FlagName = "nocacheextern"
+	FlagNameOOMMaxMem       FlagName = "oommaxmem"
+	FlagNameOOMMultiplier   FlagName = "oommultiplier"
 	FlagNamePostUseChecksum FlagName = "postusechecksum"
 	FlagNameRecomputeEmpty  FlagName = "recomputeempty"
 	// RunFlags flag names
 	FlagNameBackgroundTimeout FlagName = "backgroundtimeout"
 	FlagNameDotGraph          FlagName = "dotgraph"
+	FlagNameDotGraphCollapse  FlagName = "dotgraphcollapse"
+	FlagNameDotGraphDepth     FlagName = "dotgraphdepth"
+	FlagNameDotGraphIdent     FlagName = "dotgraphident"
+	FlagNameDotGraphMaxNodes  FlagName = "dotgraphmaxnodes"
+	FlagNameGraphAddr         FlagName = "graphaddr"
 	FlagNamePred              FlagName = "pred"
 	FlagNameTrace             FlagName = "traceflow"
 )
@@ -35,12 +47,26 @@ const (
 type CommonRunFlags struct {
 	// Assert is the policy used to assert cached flow result compatibility. e.g. never, exact.
 	Assert string
+	// CacheNamespace is the cache namespace in which the run's results are looked up and stored.
+	CacheNamespace string
+	// DryRun indicates that the run only reports which execs would run, without running them.
+	DryRun bool
 	// EvalStrategy is the evaluation strategy. Supported modes are "topdown" and "bottomup".
 	EvalStrategy string
 	// Invalidate is a regular expression for node identifiers that should be invalidated.
 	Invalidate string
+	// MaxCost is the maximum estimated cost (in USD) of the run's tasks; zero means no limit.
+	MaxCost float64
+	// MaxCostPolicy is the policy applied when MaxCost is exceeded: "abort" or "pause".
+	MaxCostPolicy string
+	// MaxFanout is the maximum number of tasks a single map or flatten may generate; zero means no limit.
+	MaxFanout int
 	// NoCacheExtern indicates if extern operations should be written to cache.
 	NoCacheExtern bool
+	// OOMMaxMem is the maximum memory (in GiB) with which execs that fail due to OOM are retried.
+	OOMMaxMem int
+	// OOMMultiplier is the factor by which the memory of execs that fail due to OOM is increased on retry.
+	OOMMultiplier float64
 	// PostUseChecksum indicates whether input filesets are checksummed after use.
 	PostUseChecksum bool
 	// RecomputeEmpty indicates if cache results with empty filesets be automatically recomputed.
@@ -74,6 +100,30 @@ has the same content as it did before, but was "touched", then it is not "exact"
 anymore (meaning, the cached result will not be accepted).`)
 	}
 
+	if names == nil || names[FlagNameCacheNamespace] {
+		flags.StringVar(&r.CacheNamespace, prefix+string(FlagNameCacheNamespace), "", `cache namespace of the run
+
+When set, the run looks up and stores its results only among cache
+entries in the given namespace, so that runs (e.g., of different teams
+or pipeline versions) may share a cluster and a cache while keeping
+their results isolated. Runs with the same namespace share their
+results. By default, runs use the default (shared) namespace.`)
+	}
+
+	if names == nil || names[FlagNameDryRun] {
+		flags.BoolVar(&r.DryRun, prefix+string(FlagNameDryRun), false, `report which execs would run, without running them
+
+In a dry run, the program is evaluated and its results are looked up
+in the cache as usual, but no execs (nor interns or externs) are run,
+and nothing is written to the cache. Instead, the run reports each
+exec which misses the cache, and why: e.g., that there is no cached
+result for it, that the inputs of its cached result have changed, or
+that it depends on other execs which would run. This is useful to
+verify that a change to a program (e.g., a refactor) does not
+invalidate cached results. Maps over the results of execs which
+would run cannot be expanded; their number is reported.`)
+	}
+
 	if names == nil || names[FlagNameEvalStrategy] {
 		flags.StringVar(&r.EvalStrategy, prefix+string(FlagNameEvalStrategy), "topdown", `values: "topdown", "bottomup"
 
@@ -103,6 +153,36 @@ Reach out to the reflow team for more information.`)
 	if names == nil || names[FlagNameInvalidate] {
 		flags.StringVar(&r.Invalidate, prefix+"invalidate", "", "regular expression for node identifiers that should be invalidated")
 	}
+	if names == nil || names[FlagNameMaxCost] {
+		flags.Float64Var(&r.MaxCost, prefix+string(FlagNameMaxCost), 0, `maximum estimated cost (in USD) of the run
+
+If set, the estimated cost of the run's tasks is accumulated as they complete, 
+and once it exceeds this amount, the run is stopped as determined by 
+"maxcostpolicy" and fails with a "cost budget exceeded" error. The cost of each 
+task is its share of the hourly price of the instance on which it ran, for 
+its duration. Costs can only be estimated for clusters which price their 
+instances (eg, ec2cluster); otherwise the limit has no effect.`)
+	}
+	if names == nil || names[FlagNameMaxCostPolicy] {
+		flags.StringVar(&r.MaxCostPolicy, prefix+string(FlagNameMaxCostPolicy), "abort", `values: "abort", "pause"
+
+This flag determines what happens when the run exceeds "maxcost".
+
+With "abort", the run fails immediately, and running tasks are canceled.
+
+With "pause", no new tasks are started, but running tasks are allowed to 
+complete (and their results are cached) before the run fails. The run can 
+then be resumed, with a larger "maxcost", from where it left off.`)
+	}
+	if names == nil || names[FlagNameMaxFanout] {
+		flags.IntVar(&r.MaxFanout, prefix+string(FlagNameMaxFanout), 100000, `maximum fan-out of a single map, comprehension or flatten
+
+This is a safety limit on the number of tasks that a single map,
+comprehension or flatten may generate. A run that exceeds it fails
+with an evaluation error (before any of the generated tasks are
+submitted), so that, e.g., a malformed sample sheet does not result
+in millions of tasks. Set to 0 to disable the limit.`)
+	}
 	if names == nil || names[FlagNameNoCacheExtern] {
 		// TODO(pboyapalli): [SYSINFRA-554] modify extern caching so that we can drop the nocacheextern flag
 		flags.BoolVar(&r.NoCacheExtern, prefix+string(FlagNameNoCacheExtern), false, `don't cache extern ops
@@ -112,6 +192,15 @@ For all extern operations, "nocacheextern=true" acts like "cache=off" and
 extern operations that have a cache hit will result in the fileset being copied 
 to the extern URL again.`)
 	}
+	if names == nil || names[FlagNameOOMMultiplier] {
+		flags.Float64Var(&r.OOMMultiplier, prefix+string(FlagNameOOMMultiplier), flow.DefaultOOMMemMultiplier, `memory multiplier for execs retried after OOM
+
+Execs that fail due to a detectable OOM error are retried (up to 3 times) with 
+their memory increased by this factor each time, up to "oommaxmem".`)
+	}
+	if names == nil || names[FlagNameOOMMaxMem] {
+		flags.IntVar(&r.OOMMaxMem, prefix+string(FlagNameOOMMaxMem), flow.DefaultOOMMaxMemory>>30, "maximum memory (in GiB) with which execs are retried after OOM")
+	}
 	if names == nil || names[FlagNamePostUseChecksum] {
 		flags.BoolVar(&r.PostUseChecksum, prefix+string(FlagNamePostUseChecksum), false, `checksum exec input files after use
 
@@ -143,6 +232,21 @@ func (r *CommonRunFlags) Err() error {
 			return err
 		}
 	}
+	if r.OOMMultiplier != 0 && r.OOMMultiplier < 1 {
+		return fmt.Errorf("invalid oom memory multiplier %v: must be at least 1", r.OOMMultiplier)
+	}
+	if r.OOMMaxMem < 0 {
+		return fmt.Errorf("invalid oom maximum memory %d GiB", r.OOMMaxMem)
+	}
+	if r.MaxCost < 0 {
+		return fmt.Errorf("invalid maximum cost %v", r.MaxCost)
+	}
+	if _, err := flow.ParseMaxCostPolicy(r.MaxCostPolicy); err != nil {
+		return err
+	}
+	if r.MaxFanout < 0 {
+		return fmt.Errorf("invalid maximum fan-out %d", r.MaxFanout)
+	}
 	return nil
 }
 
@@ -153,9 +257,18 @@ func (r *CommonRunFlags) Configure(c *flow.EvalConfig) (err error) {
 		return err
 	}
 	c.NoCacheExtern = r.NoCacheExtern
+	c.CacheNamespace = r.CacheNamespace
+	c.DryRun = r.DryRun
 	c.RecomputeEmpty = r.RecomputeEmpty
 	c.BottomUp = r.EvalStrategy == "bottomup"
 	c.PostUseChecksum = r.PostUseChecksum
+	c.OOMMemMultiplier = r.OOMMultiplier
+	c.OOMMaxMemory = float64(r.OOMMaxMem) * (1 << 30)
+	c.MaxCost = r.MaxCost
+	c.MaxFanout = r.MaxFanout
+	if c.MaxCostPolicy, err = flow.ParseMaxCostPolicy(r.MaxCostPolicy); err != nil {
+		return err
+	}
 	if r.Invalidate != "" {
 		re := regexp.MustCompile(r.Invalidate)
 		c.Invalidate = func(f *flow.Flow) bool {
@@ -174,12 +287,25 @@ type RunFlags struct {
 	Dir string
 	// Local enables execution using the local docker instance.
 	Local bool
+	// Offline, together with Local, disables network access: the run uses
+	// only a local repository and assoc (under LocalDir) and local images.
+	Offline bool
 	// Trace when set enable tracing flow evaluation.
 	Trace bool
 	Cache bool
 	Pred  bool
 	// DotGraph enables computation of an evaluation graph.
 	DotGraph bool
+	// DotGraphCollapse collapses the fan-outs of the evaluation graph.
+	DotGraphCollapse bool
+	// DotGraphDepth, if positive, limits the depth of the evaluation graph.
+	DotGraphDepth int
+	// DotGraphIdent is a regular expression for the identifiers of the nodes in the evaluation graph.
+	DotGraphIdent string
+	// DotGraphMaxNodes is the number of nodes above which the evaluation graph's fan-outs are collapsed.
+	DotGraphMaxNodes int
+	// GraphAddr is the address on which an interactive evaluation graph is served, if any.
+	GraphAddr string
 
 	// BackgroundTimeout is the duration to wait for background tasks (such as cache writes, etc) to complete.
 	// ie, this is the amount of time we wait after the user's program execution finishes but before reflow exits.
@@ -197,6 +323,14 @@ func (r *RunFlags) localFlags(flags *flag.FlagSet) {
 	flags.BoolVar(&r.Local, "local", false, "execute flow on the Local Docker instance")
 	flags.StringVar(&r.LocalDir, "localdir", defaultFlowDir, "directory where execution state is stored in Local mode")
 	flags.StringVar(&r.Dir, "dir", "", "directory where execution state is stored in Local mode (alias for Local Dir for backwards compatibility)")
+	flags.BoolVar(&r.Offline, "offline", false, `run without network access (requires -local)
+
+In offline mode, the run caches its results in a repository and an
+assoc stored in the local directory (see -localdir), in place of the
+configured ones, and so reuses the results of previous offline runs.
+Execs must use images that are present in the local Docker daemon,
+and only local (file://) data may be interned or externed; nodes that
+require network access (e.g., s3 interns or image pulls) fail.`)
 }
 
 // FlagsNoLocal adds run flags to the provided flagset with the given prefix excluding flags pertaining to local runs.
@@ -222,7 +356,38 @@ Other output file formats also are available.
 
 When running a large reflow module with lots of nodes, it is advisable to 
 disable dotgraph generation to avoid slowing down the overall execution time 
-of your run.`)
+of your run. Alternatively, see -dotgraphcollapse, -dotgraphdepth and 
+-dotgraphident, which summarize the graph.`)
+	}
+	if names == nil || names[FlagNameDotGraphCollapse] {
+		flags.BoolVar(&r.DotGraphCollapse, prefix+string(FlagNameDotGraphCollapse), false, `collapse fan-outs in the evaluation graph
+
+If this flag is provided, like nodes (of the same operation, identifier and 
+source position) which are dependencies of the same node, e.g., the execs of a 
+map, are drawn as a single node annotated with their count, as are their own 
+dependencies. Fan-outs are always collapsed in graphs with more nodes than 
+"dotgraphmaxnodes".`)
+	}
+	if names == nil || names[FlagNameDotGraphDepth] {
+		flags.IntVar(&r.DotGraphDepth, prefix+string(FlagNameDotGraphDepth), 0, "if positive, the maximum depth of nodes in the evaluation graph")
+	}
+	if names == nil || names[FlagNameDotGraphIdent] {
+		flags.StringVar(&r.DotGraphIdent, prefix+string(FlagNameDotGraphIdent), "", "regular expression for the identifiers of nodes included in the evaluation graph")
+	}
+	if names == nil || names[FlagNameDotGraphMaxNodes] {
+		flags.IntVar(&r.DotGraphMaxNodes, prefix+string(FlagNameDotGraphMaxNodes), 0, fmt.Sprintf(`number of nodes above which fan-outs are collapsed in the evaluation graph
+
+If zero, fan-outs are collapsed in graphs of more than %d nodes; if negative, 
+only if "dotgraphcollapse" is provided.`, flow.DefaultDotMaxNodes))
+	}
+	if names == nil || names[FlagNameGraphAddr] {
+		flags.StringVar(&r.GraphAddr, prefix+string(FlagNameGraphAddr), "", `serve an interactive evaluation graph over HTTP on this address
+
+If this flag is provided (e.g., -graphaddr=localhost:8080), the evaluation 
+graph is served over HTTP while the run is evaluated. The graph shows the 
+state of each node (waiting, running, cached, failed or done), updated live, 
+and individual nodes may be inspected, including the inspect of their execs. 
+Unlike -dotgraph, the graph can be navigated even for very large runs.`)
 	}
 	if names == nil || names[FlagNamePred] {
 		flags.BoolVar(&r.Pred, prefix+string(FlagNamePred), false, `predict exec memory requirements to optimize resource usage
@@ -235,7 +400,8 @@ If the exec fails for any reason, with predicted resources, reflow will
 always retry the exec with the resources specified.
 
 Additionally, reflow always retries execs that fail due to a detectable OOM 
-error, using 50% more resources each time, upto 3 times, before giving up.
+error, using 50% more resources each time (see "oommultiplier" and 
+"oommaxmem"), upto 3 times, before giving up.
 
 Both "taskdb" and "predictorconfig" need to be configured for prediction to 
 work, see "reflow config -help"`)
@@ -276,11 +442,37 @@ func (r *RunFlags) Override(overrides map[string]string) (err error) {
 
 // Err checks if the flag values are consistent and valid.
 func (r *RunFlags) Err() error {
+	if r.Offline && !r.Local {
+		return fmt.Errorf("offline mode requires -local")
+	}
+	if r.DotGraphDepth < 0 {
+		return fmt.Errorf("invalid evaluation graph depth %d", r.DotGraphDepth)
+	}
+	if r.DotGraphIdent != "" {
+		if _, err := regexp.Compile(r.DotGraphIdent); err != nil {
+			return err
+		}
+	}
 	return r.CommonRunFlags.Err()
 }
 
+// DotOptions returns the options with which the run's evaluation
+// graph is summarized. It assumes the flags are valid (see Err).
+func (r *RunFlags) DotOptions() flow.DotOptions {
+	opts := flow.DotOptions{
+		Collapse: r.DotGraphCollapse,
+		Depth:    r.DotGraphDepth,
+		MaxNodes: r.DotGraphMaxNodes,
+	}
+	if r.DotGraphIdent != "" {
+		opts.Ident = regexp.MustCompile(r.DotGraphIdent)
+	}
+	return opts
+}
+
 // needAssocAndRepo determines whether an assoc and repo is needed based on this run flags.
-// We need assoc and repo if either the run is non-local, or a local run with cache enabled.
+// We need assoc and repo if either the run is non-local, or a local run with cache enabled
+// (as offline runs always are).
 func (r *RunFlags) needAssocAndRepo() bool {
-	return !r.Local || r.Cache
+	return !r.Local || r.Cache || r.Offline
 }
@@ -19,16 +19,22 @@ func TestRunFlags(t *testing.T) {
 	}{
 		{"", []string{}, RunFlags{
 			CommonRunFlags: CommonRunFlags{
-				EvalStrategy: "topdown",
-				Assert:       "never",
+				EvalStrategy:  "topdown",
+				Assert:        "never",
+				MaxCostPolicy: "abort",
+				OOMMultiplier: 1.5,
+				OOMMaxMem:     800,
 			},
 			DotGraph:          true,
 			BackgroundTimeout: 10 * time.Minute,
 		}, false},
 		{"", []string{"--pred=true"}, RunFlags{
 			CommonRunFlags: CommonRunFlags{
-				EvalStrategy: "topdown",
-				Assert:       "never",
+				EvalStrategy:  "topdown",
+				Assert:        "never",
+				MaxCostPolicy: "abort",
+				OOMMultiplier: 1.5,
+				OOMMaxMem:     800,
 			},
 			Pred:              true,
 			DotGraph:          true,
@@ -36,21 +42,69 @@ func TestRunFlags(t *testing.T) {
 		}, false},
 		{"prefix_", []string{}, RunFlags{
 			CommonRunFlags: CommonRunFlags{
-				EvalStrategy: "topdown",
-				Assert:       "never",
+				EvalStrategy:  "topdown",
+				Assert:        "never",
+				MaxCostPolicy: "abort",
+				OOMMultiplier: 1.5,
+				OOMMaxMem:     800,
 			},
 			DotGraph:          true,
 			BackgroundTimeout: 10 * time.Minute,
 		}, false},
 		{"prefix_", []string{"--prefix_pred=true"}, RunFlags{
 			CommonRunFlags: CommonRunFlags{
-				EvalStrategy: "topdown",
-				Assert:       "never",
+				EvalStrategy:  "topdown",
+				Assert:        "never",
+				MaxCostPolicy: "abort",
+				OOMMultiplier: 1.5,
+				OOMMaxMem:     800,
 			},
 			Pred:              true,
 			DotGraph:          true,
 			BackgroundTimeout: 10 * time.Minute,
 		}, false},
+		{"", []string{"--oommultiplier=2", "--oommaxmem=400"}, RunFlags{
+			CommonRunFlags: CommonRunFlags{
+				EvalStrategy:  "topdown",
+				Assert:        "never",
+				MaxCostPolicy: "abort",
+				OOMMultiplier: 2,
+				OOMMaxMem:     400,
+			},
+			DotGraph:          true,
+			BackgroundTimeout: 10 * time.Minute,
+		}, false},
+		{"", []string{"--maxcost=12.5", "--maxcostpolicy=pause"}, RunFlags{
+			CommonRunFlags: CommonRunFlags{
+				EvalStrategy:  "topdown",
+				Assert:        "never",
+				MaxCost:       12.5,
+				MaxCostPolicy: "pause",
+				OOMMultiplier: 1.5,
+				OOMMaxMem:     800,
+			},
+			DotGraph:          true,
+			BackgroundTimeout: 10 * time.Minute,
+		}, false},
+		{"", []string{"--dotgraphcollapse", "--dotgraphdepth=3", "--dotgraphident=align"}, RunFlags{
+			CommonRunFlags: CommonRunFlags{
+				EvalStrategy:  "topdown",
+				Assert:        "never",
+				MaxCostPolicy: "abort",
+				OOMMultiplier: 1.5,
+				OOMMaxMem:     800,
+			},
+			DotGraph:          true,
+			DotGraphCollapse:  true,
+			DotGraphDepth:     3,
+			DotGraphIdent:     "align",
+			BackgroundTimeout: 10 * time.Minute,
+		}, false},
+		{"", []string{"--dotgraphdepth=-1"}, RunFlags{}, true},
+		{"", []string{"--dotgraphident=("}, RunFlags{}, true},
+		{"", []string{"--oommultiplier=0.5"}, RunFlags{}, true},
+		{"", []string{"--maxcost=-1"}, RunFlags{}, true},
+		{"", []string{"--maxcostpolicy=stop"}, RunFlags{}, true},
 		{"prefix_", []string{"--pred=true"}, RunFlags{}, true},
 		{"", []string{"--prefix_pred=true"}, RunFlags{}, true},
 	} {
@@ -9,25 +9,31 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"net"
+	"net/http"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
 	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/grailbio/base/data"
 	"github.com/grailbio/base/digest"
 	"github.com/grailbio/base/state"
 	"github.com/grailbio/base/status"
 	"github.com/grailbio/infra"
 	"github.com/grailbio/reflow"
 	"github.com/grailbio/reflow/assoc"
+	"github.com/grailbio/reflow/ec2cluster"
 	"github.com/grailbio/reflow/errors"
 	"github.com/grailbio/reflow/flow"
 	infra2 "github.com/grailbio/reflow/infra"
 	"github.com/grailbio/reflow/log"
 	"github.com/grailbio/reflow/pool"
 	"github.com/grailbio/reflow/predictor"
+	"github.com/grailbio/reflow/repository"
 	"github.com/grailbio/reflow/runner"
 	"github.com/grailbio/reflow/sched"
 	"github.com/grailbio/reflow/syntax"
@@ -44,6 +50,12 @@ type RunnerParams struct {
 
 	// Status is the status object specific to a run.
 	Status *status.Status
+
+	// RunID, if valid, is the ID of an interrupted run to resume. The
+	// runner reuses the run's ID, so that its tasks remain associated
+	// with it in TaskDB, and its frontier, so that the execs completed
+	// by the interrupted run are not recomputed.
+	RunID taskdb.RunID
 }
 
 // ReflowRunner supports the ability to run a reflow program.
@@ -69,11 +81,16 @@ func (rt *runtime) NewRunner(params RunnerParams) (ReflowRunner, error) {
 			return nil, errors.E("runtime.NewRunner", errors.Fatal, err)
 		}
 		pred = predictor.New(rt.scheduler.TaskDB, params.Logger.Tee(nil, "predictor: "),
-			rt.predCfg.MinData, rt.predCfg.MaxInspect, rt.predCfg.MemPercentile)
+			rt.predCfg.MinData, rt.predCfg.MaxInspect, rt.predCfg.MemPercentile,
+			rt.predCfg.CPUPercentile, rt.predCfg.DiskPercentile)
+		pred.Online = rt.predCfg.Online
 	}
 	if err = infraRunConfig.Instance(&runID); err != nil {
 		return nil, err
 	}
+	if params.RunID.IsValid() {
+		*runID = params.RunID
+	}
 	var user *infra2.User
 	if err = rt.Config.Instance(&user); err != nil {
 		return nil, err
@@ -84,8 +101,9 @@ func (rt *runtime) NewRunner(params RunnerParams) (ReflowRunner, error) {
 		sess:      rt.sess,
 		scheduler: rt.scheduler,
 		predictor: pred,
-		Log:       params.Logger,
+		Log:       params.Logger.With(log.RunIDKey, runID.ID()),
 		user:      user.User(),
+		resume:    params.RunID.IsValid(),
 	}
 	r.status = params.Status
 	if r.status == nil {
@@ -109,6 +127,24 @@ func (rt *runtime) NewRunner(params RunnerParams) (ReflowRunner, error) {
 	if err = infraRunConfig.Instance(&r.labels); err != nil {
 		return nil, err
 	}
+	// Runs must satisfy the profile's label policy, if any, before they
+	// allocate any cluster resources.
+	var policy *infra2.LabelPolicy
+	if err = rt.Config.Instance(&policy); err == nil && policy != nil {
+		if r.labels, err = policy.Apply(r.labels); err != nil {
+			return nil, errors.E("runtime.NewRunner", errors.Precondition, err)
+		}
+	}
+	if err = rt.Config.Instance(&r.exporter); err != nil {
+		params.Logger.Debugf("run summaries will not be exported: %v", err)
+		r.exporter = nil
+	}
+	if params.RunConfig.RunFlags.Offline {
+		// Offline runs access only local data, and do not export
+		// their summaries.
+		rt.scheduler.Mux = infra2.OfflineBlobMux(infra2.FileStore(rt.Config))
+		r.exporter = nil
+	}
 	return r, nil
 }
 
@@ -159,9 +195,13 @@ type runnerImpl struct {
 	assoc  assoc.Assoc
 	cache  *infra2.CacheProvider
 	labels pool.Labels
+	// exporter (optional) exports the run's summary on completion.
+	exporter taskdb.Exporter
 
 	status *status.Status
 	user   string
+	// resume tells whether the runner resumes an interrupted run.
+	resume bool
 }
 
 func (r *runnerImpl) SetDotWriter(w io.Writer) {
@@ -183,8 +223,18 @@ func (r *runnerImpl) Go(ctx context.Context) (runner.State, error) {
 	if err != nil {
 		return runner.State{}, err
 	}
-	if err = e.ResolveImages(r.sess); err != nil {
-		return runner.State{}, err
+	// Images cannot be resolved in offline mode; they are used as given.
+	images := &ImageAssertions{}
+	if !r.RunConfig.RunFlags.Offline {
+		auth, err := ImageAuthenticator(r.RunConfig.Config, r.sess)
+		if err != nil {
+			return runner.State{}, err
+		}
+		if err = e.ResolveImages(auth); err != nil {
+			return runner.State{}, err
+		}
+		images.Resolver = &ImageResolver{Authenticator: auth}
+		images.ImageMap, images.Arm64ImageMap = e.ImageMap, e.Arm64ImageMap
 	}
 	path, err := filepath.Abs(e.Program)
 	if err != nil {
@@ -225,6 +275,9 @@ func (r *runnerImpl) Go(ctx context.Context) (runner.State, error) {
 		panic("unexpectedly scheduler is nil")
 	}
 	tdb := r.scheduler.TaskDB
+	if tdb != nil && r.resume {
+		r.abandonTasks(tctx, tdb)
+	}
 	if tdb != nil {
 		if rerr := tdb.CreateRun(tctx, r.RunID, r.user); rerr != nil {
 			r.Log.Debugf("error writing run to taskdb: %v", rerr)
@@ -236,24 +289,34 @@ func (r *runnerImpl) Go(ctx context.Context) (runner.State, error) {
 	run := runner.Runner{
 		Flow: e.Main(),
 		EvalConfig: flow.EvalConfig{
-			Log:                r.Log,
-			Repository:         r.repo,
-			Snapshotter:        r.scheduler.Mux,
-			Assoc:              r.assoc,
-			AssertionGenerator: reflow.AssertionGeneratorMux{reflow.BlobAssertionsNamespace: r.scheduler.Mux},
-			CacheMode:          r.cache.CacheMode,
-			Status:             r.status.Group(r.RunID.IDShort()),
-			Scheduler:          r.scheduler,
-			Predictor:          r.predictor,
-			ImageMap:           e.ImageMap,
-			RunID:              r.RunID,
-			DotWriter:          r.DotWriter,
+			Log:         r.Log,
+			Repository:  r.repo,
+			Snapshotter: r.scheduler.Mux,
+			Assoc:       r.assoc,
+			AssertionGenerator: reflow.AssertionGeneratorMux{
+				reflow.BlobAssertionsNamespace:   r.scheduler.Mux,
+				reflow.DockerAssertionsNamespace: images,
+			},
+			CacheMode:     r.cache.CacheMode,
+			Status:        r.status.Group(r.RunID.IDShort()),
+			Scheduler:     r.scheduler,
+			Predictor:     r.predictor,
+			ImageMap:      e.ImageMap,
+			Arm64ImageMap: e.Arm64ImageMap,
+			RunID:         r.RunID,
+			DotWriter:     r.DotWriter,
+			DotOptions:    r.RunConfig.RunFlags.DotOptions(),
 		},
 		Type:    e.MainType(),
 		Labels:  r.labels,
 		Cmdline: r.cmdline,
 	}
 
+	// Share the scheduler's presence index of the repository, so that
+	// objects known to be present are not checked for by cache lookups.
+	if m, ok := r.scheduler.Transferer.(*repository.Manager); ok && r.repo != nil {
+		run.EvalConfig.RepositoryIndex = m.Index[r.repo.URL().String()]
+	}
 	if err = r.RunConfig.RunFlags.Configure(&run.EvalConfig); err != nil {
 		return runner.State{}, err
 	}
@@ -275,6 +338,26 @@ func (r *runnerImpl) Go(ctx context.Context) (runner.State, error) {
 	if err = stateFile.Marshal(run.State); err != nil {
 		return runner.State{}, errors.E("failed to marshal state: %v", err)
 	}
+	frontier, err := flow.OpenFileFrontier(base + ".frontier")
+	if err != nil {
+		return runner.State{}, err
+	}
+	defer frontier.Close()
+	if r.resume {
+		r.Log.Printf("resuming run %s: %d completed execs", r.RunID.IDShort(), frontier.Len())
+	}
+	run.EvalConfig.Frontier = frontier
+	if addr := r.RunConfig.RunFlags.GraphAddr; addr != "" {
+		var l net.Listener
+		if l, err = net.Listen("tcp", addr); err != nil {
+			return runner.State{}, errors.E("serve evaluation graph", err)
+		}
+		defer l.Close()
+		graph := flow.NewGraphServer()
+		run.EvalConfig.Graph = graph
+		go func() { _ = http.Serve(l, graph) }()
+		r.Log.Printf("serving evaluation graph on http://%s/", l.Addr())
+	}
 	r.wg = new(wg.WaitGroup)
 	ctx, bgcancel := flow.WithBackground(ctx, r.wg)
 
@@ -296,6 +379,14 @@ func (r *runnerImpl) Go(ctx context.Context) (runner.State, error) {
 				r.Log.Debugf("error writing run result to taskdb: %v", errTDB)
 			}
 		}
+		if r.exporter != nil {
+			summary := r.runSummary(tctx, tdb, run.State)
+			if err := r.exporter.ExportRun(tctx, summary); err != nil {
+				r.Log.Errorf("export run summary: %v", err)
+			} else {
+				r.Log.Debugf("exported summary of run %s", r.RunID.IDShort())
+			}
+		}
 	}()
 
 	if run.Err != nil {
@@ -303,11 +394,64 @@ func (r *runnerImpl) Go(ctx context.Context) (runner.State, error) {
 	} else {
 		r.Log.Printf("result: %s", run.Result)
 	}
+	r.logTransfers()
 	r.waitForBackgroundTasks(r.RunConfig.RunFlags.BackgroundTimeout)
 	bgcancel()
 	return run.State, nil
 }
 
+// logTransfers logs a summary of the data moved by the tasks of the
+// run, by task type, so that users may tell when data movement (and
+// not computation) dominates the run's runtime and cost.
+func (r *runnerImpl) logTransfers() {
+	transfers := r.scheduler.Stats.GetStats().RunTransfers(r.RunID.ID())
+	if len(transfers) == 0 {
+		return
+	}
+	types := make([]string, 0, len(transfers))
+	for typ := range transfers {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "data movement:")
+	for _, typ := range types {
+		ts := transfers[typ]
+		fmt.Fprintf(&b, "\n\t%s: %d tasks, loaded %s, saved %s", typ, ts.Tasks, data.Size(ts.BytesLoaded), data.Size(ts.BytesSaved))
+	}
+	r.Log.Print(b.String())
+}
+
+// abandonTasks marks the tasks of the (resumed) run which were in
+// flight when it was interrupted as canceled, so that they are not
+// reported as running. The resumed run does not re-attach to them:
+// their execs are run again by the resumed evaluation (as new tasks),
+// even if their allocs are still running them.
+func (r *runnerImpl) abandonTasks(ctx context.Context, tdb taskdb.TaskDB) {
+	tasks, err := tdb.Tasks(ctx, taskdb.TaskQuery{RunID: r.RunID})
+	if err != nil {
+		r.Log.Debugf("tasks of run %s: %v", r.RunID.IDShort(), err)
+	}
+	var (
+		n           int
+		now         = time.Now()
+		interrupted = errors.E(errors.Canceled, errors.New("run interrupted"))
+	)
+	for _, task := range tasks {
+		if !task.End.IsZero() {
+			continue
+		}
+		if terr := tdb.SetTaskComplete(ctx, task.ID, interrupted, now); terr != nil {
+			r.Log.Debugf("taskdb settaskcomplete %s: %v", task.ID.IDShort(), terr)
+			continue
+		}
+		n++
+	}
+	if n > 0 {
+		r.Log.Printf("run %s: abandoned %d tasks in flight when interrupted; their execs are run again", r.RunID.IDShort(), n)
+	}
+}
+
 // GetRunID is a getter for the runID associated with the runner.
 func (r *runnerImpl) GetRunID() taskdb.RunID {
 	return r.RunID
@@ -364,6 +508,56 @@ func (r *runnerImpl) setRunComplete(ctx context.Context, tdb taskdb.TaskDB, endT
 	return err
 }
 
+// runSummary returns the summary of the run with the given (final)
+// state. If tdb is non-nil, the run's tasks are used to compute
+// its (on-demand, upper bound) cost. If the scheduler's cluster prices
+// instances itself (e.g., net of reserved coverage), its prices are used.
+func (r *runnerImpl) runSummary(ctx context.Context, tdb taskdb.TaskDB, state runner.State) taskdb.RunSummary {
+	s := taskdb.RunSummary{
+		RunID:       r.RunID,
+		User:        r.user,
+		Program:     state.Program,
+		Labels:      r.labels,
+		Start:       state.Created,
+		End:         state.Completion,
+		Execs:       state.Execs,
+		CachedExecs: state.CachedExecs,
+	}
+	if state.Err != nil {
+		s.FailureClass = state.Err.Kind.String()
+		s.Error = state.Err.Error()
+	}
+	if tdb == nil {
+		return s
+	}
+	tasks, err := tdb.Tasks(ctx, taskdb.TaskQuery{RunID: r.RunID, WithAlloc: true})
+	if err != nil {
+		r.Log.Debugf("run summary: tasks: %v", err)
+	}
+	var region string
+	if r.sess != nil {
+		region = aws.StringValue(r.sess.Config.Region)
+	}
+	price := func(typ string) float64 { return ec2cluster.OnDemandPrice(typ, region) }
+	if r.scheduler != nil {
+		if pricer, ok := r.scheduler.Cluster.(interface{ InstancePriceUSD(string) float64 }); ok {
+			price = pricer.InstancePriceUSD
+		}
+	}
+	s.Tasks = len(tasks)
+	for _, t := range tasks {
+		if t.Alloc == nil || t.Alloc.Pool == nil {
+			continue
+		}
+		a, p := t.Alloc, t.Alloc.Pool
+		start, end := t.StartEnd()
+		cost := price(p.PoolType) * end.Sub(start).Hours()
+		// Scale the cost of the pool by the task's share of it.
+		s.CostUSD += cost * t.Resources.MaxRatio(a.Resources) * a.Resources.MaxRatio(p.Resources)
+	}
+	return s
+}
+
 // UploadBundle generates a bundle and updates taskdb with its digest. If the bundle does not already exist in taskdb,
 // uploadBundle caches it.
 func (r *runnerImpl) uploadBundle(ctx context.Context, tdb taskdb.TaskDB, runID taskdb.RunID, bundle *syntax.Bundle, file string, args []string) error {
@@ -8,7 +8,6 @@ import (
 	"github.com/grailbio/base/status"
 	"github.com/grailbio/base/sync/once"
 	"github.com/grailbio/infra"
-	"github.com/grailbio/reflow/blob"
 	"github.com/grailbio/reflow/blob/s3blob"
 	"github.com/grailbio/reflow/ec2cluster"
 	"github.com/grailbio/reflow/errors"
@@ -111,7 +110,14 @@ func (rt *runtime) init() (err error) {
 	if err = rt.Config.Instance(&rt.sess); err != nil {
 		return errors.E("runtime.Init", "session", errors.Fatal, err)
 	}
-	rt.scheduler.Mux = blob.Mux{"s3": s3blob.New(rt.sess)}
+	rt.scheduler.Mux = infra2.BlobMux(rt.Config, rt.sess)
+	role, err := scopedCredentialsRole(rt.Config)
+	if err != nil {
+		return errors.E("runtime.Init", "scopedcredentials", errors.Fatal, err)
+	}
+	if role != "" {
+		rt.scheduler.Scoper = s3blob.NewScoper(rt.sess, role)
+	}
 
 	// We do not validate predictor config in the runtime because
 	// - The default predictor config will not validate on non-EC2 machines (eg: laptops), preventing runs.
@@ -154,5 +160,14 @@ func (rt *runtime) doStart(ctx context.Context) {
 
 func (rt *runtime) WaitDone() {
 	rt.wg.Wait()
+	if m, ok := rt.scheduler.Transferer.(*repository.Manager); ok {
+		for _, index := range m.Index {
+			if b, ok := index.(*repository.BloomIndex); ok {
+				if err := b.Save(); err != nil {
+					rt.rtLog.Errorf("save presence index: %v", err)
+				}
+			}
+		}
+	}
 	rt.rtLog.Printf("===== shutdown =====")
 }
@@ -1,8 +1,12 @@
 package runtime
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/grailbio/infra"
 	"github.com/grailbio/reflow"
@@ -21,16 +25,31 @@ const (
 	// The number of concurrent stat operations that can
 	// be performed against a repository.
 	statLimit = 200
+
+	// The number of objects for which presence indexes are sized,
+	// and their false positive rate.
+	presenceIndexSize = 1 << 20
+	presenceIndexFP   = 1e-9
+
+	// The maximum time spent loading the instance type history.
+	instanceTypeHistoryTimeout = time.Minute
 )
 
 // newScheduler returns a new scheduler with the specified configuration.
 // Cancelling the returned context.CancelFunc stops the scheduler.
 func newScheduler(config infra.Config, logger *log.Logger) (*sched.Scheduler, error) {
 	var (
-		err   error
-		tdb   taskdb.TaskDB
-		repo  reflow.Repository
-		limit int
+		err       error
+		tdb       taskdb.TaskDB
+		repo      reflow.Repository
+		limit     int
+		outOfDisk sched.OutOfDiskPolicy
+		retry     sched.RetryPolicy
+		destLimit *repository.Limits
+		protect   bool
+		protectD  time.Duration
+		history   time.Duration
+		prefetch  int
 	)
 	if err = config.Instance(&tdb); err != nil {
 		if !strings.HasPrefix(err.Error(), "no providers for type taskdb.TaskDB") {
@@ -44,6 +63,24 @@ func newScheduler(config infra.Config, logger *log.Logger) (*sched.Scheduler, er
 	if limit, err = transferLimit(config); err != nil {
 		return nil, err
 	}
+	if outOfDisk, err = outOfDiskPolicy(config); err != nil {
+		return nil, err
+	}
+	if retry, err = retryPolicy(config); err != nil {
+		return nil, err
+	}
+	if destLimit, err = transferDestLimits(config); err != nil {
+		return nil, err
+	}
+	if protect, protectD, err = terminationProtection(config); err != nil {
+		return nil, err
+	}
+	if history, err = instanceTypeHistory(config); err != nil {
+		return nil, err
+	}
+	if prefetch, err = prefetchImages(config); err != nil {
+		return nil, err
+	}
 	transferer := &repository.Manager{
 		Status:           nil,
 		PendingTransfers: repository.NewLimits(limit),
@@ -52,12 +89,35 @@ func newScheduler(config infra.Config, logger *log.Logger) (*sched.Scheduler, er
 	}
 	if repo != nil {
 		transferer.PendingTransfers.Set(repo.URL().String(), int(^uint(0)>>1))
+		index, err := presenceIndex(config, repo, logger)
+		if err != nil {
+			return nil, err
+		}
+		transferer.Index = map[string]repository.PresenceIndex{repo.URL().String(): index}
 	}
 	scheduler := sched.New()
 
 	scheduler.Transferer = transferer
 	scheduler.Log = logger.Tee(nil, "scheduler: ")
 	scheduler.TaskDB = tdb
+	scheduler.OutOfDisk = outOfDisk
+	scheduler.RetryPolicy = retry
+	scheduler.TransferLimits = destLimit
+	scheduler.Protect = protect
+	scheduler.ProtectDuration = protectD
+	if prefetch >= 0 {
+		scheduler.PrefetchImages = prefetch
+	}
+	if tdb != nil && history > 0 {
+		// The stats are best-effort: the scheduler learns from the
+		// tasks it runs in any case.
+		ctx, cancel := context.WithTimeout(context.Background(), instanceTypeHistoryTimeout)
+		now := time.Now()
+		if err := scheduler.InstanceTypes.Load(ctx, tdb, now.Add(-history), now); err != nil {
+			logger.Errorf("loading instance type history: %v", err)
+		}
+		cancel()
+	}
 	scheduler.ExportStats()
 
 	return scheduler, nil
@@ -75,3 +135,331 @@ func transferLimit(config infra.Config) (int, error) {
 	}
 	return v, nil
 }
+
+// outOfDiskPolicy returns the configured policy for handling tasks
+// which run out of disk space.
+func outOfDiskPolicy(config infra.Config) (sched.OutOfDiskPolicy, error) {
+	v := config.Value("outofdisk")
+	if v == nil {
+		return sched.OutOfDiskFail, nil
+	}
+	s, ok := v.(string)
+	if !ok {
+		return sched.OutOfDiskFail, errors.New(fmt.Sprintf("non-string out-of-disk policy %v", v))
+	}
+	return sched.ParseOutOfDiskPolicy(s)
+}
+
+// retryPolicy returns the configured policy for retrying failed
+// attempts of tasks, or nil if the scheduler's default applies.
+// "retrypolicy" is a map of error kinds (as named in errors.Kind's
+// JSON encoding, e.g., "Net") to rules, which override the rules of
+// sched.DefaultRetryPolicy:
+//
+//	retrypolicy:
+//	  Net: {retries: 10, backoff: 1s, maxbackoff: 1m}
+//	  Unavailable: {retries: -1, backoff: 10s}
+//	  OOM: {retries: 2, results: true, escalate: {mem: 2}, max: {mem: 858993459200}}
+//
+// A rule's retries is the maximum number of retries (negative for
+// unlimited; zero, the default, disables retries); backoff and maxbackoff are the
+// initial and maximum delays before retrying; results applies the
+// rule to the errors of execs' results; and escalate gives the factors
+// by which resources are scaled, up to max, when a task is retried.
+func retryPolicy(config infra.Config) (sched.RetryPolicy, error) {
+	v := config.Value("retrypolicy")
+	if v == nil {
+		return nil, nil
+	}
+	rules, ok := v.(map[interface{}]interface{})
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("invalid retry policy %v", v))
+	}
+	policy := make(sched.KindRetryPolicy)
+	for k, r := range sched.DefaultRetryPolicy {
+		policy[k] = r
+	}
+	for k, v := range rules {
+		name, ok := k.(string)
+		if !ok {
+			return nil, errors.New(fmt.Sprintf("non-string error kind %v in retry policy", k))
+		}
+		kind, ok := errors.ParseKind(name)
+		if !ok {
+			return nil, errors.E(errors.Invalid, errors.Errorf("unknown error kind %q in retry policy", name))
+		}
+		rule, err := retryRule(v)
+		if err != nil {
+			return nil, errors.E(errors.Invalid, errors.Errorf("retry policy for %s: %v", name, err))
+		}
+		if rule.MaxRetries == 0 {
+			delete(policy, kind)
+		} else {
+			policy[kind] = rule
+		}
+	}
+	return policy, nil
+}
+
+// retryRule parses a rule of the "retrypolicy" configuration.
+func retryRule(v interface{}) (sched.RetryRule, error) {
+	var rule sched.RetryRule
+	fields, ok := v.(map[interface{}]interface{})
+	if !ok {
+		return rule, errors.Errorf("invalid rule %v", v)
+	}
+	var escalate, max reflow.Resources
+	for k, v := range fields {
+		var err error
+		switch k {
+		case "retries":
+			if n, ok := v.(int); ok {
+				rule.MaxRetries = n
+			} else {
+				err = errors.Errorf("non-integer retries %v", v)
+			}
+		case "backoff":
+			rule.Backoff, err = configDuration(v)
+		case "maxbackoff":
+			rule.MaxBackoff, err = configDuration(v)
+		case "results":
+			if b, ok := v.(bool); ok {
+				rule.Results = b
+			} else {
+				err = errors.Errorf("non-boolean results %v", v)
+			}
+		case "escalate":
+			escalate, err = configResources(v)
+		case "max":
+			max, err = configResources(v)
+		default:
+			err = errors.Errorf("unknown field %v", k)
+		}
+		if err != nil {
+			return rule, err
+		}
+	}
+	if escalate != nil {
+		rule.Escalate = sched.ScaleResources(escalate, max)
+	}
+	return rule, nil
+}
+
+// configDuration parses a duration (e.g., "1m") of the configuration.
+func configDuration(v interface{}) (time.Duration, error) {
+	s, ok := v.(string)
+	if !ok {
+		return 0, errors.Errorf("non-string duration %v", v)
+	}
+	return time.ParseDuration(s)
+}
+
+// configResources parses a map of resource names to amounts of the
+// configuration.
+func configResources(v interface{}) (reflow.Resources, error) {
+	m, ok := v.(map[interface{}]interface{})
+	if !ok {
+		return nil, errors.Errorf("invalid resources %v", v)
+	}
+	r := make(reflow.Resources)
+	for k, v := range m {
+		name, ok := k.(string)
+		if !ok {
+			return nil, errors.Errorf("non-string resource %v", k)
+		}
+		switch n := v.(type) {
+		case int:
+			r[name] = float64(n)
+		case float64:
+			r[name] = n
+		default:
+			return nil, errors.Errorf("non-numeric amount %v of resource %s", v, name)
+		}
+	}
+	return r, nil
+}
+
+// terminationProtection returns whether the instances of allocs are
+// protected from termination while they run critical tasks and the
+// expected task duration beyond which tasks are considered critical.
+// "terminationprotection" is either a boolean, which enables the
+// protection for execs marked critical, or a duration (e.g., "6h"),
+// which additionally enables it for tasks expected to run for at
+// least that long.
+func terminationProtection(config infra.Config) (bool, time.Duration, error) {
+	switch v := config.Value("terminationprotection").(type) {
+	case nil:
+		return false, 0, nil
+	case bool:
+		return v, 0, nil
+	case string:
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return false, 0, errors.E(errors.Invalid, errors.Errorf("invalid termination protection duration %q: %v", v, err))
+		}
+		return true, d, nil
+	default:
+		return false, 0, errors.New(fmt.Sprintf("invalid termination protection %v", v))
+	}
+}
+
+// prefetchImages returns the configured maximum number of images which
+// new allocs are asked to prefetch (see sched.Scheduler.PrefetchImages),
+// or -1 if the scheduler's default applies. "prefetchimages" is an
+// integer; zero disables prefetching.
+func prefetchImages(config infra.Config) (int, error) {
+	switch v := config.Value("prefetchimages").(type) {
+	case nil:
+		return -1, nil
+	case int:
+		if v < 0 {
+			return 0, errors.E(errors.Invalid, errors.Errorf("invalid number of images to prefetch %d", v))
+		}
+		return v, nil
+	default:
+		return 0, errors.New(fmt.Sprintf("non-integer number of images to prefetch %v", v))
+	}
+}
+
+// instanceTypeHistory returns the duration of the task history from
+// which the scheduler learns how instance types perform for each
+// ident (see sched.InstanceTypeStats), or zero if it does not load
+// any history. "instancetypehistory" is a duration (e.g., "168h").
+func instanceTypeHistory(config infra.Config) (time.Duration, error) {
+	switch v := config.Value("instancetypehistory").(type) {
+	case nil:
+		return 0, nil
+	case string:
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return 0, errors.E(errors.Invalid, errors.Errorf("invalid instance type history %q: %v", v, err))
+		}
+		return d, nil
+	default:
+		return 0, errors.New(fmt.Sprintf("non-string instance type history %v", v))
+	}
+}
+
+// scopedCredentialsRole returns the ARN of the IAM role assumed to
+// mint per-task credentials scoped to the data the task transfers, or
+// "" if credentials are not scoped. The role configured by
+// "scopedcredentials" must be assumable by the instance profile of
+// the process; scoped credentials never permit more than the role.
+func scopedCredentialsRole(config infra.Config) (string, error) {
+	switch v := config.Value("scopedcredentials").(type) {
+	case nil:
+		return "", nil
+	case string:
+		if !strings.HasPrefix(v, "arn:") {
+			return "", errors.E(errors.Invalid, errors.Errorf("invalid scoped credentials role %q: expected an ARN", v))
+		}
+		return v, nil
+	default:
+		return "", errors.New(fmt.Sprintf("non-string scoped credentials role %v", v))
+	}
+}
+
+// transferDestLimits returns the configured limits on the number of
+// concurrent intern and extern transfers per destination, or nil if
+// none are configured. "transferdestlimit" is either a single limit,
+// applied to every destination, or a map of destinations (e.g.,
+// "s3://bucket") to limits, where the key "default" gives the limit
+// for destinations which are not otherwise listed:
+//
+//	transferdestlimit:
+//	  default: 100
+//	  s3://hot-bucket: 20
+func transferDestLimits(config infra.Config) (*repository.Limits, error) {
+	switch v := config.Value("transferdestlimit").(type) {
+	case nil:
+		return nil, nil
+	case int:
+		return repository.NewLimits(v), nil
+	case map[interface{}]interface{}:
+		var (
+			def       = int(^uint(0) >> 1)
+			overrides = make(map[string]int)
+		)
+		for k, lim := range v {
+			dest, ok := k.(string)
+			if !ok {
+				return nil, errors.New(fmt.Sprintf("non-string transfer destination %v", k))
+			}
+			n, ok := lim.(int)
+			if !ok {
+				return nil, errors.New(fmt.Sprintf("non-integer limit %v for transfer destination %s", lim, dest))
+			}
+			if dest == "default" {
+				def = n
+			} else {
+				overrides[dest] = n
+			}
+		}
+		limits := repository.NewLimits(def)
+		for dest, n := range overrides {
+			limits.Set(dest, n)
+		}
+		return limits, nil
+	default:
+		return nil, errors.New(fmt.Sprintf("invalid transfer destination limit %v", v))
+	}
+}
+
+// presenceIndex returns the presence index of the given repository.
+// If "presenceindex" is configured (as the maximum age of the index,
+// e.g., "24h"), the index is loaded from (and saved to) a file in
+// $HOME/.reflow/presence, so that it is shared across runs; the
+// maximum age should be well below the threshold used to collect
+// objects from the repository. Otherwise, the index is maintained
+// only for the lifetime of the process.
+func presenceIndex(config infra.Config, repo reflow.Repository, logger *log.Logger) (*repository.BloomIndex, error) {
+	v := config.Value("presenceindex")
+	if v == nil {
+		return repository.NewBloomIndex(presenceIndexSize, presenceIndexFP), nil
+	}
+	s, ok := v.(string)
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("non-string presence index max age %v", v))
+	}
+	maxAge, err := time.ParseDuration(s)
+	if err != nil {
+		return nil, errors.E("presenceindex", err)
+	}
+	path, err := presenceIndexPath(repo)
+	if err != nil {
+		return nil, err
+	}
+	index, err := repository.LoadBloomIndex(path, maxAge, presenceIndexSize, presenceIndexFP)
+	if err != nil {
+		logger.Errorf("presence index: %v; using a new index", err)
+		return repository.NewBloomIndex(presenceIndexSize, presenceIndexFP), nil
+	}
+	logger.Debugf("presence index %s: %d objects", path, index.Len())
+	return index, nil
+}
+
+// presenceIndexPath returns the path of the saved presence index of
+// the given repository.
+func presenceIndexPath(repo reflow.Repository) (string, error) {
+	rundir, err := reflow.Rundir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(rundir), "presence", reflow.Digester.FromString(repo.URL().String()).Hex()), nil
+}
+
+// ResetPresenceIndex discards the saved presence index of the given
+// repository, if any. It must be called after objects are removed
+// from the repository (e.g., by reflow gc), since the index would
+// otherwise continue to report them as present. Indexes saved by
+// other hosts are bounded only by their maximum age.
+func ResetPresenceIndex(repo reflow.Repository) error {
+	path, err := presenceIndexPath(repo)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return errors.E("resetpresenceindex", path, err)
+	}
+	return nil
+}
@@ -14,7 +14,6 @@ import (
 	"github.com/grailbio/infra"
 	"github.com/grailbio/infra/tls"
 	"github.com/grailbio/reflow"
-	"github.com/grailbio/reflow/blob/s3blob"
 	"github.com/grailbio/reflow/ec2cluster"
 	"github.com/grailbio/reflow/errors"
 	infra2 "github.com/grailbio/reflow/infra"
@@ -52,7 +51,7 @@ func ClusterInstance(config infra.Config) (runner.Cluster, error) {
 	// such global registration altogether. The current way of doing this
 	// also ties the binary to specific implementations (e.g., s3), which
 	// should be avoided.
-	blobrepo.Register("s3", s3blob.New(sess))
+	blobrepo.Register("s3", infra2.S3Store(config, sess))
 	// TODO(swami): Why is this needed and can we avoid this?
 	repositoryhttp.HTTPClient, err = HttpClient(config)
 	if err != nil {
@@ -0,0 +1,61 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package sched
+
+import (
+	"container/heap"
+	"time"
+)
+
+const (
+	// defaultAgingInterval is the default Scheduler.AgingInterval.
+	defaultAgingInterval = 5 * time.Minute
+	// defaultStarvationThreshold is the default Scheduler.StarvationThreshold.
+	defaultStarvationThreshold = 30 * time.Minute
+)
+
+// agingPeriod returns the period at which queued tasks must be aged
+// given the provided aging interval and starvation threshold, or zero
+// if they need not be aged.
+func agingPeriod(interval, threshold time.Duration) time.Duration {
+	period := interval
+	if period == 0 || (threshold > 0 && threshold < period) {
+		period = threshold
+	}
+	return period / 2
+}
+
+// priority returns the task's effective priority: its priority raised
+// by the number of levels by which it was aged while queued.
+func (t *Task) priority() int {
+	return t.Priority - t.aged
+}
+
+// age ages the tasks in the queue todo: the priority of a task is
+// raised by one level for every AgingInterval for which it has been
+// queued, so that tasks are not displaced indefinitely by a stream of
+// higher priority tasks. Tasks which have been queued for longer than
+// StarvationThreshold are reported as starved.
+func (s *Scheduler) age(todo *taskq, now time.Time) {
+	var changed bool
+	for _, task := range *todo {
+		queued := now.Sub(task.queued)
+		if s.AgingInterval > 0 {
+			if aged := int(queued / s.AgingInterval); aged != task.aged {
+				task.aged = aged
+				changed = true
+			}
+		}
+		if s.StarvationThreshold > 0 && queued > s.StarvationThreshold && !task.starved {
+			task.starved = true
+			s.Stats.MarkStarved()
+			s.Log.Errorf("task %s (flow %s) is starved: queued for %s (priority %d, effective priority %d, resources %s)",
+				task.ID().IDShort(), task.FlowID.Short(), queued.Round(time.Second), task.Priority, task.priority(), task.config().Resources)
+		}
+	}
+	if changed {
+		heap.Init(todo)
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package sched
+
+import (
+	"container/heap"
+	"testing"
+	"time"
+
+	"github.com/grailbio/reflow"
+)
+
+func TestAge(t *testing.T) {
+	var (
+		s   = New()
+		now = time.Now()
+		q   taskq
+	)
+	newTask := func(priority int, cpu float64, queued time.Duration) *Task {
+		task := NewTask()
+		task.Priority = priority
+		task.Config.Resources = reflow.Resources{"cpu": cpu, "mem": 1 << 30}
+		task.queued = now.Add(-queued)
+		heap.Push(&q, task)
+		return task
+	}
+	// A stream of big, high-priority tasks, and a small, low-priority
+	// task which has been queued for a while.
+	for i := 0; i < 10; i++ {
+		newTask(0, 32, time.Duration(i)*time.Second)
+	}
+	small := newTask(2, 1, 3*s.AgingInterval)
+	if q[0] == small {
+		t.Fatal("small task should not be first before aging")
+	}
+	s.age(&q, now)
+	if got, want := small.priority(), -1; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if q[0] != small {
+		t.Errorf("aged task is not first in the queue")
+	}
+	if got, want := s.Stats.GetStats().StarvedTasks, int64(0); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// Starvation is reported once per task; the small task has
+	// been queued for longer than the others.
+	later := now.Add(s.StarvationThreshold - 2*s.AgingInterval)
+	s.age(&q, later)
+	s.age(&q, later.Add(time.Minute))
+	if got, want := s.Stats.GetStats().StarvedTasks, int64(1); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestAgingPeriod(t *testing.T) {
+	for _, c := range []struct {
+		interval, threshold, want time.Duration
+	}{
+		{0, 0, 0},
+		{4 * time.Minute, 0, 2 * time.Minute},
+		{0, 4 * time.Minute, 2 * time.Minute},
+		{10 * time.Minute, 4 * time.Minute, 2 * time.Minute},
+		{4 * time.Minute, 10 * time.Minute, 2 * time.Minute},
+	} {
+		if got := agingPeriod(c.interval, c.threshold); got != c.want {
+			t.Errorf("agingPeriod(%v, %v): got %v, want %v", c.interval, c.threshold, got, c.want)
+		}
+	}
+}
@@ -7,6 +7,7 @@ package sched
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/grailbio/base/digest"
@@ -80,6 +81,23 @@ type alloc struct {
 
 	// taskdbAllocID is the alloc's ID in taskdb.
 	taskdbAllocID digest.Digest
+
+	// diskSuspect is set when a task on this alloc ran out of disk space.
+	// Suspect allocs are not assigned any more tasks, and are released
+	// once idle.
+	diskSuspect bool
+
+	// domain is the alloc's failure domain, if known.
+	domain FailureDomain
+	// instanceType is the alloc's instance type, if known.
+	instanceType string
+
+	// execsMu guards execs, which is read while orphaned execs are
+	// reaped.
+	execsMu sync.Mutex
+	// execs is the set of IDs of the execs of the tasks assigned to
+	// this alloc.
+	execs map[digest.Digest]bool
 }
 
 // Init is called to initialize the alloc from its underlying Reflow alloc.
@@ -101,6 +119,13 @@ func (a *alloc) String() string {
 	return fmt.Sprintf("%s available %s", a.ID(), a.Available)
 }
 
+// arm64 tells whether the alloc is of the arm64 architecture. This is
+// determined from the alloc's total resources: its available arm64
+// resources are depleted by the tasks assigned to it.
+func (a *alloc) arm64() bool {
+	return a.Resources()[reflow.Arm64] > 0
+}
+
 // Assign updates this alloc to account for the provided task
 // assignment.
 func (a *alloc) Assign(task *Task) {
@@ -109,7 +134,13 @@ func (a *alloc) Assign(task *Task) {
 	}
 	task.alloc = a
 	a.Pending++
-	a.Available.Sub(a.Available, task.Config.Resources)
+	a.Available.Sub(a.Available, task.config().Resources)
+	a.execsMu.Lock()
+	if a.execs == nil {
+		a.execs = make(map[digest.Digest]bool)
+	}
+	a.execs[task.execID()] = true
+	a.execsMu.Unlock()
 }
 
 // Unassign updates this alloc to account for the completion of the
@@ -119,13 +150,24 @@ func (a *alloc) Unassign(task *Task) {
 		panic("sched: unassigned from wrong alloc")
 	}
 	a.Pending--
-	a.Available.Add(a.Available, task.Config.Resources)
+	a.Available.Add(a.Available, task.config().Resources)
 	if a.Pending == 0 {
 		a.idleTime = time.Now()
 	}
+	a.execsMu.Lock()
+	delete(a.execs, task.execID())
+	a.execsMu.Unlock()
 	task.alloc = nil
 }
 
+// assigned tells whether the exec with the given ID belongs to a task
+// assigned to this alloc.
+func (a *alloc) assigned(id digest.Digest) bool {
+	a.execsMu.Lock()
+	defer a.execsMu.Unlock()
+	return a.execs[id]
+}
+
 // IdleFor returns the time passed since the alloc had zero
 // assigned tasks.
 func (a *alloc) IdleFor() time.Duration {
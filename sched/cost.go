@@ -0,0 +1,63 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package sched
+
+import (
+	"time"
+
+	"github.com/grailbio/reflow"
+	"github.com/grailbio/reflow/pool"
+)
+
+// Pricer is implemented by clusters which can price the allocs
+// they provide. If the scheduler's cluster implements Pricer, the
+// scheduler attributes an estimated cost to each task it runs.
+type Pricer interface {
+	// AllocHourlyCostUSD returns the hourly cost in USD attributable
+	// to the given alloc, or zero if it is not known.
+	AllocHourlyCostUSD(alloc pool.Alloc) float64
+}
+
+// taskCostUSD returns the estimated cost in USD of running a task
+// with the given resources on an alloc with the given hourly cost and
+// resources for the duration d. The task is charged for its
+// (dominant) share of the alloc's resources.
+func taskCostUSD(hourlyUSD float64, alloc, task reflow.Resources, d time.Duration) float64 {
+	if hourlyUSD <= 0 || d <= 0 {
+		return 0
+	}
+	var share float64
+	for key, v := range task {
+		if alloc[key] > 0 && v/alloc[key] > share {
+			share = v / alloc[key]
+		}
+	}
+	if share > 1 {
+		share = 1
+	}
+	return hourlyUSD * share * d.Hours()
+}
+
+// addCost accounts the cost of running the task (for the duration d)
+// on the given alloc, if the scheduler's cluster can price it.
+func (s *Scheduler) addCost(task *Task, alloc *alloc, d time.Duration) {
+	pricer, ok := s.Cluster.(Pricer)
+	if !ok {
+		return
+	}
+	cost := taskCostUSD(pricer.AllocHourlyCostUSD(alloc.Alloc), alloc.Resources(), task.config().Resources, d)
+	task.mu.Lock()
+	task.costUSD += cost
+	task.mu.Unlock()
+}
+
+// CostUSD returns the estimated cost in USD of the task's attempts
+// so far. The cost is only known if the scheduler's cluster
+// implements Pricer; otherwise CostUSD returns zero.
+func (t *Task) CostUSD() float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.costUSD
+}
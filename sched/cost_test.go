@@ -0,0 +1,35 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package sched
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/grailbio/reflow"
+)
+
+func TestTaskCostUSD(t *testing.T) {
+	alloc := reflow.Resources{"cpu": 16, "mem": 64 << 30}
+	for _, tc := range []struct {
+		hourly float64
+		task   reflow.Resources
+		d      time.Duration
+		want   float64
+	}{
+		{2, reflow.Resources{"cpu": 4, "mem": 8 << 30}, time.Hour, 0.5},
+		// The task is charged for its dominant share.
+		{2, reflow.Resources{"cpu": 1, "mem": 32 << 30}, 30 * time.Minute, 0.5},
+		// The share is capped at the whole alloc.
+		{2, reflow.Resources{"cpu": 32}, time.Hour, 2},
+		{0, reflow.Resources{"cpu": 4}, time.Hour, 0},
+		{2, reflow.Resources{"cpu": 4}, 0, 0},
+	} {
+		if got, want := taskCostUSD(tc.hourly, alloc, tc.task, tc.d), tc.want; math.Abs(got-want) > 1e-9 {
+			t.Errorf("taskCostUSD(%v, %v, %v): got %v, want %v", tc.hourly, tc.task, tc.d, got, want)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package sched
+
+import (
+	"time"
+
+	"github.com/grailbio/reflow/pool"
+)
+
+// defaultSpreadTimeout is the default Scheduler.SpreadTimeout.
+const defaultSpreadTimeout = 5 * time.Minute
+
+// A FailureDomain identifies the hardware on which an alloc runs:
+// its availability zone and instance family. Tasks which fail on
+// degraded hardware are likely to fail identically when retried in
+// the same failure domain.
+type FailureDomain struct {
+	// Zone is the availability zone of the alloc's instance.
+	Zone string
+	// Family is the family (e.g., "m5") of the alloc's instance type.
+	Family string
+}
+
+// IsZero tells whether the failure domain is unknown.
+func (d FailureDomain) IsZero() bool {
+	return d == FailureDomain{}
+}
+
+// String returns the failure domain as "zone/family".
+func (d FailureDomain) String() string {
+	return d.Zone + "/" + d.Family
+}
+
+// Domainer is implemented by clusters which can determine the failure
+// domains of the allocs they provide. If the scheduler's cluster
+// implements Domainer, the scheduler spreads the retries of failed
+// tasks across failure domains: a task is preferably not placed on
+// an alloc in a failure domain in which a previous attempt failed.
+type Domainer interface {
+	// AllocFailureDomain returns the failure domain of the given
+	// alloc, or a zero FailureDomain if it is not known.
+	AllocFailureDomain(alloc pool.Alloc) FailureDomain
+}
+
+// failureDomain returns the failure domain of the given alloc, if the
+// scheduler's cluster can determine it.
+func (s *Scheduler) failureDomain(alloc pool.Alloc) FailureDomain {
+	domainer, ok := s.Cluster.(Domainer)
+	if !ok {
+		return FailureDomain{}
+	}
+	return domainer.AllocFailureDomain(alloc)
+}
+
+// avoids tells whether the task should not be placed on the given
+// alloc because a previous attempt of the task failed in the alloc's
+// failure domain. Tasks stop avoiding failure domains once they have
+// been queued for longer than the scheduler's SpreadTimeout, so that
+// they are not starved when no other failure domain is available.
+func (s *Scheduler) avoids(task *Task, alloc *alloc) bool {
+	if alloc.domain.IsZero() || time.Since(task.queued) > s.SpreadTimeout {
+		return false
+	}
+	task.mu.Lock()
+	defer task.mu.Unlock()
+	for _, d := range task.failedDomains {
+		if d == alloc.domain {
+			return true
+		}
+	}
+	return false
+}
+
+// spreadAlloc returns the smallest of the given allocs on which the
+// task fits and which the task does not avoid, or nil if there is none.
+func (s *Scheduler) spreadAlloc(task *Task, allocs allocq) *alloc {
+	var best *alloc
+	for _, alloc := range allocs {
+		if !alloc.Available.Available(task.config().Resources) || s.avoids(task, alloc) {
+			continue
+		}
+		if best == nil || alloc.Available.ScaledDistance(nil) < best.Available.ScaledDistance(nil) {
+			best = alloc
+		}
+	}
+	return best
+}
+
+// addFailedDomain records that an attempt of the task failed in the
+// given failure domain.
+func (t *Task) addFailedDomain(d FailureDomain) {
+	if d.IsZero() {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, fd := range t.failedDomains {
+		if fd == d {
+			return
+		}
+	}
+	t.failedDomains = append(t.failedDomains, d)
+}
+
+// FailedDomains returns the failure domains in which attempts of the
+// task failed.
+func (t *Task) FailedDomains() []FailureDomain {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]FailureDomain{}, t.failedDomains...)
+}
+
+// SpreadFrom makes the scheduler avoid placing the task in the failure
+// domains in which attempts of the given (previous) task failed. It is
+// used when a failed task is retried as a new task, and must be called
+// before the task is submitted.
+func (t *Task) SpreadFrom(prev *Task) {
+	for _, d := range prev.FailedDomains() {
+		t.addFailedDomain(d)
+	}
+}
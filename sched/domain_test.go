@@ -0,0 +1,78 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package sched
+
+import (
+	"container/heap"
+	"testing"
+	"time"
+
+	"github.com/grailbio/reflow"
+)
+
+func TestAssignSpread(t *testing.T) {
+	var (
+		degraded = FailureDomain{Zone: "us-west-2a", Family: "m5"}
+		other    = FailureDomain{Zone: "us-west-2b", Family: "m5"}
+		s        = New()
+	)
+	newTask := func(failed ...FailureDomain) *Task {
+		task := NewTask()
+		task.Config.Resources = reflow.Resources{"cpu": 1, "mem": 1 << 30}
+		task.queued = time.Now()
+		for _, d := range failed {
+			task.addFailedDomain(d)
+		}
+		return task
+	}
+	newAllocs := func() (*alloc, *alloc, allocq) {
+		// The alloc in the degraded domain is the smallest, and thus
+		// preferred by default.
+		small := &alloc{Available: reflow.Resources{"cpu": 2, "mem": 4 << 30}, domain: degraded}
+		large := &alloc{Available: reflow.Resources{"cpu": 8, "mem": 16 << 30}, domain: other}
+		var allocs allocq
+		heap.Push(&allocs, small)
+		heap.Push(&allocs, large)
+		return small, large, allocs
+	}
+
+	small, large, allocs := newAllocs()
+	fresh, retried := newTask(), newTask(degraded)
+	var tasks taskq
+	heap.Push(&tasks, fresh)
+	heap.Push(&tasks, retried)
+	if got, want := len(s.assignArch(&tasks, &allocs)), 2; got != want {
+		t.Fatalf("got %v assigned tasks, want %v", got, want)
+	}
+	if fresh.alloc != small {
+		t.Error("task was not assigned to the smallest alloc")
+	}
+	if retried.alloc != large {
+		t.Error("retried task was not assigned outside of its failed domain")
+	}
+
+	// A task which failed in every available domain is deferred.
+	_, _, allocs = newAllocs()
+	stuck := newTask(degraded, other)
+	tasks = taskq{}
+	heap.Push(&tasks, stuck)
+	if got := s.assignArch(&tasks, &allocs); len(got) != 0 {
+		t.Errorf("got %v assigned tasks, want none", len(got))
+	}
+	if got, want := len(tasks), 1; got != want {
+		t.Errorf("got %v queued tasks, want %v", got, want)
+	}
+	// ... until it has waited for longer than the spread timeout.
+	stuck.queued = time.Now().Add(-2 * s.SpreadTimeout)
+	if got, want := len(s.assignArch(&tasks, &allocs)), 1; got != want {
+		t.Errorf("got %v assigned tasks, want %v", got, want)
+	}
+
+	retry := NewTask()
+	retry.SpreadFrom(stuck)
+	if got, want := retry.FailedDomains(), []FailureDomain{degraded, other}; len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,323 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package sched
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/grailbio/base/digest"
+	"github.com/grailbio/reflow/errors"
+	"github.com/grailbio/reflow/pool"
+	"github.com/grailbio/reflow/taskdb"
+)
+
+const (
+	// minInstanceTypeTasks is the number of tasks of an ident that
+	// must have run on an instance type before the type's performance
+	// for the ident is judged.
+	minInstanceTypeTasks = 3
+	// maxInstanceTypeTasks is the number of tasks beyond which the
+	// outcomes recorded for an ident and instance type are halved, so
+	// that recent outcomes outweigh older ones.
+	maxInstanceTypeTasks = 100
+	// poorPenalty is the penalty at or above which an instance type is
+	// considered to perform poorly for an ident.
+	poorPenalty = 0.5
+)
+
+// InstanceTyper is implemented by clusters which can determine the
+// instance types of the allocs they provide. If the scheduler's
+// cluster implements InstanceTyper, the scheduler learns how each
+// instance type performs for the tasks of each ident (see
+// InstanceTypeStats), and de-prioritizes instance types which
+// consistently perform poorly for an ident.
+type InstanceTyper interface {
+	// AllocInstanceType returns the instance type of the given
+	// alloc, or "" if it is not known.
+	AllocInstanceType(alloc pool.Alloc) string
+}
+
+// instanceType returns the instance type of the given alloc, if the
+// scheduler's cluster can determine it.
+func (s *Scheduler) instanceType(alloc pool.Alloc) string {
+	typer, ok := s.Cluster.(InstanceTyper)
+	if !ok {
+		return ""
+	}
+	return typer.AllocInstanceType(alloc)
+}
+
+// InstanceTypeOutcomes are the outcomes of the tasks of an ident which
+// ran on an instance type.
+type InstanceTypeOutcomes struct {
+	// Tasks is the number of tasks which ran to completion (or failed).
+	Tasks float64
+	// Failed is the number of tasks whose execs failed, including
+	// those which ran out of memory.
+	Failed float64
+	// OOM is the number of tasks whose execs ran out of memory.
+	OOM float64
+	// Succeeded is the number of tasks whose execs succeeded, and
+	// Duration their total duration.
+	Succeeded float64
+	Duration  time.Duration
+}
+
+// mean returns the mean duration of the successful tasks.
+func (o InstanceTypeOutcomes) mean() time.Duration {
+	if o.Succeeded == 0 {
+		return 0
+	}
+	return time.Duration(float64(o.Duration) / o.Succeeded)
+}
+
+// InstanceTypeStats records the outcomes of tasks per ident and
+// instance type. Instance types on which the tasks of an ident
+// consistently fail, run out of memory, or run much slower than on
+// other instance types are penalized for the ident (see Penalties):
+// the scheduler prefers other allocs for the ident's tasks, and
+// clusters which receive the penalties (see InstanceTypePenalties)
+// prefer other instance types when allocating for them. Penalized
+// instance types are de-prioritized, not excluded: they are still
+// used if nothing else is available.
+type InstanceTypeStats struct {
+	mu sync.Mutex
+	// outcomes maps idents to instance types to outcomes.
+	outcomes map[string]map[string]*InstanceTypeOutcomes
+}
+
+// NewInstanceTypeStats returns a new, empty, InstanceTypeStats.
+func NewInstanceTypeStats() *InstanceTypeStats {
+	return &InstanceTypeStats{outcomes: make(map[string]map[string]*InstanceTypeOutcomes)}
+}
+
+// Add records the outcome of a task of the given ident which ran on
+// the given instance type for duration d: err is the exec's error, if
+// it failed.
+func (s *InstanceTypeStats) Add(ident, typ string, d time.Duration, err error) {
+	if ident == "" || typ == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	types := s.outcomes[ident]
+	if types == nil {
+		types = make(map[string]*InstanceTypeOutcomes)
+		s.outcomes[ident] = types
+	}
+	o := types[typ]
+	if o == nil {
+		o = new(InstanceTypeOutcomes)
+		types[typ] = o
+	}
+	if o.Tasks >= maxInstanceTypeTasks {
+		o.Tasks /= 2
+		o.Failed /= 2
+		o.OOM /= 2
+		o.Succeeded /= 2
+		o.Duration /= 2
+	}
+	o.Tasks++
+	switch {
+	case err == nil:
+		o.Succeeded++
+		o.Duration += d
+	case errors.Is(errors.OOM, err):
+		o.OOM++
+		fallthrough
+	default:
+		o.Failed++
+	}
+}
+
+// Outcomes returns the outcomes recorded for the given ident, keyed by
+// instance type.
+func (s *InstanceTypeStats) Outcomes(ident string) map[string]InstanceTypeOutcomes {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m := make(map[string]InstanceTypeOutcomes, len(s.outcomes[ident]))
+	for typ, o := range s.outcomes[ident] {
+		m[typ] = *o
+	}
+	return m
+}
+
+// Penalties returns the instance types which perform poorly for the
+// given ident, mapped to their penalties, in (0, 1]. An instance type
+// is judged once at least minInstanceTypeTasks tasks of the ident ran
+// on it. Its penalty is the larger of the rate at which the ident's
+// tasks failed on it, and its slowness: 1-f/m, where m is the mean
+// duration of the ident's successful tasks on it, and f is the
+// smallest such mean among the judged instance types. Only penalties
+// of at least poorPenalty (e.g., half of the tasks failed, or they
+// ran twice as slow) are returned.
+func (s *InstanceTypeStats) Penalties(ident string) map[string]float64 {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var fastest time.Duration
+	for _, o := range s.outcomes[ident] {
+		if o.Succeeded < minInstanceTypeTasks {
+			continue
+		}
+		if m := o.mean(); fastest == 0 || m < fastest {
+			fastest = m
+		}
+	}
+	var penalties map[string]float64
+	for typ, o := range s.outcomes[ident] {
+		if o.Tasks < minInstanceTypeTasks {
+			continue
+		}
+		penalty := o.Failed / o.Tasks
+		if m := o.mean(); fastest > 0 && o.Succeeded >= minInstanceTypeTasks && m > 0 {
+			if slow := 1 - float64(fastest)/float64(m); slow > penalty {
+				penalty = slow
+			}
+		}
+		if penalty < poorPenalty {
+			continue
+		}
+		if penalties == nil {
+			penalties = make(map[string]float64)
+		}
+		penalties[typ] = penalty
+	}
+	return penalties
+}
+
+// taskPenalties returns the instance type penalties of the given
+// tasks: the largest penalty of each instance type among the tasks'
+// idents.
+func (s *InstanceTypeStats) taskPenalties(tasks []*Task) map[string]float64 {
+	if s == nil {
+		return nil
+	}
+	var (
+		penalties map[string]float64
+		idents    = make(map[string]bool)
+	)
+	for _, task := range tasks {
+		ident := task.config().Ident
+		if idents[ident] {
+			continue
+		}
+		idents[ident] = true
+		for typ, p := range s.Penalties(ident) {
+			if penalties == nil {
+				penalties = make(map[string]float64)
+			}
+			if p > penalties[typ] {
+				penalties[typ] = p
+			}
+		}
+	}
+	return penalties
+}
+
+// Load records the outcomes of the tasks in the given TaskDB which
+// were active between since and until, so that the scheduler benefits
+// from the performance of instance types in previous runs. Tasks which
+// did not complete, or which were lost (e.g., because their alloc
+// died), are ignored.
+func (s *InstanceTypeStats) Load(ctx context.Context, tdb taskdb.TaskDB, since, until time.Time) error {
+	tasks, err := tdb.Tasks(ctx, taskdb.TaskQuery{Since: since, Until: until})
+	if err != nil {
+		return errors.E("instancetypestats", "load", err)
+	}
+	var (
+		ids  []digest.Digest
+		seen = make(map[digest.Digest]bool)
+	)
+	for _, task := range tasks {
+		if task.AllocID.IsZero() || seen[task.AllocID] {
+			continue
+		}
+		seen[task.AllocID] = true
+		ids = append(ids, task.AllocID)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	allocs, err := tdb.Allocs(ctx, taskdb.AllocQuery{IDs: ids})
+	if err != nil {
+		return errors.E("instancetypestats", "load", err)
+	}
+	types := make(map[digest.Digest]string, len(allocs))
+	for _, alloc := range allocs {
+		if alloc.Pool != nil {
+			types[alloc.ID] = alloc.Pool.PoolType
+		}
+	}
+	for _, task := range tasks {
+		typ := types[task.AllocID]
+		if typ == "" || task.End.IsZero() || task.Start.IsZero() {
+			continue
+		}
+		var err error
+		if task.Err.Kind != errors.Other || task.Err.Err != nil {
+			err = &task.Err
+		}
+		if isLost(err) {
+			continue
+		}
+		s.Add(task.Ident, typ, task.End.Sub(task.Start), err)
+	}
+	return nil
+}
+
+// isLost tells whether a task which failed with the given error was
+// lost, rather than failed by its exec.
+func isLost(err error) bool {
+	return errors.Is(errors.Canceled, err) || errors.Is(errors.Net, err) ||
+		errors.Is(errors.Timeout, err) || errors.Is(errors.Unavailable, err)
+}
+
+type instanceTypePenaltiesKey struct{}
+
+// WithInstanceTypePenalties returns a context which carries the given
+// instance type penalties (see InstanceTypeStats.Penalties). The
+// scheduler passes the penalties of the tasks for which it requests an
+// alloc to its cluster's Allocate in this way.
+func WithInstanceTypePenalties(ctx context.Context, penalties map[string]float64) context.Context {
+	if len(penalties) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, instanceTypePenaltiesKey{}, penalties)
+}
+
+// InstanceTypePenalties returns the instance type penalties carried by
+// the given context, if any. Clusters should prefer instance types
+// with smaller penalties.
+func InstanceTypePenalties(ctx context.Context) map[string]float64 {
+	penalties, _ := ctx.Value(instanceTypePenaltiesKey{}).(map[string]float64)
+	return penalties
+}
+
+// preferredAlloc returns the alloc among allocs on which the task fits,
+// which the task does not avoid, and whose instance type has the
+// smallest penalty for the task's ident. It returns the given alloc,
+// which the task fits, unless its instance type is penalized and
+// another alloc's is penalized less.
+func (s *Scheduler) preferredAlloc(task *Task, alloc *alloc, allocs allocq) *alloc {
+	penalties := s.InstanceTypes.Penalties(task.config().Ident)
+	if len(penalties) == 0 || penalties[alloc.instanceType] == 0 {
+		return alloc
+	}
+	best := alloc
+	for _, a := range allocs {
+		if !a.Available.Available(task.config().Resources) || s.avoids(task, a) {
+			continue
+		}
+		if penalties[a.instanceType] < penalties[best.instanceType] {
+			best = a
+		}
+	}
+	return best
+}
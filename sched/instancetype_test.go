@@ -0,0 +1,125 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package sched
+
+import (
+	"container/heap"
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/grailbio/reflow"
+	"github.com/grailbio/reflow/errors"
+)
+
+func TestInstanceTypePenalties(t *testing.T) {
+	s := NewInstanceTypeStats()
+	for i := 0; i < 4; i++ {
+		s.Add("align", "m5.xlarge", time.Minute, nil)
+		// Tasks run twice as slow on c5.xlarge...
+		s.Add("align", "c5.xlarge", 2*time.Minute, nil)
+		// ... and run out of memory on r5.large half the time.
+		s.Add("align", "r5.large", time.Minute, nil)
+		s.Add("align", "r5.large", 0, errors.E(errors.OOM, errors.New("killed")))
+		// Too few tasks ran on m5.large to judge it.
+		if i < 2 {
+			s.Add("align", "m5.large", 0, errors.New("exit status 1"))
+		}
+		// Tasks of other idents are not affected.
+		s.Add("sort", "c5.xlarge", 10*time.Minute, nil)
+	}
+	if got, want := s.Penalties("align"), map[string]float64{"c5.xlarge": 0.5, "r5.large": 0.5}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got := s.Penalties("sort"); got != nil {
+		t.Errorf("got %v, want none", got)
+	}
+	if got, want := s.Outcomes("align")["r5.large"], (InstanceTypeOutcomes{Tasks: 8, Failed: 4, OOM: 4, Succeeded: 4, Duration: 4 * time.Minute}); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	var tasks []*Task
+	for _, ident := range []string{"align", "sort"} {
+		task := NewTask()
+		task.Config.Ident = ident
+		tasks = append(tasks, task)
+	}
+	s.Add("sort", "c5.xlarge", 0, errors.New("exit status 1"))
+	for i := 0; i < 3; i++ {
+		s.Add("sort", "r5.large", 0, errors.New("exit status 1"))
+	}
+	if got, want := s.taskPenalties(tasks), map[string]float64{"c5.xlarge": 0.5, "r5.large": 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	ctx := WithInstanceTypePenalties(context.Background(), map[string]float64{"r5.large": 1})
+	if got, want := InstanceTypePenalties(ctx), map[string]float64{"r5.large": 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got := InstanceTypePenalties(context.Background()); got != nil {
+		t.Errorf("got %v, want none", got)
+	}
+}
+
+func TestInstanceTypeRecency(t *testing.T) {
+	s := NewInstanceTypeStats()
+	for i := 0; i < maxInstanceTypeTasks; i++ {
+		s.Add("align", "r5.large", 0, errors.New("exit status 1"))
+	}
+	if got, want := s.Penalties("align")["r5.large"], 1.0; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	// Recent successes outweigh older failures.
+	for i := 0; i < maxInstanceTypeTasks; i++ {
+		s.Add("align", "r5.large", time.Minute, nil)
+	}
+	if got := s.Penalties("align"); got != nil {
+		t.Errorf("got %v, want none", got)
+	}
+}
+
+func TestAssignInstanceType(t *testing.T) {
+	s := New()
+	for i := 0; i < minInstanceTypeTasks; i++ {
+		s.InstanceTypes.Add("align", "r5.large", 0, errors.E(errors.OOM, errors.New("killed")))
+	}
+	newTask := func(ident string) *Task {
+		task := NewTask()
+		task.Config.Ident = ident
+		task.Config.Resources = reflow.Resources{"cpu": 1, "mem": 1 << 30}
+		task.queued = time.Now()
+		return task
+	}
+	// The alloc of the penalized instance type is the smallest, and
+	// thus preferred by default.
+	small := &alloc{Available: reflow.Resources{"cpu": 2, "mem": 4 << 30}, instanceType: "r5.large"}
+	large := &alloc{Available: reflow.Resources{"cpu": 8, "mem": 16 << 30}, instanceType: "m5.2xlarge"}
+	var allocs allocq
+	heap.Push(&allocs, small)
+	heap.Push(&allocs, large)
+	align, sort := newTask("align"), newTask("sort")
+	var tasks taskq
+	heap.Push(&tasks, align)
+	heap.Push(&tasks, sort)
+	if got, want := len(s.assignArch(&tasks, &allocs)), 2; got != want {
+		t.Fatalf("got %v assigned tasks, want %v", got, want)
+	}
+	if align.alloc != large {
+		t.Error("task was not assigned outside of its penalized instance type")
+	}
+	if sort.alloc != small {
+		t.Error("task was not assigned to the smallest alloc")
+	}
+
+	// Penalized instance types are used if nothing else fits.
+	allocs = allocq{}
+	heap.Push(&allocs, &alloc{Available: reflow.Resources{"cpu": 2, "mem": 4 << 30}, instanceType: "r5.large"})
+	tasks = taskq{}
+	heap.Push(&tasks, newTask("align"))
+	if got, want := len(s.assignArch(&tasks, &allocs)), 1; got != want {
+		t.Errorf("got %v assigned tasks, want %v", got, want)
+	}
+}
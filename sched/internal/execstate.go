@@ -77,6 +77,11 @@ func (e ExecState) Next(ctx context.Context, err error, postUseChecksum bool) (n
 	case errors.NonRetryable(err):
 		msg = fmt.Sprintf("non-retryable error: %v", err)
 		next = StateDone
+	case errors.Is(errors.OutOfDisk, err):
+		// Retrying on the same alloc is unlikely to help; the scheduler
+		// decides how to handle out-of-disk errors.
+		msg = fmt.Sprintf("out of disk: %v", err)
+		next = StateDone
 	default:
 		msg = fmt.Sprintf("retryable error: %v", err)
 		nextIsRetry = true
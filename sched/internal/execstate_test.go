@@ -65,6 +65,15 @@ func TestExecStateNext(t *testing.T) {
 			wantNext:        StatePut,
 			wantNextIsRetry: true,
 		},
+		{
+			name:            "out of disk error should go to stateDone",
+			state:           StateLoad,
+			ctx:             bgCtx,
+			err:             errors.E(errors.OutOfDisk, "some out of disk error"),
+			postUseChecksum: false,
+			wantNext:        StateDone,
+			wantNextIsRetry: false,
+		},
 		{
 			name:            "retryable errors (other than in stateWait) should retry same state",
 			state:           StateUnload,
@@ -132,7 +132,7 @@ type TestCluster struct {
 
 func NewTestCluster() *TestCluster {
 	return &TestCluster{
-		max:  reflow.Resources{"cpu": 64, "mem": 256 << 30},
+		max:  reflow.Resources{"cpu": 64, "mem": 256 << 30, "disk": 1 << 40},
 		reqs: make(chan testClusterAllocReq),
 	}
 }
@@ -0,0 +1,74 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package sched
+
+import (
+	"time"
+
+	"github.com/grailbio/reflow"
+	"golang.org/x/sync/errgroup"
+)
+
+// defaultOrphanInterval is the default interval at which the execs
+// of live allocs are reconciled with the tasks assigned to them.
+const defaultOrphanInterval = 10 * time.Minute
+
+// reapOrphans kills the orphaned execs of the given allocs: execs
+// which have yet to complete, but which are not referenced by any
+// task assigned to their alloc. Orphaned execs are left behind, for
+// example, by tasks which were lost (e.g., because their alloc was
+// briefly unreachable) and since retried elsewhere, or by a
+// scheduler which crashed while they were running on an alloc that
+// was later adopted. They would otherwise hold the alloc's resources
+// until they complete.
+func (s *Scheduler) reapOrphans(allocs []*alloc) {
+	var g errgroup.Group
+	for _, a := range allocs {
+		a := a
+		g.Go(func() error {
+			n, reclaimed, err := a.reapOrphans()
+			if err != nil {
+				s.Log.Debugf("alloc %s: reap orphaned execs: %v", a.id, err)
+			}
+			if n > 0 {
+				s.Log.Printf("alloc %s: killed %d orphaned execs, reclaiming %s", a.id, n, reclaimed)
+			}
+			return nil
+		})
+	}
+	_ = g.Wait()
+}
+
+// reapOrphans kills the orphaned execs of alloc a, and returns the
+// number of execs killed and the resources they held.
+func (a *alloc) reapOrphans() (n int, reclaimed reflow.Resources, err error) {
+	ctx := a.Context
+	execs, err := a.Execs(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
+	for _, x := range execs {
+		if a.assigned(x.ID()) {
+			continue
+		}
+		resp, err := x.Inspect(ctx, nil)
+		if err != nil {
+			return n, reclaimed, err
+		}
+		if resp.Inspect == nil || resp.Inspect.State == "complete" || resp.Inspect.State == "zombie" {
+			continue
+		}
+		// The exec may have been assigned while it was inspected.
+		if a.assigned(x.ID()) {
+			continue
+		}
+		if err := a.Remove(ctx, x.ID()); err != nil {
+			return n, reclaimed, err
+		}
+		n++
+		reclaimed.Add(reclaimed, resp.Inspect.Config.Resources)
+	}
+	return n, reclaimed, nil
+}
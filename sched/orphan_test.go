@@ -0,0 +1,91 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package sched
+
+import (
+	"context"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/grailbio/base/digest"
+	"github.com/grailbio/reflow"
+	"github.com/grailbio/reflow/pool"
+)
+
+type orphanExec struct {
+	reflow.Exec
+	id    digest.Digest
+	state string
+	cpu   float64
+}
+
+func (x *orphanExec) ID() digest.Digest { return x.id }
+
+func (x *orphanExec) Inspect(ctx context.Context, repo *url.URL) (reflow.InspectResponse, error) {
+	inspect := reflow.ExecInspect{State: x.state}
+	inspect.Config.Resources = reflow.Resources{"cpu": x.cpu}
+	return reflow.InspectResponse{Inspect: &inspect}, nil
+}
+
+// orphanAlloc is an alloc which records the execs removed from it.
+type orphanAlloc struct {
+	pool.Alloc
+	execs   []*orphanExec
+	removed []digest.Digest
+}
+
+func (a *orphanAlloc) Execs(ctx context.Context) ([]reflow.Exec, error) {
+	execs := make([]reflow.Exec, len(a.execs))
+	for i := range a.execs {
+		execs[i] = a.execs[i]
+	}
+	return execs, nil
+}
+
+func (a *orphanAlloc) Remove(ctx context.Context, id digest.Digest) error {
+	a.removed = append(a.removed, id)
+	return nil
+}
+
+func TestReapOrphans(t *testing.T) {
+	var (
+		assigned, lost = NewTask(), NewTask()
+		done, zombie   = NewTask(), NewTask()
+		orphan         = NewTask()
+	)
+	for _, task := range []*Task{assigned, lost, done, zombie, orphan} {
+		task.Init()
+	}
+	execs := []*orphanExec{
+		{id: digest.Digest(assigned.ID()), state: "running", cpu: 1},
+		{id: digest.Digest(lost.ID()), state: "running", cpu: 2},
+		{id: digest.Digest(done.ID()), state: "complete", cpu: 4},
+		{id: digest.Digest(zombie.ID()), state: "zombie", cpu: 8},
+		{id: digest.Digest(orphan.ID()), state: "waiting", cpu: 16},
+	}
+	pa := &orphanAlloc{execs: execs}
+	a := newAlloc()
+	a.Alloc = pa
+	a.Context = context.Background()
+	a.Available = reflow.Resources{"cpu": 32}
+	a.Assign(assigned)
+	a.Assign(lost)
+	a.Unassign(lost)
+
+	n, reclaimed, err := a.reapOrphans()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := n, 2; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := reclaimed, (reflow.Resources{"cpu": 18}); !got.Equal(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := pa.removed, []digest.Digest{digest.Digest(lost.ID()), digest.Digest(orphan.ID())}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
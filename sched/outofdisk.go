@@ -0,0 +1,79 @@
+// Copyright 2018 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package sched
+
+import (
+	"fmt"
+
+	"github.com/grailbio/reflow"
+)
+
+const (
+	// maxOutOfDiskRetries is the maximum number of times a task is
+	// retried because its alloc ran out of disk space.
+	maxOutOfDiskRetries = 3
+	// defaultOutOfDiskFactor is the default factor by which a task's
+	// disk requirement is increased when it is retried after running
+	// out of disk space.
+	defaultOutOfDiskFactor = 2.0
+)
+
+// OutOfDiskPolicy determines how the scheduler handles tasks which
+// fail because their alloc ran out of disk space.
+type OutOfDiskPolicy int
+
+const (
+	// OutOfDiskFail fails the task with the out-of-disk error, as with
+	// any other fatal error.
+	OutOfDiskFail OutOfDiskPolicy = iota
+	// OutOfDiskRetry marks the alloc's disk as suspect, so that no
+	// further tasks are assigned to it and it is released once its
+	// remaining tasks complete, and retries the task on another alloc
+	// with a larger disk requirement.
+	OutOfDiskRetry
+)
+
+// String returns the name of the policy, as accepted by ParseOutOfDiskPolicy.
+func (p OutOfDiskPolicy) String() string {
+	switch p {
+	case OutOfDiskFail:
+		return "fail"
+	case OutOfDiskRetry:
+		return "retry"
+	default:
+		return fmt.Sprintf("OutOfDiskPolicy(%d)", p)
+	}
+}
+
+// ParseOutOfDiskPolicy parses an OutOfDiskPolicy from its name.
+func ParseOutOfDiskPolicy(s string) (OutOfDiskPolicy, error) {
+	switch s {
+	case "fail":
+		return OutOfDiskFail, nil
+	case "retry":
+		return OutOfDiskRetry, nil
+	default:
+		return OutOfDiskFail, fmt.Errorf("unknown out-of-disk policy %q (must be one of \"fail\", \"retry\")", s)
+	}
+}
+
+// outOfDiskAdjust returns a copy of the given resources with the disk
+// requirement increased by the scheduler's OutOfDiskFactor. Tasks with
+// a disk requirement smaller than the scheduler's minimum allocation are
+// adjusted relative to the minimum allocation instead.
+func (s *Scheduler) outOfDiskAdjust(r reflow.Resources) reflow.Resources {
+	var adjusted reflow.Resources
+	adjusted.Set(r)
+	factor := s.OutOfDiskFactor
+	if factor <= 1 {
+		factor = defaultOutOfDiskFactor
+	}
+	disk := adjusted["disk"]
+	if min := s.MinAlloc["disk"]; disk < min {
+		disk = min
+	}
+	adjusted["disk"] = disk * factor
+	return adjusted
+}
@@ -0,0 +1,86 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package sched
+
+import (
+	"sort"
+
+	"github.com/grailbio/reflow"
+)
+
+// defaultPrefetchImages is the default maximum number of images
+// prefetched on a new alloc.
+const defaultPrefetchImages = 4
+
+// prefetchImages returns the distinct images of up to n queued exec
+// tasks which fit in resources r, in the order in which the tasks are
+// assigned.
+func prefetchImages(todo taskq, r reflow.Resources, n int) []string {
+	if n <= 0 {
+		return nil
+	}
+	tasks := sameArch(todo, r)
+	sort.Slice(tasks, taskq(tasks).Less)
+	var (
+		images []string
+		seen   = make(map[string]bool)
+	)
+	for _, task := range tasks {
+		if len(images) == n {
+			break
+		}
+		cfg := task.config()
+		if cfg.Type != "exec" || cfg.Image == "" || seen[cfg.Image] {
+			continue
+		}
+		if !r.Available(cfg.Resources) {
+			continue
+		}
+		seen[cfg.Image] = true
+		images = append(images, cfg.Image)
+	}
+	return images
+}
+
+// allocImages returns the images which a new alloc with resources r is
+// asked to prefetch: those of the queued exec tasks which fit in it,
+// followed by the scheduler's WarmImages, up to s.PrefetchImages in
+// all.
+func (s *Scheduler) allocImages(todo taskq, r reflow.Resources) []string {
+	images := prefetchImages(todo, r, s.PrefetchImages)
+	for _, image := range s.WarmImages {
+		if len(images) >= s.PrefetchImages {
+			break
+		}
+		if !containsImage(images, image) {
+			images = append(images, image)
+		}
+	}
+	return images
+}
+
+func containsImage(images []string, image string) bool {
+	for _, im := range images {
+		if im == image {
+			return true
+		}
+	}
+	return false
+}
+
+// prefetch asks the given alloc to begin fetching the given images, if
+// it can, so that the execs of the tasks assigned to it need not wait
+// for their images to be pulled.
+func (s *Scheduler) prefetch(alloc *alloc, images []string) {
+	p, ok := alloc.Alloc.(reflow.ImagePrefetcher)
+	if !ok {
+		return
+	}
+	if err := p.PrefetchImages(alloc.Context, images); err != nil {
+		s.Log.Debugf("alloc %s: prefetch images %v: %v", alloc.id, images, err)
+		return
+	}
+	s.Log.Debugf("alloc %s: prefetching images %v", alloc.id, images)
+}
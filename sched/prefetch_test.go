@@ -0,0 +1,78 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package sched
+
+import (
+	"container/heap"
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/grailbio/reflow"
+	"github.com/grailbio/reflow/pool"
+)
+
+type prefetchAlloc struct {
+	pool.Alloc
+	images []string
+}
+
+func (a *prefetchAlloc) PrefetchImages(ctx context.Context, images []string) error {
+	a.images = images
+	return nil
+}
+
+func TestPrefetchImages(t *testing.T) {
+	var todo taskq
+	for _, c := range []struct {
+		typ, image string
+		priority   int
+		resources  reflow.Resources
+	}{
+		{"exec", "ubuntu", 0, reflow.Resources{"cpu": 1, "mem": 1 << 30}},
+		{"exec", "bwa", 1, reflow.Resources{"cpu": 4, "mem": 8 << 30}},
+		{"exec", "ubuntu", 1, reflow.Resources{"cpu": 1, "mem": 1 << 30}},
+		{"intern", "", 0, reflow.Resources{"cpu": 1, "mem": 1 << 30}},
+		// Too large for the alloc.
+		{"exec", "gatk", 0, reflow.Resources{"cpu": 64, "mem": 256 << 30}},
+		// Of another architecture.
+		{"exec", "graviton", 0, reflow.Resources{"cpu": 1, "mem": 1 << 30, reflow.Arm64: 1}},
+		{"exec", "samtools", 2, reflow.Resources{"cpu": 1, "mem": 1 << 30}},
+	} {
+		task := NewTask()
+		task.Config.Type = c.typ
+		task.Config.Image = c.image
+		task.Config.Resources = c.resources
+		task.Priority = c.priority
+		heap.Push(&todo, task)
+	}
+	r := reflow.Resources{"cpu": 8, "mem": 16 << 30}
+	if got, want := prefetchImages(todo, r, 4), []string{"ubuntu", "bwa", "samtools"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := prefetchImages(todo, r, 1), []string{"ubuntu"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got := prefetchImages(todo, r, 0); got != nil {
+		t.Errorf("got %v, want none", got)
+	}
+	// The queue is left intact.
+	for i, task := range todo {
+		if task.index != i {
+			t.Errorf("task %d has index %d", i, task.index)
+		}
+	}
+
+	s := New()
+	s.WarmImages = []string{"bwa", "star", "salmon"}
+	if got, want := s.allocImages(todo, r), []string{"ubuntu", "bwa", "samtools", "star"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	a := &prefetchAlloc{}
+	s.prefetch(&alloc{Alloc: a, Context: context.Background()}, []string{"ubuntu"})
+	if got, want := a.images, []string{"ubuntu"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
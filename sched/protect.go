@@ -0,0 +1,57 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package sched
+
+import (
+	"context"
+
+	"github.com/grailbio/reflow/log"
+	"github.com/grailbio/reflow/pool"
+)
+
+// Protector is implemented by clusters which can protect the instances
+// on which allocs run from (administrative) termination. If the
+// scheduler's cluster implements Protector and Scheduler.Protect is
+// set, the instances of allocs are protected while they run critical
+// tasks, so that the work of long running tasks is not lost.
+type Protector interface {
+	// ProtectAlloc protects the instance of the given alloc from
+	// termination until the returned function is called.
+	ProtectAlloc(ctx context.Context, alloc pool.Alloc) (unprotect func(), err error)
+}
+
+// critical tells whether the task is critical, and thus whether the
+// instance of its alloc must be protected while it runs: tasks are
+// critical if they are marked Critical, or if they are expected to
+// run for at least the scheduler's ProtectDuration.
+func (s *Scheduler) critical(task *Task) bool {
+	if !s.Protect {
+		return false
+	}
+	return task.Critical || (s.ProtectDuration > 0 && task.ExpectedDuration >= s.ProtectDuration)
+}
+
+// protect protects the instance of the alloc on which the task runs,
+// if the task is critical and the scheduler's cluster is a Protector.
+// It returns a function which removes the protection. Failures to
+// protect instances are logged: tasks run regardless.
+func (s *Scheduler) protect(ctx context.Context, task *Task, alloc *alloc, log *log.Logger) (unprotect func()) {
+	unprotect = func() {}
+	if !s.critical(task) {
+		return
+	}
+	protector, ok := s.Cluster.(Protector)
+	if !ok {
+		return
+	}
+	u, err := protector.ProtectAlloc(ctx, alloc.Alloc)
+	if err != nil {
+		log.Errorf("protect alloc %s: %v", alloc.ID(), err)
+		return
+	}
+	s.Stats.MarkProtected()
+	log.Debugf("protected alloc %s from termination", alloc.ID())
+	return u
+}
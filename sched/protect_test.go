@@ -0,0 +1,72 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package sched
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grailbio/reflow/pool"
+)
+
+type protectAlloc struct {
+	pool.Alloc
+}
+
+func (protectAlloc) ID() string { return "alloc" }
+
+// protectCluster is a Cluster which counts protected allocs.
+type protectCluster struct {
+	Cluster
+	protected int
+}
+
+func (c *protectCluster) ProtectAlloc(ctx context.Context, alloc pool.Alloc) (func(), error) {
+	c.protected++
+	return func() { c.protected-- }, nil
+}
+
+func TestProtect(t *testing.T) {
+	var (
+		cluster = new(protectCluster)
+		s       = New()
+		a       = &alloc{Alloc: protectAlloc{}}
+		ctx     = context.Background()
+	)
+	s.Cluster = cluster
+	critical, long, other := NewTask(), NewTask(), NewTask()
+	critical.Critical = true
+	long.ExpectedDuration = 3 * time.Hour
+
+	// Instances are not protected unless the scheduler is configured to.
+	s.protect(ctx, critical, a, s.Log)()
+	if got, want := s.Stats.GetStats().ProtectedTasks, int64(0); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	s.Protect = true
+	s.ProtectDuration = 2 * time.Hour
+	for _, c := range []struct {
+		task      *Task
+		protected int
+	}{
+		{critical, 1},
+		{long, 1},
+		{other, 0},
+	} {
+		unprotect := s.protect(ctx, c.task, a, s.Log)
+		if got, want := cluster.protected, c.protected; got != want {
+			t.Errorf("got %v, want %v", got, want)
+		}
+		unprotect()
+		if got, want := cluster.protected, 0; got != want {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	}
+	if got, want := s.Stats.GetStats().ProtectedTasks, int64(2); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
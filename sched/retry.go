@@ -0,0 +1,200 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package sched
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/grailbio/reflow"
+	"github.com/grailbio/reflow/errors"
+)
+
+// A TaskFailure describes a failed attempt of a task.
+type TaskFailure struct {
+	// Task is the task whose attempt failed.
+	Task *Task
+	// Err is the error with which the attempt failed.
+	Err error
+	// Kind is the kind of Err: the kind of the first error in its
+	// chain whose kind is not errors.Other.
+	Kind errors.Kind
+	// Result tells whether Err is the error of the result of the
+	// task's exec (e.g., an OOM), rather than an error encountered by
+	// the scheduler while running the task (e.g., a network error).
+	Result bool
+	// Retries is the number of times the task was previously retried
+	// after failing with an error of the same kind.
+	Retries int
+}
+
+// A RetryPolicy determines which failed attempts of tasks the
+// scheduler retries, and how. Tasks whose allocs die are always
+// retried, and tasks which run out of disk space are retried as
+// determined by the scheduler's OutOfDisk policy.
+type RetryPolicy interface {
+	// Retry tells whether the task whose attempt failed as described
+	// by f is retried. If so, it also returns the delay after which
+	// the task is requeued, and the resources with which it is
+	// retried (nil if the task's resources are unchanged).
+	Retry(f TaskFailure) (retry bool, delay time.Duration, resources reflow.Resources)
+}
+
+// A RetryRule determines how a KindRetryPolicy retries tasks whose
+// attempts fail with errors of a kind.
+type RetryRule struct {
+	// MaxRetries is the maximum number of times a task is retried for
+	// errors of the kind. If negative, tasks are retried indefinitely.
+	MaxRetries int
+	// Backoff is the delay before the first retry; it doubles with each
+	// subsequent retry, up to MaxBackoff (if nonzero).
+	Backoff, MaxBackoff time.Duration
+	// Results tells whether the rule also applies to the errors of the
+	// results of tasks' execs. These are otherwise returned to the
+	// evaluator, which may retry them itself (see flow.Eval).
+	Results bool
+	// Escalate, if not nil, returns the resources with which the task
+	// is retried, given its current resources. It must not modify them.
+	Escalate func(f TaskFailure, resources reflow.Resources) reflow.Resources
+}
+
+// String returns a description of the rule.
+func (r RetryRule) String() string {
+	max := "unlimited"
+	if r.MaxRetries >= 0 {
+		max = fmt.Sprint(r.MaxRetries)
+	}
+	s := fmt.Sprintf("retries %s", max)
+	if r.Backoff > 0 {
+		s += fmt.Sprintf(" backoff %s", r.Backoff)
+		if r.MaxBackoff > 0 {
+			s += fmt.Sprintf("-%s", r.MaxBackoff)
+		}
+	}
+	if r.Results {
+		s += " results"
+	}
+	if r.Escalate != nil {
+		s += " escalate"
+	}
+	return s
+}
+
+// delay returns the delay before the given (zero-based) retry.
+func (r RetryRule) delay(retries int) time.Duration {
+	d := r.Backoff
+	for i := 0; i < retries && d > 0; i++ {
+		if r.MaxBackoff > 0 && d >= r.MaxBackoff {
+			break
+		}
+		d *= 2
+	}
+	if r.MaxBackoff > 0 && d > r.MaxBackoff {
+		d = r.MaxBackoff
+	}
+	return d
+}
+
+// A KindRetryPolicy is a RetryPolicy which retries failed attempts
+// according to the rule for the kind of their error. Attempts which
+// fail with errors of kinds without a rule are not retried.
+type KindRetryPolicy map[errors.Kind]RetryRule
+
+// DefaultRetryPolicy is the retry policy used by schedulers which are
+// not configured with one. It indefinitely retries tasks which fail
+// with errors indicating that their alloc is unreachable or unusable.
+var DefaultRetryPolicy = KindRetryPolicy{
+	errors.Canceled:    {MaxRetries: -1},
+	errors.Net:         {MaxRetries: -1},
+	errors.Timeout:     {MaxRetries: -1},
+	errors.Unavailable: {MaxRetries: -1},
+}
+
+// Retry implements RetryPolicy.
+func (p KindRetryPolicy) Retry(f TaskFailure) (bool, time.Duration, reflow.Resources) {
+	rule, ok := p[f.Kind]
+	if !ok || (f.Result && !rule.Results) || (rule.MaxRetries >= 0 && f.Retries >= rule.MaxRetries) {
+		return false, 0, nil
+	}
+	var resources reflow.Resources
+	if rule.Escalate != nil {
+		resources = rule.Escalate(f, f.Task.config().Resources)
+	}
+	return true, rule.delay(f.Retries), resources
+}
+
+// String returns a description of the policy.
+func (p KindRetryPolicy) String() string {
+	kinds := make([]string, 0, len(p))
+	for k, r := range p {
+		kinds = append(kinds, fmt.Sprintf("%s(%s)", k, r))
+	}
+	sort.Strings(kinds)
+	return strings.Join(kinds, " ")
+}
+
+// ScaleResources returns a RetryRule.Escalate function which scales
+// each resource by the given factor, e.g., {"mem": 2} doubles the
+// memory of tasks which are retried. Resources are scaled for each
+// retry, up to the given maximum amounts, if any.
+func ScaleResources(factors, max reflow.Resources) func(TaskFailure, reflow.Resources) reflow.Resources {
+	return func(_ TaskFailure, r reflow.Resources) reflow.Resources {
+		var scaled reflow.Resources
+		scaled.Set(r)
+		for k, f := range factors {
+			scaled[k] *= f
+			if m, ok := max[k]; ok && scaled[k] > m {
+				scaled[k] = m
+			}
+		}
+		return scaled
+	}
+}
+
+// kindOf returns the kind of the first error in err's chain whose
+// kind is not errors.Other, as tested by errors.Is.
+func kindOf(err error) errors.Kind {
+	e := errors.Recover(err)
+	for e.Kind == errors.Other {
+		next, ok := e.Err.(*errors.Error)
+		if !ok {
+			break
+		}
+		e = next
+	}
+	return e.Kind
+}
+
+// retry tells whether the task, whose attempt failed with the given
+// error or else with the error of its exec's result, is retried under
+// the scheduler's RetryPolicy. If so, the delay and resources with
+// which it is retried are recorded in the task.
+func (s *Scheduler) retry(task *Task, err error) bool {
+	f := TaskFailure{Task: task, Err: err}
+	if err == nil {
+		if task.Result.Err == nil {
+			return false
+		}
+		f.Err, f.Result = task.Result.Err, true
+	}
+	f.Kind = kindOf(f.Err)
+	f.Retries = task.retries[f.Kind]
+	policy := s.RetryPolicy
+	if policy == nil {
+		policy = DefaultRetryPolicy
+	}
+	retry, delay, resources := policy.Retry(f)
+	if !retry {
+		return false
+	}
+	if task.retries == nil {
+		task.retries = make(map[errors.Kind]int)
+	}
+	task.retries[f.Kind]++
+	task.retryDelay, task.retryResources, task.retryResult = delay, resources, f.Result
+	return true
+}
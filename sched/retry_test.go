@@ -0,0 +1,64 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package sched
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grailbio/reflow"
+	"github.com/grailbio/reflow/errors"
+)
+
+func TestKindRetryPolicy(t *testing.T) {
+	policy := KindRetryPolicy{
+		errors.Net:         {MaxRetries: 3, Backoff: time.Second, MaxBackoff: 3 * time.Second},
+		errors.OOM:         {MaxRetries: -1, Results: true, Escalate: ScaleResources(reflow.Resources{"mem": 2}, reflow.Resources{"mem": 6})},
+		errors.Unavailable: {MaxRetries: -1},
+	}
+	task := NewTask()
+	task.Config.Resources = reflow.Resources{"cpu": 1, "mem": 4}
+	netErr := errors.E("exec", errors.Net, errors.New("connection reset"))
+	for _, tt := range []struct {
+		f         TaskFailure
+		retry     bool
+		delay     time.Duration
+		resources reflow.Resources
+	}{
+		{TaskFailure{Kind: errors.Net, Err: netErr}, true, time.Second, nil},
+		{TaskFailure{Kind: errors.Net, Err: netErr, Retries: 1}, true, 2 * time.Second, nil},
+		{TaskFailure{Kind: errors.Net, Err: netErr, Retries: 2}, true, 3 * time.Second, nil},
+		{TaskFailure{Kind: errors.Net, Err: netErr, Retries: 3}, false, 0, nil},
+		{TaskFailure{Kind: errors.Net, Err: netErr, Result: true}, false, 0, nil},
+		{TaskFailure{Kind: errors.Unavailable, Retries: 100}, true, 0, nil},
+		{TaskFailure{Kind: errors.OOM, Result: true}, true, 0, reflow.Resources{"cpu": 1, "mem": 6}},
+		{TaskFailure{Kind: errors.Fatal}, false, 0, nil},
+	} {
+		tt.f.Task = task
+		retry, delay, resources := policy.Retry(tt.f)
+		if got, want := retry, tt.retry; got != want {
+			t.Errorf("%v: got %v, want %v", tt.f.Kind, got, want)
+		}
+		if got, want := delay, tt.delay; got != want {
+			t.Errorf("%v: got %v, want %v", tt.f.Kind, got, want)
+		}
+		if got, want := resources, tt.resources; !got.Equal(want) {
+			t.Errorf("%v: got %v, want %v", tt.f.Kind, got, want)
+		}
+	}
+	if got, want := task.Config.Resources["mem"], 4.0; got != want {
+		t.Errorf("escalation modified the task's resources: got %v, want %v", got, want)
+	}
+}
+
+func TestKindOf(t *testing.T) {
+	err := errors.E("exec", errors.E(errors.Unavailable, errors.New("alloc is unreachable")))
+	if got, want := kindOf(err), errors.Unavailable; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := kindOf(errors.New("some error")), errors.Other; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
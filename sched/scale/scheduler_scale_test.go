@@ -426,7 +426,7 @@ func (a *allocator) Refresh(ctx context.Context) (map[string]string, error) {
 
 // Available returns any available instance specification that can satisfy the need.
 // The returned InstanceSpec should be subsequently be 'Launch'able.
-func (a *allocator) Available(need reflow.Resources, maxPrice float64) (ec2cluster.InstanceSpec, bool) {
+func (a *allocator) Available(need reflow.Resources, maxPrice float64, penalties map[string]float64) (ec2cluster.InstanceSpec, bool) {
 	typ, r := ec2cluster.InstanceType(need, true, maxPrice)
 	if typ == "" || r.Equal(nil) || maxPrice < testInstancePrice {
 		return ec2cluster.InstanceSpec{}, false
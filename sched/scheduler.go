@@ -28,16 +28,19 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/grailbio/base/data"
 	"github.com/grailbio/base/digest"
+	"github.com/grailbio/base/limiter"
 	"github.com/grailbio/reflow"
 	"github.com/grailbio/reflow/blob"
 	"github.com/grailbio/reflow/errors"
 	"github.com/grailbio/reflow/log"
 	"github.com/grailbio/reflow/metrics"
 	"github.com/grailbio/reflow/pool"
+	"github.com/grailbio/reflow/repository"
 	"github.com/grailbio/reflow/sched/internal"
 	"github.com/grailbio/reflow/taskdb"
 	"github.com/grailbio/reflow/trace"
@@ -47,6 +50,9 @@ import (
 const (
 	numExecTries        = 5
 	defaultDrainTimeout = 50 * time.Millisecond
+	// progressInterval is the interval at which the progress of running
+	// execs is recorded in the taskdb.
+	progressInterval = time.Minute
 )
 
 var allocateTraceId = reflow.Digester.FromString("allocate")
@@ -104,7 +110,96 @@ type Scheduler struct {
 	// Stats is the scheduler stats.
 	Stats *Stats
 
+	// OutOfDisk is the policy for handling tasks which fail because
+	// their alloc ran out of disk space.
+	OutOfDisk OutOfDiskPolicy
+	// OutOfDiskFactor is the factor by which a task's disk requirement
+	// is increased when it is retried under the OutOfDiskRetry policy.
+	OutOfDiskFactor float64
+
+	// RetryPolicy determines which failed attempts of tasks are
+	// retried, after what delay, and with what resources. If nil,
+	// DefaultRetryPolicy is used.
+	RetryPolicy RetryPolicy
+
+	// SpreadTimeout is the maximum time for which a task whose
+	// previous attempts failed waits to be placed on an alloc outside
+	// of the failure domains in which they failed (see Domainer).
+	// After that, the task may be placed on any alloc.
+	SpreadTimeout time.Duration
+
+	// TransferLimits limits the number of concurrent intern and extern
+	// transfers per destination: the bucket or host (e.g.,
+	// "s3://bucket") to which files are externed, or from which they
+	// are interned. The limits are shared by all tasks submitted to the
+	// scheduler, and thus by all runs in a process which share one.
+	// Each file transferred directly by the scheduler counts against
+	// the limit, as does each intern or extern task run on an alloc.
+	// If nil, transfers are not limited.
+	TransferLimits *repository.Limits
+
+	// AgingInterval is the interval at which the priority of a queued
+	// task is raised by one level, so that tasks (e.g., small ones)
+	// are not displaced indefinitely by a stream of higher priority
+	// tasks. If zero, tasks are not aged.
+	AgingInterval time.Duration
+	// StarvationThreshold is the time after which a task which is
+	// still queued is reported as starved: an error is logged and the
+	// task is counted in the scheduler's stats. If zero, starvation is
+	// not reported.
+	StarvationThreshold time.Duration
+
+	// Protect enables the termination protection of the instances of
+	// allocs while they run critical tasks (see Task.Critical), if the
+	// scheduler's cluster implements Protector.
+	Protect bool
+	// ProtectDuration, if positive, is the expected duration (see
+	// Task.ExpectedDuration) beyond which tasks are considered critical
+	// even if they are not marked as such. It applies only if Protect
+	// is set.
+	ProtectDuration time.Duration
+
+	// Scoper, if set, mints credentials for each intern and extern
+	// task which permit access only to the data the task transfers.
+	// Tasks run on allocs are passed the credentials, minted as the
+	// task is put, in their exec config (see
+	// reflow.ExecConfig.Credentials); direct transfers are performed
+	// with them by the scheduler itself, which refreshes them before
+	// they expire.
+	Scoper blob.Scoper
+
+	// OrphanInterval is the interval at which the execs of live allocs
+	// are reconciled with the tasks assigned to them. Execs which have
+	// yet to complete but which no assigned task references (e.g.,
+	// those of lost tasks, or left over from a crashed scheduler) are
+	// killed, freeing their resources. If zero, execs are not
+	// reconciled.
+	OrphanInterval time.Duration
+
+	// InstanceTypes records the outcomes of exec tasks per ident and
+	// instance type, if the scheduler's cluster implements
+	// InstanceTyper. Instance types which perform poorly for an ident
+	// are de-prioritized when placing its tasks and when allocating
+	// for them. If nil, instance types are not de-prioritized.
+	InstanceTypes *InstanceTypeStats
+
+	// PrefetchImages is the maximum number of distinct images of
+	// queued exec tasks which a new alloc is asked to prefetch (see
+	// reflow.ImagePrefetcher) when it becomes live, so that the
+	// tasks' execs need not wait for their images to be pulled. If
+	// zero, images are not prefetched.
+	PrefetchImages int
+
+	// WarmImages are images which new allocs are asked to prefetch
+	// after those of queued exec tasks (up to PrefetchImages in all),
+	// e.g., so that a cluster's instances are warmed up ahead of a
+	// run. It must not be modified once scheduling has started.
+	WarmImages []string
+
 	submitc chan []*Task
+
+	transferMu       sync.Mutex
+	transferLimiters map[string]*limiter.Limiter
 }
 
 // New returns a new Scheduler instance. The caller may customize its
@@ -117,6 +212,17 @@ func New() *Scheduler {
 		DrainTimeout:     defaultDrainTimeout,
 		MinAlloc:         reflow.Resources{"cpu": 1, "mem": 1 << 30, "disk": 1 << 30},
 		Stats:            newStats(),
+		OutOfDiskFactor:  defaultOutOfDiskFactor,
+		SpreadTimeout:    defaultSpreadTimeout,
+
+		AgingInterval:       defaultAgingInterval,
+		StarvationThreshold: defaultStarvationThreshold,
+
+		OrphanInterval: defaultOrphanInterval,
+
+		InstanceTypes: NewInstanceTypeStats(),
+
+		PrefetchImages: defaultPrefetchImages,
 	}
 }
 
@@ -128,7 +234,7 @@ func (s *Scheduler) Submit(tasks ...*Task) {
 		if task.Repository == nil {
 			panic(fmt.Sprintf("scheduler Submit task (flow %s) with no repository", task.FlowID.Short()))
 		}
-		s.Log.Debugf("task (flow %s) submitted with %v", task.FlowID.Short(), task.Config)
+		s.Log.Debugf("task (flow %s) submitted with %v", task.FlowID.Short(), task.config())
 	}
 	tasksCopy := append([]*Task{}, tasks...)
 	s.submitc <- tasksCopy
@@ -156,6 +262,23 @@ func (s *Scheduler) configString() string {
 	}
 	sort.Strings(schemes)
 	_, _ = fmt.Fprintf(&b, " blob.Mux[%s]", strings.Join(schemes, ", "))
+	_, _ = fmt.Fprintf(&b, " outofdisk %s", s.OutOfDisk)
+	if s.RetryPolicy != nil {
+		_, _ = fmt.Fprintf(&b, " retry %v", s.RetryPolicy)
+	}
+	if s.TransferLimits != nil {
+		_, _ = fmt.Fprintf(&b, " transferlimits %s", s.TransferLimits)
+	}
+	_, _ = fmt.Fprintf(&b, " aging %s starvation %s", s.AgingInterval, s.StarvationThreshold)
+	if s.OrphanInterval > 0 {
+		_, _ = fmt.Fprintf(&b, " orphans %s", s.OrphanInterval)
+	}
+	if s.Protect {
+		_, _ = fmt.Fprintf(&b, " protect(%s)", s.ProtectDuration)
+	}
+	if s.PrefetchImages > 0 {
+		_, _ = fmt.Fprintf(&b, " prefetch %d", s.PrefetchImages)
+	}
 	return b.String()
 }
 
@@ -189,10 +312,30 @@ func (s *Scheduler) Do(ctx context.Context) error {
 		notifyc = make(chan *alloc)
 		deadc   = make(chan *alloc)
 		returnc = make(chan *Task)
+		// retryc receives the tasks which are retried after a delay,
+		// of which there are ndelayed.
+		retryc   = make(chan *Task)
+		ndelayed int
 
 		tick = time.NewTicker(s.MaxAllocIdleTime / 2)
+		// agec ticks at which queued tasks are aged.
+		agec <-chan time.Time
+		// orphanc ticks at which orphaned execs are reaped; reaping is
+		// set while they are.
+		orphanc <-chan time.Time
+		reaping int32
 	)
 	defer tick.Stop()
+	if period := agingPeriod(s.AgingInterval, s.StarvationThreshold); period > 0 {
+		ageTick := time.NewTicker(period)
+		defer ageTick.Stop()
+		agec = ageTick.C
+	}
+	if s.OrphanInterval > 0 {
+		orphanTick := time.NewTicker(s.OrphanInterval)
+		defer orphanTick.Stop()
+		orphanc = orphanTick.C
+	}
 
 	s.Log.Debugf("starting with configuration: %s", s.configString())
 	for {
@@ -218,6 +361,11 @@ func (s *Scheduler) Do(ctx context.Context) error {
 				case TaskDone:
 				}
 			}
+			for ; ndelayed > 0; ndelayed-- {
+				task := <-retryc
+				task.Err = ctx.Err()
+				task.Set(TaskDone)
+			}
 			for n := len(live); n > 0; n-- {
 				<-deadc
 			}
@@ -231,6 +379,17 @@ func (s *Scheduler) Do(ctx context.Context) error {
 					alloc.Cancel()
 				}
 			}
+		case now := <-agec:
+			s.age(&todo, now)
+		case <-orphanc:
+			if len(live) == 0 || !atomic.CompareAndSwapInt32(&reaping, 0, 1) {
+				break
+			}
+			allocs := append([]*alloc{}, live...)
+			go func() {
+				s.reapOrphans(allocs)
+				atomic.StoreInt32(&reaping, 0)
+			}()
 		case tasks := <-s.submitc:
 			tasks = append(tasks, s.drain()...)
 			for _, task := range tasks {
@@ -239,17 +398,19 @@ func (s *Scheduler) Do(ctx context.Context) error {
 			}
 			s.Stats.AddTasks(tasks)
 			for _, task := range tasks {
-				if task.Config.Type == "extern" && !task.nonDirectTransfer {
+				if (task.config().Type == "extern" || task.config().Type == "intern") && !task.nonDirectTransfer {
+					task.materialize()
 					go s.directTransfer(ctx, task)
 					continue
 				}
-				if ok, err := s.Cluster.CanAllocate(task.Config.Resources); !ok {
+				if ok, err := s.Cluster.CanAllocate(task.config().Resources); !ok {
 					task.Err = err
 					task.Set(TaskDone)
 					continue
 				}
 				metrics.GetTasksSubmittedCountCounter(ctx).Inc()
-				metrics.GetTasksSubmittedSizeCounter(ctx).Add(task.Config.ScaledDistance(nil))
+				metrics.GetTasksSubmittedSizeCounter(ctx).Add(task.config().ScaledDistance(nil))
+				task.queued = time.Now()
 				heap.Push(&todo, task)
 			}
 		case task := <-returnc:
@@ -259,6 +420,46 @@ func (s *Scheduler) Do(ctx context.Context) error {
 			if alloc.index != -1 {
 				heap.Fix(&live, alloc.index)
 			}
+			if task.outOfDisk {
+				task.outOfDisk = false
+				// The alloc's disk is suspect: stop assigning tasks to it,
+				// and release it once its remaining tasks are done.
+				alloc.diskSuspect = true
+				if alloc.index != -1 {
+					heap.Remove(&live, alloc.index)
+					alloc.index = -1
+				}
+				task.diskRetries++
+				resources := s.outOfDiskAdjust(task.Config.Resources)
+				if ok, err := s.Cluster.CanAllocate(resources); !ok {
+					// Give up and return the out-of-disk error as is.
+					task.Log.Printf("task %s (flow %s) ran out of disk on alloc %s; cannot retry with %s: %v", task.ID().IDShort(), task.FlowID.Short(), alloc.id, resources, err)
+					task.Set(TaskDone)
+				} else {
+					task.Log.Printf("task %s (flow %s) ran out of disk on alloc %s; retrying with %s", task.ID().IDShort(), task.FlowID.Short(), alloc.id, resources)
+					task.Config.Resources = resources
+					task.Result = reflow.Result{}
+				}
+			}
+			if alloc.diskSuspect && alloc.Pending == 0 {
+				alloc.Cancel()
+			}
+			if resources := task.retryResources; resources != nil {
+				task.retryResources = nil
+				if ok, err := s.Cluster.CanAllocate(resources); !ok {
+					// Give up and return the task's error as is.
+					task.Log.Printf("task %s (flow %s) failed on alloc %s; cannot retry with %s: %v", task.ID().IDShort(), task.FlowID.Short(), alloc.id, resources, err)
+					task.retryDelay, task.retryResult = 0, false
+					task.Set(TaskDone)
+				} else {
+					task.Log.Printf("task %s (flow %s) failed on alloc %s; retrying with %s", task.ID().IDShort(), task.FlowID.Short(), alloc.id, resources)
+					task.Config.Resources = resources
+				}
+			}
+			if task.retryResult {
+				task.retryResult = false
+				task.Result = reflow.Result{}
+			}
 			switch task.State() {
 			default:
 				panic("illegal task state")
@@ -266,10 +467,28 @@ func (s *Scheduler) Do(ctx context.Context) error {
 				old := task.ID().IDShort()
 				// Reset the task (which also assigns it a new task identifier)
 				task.Reset()
+				if delay := task.retryDelay; delay > 0 {
+					task.retryDelay = 0
+					task.Log.Printf("task %s (flow %s) has been lost, will retry (attempt %d) as task %s in %s", old, task.FlowID.Short(), 1+task.Attempt(), task.ID().IDShort(), delay)
+					ndelayed++
+					go func(task *Task) {
+						t := time.NewTimer(delay)
+						select {
+						case <-t.C:
+						case <-ctx.Done():
+							t.Stop()
+						}
+						retryc <- task
+					}(task)
+					break
+				}
 				task.Log.Printf("task %s (flow %s) has been lost, will retry (attempt %d) as task %s", old, task.FlowID.Short(), 1+task.Attempt(), task.ID().IDShort())
+				task.queued = time.Now()
+				task.aged, task.starved = 0, false
 				heap.Push(&todo, task)
 			case TaskDone:
 				// In this case we're done, and we can forget about the task.
+				metrics.GetTasksAttemptsHistogram(ctx).Observe(float64(1 + task.Attempt()))
 			}
 			s.Stats.ReturnTask(task, alloc)
 			// Network errors imply that the alloc is unreachable.
@@ -280,12 +499,22 @@ func (s *Scheduler) Do(ctx context.Context) error {
 				heap.Remove(&live, alloc.index)
 				alloc.index = -1
 			}
+		case task := <-retryc:
+			ndelayed--
+			task.queued = time.Now()
+			task.aged, task.starved = 0, false
+			heap.Push(&todo, task)
 		case alloc := <-notifyc:
 			heap.Remove(&pending, alloc.index)
 			if alloc.Alloc != nil {
 				alloc.Init(ctx, s.Log)
+				alloc.domain = s.failureDomain(alloc.Alloc)
+				alloc.instanceType = s.instanceType(alloc.Alloc)
 				heap.Push(&live, alloc)
 				s.Stats.AddAlloc(alloc)
+				if images := s.allocImages(todo, alloc.Available); len(images) > 0 {
+					go s.prefetch(alloc, images)
+				}
 			}
 		case alloc := <-deadc:
 			// The allocs tasks will be returned with state TaskLost.
@@ -293,14 +522,20 @@ func (s *Scheduler) Do(ctx context.Context) error {
 				heap.Remove(&live, alloc.index)
 			}
 			s.Stats.MarkAllocDead(alloc)
+			for _, r := range utilizationResources {
+				metrics.GetAllocsUtilizationGauge(ctx, alloc.id, r).Set(0)
+			}
 		}
 
 		assigned := s.assign(&todo, &live, s.Stats)
 		for _, task := range assigned {
 			task.Log.Printf("task %s (flow %s) assigning to alloc %v", task.ID().IDShort(), task.FlowID.Short(), task.alloc)
+			metrics.GetTasksPlacementLatencySecondsHistogram(ctx).Observe(time.Since(task.queued).Seconds())
 			nrunning++
+			task.materialize()
 			go s.run(task, returnc)
 		}
+		setQueueMetrics(ctx, todo, nrunning, live, pending)
 
 		// At this point, we've scheduled everything we can onto the current
 		// set of allocs. If we have more work, we'll need to try to create more
@@ -318,9 +553,17 @@ func (s *Scheduler) Do(ctx context.Context) error {
 			// This is needed in addition to `req` because if all tasks have empty resources
 			// we end up getting empty requirements, but we should trigger at least one allocation.
 			needMore bool
+			// penalties are the instance type penalties of the tasks
+			// for which the alloc is requested.
+			penalties map[string]float64
 		)
 		if len(todo) > 0 {
-			req = requirements(todo)
+			// A single alloc can serve only one architecture; tasks
+			// of other architectures are allocated for in a
+			// subsequent iteration.
+			tasks := sameArch(todo, todo[0].config().Resources)
+			req = requirements(tasks)
+			penalties = s.InstanceTypes.taskPenalties(tasks)
 			needMore = true
 		}
 		for _, task := range assigned {
@@ -336,7 +579,29 @@ func (s *Scheduler) Do(ctx context.Context) error {
 		alloc.Requirements = req
 		alloc.Available = req.Min
 		heap.Push(&pending, alloc)
-		go s.allocate(ctx, alloc, notifyc, deadc)
+		go s.allocate(WithInstanceTypePenalties(ctx, penalties), alloc, notifyc, deadc)
+	}
+}
+
+// utilizationResources are the resources for which alloc utilization
+// metrics are reported.
+var utilizationResources = []string{"cpu", "mem", "disk"}
+
+// setQueueMetrics sets the scheduler's queue depth metrics and the
+// utilization metrics of its live allocs.
+func setQueueMetrics(ctx context.Context, todo taskq, nrunning int, live, pending allocq) {
+	metrics.GetSchedulerTasksGauge(ctx, "queued").Set(float64(len(todo)))
+	metrics.GetSchedulerTasksGauge(ctx, "running").Set(float64(nrunning))
+	metrics.GetSchedulerAllocsGauge(ctx, "live").Set(float64(len(live)))
+	metrics.GetSchedulerAllocsGauge(ctx, "pending").Set(float64(len(pending)))
+	for _, alloc := range live {
+		total := alloc.Resources()
+		for _, r := range utilizationResources {
+			if total[r] == 0 {
+				continue
+			}
+			metrics.GetAllocsUtilizationGauge(ctx, alloc.id, r).Set(1 - alloc.Available[r]/total[r])
+		}
 	}
 }
 
@@ -367,14 +632,107 @@ func (s *Scheduler) drain() (tasks []*Task) {
 	return
 }
 
+// assign assigns tasks to allocs, gang scheduling the tasks of
+// gang-scheduled groups (see TaskGroup), and records the assignments
+// in stats, if it is not nil.
 func (s *Scheduler) assign(tasks *taskq, allocs *allocq, stats *Stats) (assigned []*Task) {
-	var unassigned []*alloc
+	assigned = s.assignGangs(tasks, allocs)
+	if stats != nil {
+		for _, task := range assigned {
+			stats.AssignTask(task, task.alloc)
+		}
+	}
+	return
+}
+
+// assignTasks assigns tasks to allocs of their own architectures.
+func (s *Scheduler) assignTasks(tasks *taskq, allocs *allocq) (assigned []*Task) {
+	if !mixedArch(*tasks, *allocs) {
+		return s.assignArch(tasks, allocs)
+	}
+	// Tasks may only be assigned to allocs of their own architecture,
+	// so we perform assignment separately for each.
+	var (
+		armTasks, otherTasks   taskq
+		armAllocs, otherAllocs allocq
+	)
+	for len(*tasks) > 0 {
+		task := heap.Pop(tasks).(*Task)
+		if task.config().Resources[reflow.Arm64] > 0 {
+			heap.Push(&armTasks, task)
+		} else {
+			heap.Push(&otherTasks, task)
+		}
+	}
+	for len(*allocs) > 0 {
+		alloc := heap.Pop(allocs).(*alloc)
+		if alloc.arm64() {
+			heap.Push(&armAllocs, alloc)
+		} else {
+			heap.Push(&otherAllocs, alloc)
+		}
+	}
+	assigned = s.assignArch(&armTasks, &armAllocs)
+	assigned = append(assigned, s.assignArch(&otherTasks, &otherAllocs)...)
+	for _, q := range []taskq{armTasks, otherTasks} {
+		for _, task := range q {
+			heap.Push(tasks, task)
+		}
+	}
+	for _, q := range []allocq{armAllocs, otherAllocs} {
+		for _, alloc := range q {
+			heap.Push(allocs, alloc)
+		}
+	}
+	return
+}
+
+// mixedArch tells whether the provided tasks and allocs span more
+// than one architecture.
+func mixedArch(tasks taskq, allocs allocq) bool {
+	var arm, other bool
+	for _, task := range tasks {
+		if task.config().Resources[reflow.Arm64] > 0 {
+			arm = true
+		} else {
+			other = true
+		}
+	}
+	for _, alloc := range allocs {
+		if alloc.arm64() {
+			arm = true
+		} else {
+			other = true
+		}
+	}
+	return arm && other
+}
+
+// sameArch returns the subset of tasks which require the same
+// architecture as the resources r.
+func sameArch(tasks []*Task, r reflow.Resources) []*Task {
+	var matched []*Task
+	for _, task := range tasks {
+		if r.ArchCompatible(task.config().Resources) {
+			matched = append(matched, task)
+		}
+	}
+	return matched
+}
+
+// assignArch assigns tasks to allocs, assuming that they are all
+// of the same architecture.
+func (s *Scheduler) assignArch(tasks *taskq, allocs *allocq) (assigned []*Task) {
+	var (
+		unassigned []*alloc
+		deferred   []*Task
+	)
 	for len(*tasks) > 0 && len(*allocs) > 0 {
 		var (
 			task  = (*tasks)[0]
 			alloc = (*allocs)[0]
 		)
-		if !alloc.Available.Available(task.Config.Resources) {
+		if !alloc.Available.Available(task.config().Resources) {
 			// We can't fit the smallest task in the smallest alloc.
 			// Remove the alloc from consideration.
 			heap.Pop(allocs)
@@ -382,16 +740,28 @@ func (s *Scheduler) assign(tasks *taskq, allocs *allocq, stats *Stats) (assigned
 			continue
 		}
 		heap.Pop(tasks)
-		alloc.Assign(task)
-		if stats != nil {
-			stats.AssignTask(task, alloc)
+		if s.avoids(task, alloc) {
+			// A previous attempt of the task failed in the alloc's failure
+			// domain: place it on another alloc, or defer it until one is
+			// available.
+			if alloc = s.spreadAlloc(task, *allocs); alloc == nil {
+				deferred = append(deferred, task)
+				continue
+			}
 		}
+		// Prefer allocs whose instance types perform better for the
+		// task's ident.
+		alloc = s.preferredAlloc(task, alloc, *allocs)
+		alloc.Assign(task)
 		assigned = append(assigned, task)
-		heap.Fix(allocs, 0)
+		heap.Fix(allocs, alloc.index)
 	}
 	for _, alloc := range unassigned {
 		heap.Push(allocs, alloc)
 	}
+	for _, task := range deferred {
+		heap.Push(tasks, task)
+	}
 	return
 }
 
@@ -460,10 +830,17 @@ func (s *Scheduler) run(task *Task, returnc chan<- *Task) {
 		tctx           context.Context
 		loadedData     sync.Map // map[int]bool - where int is the index of task.Config.Args.
 		resultUnloaded bool
+		start          = time.Now()
+		// release releases the transfer slot held by intern and extern tasks.
+		release func()
+		// loaded and saved are the number of bytes of inputs loaded onto
+		// the alloc, and of results saved, by this attempt of the task.
+		loaded, saved int64
 	)
 	task.TaskDB = s.TaskDB
 
-	taskLogger := task.Log.Tee(nil, fmt.Sprintf("scheduler task %s (flow %s): ", task.ID().IDShort(), task.FlowID.Short()))
+	taskLogger := task.Log.Tee(nil, fmt.Sprintf("scheduler task %s (flow %s): ", task.ID().IDShort(), task.FlowID.Short())).
+		With(log.TaskIDKey, task.ID().ID()).With(log.AllocIDKey, alloc.ID())
 
 	metrics.GetTasksStartedCountCounter(ctx).Inc()
 	metrics.GetTasksStartedSizeCounter(ctx).Add(task.Config.ScaledDistance(nil))
@@ -474,8 +851,15 @@ func (s *Scheduler) run(task *Task, returnc chan<- *Task) {
 		if tcancel == nil {
 			return
 		}
+		// Record the exec's error (including its post-mortem, if any) when
+		// the task ran to completion but the exec itself failed.
+		taskErr := err
+		if taskErr == nil && task.Result.Err != nil {
+			taskErr = task.Result.Err
+		}
 		// Use background context for setting task completion status.
-		if taskdbErr := s.TaskDB.SetTaskComplete(context.Background(), task.ID(), err, time.Now()); taskdbErr != nil {
+		if taskdbErr := s.TaskDB.SetTaskComplete(context.Background(), task.ID(), taskErr, time.Now()); taskdbErr != nil {
+			metrics.GetTaskdbErrorsCountCounter(ctx, "settaskcomplete").Inc()
 			taskLogger.Errorf("taskdb settaskcomplete: %v", taskdbErr)
 		}
 		tcancel()
@@ -485,7 +869,11 @@ func (s *Scheduler) run(task *Task, returnc chan<- *Task) {
 	// If we get reassigned to a new alloc, that will not be true anymore, and hence we need to resolve
 	// the files all over again.
 	savedArgs := append([]reflow.Arg{}, task.Config.Args...)
-	ctx, endTrace := trace.Start(ctx, state.TraceKind(), task.FlowID, fmt.Sprintf("%s_%s %s", task.Config.Ident, task.FlowID.Short(), state.String()))
+	ctx, endTrace := trace.StartNode(ctx, state.TraceKind(), task.FlowID, taskLogicalID(task, state), fmt.Sprintf("%s_%s %s", task.Config.Ident, task.FlowID.Short(), state.String()))
+	trace.Note(ctx, "execDigest", digest.Digest(task.ID()).String())
+	trace.Note(ctx, "resources", task.Config.Resources.String())
+	trace.Note(ctx, "allocID", alloc.Alloc.ID())
+	defer s.protect(ctx, task, alloc, taskLogger)()
 	for attempt < numExecTries && state < internal.StateDone {
 		taskLogger.Debugf("%s (try %d): started", state, attempt)
 		switch state {
@@ -500,13 +888,20 @@ func (s *Scheduler) run(task *Task, returnc chan<- *Task) {
 					ID:        task.ID(),
 					RunID:     task.RunID,
 					FlowID:    task.FlowID,
+					CacheKeys: task.CacheKeys,
 					ImgCmdID:  taskdb.NewImgCmdID(task.Config.Image, task.Config.Cmd),
 					Ident:     task.Config.Ident,
 					Attempt:   task.Attempt(),
+					Retry:     task.Retry,
 					Resources: task.Config.Resources,
 					AllocID:   alloc.taskdbAllocID,
 				}
+				if g := task.group; g != nil && s.createArray(ctx, g, taskLogger) {
+					// The array entry records the cache keys of its tasks.
+					tdbtask.ArrayID, tdbtask.ArrayIndex, tdbtask.CacheKeys = g.id, task.arrayIndex, nil
+				}
 				if taskdbErr := s.TaskDB.CreateTask(tctx, tdbtask); taskdbErr != nil {
+					metrics.GetTaskdbErrorsCountCounter(ctx, "createtask").Inc()
 					taskLogger.Errorf("taskdb createtask: %v", taskdbErr)
 				} else {
 					go func() { _ = taskdb.KeepTaskAlive(tctx, s.TaskDB, task.ID()) }()
@@ -532,6 +927,7 @@ func (s *Scheduler) run(task *Task, returnc chan<- *Task) {
 						return lerr
 					}
 					taskLogger.Debugf("loaded %s", fs.Short())
+					atomic.AddInt64(&loaded, fs.Size())
 					task.Config.Args[i].Fileset = &fs
 					loadedData.Store(i, true)
 					return nil
@@ -540,19 +936,37 @@ func (s *Scheduler) run(task *Task, returnc chan<- *Task) {
 			})
 			err = g.Wait()
 		case internal.StatePut:
-			x, err = alloc.Put(ctx, digest.Digest(task.ID()), task.Config)
+			if release == nil && (task.Config.Type == "intern" || task.Config.Type == "extern") {
+				if release, err = s.acquireTransfer(ctx, task); err != nil {
+					break
+				}
+				defer release()
+			}
+			task.Config.Priority = task.Priority
+			task.Config.Timeout = task.Timeout
+			// Credentials are set on a copy of the config so that they
+			// are not retained (or logged) with the task.
+			cfg := task.Config
+			if cfg.Credentials, err = s.scopeCredentials(ctx, task); err != nil {
+				break
+			}
+			x, err = alloc.Put(ctx, digest.Digest(task.ID()), cfg)
 		case internal.StateWait:
 			if s.TaskDB != nil {
 				if taskdbErr := s.TaskDB.SetTaskUri(tctx, task.ID(), x.URI()); taskdbErr != nil {
+					metrics.GetTaskdbErrorsCountCounter(ctx, "settaskuri").Inc()
 					taskLogger.Errorf("taskdb settaskuri: %v", taskdbErr)
 				}
 			}
 			task.Exec = x
 			task.Set(TaskRunning)
+			stopProgress := s.reportProgress(tctx, task, x, taskLogger)
 			err = x.Wait(ctx)
+			stopProgress()
 			if s.TaskDB != nil {
 				// TODO(swami): Fix this so that the task result points to the result fileset.
 				if taskdbErr := s.TaskDB.SetTaskResult(tctx, task.ID(), x.ID()); taskdbErr != nil {
+					metrics.GetTaskdbErrorsCountCounter(ctx, "settaskresult").Inc()
 					taskLogger.Errorf("taskdb settaskresult: %v", taskdbErr)
 				}
 			}
@@ -589,6 +1003,9 @@ func (s *Scheduler) run(task *Task, returnc chan<- *Task) {
 			}
 		case internal.StateResult:
 			task.Result, err = x.Result(ctx)
+			if err == nil {
+				saved = task.Result.Fileset.Size()
+			}
 			if err == nil && task.Config.Type == "extern" {
 				// If files are 'extern'ed without using direct transfer, the result fileset contains
 				// the same files as the input, but are missing assertions. The assertions do however
@@ -608,7 +1025,7 @@ func (s *Scheduler) run(task *Task, returnc chan<- *Task) {
 			trace.Note(ctx, fmt.Sprintf("\"%s\" retries", state.String()), attempt)
 		} else {
 			endTrace() // end the trace for the current state and start it for the next one
-			_, endTrace = trace.Start(ctx, next.TraceKind(), task.FlowID, fmt.Sprintf("%s_%s %s", task.Config.Ident, task.FlowID.Short(), next.String()))
+			_, endTrace = trace.StartNode(ctx, next.TraceKind(), task.FlowID, taskLogicalID(task, next), fmt.Sprintf("%s_%s %s", task.Config.Ident, task.FlowID.Short(), next.String()))
 		}
 		state = next
 	}
@@ -622,13 +1039,35 @@ func (s *Scheduler) run(task *Task, returnc chan<- *Task) {
 		err = s.TaskDB.SetTaskAttrs(ctx, task.ID(), task.RunInfo.Stdout.Digest, task.RunInfo.Stderr.Digest, task.RunInfo.InspectDigest.Digest)
 	}
 	task.Err = err
+	s.addCost(task, alloc, time.Since(start))
+	s.Stats.AddTransfer(task, alloc, loaded, saved)
+	if setter, ok := s.TaskDB.(taskdb.TransferSetter); ok && tctx != nil {
+		if taskdbErr := setter.SetTaskTransfer(context.Background(), task.ID(), loaded, saved); taskdbErr != nil {
+			metrics.GetTaskdbErrorsCountCounter(ctx, "settasktransfer").Inc()
+			taskLogger.Errorf("taskdb settasktransfer: %v", taskdbErr)
+		}
+	}
+	if err != nil || task.Result.Err != nil {
+		task.addFailedDomain(alloc.domain)
+	}
+	if s.InstanceTypes != nil && task.Config.Type == "exec" && alloc.instanceType != "" && alloc.Context.Err() == nil {
+		execErr := err
+		if execErr == nil {
+			execErr = task.Result.Err
+		}
+		if !isLost(execErr) {
+			s.InstanceTypes.Add(task.Config.Ident, alloc.instanceType, time.Since(start), execErr)
+		}
+	}
 	switch {
-	case err == nil:
-		task.Set(TaskDone)
-	case alloc.Context.Err() != nil:
+	case s.OutOfDisk == OutOfDiskRetry && task.diskRetries < maxOutOfDiskRetries && isOutOfDisk(err, task.Result.Err):
 		task.Config.Args = savedArgs
+		task.outOfDisk = true
 		task.Set(TaskLost)
-	case errors.Is(errors.Canceled, err), errors.Is(errors.Net, err), errors.Is(errors.Timeout, err), errors.Is(errors.Unavailable, err):
+	case err != nil && alloc.Context.Err() != nil:
+		task.Config.Args = savedArgs
+		task.Set(TaskLost)
+	case s.retry(task, err):
 		task.Config.Args = savedArgs
 		task.Set(TaskLost)
 	default:
@@ -638,6 +1077,69 @@ func (s *Scheduler) run(task *Task, returnc chan<- *Task) {
 	returnc <- task
 }
 
+// reportProgress periodically records in the taskdb the progress
+// reported by the task's running exec x, if the taskdb supports it,
+// until the returned function is called.
+func (s *Scheduler) reportProgress(ctx context.Context, task *Task, x reflow.Exec, taskLogger *log.Logger) (stop func()) {
+	setter, ok := s.TaskDB.(taskdb.ProgressSetter)
+	if !ok || ctx == nil {
+		return func() {}
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+		last := -1.0
+		for {
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+			resp, err := x.Inspect(ctx, nil)
+			if err != nil || resp.Inspect == nil {
+				continue
+			}
+			p, ok := resp.Inspect.Gauges[reflow.ProgressGauge]
+			if !ok || p == last {
+				continue
+			}
+			if err := setter.SetTaskProgress(ctx, task.ID(), p); err != nil {
+				metrics.GetTaskdbErrorsCountCounter(ctx, "settaskprogress").Inc()
+				taskLogger.Errorf("taskdb settaskprogress: %v", err)
+				continue
+			}
+			last = p
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// taskLogicalID returns the logical trace ID of the provided task in
+// the provided state. It is derived from the task's exec identifier,
+// which (unlike its flow ID) is stable across runs of the same program.
+// It is empty if the task has no identifier.
+func taskLogicalID(task *Task, state internal.ExecState) string {
+	if task.Config.Ident == "" {
+		return ""
+	}
+	return task.Config.Ident + "/" + state.String()
+}
+
+// isOutOfDisk tells whether the given task error, or else the task's
+// result error, indicates that the task ran out of disk space.
+func isOutOfDisk(err error, resultErr *errors.Error) bool {
+	if err != nil {
+		return errors.Is(errors.OutOfDisk, err)
+	}
+	return resultErr != nil && errors.Is(errors.OutOfDisk, resultErr)
+}
+
 func unload(ctx context.Context, task *Task, taskLogger *log.Logger, loadedData *sync.Map, alloc *alloc, resultUnloaded *bool) error {
 	g, gctx := errgroup.WithContext(ctx)
 	loadedData.Range(func(key, value interface{}) bool {
@@ -680,7 +1182,7 @@ func unload(ctx context.Context, task *Task, taskLogger *log.Logger, loadedData
 
 func (s *Scheduler) directTransfer(ctx context.Context, task *Task) {
 	const identifier = "scheduler.directTransfer"
-	taskLogger := s.Log.Tee(nil, fmt.Sprintf("direct transfer %s: ", task.ID().IDShort()))
+	taskLogger := s.Log.Tee(nil, fmt.Sprintf("direct transfer %s: ", task.ID().IDShort())).With(log.TaskIDKey, task.ID().ID())
 	if s.TaskDB != nil {
 		taskdbErr := s.TaskDB.CreateTask(ctx, taskdb.Task{
 			ID:       task.ID(),
@@ -691,11 +1193,13 @@ func (s *Scheduler) directTransfer(ctx context.Context, task *Task) {
 			URI:      "local",
 		})
 		if taskdbErr != nil {
+			metrics.GetTaskdbErrorsCountCounter(ctx, "createtask").Inc()
 			taskLogger.Errorf("taskdb createtask: %v", taskdbErr)
 		} else {
 			tctx, tcancel := context.WithCancel(ctx)
 			defer func() {
 				if err := s.TaskDB.SetTaskComplete(context.Background(), task.ID(), task.Err, time.Now()); err != nil {
+					metrics.GetTaskdbErrorsCountCounter(ctx, "settaskcomplete").Inc()
 					taskLogger.Errorf("taskdb settaskcomplete: %v", err)
 				}
 				tcancel()
@@ -717,6 +1221,7 @@ func (s *Scheduler) directTransfer(ctx context.Context, task *Task) {
 	}
 	if s.TaskDB != nil && task.Result.Err == nil {
 		if err := s.TaskDB.SetTaskResult(ctx, task.ID(), task.Result.Fileset.Digest()); err != nil {
+			metrics.GetTaskdbErrorsCountCounter(ctx, "settaskresult").Inc()
 			taskLogger.Errorf("taskdb settaskresult: %v", err)
 		}
 	}
@@ -740,21 +1245,21 @@ func requirements(tasks []*Task) reflow.Requirements {
 	tasksCopy := append([]*Task{}, tasks...)
 	// Sort the tasks by resource needs
 	sort.Slice(tasksCopy, func(i, j int) bool {
-		return tasksCopy[i].Config.Resources.ScaledDistance(nil) > tasksCopy[j].Config.Resources.ScaledDistance(nil)
+		return tasksCopy[i].config().Resources.ScaledDistance(nil) > tasksCopy[j].config().Resources.ScaledDistance(nil)
 	})
 	for len(tasksCopy) > 0 {
 		i = sort.Search(len(tasksCopy), func(i int) bool {
-			return have.Available(tasksCopy[i].Config.Resources)
+			return have.Available(tasksCopy[i].config().Resources)
 		})
 		if i == len(tasksCopy) {
 			// Found nothing, so add the current biggest task's resources
-			req.AddParallel(tasksCopy[0].Config.Resources)
+			req.AddParallel(tasksCopy[0].config().Resources)
 			i = 0
 			// Reset current available resources
 			have.Set(req.Min)
 		}
 		// Found the biggest one which'll fit in the current available resources.
-		have.Sub(have, tasksCopy[i].Config.Resources)
+		have.Sub(have, tasksCopy[i].config().Resources)
 		tasksCopy = append(tasksCopy[0:i], tasksCopy[i+1:]...)
 	}
 	return req
@@ -766,53 +1271,40 @@ type directTransfer struct {
 	srcUrl, dstUrl string
 }
 
-// doDirectTransfer attempts to do a direct transfer for externs.
-// Direct transfers are supported only if the scheduler's Repository
-// and the destination repository are both blob stores.
+// doDirectTransfer attempts to do a direct transfer for externs and
+// interns. Direct transfers are supported only if the scheduler's
+// Repository and the extern destination (or intern source) are both
+// blob stores.
 func (s *Scheduler) doDirectTransfer(ctx context.Context, task *Task, taskLogger *log.Logger) error {
-	if task.Config.Type != "extern" {
-		panic("direct transfers only supported for extern")
-	}
-	if len(task.Config.Args) != 1 {
-		return errors.E(errors.Precondition,
-			errors.Errorf("unexpected args (must be 1, but was %d): %v", len(task.Config.Args), task.Config.Args))
-	}
-	// Check if the task's repository supports blobLocator.
-	fileLocator, ok := task.Repository.(blobLocator)
-	if !ok {
-		return errors.E(errors.NotSupported, errors.New("scheduler repository does not support locating blobs"))
+	var (
+		transfers []directTransfer
+		err       error
+	)
+	switch task.Config.Type {
+	case "extern":
+		transfers, err = s.externTransfers(ctx, task)
+	case "intern":
+		transfers, err = s.internTransfers(ctx, task)
+	default:
+		panic("direct transfers only supported for extern and intern")
 	}
-	// Check if the destination is a blob store.
-	if _, _, err := s.Mux.Bucket(ctx, task.Config.URL); err != nil {
+	if err != nil {
 		return err
 	}
-
-	extUrl := strings.TrimSuffix(task.Config.URL, "/")
-	fs := task.Config.Args[0].Fileset.Pullup()
-
-	var transfers []directTransfer
-	for k, v := range fs.Map {
-		filename, file := k, v
-		var srcUrl string
-		if !file.IsRef() {
-			// resolved file
-			if src, err := fileLocator.Location(ctx, file.ID); err != nil {
-				return err
-			} else {
-				srcUrl = src
+	mux := s.Mux
+	if s.Scoper != nil {
+		accesses := make([]blob.Access, 0, 2*len(transfers))
+		for _, t := range transfers {
+			accesses = append(accesses, blob.Access{URL: t.srcUrl})
+			if task.Config.Type == "extern" {
+				accesses = append(accesses, blob.Access{URL: t.dstUrl, Write: true})
 			}
-		} else {
-			// reference file
-			srcUrl = file.Source
 		}
-		dstUrl := extUrl + "/" + filename
-		if filename == "." {
-			dstUrl = extUrl
+		creds, err := s.Scoper.Scope(ctx, accesses...)
+		if err != nil {
+			return err
 		}
-		if ok, err := s.Mux.CanTransfer(ctx, dstUrl, srcUrl); !ok {
-			return errors.E(fmt.Sprintf("scheduler cannot direct transfer: %s -> %s", srcUrl, dstUrl), err)
-		}
-		transfers = append(transfers, directTransfer{filename, file, srcUrl, dstUrl})
+		mux = mux.WithCredentials(creds)
 	}
 
 	task.mu.Lock()
@@ -831,8 +1323,19 @@ func (s *Scheduler) doDirectTransfer(ctx context.Context, task *Task, taskLogger
 			taskLogger.Debugf("%d transfers remaining, stalled attempts: %d/%d", len(transfers), stalledAttempts, maxStalledAttempts)
 			t := t
 			g.Go(func() error {
+				release, err := s.acquireTransfer(gctx, task)
+				if err != nil {
+					return err
+				}
+				defer release()
 				start := time.Now()
-				if err := s.Mux.Transfer(gctx, t.dstUrl, t.srcUrl); err != nil {
+				file := t.file
+				if task.Config.Type == "intern" {
+					file, err = s.internFile(gctx, mux, task, t)
+				} else {
+					err = mux.Transfer(gctx, t.dstUrl, t.srcUrl)
+				}
+				if err != nil {
 					if !errors.Restartable(err) {
 						return errors.E(fmt.Sprintf("scheduler direct transfer: %s -> %s", t.srcUrl, t.dstUrl), err)
 					}
@@ -848,9 +1351,11 @@ func (s *Scheduler) doDirectTransfer(ctx context.Context, task *Task, taskLogger
 					dur += time.Second
 				}
 				sz := t.file.Size
+				metrics.GetDirectTransferBytesCounter(ctx).Add(float64(sz))
+				s.Stats.AddTransfer(task, nil, 0, sz)
 				taskLogger.Debugf("completed %s -> %s (%s) in %s (%s/s) ", t.srcUrl, t.dstUrl, data.Size(sz), dur, data.Size(sz/int64(dur.Seconds())))
 				task.mu.Lock()
-				task.Result.Fileset.Map[t.filename] = t.file
+				task.Result.Fileset.Map[t.filename] = file
 				task.mu.Unlock()
 				return nil
 			})
@@ -876,3 +1381,138 @@ func (s *Scheduler) doDirectTransfer(ctx context.Context, task *Task, taskLogger
 	}
 	return nil
 }
+
+// scopeCredentials returns the credentials with which the given task
+// is run on an alloc: interns may read their source URL and externs
+// may write their destination URL. scopeCredentials returns nil
+// credentials for other tasks, or if the scheduler has no Scoper.
+func (s *Scheduler) scopeCredentials(ctx context.Context, task *Task) (*reflow.Credentials, error) {
+	if s.Scoper == nil {
+		return nil, nil
+	}
+	switch task.Config.Type {
+	case "intern":
+		return s.Scoper.Scope(ctx, blob.Access{URL: task.Config.URL})
+	case "extern":
+		return s.Scoper.Scope(ctx, blob.Access{URL: task.Config.URL, Write: true})
+	}
+	return nil, nil
+}
+
+// externTransfers returns the transfers needed to export the extern
+// task's fileset from the scheduler's repository to its destination.
+func (s *Scheduler) externTransfers(ctx context.Context, task *Task) ([]directTransfer, error) {
+	if len(task.Config.Args) != 1 {
+		return nil, errors.E(errors.Precondition,
+			errors.Errorf("unexpected args (must be 1, but was %d): %v", len(task.Config.Args), task.Config.Args))
+	}
+	// Check if the task's repository supports blobLocator.
+	fileLocator, ok := task.Repository.(blobLocator)
+	if !ok {
+		return nil, errors.E(errors.NotSupported, errors.New("scheduler repository does not support locating blobs"))
+	}
+	// Check if the destination is a blob store.
+	if _, _, err := s.Mux.Bucket(ctx, task.Config.URL); err != nil {
+		return nil, err
+	}
+
+	extUrl := strings.TrimSuffix(task.Config.URL, "/")
+	fs := task.Config.Args[0].Fileset.Pullup()
+
+	var transfers []directTransfer
+	for k, v := range fs.Map {
+		filename, file := k, v
+		var srcUrl string
+		if !file.IsRef() {
+			// resolved file
+			if src, err := fileLocator.Location(ctx, file.ID); err != nil {
+				return nil, err
+			} else {
+				srcUrl = src
+			}
+		} else {
+			// reference file
+			srcUrl = file.Source
+		}
+		dstUrl := extUrl + "/" + filename
+		if filename == "." {
+			dstUrl = extUrl
+		}
+		if ok, err := s.Mux.CanTransfer(ctx, dstUrl, srcUrl); !ok {
+			return nil, errors.E(fmt.Sprintf("scheduler cannot direct transfer: %s -> %s", srcUrl, dstUrl), err)
+		}
+		transfers = append(transfers, directTransfer{filename, file, srcUrl, dstUrl})
+	}
+	return transfers, nil
+}
+
+// internTransfers returns the transfers needed to import the intern
+// task's source into the scheduler's repository. Interned objects are
+// stored under the digest computed while streaming them (see internFile):
+// content hashes recorded in object metadata are not trusted.
+func (s *Scheduler) internTransfers(ctx context.Context, task *Task) (transfers []directTransfer, err error) {
+	// Check if the task's repository is a blob store.
+	repoUrl := task.Repository.URL().String()
+	if _, _, err = s.Mux.Bucket(ctx, repoUrl); err != nil {
+		return nil, errors.E(errors.NotSupported, errors.Errorf("scheduler repository %s is not a blob store", repoUrl), err)
+	}
+	// Check if the source is a blob store.
+	bucket, prefix, err := s.Mux.Bucket(ctx, task.Config.URL)
+	if err != nil {
+		return nil, err
+	}
+	add := func(filename, key string, file reflow.File) {
+		transfers = append(transfers, directTransfer{filename, file, bucket.Location() + key, repoUrl})
+	}
+
+	if !strings.HasSuffix(prefix, "/") {
+		file, err := bucket.File(ctx, prefix)
+		if err != nil {
+			return nil, err
+		}
+		add(".", prefix, file)
+		return transfers, nil
+	}
+	nprefix := len(prefix)
+	scan := bucket.Scan(prefix)
+	for scan.Scan(ctx) {
+		key := scan.Key()
+		// Skip "directories".
+		if len(key) < nprefix || strings.HasSuffix(key, "/") {
+			continue
+		}
+		add(key[nprefix:], key, scan.File())
+	}
+	if err := scan.Err(); err != nil {
+		return nil, err
+	}
+	return transfers, nil
+}
+
+// internFile streams the source object of the given intern transfer,
+// read through the provided mux, into the task's repository, which
+// computes the object's digest as it is written. The returned file is
+// identified by that digest; a content hash in the object's metadata
+// which disagrees with it is stale (or forged) and is logged and
+// discarded.
+func (s *Scheduler) internFile(ctx context.Context, mux blob.Mux, task *Task, t directTransfer) (reflow.File, error) {
+	rc, file, err := mux.Get(ctx, t.srcUrl, t.file.ETag)
+	if err != nil {
+		return reflow.File{}, err
+	}
+	defer rc.Close()
+	id, err := task.Repository.Put(ctx, rc)
+	if err != nil {
+		return reflow.File{}, err
+	}
+	if !file.ContentHash.IsZero() && file.ContentHash != id {
+		s.Log.Printf("intern %s: content hash metadata %v does not match digest %v", t.srcUrl, file.ContentHash, id)
+	}
+	return reflow.File{
+		ID:           id,
+		Size:         file.Size,
+		Source:       file.Source,
+		ETag:         file.ETag,
+		LastModified: file.LastModified,
+	}, nil
+}
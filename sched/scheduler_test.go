@@ -5,6 +5,7 @@
 package sched_test
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	golog "log"
@@ -25,6 +26,7 @@ import (
 	"github.com/grailbio/reflow/log"
 	"github.com/grailbio/reflow/pool"
 	"github.com/grailbio/reflow/repository"
+	"github.com/grailbio/reflow/repository/blobrepo"
 	"github.com/grailbio/reflow/sched"
 	"github.com/grailbio/reflow/sched/internal/utiltest"
 	"github.com/grailbio/reflow/taskdb"
@@ -162,6 +164,25 @@ func TestSchedulerBasic(t *testing.T) {
 	if got := stats.OverallStats; got != want {
 		t.Errorf("got %v, want %v", got, want)
 	}
+	taskStats := stats.Tasks[sched.GetTaskStatsId(task)]
+	if got, want := taskStats.BytesLoaded, in.Size(); got != want {
+		t.Errorf("got %v bytes loaded, want %v", got, want)
+	}
+	if got, want := taskStats.BytesSaved, out.Size(); got != want {
+		t.Errorf("got %v bytes saved, want %v", got, want)
+	}
+	for _, a := range stats.Allocs {
+		if got, want := a.BytesLoaded, in.Size(); got != want {
+			t.Errorf("got %v alloc bytes loaded, want %v", got, want)
+		}
+		if got, want := a.BytesSaved, out.Size(); got != want {
+			t.Errorf("got %v alloc bytes saved, want %v", got, want)
+		}
+	}
+	transfers := stats.RunTransfers(task.RunID.ID())
+	if got, want := transfers[task.Config.Type], (sched.TransferStats{Tasks: 1, BytesLoaded: in.Size(), BytesSaved: out.Size()}); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
 	expectExists(t, repo, out)
 
 	mtdb := scheduler.TaskDB.(*inmemorytaskdb.InmemoryTaskDB)
@@ -177,6 +198,84 @@ func TestSchedulerBasic(t *testing.T) {
 	if got, want := tsk.AllocID, ai.TaskDBAllocID; got != want {
 		t.Errorf("got %v, want %v", got, want)
 	}
+	if got, want := tsk.BytesLoaded, in.Size(); got != want {
+		t.Errorf("got %v bytes loaded, want %v", got, want)
+	}
+	if got, want := tsk.BytesSaved, out.Size(); got != want {
+		t.Errorf("got %v bytes saved, want %v", got, want)
+	}
+}
+
+func TestSchedulerTaskArray(t *testing.T) {
+	scheduler, cluster, shutdown := newTestScheduler(t)
+	defer shutdown()
+	ctx := context.Background()
+
+	repo := testutil.NewInmemoryRepository("")
+	in := utiltest.RandomFileset(repo)
+	template := utiltest.NewTask(1, 1<<30, 0).WithRepo(repo)
+	template.Config.Args = []reflow.Arg{{Fileset: &in}}
+	template.CacheKeys = []digest.Digest{reflow.Digester.Rand(nil)}
+	array := sched.NewTaskArray(template, 3, true)
+	if got, want := array.Status().String(), "3 tasks: 3 initializing"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	scheduler.SubmitGroup(array)
+	req := <-cluster.Req()
+	alloc := utiltest.NewTestAllocWithId("array", reflow.Resources{"cpu": 3, "mem": 3 << 30})
+	req.Reply <- utiltest.TestClusterAllocReply{Alloc: alloc, Err: nil}
+	if err := array.Wait(ctx, sched.TaskRunning); err != nil {
+		t.Fatal(err)
+	}
+	for i, task := range array.Tasks {
+		if got, want := task.Config.ArrayIndex, i; got != want {
+			t.Errorf("task %d: got index %v, want %v", i, got, want)
+		}
+		if got, want := task.Config.ArraySize, 3; got != want {
+			t.Errorf("task %d: got size %v, want %v", i, got, want)
+		}
+		var err error
+		if i == 1 {
+			err = errors.New("task failed")
+		}
+		alloc.Exec(digest.Digest(task.ID())).Complete(reflow.Result{Err: errors.Recover(err)}, nil)
+	}
+	if err := array.Wait(ctx, sched.TaskDone); err != nil {
+		t.Fatal(err)
+	}
+	status := array.Status()
+	if !status.Done() {
+		t.Errorf("array not done: %v", status)
+	}
+	if got, want := status.String(), "3 tasks: 3 done (1 failed)"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if err := array.Err(); err == nil || !strings.Contains(err.Error(), "tasks [1] failed") {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	mtdb := scheduler.TaskDB.(*inmemorytaskdb.InmemoryTaskDB)
+	if got, want := mtdb.NumCalls("CreateArray"), 1; got != want {
+		t.Errorf("got %v arrays, want %v", got, want)
+	}
+	tasks, _ := mtdb.Tasks(ctx, taskdb.TaskQuery{})
+	if got, want := len(tasks), 3; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	indices := make(map[int]bool)
+	for _, tsk := range tasks {
+		if !tsk.ArrayID.IsValid() || tsk.ArrayID != tasks[0].ArrayID {
+			t.Errorf("task %v: bad array %v", tsk.ID, tsk.ArrayID)
+		}
+		if got, want := tsk.CacheKeys, template.CacheKeys; len(got) != 1 || got[0] != want[0] {
+			t.Errorf("got %v, want %v", got, want)
+		}
+		indices[tsk.ArrayIndex] = true
+	}
+	if got, want := len(indices), 3; got != want {
+		t.Errorf("got %v indices, want %v", got, want)
+	}
 }
 
 func TestSchedulerDifferentTaskRepos(t *testing.T) {
@@ -365,6 +464,40 @@ func TestSchedulerTaskTooBig(t *testing.T) {
 	}
 }
 
+func TestSchedulerArchDepletedAlloc(t *testing.T) {
+	scheduler, cluster, shutdown := newTestScheduler(t)
+	defer shutdown()
+	ctx := context.Background()
+
+	armTask := utiltest.NewTask(1, 1<<30, 0)
+	armTask.Config.Resources[reflow.Arm64] = 4
+	scheduler.Submit(armTask)
+	req := <-cluster.Req()
+	armAlloc := utiltest.NewTestAllocWithId("arm", reflow.Resources{"cpu": 8, "mem": 16 << 30, reflow.Arm64: 4})
+	req.Reply <- utiltest.TestClusterAllocReply{Alloc: armAlloc, Err: nil}
+	if err := armTask.Wait(ctx, sched.TaskRunning); err != nil {
+		t.Fatal(err)
+	}
+
+	// The arm64 alloc's arm64 resources are now depleted, but it
+	// must not be used for tasks which do not require arm64.
+	task := utiltest.NewTask(1, 1<<30, 0)
+	scheduler.Submit(task)
+	select {
+	case req = <-cluster.Req():
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected an alloc request")
+	}
+	if got := req.Requirements.Min[reflow.Arm64]; got != 0 {
+		t.Errorf("got %v arm64 requirement, want 0", got)
+	}
+	alloc := utiltest.NewTestAllocWithId("x86", reflow.Resources{"cpu": 8, "mem": 16 << 30})
+	req.Reply <- utiltest.TestClusterAllocReply{Alloc: alloc, Err: nil}
+	if err := task.Wait(ctx, sched.TaskRunning); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestTaskLost(t *testing.T) {
 	scheduler, cluster, shutdown := newTestScheduler(t)
 	defer shutdown()
@@ -588,6 +721,93 @@ func TestTaskErrors(t *testing.T) {
 	}
 }
 
+func TestTaskOutOfDisk(t *testing.T) {
+	scheduler, cluster, shutdown := newTestScheduler(t)
+	defer shutdown()
+	scheduler.OutOfDisk = sched.OutOfDiskRetry
+	ctx := context.Background()
+
+	repo := testutil.NewInmemoryRepository("")
+	task := utiltest.NewTask(1, 1, 0).WithRepo(repo)
+	task.Config.Resources["disk"] = 10
+	scheduler.Submit(task)
+
+	allocs := []*utiltest.TestAlloc{
+		utiltest.NewTestAlloc(reflow.Resources{"cpu": 2, "mem": 2, "disk": 10}),
+		utiltest.NewTestAlloc(reflow.Resources{"cpu": 2, "mem": 2, "disk": 20}),
+	}
+	req := <-cluster.Req()
+	req.Reply <- utiltest.TestClusterAllocReply{Alloc: allocs[0]}
+	if err := task.Wait(ctx, sched.TaskRunning); err != nil {
+		t.Fatal(err)
+	}
+	oodErr := errors.Recover(errors.E("exec", errors.OutOfDisk, errors.New("no space left on device")))
+	allocs[0].Exec(digest.Digest(task.ID())).Complete(reflow.Result{Err: oodErr}, nil)
+
+	// The task should be retried on a new alloc with a larger disk requirement.
+	req = <-cluster.Req()
+	if got, want := req.Requirements.Min["disk"], 20.0; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := task.Attempt(), 1; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	req.Reply <- utiltest.TestClusterAllocReply{Alloc: allocs[1]}
+	allocs[1].Exec(digest.Digest(task.ID())).Complete(reflow.Result{}, nil)
+	if err := task.Wait(ctx, sched.TaskDone); err != nil {
+		t.Fatal(err)
+	}
+	if task.Err != nil || task.Result.Err != nil {
+		t.Errorf("unexpected task errors: %v, %v", task.Err, task.Result.Err)
+	}
+}
+
+func TestTaskRetryPolicy(t *testing.T) {
+	scheduler, cluster, shutdown := newTestScheduler(t)
+	defer shutdown()
+	scheduler.RetryPolicy = sched.KindRetryPolicy{
+		errors.OOM: {MaxRetries: 1, Results: true, Escalate: sched.ScaleResources(reflow.Resources{"mem": 4}, nil)},
+	}
+	ctx := context.Background()
+
+	repo := testutil.NewInmemoryRepository("")
+	task := utiltest.NewTask(1, 1, 0).WithRepo(repo)
+	scheduler.Submit(task)
+
+	allocs := []*utiltest.TestAlloc{
+		utiltest.NewTestAlloc(reflow.Resources{"cpu": 2, "mem": 2}),
+		utiltest.NewTestAlloc(reflow.Resources{"cpu": 2, "mem": 4}),
+	}
+	req := <-cluster.Req()
+	req.Reply <- utiltest.TestClusterAllocReply{Alloc: allocs[0]}
+	if err := task.Wait(ctx, sched.TaskRunning); err != nil {
+		t.Fatal(err)
+	}
+	oomErr := errors.Recover(errors.E("exec", errors.OOM, errors.New("out of memory")))
+	allocs[0].Exec(digest.Digest(task.ID())).Complete(reflow.Result{Err: oomErr}, nil)
+
+	// The task should be retried on a new alloc with more memory.
+	req = <-cluster.Req()
+	if got, want := req.Requirements.Min["mem"], 4.0; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := task.Attempt(), 1; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	req.Reply <- utiltest.TestClusterAllocReply{Alloc: allocs[1]}
+	if err := task.Wait(ctx, sched.TaskRunning); err != nil {
+		t.Fatal(err)
+	}
+	// Once its retries are exhausted, the task's result is returned as is.
+	allocs[1].Exec(digest.Digest(task.ID())).Complete(reflow.Result{Err: oomErr}, nil)
+	if err := task.Wait(ctx, sched.TaskDone); err != nil {
+		t.Fatal(err)
+	}
+	if task.Err != nil || !errors.Is(errors.OOM, task.Result.Err) {
+		t.Errorf("got task errors %v, %v, want OOM result", task.Err, task.Result.Err)
+	}
+}
+
 // TestLostTasksSwitchAllocs tests scenarios where lost tasks are re-allocated.
 // Only some type of task errors are considered 'lost' (and retries are attempted),
 // whereas any error from alloc keepalives will result in tasks being considered as lost.
@@ -829,6 +1049,96 @@ func TestSchedulerDirectTransfer_unresolvedFile(t *testing.T) {
 	assertNonDirectTransfer(t, scheduler, &in, repo)
 }
 
+func TestSchedulerDirectTransferIntern(t *testing.T) {
+	scheduler, _, shutdown := newTestScheduler(t)
+	store := testblob.New("test")
+	scheduler.Mux = blob.Mux{"test": store}
+	defer shutdown()
+	ctx := context.Background()
+	repoBucket, _ := store.Bucket(ctx, "bucketrepo")
+	repo := &blobrepo.Repository{Bucket: repoBucket, Prefix: "repo"}
+	want := make(map[string]digest.Digest)
+	for i := 0; i < 5; i++ {
+		p := []byte(fmt.Sprintf("intern contents %d", i))
+		d := reflow.Digester.FromBytes(p)
+		name := fmt.Sprintf("file%d", i)
+		want[name] = d
+		_ = scheduler.Mux.Put(ctx, "test://bucketin/data/"+name, int64(len(p)), bytes.NewReader(p), d.Hex())
+		// One of the objects is already present in the repository.
+		if i == 0 {
+			if _, err := repo.Put(ctx, bytes.NewReader(p)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	task := utiltest.NewTask(1, 10<<20, 0).WithRepo(repo)
+	task.Config.Type = "intern"
+	task.Config.URL = "test://bucketin/data/"
+
+	scheduler.Submit(task)
+	_ = task.Wait(ctx, sched.TaskDone)
+	if task.Err != nil {
+		t.Fatal(task.Err)
+	}
+	if task.NonDirectTransfer() {
+		t.Fatal("task must not be marked as non-direct")
+	}
+	out := task.Result.Fileset
+	if got, want := len(out.Map), len(want); got != want {
+		t.Fatalf("got %v files, want %v", got, want)
+	}
+	for name, d := range want {
+		if got := out.Map[name].ID; got != d {
+			t.Errorf("%s: got %v, want %v", name, got, d)
+		}
+	}
+	expectExists(t, repo, out)
+}
+
+func TestSchedulerDirectTransferIntern_contentHash(t *testing.T) {
+	p := []byte("intern contents")
+	d := reflow.Digester.FromBytes(p)
+	for _, tc := range []struct {
+		name, contentHash string
+	}{
+		{"none", ""},
+		{"valid", d.Hex()},
+		{"stale", reflow.Digester.FromString("other contents").Hex()},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			scheduler, _, shutdown := newTestScheduler(t)
+			store := testblob.New("test")
+			scheduler.Mux = blob.Mux{"test": store}
+			defer shutdown()
+			ctx := context.Background()
+			repoBucket, _ := store.Bucket(ctx, "bucketrepo")
+			repo := &blobrepo.Repository{Bucket: repoBucket}
+			_ = scheduler.Mux.Put(ctx, "test://bucketin/data", int64(len(p)), bytes.NewReader(p), tc.contentHash)
+
+			task := utiltest.NewTask(1, 10<<20, 0).WithRepo(repo)
+			task.Config.Type = "intern"
+			task.Config.URL = "test://bucketin/data"
+
+			scheduler.Submit(task)
+			_ = task.Wait(ctx, sched.TaskDone)
+			if task.Err != nil {
+				t.Fatal(task.Err)
+			}
+			if task.NonDirectTransfer() {
+				t.Fatal("task must not be marked as non-direct")
+			}
+			// The object is always stored under its computed digest,
+			// regardless of its content hash metadata.
+			out := task.Result.Fileset
+			if got, want := out.Map["."].ID, d; got != want {
+				t.Errorf("got %v, want %v", got, want)
+			}
+			expectExists(t, repo, out)
+		})
+	}
+}
+
 func assertNonDirectTransfer(t *testing.T, scheduler *sched.Scheduler, in *reflow.Fileset, repo reflow.Repository) {
 	ctx := context.Background()
 
@@ -16,6 +16,12 @@ type OverallStats struct {
 	TotalAllocs int64
 	// TotalTasks is the total number of tasks (pending, running or completed).
 	TotalTasks int64
+	// StarvedTasks is the number of tasks which were queued for
+	// longer than the scheduler's starvation threshold.
+	StarvedTasks int64
+	// ProtectedTasks is the number of tasks during which the instances
+	// of their allocs were protected from termination.
+	ProtectedTasks int64
 }
 
 // AllocStatsData is the per alloc stats snapshot.
@@ -26,6 +32,12 @@ type AllocStatsData struct {
 	Dead bool
 	// TaskIDs is the list of tasks running in this alloc.
 	TaskIDs map[string]int
+	// BytesLoaded is the number of bytes of task inputs loaded onto
+	// this alloc.
+	BytesLoaded int64
+	// BytesSaved is the number of bytes of task results saved by this
+	// alloc.
+	BytesSaved int64
 }
 
 // AllocStats is the per alloc stats used to update stats.
@@ -38,7 +50,7 @@ type AllocStats struct {
 func (a *AllocStats) AssignTask(task *Task) {
 	a.Mutex.Lock()
 	defer a.Mutex.Unlock()
-	a.Resources.Sub(a.Resources, task.Config.Resources)
+	a.Resources.Sub(a.Resources, task.config().Resources)
 	a.TaskIDs[GetTaskStatsId(task)] = 1
 }
 
@@ -46,10 +58,19 @@ func (a *AllocStats) AssignTask(task *Task) {
 func (a *AllocStats) RemoveTask(task *Task) {
 	a.Mutex.Lock()
 	defer a.Mutex.Unlock()
-	a.Resources.Add(a.Resources, task.Config.Resources)
+	a.Resources.Add(a.Resources, task.config().Resources)
 	delete(a.TaskIDs, GetTaskStatsId(task))
 }
 
+// AddTransfer accounts for bytes of task inputs loaded onto, and
+// task results saved by, the alloc.
+func (a *AllocStats) AddTransfer(loaded, saved int64) {
+	a.Mutex.Lock()
+	defer a.Mutex.Unlock()
+	a.BytesLoaded += loaded
+	a.BytesSaved += saved
+}
+
 // MarkDead marks an alloc dead.
 func (a *AllocStats) MarkDead() {
 	a.Mutex.Lock()
@@ -64,6 +85,8 @@ func (a *AllocStats) Copy() AllocStatsData {
 	defer a.Mutex.Unlock()
 	copy.Resources.Set(a.Resources)
 	copy.Dead = a.Dead
+	copy.BytesLoaded = a.BytesLoaded
+	copy.BytesSaved = a.BytesSaved
 	copy.TaskIDs = make(map[string]int, len(a.TaskIDs))
 	for k, v := range a.TaskIDs {
 		copy.TaskIDs[k] = v
@@ -85,6 +108,14 @@ type TaskStatsData struct {
 	RunID string
 	// FlowID is the flow corresponding to this task.
 	FlowID string
+	// BytesLoaded is the number of bytes of input filesets loaded onto
+	// allocs for this task, over all of its attempts.
+	BytesLoaded int64
+	// BytesSaved is the number of bytes of the task's results, over
+	// all of its attempts. For interns and externs, these are the bytes
+	// transferred into the repository and to the destination
+	// respectively.
+	BytesSaved int64
 }
 
 // TaskStats is the per task info and stats used to update stats.
@@ -105,6 +136,14 @@ func (t *TaskStats) Update(task *Task) {
 	}
 }
 
+// AddTransfer accounts for bytes loaded and saved by the task.
+func (t *TaskStats) AddTransfer(loaded, saved int64) {
+	t.Mutex.Lock()
+	defer t.Mutex.Unlock()
+	t.BytesLoaded += loaded
+	t.BytesSaved += saved
+}
+
 // Copy returns a immutable snapshot of TaskStats.
 func (t *TaskStats) Copy() TaskStatsData {
 	t.Mutex.Lock()
@@ -165,7 +204,7 @@ func (s *Stats) AddTasks(tasks []*Task) {
 	defer s.Mutex.Unlock()
 	s.TotalTasks += int64(len(tasks))
 	for _, t := range tasks {
-		s.Tasks[GetTaskStatsId(t)] = &TaskStats{TaskStatsData: TaskStatsData{Ident: t.Config.Ident, Type: t.Config.Type, RunID: t.RunID.ID(), FlowID: t.FlowID.String()}}
+		s.Tasks[GetTaskStatsId(t)] = &TaskStats{TaskStatsData: TaskStatsData{Ident: t.config().Ident, Type: t.config().Type, RunID: t.RunID.ID(), FlowID: t.FlowID.String()}}
 		t.stats = s.Tasks[GetTaskStatsId(t)]
 	}
 }
@@ -202,6 +241,37 @@ func (s *Stats) AddAlloc(alloc *alloc) {
 	s.Allocs[alloc.id] = &AllocStats{AllocStatsData: AllocStatsData{TaskIDs: make(map[string]int), Resources: resources}}
 }
 
+// AddTransfer accounts for bytes loaded and saved by the task on the
+// given alloc, which is nil for transfers performed directly by the
+// scheduler.
+func (s *Stats) AddTransfer(task *Task, alloc *alloc, loaded, saved int64) {
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+	if t := s.Tasks[GetTaskStatsId(task)]; t != nil {
+		t.AddTransfer(loaded, saved)
+	}
+	if alloc == nil {
+		return
+	}
+	if a := s.Allocs[alloc.id]; a != nil {
+		a.AddTransfer(loaded, saved)
+	}
+}
+
+// MarkStarved counts a starved task.
+func (s *Stats) MarkStarved() {
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+	s.StarvedTasks++
+}
+
+// MarkProtected counts a task whose alloc's instance was protected.
+func (s *Stats) MarkProtected() {
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+	s.ProtectedTasks++
+}
+
 // MarkAllocDead marks an alloc dead.
 func (s *Stats) MarkAllocDead(alloc *alloc) {
 	s.Allocs[alloc.id].MarkDead()
@@ -224,6 +294,33 @@ func (s *Stats) GetStats() StatsData {
 	return copy
 }
 
+// TransferStats summarizes the data moved by a set of tasks.
+type TransferStats struct {
+	// Tasks is the number of tasks.
+	Tasks int
+	// BytesLoaded is the number of bytes loaded by the tasks.
+	BytesLoaded int64
+	// BytesSaved is the number of bytes saved by the tasks.
+	BytesSaved int64
+}
+
+// RunTransfers returns the data moved by the tasks of the given run,
+// by task type (e.g., "exec", "intern", "extern").
+func (s StatsData) RunTransfers(runID string) map[string]TransferStats {
+	transfers := make(map[string]TransferStats)
+	for _, t := range s.Tasks {
+		if t.RunID != runID {
+			continue
+		}
+		ts := transfers[t.Type]
+		ts.Tasks++
+		ts.BytesLoaded += t.BytesLoaded
+		ts.BytesSaved += t.BytesSaved
+		transfers[t.Type] = ts
+	}
+	return transfers
+}
+
 func GetTaskStatsId(task *Task) string {
 	return task.FlowID.String()
 }
@@ -14,6 +14,7 @@ import (
 	"github.com/grailbio/base/digest"
 	"github.com/grailbio/base/sync/ctxsync"
 	"github.com/grailbio/reflow"
+	"github.com/grailbio/reflow/errors"
 	"github.com/grailbio/reflow/log"
 	"github.com/grailbio/reflow/taskdb"
 )
@@ -58,7 +59,9 @@ func (s TaskState) String() string {
 // submission, all coordination is performed through the task struct.
 type Task struct {
 	// Config is the task's exec config, which is passed on to the
-	// alloc after scheduling.
+	// alloc after scheduling. The tasks of a task array share their
+	// array's template (see NewTaskArray) instead: their Config is set
+	// only once the scheduler runs them.
 	Config reflow.ExecConfig
 	// Repository to use for this task.
 	// Repository is the repository from which dependent objects are
@@ -84,8 +87,15 @@ type Task struct {
 
 	// Priority is the task priority. Lower numbers indicate higher priority.
 	// Higher priority tasks will get scheduler before any lower priority tasks.
+	// The priority is also passed on to the executor (see reflow.ExecConfig.Priority),
+	// so that lower priority tasks yield to higher priority ones on the same host.
 	Priority int
 
+	// Timeout is the maximum duration for which the task's exec may run;
+	// zero means no limit. It is passed on to the executor (see
+	// reflow.ExecConfig.Timeout), which fails execs that exceed it.
+	Timeout time.Duration
+
 	// PostUseChecksum indicates whether input filesets are checksummed after use.
 	PostUseChecksum bool
 
@@ -93,10 +103,24 @@ type Task struct {
 	// by the scheduler for better scheduling.
 	ExpectedDuration time.Duration
 
+	// Critical indicates that the task is critical (e.g., long
+	// running): if the scheduler's Protect is set, the instance of the
+	// task's alloc is protected from termination while the task runs.
+	Critical bool
+
+	// Retry is the number of times the task's flow was previously
+	// retried by the evaluator (e.g., with increased memory after an OOM).
+	// It is recorded in TaskDB separately from the task's attempt number.
+	Retry int
+
 	// RunID that created this task.
 	RunID taskdb.RunID
 	// FlowID is the digest (flow.Digest) of the flow for which this task was created.
 	FlowID digest.Digest
+	// CacheKeys are the cache keys of the flow for which this task was
+	// created (see flow.Flow.CacheKeys). They are recorded in TaskDB
+	// so that the task's cached result can be invalidated later.
+	CacheKeys []digest.Digest
 
 	// TaskDB is where the task row for this task is recorded and is set by the scheduler only after the task was attempted.
 	TaskDB taskdb.TaskDB
@@ -113,9 +137,41 @@ type Task struct {
 	id taskdb.TaskID
 	// attempt stores the (zero-based) current attempt number for this task.
 	attempt int
+	// queued is the time at which the task was (last) added to the scheduler's queue.
+	queued time.Time
 
 	// nonDirectTransfer represents a task which cannot be executed as a direct transfer.
 	nonDirectTransfer bool
+
+	// outOfDisk is set when the task was lost because its alloc ran out of disk space.
+	outOfDisk bool
+	// diskRetries is the number of times the task was retried after running out of disk space.
+	diskRetries int
+	// retries is the number of times the task was retried under the
+	// scheduler's RetryPolicy, by the kind of error with which it failed.
+	retries map[errors.Kind]int
+	// retryDelay, retryResources and retryResult are the delay and
+	// resources with which the task is retried under the scheduler's
+	// RetryPolicy, and whether it is retried because of its result's error.
+	retryDelay     time.Duration
+	retryResources reflow.Resources
+	retryResult    bool
+	// costUSD is the estimated cost (in USD) of the task's attempts so far.
+	costUSD float64
+	// failedDomains are the failure domains in which attempts of the task failed.
+	failedDomains []FailureDomain
+	// aged is the number of levels by which the task's priority was
+	// raised while it was queued (see Scheduler.AgingInterval).
+	aged int
+	// starved is set once the task is reported as starved.
+	starved bool
+	// group is the task group to which the task belongs, if any.
+	group *TaskGroup
+	// arrayIndex is the index of the task in its task array, if any.
+	arrayIndex int
+	// materialized is set once the config of a task of a task array
+	// is materialized from the array's template (see materialize).
+	materialized bool
 }
 
 // NewTask returns a new, initialized task. The Task may be populated
@@ -165,6 +221,41 @@ func (t *Task) Wait(ctx context.Context, state TaskState) error {
 	return err
 }
 
+// config returns the task's exec config: for the tasks of task arrays
+// whose configs are not yet materialized, this is the array's template,
+// which lacks the task's index. The returned config must not be
+// modified.
+func (t *Task) config() *reflow.ExecConfig {
+	if g := t.group; g != nil && g.Template != nil && !t.materialized {
+		return g.Template
+	}
+	return &t.Config
+}
+
+// materialize sets the config of a task of a task array from its
+// array's template, with the task's index and its own copy of the
+// template's arguments, which the scheduler modifies as it loads them.
+// The scheduler materializes tasks as it runs them, so that queued
+// tasks do not each hold a copy of the template.
+func (t *Task) materialize() {
+	g := t.group
+	if g == nil || g.Template == nil || t.materialized {
+		return
+	}
+	t.Config = *g.Template
+	t.Config.Args = append([]reflow.Arg(nil), g.Template.Args...)
+	t.Config.ArrayIndex = t.arrayIndex
+	t.materialized = true
+}
+
+// execID returns the ID of the task's exec, which is the task's ID,
+// or the zero digest if the task was not initialized.
+func (t *Task) execID() digest.Digest {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return digest.Digest(t.id)
+}
+
 // Init initializes the task's ID.
 func (t *Task) Init() {
 	t.mu.Lock()
@@ -245,10 +336,10 @@ type taskq []*Task
 func (q taskq) Len() int { return len(q) }
 
 func (q taskq) Less(i, j int) bool {
-	if q[i].Priority != q[j].Priority {
-		return q[i].Priority < q[j].Priority
+	if pi, pj := q[i].priority(), q[j].priority(); pi != pj {
+		return pi < pj
 	}
-	return q[i].Config.Resources.ScaledDistance(nil) < q[j].Config.Resources.ScaledDistance(nil)
+	return q[i].config().Resources.ScaledDistance(nil) < q[j].config().Resources.ScaledDistance(nil)
 }
 
 func (q taskq) Swap(i, j int) {
@@ -0,0 +1,294 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package sched
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grailbio/reflow"
+	"github.com/grailbio/reflow/errors"
+	"github.com/grailbio/reflow/log"
+	"github.com/grailbio/reflow/metrics"
+	"github.com/grailbio/reflow/taskdb"
+)
+
+// A TaskGroup is a set of tasks which are submitted, and may be
+// scheduled, together. The tasks of a gang-scheduled group are
+// assigned to allocs all at once, or not at all, so that they start
+// simultaneously, as required by, e.g., MPI-style tools. Gang
+// scheduling applies to the group's tasks which are queued together:
+// a task which is retried (e.g., because its alloc was lost) is
+// scheduled by itself.
+type TaskGroup struct {
+	// Gang determines whether the group's tasks are gang scheduled.
+	Gang bool
+	// Tasks are the group's tasks.
+	Tasks []*Task
+	// Template is the exec config shared by the tasks of a task array
+	// (see NewTaskArray); it is nil for other groups.
+	Template *reflow.ExecConfig
+
+	// id identifies the task array in TaskDB.
+	id         taskdb.TaskID
+	createOnce sync.Once
+	// created is set if the task array was recorded in TaskDB.
+	created bool
+}
+
+// NewTaskGroup returns a new task group of the given tasks, which are
+// gang scheduled if gang is set. A task may belong to only one group.
+func NewTaskGroup(gang bool, tasks ...*Task) *TaskGroup {
+	g := &TaskGroup{Gang: gang, Tasks: tasks}
+	for _, task := range tasks {
+		if task.group != nil {
+			panic("sched: task already belongs to a group")
+		}
+		task.group = g
+	}
+	return g
+}
+
+// NewTaskArray returns a new task group of n tasks which run the
+// config of the given template task, each with its index in the array
+// (see reflow.ExecConfig.ArrayIndex), so that the tasks of an
+// embarrassingly parallel computation are described once: the tasks
+// share the array's template config until they are run, and are
+// recorded in TaskDB as members of a single array entry. The tasks'
+// other fields (e.g., their repository) are those of the template,
+// which is not itself submitted.
+func NewTaskArray(template *Task, n int, gang bool) *TaskGroup {
+	config := template.Config
+	config.ArrayIndex, config.ArraySize = 0, n
+	g := &TaskGroup{Gang: gang, Template: &config, id: taskdb.NewTaskID()}
+	g.Tasks = make([]*Task, n)
+	for i := range g.Tasks {
+		task := NewTask()
+		task.Repository = template.Repository
+		task.Log = template.Log
+		task.Priority = template.Priority
+		task.Timeout = template.Timeout
+		task.PostUseChecksum = template.PostUseChecksum
+		task.ExpectedDuration = template.ExpectedDuration
+		task.Critical = template.Critical
+		task.Retry = template.Retry
+		task.RunID = template.RunID
+		task.FlowID = template.FlowID
+		task.CacheKeys = template.CacheKeys
+		task.group = g
+		task.arrayIndex = i
+		g.Tasks[i] = task
+	}
+	return g
+}
+
+// Index returns the index of the given task in its task array.
+func (t *Task) Index() int {
+	return t.arrayIndex
+}
+
+// Wait returns when all of the group's tasks are in at least the
+// given state, or when the context is done.
+func (g *TaskGroup) Wait(ctx context.Context, state TaskState) error {
+	for _, task := range g.Tasks {
+		if err := task.Wait(ctx, state); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Err returns an error describing the group's failed tasks, if any:
+// those which are done with either a scheduling error or an exec
+// error. Err should only be called after all tasks are done.
+func (g *TaskGroup) Err() error {
+	var (
+		first   error
+		indices []string
+	)
+	for i, task := range g.Tasks {
+		if task.State() != TaskDone {
+			continue
+		}
+		err := task.Err
+		if err == nil && task.Result.Err != nil {
+			err = task.Result.Err
+		}
+		if err == nil {
+			continue
+		}
+		if first == nil {
+			first = err
+		}
+		indices = append(indices, fmt.Sprint(i))
+	}
+	if first == nil {
+		return nil
+	}
+	return errors.E(fmt.Sprintf("task group (flow %s): tasks [%s] failed", g.Tasks[0].FlowID.Short(), strings.Join(indices, ",")), first)
+}
+
+// TaskGroupStatus summarizes the states of a task group's tasks.
+type TaskGroupStatus struct {
+	// N is the number of tasks in the group.
+	N int
+	// Counts is the number of tasks in each state.
+	Counts map[TaskState]int
+	// Failed is the number of done tasks which failed.
+	Failed int
+}
+
+// Done tells whether all of the group's tasks are done.
+func (s TaskGroupStatus) Done() bool {
+	return s.Counts[TaskDone] == s.N
+}
+
+// String returns a summary of the status, e.g.,
+// "10 tasks: 2 running, 8 done (1 failed)".
+func (s TaskGroupStatus) String() string {
+	var states []TaskState
+	for state, n := range s.Counts {
+		if n > 0 {
+			states = append(states, state)
+		}
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i] < states[j] })
+	parts := make([]string, len(states))
+	for i, state := range states {
+		parts[i] = fmt.Sprintf("%d %s", s.Counts[state], state)
+		if state == TaskDone && s.Failed > 0 {
+			parts[i] += fmt.Sprintf(" (%d failed)", s.Failed)
+		}
+	}
+	return fmt.Sprintf("%d tasks: %s", s.N, strings.Join(parts, ", "))
+}
+
+// Status returns the aggregate status of the group's tasks.
+func (g *TaskGroup) Status() TaskGroupStatus {
+	s := TaskGroupStatus{N: len(g.Tasks), Counts: make(map[TaskState]int)}
+	for _, task := range g.Tasks {
+		state := task.State()
+		s.Counts[state]++
+		if state == TaskDone && (task.Err != nil || task.Result.Err != nil) {
+			s.Failed++
+		}
+	}
+	return s
+}
+
+// SubmitGroup submits the tasks of the given group to the scheduler
+// (see Submit).
+func (s *Scheduler) SubmitGroup(g *TaskGroup) {
+	if g.Template == nil {
+		s.Submit(g.Tasks...)
+		return
+	}
+	if len(g.Tasks) > 0 && g.Tasks[0].Repository == nil {
+		panic(fmt.Sprintf("scheduler SubmitGroup array (flow %s) with no repository", g.Tasks[0].FlowID.Short()))
+	}
+	s.Log.Debugf("array of %d tasks submitted with %v", len(g.Tasks), g.Template)
+	s.submitc <- append([]*Task{}, g.Tasks...)
+}
+
+// createArray records the task array of the given group in the
+// scheduler's TaskDB, once, if the TaskDB supports arrays. It tells
+// whether the array was recorded, in which case its tasks are
+// recorded as its members.
+func (s *Scheduler) createArray(ctx context.Context, g *TaskGroup, taskLogger *log.Logger) bool {
+	creator, ok := s.TaskDB.(taskdb.ArrayCreator)
+	if !ok || g.Template == nil || len(g.Tasks) == 0 {
+		return false
+	}
+	g.createOnce.Do(func() {
+		first := g.Tasks[0]
+		err := creator.CreateArray(ctx, taskdb.Array{
+			TimeFields: taskdb.TimeFields{Start: time.Now()},
+			ID:         g.id,
+			RunID:      first.RunID,
+			FlowID:     first.FlowID,
+			CacheKeys:  first.CacheKeys,
+			ImgCmdID:   taskdb.NewImgCmdID(g.Template.Image, g.Template.Cmd),
+			Ident:      g.Template.Ident,
+			Resources:  g.Template.Resources,
+			Size:       len(g.Tasks),
+		})
+		if err != nil {
+			metrics.GetTaskdbErrorsCountCounter(ctx, "createarray").Inc()
+			taskLogger.Errorf("taskdb createarray: %v", errors.E("createarray", g.id.ID(), err))
+			return
+		}
+		g.created = true
+	})
+	return g.created
+}
+
+// gangSizes returns the number of queued tasks of each gang-scheduled
+// group among tasks.
+func gangSizes(tasks []*Task) map[*TaskGroup]int {
+	var sizes map[*TaskGroup]int
+	for _, task := range tasks {
+		if task.group == nil || !task.group.Gang {
+			continue
+		}
+		if sizes == nil {
+			sizes = make(map[*TaskGroup]int)
+		}
+		sizes[task.group]++
+	}
+	return sizes
+}
+
+// assignGangs assigns tasks to allocs as assignTasks does, but assigns
+// the queued tasks of each gang-scheduled group only if all of them
+// can be assigned. The tasks of groups which cannot be assigned in
+// full are unassigned and returned to the queue, and the remaining
+// tasks are assigned to the resources they would have used.
+func (s *Scheduler) assignGangs(tasks *taskq, allocs *allocq) (assigned []*Task) {
+	sizes := gangSizes(*tasks)
+	if len(sizes) == 0 {
+		return s.assignTasks(tasks, allocs)
+	}
+	var held []*Task
+	for {
+		var (
+			round  = s.assignTasks(tasks, allocs)
+			counts = make(map[*TaskGroup]int)
+		)
+		for _, task := range round {
+			if task.group != nil && task.group.Gang {
+				counts[task.group]++
+			}
+		}
+		var incomplete bool
+		for _, task := range round {
+			g := task.group
+			if g == nil || !g.Gang || counts[g] == sizes[g] {
+				assigned = append(assigned, task)
+				continue
+			}
+			incomplete = true
+			alloc := task.alloc
+			alloc.Unassign(task)
+			if alloc.index != -1 {
+				heap.Fix(allocs, alloc.index)
+			}
+			held = append(held, task)
+		}
+		// Each round which does not assign a group in full holds back
+		// some of its tasks, so this terminates.
+		if !incomplete {
+			break
+		}
+	}
+	for _, task := range held {
+		heap.Push(tasks, task)
+	}
+	return assigned
+}
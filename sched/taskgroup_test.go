@@ -0,0 +1,121 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package sched
+
+import (
+	"container/heap"
+	"testing"
+
+	"github.com/grailbio/reflow"
+)
+
+func TestNewTaskArray(t *testing.T) {
+	template := NewTask()
+	template.Config = reflow.ExecConfig{
+		Type:      "exec",
+		Image:     "mpi",
+		Cmd:       "shard %s",
+		Args:      []reflow.Arg{{Fileset: &reflow.Fileset{}}},
+		Resources: reflow.Resources{"cpu": 1},
+	}
+	template.Priority = 2
+	g := NewTaskArray(template, 3, true)
+	if got, want := len(g.Tasks), 3; got != want {
+		t.Fatalf("got %v tasks, want %v", got, want)
+	}
+	for i, task := range g.Tasks {
+		if task.group != g {
+			t.Errorf("task %d: not in group", i)
+		}
+		if got, want := task.Index(), i; got != want {
+			t.Errorf("task %d: got index %v, want %v", i, got, want)
+		}
+		if got, want := task.Priority, 2; got != want {
+			t.Errorf("task %d: got priority %v, want %v", i, got, want)
+		}
+		// Queued tasks share the array's template.
+		if task.config() != g.Template {
+			t.Errorf("task %d: does not share the template", i)
+		}
+		if task.Config.Cmd != "" {
+			t.Errorf("task %d: unexpected config %v", i, task.Config)
+		}
+		task.materialize()
+		if got, want := task.Config.ArrayIndex, i; got != want {
+			t.Errorf("task %d: got index %v, want %v", i, got, want)
+		}
+		if got, want := task.Config.ArraySize, 3; got != want {
+			t.Errorf("task %d: got size %v, want %v", i, got, want)
+		}
+		if got, want := task.Config.Cmd, template.Config.Cmd; got != want {
+			t.Errorf("task %d: got %v, want %v", i, got, want)
+		}
+		if task.config() != &task.Config {
+			t.Errorf("task %d: config not materialized", i)
+		}
+		// Materialized tasks do not share the template's arguments.
+		task.Config.Args[0].Fileset = nil
+		if g.Template.Args[0].Fileset == nil {
+			t.Fatalf("task %d: modified the template", i)
+		}
+	}
+}
+
+func TestAssignGang(t *testing.T) {
+	s := New()
+	resources := reflow.Resources{"cpu": 2, "mem": 1 << 30}
+	newAlloc := func(cpu float64) *alloc {
+		return &alloc{Available: reflow.Resources{"cpu": cpu, "mem": 8 << 30}}
+	}
+	template := NewTask()
+	template.Config = reflow.ExecConfig{Type: "exec", Resources: resources}
+	gang := NewTaskArray(template, 3, true)
+	single := NewTask()
+	single.Config.Resources = reflow.Resources{"cpu": 1, "mem": 1 << 30}
+	var tasks taskq
+	for _, task := range append(gang.Tasks, single) {
+		heap.Push(&tasks, task)
+	}
+
+	// The gang does not fit: none of its tasks are assigned, and the
+	// resources they would have used go to other tasks.
+	small := newAlloc(4)
+	var allocs allocq
+	heap.Push(&allocs, small)
+	assigned := s.assign(&tasks, &allocs, nil)
+	if got, want := len(assigned), 1; got != want {
+		t.Fatalf("got %v assigned tasks, want %v", got, want)
+	}
+	if assigned[0] != single {
+		t.Error("expected the single task to be assigned")
+	}
+	if got, want := len(tasks), 3; got != want {
+		t.Errorf("got %v queued tasks, want %v", got, want)
+	}
+	if got, want := small.Available["cpu"], 3.0; got != want {
+		t.Errorf("got %v available cpus, want %v", got, want)
+	}
+
+	// The gang is assigned all at once when it fits, across allocs.
+	heap.Push(&allocs, newAlloc(4))
+	if got, want := len(s.assign(&tasks, &allocs, nil)), 3; got != want {
+		t.Errorf("got %v assigned tasks, want %v", got, want)
+	}
+	if got := len(tasks); got != 0 {
+		t.Errorf("got %v queued tasks, want none", got)
+	}
+
+	// Groups which are not gang scheduled are assigned as usual.
+	group := NewTaskArray(template, 3, false)
+	tasks = taskq{}
+	for _, task := range group.Tasks {
+		heap.Push(&tasks, task)
+	}
+	allocs = allocq{}
+	heap.Push(&allocs, newAlloc(4))
+	if got, want := len(s.assign(&tasks, &allocs, nil)), 2; got != want {
+		t.Errorf("got %v assigned tasks, want %v", got, want)
+	}
+}
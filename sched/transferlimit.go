@@ -0,0 +1,60 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package sched
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/grailbio/base/limiter"
+)
+
+// transferDest returns the destination, for the purpose of transfer
+// limits, of the provided intern or extern URL: its scheme and host
+// (e.g., "s3://bucket"). If the URL cannot be parsed, it is used as
+// is.
+func transferDest(rawurl string) string {
+	u, err := url.Parse(rawurl)
+	if err != nil || u.Host == "" {
+		return rawurl
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+// transferLimiter returns the limiter for the destination of the
+// provided intern or extern task, or nil if the scheduler imposes no
+// transfer limits. Limiters are created lazily, one per destination.
+func (s *Scheduler) transferLimiter(task *Task) *limiter.Limiter {
+	if s.TransferLimits == nil {
+		return nil
+	}
+	dest := transferDest(task.config().URL)
+	s.transferMu.Lock()
+	defer s.transferMu.Unlock()
+	if s.transferLimiters == nil {
+		s.transferLimiters = make(map[string]*limiter.Limiter)
+	}
+	lim := s.transferLimiters[dest]
+	if lim == nil {
+		lim = limiter.New()
+		lim.Release(s.TransferLimits.Limit(dest))
+		s.transferLimiters[dest] = lim
+	}
+	return lim
+}
+
+// acquireTransfer acquires a transfer slot for the destination of the
+// provided intern or extern task, blocking until one is available. The
+// returned function releases the slot.
+func (s *Scheduler) acquireTransfer(ctx context.Context, task *Task) (release func(), err error) {
+	lim := s.transferLimiter(task)
+	if lim == nil {
+		return func() {}, nil
+	}
+	if err := lim.Acquire(ctx, 1); err != nil {
+		return nil, err
+	}
+	return func() { lim.Release(1) }, nil
+}
@@ -0,0 +1,60 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package sched
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grailbio/reflow"
+	"github.com/grailbio/reflow/repository"
+)
+
+func TestTransferDest(t *testing.T) {
+	for _, tc := range []struct{ url, want string }{
+		{"s3://bucket/prefix/file", "s3://bucket"},
+		{"s3://bucket", "s3://bucket"},
+		{"https://host:8080/path", "https://host:8080"},
+		{"localfile", "localfile"},
+	} {
+		if got := transferDest(tc.url); got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.url, got, tc.want)
+		}
+	}
+}
+
+func TestTransferLimits(t *testing.T) {
+	s := New()
+	s.TransferLimits = repository.NewLimits(2)
+	s.TransferLimits.Set("s3://hot", 1)
+	newTask := func(url string) *Task {
+		return &Task{Config: reflow.ExecConfig{Type: "extern", URL: url}}
+	}
+	ctx := context.Background()
+	// Tasks externing to the same bucket share a limiter.
+	if s.transferLimiter(newTask("s3://hot/a")) != s.transferLimiter(newTask("s3://hot/b")) {
+		t.Error("expected shared limiter")
+	}
+	release, err := s.acquireTransfer(ctx, newTask("s3://hot/a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Other destinations are not affected.
+	for i := 0; i < 2; i++ {
+		if _, err := s.acquireTransfer(ctx, newTask("s3://cold/a")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := s.acquireTransfer(tctx, newTask("s3://hot/b")); err == nil {
+		t.Error("expected limit to be enforced")
+	}
+	release()
+	if _, err := s.acquireTransfer(ctx, newTask("s3://hot/b")); err != nil {
+		t.Error(err)
+	}
+}
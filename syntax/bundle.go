@@ -31,6 +31,10 @@ type bundleManifest struct {
 	// of the the file's contents. The files are stored by hash directly
 	// in the zip file.
 	Files map[string]digest.Digest
+	// Versions stores the versions (e.g., git commits) of the remote
+	// modules embedded in this bundle, where known, so that the exact
+	// sources of a bundle may be traced back to their origin.
+	Versions map[string]string `json:",omitempty"`
 }
 
 // Bundle represents a self-contained Reflow module. A bundle
@@ -68,6 +72,26 @@ func (b *Bundle) Entrypoint() (source []byte, args []string, path string, err er
 	return p, b.manifest.Args, b.manifest.EntrypointPath, nil
 }
 
+// Remote returns the (sorted) paths of the remote modules embedded
+// in this bundle.
+func (b *Bundle) Remote() []string {
+	var paths []string
+	for path := range b.manifest.Files {
+		if isRemote(path) {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Version returns the version (e.g., "commit 1a2b3c..." for a module
+// imported from a git repository) of the remote module with the given
+// path embedded in this bundle, or "" if it is unknown.
+func (b *Bundle) Version(path string) string {
+	return b.manifest.Versions[path]
+}
+
 // WriteTo writes an archive (ZIP formatted) of this bundle to the provided
 // io.Writer. Archives written by Write can be opened by OpenBundleModule.
 func (b *Bundle) WriteTo(w io.Writer) error { // "go vet" complaint expected
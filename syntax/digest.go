@@ -11,6 +11,7 @@ import (
 
 	"github.com/grailbio/base/digest"
 	"github.com/grailbio/reflow"
+	"github.com/grailbio/reflow/types"
 	"github.com/grailbio/reflow/values"
 )
 
@@ -119,6 +120,10 @@ func (e *Expr) digest(w io.Writer, env *values.Env) {
 			io.WriteString(w, k)
 			fm[k].Expr.digest(w, env)
 		}
+		if e.Left != nil {
+			io.WriteString(w, "|")
+			e.Left.digest(w, env)
+		}
 	case ExprList:
 		writeN(w, len(e.List))
 		for _, ee := range e.List {
@@ -145,10 +150,32 @@ func (e *Expr) digest(w io.Writer, env *values.Env) {
 				break
 			}
 		}
+		for _, d := range e.Decls {
+			if d.Pat.Ident == "stdin" {
+				// Unlike other parameters, standard input affects
+				// the exec's result.
+				io.WriteString(w, "stdin")
+				d.Expr.digest(w, env)
+				break
+			}
+		}
+		for _, d := range e.Decls {
+			if d.Pat.Ident == "env" {
+				// So do environment variables (but not secrets).
+				io.WriteString(w, "env")
+				d.Expr.digest(w, env)
+				break
+			}
+		}
 		// TODO(marius): normalize this to strip out identifier names;
 		// instead rely on indices.
 		io.WriteString(w, e.Template.FormatString())
-		fm := e.Type.Tupled().FieldMap()
+		fm := e.execOutputs().FieldMap()
+		if e.Type.Kind == types.StructKind {
+			// Execs which return structs are distinguished from those
+			// which return tuples of the same outputs.
+			io.WriteString(w, "struct")
+		}
 		for i, ae := range e.Template.Args {
 			if ae.Kind == ExprIdent && fm[ae.Ident] != nil {
 				// We use position here so that we can change output
@@ -156,6 +183,10 @@ func (e *Expr) digest(w io.Writer, env *values.Env) {
 				writeN(w, i)
 				continue
 			}
+			if e.Template.Resources[i] != "" {
+				io.WriteString(w, ae.Ident)
+				continue
+			}
 			ae.digest(w, env)
 		}
 	case ExprCond:
@@ -209,8 +240,14 @@ func (e *Expr) digest(w io.Writer, env *values.Env) {
 		switch e.Op {
 		default:
 			panic("bad builtin " + e.Op)
-		case "len", "unzip", "panic", "map", "list", "flatten", "delay", "trace", "error":
+		case "len", "unzip", "map", "list", "flatten", "delay", "trace", "error", "inline":
 			e.Fields[0].Expr.digest(w, env)
+		case "panic", "assert":
+			// The structured data argument to panic is optional; digesting only
+			// the arguments present retains backwards compatibility.
+			for _, f := range e.Fields {
+				f.Expr.digest(w, env)
+			}
 		case "zip", "range":
 			// To retain digest backwards compatibility with a previous AST representation for builtins,
 			// we digest the second argument before the first.
@@ -223,6 +260,9 @@ func (e *Expr) digest(w io.Writer, env *values.Env) {
 			e.Fields[0].digest(w, env)
 			e.Fields[1].digest(w, env)
 			e.Fields[2].digest(w, env)
+		case "scratch":
+			// Scratch directories are private to the exec; their
+			// names do not affect the exec's result.
 		}
 	case ExprRequires:
 		e.Left.digest(w, e.Env)
@@ -46,14 +46,40 @@ expressions, d1, d2, .. are declarations; t1, t2, .. are types):
 	(e1, e2, e3, ..)                   // a tuple of e1, e2, e3, ..
 	{id1: e1, id2: e2, ..}             // a struct with fields id1 with value e1, id2 with value e2, ..
 	{id1, id2, ..}                     // a shorthand for {id1: id1, id2: id2}
+	{e1 | id1: e2, id2: e3, ..}        // struct e1 with fields id1, id2, .. replaced by e2, e3, ..
 	{d1; d2; ...; dn; e1}              // a block of declarations usable by expression e1
 	func(id1, id2 t1, id3 t3) t4 => e1 // a function literal with arguments and return type; evaluates e1
 	func(id1, id2 t1, id3 t3) => e1    // a function literal with arguments, return type omitted
 	exec(d1, d2, ..) t1 {{ template }} // an exec with declarations d1, d2, .., returning t1 with template
+	                                   // t1 is a labelled file or dir, or a tuple or struct of them;
+	                                   // template interpolations ({{e1}}) may be arbitrary expressions;
 	                                   // identifiers are valid declarations in this context; they are
 	                                   // deparsed as id := id.
 	                                   // takes an optional declaration nondeterministic bool, which tags
 	                                   // this exec as being non-deterministic.
+	                                   // takes an optional declaration ondemand bool, which requires
+	                                   // this exec to run on on-demand (non-preemptible) capacity.
+	                                   // takes an optional declaration timeout, a string (e.g., "6h") or
+	                                   // an integer number of seconds (e.g., 2*hour), a duration after
+	                                   // which the exec is killed and fails.
+	                                   // takes an optional declaration retries int, the number of times
+	                                   // the exec is retried if it fails, whatever its error.
+	                                   // takes an optional declaration stdin string, which is provided
+	                                   // as the command's standard input.
+	                                   // takes optional declarations env and secrets, maps of strings
+	                                   // to strings, which define environment variables of the command;
+	                                   // secrets map variables to secret references (e.g., "ssm:name"),
+	                                   // whose values are fetched when the exec starts.
+	                                   // takes an optional declaration critical bool, which protects
+	                                   // the instance running this exec from termination while it runs.
+	                                   // takes an optional declaration cache string, one of "off",
+	                                   // "read" (read-only) or "write" (write-only), which restricts
+	                                   // the use of the cache for this exec.
+	                                   // takes an optional declaration array int, which runs this exec
+	                                   // as that many tasks, each told its index; the exec may only
+	                                   // return dirs, which merge the tasks' outputs under "<index>/".
+	                                   // takes an optional declaration gang bool, which requires
+	                                   // array, and gang schedules the array's tasks.
 	e1 <op> e2                         // a binary op (||, &&, <, >, <=, >=, !=, ==, +, /, %, &, <<, >>)
 	<op> e1                            // unary expression (!)
 	if e1 { d1; d2; ..; e2 }
@@ -69,6 +95,11 @@ expressions, d1, d2, .. are declarations; t1, t2, .. are types):
 	make(strlit, d1, ..., dn)          // builtin make primitive. identifiers are valid declarations in
 	                                   // this context; they are deparsed as id := id.
 	panic(e1)                          // terminate the program with error e1
+	panic(e1, e2)                      // terminate the program with error e1, annotated with the
+	                                   // key/value context in the map e2 (of type map[string:t])
+	assert(e1, e2)                     // terminate the program with error e2 if the boolean e1 is false
+	assert(e1, e2, e3)                 // as above, annotating the error with the key/value context in
+	                                   // the map e3 (of type map[string:t])
 	[e1 | c1, c2,..., cn]              // list comprehension: evaluate e1 in the environment provided by
 	                                   // the given clauses (see below)
 	e1 ~> e2                           // force evaluation of e1, ignore its result, then evaluate e2.
@@ -76,6 +107,12 @@ expressions, d1, d2, .. are declarations; t1, t2, .. are types):
 	trace(e1)                          // trace expression e1: evaluate it, print it to console,
 	                                   // and return it. Can be used for debugging.
 	range(e1, e2)                      // produce a list of integers with the range of the two expressions.
+	scratch(strlit)                    // in exec templates only: a temporary directory private to the exec,
+	                                   // which is neither returned nor uploaded.
+	inline(e1)                         // in exec templates only: the path of a file with contents e1
+	                                   // (a string), materialized in the exec's sandbox.
+	cpu, mem, disk                     // in exec templates only, when not otherwise bound: the exec's
+	                                   // reserved CPUs, memory (bytes), and disk (bytes).
 
 A comprehension clause is one of the following:
 
@@ -108,6 +145,10 @@ annotations, each of which takes a list of declarations.
 	                                   // parallelization factor is not
 	                                   // known statically, for example
 	                                   // when processing sharded data.
+	                                   // If ondemand bool is set to
+	                                   // true, the declaration must be
+	                                   // computed on on-demand
+	                                   // (non-preemptible) capacity.
 
 Value declarations can take destructive pattern bindings, mimicking
 value constructors. Currently tuples and lists are supported.
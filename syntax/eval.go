@@ -8,11 +8,14 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"math"
 	"math/big"
 	"net/url"
 	"os"
 	"runtime/debug"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/grailbio/base/digest"
 	"github.com/grailbio/base/log"
@@ -24,13 +27,14 @@ import (
 )
 
 var (
-	coerceExecOutputDigest = reflow.Digester.FromString("grail.com/reflow/syntax.Eval.coerceExecOutput")
-	sequenceDigest         = reflow.Digester.FromString("grail.com/reflow/syntax.Eval.~>")
-	notDigest              = reflow.Digester.FromString("grail.com/reflow/syntax.Eval.not")
-	errMatch               = errors.New("match error")
-	compareDigest          = reflow.Digester.FromString("grail.com/reflow/syntax.evalEq")
-	one                    = big.NewInt(1)
-	errParam               = errors.New("flag parameters may not depend on other flag parameters")
+	coerceExecOutputDigest       = reflow.Digester.FromString("grail.com/reflow/syntax.Eval.coerceExecOutput")
+	coerceExecStructOutputDigest = reflow.Digester.FromString("grail.com/reflow/syntax.Eval.coerceExecStructOutput")
+	sequenceDigest               = reflow.Digester.FromString("grail.com/reflow/syntax.Eval.~>")
+	notDigest                    = reflow.Digester.FromString("grail.com/reflow/syntax.Eval.not")
+	errMatch                     = errors.New("match error")
+	compareDigest                = reflow.Digester.FromString("grail.com/reflow/syntax.evalEq")
+	one                          = big.NewInt(1)
+	errParam                     = errors.New("flag parameters may not depend on other flag parameters")
 )
 
 // Eval evaluates the expression e and returns its value (or error).
@@ -321,7 +325,21 @@ func (e *Expr) eval(sess *Session, env *values.Env, ident string) (val values.T,
 				return nil, err
 			}
 		}
-		return v, nil
+		if e.Left == nil {
+			return v, nil
+		}
+		// Struct updates force the updated struct, and then
+		// replace the updated fields in a copy of it.
+		return e.k(sess, env, ident, func(vs []values.T) (values.T, error) {
+			w := make(values.Struct)
+			for k, fv := range vs[0].(values.Struct) {
+				w[k] = fv
+			}
+			for k, fv := range v {
+				w[k] = fv
+			}
+			return w, nil
+		}, e.Left)
 	case ExprList:
 		v := make(values.List, len(e.List))
 		for i, el := range e.List {
@@ -389,13 +407,9 @@ func (e *Expr) eval(sess *Session, env *values.Env, ident string) (val values.T,
 			}
 			tvals[i] = tval{d.Type, v}
 		}
-		// TODO(marius): abstract into a utility (IsOutput(...))
-		outputs := make(map[string]*types.T)
-		for _, f := range e.Type.Tupled().Fields {
-			outputs[f.Name] = f.T
-		}
+		outputs := e.execOutputs().FieldMap()
 		for i, arg := range e.Template.Args {
-			if arg.Kind == ExprIdent && outputs[arg.Ident] != nil {
+			if arg.Kind == ExprIdent && outputs[arg.Ident] != nil || e.Template.Resources[i] != "" {
 				continue
 			}
 			argIndex[len(tvals)] = i
@@ -427,7 +441,33 @@ func (e *Expr) eval(sess *Session, env *values.Env, ident string) (val values.T,
 			for i := len(e.Decls); i < len(vs); i++ {
 				args[argIndex[i]] = vs[i]
 			}
-			return e.exec(sess, env, image, ident, args, makeResources(penv))
+			timeout, err := execTimeout(penv)
+			if err != nil {
+				return nil, errors.E(fmt.Sprintf("%s:", e.Position), err)
+			}
+			stdin, err := execStdin(penv)
+			if err != nil {
+				return nil, errors.E(fmt.Sprintf("%s:", e.Position), err)
+			}
+			retries, err := execRetries(penv)
+			if err != nil {
+				return nil, errors.E(fmt.Sprintf("%s:", e.Position), err)
+			}
+			critical, _ := penv.Value("critical").(bool)
+			noCacheRead, noCacheWrite, err := execCache(penv)
+			if err != nil {
+				return nil, errors.E(fmt.Sprintf("%s:", e.Position), err)
+			}
+			envVars, secrets, err := execEnv(penv)
+			if err != nil {
+				return nil, errors.E(fmt.Sprintf("%s:", e.Position), err)
+			}
+			array, err := execArray(penv)
+			if err != nil {
+				return nil, errors.E(fmt.Sprintf("%s:", e.Position), err)
+			}
+			gang, _ := penv.Value("gang").(bool)
+			return e.exec(sess, env, image, ident, args, makeResources(penv), timeout, retries, stdin, envVars, secrets, critical, noCacheRead, noCacheWrite, array, gang)
 		}, tvals...)
 		kf := k.(*flow.Flow)
 
@@ -694,9 +734,32 @@ func (e *Expr) eval(sess *Session, env *values.Env, ident string) (val values.T,
 				return list, nil
 			}, e.Fields[0].Expr)
 		case "panic":
+			args := make([]interface{}, len(e.Fields))
+			for i := range e.Fields {
+				args[i] = e.Fields[i].Expr
+			}
 			return e.k(sess, env, ident, func(vs []values.T) (values.T, error) {
-				return nil, fmt.Errorf("%v: panic: %s", e.Position, vs[0].(string))
-			}, e.Fields[0].Expr)
+				err := fmt.Errorf("%v: panic: %s", e.Position, vs[0].(string))
+				if len(vs) > 1 {
+					err = dataError("panic", vs[1], e.Fields[1].Expr.Type, err)
+				}
+				return nil, err
+			}, args...)
+		case "assert":
+			args := make([]interface{}, len(e.Fields))
+			for i := range e.Fields {
+				args[i] = e.Fields[i].Expr
+			}
+			return e.k(sess, env, ident, func(vs []values.T) (values.T, error) {
+				if vs[0].(bool) {
+					return values.Unit, nil
+				}
+				err := fmt.Errorf("%v: assertion failed: %s", e.Position, vs[1].(string))
+				if len(vs) > 2 {
+					err = dataError("assert", vs[2], e.Fields[2].Expr.Type, err)
+				}
+				return nil, err
+			}, args...)
 		case "error":
 			return e.k(sess, env, ident, func(vs []values.T) (values.T, error) {
 				return nil, errors.E("module error", errors.Module, fmt.Sprintf("code %d", vs[0].(*big.Int)), fmt.Errorf("%s: %s", e.Position, vs[1].(string)))
@@ -709,6 +772,12 @@ func (e *Expr) eval(sess *Session, env *values.Env, ident string) (val values.T,
 			return e.k(sess, env, ident, func(vs []values.T) (values.T, error) {
 				return vs[0], nil
 			}, e.Fields[0].Expr)
+		case "scratch", "inline":
+			// Scratch and inline expressions evaluate to their argument
+			// (a name, or data); they are rendered by the enclosing exec.
+			return e.k(sess, env, ident, func(vs []values.T) (values.T, error) {
+				return vs[0], nil
+			}, e.Fields[0].Expr)
 		case "trace":
 			left, err := e.Fields[0].Expr.eval(sess, env, ident)
 			if err != nil {
@@ -764,17 +833,14 @@ func (e *Expr) eval(sess *Session, env *values.Env, ident string) (val values.T,
 
 // Exec returns a Flow value for an exec expression. The resolved
 // image and resources are passed by the caller.
-func (e *Expr) exec(sess *Session, env *values.Env, image string, ident string, args map[int]values.T, resources reflow.Resources) (values.T, error) {
+func (e *Expr) exec(sess *Session, env *values.Env, image string, ident string, args map[int]values.T, resources reflow.Resources, timeout time.Duration, retries int, stdin string, envVars, secrets map[string]string, critical, noCacheRead, noCacheWrite bool, array int, gang bool) (values.T, error) {
 	// Execs are special. The interpolation environment also has the
 	// output ids.
 	narg := len(e.Template.Args)
-	outputs := make(map[string]*types.T)
-	for _, f := range e.Type.Tupled().Fields {
-		outputs[f.Name] = f.T
-	}
+	outputs := e.execOutputs().FieldMap()
 	varg := make([]values.T, narg)
 	for i, ae := range e.Template.Args {
-		if ae.Kind == ExprIdent && outputs[ae.Ident] != nil {
+		if ae.Kind == ExprIdent && outputs[ae.Ident] != nil || e.Template.Resources[i] != "" {
 			continue
 		}
 		var ok bool
@@ -798,6 +864,7 @@ func (e *Expr) exec(sess *Session, env *values.Env, image string, ident string,
 		deps    []*flow.Flow
 		earg    []flow.ExecArg
 		indexer = newIndexer()
+		scratch = newIndexer()
 		argstrs []string
 		b       bytes.Buffer
 	)
@@ -809,6 +876,29 @@ func (e *Expr) exec(sess *Session, env *values.Env, image string, ident string,
 			b.WriteString("%s")
 			argstrs = append(argstrs, fmt.Sprintf("{{%s}}", ae.Ident))
 			earg = append(earg, flow.ExecArg{Out: true, Index: indexer.Index(ae.Ident)})
+		} else if v := e.Template.Resources[i]; v != "" {
+			// A reserved resource: the runtime provides it in the
+			// exec's environment.
+			b.WriteString("${" + v + "}")
+		} else if ae.Kind == ExprBuiltin && ae.Op == "scratch" {
+			// A scratch directory: the runtime substitutes a temporary
+			// directory which is private to the exec, and which is
+			// neither returned nor uploaded. Scratch directories are
+			// indexed by name so that they may be referenced more than once.
+			b.WriteString("%s")
+			argstrs = append(argstrs, fmt.Sprintf("{{%s}}", ae.Abbrev()))
+			earg = append(earg, flow.ExecArg{Scratch: true, Index: scratch.Index(varg[i].(string))})
+		} else if ae.Kind == ExprBuiltin && ae.Op == "inline" {
+			// An inline file: its contents are carried in the exec
+			// itself (and are thus part of its digest), and the runtime
+			// substitutes the path of a file with these contents.
+			data := varg[i].(string)
+			if len(data) > execInlineSizeLimit {
+				return nil, errors.E(errors.Invalid, fmt.Errorf("%v: inline data is too large (%d bytes); inline data may not exceed %d bytes", ae.Position, len(data), execInlineSizeLimit))
+			}
+			b.WriteString("%s")
+			argstrs = append(argstrs, "{{inline}}")
+			earg = append(earg, flow.ExecArg{Inline: true, Data: data})
 		} else if f, ok := varg[i].(*flow.Flow); ok {
 			// Runtime dependency: we attach this to our exec nodes, and let
 			// the runtime perform argument substitution. Only files and dirs
@@ -872,6 +962,11 @@ func (e *Expr) exec(sess *Session, env *values.Env, image string, ident string,
 
 	sess.SeeImage(image)
 
+	coerceDigest := coerceExecOutputDigest
+	if e.Type.Kind == types.StructKind {
+		coerceDigest = coerceExecStructOutputDigest
+	}
+
 	// The output from an exec is a fileset, so we must coerce it back into a
 	// tuple indexed by the our indexer. We must also coerce filesets into
 	// files and dirs.
@@ -892,17 +987,28 @@ func (e *Expr) exec(sess *Session, env *values.Env, image string, ident string,
 			Argstrs:          argstrs,
 			OutputIsDir:      dirs,
 			NonDeterministic: e.NonDeterministic,
+			Timeout:          timeout,
+			Retries:          retries,
+			Stdin:            stdin,
+			EnvVars:          envVars,
+			Secrets:          secrets,
+			Critical:         critical,
+			NoCacheRead:      noCacheRead,
+			NoCacheWrite:     noCacheWrite,
+			ArraySize:        array,
+			Gang:             gang,
 		}},
 
 		Op:         flow.Coerce,
-		FlowDigest: coerceExecOutputDigest,
+		FlowDigest: coerceDigest,
 		Coerce: func(v values.T) (values.T, error) {
 			list := v.(reflow.Fileset).List
 			if got, want := len(list), indexer.N(); got != want {
 				return nil, fmt.Errorf("%v: bad exec result: expected size %d, got %d (deps %v, argmap %v, outputisdir %v)", e.Position, want, got, deps, earg, dirs)
 			}
 			tup := make(values.Tuple, len(outputs))
-			for i, f := range e.Type.Tupled().Fields {
+			fields := e.execOutputs().Fields
+			for i, f := range fields {
 				idx, ok := indexer.Lookup(f.Name)
 				if ok {
 					fs := list[idx]
@@ -935,6 +1041,13 @@ func (e *Expr) exec(sess *Session, env *values.Env, image string, ident string,
 					}
 				}
 			}
+			if e.Type.Kind == types.StructKind {
+				s := make(values.Struct, len(fields))
+				for i, f := range fields {
+					s[f.Name] = tup[i]
+				}
+				return s, nil
+			}
 			if len(tup) == 1 {
 				return tup[0], nil
 			}
@@ -1638,6 +1751,16 @@ var stdEvalK evalK = func(e *Expr, env *values.Env, dw io.Writer) {
 		for _, f := range e.Fields {
 			f.Expr.digest(dw, env)
 		}
+	case ExprStruct:
+		// Struct updates depend only on the updated struct; the new
+		// field values are captured here, in field order.
+		fields := make([]*FieldExpr, len(e.Fields))
+		copy(fields, e.Fields)
+		sort.Slice(fields, func(i, j int) bool { return fields[i].Name < fields[j].Name })
+		for _, f := range fields {
+			io.WriteString(dw, f.Name)
+			f.Expr.digest(dw, env)
+		}
 	case ExprCompr:
 		panic("stdEvalK used for ExprCompr")
 	case ExprBlock:
@@ -1653,9 +1776,206 @@ var stdEvalK evalK = func(e *Expr, env *values.Env, dw io.Writer) {
 	}
 }
 
+// dataError returns an error which annotates err with the structured
+// context data (a map keyed by strings) as sorted key=value arguments,
+// so that the context is retained wherever the error is reported or
+// stored. Data values that are not strings are rendered as reflow values.
+func dataError(op string, data values.T, t *types.T, err error) error {
+	m := data.(*values.Map)
+	if m.Len() == 0 {
+		return err
+	}
+	kvs := make([]string, 0, m.Len())
+	m.Each(func(k, v values.T) {
+		var s string
+		if t.Elem.Kind == types.StringKind {
+			s = v.(string)
+		} else {
+			s = values.Sprint(v, t.Elem)
+		}
+		kvs = append(kvs, k.(string)+"="+s)
+	})
+	sort.Strings(kvs)
+	args := make([]interface{}, 0, len(kvs)+2)
+	args = append(args, op)
+	for _, kv := range kvs {
+		args = append(args, kv)
+	}
+	return errors.E(append(args, err)...)
+}
+
+// execInlineSizeLimit is the maximum size (in bytes) of an exec's
+// inline data: its standard input and each of its inline files.
+// Inline data is carried in the exec's configuration, and so must
+// be small; larger data should be created with files.Create.
+const execInlineSizeLimit = 64 << 10
+
+// maxExecRetries is the maximum number of retries an exec may request.
+const maxExecRetries = 100
+
+// maxExecArraySize is the maximum number of tasks as which an exec
+// may run (see execArray).
+const maxExecArraySize = 100000
+
+// execResourceVars maps the identifiers which, in exec templates,
+// refer to the exec's reserved resources (unless they are otherwise
+// bound) to the environment variables through which the runtime
+// provides them. References are interpolated as the variables, so
+// that they reflect the exec's actual reservation.
+var execResourceVars = map[string]string{
+	"cpu":  reflow.ExecCPUEnv,
+	"mem":  reflow.ExecMemEnv,
+	"disk": reflow.ExecDiskEnv,
+}
+
+// execStdin returns the exec's standard input, as specified by the
+// "stdin" parameter in the value environment.
+func execStdin(env *values.Env) (string, error) {
+	v := env.Value("stdin")
+	if v == nil {
+		return "", nil
+	}
+	stdin := v.(string)
+	if len(stdin) > execInlineSizeLimit {
+		return "", errors.E(errors.Invalid, errors.Errorf("exec stdin is too large (%d bytes); stdin may not exceed %d bytes", len(stdin), execInlineSizeLimit))
+	}
+	return stdin, nil
+}
+
+// execEnv returns the exec's environment variables and secrets, as
+// specified by the "env" and "secrets" parameters in the value
+// environment. Secrets must be valid secret references (see
+// reflow.ParseSecretRef), and a variable may not be defined by both.
+func execEnv(env *values.Env) (envVars, secrets map[string]string, err error) {
+	if envVars, err = execStringMap(env, "env"); err != nil {
+		return nil, nil, err
+	}
+	if secrets, err = execStringMap(env, "secrets"); err != nil {
+		return nil, nil, err
+	}
+	for name, ref := range secrets {
+		if _, ok := envVars[name]; ok {
+			return nil, nil, errors.E(errors.Invalid, errors.Errorf("environment variable %s is defined by both env and secrets", name))
+		}
+		if _, err := reflow.ParseSecretRef(ref); err != nil {
+			return nil, nil, errors.E("secret", name, err)
+		}
+	}
+	return envVars, secrets, nil
+}
+
+// execStringMap returns the map of environment variables specified
+// by the given parameter in the value environment. Names are checked
+// by reflow.CheckEnvName.
+func execStringMap(env *values.Env, param string) (map[string]string, error) {
+	v := env.Value(param)
+	if v == nil {
+		return nil, nil
+	}
+	var (
+		m   map[string]string
+		err error
+	)
+	v.(*values.Map).Each(func(k, v values.T) {
+		if err != nil {
+			return
+		}
+		name := k.(string)
+		if err = reflow.CheckEnvName(name); err != nil {
+			err = errors.E(param, err)
+			return
+		}
+		if m == nil {
+			m = make(map[string]string)
+		}
+		m[name] = v.(string)
+	})
+	return m, err
+}
+
+// execTimeout returns the exec timeout specified by the "timeout"
+// parameter in the value environment: either a duration string parsed
+// by time.ParseDuration, or an integer number of seconds (e.g.,
+// 2*hour). A missing timeout is taken to be zero (no limit).
+func execTimeout(env *values.Env) (time.Duration, error) {
+	v := env.Value("timeout")
+	if v == nil {
+		return 0, nil
+	}
+	if secs, ok := v.(*big.Int); ok {
+		if secs.Sign() < 0 || !secs.IsInt64() || secs.Int64() > math.MaxInt64/int64(time.Second) {
+			return 0, errors.E(errors.Invalid, errors.Errorf("invalid exec timeout %s: must be a non-negative number of seconds", secs))
+		}
+		return time.Duration(secs.Int64()) * time.Second, nil
+	}
+	d, err := time.ParseDuration(v.(string))
+	if err != nil {
+		return 0, errors.E(errors.Invalid, errors.Errorf("invalid exec timeout %q: %v", v.(string), err))
+	}
+	if d < 0 {
+		return 0, errors.E(errors.Invalid, errors.Errorf("invalid exec timeout %q: must not be negative", v.(string)))
+	}
+	return d, nil
+}
+
+// execRetries returns the number of times the exec is retried on
+// failure, as specified by the "retries" parameter in the value
+// environment. A missing parameter is taken to be zero.
+func execRetries(env *values.Env) (int, error) {
+	v := env.Value("retries")
+	if v == nil {
+		return 0, nil
+	}
+	n := v.(*big.Int)
+	if n.Sign() < 0 || !n.IsInt64() || n.Int64() > maxExecRetries {
+		return 0, errors.E(errors.Invalid, errors.Errorf("invalid exec retries %s: must be between 0 and %d", n, maxExecRetries))
+	}
+	return int(n.Int64()), nil
+}
+
+// execArray returns the number of tasks as which the exec runs, as
+// specified by the "array" parameter in the value environment, or
+// zero if the exec does not run as a task array.
+func execArray(env *values.Env) (int, error) {
+	v := env.Value("array")
+	if v == nil {
+		return 0, nil
+	}
+	n := v.(*big.Int)
+	if n.Sign() <= 0 || !n.IsInt64() || n.Int64() > maxExecArraySize {
+		return 0, errors.E(errors.Invalid, errors.Errorf("invalid exec array size %s: must be between 1 and %d", n, maxExecArraySize))
+	}
+	return int(n.Int64()), nil
+}
+
+// execCache returns whether cache reads and writes are disabled for
+// the exec, as specified by the "cache" parameter in the value
+// environment: "off" disables both, "read" (read-only) disables
+// writes, and "write" (write-only) disables reads. A missing
+// parameter (or "readwrite") disables neither.
+func execCache(env *values.Env) (noRead, noWrite bool, err error) {
+	v := env.Value("cache")
+	if v == nil {
+		return false, false, nil
+	}
+	switch mode := v.(string); mode {
+	case "off":
+		return true, true, nil
+	case "read":
+		return false, true, nil
+	case "write":
+		return true, false, nil
+	case "readwrite":
+		return false, false, nil
+	default:
+		return false, false, errors.E(errors.Invalid, errors.Errorf("invalid exec cache mode %q: must be one of \"off\", \"read\", \"write\" or \"readwrite\"", mode))
+	}
+}
+
 // makeResources constructs a resource specification
 // from a value environment, where "mem", "cpu", and
-// "disk" are integers; "cpufeatures" is a list of strings.
+// "disk" are integers; "cpufeatures" is a list of strings;
+// and "ondemand" is a boolean.
 // Missing values are taken to be the zero value.
 func makeResources(env *values.Env) reflow.Resources {
 	f64 := func(id string) float64 {
@@ -1678,6 +1998,14 @@ func makeResources(env *values.Env) reflow.Resources {
 		"cpu":  f64("cpu"),
 		"disk": f64("disk"),
 	}
+	if v := env.Value("ondemand"); v != nil && v.(bool) {
+		// Like CPU features, on-demand capacity is requested per CPU;
+		// execs which request no CPU still require one unit.
+		resources[reflow.OnDemand] = resources["cpu"]
+		if resources[reflow.OnDemand] < 1 {
+			resources[reflow.OnDemand] = 1
+		}
+	}
 	v := env.Value("cpufeatures")
 	if v == nil {
 		return resources
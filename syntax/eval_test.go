@@ -10,6 +10,7 @@ import (
 	"math/big"
 	"reflect"
 	"regexp"
+	"strings"
 	"testing"
 
 	"github.com/grailbio/base/digest"
@@ -85,6 +86,18 @@ func TestEvalSimple(t *testing.T) {
 			&values.Variant{Tag: "Foo", Elem: big.NewInt(3)},
 		},
 		{`switch 123 { case i: i + 333 }`, types.Int, values.NewInt(456)},
+		{
+			`{x := {a: 1, b: "ok"}; {x | a: 2}}`,
+			types.Struct(
+				&types.Field{Name: "a", T: types.Int},
+				&types.Field{Name: "b", T: types.String}),
+			values.Struct{"a": values.NewInt(2), "b": "ok"},
+		},
+		{
+			`{x := {a: {c: 1}}; {x | a: {c: 2, d: 3}}.a.c}`,
+			types.Int,
+			values.NewInt(2),
+		},
 	} {
 		v, typ, _, err := eval(c.e)
 		if err != nil {
@@ -218,6 +231,297 @@ func TestExec(t *testing.T) {
 	}
 }
 
+func TestExecScratch(t *testing.T) {
+	execFlow := func(template string) *flow.Flow {
+		t.Helper()
+		v, _, _, err := eval(template)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f := v.(*flow.Flow)
+		if f.Op == flow.K {
+			f = f.K(nil)
+		}
+		if got, want := f.Op, flow.Coerce; got != want {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+		return f.Deps[0]
+	}
+	f := execFlow(`
+		exec(image := "ubuntu") (out file) {"
+			sort -T {{scratch("tmp")}} -o {{scratch("sorted")}}/x {{"input"}}
+			cp {{scratch("sorted")}}/x {{out}}
+		"}
+	`)
+	if got, want := f.Cmd, "\n\t\t\tsort -T %s -o %s/x input\n\t\t\tcp %s/x %s\n\t\t"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	want := []flow.ExecArg{
+		{Scratch: true, Index: 0},
+		{Scratch: true, Index: 1},
+		{Scratch: true, Index: 1},
+		{Out: true, Index: 0},
+	}
+	if got := f.Argmap; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := len(f.Deps), 0; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	config := f.ExecConfig()
+	if got, want := config.Args[0], (reflow.Arg{Scratch: true, Index: 0}); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := config.OutputIsDir, []bool{false}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// Scratch names do not affect digests.
+	g := execFlow(`
+		exec(image := "ubuntu") (out file) {"
+			sort -T {{scratch("a")}} -o {{scratch("b")}}/x {{"input"}}
+			cp {{scratch("b")}}/x {{out}}
+		"}
+	`)
+	if got, want := g.Digest(), f.Digest(); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestExecInline(t *testing.T) {
+	execFlow := func(template string) *flow.Flow {
+		t.Helper()
+		v, _, _, err := eval(template)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f := v.(*flow.Flow)
+		if f.Op == flow.K {
+			f = f.K(nil)
+		}
+		if got, want := f.Op, flow.Coerce; got != want {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+		return f.Deps[0]
+	}
+	f := execFlow(`
+		exec(image := "ubuntu", stdin := "a\nb\n") (out file) {"
+			tool -config {{inline("key: value\n")}} > {{out}}
+		"}
+	`)
+	if got, want := f.Stdin, "a\nb\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, want := f.Argmap, []flow.ExecArg{{Inline: true, Data: "key: value\n"}, {Out: true}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	config := f.ExecConfig()
+	if got, want := config.Stdin, "a\nb\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, want := config.Args[0], (reflow.Arg{Inline: true, Data: "key: value\n"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// Inline data is part of the exec's digest.
+	for _, template := range []string{
+		`exec(image := "ubuntu", stdin := "a\nb\n") (out file) {"
+			tool -config {{inline("key: other\n")}} > {{out}}
+		"}`,
+		`exec(image := "ubuntu", stdin := "a\n") (out file) {"
+			tool -config {{inline("key: value\n")}} > {{out}}
+		"}`,
+		`exec(image := "ubuntu") (out file) {"
+			tool -config {{inline("key: value\n")}} > {{out}}
+		"}`,
+	} {
+		if g := execFlow(template); g.Digest() == f.Digest() {
+			t.Errorf("%s: digests of different exec flows are not different", template)
+		}
+	}
+}
+
+func TestExecEnv(t *testing.T) {
+	execFlow := func(template string) *flow.Flow {
+		t.Helper()
+		v, _, _, err := eval(template)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f := v.(*flow.Flow)
+		if f.Op == flow.K {
+			f = f.K(nil)
+		}
+		return f.Deps[0]
+	}
+	f := execFlow(`
+		exec(image := "ubuntu", env := ["LEVEL": "debug"], secrets := ["TOKEN": "ssm:/prod/token"]) (out file) {"
+			tool --level=$LEVEL > {{out}}
+		"}
+	`)
+	config := f.ExecConfig()
+	if got, want := config.EnvVars, map[string]string{"LEVEL": "debug"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := config.Secrets, map[string]string{"TOKEN": "ssm:/prod/token"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// Environment variables, but not secrets, are part of the exec's
+	// digest.
+	g := execFlow(`
+		exec(image := "ubuntu", env := ["LEVEL": "info"], secrets := ["TOKEN": "ssm:/prod/token"]) (out file) {"
+			tool --level=$LEVEL > {{out}}
+		"}
+	`)
+	if g.Digest() == f.Digest() {
+		t.Error("digests of execs with different environments are not different")
+	}
+	g = execFlow(`
+		exec(image := "ubuntu", env := ["LEVEL": "debug"], secrets := ["TOKEN": "ssm:/dev/token"]) (out file) {"
+			tool --level=$LEVEL > {{out}}
+		"}
+	`)
+	if g.Digest() != f.Digest() {
+		t.Error("digests of execs with different secrets are different")
+	}
+
+	for _, template := range []string{
+		`exec(image := "ubuntu", env := ["HOME": "/root"]) (out file) {" true "}`,
+		`exec(image := "ubuntu", env := ["A-B": "x"]) (out file) {" true "}`,
+		`exec(image := "ubuntu", secrets := ["TOKEN": "vault:token"]) (out file) {" true "}`,
+		`exec(image := "ubuntu", env := ["TOKEN": "x"], secrets := ["TOKEN": "ssm:token"]) (out file) {" true "}`,
+	} {
+		if _, _, _, err := eval(template); err == nil {
+			t.Errorf("%s: expected error", template)
+		}
+	}
+}
+
+func TestExecArray(t *testing.T) {
+	execFlow := func(template string) *flow.Flow {
+		t.Helper()
+		v, _, _, err := eval(template)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f := v.(*flow.Flow)
+		if f.Op == flow.K {
+			f = f.K(nil)
+		}
+		return f.Deps[0]
+	}
+	f := execFlow(`
+		exec(image := "ubuntu", array := 4, gang := true) (out dir) {"
+			shard $REFLOW_ARRAY_INDEX > {{out}}/part
+		"}
+	`)
+	if got, want := f.ArraySize, 4; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if !f.Gang {
+		t.Error("exec not gang scheduled")
+	}
+
+	// The array's size, but not gang scheduling, is part of the
+	// exec's digest.
+	g := execFlow(`
+		exec(image := "ubuntu", array := 8, gang := true) (out dir) {"
+			shard $REFLOW_ARRAY_INDEX > {{out}}/part
+		"}
+	`)
+	if g.Digest() == f.Digest() {
+		t.Error("digests of arrays with different sizes are not different")
+	}
+	g = execFlow(`
+		exec(image := "ubuntu", array := 4) (out dir) {"
+			shard $REFLOW_ARRAY_INDEX > {{out}}/part
+		"}
+	`)
+	if g.Digest() != f.Digest() {
+		t.Error("digests of gang and non-gang arrays are different")
+	}
+
+	for _, template := range []string{
+		`exec(image := "ubuntu", array := 0) (out dir) {" true "}`,
+		`exec(image := "ubuntu", array := 2) (out file) {" true "}`,
+		`exec(image := "ubuntu", gang := true) (out dir) {" true "}`,
+		`exec(image := "ubuntu", array := "2") (out dir) {" true "}`,
+	} {
+		if _, _, _, err := eval(template); err == nil {
+			t.Errorf("%s: expected error", template)
+		}
+	}
+}
+
+func TestExecStructOutputs(t *testing.T) {
+	v, typ, _, err := eval(`
+		exec(image := "ubuntu") {bam file, metrics dir} {"
+			align > {{bam}}; summarize > {{metrics}}/summary
+		"}
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := types.Struct(&types.Field{Name: "bam", T: types.File}, &types.Field{Name: "metrics", T: types.Dir})
+	if !typ.Equal(want) {
+		t.Fatalf("got %v, want %v", typ, want)
+	}
+	f := v.(*flow.Flow)
+	if f.Op == flow.K {
+		f = f.K(nil)
+	}
+	if got, want := f.Op, flow.Coerce; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got, want := f.Deps[0].OutputIsDir, []bool{false, true}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	bam := reflow.File{ID: reflow.Digester.FromString("bam")}
+	summary := reflow.File{ID: reflow.Digester.FromString("summary")}
+	out, err := f.Coerce(reflow.Fileset{List: []reflow.Fileset{
+		{Map: map[string]reflow.File{".": bam}},
+		{Map: map[string]reflow.File{"summary": summary}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := out.(values.Struct)
+	if got, want := s["bam"], bam; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	metrics := s["metrics"].(values.Dir)
+	if file, ok := metrics.Lookup("summary"); !ok || file != summary {
+		t.Errorf("got %v, want %v", file, summary)
+	}
+}
+
+func TestExecInterpolationExpr(t *testing.T) {
+	v, _, _, err := eval(`{
+		samples := [{name: "a"}, {name: "b"}];
+		exec(image := "ubuntu") (out file) {" cat {{samples[1].name + ".bam"}} {{ {n: len(samples)}.n * 2 }} > {{out}} "}
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := v.(*flow.Flow)
+	if f.Op == flow.K {
+		f = f.K(nil)
+	}
+	if got, want := f.Op, flow.Coerce; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	f = f.Deps[0]
+	if got, want := f.Cmd, " cat b.bam 4 > %s "; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	_, _, _, err = eval(`exec(image := "ubuntu") (out file) {" cat {{"a" + 1}} > {{out}} "}`)
+	if err == nil || !strings.Contains(err.Error(), "interpolation expression error") {
+		t.Errorf("got %v, want interpolation expression error", err)
+	}
+}
+
 // We have to test this manually because the eval tests aren't run with
 // an executor.
 //
@@ -536,6 +840,8 @@ func TestEvalErr(t *testing.T) {
 		{"testdata/err6.rf", "testdata/err6.rf:8:6 failed assertion err6.TestAllFail[1]"},
 		{"testdata/err7.rf", "testdata/lib.rf:8:6 failed assertion lib.AssertErr7[1]"},
 		{"testdata/err8.rf", "testdata/err8.rf:8:6 failed assertion err8.TestAllFail[a, c]"},
+		{"testdata/err9.rf", "assert file=a.bam sample=S1: testdata/err9.rf:5:8: assertion failed: empty input file"},
+		{"testdata/err10.rf", "panic build=18: testdata/err10.rf:1:17: panic: unsupported reference"},
 	} {
 		m, err := sess.Open(c.file)
 		if err != nil {
@@ -584,6 +890,11 @@ func TestTypeErr(t *testing.T) {
 		{"testdata/typerr19.rf", `testdata/typerr19.rf:2:7: nondeterministic must be a bool`},
 		{"testdata/typerr20.rf", `typerr20.rf:1:17: error expects an int and string, not string and string`},
 		{"testdata/typerr21.rf", `typerr21.rf:2:17: error expects an int and string, not int and int`},
+		{"testdata/typerr22.rf", `typerr22.rf:1:19: scratch may only be used in exec templates`},
+		{"testdata/typerr23.rf", `typerr23.rf:2:9: scratch expects a constant string name`},
+		{"testdata/typerr24.rf", `testdata/typerr24.rf:2:9: cannot update x \(type \{a int, b string\}\): no field c$`},
+		{"testdata/typerr25.rf", `testdata/typerr25.rf:2:9: cannot use value \(type string\) as type int in field a$`},
+		{"testdata/typerr26.rf", `testdata/typerr26.rf:2:\d+: struct .* does not have field stats$`},
 	} {
 		_, terr := sess.Open(c.file)
 		if terr == nil {
@@ -18,16 +18,19 @@ import (
 
 func init() {
 	builtins = map[string]bool{
+		"assert":  true,
 		"delay":   true,
 		"error":   true,
 		"fold":    true,
 		"flatten": true,
+		"inline":  true,
 		"len":     true,
 		"list":    true,
 		"map":     true,
 		"panic":   true,
 		"range":   true,
 		"reduce":  true,
+		"scratch": true,
 		"trace":   true,
 		"unzip":   true,
 		"zip":     true,
@@ -138,6 +141,11 @@ type Template struct {
 	Text  string
 	Frags []string
 	Args  []*Expr
+
+	// Resources maps the indices of arguments which refer to the
+	// exec's reserved resources to the environment variables which
+	// provide them. It is computed by the typechecker.
+	Resources map[int]string
 }
 
 // String returns t.Text.
@@ -542,6 +550,10 @@ func (e *Expr) init(sess *Session, env *types.Env) {
 		}
 		e.Type = types.Tuple(fields...).Const()
 	case ExprStruct:
+		if e.Left != nil {
+			e.Type = e.updateType()
+			return
+		}
 		fields := make([]*types.Field, len(e.Fields))
 		for i, f := range e.Fields {
 			fields[i] = &types.Field{Name: f.Name, T: f.Expr.Type}
@@ -617,11 +629,33 @@ func (e *Expr) init(sess *Session, env *types.Env) {
 					e.Type = types.Errorf("%s must be a list of strings", ident)
 					return
 				}
-			case "nondeterministic":
+			case "nondeterministic", "ondemand", "critical", "gang":
 				if d.Type.Kind != types.BoolKind {
 					e.Type = types.Errorf("%s must be a bool", ident)
 					return
 				}
+			case "timeout":
+				switch d.Type.Kind {
+				case types.StringKind, types.IntKind:
+				default:
+					e.Type = types.Errorf("%s must be a string or an integer (seconds)", ident)
+					return
+				}
+			case "retries", "array":
+				if d.Type.Kind != types.IntKind {
+					e.Type = types.Errorf("%s must be an integer", ident)
+					return
+				}
+			case "stdin", "cache":
+				if d.Type.Kind != types.StringKind {
+					e.Type = types.Errorf("%s must be a string", ident)
+					return
+				}
+			case "env", "secrets":
+				if d.Type.Kind != types.MapKind || d.Type.Index.Kind != types.StringKind || d.Type.Elem.Kind != types.StringKind {
+					e.Type = types.Errorf("%s must be a map of strings to strings", ident)
+					return
+				}
 			default:
 				e.Type = types.Errorf("unrecognized exec parameter %s", ident)
 				return
@@ -631,8 +665,16 @@ func (e *Expr) init(sess *Session, env *types.Env) {
 			e.Type = types.Errorf("exec image parameter is required")
 			return
 		}
+		if params["gang"] && !params["array"] {
+			e.Type = types.Errorf("exec parameter gang requires parameter array")
+			return
+		}
+		if e.Type.Kind == types.StructKind && len(e.Type.Fields) == 0 {
+			e.Type = types.Errorf("execs must return at least one output")
+			return
+		}
 		fields := map[string]*types.T{}
-		for i, f := range e.Type.Tupled().Fields {
+		for i, f := range e.execOutputs().Fields {
 			if f.Name == "" {
 				e.Type = types.Errorf("output %d (type %s) must be labelled", i, f.T)
 				return
@@ -643,14 +685,49 @@ func (e *Expr) init(sess *Session, env *types.Env) {
 				e.Type = types.Errorf("execs can only return files and dirs, not %s", f.T)
 				return
 			}
+			if params["array"] && f.T.Kind != types.DirKind {
+				e.Type = types.Errorf("exec arrays can only return dirs, not %s", f.T)
+				return
+			}
 			fields[f.Name] = f.T
 		}
-		for _, ae := range e.Template.Args {
+		e.Template.Resources = nil
+		for i, ae := range e.Template.Args {
 			if t, ok := fields[ae.Ident]; ok && ae.Kind == ExprIdent {
 				ae.Type = t
 				continue
 			}
+			if v := execResourceVars[ae.Ident]; ae.Kind == ExprIdent && v != "" && env.Type(ae.Ident) == nil {
+				// Unbound references to cpu, mem and disk are to the
+				// exec's reserved resources.
+				if e.Template.Resources == nil {
+					e.Template.Resources = make(map[int]string)
+				}
+				e.Template.Resources[i] = v
+				ae.Type = types.Int
+				continue
+			}
 			ae.init(sess, env)
+			if ae.Kind == ExprBuiltin && ae.Op == "scratch" {
+				// Scratch directories are interpolated as paths to temporary
+				// directories that are private to the exec.
+				if len(ae.Fields) != 1 || ae.Fields[0].Expr.Type.Kind != types.StringKind || !ae.Fields[0].Expr.Type.IsConst(nil) {
+					e.Type = types.Errorf("scratch expects a constant string name")
+					return
+				}
+				ae.Type = types.String
+				continue
+			}
+			if ae.Kind == ExprBuiltin && ae.Op == "inline" {
+				// Inline files are interpolated as paths to files with the
+				// provided contents, materialized in the exec's sandbox.
+				if len(ae.Fields) != 1 || ae.Fields[0].Expr.Type.Kind != types.StringKind {
+					e.Type = types.Errorf("inline expects a string")
+					return
+				}
+				ae.Type = types.String
+				continue
+			}
 			// Promote interpolation errors here since they are not part of the regular
 			// syntax tree.
 			if err := ae.Type.Error; err != nil {
@@ -911,12 +988,31 @@ func (e *Expr) init(sess *Session, env *types.Env) {
 			}
 			e.Type = types.Swizzle(e.Type, types.Const, arg0.Type)
 		case "panic":
+			if len(e.Fields) < 1 || len(e.Fields) > 2 {
+				e.Type = types.Errorf("panic expects one or two arguments, got %v", len(e.Fields))
+				break
+			}
 			arg0 := e.Fields[0].Expr
 			if arg0.Type.Kind != types.StringKind {
 				e.Type = types.Errorf("panic expects a string, not %s", arg0.Type)
+			} else if len(e.Fields) == 2 && !isErrorData(e.Fields[1].Expr.Type) {
+				e.Type = types.Errorf("panic expects a map[string:_] as its second argument, not %s", e.Fields[1].Expr.Type)
 			} else {
 				e.Type = types.Bottom
 			}
+		case "assert":
+			if len(e.Fields) < 2 || len(e.Fields) > 3 {
+				e.Type = types.Errorf("assert expects two or three arguments, got %v", len(e.Fields))
+				break
+			}
+			arg0, arg1 := e.Fields[0].Expr, e.Fields[1].Expr
+			if arg0.Type.Kind != types.BoolKind || arg1.Type.Kind != types.StringKind {
+				e.Type = types.Errorf("assert expects a bool and string, not %s and %s", arg0.Type, arg1.Type)
+			} else if len(e.Fields) == 3 && !isErrorData(e.Fields[2].Expr.Type) {
+				e.Type = types.Errorf("assert expects a map[string:_] as its third argument, not %s", e.Fields[2].Expr.Type)
+			} else {
+				e.Type = types.Unit
+			}
 		case "error":
 			arg0, arg1 := e.Fields[0].Expr, e.Fields[1].Expr
 			if arg0.Type.Kind != types.IntKind || arg1.Type.Kind != types.StringKind {
@@ -931,6 +1027,10 @@ func (e *Expr) init(sess *Session, env *types.Env) {
 			e.Type.Flow = true
 		case "trace":
 			e.Type = e.Fields[0].Expr.Type
+		case "scratch", "inline":
+			// Scratch directories and inline files are typechecked by
+			// their enclosing exec.
+			e.Type = types.Errorf("%s may only be used in exec templates", e.Op)
 		case "range":
 			arg0, arg1 := e.Fields[0].Expr, e.Fields[1].Expr
 			if arg0.Type.Kind != types.IntKind {
@@ -1014,6 +1114,39 @@ func (e *Expr) init(sess *Session, env *types.Env) {
 	}
 }
 
+// updateType computes the type of the struct update expression
+// {e.Left | e.Fields}. Updates may only replace existing fields, and
+// a field's new value must be a subtype of the field's type, so that
+// the updated struct has the same type as the original.
+func (e *Expr) updateType() *types.T {
+	if e.Left.Type.Kind == types.ErrorKind {
+		return e.Left.Type
+	}
+	if e.Left.Type.Kind != types.StructKind {
+		return types.Errorf("cannot update %s (type %v): expected struct", e.Left.identOr("value"), e.Left.Type)
+	}
+	ts := make([]*types.T, len(e.Fields))
+	seen := make(map[string]bool)
+	for i, f := range e.Fields {
+		if seen[f.Name] {
+			return types.Errorf("field %s updated more than once", f.Name)
+		}
+		seen[f.Name] = true
+		if f.Expr.Type.Kind == types.ErrorKind {
+			return f.Expr.Type
+		}
+		t := e.Left.Type.Field(f.Name)
+		if t.Kind == types.ErrorKind {
+			return types.Errorf("cannot update %s (type %v): no field %s", e.Left.identOr("value"), e.Left.Type, f.Name)
+		}
+		if !f.Expr.Type.Sub(t) {
+			return types.Errorf("cannot use %s (type %v) as type %v in field %s", f.Expr.identOr("value"), f.Expr.Type, t, f.Name)
+		}
+		ts[i] = f.Expr.Type
+	}
+	return types.Swizzle(e.Left.Type, types.Const, ts...)
+}
+
 func (e *Expr) initResources(sess *Session, env *types.Env) error {
 	for _, d := range e.Decls {
 		if d.Pat.Kind != PatIdent {
@@ -1041,7 +1174,7 @@ func (e *Expr) initResources(sess *Session, env *types.Env) error {
 			if d.Type.Kind != types.ListKind || d.Type.Elem.Kind != types.StringKind {
 				return fmt.Errorf("%s must be a list of strings", ident)
 			}
-		case "wide":
+		case "wide", "ondemand":
 			if d.Type.Kind != types.BoolKind {
 				return fmt.Errorf("%s must be a boolean", ident)
 			}
@@ -1141,6 +1274,9 @@ func (e *Expr) Equal(f *Expr) bool {
 		if len(e.Fields) != len(f.Fields) {
 			return false
 		}
+		if (e.Left == nil) != (f.Left == nil) || e.Left != nil && !e.Left.Equal(f.Left) {
+			return false
+		}
 		for i := range e.Fields {
 			if !e.Fields[i].Equal(f.Fields[i]) {
 				return false
@@ -1212,6 +1348,16 @@ func (e *Expr) Equal(f *Expr) bool {
 	}
 }
 
+// execOutputs returns the outputs of exec expression e as a tuple
+// type: execs return either a struct of (named) outputs, or a tuple
+// of outputs (a single output is returned as is).
+func (e *Expr) execOutputs() *types.T {
+	if e.Type.Kind == types.StructKind {
+		return types.Tuple(e.Type.Fields...)
+	}
+	return e.Type.Tupled()
+}
+
 // String renders a tree-formatted version of e.
 func (e *Expr) String() string {
 	if e == nil {
@@ -1261,7 +1407,11 @@ func (e *Expr) String() string {
 		for i, f := range e.Fields {
 			list[i] = f.Name + ":" + f.Expr.String()
 		}
-		fmt.Fprintf(b, "struct(%v)", strings.Join(list, ", "))
+		if e.Left != nil {
+			fmt.Fprintf(b, "update(%v, %v)", e.Left, strings.Join(list, ", "))
+		} else {
+			fmt.Fprintf(b, "struct(%v)", strings.Join(list, ", "))
+		}
 	case ExprList:
 		list := make([]string, len(e.List))
 		for i, ee := range e.List {
@@ -1417,6 +1567,9 @@ func (e *Expr) Abbrev() string {
 		for i := range fields {
 			fields[i] = e.Fields[i].Name + ":" + e.Fields[i].Abbrev()
 		}
+		if e.Left != nil {
+			return "{" + e.Left.Abbrev() + " | " + strings.Join(fields, ", ") + "}"
+		}
 		return "{" + strings.Join(fields, ", ") + ")"
 	case ExprList:
 		elems := make([]string, len(e.List))
@@ -1534,3 +1687,9 @@ func reportUnused(sess *Session, env *types.Env) {
 		sess.Warnf(sym.Position, "%s declared and not used", sym.Name)
 	}
 }
+
+// isErrorData tells whether a value of type t may be used as the
+// structured context of an error raised by panic or assert.
+func isErrorData(t *types.T) bool {
+	return t.Kind == types.MapKind && t.Index.Kind == types.StringKind
+}
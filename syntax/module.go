@@ -9,6 +9,7 @@ import (
 	"flag"
 	"fmt"
 	"math/big"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -641,6 +642,52 @@ func (m *ModuleImpl) Doc(ident string) string {
 	return m.Docs[ident]
 }
 
+// Imports returns the paths, as written, of the modules instantiated
+// by make expressions in m, in sorted order.
+func (m *ModuleImpl) Imports() []string {
+	paths := make(map[string]bool)
+	var walk func(e *Expr)
+	walk = func(e *Expr) {
+		if e == nil {
+			return
+		}
+		if e.Kind == ExprMake && e.Left != nil && e.Left.Kind == ExprLit {
+			if path, ok := e.Left.Val.(string); ok {
+				paths[path] = true
+			}
+		}
+		for _, sub := range e.Subexpr() {
+			walk(sub)
+		}
+		for _, d := range e.Decls {
+			walk(d.Expr)
+		}
+		for _, c := range e.CaseClauses {
+			walk(c.Expr)
+		}
+		walk(e.ComprExpr)
+		for _, c := range e.ComprClauses {
+			walk(c.Expr)
+		}
+		if e.Template != nil {
+			for _, arg := range e.Template.Args {
+				walk(arg)
+			}
+		}
+	}
+	for _, decls := range [][]*Decl{m.ParamDecls, m.Decls} {
+		for _, d := range decls {
+			walk(d.Expr)
+		}
+	}
+	imports := make([]string, 0, len(paths))
+	for path := range paths {
+		imports = append(imports, path)
+	}
+	sort.Strings(imports)
+	return imports
+}
+
 // Type returns the module type of m.
 func (m *ModuleImpl) Type(penv *types.Env) *types.T {
 	predicates := m.predicates.Copy()
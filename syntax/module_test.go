@@ -4,6 +4,7 @@
 package syntax
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/grailbio/reflow/flow"
@@ -137,3 +138,32 @@ func TestFlowFlag(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestModuleImports(t *testing.T) {
+	sess := NewSession(memorySourcer{
+		"main.rf": []byte(`
+param x = make("./params.rf").Default
+
+val strings = make("$/strings")
+
+func Align(s string) = {
+	lib := make("./lib.rf")
+	lib.Align(s)
+}
+
+val Check = [make("./lib.rf").Check(s) | s <- ["a", "b"]]
+`),
+		"params.rf": []byte(`val Default = "x"`),
+		"lib.rf": []byte(`
+func Align(s string) = s
+func Check(s string) = s
+`),
+	})
+	m, err := sess.Open("main.rf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := m.(*ModuleImpl).Imports(), []string{"$/strings", "./lib.rf", "./params.rf"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -386,7 +386,7 @@ func (x *Parser) scanTemplate(s string) *Template {
 		if i := strings.LastIndex(s[:beg+2], "\n"); i >= 0 {
 			pos.Column = beg - i
 		}
-		end := strings.Index(s, "}}")
+		end := interpolationEnd(s, beg+2)
 		if end < 0 {
 			x.Error("unterminated interpolation")
 			return nil
@@ -415,3 +415,33 @@ func (x *Parser) scanTemplate(s string) *Template {
 	t.Frags = append(t.Frags, s)
 	return t
 }
+
+// interpolationEnd returns the index in s of the "}}" which closes the
+// template interpolation whose expression begins at s[i], or -1 if
+// the interpolation is not terminated. As in the scanner, string
+// literals and braces within the expression are skipped.
+func interpolationEnd(s string, i int) int {
+	depth := 0
+	for ; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			for i++; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' {
+					i++
+				}
+			}
+		case '`':
+			for i++; i < len(s) && s[i] != '`'; i++ {
+			}
+		case '{':
+			depth++
+		case '}':
+			if depth > 0 {
+				depth--
+			} else if i+1 < len(s) && s[i+1] == '}' {
+				return i
+			}
+		}
+	}
+	return -1
+}
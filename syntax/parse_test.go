@@ -340,6 +340,8 @@ func TestParseTemplate(t *testing.T) {
 		{`{{x}}`, []string{"", ""}},
 		{`x{{x}}y`, []string{"x", "y"}},
 		{`{{x}}{{y}}{{z}}`, []string{"", "", "", ""}},
+		{`cat {{x + ".bam"}} {{"}}"}}`, []string{"cat ", " ", ""}},
+		{`{{ {a: "\"}"}.a}}{{ {b: 1}}}x`, []string{"", "", "x"}},
 	} {
 		temp, err := template(c.temp)
 		if err != nil {
@@ -581,6 +581,8 @@ term:
 	{$$ = &Expr{Position: $1.Position, Comment: $1.comment, Kind: ExprTuple, Fields: append([]*FieldExpr{{Expr: $2}}, $4...)}}
 |	 '{' structfieldargs commaOk '}'
 	{$$ = &Expr{Position: $1.Position, Comment: $1.comment, Kind: ExprStruct, Fields: $2}}
+|	'{' expr '|' structfieldargs commaOk '}'
+	{$$ = &Expr{Position: $1.Position, Comment: $1.comment, Kind: ExprStruct, Left: $2, Fields: $4}}
 |	'[' listargs commaOk ']'
 	{$$ = &Expr{Position: $1.Position, Comment: $1.comment, Kind: ExprList, List: $2}}
 |	'[' listargs commaOk listappendargs commaOk ']'
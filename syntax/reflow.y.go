@@ -225,179 +225,192 @@ var yyExca = [...]int{
 	1, -1,
 	-2, 0,
 	-1, 57,
-	76, 166,
+	76, 167,
 	-2, 54,
+	-1, 101,
+	71, 148,
+	75, 148,
+	-2, 108,
 }
 
 const yyPrivate = 57344
 
-const yyLast = 1146
+const yyLast = 1234
 
 var yyAct = [...]int{
-	11, 97, 171, 231, 245, 61, 120, 336, 170, 165,
-	256, 167, 32, 60, 89, 218, 90, 91, 176, 113,
-	131, 249, 169, 119, 98, 95, 47, 104, 99, 117,
-	356, 10, 108, 319, 283, 246, 127, 87, 86, 337,
-	343, 244, 217, 49, 322, 83, 84, 111, 168, 235,
-	77, 78, 304, 299, 79, 80, 81, 82, 305, 267,
-	213, 237, 236, 199, 214, 88, 238, 235, 353, 182,
-	144, 145, 146, 147, 148, 149, 150, 151, 152, 153,
-	154, 155, 156, 157, 158, 159, 160, 161, 163, 134,
-	112, 198, 137, 300, 330, 199, 296, 199, 179, 230,
-	178, 140, 212, 210, 204, 196, 188, 192, 193, 46,
-	197, 33, 35, 36, 34, 185, 37, 38, 141, 42,
-	184, 201, 187, 177, 44, 183, 46, 205, 33, 35,
-	36, 34, 215, 37, 38, 349, 42, 60, 311, 221,
-	297, 44, 225, 241, 41, 43, 40, 224, 351, 207,
-	227, 206, 332, 324, 316, 314, 285, 269, 234, 250,
-	250, 41, 43, 40, 209, 202, 203, 110, 48, 87,
-	86, 338, 183, 334, 124, 240, 312, 211, 186, 109,
-	280, 229, 279, 309, 247, 48, 251, 56, 122, 254,
-	48, 259, 260, 252, 248, 288, 126, 88, 233, 346,
-	226, 222, 242, 65, 264, 318, 216, 208, 191, 253,
-	121, 166, 142, 107, 106, 270, 63, 64, 66, 265,
-	50, 94, 93, 92, 282, 116, 257, 278, 268, 50,
-	138, 286, 136, 221, 289, 274, 60, 67, 284, 258,
-	9, 291, 287, 293, 292, 281, 50, 271, 92, 271,
-	129, 301, 275, 276, 232, 54, 52, 53, 295, 307,
-	273, 294, 306, 59, 54, 52, 53, 328, 298, 219,
-	141, 329, 302, 173, 58, 321, 313, 51, 115, 55,
-	320, 54, 52, 53, 323, 132, 51, 266, 55, 325,
-	243, 327, 195, 317, 326, 164, 143, 333, 142, 133,
-	335, 123, 105, 51, 339, 55, 1, 341, 65, 128,
-	315, 172, 125, 331, 130, 272, 340, 135, 57, 65,
-	344, 63, 64, 66, 63, 64, 66, 7, 239, 350,
-	345, 347, 63, 64, 66, 348, 162, 96, 220, 292,
-	114, 45, 257, 352, 118, 67, 100, 355, 342, 39,
-	255, 354, 357, 67, 359, 361, 99, 362, 103, 63,
-	64, 66, 364, 363, 178, 87, 86, 69, 70, 73,
+	11, 12, 28, 14, 47, 39, 45, 122, 62, 61,
+	115, 178, 173, 32, 89, 116, 90, 91, 172, 170,
+	171, 121, 341, 60, 129, 95, 97, 106, 167, 99,
+	248, 49, 110, 10, 98, 220, 233, 252, 100, 120,
+	259, 119, 169, 222, 96, 280, 128, 141, 113, 133,
+	8, 363, 365, 266, 314, 103, 105, 258, 164, 241,
+	7, 114, 57, 137, 275, 132, 127, 130, 1, 9,
+	146, 147, 148, 149, 150, 151, 152, 153, 154, 155,
+	156, 157, 158, 159, 160, 161, 162, 163, 165, 142,
+	65, 136, 2, 3, 4, 5, 6, 143, 56, 182,
+	131, 144, 198, 63, 64, 66, 58, 199, 303, 194,
+	195, 181, 87, 86, 200, 186, 223, 189, 201, 92,
+	101, 17, 29, 50, 203, 30, 50, 19, 20, 209,
+	207, 22, 65, 63, 64, 102, 107, 92, 204, 13,
+	88, 31, 261, 23, 227, 63, 64, 66, 304, 60,
+	226, 185, 221, 229, 67, 25, 24, 26, 54, 52,
+	53, 54, 52, 53, 50, 208, 67, 215, 238, 239,
+	16, 216, 253, 237, 240, 315, 231, 242, 15, 27,
+	51, 253, 55, 51, 223, 55, 250, 93, 254, 111,
+	174, 257, 59, 262, 263, 276, 94, 291, 108, 54,
+	52, 53, 235, 63, 64, 66, 251, 267, 109, 244,
+	270, 308, 268, 168, 201, 255, 260, 309, 273, 112,
+	313, 51, 117, 55, 67, 246, 285, 256, 271, 326,
+	281, 118, 124, 289, 237, 123, 292, 274, 125, 274,
+	126, 134, 278, 279, 294, 284, 296, 135, 60, 138,
+	322, 139, 143, 298, 305, 144, 295, 140, 300, 65,
+	145, 297, 311, 290, 302, 166, 277, 175, 306, 179,
+	193, 287, 63, 64, 66, 184, 187, 188, 190, 197,
+	317, 205, 206, 324, 48, 210, 211, 327, 212, 214,
+	218, 213, 329, 67, 331, 219, 234, 217, 224, 228,
+	337, 319, 232, 236, 340, 245, 201, 243, 344, 335,
+	46, 346, 33, 35, 36, 34, 321, 37, 38, 269,
+	42, 286, 247, 330, 349, 44, 185, 272, 299, 310,
+	288, 323, 316, 355, 260, 301, 352, 318, 320, 325,
+	347, 328, 332, 333, 334, 41, 43, 40, 336, 350,
+	345, 338, 360, 295, 339, 343, 87, 86, 353, 364,
+	359, 356, 367, 357, 83, 84, 348, 369, 342, 48,
+	354, 100, 358, 79, 80, 81, 82, 361, 249, 181,
+	0, 283, 0, 282, 88, 0, 0, 0, 0, 0,
+	362, 0, 0, 366, 368, 87, 86, 69, 70, 73,
 	74, 75, 76, 83, 84, 85, 71, 72, 77, 78,
-	67, 101, 79, 80, 81, 82, 2, 3, 4, 5,
-	6, 310, 263, 88, 360, 358, 14, 28, 8, 12,
-	62, 337, 87, 86, 69, 70, 73, 74, 75, 76,
-	83, 84, 85, 71, 72, 77, 78, 139, 277, 79,
-	80, 81, 82, 0, 0, 0, 0, 0, 0, 0,
-	88, 0, 0, 0, 0, 0, 0, 0, 246, 87,
-	86, 69, 70, 73, 74, 75, 76, 83, 84, 85,
-	71, 72, 77, 78, 0, 0, 79, 80, 81, 82,
-	0, 0, 0, 0, 0, 0, 0, 88, 0, 175,
-	0, 0, 0, 0, 174, 87, 86, 69, 70, 73,
+	0, 0, 79, 80, 81, 82, 0, 0, 0, 0,
+	0, 0, 0, 88, 0, 177, 0, 0, 0, 0,
+	176, 87, 86, 69, 70, 73, 74, 75, 76, 83,
+	84, 85, 71, 72, 77, 78, 191, 0, 79, 80,
+	81, 82, 0, 0, 0, 0, 0, 0, 0, 88,
+	0, 0, 0, 0, 0, 192, 87, 86, 69, 70,
+	73, 74, 75, 76, 83, 84, 85, 71, 72, 77,
+	78, 0, 0, 79, 80, 81, 82, 0, 0, 0,
+	68, 0, 0, 0, 88, 168, 87, 86, 69, 70,
+	73, 74, 75, 76, 83, 84, 85, 71, 72, 77,
+	78, 0, 0, 79, 80, 81, 82, 0, 0, 0,
+	0, 0, 0, 0, 88, 87, 86, 69, 70, 73,
 	74, 75, 76, 83, 84, 85, 71, 72, 77, 78,
-	189, 0, 79, 80, 81, 82, 0, 0, 0, 0,
-	0, 0, 0, 88, 0, 0, 0, 0, 0, 190,
-	87, 86, 69, 70, 73, 74, 75, 76, 83, 84,
-	85, 71, 72, 77, 78, 0, 0, 79, 80, 81,
-	82, 0, 18, 17, 29, 0, 0, 30, 88, 19,
-	20, 0, 0, 22, 303, 0, 0, 21, 0, 0,
-	0, 13, 0, 31, 0, 23, 87, 86, 0, 0,
-	0, 0, 0, 0, 83, 84, 0, 25, 24, 26,
-	0, 0, 0, 79, 80, 81, 82, 0, 0, 0,
-	0, 0, 16, 0, 88, 0, 0, 0, 0, 0,
-	15, 27, 0, 0, 0, 0, 0, 0, 0, 0,
-	0, 0, 102, 87, 86, 69, 70, 73, 74, 75,
-	76, 83, 84, 85, 71, 72, 77, 78, 0, 0,
-	79, 80, 81, 82, 0, 0, 0, 0, 0, 0,
-	0, 88, 0, 308, 87, 86, 69, 70, 73, 74,
-	75, 76, 83, 84, 85, 71, 72, 77, 78, 0,
-	0, 79, 80, 81, 82, 0, 0, 0, 0, 0,
-	0, 0, 88, 0, 262, 87, 86, 69, 70, 73,
+	180, 0, 79, 80, 81, 82, 0, 0, 0, 0,
+	0, 0, 0, 88, 196, 87, 86, 69, 70, 73,
 	74, 75, 76, 83, 84, 85, 71, 72, 77, 78,
 	0, 0, 79, 80, 81, 82, 0, 0, 0, 0,
-	0, 0, 0, 88, 46, 261, 33, 35, 36, 34,
-	0, 37, 38, 0, 42, 0, 0, 0, 0, 44,
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 41,
-	43, 40, 87, 86, 69, 70, 73, 74, 75, 76,
+	0, 0, 0, 88, 87, 86, 69, 70, 73, 74,
+	75, 76, 83, 84, 85, 71, 72, 77, 78, 0,
+	0, 79, 80, 81, 82, 0, 0, 0, 0, 0,
+	0, 0, 88, 230, 87, 86, 69, 70, 73, 74,
+	75, 76, 83, 84, 85, 71, 72, 77, 78, 0,
+	0, 79, 80, 81, 82, 0, 0, 0, 0, 0,
+	0, 0, 88, 0, 0, 0, 0, 0, 0, 0,
+	249, 87, 86, 69, 70, 73, 74, 75, 76, 83,
+	84, 85, 71, 72, 77, 78, 0, 0, 79, 80,
+	81, 82, 0, 0, 0, 0, 0, 0, 0, 88,
+	0, 264, 87, 86, 69, 70, 73, 74, 75, 76,
 	83, 84, 85, 71, 72, 77, 78, 0, 0, 79,
-	80, 81, 82, 48, 0, 0, 0, 0, 0, 0,
-	88, 228, 0, 0, 0, 0, 0, 223, 166, 87,
+	80, 81, 82, 0, 0, 0, 0, 0, 0, 0,
+	88, 0, 265, 87, 86, 69, 70, 73, 74, 75,
+	76, 83, 84, 85, 71, 72, 77, 78, 0, 0,
+	79, 80, 81, 82, 0, 0, 0, 0, 0, 0,
+	0, 88, 0, 0, 0, 0, 0, 307, 87, 86,
+	69, 70, 73, 74, 75, 76, 83, 84, 85, 71,
+	72, 77, 78, 0, 0, 79, 80, 81, 82, 0,
+	0, 0, 0, 0, 0, 0, 88, 0, 312, 87,
 	86, 69, 70, 73, 74, 75, 76, 83, 84, 85,
 	71, 72, 77, 78, 0, 0, 79, 80, 81, 82,
-	0, 46, 0, 33, 35, 36, 34, 88, 37, 38,
-	0, 42, 0, 0, 87, 86, 44, 70, 73, 74,
-	75, 76, 83, 84, 0, 71, 72, 77, 78, 0,
-	0, 79, 80, 81, 82, 0, 41, 43, 40, 0,
-	0, 0, 88, 0, 194, 87, 86, 69, 70, 73,
-	74, 75, 76, 83, 84, 85, 71, 72, 77, 78,
-	48, 0, 79, 80, 81, 82, 0, 0, 0, 0,
-	0, 0, 200, 88, 87, 86, 69, 70, 73, 74,
-	75, 76, 83, 84, 85, 71, 72, 77, 78, 0,
-	0, 79, 80, 81, 82, 0, 0, 0, 68, 0,
-	0, 0, 88, 87, 86, 69, 70, 73, 74, 75,
+	0, 0, 0, 183, 17, 29, 0, 88, 30, 0,
+	19, 20, 0, 0, 22, 342, 63, 64, 102, 0,
+	0, 0, 13, 0, 31, 0, 23, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 0, 67, 25, 24,
+	26, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+	0, 0, 0, 16, 0, 0, 0, 0, 0, 0,
+	0, 15, 27, 87, 86, 69, 70, 73, 74, 75,
 	76, 83, 84, 85, 71, 72, 77, 78, 0, 0,
 	79, 80, 81, 82, 0, 0, 0, 0, 0, 0,
 	0, 88, 87, 86, 69, 70, 73, 74, 75, 76,
 	83, 84, 0, 71, 72, 77, 78, 0, 0, 79,
-	80, 81, 82, 0, 0, 0, 0, 87, 86, 0,
-	88, 73, 74, 75, 76, 83, 84, 0, 71, 72,
-	77, 78, 0, 0, 79, 80, 81, 82, 0, 180,
-	17, 29, 0, 0, 30, 88, 19, 20, 0, 0,
-	22, 0, 63, 64, 181, 0, 0, 0, 13, 0,
-	31, 0, 23, 0, 0, 0, 0, 0, 0, 0,
-	0, 0, 0, 67, 25, 24, 26, 0, 0, 0,
-	18, 17, 29, 0, 0, 30, 0, 19, 20, 16,
-	0, 22, 0, 0, 0, 21, 0, 15, 27, 13,
-	0, 31, 0, 23, 0, 0, 0, 0, 0, 0,
-	0, 0, 0, 0, 0, 25, 24, 26, 46, 0,
-	33, 35, 36, 34, 0, 37, 38, 0, 42, 0,
-	16, 0, 0, 44, 0, 290, 0, 0, 15, 27,
+	80, 81, 82, 0, 18, 17, 29, 0, 0, 30,
+	88, 19, 20, 0, 0, 22, 0, 0, 0, 21,
+	0, 0, 0, 13, 0, 31, 0, 23, 0, 87,
+	86, 0, 0, 0, 0, 0, 0, 83, 84, 25,
+	24, 26, 77, 78, 0, 0, 79, 80, 81, 82,
+	0, 0, 0, 0, 16, 0, 0, 88, 0, 0,
+	0, 0, 15, 27, 87, 86, 0, 70, 73, 74,
+	75, 76, 83, 84, 104, 71, 72, 77, 78, 0,
+	0, 79, 80, 81, 82, 0, 18, 17, 29, 0,
+	0, 30, 88, 19, 20, 0, 0, 22, 0, 0,
+	0, 21, 0, 0, 0, 13, 0, 31, 0, 23,
 	0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
-	0, 0, 0, 41, 43, 40, 46, 0, 33, 35,
-	36, 34, 0, 37, 38, 0, 42, 0, 0, 0,
-	0, 44, 0, 0, 0, 0, 0, 48, 0, 0,
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
-	0, 41, 43, 40, 0, 0, 0, 0, 0, 0,
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
-	0, 0, 0, 0, 0, 48,
+	0, 25, 24, 26, 0, 0, 0, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 16, 0, 0, 0,
+	0, 0, 87, 86, 15, 27, 73, 74, 75, 76,
+	83, 84, 0, 71, 72, 77, 78, 0, 0, 79,
+	80, 81, 82, 0, 46, 0, 33, 35, 36, 34,
+	88, 37, 38, 46, 42, 33, 35, 36, 34, 44,
+	37, 38, 0, 42, 0, 0, 0, 0, 44, 0,
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 41,
+	43, 40, 0, 0, 0, 0, 0, 0, 41, 43,
+	40, 0, 46, 0, 33, 35, 36, 34, 0, 37,
+	38, 0, 42, 48, 0, 0, 0, 44, 0, 293,
+	0, 0, 48, 0, 0, 202, 0, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 225, 41, 43, 40,
+	46, 0, 33, 35, 36, 34, 0, 37, 38, 46,
+	42, 33, 35, 36, 34, 44, 37, 38, 0, 42,
+	0, 48, 0, 0, 44, 0, 0, 0, 0, 0,
+	0, 0, 0, 0, 0, 41, 43, 40, 0, 0,
+	0, 0, 0, 0, 41, 43, 40, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 48,
+	0, 0, 0, 0, 0, 0, 0, 0, 48, 0,
+	0, 0, 0, 351,
 }
 
 var yyPact = [...]int{
-	358, -1000, 207, -1000, 1006, 1082, 242, 123, -1000, 269,
-	199, 824, -1000, 1006, -1000, 1006, 1006, -1000, -1000, -1000,
-	-1000, 183, 182, 181, 1006, 342, 528, 298, -1000, 174,
-	173, 1006, 115, -1000, -1000, -1000, -1000, -1000, -1000, 99,
-	1082, 274, 186, 1082, 170, 133, -1000, -1000, 297, 110,
-	-1000, -1000, 242, 242, 281, 295, -1000, 198, -1000, -1000,
-	16, -1000, -1000, 193, 242, 250, 294, 292, -1000, 1006,
-	1006, 1006, 1006, 1006, 1006, 1006, 1006, 1006, 1006, 1006,
-	1006, 1006, 1006, 1006, 1006, 1006, 1006, 1006, 291, 729,
-	129, 129, 274, 307, 268, 399, 48, 965, -1000, -7,
-	98, 40, 109, 31, 435, 168, 1006, 1006, 795, -1000,
-	288, 36, 20, -1000, 787, -1000, 274, 96, 29, -1000,
-	1082, 1082, 127, 167, -1000, 94, 28, -1000, 108, 27,
-	-11, -1000, 58, 166, 315, -34, 229, -1000, 161, -1000,
-	690, 1006, 160, 1082, 764, 907, -3, -3, -3, -3,
-	-3, -3, 516, 516, 129, 129, 129, 129, 129, 129,
-	882, 692, 24, 853, -1000, 230, -1000, 88, 22, -8,
-	-1000, -1000, 250, -9, 1006, -1000, 72, 286, -35, 362,
-	250, 208, -1000, 1006, 125, 1006, -1000, 124, 1006, 216,
-	1006, 1006, 625, 594, -1000, -1000, -1000, 1082, -1000, 274,
-	283, -1000, -12, -1000, 1082, -1000, 87, -1000, 1082, -1000,
-	242, -1000, 225, -1000, 281, 242, 242, -1000, -1000, -1000,
-	105, -1000, 307, 1006, -43, 853, 274, -1000, -1000, 86,
-	1006, -1000, 172, 965, 1044, 307, 1082, -1000, 307, 21,
-	853, -1000, -1000, 51, -1000, 69, -1000, 853, -1000, 18,
-	1006, 853, -1000, 18, 470, -17, -1000, 240, 1006, 853,
-	563, -1000, -1000, 112, 107, -1000, -1000, -1000, -1000, 1082,
-	85, -1000, -1000, 242, -1000, -1000, 84, 135, -44, 1006,
-	271, -26, 853, 1006, 83, -1000, 853, -1000, 1006, 362,
-	1006, 246, -1000, 261, 19, 82, 1006, -1000, 104, 1006,
-	-1000, 325, 102, 1006, -1000, 216, 1006, 853, -1000, -1000,
-	-1000, 242, -1000, -1000, -1000, -1000, -1000, -36, -1000, 1006,
-	853, -1000, -37, 853, 122, 729, 64, 853, 1006, -1000,
-	307, 78, -1000, 853, -1000, 325, -1000, -1000, -1000, 853,
-	-1000, 853, -6, -1000, 853, 304, 1006, -47, 230, -1000,
-	853, -1000, -1000, 965, -1000, 853, 1006, -1000, -41, 853,
-	-1000, 965, 853, -1000, 853,
+	64, -1000, 36, -1000, 992, 1165, 122, 34, -1000, 101,
+	128, 426, -1000, 992, -1000, 992, 992, -1000, -1000, -1000,
+	-1000, 79, 147, 156, 992, 116, 910, 132, -1000, 158,
+	168, 992, 125, -1000, -1000, -1000, -1000, -1000, -1000, 151,
+	1165, 218, 192, 1165, 195, 177, -1000, -1000, 234, 176,
+	-1000, -1000, 122, 122, 237, 243, -1000, 215, -1000, -1000,
+	175, -1000, -1000, 220, 122, 232, 251, 256, -1000, 992,
+	992, 992, 992, 992, 992, 992, 992, 992, 992, 992,
+	992, 992, 992, 992, 992, 992, 992, 992, 261, 456,
+	72, 72, 218, 186, 262, 355, 194, 485, 799, -1000,
+	199, 77, 97, 201, 208, 203, 391, 230, 992, 992,
+	515, -1000, 275, 33, 43, -1000, 1070, -1000, 218, 211,
+	207, -1000, 1165, 1165, 221, 245, -1000, 216, 213, -1000,
+	222, 214, 96, -1000, 223, 250, 255, 219, 112, -1000,
+	258, -1000, 1079, 992, 259, 1165, 934, 1012, 899, 899,
+	899, 899, 899, 899, 316, 316, 72, 72, 72, 72,
+	72, 72, 852, 544, 227, 823, -1000, 272, -1000, 233,
+	231, 98, -1000, -1000, 232, 99, 992, -1000, 236, 301,
+	301, 246, 574, 232, -1000, 992, 137, 992, -1000, 146,
+	992, 119, 992, 992, 611, 642, -1000, -1000, -1000, 1165,
+	-1000, 218, 315, -1000, 139, -1000, 1165, -1000, 257, -1000,
+	1165, -1000, 122, -1000, 160, -1000, 237, 122, 122, -1000,
+	-1000, -1000, 306, -1000, 186, 992, 244, 823, 218, -1000,
+	-1000, 260, 992, -1000, 174, 799, 1118, 186, 1165, -1000,
+	186, 253, 823, -1000, -1000, 252, 194, -1000, 264, -1000,
+	823, -1000, 73, 992, 823, -1000, 73, 673, 142, -1000,
+	307, 992, 823, 708, -1000, -1000, 149, 263, -1000, -1000,
+	-1000, -1000, 1165, 267, -1000, -1000, 122, -1000, -1000, 268,
+	180, 254, 992, 335, 159, 823, 992, 271, -1000, 823,
+	-1000, 992, 574, 992, 321, -1000, 333, 269, 278, 992,
+	280, -1000, 285, 992, -1000, 739, 286, 992, -1000, 119,
+	992, 823, -1000, -1000, -1000, 122, -1000, -1000, -1000, -1000,
+	-1000, 290, -1000, 992, 823, -1000, 292, 823, 1156, 456,
+	299, 823, 992, -1000, 186, 291, -1000, 823, -1000, -1000,
+	739, -1000, -1000, -1000, 823, -1000, 823, 298, -1000, 823,
+	86, 992, 300, 272, -1000, 823, -1000, -1000, 799, -1000,
+	823, 992, -1000, 302, 823, -1000, 799, 823, -1000, 823,
 }
 
 var yyPgo = [...]int{
-	0, 31, 1, 22, 15, 418, 417, 5, 400, 2,
-	8, 0, 399, 398, 397, 9, 3, 396, 395, 394,
-	392, 391, 381, 21, 358, 350, 10, 349, 6, 23,
-	344, 29, 48, 19, 11, 26, 341, 340, 338, 24,
-	337, 336, 328, 327, 318, 317, 36, 315, 20, 314,
-	312, 196, 309, 306, 7, 18, 4,
+	0, 33, 34, 20, 35, 45, 47, 9, 8, 12,
+	18, 0, 1, 50, 2, 28, 36, 3, 51, 52,
+	53, 54, 55, 37, 56, 57, 40, 5, 7, 21,
+	39, 41, 19, 10, 42, 4, 6, 15, 43, 29,
+	44, 58, 59, 60, 62, 63, 24, 64, 49, 65,
+	66, 46, 67, 68, 22, 11, 30,
 }
 
 var yyR1 = [...]int{
@@ -414,11 +427,11 @@ var yyR1 = [...]int{
 	11, 11, 11, 11, 11, 16, 16, 12, 12, 12,
 	12, 12, 12, 12, 12, 12, 12, 12, 12, 12,
 	12, 12, 12, 12, 12, 12, 12, 12, 12, 12,
-	14, 15, 17, 20, 20, 21, 18, 18, 19, 25,
-	25, 26, 26, 56, 56, 40, 40, 39, 39, 22,
-	22, 22, 23, 23, 42, 42, 41, 41, 24, 24,
-	34, 43, 13, 13, 44, 44, 45, 45, 45, 55,
-	55, 54, 54,
+	12, 14, 15, 17, 20, 20, 21, 18, 18, 19,
+	25, 25, 26, 26, 56, 56, 40, 40, 39, 39,
+	22, 22, 22, 23, 23, 42, 42, 41, 41, 24,
+	24, 34, 43, 13, 13, 44, 44, 45, 45, 45,
+	55, 55, 54, 54,
 }
 
 var yyR2 = [...]int{
@@ -433,13 +446,13 @@ var yyR2 = [...]int{
 	1, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 4, 1,
 	4, 5, 3, 2, 2, 2, 5, 1, 1, 1,
-	1, 6, 7, 6, 4, 7, 6, 4, 4, 6,
-	3, 4, 6, 5, 2, 5, 3, 1, 4, 4,
-	5, 5, 5, 0, 2, 5, 1, 1, 2, 1,
-	3, 3, 2, 0, 1, 1, 3, 1, 3, 0,
-	1, 3, 3, 4, 1, 3, 1, 3, 3, 5,
-	1, 3, 0, 2, 0, 3, 0, 2, 4, 0,
-	1, 0, 1,
+	1, 6, 7, 6, 4, 7, 6, 4, 6, 4,
+	6, 3, 4, 6, 5, 2, 5, 3, 1, 4,
+	4, 5, 5, 5, 0, 2, 5, 1, 1, 2,
+	1, 3, 3, 2, 0, 1, 1, 3, 1, 3,
+	0, 1, 3, 3, 4, 1, 3, 1, 3, 3,
+	5, 1, 3, 0, 2, 0, 3, 0, 2, 4,
+	0, 1, 0, 1,
 }
 
 var yyChk = [...]int{
@@ -452,74 +465,74 @@ var yyChk = [...]int{
 	-9, -7, -8, 17, 18, 4, 19, 38, 64, 42,
 	43, 51, 52, 44, 45, 46, 47, 53, 54, 57,
 	58, 59, 60, 48, 49, 50, 41, 40, 68, -11,
-	-11, -11, 40, 40, 40, -11, -40, -2, -39, -9,
-	4, -22, 74, -24, -11, 4, 40, 40, -11, 64,
-	68, -28, -32, -33, -37, 4, 39, -31, -30, -29,
-	-28, 40, 55, 4, 64, -50, -51, -46, -52, -51,
-	-49, -48, 4, 4, -1, -45, 34, 76, 37, -6,
-	-46, 20, 4, 4, -11, -11, -11, -11, -11, -11,
+	-11, -11, 40, 40, 40, -11, -40, -11, -2, -39,
+	-9, 4, 19, -22, 74, -24, -11, 4, 40, 40,
+	-11, 64, 68, -28, -32, -33, -37, 4, 39, -31,
+	-30, -29, -28, 40, 55, 4, 64, -50, -51, -46,
+	-52, -51, -49, -48, 4, 4, -1, -45, 34, 76,
+	37, -6, -46, 20, 4, 4, -11, -11, -11, -11,
 	-11, -11, -11, -11, -11, -11, -11, -11, -11, -11,
-	-11, -11, -41, -11, 4, -15, 39, -34, -32, -3,
-	-10, -9, 4, 5, 75, 70, -55, 75, -9, -11,
-	4, 19, 76, 74, -55, 75, 69, -55, 75, 55,
-	74, 40, -11, -11, 39, 4, 69, 74, 71, 75,
-	75, -28, -32, 70, 75, -28, -31, -35, 40, 70,
-	75, 69, 75, 71, 75, 74, 40, 76, -4, 40,
-	-38, 4, 40, 77, -28, -11, 40, -28, 69, -55,
-	75, -16, 24, -1, 70, 75, 70, 70, 75, -42,
-	-11, 71, -39, 4, 76, -56, 76, -11, 69, -23,
-	35, -11, 69, -23, -11, -25, -26, -46, 23, -11,
-	-11, 70, 70, -20, -28, -33, 4, 71, -29, 70,
-	-28, -46, -47, 35, -48, -46, -46, -5, -28, 77,
-	75, -3, -11, 77, -34, 70, -11, -15, 23, -11,
-	21, -28, -10, -28, -3, -55, 75, 71, -55, 35,
-	75, -11, -55, 74, 69, 75, 22, -11, 70, 71,
-	-21, 26, 69, -28, 70, -46, 70, -4, 70, 77,
-	-11, 4, 70, -11, 70, -11, -56, -11, 21, 10,
-	75, -55, 70, -11, 69, -11, -54, 76, 69, -11,
-	-26, -11, -46, 76, -11, -54, 77, -28, -15, 71,
-	-11, 70, -54, 74, -7, -11, 77, -16, -18, -11,
-	-19, -2, -11, -56, -11,
+	-11, -11, -11, -11, -41, -11, 4, -15, 39, -34,
+	-32, -3, -10, -9, 4, 5, 75, 70, -55, 75,
+	55, -9, -11, 4, 76, 74, -55, 75, 69, -55,
+	75, 55, 74, 40, -11, -11, 39, 4, 69, 74,
+	71, 75, 75, -28, -32, 70, 75, -28, -31, -35,
+	40, 70, 75, 69, 75, 71, 75, 74, 40, 76,
+	-4, 40, -38, 4, 40, 77, -28, -11, 40, -28,
+	69, -55, 75, -16, 24, -1, 70, 75, 70, 70,
+	75, -42, -11, 71, -39, 4, -40, 76, -56, 76,
+	-11, 69, -23, 35, -11, 69, -23, -11, -25, -26,
+	-46, 23, -11, -11, 70, 70, -20, -28, -33, 4,
+	71, -29, 70, -28, -46, -47, 35, -48, -46, -46,
+	-5, -28, 77, 75, -3, -11, 77, -34, 70, -11,
+	-15, 23, -11, 21, -28, -10, -28, -3, -55, 75,
+	-55, 71, -55, 35, 75, -11, -55, 74, 69, 75,
+	22, -11, 70, 71, -21, 26, 69, -28, 70, -46,
+	70, -4, 70, 77, -11, 4, 70, -11, 70, -11,
+	-56, -11, 21, 10, 75, -55, 70, -11, 71, 69,
+	-11, -54, 76, 69, -11, -26, -11, -46, 76, -11,
+	-54, 77, -28, -15, 71, -11, 70, -54, 74, -7,
+	-11, 77, -16, -18, -11, -19, -2, -11, -56, -11,
 }
 
 var yyDef = [...]int{
-	0, -2, 162, 54, 0, 0, 0, 0, 164, 0,
+	0, -2, 163, 54, 0, 0, 0, 0, 165, 0,
 	0, 0, 80, 0, 99, 0, 0, 107, 108, 109,
-	110, 0, 0, 0, 0, 0, 149, 0, 127, 0,
+	110, 0, 0, 0, 0, 0, 150, 0, 128, 0,
 	0, 0, 0, 8, 9, 10, 11, 12, 13, 14,
 	0, 0, 0, 0, 0, 21, 6, 22, 0, 0,
-	36, 37, 0, 0, 0, 0, 1, -2, 163, 2,
+	36, 37, 0, 0, 0, 0, 1, -2, 164, 2,
 	0, 65, 66, 0, 0, 0, 0, 0, 3, 0,
 	0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
 	0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
-	103, 104, 0, 58, 0, 0, 169, 0, 145, 0,
-	147, 169, 0, 169, 150, 124, 0, 0, 0, 4,
-	0, 0, 0, 29, 0, 26, 0, 0, 35, 33,
-	31, 0, 0, 25, 5, 0, 47, 48, 0, 43,
-	0, 50, 52, 41, 161, 0, 0, 55, 0, 68,
-	0, 0, 0, 0, 81, 82, 83, 84, 85, 86,
-	87, 88, 89, 90, 91, 92, 93, 94, 95, 96,
-	97, 0, 169, 156, 102, 0, 54, 0, 160, 0,
-	59, 61, 62, 0, 0, 126, 0, 170, 0, 143,
-	108, 0, 56, 0, 0, 170, 120, 0, 170, 0,
-	0, 0, 0, 0, 133, 7, 15, 0, 17, 0,
-	0, 28, 0, 19, 0, 32, 0, 23, 0, 38,
-	0, 39, 0, 40, 0, 0, 0, 165, 167, 63,
-	0, 78, 58, 0, 0, 69, 0, 72, 100, 0,
-	170, 98, 0, 0, 0, 0, 0, 114, 58, 169,
-	154, 117, 146, 147, 57, 0, 144, 148, 118, 169,
-	0, 151, 121, 169, 0, 0, 139, 0, 0, 158,
-	0, 128, 129, 0, 0, 30, 27, 18, 34, 0,
-	0, 49, 44, 45, 51, 53, 0, 0, 75, 0,
-	0, 0, 73, 0, 0, 101, 157, 105, 0, 143,
-	0, 0, 60, 0, 169, 0, 170, 130, 0, 0,
-	170, 171, 0, 0, 123, 0, 0, 142, 125, 132,
-	134, 0, 16, 20, 24, 46, 42, 0, 168, 0,
-	76, 79, 171, 74, 0, 0, 0, 111, 0, 113,
-	170, 0, 116, 155, 119, 171, 152, 172, 122, 159,
-	140, 141, 0, 64, 77, 0, 0, 0, 0, 131,
-	112, 115, 153, 0, 67, 70, 0, 106, 143, 136,
-	137, 0, 71, 135, 138,
+	103, 104, 0, 58, 0, 0, 170, 0, 0, 146,
+	0, -2, 0, 170, 0, 170, 151, 125, 0, 0,
+	0, 4, 0, 0, 0, 29, 0, 26, 0, 0,
+	35, 33, 31, 0, 0, 25, 5, 0, 47, 48,
+	0, 43, 0, 50, 52, 41, 162, 0, 0, 55,
+	0, 68, 0, 0, 0, 0, 81, 82, 83, 84,
+	85, 86, 87, 88, 89, 90, 91, 92, 93, 94,
+	95, 96, 97, 0, 170, 157, 102, 0, 54, 0,
+	161, 0, 59, 61, 62, 0, 0, 127, 0, 171,
+	0, 0, 144, 108, 56, 0, 0, 171, 121, 0,
+	171, 0, 0, 0, 0, 0, 134, 7, 15, 0,
+	17, 0, 0, 28, 0, 19, 0, 32, 0, 23,
+	0, 38, 0, 39, 0, 40, 0, 0, 0, 166,
+	168, 63, 0, 78, 58, 0, 0, 69, 0, 72,
+	100, 0, 171, 98, 0, 0, 0, 0, 0, 114,
+	58, 170, 155, 117, 147, 148, 170, 57, 0, 145,
+	149, 119, 170, 0, 152, 122, 170, 0, 0, 140,
+	0, 0, 159, 0, 129, 130, 0, 0, 30, 27,
+	18, 34, 0, 0, 49, 44, 45, 51, 53, 0,
+	0, 75, 0, 0, 0, 73, 0, 0, 101, 158,
+	105, 0, 144, 0, 0, 60, 0, 170, 0, 171,
+	0, 131, 0, 0, 171, 172, 0, 0, 124, 0,
+	0, 143, 126, 133, 135, 0, 16, 20, 24, 46,
+	42, 0, 169, 0, 76, 79, 172, 74, 0, 0,
+	0, 111, 0, 113, 171, 0, 116, 156, 118, 120,
+	172, 153, 173, 123, 160, 141, 142, 0, 64, 77,
+	0, 0, 0, 0, 132, 112, 115, 154, 0, 67,
+	70, 0, 106, 144, 137, 138, 0, 71, 136, 139,
 }
 
 var yyTok1 = [...]int{
@@ -1009,11 +1022,11 @@ yydefault:
 			// "()" is unit
 			case 0:
 				yyVAL.typ = types.Unit
-				// "(type)" and "(name type)" get collapsed with
+			// "(type)" and "(name type)" get collapsed with
 			// (optional) label
 			case 1:
 				yyVAL.typ = types.Labeled(yyDollar[2].typfields[0].Name, yyDollar[2].typfields[0].T)
-				// a regular tuple must have at least two members
+			// a regular tuple must have at least two members
 			default:
 				yyVAL.typ = types.Tuple(yyDollar[2].typfields...)
 			}
@@ -1668,44 +1681,50 @@ yydefault:
 			yyVAL.expr = &Expr{Position: yyDollar[1].pos.Position, Comment: yyDollar[1].pos.comment, Kind: ExprStruct, Fields: yyDollar[2].exprfields}
 		}
 	case 118:
-		yyDollar = yyS[yypt-4 : yypt+1]
+		yyDollar = yyS[yypt-6 : yypt+1]
 //line reflow.y:585
 		{
-			yyVAL.expr = &Expr{Position: yyDollar[1].pos.Position, Comment: yyDollar[1].pos.comment, Kind: ExprList, List: yyDollar[2].exprlist}
+			yyVAL.expr = &Expr{Position: yyDollar[1].pos.Position, Comment: yyDollar[1].pos.comment, Kind: ExprStruct, Left: yyDollar[2].expr, Fields: yyDollar[4].exprfields}
 		}
 	case 119:
-		yyDollar = yyS[yypt-6 : yypt+1]
+		yyDollar = yyS[yypt-4 : yypt+1]
 //line reflow.y:587
+		{
+			yyVAL.expr = &Expr{Position: yyDollar[1].pos.Position, Comment: yyDollar[1].pos.comment, Kind: ExprList, List: yyDollar[2].exprlist}
+		}
+	case 120:
+		yyDollar = yyS[yypt-6 : yypt+1]
+//line reflow.y:589
 		{
 			yyVAL.expr = &Expr{Position: yyDollar[1].pos.Position, Comment: yyDollar[1].pos.comment, Kind: ExprList, List: yyDollar[2].exprlist}
 			for _, list := range yyDollar[4].exprlist {
 				yyVAL.expr = &Expr{Position: yyDollar[1].pos.Position, Kind: ExprBinop, Op: "+", Left: yyVAL.expr, Right: list}
 			}
 		}
-	case 120:
+	case 121:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line reflow.y:594
+//line reflow.y:596
 		{
 			yyVAL.expr = &Expr{Position: yyDollar[1].pos.Position, Comment: yyDollar[1].pos.comment, Kind: ExprMap}
 		}
-	case 121:
+	case 122:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line reflow.y:596
+//line reflow.y:598
 		{
 			yyVAL.expr = &Expr{Position: yyDollar[1].pos.Position, Comment: yyDollar[1].pos.comment, Kind: ExprMap, Map: yyDollar[2].exprmap}
 		}
-	case 122:
+	case 123:
 		yyDollar = yyS[yypt-6 : yypt+1]
-//line reflow.y:598
+//line reflow.y:600
 		{
 			yyVAL.expr = &Expr{Position: yyDollar[1].pos.Position, Comment: yyDollar[1].pos.comment, Kind: ExprMap, Map: yyDollar[2].exprmap}
 			for _, list := range yyDollar[4].exprlist {
 				yyVAL.expr = &Expr{Position: yyDollar[1].pos.Position, Kind: ExprBinop, Op: "+", Left: list, Right: yyVAL.expr}
 			}
 		}
-	case 123:
+	case 124:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line reflow.y:605
+//line reflow.y:607
 		{
 			yyVAL.expr = &Expr{
 				Position:     yyDollar[1].pos.Position,
@@ -1715,232 +1734,232 @@ yydefault:
 				ComprClauses: yyDollar[4].comprclauses,
 			}
 		}
-	case 124:
+	case 125:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line reflow.y:615
+//line reflow.y:617
 		{
 			yyVAL.expr = &Expr{Position: yyDollar[1].pos.Position, Comment: yyDollar[1].pos.comment, Kind: ExprVariant, Ident: yyDollar[2].expr.Ident}
 		}
-	case 125:
+	case 126:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line reflow.y:617
+//line reflow.y:619
 		{
 			yyVAL.expr = &Expr{Position: yyDollar[1].pos.Position, Comment: yyDollar[1].pos.comment, Kind: ExprVariant, Ident: yyDollar[2].expr.Ident, Left: yyDollar[4].expr}
 		}
-	case 126:
+	case 127:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line reflow.y:619
+//line reflow.y:621
 		{
 			yyVAL.expr = yyDollar[2].expr
 		}
-	case 128:
+	case 129:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line reflow.y:622
+//line reflow.y:624
 		{
 			yyVAL.expr = &Expr{Position: yyDollar[1].expr.Position, Comment: yyDollar[1].expr.Comment, Kind: ExprBuiltin, Op: "int", Fields: []*FieldExpr{{Expr: yyDollar[3].expr}}}
 		}
-	case 129:
+	case 130:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line reflow.y:624
+//line reflow.y:626
 		{
 			yyVAL.expr = &Expr{Position: yyDollar[1].expr.Position, Comment: yyDollar[1].expr.Comment, Kind: ExprBuiltin, Op: "float", Fields: []*FieldExpr{{Expr: yyDollar[3].expr}}}
 		}
-	case 130:
+	case 131:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line reflow.y:628
+//line reflow.y:630
 		{
 			yyVAL.expr = &Expr{Position: yyDollar[1].pos.Position, Comment: yyDollar[1].pos.comment, Kind: ExprBlock, Decls: yyDollar[2].decllist, Left: yyDollar[3].expr}
 		}
-	case 131:
+	case 132:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line reflow.y:632
+//line reflow.y:634
 		{
 			yyVAL.expr = &Expr{Position: yyDollar[1].pos.Position, Comment: yyDollar[1].pos.comment, Kind: ExprBlock, Decls: yyDollar[2].decllist, Left: yyDollar[3].expr}
 		}
-	case 132:
+	case 133:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line reflow.y:636
+//line reflow.y:638
 		{
 			yyVAL.expr = &Expr{Position: yyDollar[1].pos.Position, Comment: yyDollar[1].pos.comment, Kind: ExprSwitch, Left: yyDollar[2].expr, CaseClauses: yyDollar[4].caseclauses}
 		}
-	case 133:
+	case 134:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line reflow.y:639
+//line reflow.y:641
 		{
 			yyVAL.caseclauses = nil
 		}
-	case 134:
+	case 135:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line reflow.y:641
+//line reflow.y:643
 		{
 			yyVAL.caseclauses = append(yyDollar[1].caseclauses, yyDollar[2].caseclause)
 		}
-	case 135:
+	case 136:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line reflow.y:645
+//line reflow.y:647
 		{
 			yyVAL.caseclause = &CaseClause{Position: yyDollar[1].pos.Position, Comment: yyDollar[1].pos.comment, Pat: yyDollar[2].pat, Expr: yyDollar[4].expr}
 		}
-	case 138:
+	case 139:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line reflow.y:651
+//line reflow.y:653
 		{
 			yyVAL.expr = &Expr{Kind: ExprBlock, Decls: yyDollar[1].decllist, Left: yyDollar[2].expr}
 		}
-	case 139:
+	case 140:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line reflow.y:655
+//line reflow.y:657
 		{
 			yyVAL.comprclauses = []*ComprClause{yyDollar[1].comprclause}
 		}
-	case 140:
+	case 141:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line reflow.y:657
+//line reflow.y:659
 		{
 			yyVAL.comprclauses = append(yyDollar[1].comprclauses, yyDollar[3].comprclause)
 		}
-	case 141:
+	case 142:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line reflow.y:661
+//line reflow.y:663
 		{
 			yyVAL.comprclause = &ComprClause{Kind: ComprEnum, Pat: yyDollar[1].pat, Expr: yyDollar[3].expr}
 		}
-	case 142:
+	case 143:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line reflow.y:663
+//line reflow.y:665
 		{
 			yyVAL.comprclause = &ComprClause{Kind: ComprFilter, Expr: yyDollar[2].expr}
 		}
-	case 145:
+	case 146:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line reflow.y:670
+//line reflow.y:672
 		{
 			yyVAL.exprfields = []*FieldExpr{yyDollar[1].exprfield}
 		}
-	case 146:
+	case 147:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line reflow.y:672
+//line reflow.y:674
 		{
 			yyVAL.exprfields = append(yyDollar[1].exprfields, yyDollar[3].exprfield)
 		}
-	case 147:
+	case 148:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line reflow.y:676
+//line reflow.y:678
 		{
 			yyVAL.exprfield = &FieldExpr{Name: yyDollar[1].expr.Ident, Expr: &Expr{Position: yyDollar[1].expr.Position, Kind: ExprIdent, Ident: yyDollar[1].expr.Ident}}
 		}
-	case 148:
+	case 149:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line reflow.y:678
+//line reflow.y:680
 		{
 			yyVAL.exprfield = &FieldExpr{Name: yyDollar[1].expr.Ident, Expr: yyDollar[3].expr}
 		}
-	case 149:
+	case 150:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line reflow.y:681
+//line reflow.y:683
 		{
 			yyVAL.exprlist = nil
 		}
-	case 150:
+	case 151:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line reflow.y:683
+//line reflow.y:685
 		{
 			yyVAL.exprlist = []*Expr{yyDollar[1].expr}
 		}
-	case 151:
+	case 152:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line reflow.y:685
+//line reflow.y:687
 		{
 			yyVAL.exprlist = append(yyDollar[1].exprlist, yyDollar[3].expr)
 		}
-	case 152:
+	case 153:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line reflow.y:689
+//line reflow.y:691
 		{
 			yyVAL.exprlist = []*Expr{yyDollar[2].expr}
 		}
-	case 153:
+	case 154:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line reflow.y:691
+//line reflow.y:693
 		{
 			yyVAL.exprlist = append(yyDollar[1].exprlist, yyDollar[3].expr)
 		}
-	case 154:
+	case 155:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line reflow.y:695
+//line reflow.y:697
 		{
 			yyVAL.exprfields = []*FieldExpr{{Expr: yyDollar[1].expr}}
 		}
-	case 155:
+	case 156:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line reflow.y:697
+//line reflow.y:699
 		{
 			yyVAL.exprfields = append(yyDollar[1].exprfields, &FieldExpr{Expr: yyDollar[3].expr})
 		}
-	case 156:
+	case 157:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line reflow.y:701
+//line reflow.y:703
 		{
 			yyVAL.exprfields = []*FieldExpr{{Expr: yyDollar[1].expr}}
 		}
-	case 157:
+	case 158:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line reflow.y:703
+//line reflow.y:705
 		{
 			yyVAL.exprfields = append(yyDollar[1].exprfields, &FieldExpr{Expr: yyDollar[3].expr})
 		}
-	case 158:
+	case 159:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line reflow.y:707
+//line reflow.y:709
 		{
 			yyVAL.exprmap = map[*Expr]*Expr{yyDollar[1].expr: yyDollar[3].expr}
 		}
-	case 159:
+	case 160:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line reflow.y:709
+//line reflow.y:711
 		{
 			yyVAL.exprmap = yyDollar[1].exprmap
 			yyVAL.exprmap[yyDollar[3].expr] = yyDollar[5].expr
 		}
-	case 161:
+	case 162:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line reflow.y:720
+//line reflow.y:722
 		{
 			yyVAL.module = &ModuleImpl{Keyspace: yyDollar[1].expr, ParamDecls: yyDollar[2].decllist, Decls: yyDollar[3].decllist}
 		}
-	case 162:
+	case 163:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line reflow.y:723
+//line reflow.y:725
 		{
 			yyVAL.expr = nil
 		}
-	case 163:
+	case 164:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line reflow.y:725
+//line reflow.y:727
 		{
 			yyVAL.expr = yyDollar[2].expr
 		}
-	case 164:
+	case 165:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line reflow.y:728
+//line reflow.y:730
 		{
 			yyVAL.decllist = nil
 		}
-	case 165:
+	case 166:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line reflow.y:730
+//line reflow.y:732
 		{
 			yyVAL.decllist = append(yyDollar[1].decllist, yyDollar[2].decllist...)
 		}
-	case 166:
+	case 167:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line reflow.y:733
+//line reflow.y:735
 		{
 			yyVAL.decllist = nil
 		}
-	case 167:
+	case 168:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line reflow.y:735
+//line reflow.y:737
 		{
 			yyVAL.decllist = yyDollar[2].decllist
 			for _, d := range yyVAL.decllist {
@@ -1949,9 +1968,9 @@ yydefault:
 				}
 			}
 		}
-	case 168:
+	case 169:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line reflow.y:744
+//line reflow.y:746
 		{
 			yyVAL.decllist = yyDollar[3].decllist
 		}
@@ -0,0 +1,251 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package syntax
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grailbio/base/digest"
+	"github.com/grailbio/reflow"
+)
+
+// remoteTimeout is the timeout for fetching a remote module.
+const remoteTimeout = time.Minute
+
+// A RemoteFetcher fetches the source of the remote module named by
+// the provided URL. It also returns the version (e.g., a git commit
+// or an object's entity tag) of the fetched source, if it is known,
+// so that bundles record exactly which sources they embed.
+type RemoteFetcher func(ctx context.Context, rawurl string) (source []byte, version string, err error)
+
+var (
+	remoteMu       sync.Mutex
+	remoteFetchers = map[string]RemoteFetcher{
+		"http":  fetchHTTP,
+		"https": fetchHTTP,
+		"git":   fetchGit,
+	}
+)
+
+// RegisterRemote registers the fetcher of remote modules named by
+// URLs of the given scheme, replacing any previously registered one.
+// Modules are fetched over HTTP(S) and from git repositories by
+// default; other schemes (e.g., "s3") are registered by the tools
+// which have access to the corresponding storage.
+func RegisterRemote(scheme string, fetch RemoteFetcher) {
+	remoteMu.Lock()
+	remoteFetchers[scheme] = fetch
+	remoteMu.Unlock()
+}
+
+// remoteScheme returns the URL scheme of the module path, or "" if
+// the path does not name a remote module.
+func remoteScheme(path string) string {
+	i := strings.Index(path, "://")
+	if i <= 0 {
+		return ""
+	}
+	for _, c := range path[:i] {
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '+' || c == '-' || c == '.') {
+			return ""
+		}
+	}
+	return path[:i]
+}
+
+// isRemote tells whether the module path names a remote module,
+// i.e., one that is named by a URL (e.g., https://, git://, s3://)
+// and fetched. Remote modules (and the modules they import) are
+// embedded in bundles, so that bundles are self-contained.
+func isRemote(path string) bool {
+	return remoteScheme(path) != ""
+}
+
+// moduleDir returns the directory of the module with the given path,
+// against which its relative imports are resolved.
+func moduleDir(p string) string {
+	if g, ok := parseGitPath(p); ok {
+		g.path = path.Dir(g.path)
+		return g.String()
+	}
+	if isRemote(p) {
+		return p[:strings.LastIndex(p, "/")]
+	}
+	return filepath.Dir(p)
+}
+
+// joinModulePath resolves the relative module path rel against the
+// module directory dir.
+func joinModulePath(dir, rel string) string {
+	if g, ok := parseGitPath(dir); ok {
+		g.path = path.Join(g.path, rel)
+		return g.String()
+	}
+	if !isRemote(dir) {
+		return filepath.Join(dir, rel)
+	}
+	base, err := url.Parse(dir + "/")
+	if err != nil {
+		return dir + "/" + strings.TrimPrefix(rel, "./")
+	}
+	ref, err := url.Parse(rel)
+	if err != nil {
+		return dir + "/" + strings.TrimPrefix(rel, "./")
+	}
+	return base.ResolveReference(ref).String()
+}
+
+// moduleFile returns the module path stripped of its git revision,
+// if any, so that the module's file name may be examined.
+func moduleFile(p string) string {
+	if g, ok := parseGitPath(p); ok {
+		g.ref = ""
+		return g.String()
+	}
+	return p
+}
+
+// remoteSource fetches the source of the remote module at the given
+// URL, and returns it along with its digest and version.
+func remoteSource(rawurl string) (b []byte, d digest.Digest, version string, err error) {
+	scheme := remoteScheme(rawurl)
+	remoteMu.Lock()
+	fetch := remoteFetchers[scheme]
+	remoteMu.Unlock()
+	if fetch == nil {
+		return nil, d, "", fmt.Errorf("fetch %s: modules cannot be imported from %s URLs", rawurl, scheme)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), remoteTimeout)
+	defer cancel()
+	if b, version, err = fetch(ctx, rawurl); err != nil {
+		return nil, d, "", fmt.Errorf("fetch %s: %v", rawurl, err)
+	}
+	return b, reflow.Digester.FromBytes(b), version, nil
+}
+
+var remoteClient = &http.Client{Timeout: remoteTimeout}
+
+// fetchHTTP fetches a module over HTTP(S). Its version is the entity
+// tag provided by the server, if any.
+func fetchHTTP(ctx context.Context, rawurl string) ([]byte, string, error) {
+	req, err := http.NewRequest("GET", rawurl, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	resp, err := remoteClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("%s", resp.Status)
+	}
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	var version string
+	if etag := resp.Header.Get("ETag"); etag != "" {
+		version = "etag " + etag
+	}
+	return b, version, nil
+}
+
+// gitPath is a module path which names a module in a git
+// repository: git://host/repository//path@ref, where the revision
+// ref (a branch, tag or commit) is optional and defaults to the
+// repository's HEAD.
+type gitPath struct {
+	repo, path, ref string
+}
+
+// parseGitPath parses the git module path p.
+func parseGitPath(p string) (gitPath, bool) {
+	if !strings.HasPrefix(p, "git://") {
+		return gitPath{}, false
+	}
+	p = strings.TrimPrefix(p, "git://")
+	i := strings.Index(p, "//")
+	if i < 0 {
+		return gitPath{}, false
+	}
+	g := gitPath{repo: p[:i], path: p[i+2:]}
+	if j := strings.LastIndex(g.path, "@"); j >= 0 {
+		g.path, g.ref = g.path[:j], g.path[j+1:]
+	}
+	return g, true
+}
+
+// String returns the module path of g.
+func (g gitPath) String() string {
+	s := "git://" + g.repo + "//" + g.path
+	if g.ref != "" {
+		s += "@" + g.ref
+	}
+	return s
+}
+
+// gitRemote returns the URL from which the given repository is
+// fetched. Repositories are fetched over HTTPS, which (unlike the git
+// protocol) is supported by all hosting services.
+var gitRemote = func(repo string) string {
+	return "https://" + repo
+}
+
+// fetchGit fetches a module from a git repository, using the git
+// command. Only the requested revision is fetched. The module's
+// version is the commit to which the revision resolved.
+func fetchGit(ctx context.Context, rawurl string) ([]byte, string, error) {
+	g, ok := parseGitPath(rawurl)
+	if !ok || g.path == "" {
+		return nil, "", fmt.Errorf("invalid git module path: must be of the form git://host/repository//path[@revision]")
+	}
+	dir, err := ioutil.TempDir("", "reflow-git")
+	if err != nil {
+		return nil, "", err
+	}
+	defer os.RemoveAll(dir)
+	git := func(args ...string) ([]byte, error) {
+		var stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...)
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			return nil, fmt.Errorf("git %s: %v: %s", args[0], err, strings.TrimSpace(stderr.String()))
+		}
+		return out, nil
+	}
+	ref := g.ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+	if _, err := git("init", "-q"); err != nil {
+		return nil, "", err
+	}
+	if _, err := git("fetch", "-q", "--depth=1", gitRemote(g.repo), ref); err != nil {
+		return nil, "", err
+	}
+	commit, err := git("rev-parse", "FETCH_HEAD")
+	if err != nil {
+		return nil, "", err
+	}
+	b, err := git("show", "FETCH_HEAD:"+g.path)
+	if err != nil {
+		return nil, "", err
+	}
+	return b, "commit " + strings.TrimSpace(string(commit)), nil
+}
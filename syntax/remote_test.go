@@ -0,0 +1,193 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package syntax
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/grailbio/reflow/types"
+	"github.com/grailbio/reflow/values"
+)
+
+func TestJoinModulePath(t *testing.T) {
+	for _, c := range []struct{ dir, rel, want string }{
+		{"", "./a.rf", "a.rf"},
+		{"x/y", "./a.rf", "x/y/a.rf"},
+		{"https://example.com/lib", "./a.rf", "https://example.com/lib/a.rf"},
+		{"https://example.com/lib", "./sub/../b.rf", "https://example.com/lib/b.rf"},
+		{"s3://bucket/modules", "./x.rf", "s3://bucket/modules/x.rf"},
+		{"git://github.com/org/repo//pipelines@v1.2", "./align.rf", "git://github.com/org/repo//pipelines/align.rf@v1.2"},
+		{"git://github.com/org/repo//pipelines", "../lib/util.rf", "git://github.com/org/repo//lib/util.rf"},
+	} {
+		if got := joinModulePath(c.dir, c.rel); got != c.want {
+			t.Errorf("joinModulePath(%q, %q): got %v, want %v", c.dir, c.rel, got, c.want)
+		}
+	}
+	if got, want := moduleDir("https://example.com/lib/a.rf"), "https://example.com/lib"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := moduleDir("git://github.com/org/repo//pipelines/align.rf@v1.2"), "git://github.com/org/repo//pipelines@v1.2"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := moduleFile("git://github.com/org/repo//pipelines/align.rf@v1.2"), "git://github.com/org/repo//pipelines/align.rf"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	for _, path := range []string{"a.rf", "./x/a.rf", "/x/a.rf"} {
+		if isRemote(path) {
+			t.Errorf("%s: is remote", path)
+		}
+	}
+}
+
+func TestBundleRemote(t *testing.T) {
+	modules := map[string]string{
+		"/lib/hello.rf": `val Hello = make("./world.rf").World`,
+		"/lib/world.rf": `val World = "hello world"`,
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		src, ok := modules[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(src))
+	}))
+	sess := NewSession(nil)
+	if _, err := sess.Open(srv.URL + "/lib/hello.rf"); err != nil {
+		t.Fatal(err)
+	}
+	bundle := sess.Bundle()
+	if got, want := bundle.Remote(), []string{srv.URL + "/lib/hello.rf", srv.URL + "/lib/world.rf"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	var buf bytes.Buffer
+	if err := bundle.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	// The bundle must not depend on the remote modules.
+	srv.Close()
+	sess = NewSession(memorySourcer{"main.rfx": buf.Bytes()})
+	m, err := sess.Open("main.rfx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := m.Make(sess, sess.Values.Push())
+	if err != nil {
+		t.Fatal(err)
+	}
+	v = Force(v.(values.Module)["Hello"], types.String)
+	if got, want := v.(string), "hello world"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestBundleRemoteVersions(t *testing.T) {
+	RegisterRemote("testmod", func(ctx context.Context, rawurl string) ([]byte, string, error) {
+		switch rawurl {
+		case "testmod://lib/hello.rf":
+			return []byte(`val Hello = make("./world.rf").World`), "v1", nil
+		case "testmod://lib/world.rf":
+			return []byte(`val World = "hello world"`), "", nil
+		}
+		return nil, "", os.ErrNotExist
+	})
+	sess := NewSession(nil)
+	if _, err := sess.Open("testmod://lib/hello.rf"); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := sess.Bundle().WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	bundle, err := openBundle(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := bundle.Remote(), []string{"testmod://lib/hello.rf", "testmod://lib/world.rf"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for path, want := range map[string]string{"testmod://lib/hello.rf": "v1", "testmod://lib/world.rf": ""} {
+		if got := bundle.Version(path); got != want {
+			t.Errorf("%s: got %v, want %v", path, got, want)
+		}
+	}
+
+	if _, err := NewSession(nil).Open("unknown://lib/hello.rf"); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestFetchGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	git := func(args ...string) string {
+		t.Helper()
+		args = append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)
+		out, err := exec.Command("git", args...).Output()
+		if err != nil {
+			t.Fatalf("git %v: %v", args, err)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	git("init", "-q")
+	if err := os.MkdirAll(filepath.Join(dir, "pipelines"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	for name, src := range map[string]string{
+		"pipelines/align.rf": `val Align = make("./util.rf").Util`,
+		"pipelines/util.rf":  `val Util = "v1"`,
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	git("add", ".")
+	git("commit", "-q", "-m", "v1")
+	git("tag", "v1")
+	commit := git("rev-parse", "HEAD")
+	// Later changes are not visible at v1.
+	if err := ioutil.WriteFile(filepath.Join(dir, "pipelines/util.rf"), []byte(`val Util = "v2"`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	git("commit", "-q", "-a", "-m", "v2")
+
+	save := gitRemote
+	gitRemote = func(string) string { return "file://" + dir }
+	defer func() { gitRemote = save }()
+
+	sess := NewSession(nil)
+	m, err := sess.Open("git://example.com/repo//pipelines/align.rf@v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := m.Make(sess, sess.Values.Push())
+	if err != nil {
+		t.Fatal(err)
+	}
+	v = Force(v.(values.Module)["Align"], types.String)
+	if got, want := v.(string), "v1"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	bundle := sess.Bundle()
+	if got, want := bundle.Version("git://example.com/repo//pipelines/util.rf@v1"), "commit "+commit; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
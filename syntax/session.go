@@ -41,6 +41,9 @@ type Session struct {
 	path        string
 	modules     map[string]Module
 	srcByModule map[string][]byte
+	// versions stores the versions of the remote modules' sources,
+	// where known.
+	versions map[string]string
 
 	// Entrypoint is the first module opened.
 	entrypoint     Module
@@ -63,6 +66,7 @@ func NewSession(src Sourcer) *Session {
 	s := &Session{
 		modules:     map[string]Module{},
 		srcByModule: make(map[string][]byte),
+		versions:    make(map[string]string),
 		images:      map[string]bool{},
 		src:         src,
 	}
@@ -88,21 +92,24 @@ func (s *Session) Open(path string) (Module, error) {
 		return nil, errors.New("nil session")
 	}
 	if strings.HasPrefix(path, "./") {
-		path = filepath.Join(s.path, path)
+		path = joinModulePath(s.path, path)
 	}
 	if m, ok := s.modules[path]; ok {
 		return m, nil
 	}
-	source, srcDig, err := s.src.Source(path)
+	source, srcDig, version, err := s.source(path)
 	if err != nil {
 		return nil, err
 	}
+	if version != "" {
+		s.versions[path] = version
+	}
 	var (
 		mod              Module
-		modulePath       = filepath.Dir(path)
+		modulePath       = moduleDir(path)
 		assignEntrypoint = s.entrypoint == nil
 	)
-	switch ext := filepath.Ext(path); ext {
+	switch ext := filepath.Ext(moduleFile(path)); ext {
 	default:
 		return nil, fmt.Errorf("unknown module extension %s", ext)
 	case ".rf": // Regular reflow module.
@@ -115,14 +122,14 @@ func (s *Session) Open(path string) (Module, error) {
 			return nil, err
 		}
 		save := s.path
-		s.path = filepath.Dir(path)
+		s.path = moduleDir(path)
 		if err := lx.Module.Init(s, s.Types); err != nil {
 			s.path = save
 			return nil, err
 		}
 		s.path = save
 		// Label each toplevel declaration with the module name.
-		base := filepath.Base(path)
+		base := filepath.Base(moduleFile(path))
 		ext := filepath.Ext(base)
 		base = strings.TrimSuffix(base, ext)
 		for _, decl := range lx.Module.Decls {
@@ -191,6 +198,12 @@ func (s *Session) Bundle() *Bundle {
 	bundle.files = make(map[digest.Digest][]byte)
 	bundle.manifest.Files = make(map[string]digest.Digest)
 	for path, mod := range s.modules {
+		if version, ok := s.versions[path]; ok {
+			if bundle.manifest.Versions == nil {
+				bundle.manifest.Versions = make(map[string]string)
+			}
+			bundle.manifest.Versions[path] = version
+		}
 		p, ok := s.srcByModule[path]
 		if !ok || p == nil {
 			panic(fmt.Sprintf("missing source for path %s", path))
@@ -207,6 +220,17 @@ func (s *Session) Bundle() *Bundle {
 	return bundle
 }
 
+// source returns the source of the module at the given path, along
+// with its digest and, for remote modules, the version of the source,
+// if known.
+func (s *Session) source(path string) ([]byte, digest.Digest, string, error) {
+	if _, ok := s.src.(filesystem); ok && isRemote(path) {
+		return remoteSource(path)
+	}
+	b, d, err := s.src.Source(path)
+	return b, d, "", err
+}
+
 // SeeImage records an image name. Call during expression evaluation.
 func (s *Session) SeeImage(image string) {
 	s.mu.Lock()
@@ -264,11 +288,16 @@ type Sourcer interface {
 }
 
 // Filesystem is a Sourcer that reads from the local file system.
+// Remote modules, named by URLs, are fetched (see RegisterRemote).
 var Filesystem Sourcer = filesystem{}
 
 type filesystem struct{}
 
 func (filesystem) Source(path string) (b []byte, d digest.Digest, err error) {
+	if isRemote(path) {
+		b, d, _, err = remoteSource(path)
+		return
+	}
 	b, err = ioutil.ReadFile(path)
 	if err != nil {
 		return
@@ -14,6 +14,7 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"time"
 	"unicode/utf8"
 
 	"github.com/grailbio/base/digest"
@@ -267,6 +268,34 @@ func Stdlib() (*types.Env, *values.Env) {
 				}, nil
 			},
 		},
+		{
+			Id: "glob",
+			Doc: "glob returns the subdirectory of the paths in dir which match the given " +
+				"glob pattern (see path.Match). Patterns without a slash are matched against " +
+				"the last element of each path.",
+			Type: types.Func(types.Dir,
+				&types.Field{Name: "dir", T: types.Dir},
+				&types.Field{Name: "pattern", T: types.String}),
+			Do: func(loc values.Location, args []values.T) (values.T, error) {
+				return globDir(args[0].(values.Dir), args[1].(string))
+			},
+		},
+		{
+			Id: "partition",
+			Doc: "partition splits dir into n directories (partitions) of nearly equal " +
+				"numbers of paths, in path order. Directories with fewer than n paths are " +
+				"split into one partition per path.",
+			Type: types.Func(types.List(types.Dir),
+				&types.Field{Name: "dir", T: types.Dir},
+				&types.Field{Name: "n", T: types.Int}),
+			Do: func(loc values.Location, args []values.T) (values.T, error) {
+				dir, n := args[0].(values.Dir), args[1].(*big.Int)
+				if n.Sign() <= 0 || !n.IsInt64() {
+					return nil, fmt.Errorf("partition: number of partitions %s must be positive", n)
+				}
+				return partitionDir(dir, n.Int64()), nil
+			},
+		},
 	}
 
 	for _, f := range funcs {
@@ -276,6 +305,9 @@ func Stdlib() (*types.Env, *values.Env) {
 	define("MiB", "one mebibyte", types.Int, big.NewInt(1<<20))
 	define("GiB", "one gibibyte", types.Int, big.NewInt(1<<30))
 	define("TiB", "one tebibyte", types.Int, big.NewInt(1<<40))
+	define("second", "one second, in seconds", types.Int, big.NewInt(1))
+	define("minute", "one minute, in seconds", types.Int, big.NewInt(60))
+	define("hour", "one hour, in seconds", types.Int, big.NewInt(60*60))
 
 	return tenv, venv
 }
@@ -390,6 +422,84 @@ func coerceFilesetToDir(v values.T) (values.T, error) {
 	return mDir.Dir(), nil
 }
 
+var autoShardsDigest = reflow.Digester.FromString("grail.com/reflow/syntax.autoShards")
+
+// shardDir splits dir into shards of (up to) size paths each, in path order.
+func shardDir(dir values.Dir, size int) values.List {
+	var (
+		shards values.List
+		shard  = new(values.MutableDir)
+		n      int
+	)
+	for scan := dir.Scan(); scan.Scan(); {
+		shard.Set(scan.Path(), scan.File())
+		n++
+		if n == size {
+			shards = append(shards, shard.Dir())
+			shard, n = new(values.MutableDir), 0
+		}
+	}
+	if n > 0 {
+		shards = append(shards, shard.Dir())
+	}
+	return shards
+}
+
+// globDir returns the subdirectory of dir whose paths match pattern.
+// Patterns without a slash are matched against the paths' last
+// elements.
+func globDir(dir values.Dir, pattern string) (values.Dir, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return values.Dir{}, fmt.Errorf("glob: invalid pattern %q: %v", pattern, err)
+	}
+	base := !strings.Contains(pattern, "/")
+	var matched values.MutableDir
+	for scan := dir.Scan(); scan.Scan(); {
+		name := scan.Path()
+		if base {
+			name = path.Base(name)
+		}
+		// The pattern has been checked, so Match cannot fail.
+		if ok, _ := path.Match(pattern, name); ok {
+			matched.Set(scan.Path(), scan.File())
+		}
+	}
+	return matched.Dir(), nil
+}
+
+// partitionDir splits dir into (up to) n partitions, in path order,
+// whose sizes differ by at most one path.
+func partitionDir(dir values.Dir, n int64) values.List {
+	total := int64(dir.Len())
+	if total < n {
+		n = total
+	}
+	if n == 0 {
+		return values.List{}
+	}
+	var (
+		parts values.List
+		part  = new(values.MutableDir)
+		size  int64
+		i     int64
+	)
+	for scan := dir.Scan(); scan.Scan(); {
+		part.Set(scan.Path(), scan.File())
+		size++
+		// The first total%n partitions have one more path than the rest.
+		want := total / n
+		if i < total%n {
+			want++
+		}
+		if size == want {
+			parts = append(parts, part.Dir())
+			part, size = new(values.MutableDir), 0
+			i++
+		}
+	}
+	return parts
+}
+
 var dirsDecls = []*Decl{
 	SystemFunc{
 		Id:     "Groups",
@@ -424,6 +534,64 @@ var dirsDecls = []*Decl{
 			return m, nil
 		},
 	}.Decl(),
+	SystemFunc{
+		Id:     "Shards",
+		Module: "dirs",
+		Doc: "Shards splits a directory into a list of directories (shards), each " +
+			"containing (up to) the given number of paths, in path order.",
+		Type: types.Func(types.List(types.Dir),
+			&types.Field{Name: "dir", T: types.Dir},
+			&types.Field{Name: "size", T: types.Int}),
+		Do: func(loc values.Location, args []values.T) (values.T, error) {
+			dir, size := args[0].(values.Dir), args[1].(*big.Int)
+			if size.Sign() <= 0 {
+				return nil, fmt.Errorf("dirs.Shards: size %s must be positive", size)
+			}
+			return shardDir(dir, int(size.Int64())), nil
+		},
+	}.Decl(),
+	SystemFunc{
+		Id:     "AutoShards",
+		Module: "dirs",
+		Doc: "AutoShards splits a directory into a list of directories (shards), like Shards, " +
+			"but chooses the shard size so that an exec with the given ident processing a shard " +
+			"takes about the given target duration (eg, \"30m\"). The shard size is estimated " +
+			"at runtime from the per-file processing times of previous execs with the same ident " +
+			"(requires the predictor) and rounded down to a power of two; size is used if no such " +
+			"estimate can be made. The chosen size is recorded in the cache, so that subsequent " +
+			"evaluations of the same call produce the same shards.",
+		Type: types.Flow(types.Func(types.List(types.Dir),
+			&types.Field{Name: "dir", T: types.Dir},
+			&types.Field{Name: "ident", T: types.String},
+			&types.Field{Name: "target", T: types.String},
+			&types.Field{Name: "size", T: types.Int})),
+		Do: func(loc values.Location, args []values.T) (values.T, error) {
+			dir, ident, raw, size := args[0].(values.Dir), args[1].(string), args[2].(string), args[3].(*big.Int)
+			target, err := time.ParseDuration(raw)
+			if err != nil {
+				return nil, fmt.Errorf("dirs.AutoShards: invalid target duration %s: %v", raw, err)
+			}
+			if size.Sign() <= 0 {
+				return nil, fmt.Errorf("dirs.AutoShards: size %s must be positive", size)
+			}
+			dw := reflow.Digester.NewWriterShort()
+			digest.WriteDigest(dw, autoShardsDigest)
+			values.WriteDigest(dw, dir, types.Dir)
+			values.WriteDigest(dw, ident, types.String)
+			values.WriteDigest(dw, raw, types.String)
+			values.WriteDigest(dw, size, types.Int)
+			return &flow.Flow{
+				Op:         flow.Kctx,
+				FlowDigest: dw.Digest(),
+				Position:   loc.Position,
+				Ident:      loc.Ident,
+				Kctx: func(ctx flow.KContext, _ []values.T) *flow.Flow {
+					n := ctx.ShardSize(ident, target, int(size.Int64()))
+					return toFlow(shardDir(dir, n), types.List(types.Dir))
+				},
+			}, nil
+		},
+	}.Decl(),
 	SystemFunc{
 		Id:     "Make",
 		Module: "dirs",
@@ -865,6 +1033,118 @@ var stringsDecls = []*Decl{
 			return floatVal, err
 		},
 	}.Decl(),
+	SystemFunc{
+		Id:     "FormatInt",
+		Module: "strings",
+		Mode:   ModeForced,
+		Doc: "FormatInt formats an integer in decimal, padded with leading zeros to at least width digits " +
+			"(e.g., FormatInt(7, 3) is \"007\"), so that the strings of integers of up to width digits " +
+			"have the same length and sort in numeric order.",
+		Type: types.Func(types.String,
+			&types.Field{Name: "intVal", T: types.Int},
+			&types.Field{Name: "width", T: types.Int}),
+		Do: func(loc values.Location, args []values.T) (values.T, error) {
+			intVal, width := args[0].(*big.Int), args[1].(*big.Int)
+			w, err := formatWidth("strings.FormatInt", "width", width)
+			if err != nil {
+				return nil, err
+			}
+			digits := new(big.Int).Abs(intVal).String()
+			if n := w - len(digits); n > 0 {
+				digits = strings.Repeat("0", n) + digits
+			}
+			if intVal.Sign() < 0 {
+				digits = "-" + digits
+			}
+			return digits, nil
+		},
+	}.Decl(),
+	SystemFunc{
+		Id:     "FormatFloat",
+		Module: "strings",
+		Mode:   ModeForced,
+		Doc: "FormatFloat formats a float in decimal, without an exponent, with exactly precision " +
+			"digits after the decimal point (e.g., FormatFloat(0.5, 2) is \"0.50\"). Unlike FromFloat, " +
+			"whose precision is the number of significant digits, the format of the result does not " +
+			"depend on the magnitude of the float.",
+		Type: types.Func(types.String,
+			&types.Field{Name: "floatVal", T: types.Float},
+			&types.Field{Name: "precision", T: types.Int}),
+		Do: func(loc values.Location, args []values.T) (values.T, error) {
+			floatVal, precision := args[0].(*big.Float), args[1].(*big.Int)
+			prec, err := formatWidth("strings.FormatFloat", "precision", precision)
+			if err != nil {
+				return nil, err
+			}
+			return floatVal.Text('f', prec), nil
+		},
+	}.Decl(),
+	SystemFunc{
+		Id:     "PadLeft",
+		Module: "strings",
+		Doc: "PadLeft pads the string s on the left with the character pad to a length of at least " +
+			"width characters (e.g., PadLeft(\"7\", 3, \" \") is \"  7\").",
+		Type: types.Func(types.String,
+			&types.Field{Name: "s", T: types.String},
+			&types.Field{Name: "width", T: types.Int},
+			&types.Field{Name: "pad", T: types.String}),
+		Do: func(loc values.Location, args []values.T) (values.T, error) {
+			padding, err := formatPadding("strings.PadLeft", args)
+			if err != nil {
+				return nil, err
+			}
+			return padding + args[0].(string), nil
+		},
+	}.Decl(),
+	SystemFunc{
+		Id:     "PadRight",
+		Module: "strings",
+		Doc: "PadRight pads the string s on the right with the character pad to a length of at least " +
+			"width characters (e.g., PadRight(\"ab\", 4, \".\") is \"ab..\").",
+		Type: types.Func(types.String,
+			&types.Field{Name: "s", T: types.String},
+			&types.Field{Name: "width", T: types.Int},
+			&types.Field{Name: "pad", T: types.String}),
+		Do: func(loc values.Location, args []values.T) (values.T, error) {
+			padding, err := formatPadding("strings.PadRight", args)
+			if err != nil {
+				return nil, err
+			}
+			return args[0].(string) + padding, nil
+		},
+	}.Decl(),
+}
+
+// maxFormatWidth is the largest width (or precision) accepted by the
+// strings module's formatting functions.
+const maxFormatWidth = 1 << 10
+
+// formatWidth returns the width (or precision) argument w, named
+// name, of the formatting function fn; w must be between zero and
+// maxFormatWidth.
+func formatWidth(fn, name string, w *big.Int) (int, error) {
+	if w.Sign() < 0 || w.Cmp(big.NewInt(maxFormatWidth)) > 0 {
+		return 0, errors.Errorf("%s: %s %s must be between 0 and %d", fn, name, w, maxFormatWidth)
+	}
+	return int(w.Int64()), nil
+}
+
+// formatPadding returns the padding which the padding function fn,
+// given arguments (s, width, pad), adds to string s.
+func formatPadding(fn string, args []values.T) (string, error) {
+	s, pad := args[0].(string), args[2].(string)
+	width, err := formatWidth(fn, "width", args[1].(*big.Int))
+	if err != nil {
+		return "", err
+	}
+	if utf8.RuneCountInString(pad) != 1 {
+		return "", errors.Errorf("%s: pad %q must be a single character", fn, pad)
+	}
+	n := width - utf8.RuneCountInString(s)
+	if n <= 0 {
+		return "", nil
+	}
+	return strings.Repeat(pad, n), nil
 }
 
 var pathDecls = []*Decl{
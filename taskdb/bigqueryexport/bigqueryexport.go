@@ -0,0 +1,189 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+// Package bigqueryexport implements a taskdb.Exporter which streams
+// run summaries into a BigQuery table.
+package bigqueryexport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/grailbio/infra"
+	"github.com/grailbio/reflow/errors"
+	"github.com/grailbio/reflow/taskdb"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+// ProviderName is the name of the BigQuery exporter infra provider.
+const ProviderName = "bigqueryexport"
+
+// TokenEnv is the environment variable from which a (static) OAuth2
+// access token used to authenticate BigQuery API requests may be read.
+// If it is not set, Google application default credentials are used.
+const TokenEnv = "GOOGLE_OAUTH_ACCESS_TOKEN"
+
+const (
+	defaultEndpoint = "https://bigquery.googleapis.com/bigquery/v2"
+	// bigqueryScope is the OAuth2 scope required to insert rows.
+	bigqueryScope = "https://www.googleapis.com/auth/bigquery.insertdata"
+)
+
+func init() {
+	infra.Register(ProviderName, new(Exporter))
+}
+
+// Exporter exports run summaries to a BigQuery table using the
+// BigQuery streaming insert API. The table's schema must have the
+// columns taskdb.RunSummaryColumns; start_time and end_time are of
+// type TIMESTAMP.
+type Exporter struct {
+	// Project, Dataset and Table name the BigQuery table to which
+	// run summaries are exported.
+	Project, Dataset, Table string
+	// Endpoint is the BigQuery API endpoint.
+	Endpoint string
+	// Token is a static OAuth2 access token used to authenticate
+	// requests. Static tokens expire (typically after an hour), so
+	// TokenSource should be preferred for long-lived processes.
+	Token string
+	// TokenSource provides the (refreshed) OAuth2 access tokens used to
+	// authenticate requests. If nil, it is set by Init: to Token, if
+	// given, or else to Google application default credentials.
+	TokenSource oauth2.TokenSource
+	// Client is the HTTP client used to make requests.
+	Client *http.Client
+}
+
+// Help implements infra.Provider.
+func (Exporter) Help() string {
+	return "export run summaries to a BigQuery table (authenticated by Google application default credentials, or $" + TokenEnv + ")"
+}
+
+// Flags implements infra.Provider.
+func (e *Exporter) Flags(flags *flag.FlagSet) {
+	flags.StringVar(&e.Project, "project", "", "the GCP project of the BigQuery table")
+	flags.StringVar(&e.Dataset, "dataset", "", "the BigQuery dataset of the table")
+	flags.StringVar(&e.Table, "table", "", "the BigQuery table to which run summaries are exported")
+}
+
+// Init implements infra.Provider.
+func (e *Exporter) Init() error {
+	if e.Project == "" || e.Dataset == "" || e.Table == "" {
+		return fmt.Errorf("bigqueryexport: project, dataset and table must be specified")
+	}
+	if e.Endpoint == "" {
+		e.Endpoint = defaultEndpoint
+	}
+	if e.Token == "" {
+		e.Token = os.Getenv(TokenEnv)
+	}
+	if e.TokenSource == nil {
+		if e.Token != "" {
+			e.TokenSource = oauth2.StaticTokenSource(&oauth2.Token{AccessToken: e.Token})
+		} else {
+			ts, err := google.DefaultTokenSource(context.Background(), bigqueryScope)
+			if err != nil {
+				return fmt.Errorf("bigqueryexport: no credentials: %v (set $%s, or configure application default credentials)", err, TokenEnv)
+			}
+			e.TokenSource = ts
+		}
+	}
+	if e.Client == nil {
+		e.Client = &http.Client{Timeout: time.Minute}
+	}
+	return nil
+}
+
+type insertAllRow struct {
+	InsertID string                 `json:"insertId"`
+	JSON     map[string]interface{} `json:"json"`
+}
+
+type insertAllRequest struct {
+	Rows []insertAllRow `json:"rows"`
+}
+
+type insertAllResponse struct {
+	InsertErrors []struct {
+		Index  int `json:"index"`
+		Errors []struct {
+			Reason  string `json:"reason"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	} `json:"insertErrors"`
+}
+
+// row returns the BigQuery row for the run summary s.
+func row(s taskdb.RunSummary) insertAllRow {
+	values := s.Values()
+	r := insertAllRow{InsertID: s.RunID.ID(), JSON: make(map[string]interface{}, len(values))}
+	for i, v := range values {
+		if t, ok := v.(time.Time); ok {
+			v = t.UTC().Format(time.RFC3339Nano)
+		}
+		r.JSON[taskdb.RunSummaryColumns[i]] = v
+	}
+	return r
+}
+
+// ExportRun implements taskdb.Exporter. The run ID is used as the
+// insert ID, so that retried exports are deduplicated by BigQuery.
+func (e *Exporter) ExportRun(ctx context.Context, s taskdb.RunSummary) error {
+	b, err := json.Marshal(insertAllRequest{Rows: []insertAllRow{row(s)}})
+	if err != nil {
+		return errors.E("bigqueryexport", s.RunID.ID(), err)
+	}
+	u := fmt.Sprintf("%s/projects/%s/datasets/%s/tables/%s/insertAll",
+		e.Endpoint, url.PathEscape(e.Project), url.PathEscape(e.Dataset), url.PathEscape(e.Table))
+	req, err := http.NewRequest("POST", u, bytes.NewReader(b))
+	if err != nil {
+		return errors.E("bigqueryexport", s.RunID.ID(), err)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	// The token source refreshes tokens as they expire, so that exports
+	// from long-running processes remain authenticated.
+	tok, err := e.TokenSource.Token()
+	if err != nil {
+		return errors.E("bigqueryexport", s.RunID.ID(), errors.NotAllowed, err)
+	}
+	tok.SetAuthHeader(req)
+	resp, err := e.Client.Do(req)
+	if err != nil {
+		return errors.E("bigqueryexport", s.RunID.ID(), errors.Net, err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return errors.E("bigqueryexport", s.RunID.ID(), errors.Net, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return errors.E("bigqueryexport", s.RunID.ID(), errors.Errorf("%s: %s", resp.Status, bytes.TrimSpace(body)))
+	}
+	var r insertAllResponse
+	if err := json.Unmarshal(body, &r); err != nil {
+		return errors.E("bigqueryexport", s.RunID.ID(), err)
+	}
+	if len(r.InsertErrors) > 0 {
+		var msgs []string
+		for _, ie := range r.InsertErrors {
+			for _, e := range ie.Errors {
+				msgs = append(msgs, fmt.Sprintf("%s: %s", e.Reason, e.Message))
+			}
+		}
+		return errors.E("bigqueryexport", s.RunID.ID(), errors.Invalid, errors.New(strings.Join(msgs, "; ")))
+	}
+	return nil
+}
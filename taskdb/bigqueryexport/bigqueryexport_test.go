@@ -0,0 +1,116 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package bigqueryexport
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/grailbio/reflow/errors"
+	"github.com/grailbio/reflow/taskdb"
+	"golang.org/x/oauth2"
+)
+
+func TestExportRun(t *testing.T) {
+	var (
+		req      insertAllRequest
+		gotPath  string
+		gotAuth  string
+		response = `{}`
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotAuth = r.URL.Path, r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		_, _ = w.Write([]byte(response))
+	}))
+	defer srv.Close()
+
+	e := &Exporter{Project: "p", Dataset: "d", Table: "runs", Endpoint: srv.URL, Token: "secret"}
+	if err := e.Init(); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	s := taskdb.RunSummary{
+		RunID:       taskdb.NewRunID(),
+		User:        "test@example.com",
+		Start:       start,
+		End:         start.Add(time.Hour),
+		Execs:       4,
+		CachedExecs: 3,
+	}
+	ctx := context.Background()
+	if err := e.ExportRun(ctx, s); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := gotPath, "/projects/p/datasets/d/tables/runs/insertAll"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := gotAuth, "Bearer secret"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := len(req.Rows), 1; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	row := req.Rows[0]
+	if got, want := row.InsertID, s.RunID.ID(); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	for k, want := range map[string]interface{}{
+		"user":             "test@example.com",
+		"start_time":       "2021-03-04T05:06:07Z",
+		"end_time":         "2021-03-04T06:06:07Z",
+		"duration_seconds": 3600.0,
+		"cache_hit_rate":   0.75,
+	} {
+		if got := row.JSON[k]; got != want {
+			t.Errorf("%s: got %v, want %v", k, got, want)
+		}
+	}
+
+	response = `{"insertErrors": [{"index": 0, "errors": [{"reason": "invalid", "message": "no such field"}]}]}`
+	if err := e.ExportRun(ctx, s); !errors.Is(errors.Invalid, err) {
+		t.Errorf("expected invalid error, got %v", err)
+	}
+}
+
+// countingTokenSource returns a new token on each call, as a
+// refreshing token source does once tokens expire.
+type countingTokenSource int
+
+func (n *countingTokenSource) Token() (*oauth2.Token, error) {
+	*n++
+	return &oauth2.Token{AccessToken: fmt.Sprintf("token%d", *n)}, nil
+}
+
+func TestExportRunTokenSource(t *testing.T) {
+	var gotAuth []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = append(gotAuth, r.Header.Get("Authorization"))
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	e := &Exporter{Project: "p", Dataset: "d", Table: "runs", Endpoint: srv.URL, TokenSource: new(countingTokenSource)}
+	if err := e.Init(); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if err := e.ExportRun(ctx, taskdb.RunSummary{RunID: taskdb.NewRunID()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := gotAuth, []string{"Bearer token1", "Bearer token2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -12,7 +12,8 @@
 // buckets. Dynamodbtask also uses a bunch of secondary indices to help with run/task querying.
 // Schema:
 // run:  {ID, ID4, Type="run", Labels, Bundle, Args, Date, Keepalive, StartTime, EndTime, User}
-// task: {ID, ID4, Type="task", Labels, Date, Attempt, Keepalive, StartTime, EndTime, FlowID, Inspect, Error, ResultID, RunID, RunID4, AllocID, ImgCmdID, Ident, Stderr, Stdout, URI}
+// task: {ID, ID4, Type="task", Labels, Date, Attempt, Retry, CacheKeys, Keepalive, StartTime, EndTime, FlowID, Inspect, Error, ResultID, RunID, RunID4, AllocID, ImgCmdID, Ident, Stderr, Stdout, URI, ArrayID, ArrayIndex}
+// array: {ID, ID4, Type="array", Labels, CacheKeys, StartTime, FlowID, RunID, RunID4, ImgCmdID, Ident, Resources, ArraySize}
 // alloc: {ID, ID4, Type="alloc", PoolID, AllocID, Resources, URI, Keepalive, StartTime, EndTime}
 // pool: {ID, ID4, Type="pool", PoolID, PoolType, ClusterID.*, Resources, URI, Keepalive, StartTime, EndTime}
 // Note:
@@ -20,6 +21,8 @@
 // rows of type "alloc" will contain the digest of PoolID in this field (of the pool they belong to).
 // AllocID: Similarly, While rows of type "alloc" are expected to store the value Alloc.ID(),
 // rows of type "task" will contain the digest of Alloc.ID() (of the alloc where they are attempted).
+// ArrayID: Rows of type "task" of the tasks of task arrays contain the ID of their array's row, from
+// which their cache keys are read.
 // Indexes:
 // 1. Date-Keepalive-index - for time-based queries.
 // 2. RunID-index - for finding all tasks that belong to a run.
@@ -92,6 +95,14 @@ const (
 	PoolType
 	ClusterName
 	ReflowVersion
+	Progress
+	Retry
+	CacheKeys
+	BytesLoaded
+	BytesSaved
+	ArrayID
+	ArrayIndex
+	ArraySize
 )
 
 func init() {
@@ -105,6 +116,7 @@ const (
 	taskObj  objType = "task"
 	allocObj objType = "alloc"
 	poolObj  objType = "pool"
+	arrayObj objType = "array"
 )
 
 const (
@@ -156,6 +168,14 @@ const (
 	colResources     = "Resources"
 	colClusterName   = "ClusterName"
 	colReflowVersion = "ReflowVersion"
+	colProgress      = "Progress"
+	colRetry         = "Retry"
+	colCacheKeys     = "CacheKeys"
+	colBytesLoaded   = "BytesLoaded"
+	colBytesSaved    = "BytesSaved"
+	colArrayID       = "ArrayID"
+	colArrayIndex    = "ArrayIndex"
+	colArraySize     = "ArraySize"
 )
 
 var colmap = map[taskdb.Kind]string{
@@ -193,6 +213,14 @@ var colmap = map[taskdb.Kind]string{
 	Resources:     colResources,
 	ClusterName:   colClusterName,
 	ReflowVersion: colReflowVersion,
+	Progress:      colProgress,
+	Retry:         colRetry,
+	CacheKeys:     colCacheKeys,
+	BytesLoaded:   colBytesLoaded,
+	BytesSaved:    colBytesSaved,
+	ArrayID:       colArrayID,
+	ArrayIndex:    colArrayIndex,
+	ArraySize:     colArraySize,
 }
 
 // Index names used in dynamodb table.
@@ -458,6 +486,9 @@ func (t *TaskDB) CreateTask(ctx context.Context, task taskdb.Task) error {
 			colAttempt: {
 				N: aws.String(strconv.Itoa(task.Attempt)),
 			},
+			colRetry: {
+				N: aws.String(strconv.Itoa(task.Retry)),
+			},
 			colResources: {
 				S: aws.String(res),
 			},
@@ -481,10 +512,96 @@ func (t *TaskDB) CreateTask(ctx context.Context, task taskdb.Task) error {
 			},
 		},
 	}
+	if len(task.CacheKeys) > 0 {
+		input.Item[colCacheKeys] = cacheKeysAttr(task.CacheKeys)
+	}
+	if task.ArrayID.IsValid() {
+		input.Item[colArrayID] = &dynamodb.AttributeValue{S: aws.String(task.ArrayID.ID())}
+		input.Item[colArrayIndex] = &dynamodb.AttributeValue{N: aws.String(strconv.Itoa(task.ArrayIndex))}
+	}
 	_, err := t.DB.PutItemWithContext(ctx, input)
 	return err
 }
 
+// CreateArray implements taskdb.ArrayCreator.
+func (t *TaskDB) CreateArray(ctx context.Context, array taskdb.Array) error {
+	var res string
+	if r := array.Resources; !r.Equal(nil) {
+		if b, err := json.Marshal(r); err == nil {
+			res = string(b)
+		}
+	}
+	input := &dynamodb.PutItemInput{
+		TableName: aws.String(t.TableName),
+		Item: map[string]*dynamodb.AttributeValue{
+			colID: {
+				S: aws.String(array.ID.ID()),
+			},
+			colID4: {
+				S: aws.String(array.ID.IDShort()),
+			},
+			colRunID: {
+				S: aws.String(array.RunID.ID()),
+			},
+			colRunID4: {
+				S: aws.String(array.RunID.IDShort()),
+			},
+			colFlowID: {
+				S: aws.String(array.FlowID.String()),
+			},
+			colImgCmdID: {
+				S: aws.String(array.ImgCmdID.ID()),
+			},
+			colIdent: {
+				S: aws.String(array.Ident),
+			},
+			colResources: {
+				S: aws.String(res),
+			},
+			colArraySize: {
+				N: aws.String(strconv.Itoa(array.Size)),
+			},
+			colType: {
+				S: aws.String(string(arrayObj)),
+			},
+			colStartTime: {
+				S: aws.String(time.Now().UTC().Format(timeLayout)),
+			},
+			colLabels: {
+				SS: aws.StringSlice(t.Labels),
+			},
+		},
+	}
+	if len(array.CacheKeys) > 0 {
+		input.Item[colCacheKeys] = cacheKeysAttr(array.CacheKeys)
+	}
+	_, err := t.DB.PutItemWithContext(ctx, input)
+	return err
+}
+
+// arrayCacheKeys returns the cache keys of the task arrays with the
+// given IDs.
+func (t *TaskDB) arrayCacheKeys(ctx context.Context, ids []taskdb.TaskID) (map[taskdb.TaskID][]digest.Digest, error) {
+	queries := make([]*dynamodb.QueryInput, len(ids))
+	for i, id := range ids {
+		queries[i] = t.buildIndexQuery(ID, idIndex, id.ID(), arrayObj, ID, CacheKeys)
+	}
+	var (
+		keys = make(map[taskdb.TaskID][]digest.Digest)
+		errs errors.Multi
+	)
+	for si := range t.streamItems(ctx, queries, 0) {
+		if si.err != nil {
+			errs.Add(errors.E("query", si.query.GoString(), si.err))
+			continue
+		}
+		if v := parseAttr(si.item, ID, parseDigestFunc, &errs); v != nil {
+			keys[taskdb.TaskID(v.(digest.Digest))] = parseCacheKeys(si.item, &errs)
+		}
+	}
+	return keys, errs.Combined()
+}
+
 // SetTaskResult sets the task result id.
 func (t *TaskDB) SetTaskResult(ctx context.Context, id taskdb.TaskID, result digest.Digest) error {
 	input := &dynamodb.UpdateItemInput{
@@ -521,6 +638,48 @@ func (t *TaskDB) SetTaskUri(ctx context.Context, id taskdb.TaskID, uri string) e
 	return err
 }
 
+// SetTaskProgress implements taskdb.ProgressSetter.
+func (t *TaskDB) SetTaskProgress(ctx context.Context, id taskdb.TaskID, progress float64) error {
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String(t.TableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			colID: {
+				S: aws.String(id.ID()),
+			},
+		},
+		UpdateExpression: aws.String("SET #Progress = :progress"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":progress": {N: aws.String(strconv.FormatFloat(progress, 'f', -1, 64))},
+		},
+		ExpressionAttributeNames: map[string]*string{"#Progress": aws.String(colProgress)},
+	}
+	_, err := t.DB.UpdateItemWithContext(ctx, input)
+	return err
+}
+
+// SetTaskTransfer implements taskdb.TransferSetter.
+func (t *TaskDB) SetTaskTransfer(ctx context.Context, id taskdb.TaskID, loaded, saved int64) error {
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String(t.TableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			colID: {
+				S: aws.String(id.ID()),
+			},
+		},
+		UpdateExpression: aws.String("SET #BytesLoaded = :loaded, #BytesSaved = :saved"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":loaded": {N: aws.String(strconv.FormatInt(loaded, 10))},
+			":saved":  {N: aws.String(strconv.FormatInt(saved, 10))},
+		},
+		ExpressionAttributeNames: map[string]*string{
+			"#BytesLoaded": aws.String(colBytesLoaded),
+			"#BytesSaved":  aws.String(colBytesSaved),
+		},
+	}
+	_, err := t.DB.UpdateItemWithContext(ctx, input)
+	return err
+}
+
 // SetTaskAttrs sets the stdout, stderr and inspect ids for the task.
 func (t *TaskDB) SetTaskAttrs(ctx context.Context, id taskdb.TaskID, stdout, stderr, inspect digest.Digest) error {
 	input := &dynamodb.UpdateItemInput{
@@ -1093,9 +1252,67 @@ func (t *TaskDB) Tasks(ctx context.Context, q taskdb.TaskQuery) ([]taskdb.Task,
 				errs.Add(fmt.Errorf("parse attempt %v: %v", *v.N, err))
 			}
 		}
+		if v, ok := it[colRetry]; ok && v.N != nil {
+			t.Retry, err = strconv.Atoi(*v.N)
+			if err != nil {
+				errs.Add(fmt.Errorf("parse retry %v: %v", *v.N, err))
+			}
+		}
+		t.CacheKeys = parseCacheKeys(it, &errs)
+		if v, ok := it[colProgress]; ok && v.N != nil {
+			t.Progress, err = strconv.ParseFloat(*v.N, 64)
+			if err != nil {
+				errs.Add(fmt.Errorf("parse progress %v: %v", *v.N, err))
+			}
+		}
+		if v, ok := it[colBytesLoaded]; ok && v.N != nil {
+			t.BytesLoaded, err = strconv.ParseInt(*v.N, 10, 64)
+			if err != nil {
+				errs.Add(fmt.Errorf("parse bytes loaded %v: %v", *v.N, err))
+			}
+		}
+		if v, ok := it[colBytesSaved]; ok && v.N != nil {
+			t.BytesSaved, err = strconv.ParseInt(*v.N, 10, 64)
+			if err != nil {
+				errs.Add(fmt.Errorf("parse bytes saved %v: %v", *v.N, err))
+			}
+		}
+		if v := parseAttr(it, ArrayID, parseDigestFunc, &errs); v != nil {
+			t.ArrayID = taskdb.TaskID(v.(digest.Digest))
+		}
+		if v, ok := it[colArrayIndex]; ok && v.N != nil {
+			t.ArrayIndex, err = strconv.Atoi(*v.N)
+			if err != nil {
+				errs.Add(fmt.Errorf("parse array index %v: %v", *v.N, err))
+			}
+		}
 		tasks = append(tasks, t)
 	}
 
+	// The cache keys of the tasks of task arrays are recorded with
+	// their arrays.
+	var (
+		arrayIDs []taskdb.TaskID
+		seen     = make(map[taskdb.TaskID]bool)
+	)
+	for _, t := range tasks {
+		if t.ArrayID.IsValid() && len(t.CacheKeys) == 0 && !seen[t.ArrayID] {
+			seen[t.ArrayID] = true
+			arrayIDs = append(arrayIDs, t.ArrayID)
+		}
+	}
+	if len(arrayIDs) > 0 {
+		keys, aerr := t.arrayCacheKeys(ctx, arrayIDs)
+		if aerr != nil {
+			log.Errorf("taskdb arrays for tasks: %v", aerr)
+		}
+		for i := range tasks {
+			if tasks[i].ArrayID.IsValid() && len(tasks[i].CacheKeys) == 0 {
+				tasks[i].CacheKeys = keys[tasks[i].ArrayID]
+			}
+		}
+	}
+
 	if withAlloc {
 		allocsById := make(map[digest.Digest]*taskdb.Alloc)
 		var aids []digest.Digest
@@ -1445,6 +1662,33 @@ func (t *TaskDB) streamItems(ctx context.Context, queries []*dynamodb.QueryInput
 	return s
 }
 
+// cacheKeysAttr returns the attribute value of the given cache keys.
+func cacheKeysAttr(keys []digest.Digest) *dynamodb.AttributeValue {
+	ss := make([]string, len(keys))
+	for i, key := range keys {
+		ss[i] = key.String()
+	}
+	return &dynamodb.AttributeValue{SS: aws.StringSlice(ss)}
+}
+
+// parseCacheKeys parses the cache keys in item it, if any.
+func parseCacheKeys(it map[string]*dynamodb.AttributeValue, errs *errors.Multi) []digest.Digest {
+	v, ok := it[colCacheKeys]
+	if !ok {
+		return nil
+	}
+	var keys []digest.Digest
+	for _, s := range v.SS {
+		key, err := digest.Parse(*s)
+		if err != nil {
+			errs.Add(fmt.Errorf("parse cache key %v: %v", *s, err))
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
 // parseAttr gets the AttributeValue corresponding to the given taskdb.Kind from map 'it'
 // and parses the 'S' field of the AttributeValue using the given func 'f' (nil f acts as identity)
 func parseAttr(it map[string]*dynamodb.AttributeValue, k taskdb.Kind, f func(s string) (interface{}, error), errs *errors.Multi) interface{} {
@@ -207,6 +207,61 @@ func TestTaskCreate(t *testing.T) {
 	}
 }
 
+func TestArrayCreate(t *testing.T) {
+	var (
+		mockdb  = mockDynamodbPut{}
+		taskb   = &TaskDB{DB: &mockdb}
+		arrayID = taskdb.NewTaskID()
+		runID   = taskdb.NewRunID()
+		key     = reflow.Digester.Rand(nil)
+	)
+	taskb.TableName = mockTableName
+	err := taskb.CreateArray(context.Background(), taskdb.Array{
+		ID:        arrayID,
+		RunID:     runID,
+		CacheKeys: []digest.Digest{key},
+		Ident:     "shards",
+		Size:      8,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		actual   string
+		expected string
+	}{
+		{*mockdb.pinput.Item[colID].S, arrayID.ID()},
+		{*mockdb.pinput.Item[colRunID].S, runID.ID()},
+		{*mockdb.pinput.Item[colIdent].S, "shards"},
+		{*mockdb.pinput.Item[colArraySize].N, "8"},
+		{*mockdb.pinput.Item[colType].S, "array"},
+		{*mockdb.pinput.Item[colCacheKeys].SS[0], key.String()},
+	} {
+		if test.expected != test.actual {
+			t.Errorf("expected %s, got %v", test.expected, test.actual)
+		}
+	}
+
+	err = taskb.CreateTask(context.Background(), taskdb.Task{
+		ID:         taskdb.NewTaskID(),
+		RunID:      runID,
+		ArrayID:    arrayID,
+		ArrayIndex: 3,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := *mockdb.pinput.Item[colArrayID].S, arrayID.ID(); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := *mockdb.pinput.Item[colArrayIndex].N, "3"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if _, ok := mockdb.pinput.Item[colCacheKeys]; ok {
+		t.Error("unexpected cache keys")
+	}
+}
+
 type mockDynamoDBUpdate struct {
 	dynamodbiface.DynamoDBAPI
 	uInput dynamodb.UpdateItemInput
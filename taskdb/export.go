@@ -0,0 +1,110 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package taskdb
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/grailbio/reflow/pool"
+)
+
+// RunSummary is a denormalized summary of a completed run. Run
+// summaries are exported (see Exporter) to external systems for
+// organizational reporting.
+type RunSummary struct {
+	// RunID is the id of the run.
+	RunID RunID
+	// User is the user who started the run.
+	User string
+	// Program is the reflow program that was run.
+	Program string
+	// Labels are the labels of the run.
+	Labels pool.Labels
+	// Start and End are the start and end times of the run.
+	Start, End time.Time
+	// Tasks is the number of tasks (including retries) executed by the run.
+	Tasks int
+	// Execs is the number of execs evaluated by the run, and CachedExecs
+	// is the number of those whose results were retrieved from the cache.
+	Execs, CachedExecs int
+	// CostUSD is the (upper bound) cost of the run's tasks, in USD.
+	CostUSD float64
+	// FailureClass is the kind of error with which the run failed.
+	// It is empty for successful runs.
+	FailureClass string
+	// Error is the error with which the run failed, if any.
+	Error string
+}
+
+// Duration returns the duration of the run.
+func (s RunSummary) Duration() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+// CacheHitRate returns the fraction of the run's execs whose results
+// were retrieved from the cache.
+func (s RunSummary) CacheHitRate() float64 {
+	if s.Execs == 0 {
+		return 0
+	}
+	return float64(s.CachedExecs) / float64(s.Execs)
+}
+
+// RunSummaryColumns are the names of the columns of an exported run
+// summary, in the order of the values returned by RunSummary.Values.
+var RunSummaryColumns = []string{
+	"run_id",
+	"user",
+	"program",
+	"labels",
+	"start_time",
+	"end_time",
+	"duration_seconds",
+	"tasks",
+	"execs",
+	"cached_execs",
+	"cache_hit_rate",
+	"cost_usd",
+	"failure_class",
+	"error",
+}
+
+// Values returns the column values of the run summary, in the order
+// of RunSummaryColumns. Values are of type string, time.Time, int or
+// float64.
+func (s RunSummary) Values() []interface{} {
+	labels := make([]string, 0, len(s.Labels))
+	for k, v := range s.Labels {
+		labels = append(labels, fmt.Sprintf("%s=%s", k, v))
+	}
+	sort.Strings(labels)
+	return []interface{}{
+		s.RunID.ID(),
+		s.User,
+		s.Program,
+		strings.Join(labels, ","),
+		s.Start,
+		s.End,
+		s.Duration().Seconds(),
+		s.Tasks,
+		s.Execs,
+		s.CachedExecs,
+		s.CacheHitRate(),
+		s.CostUSD,
+		s.FailureClass,
+		s.Error,
+	}
+}
+
+// Exporter exports summaries of completed runs to an external
+// system (e.g., a data warehouse).
+type Exporter interface {
+	// ExportRun exports the summary of a completed run.
+	ExportRun(ctx context.Context, summary RunSummary) error
+}
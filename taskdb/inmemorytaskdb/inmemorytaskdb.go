@@ -51,6 +51,7 @@ type InmemoryTaskDB struct {
 	mu        sync.Mutex
 	numCalls  map[string]int
 	tasks     map[taskdb.TaskID]taskdb.Task
+	arrays    map[taskdb.TaskID]taskdb.Array
 }
 
 type tableRepo struct {
@@ -74,6 +75,7 @@ func NewInmemoryTaskDB(tableName, repoName string) *InmemoryTaskDB {
 		repo:      testutil.NewInmemoryRepository(repoName),
 		numCalls:  make(map[string]int),
 		tasks:     make(map[taskdb.TaskID]taskdb.Task),
+		arrays:    make(map[taskdb.TaskID]taskdb.Array),
 	}
 	tdbs[tr] = tdb
 	return tdb
@@ -119,11 +121,24 @@ func (t *InmemoryTaskDB) KeepRunAlive(ctx context.Context, id taskdb.RunID, keep
 func (t *InmemoryTaskDB) Tasks(ctx context.Context, taskQuery taskdb.TaskQuery) ([]taskdb.Task, error) {
 	tasks := make([]taskdb.Task, 0, len(t.tasks))
 	for _, tsk := range t.tasks {
+		if a, ok := t.arrays[tsk.ArrayID]; ok && tsk.ArrayID.IsValid() && len(tsk.CacheKeys) == 0 {
+			tsk.CacheKeys = a.CacheKeys
+		}
 		tasks = append(tasks, tsk)
 	}
 	return tasks, nil
 }
 
+// CreateArray creates a new task array in the taskdb.
+func (t *InmemoryTaskDB) CreateArray(ctx context.Context, array taskdb.Array) error {
+	callType := "CreateArray"
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.numCalls[callType] = t.numCalls[callType] + 1
+	t.arrays[array.ID] = array
+	return nil
+}
+
 // CreateTask creates a new task in the taskdb with the provided task.
 func (t *InmemoryTaskDB) CreateTask(ctx context.Context, task taskdb.Task) error {
 	callType := "CreateTask"
@@ -164,6 +179,19 @@ func (t *InmemoryTaskDB) SetTaskAttrs(ctx context.Context, id taskdb.TaskID, std
 	return nil
 }
 
+// SetTaskTransfer implements taskdb.TransferSetter.
+func (t *InmemoryTaskDB) SetTaskTransfer(ctx context.Context, id taskdb.TaskID, loaded, saved int64) error {
+	callType := "SetTaskTransfer"
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.numCalls[callType] = t.numCalls[callType] + 1
+	if tsk, ok := t.tasks[id]; ok {
+		tsk.BytesLoaded, tsk.BytesSaved = loaded, saved
+		t.tasks[id] = tsk
+	}
+	return nil
+}
+
 // SetTaskComplete mark the task as completed as of the given end time with the error (if any)
 func (t *InmemoryTaskDB) SetTaskComplete(ctx context.Context, id taskdb.TaskID, err error, end time.Time) error {
 	callType := "SetTaskComplete"
@@ -0,0 +1,159 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+// Package redshiftexport implements a taskdb.Exporter which inserts
+// run summaries into an Amazon Redshift table through the Redshift
+// Data API.
+package redshiftexport
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/redshiftdataapiservice"
+	"github.com/aws/aws-sdk-go/service/redshiftdataapiservice/redshiftdataapiserviceiface"
+	"github.com/grailbio/infra"
+	"github.com/grailbio/reflow/errors"
+	"github.com/grailbio/reflow/taskdb"
+)
+
+// ProviderName is the name of the Redshift exporter infra provider.
+const ProviderName = "redshiftexport"
+
+// pollInterval is the interval at which the status of an
+// insert statement is polled.
+const pollInterval = time.Second
+
+func init() {
+	infra.Register(ProviderName, new(Exporter))
+}
+
+// tableNameRE matches valid (optionally schema-qualified) table names.
+var tableNameRE = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_$]*(\.[A-Za-z_][A-Za-z0-9_$]*)?$`)
+
+// Exporter exports run summaries to a Redshift table. The table
+// must have the columns taskdb.RunSummaryColumns; start_time and
+// end_time are of type TIMESTAMP.
+type Exporter struct {
+	// Client is the Redshift Data API client.
+	Client redshiftdataapiserviceiface.RedshiftDataAPIServiceAPI
+	// Cluster is the identifier of the Redshift cluster.
+	Cluster string
+	// Database is the name of the database.
+	Database string
+	// DBUser is the database user, used to obtain temporary credentials.
+	// It is ignored if SecretARN is set.
+	DBUser string
+	// SecretARN is the ARN of the Secrets Manager secret holding the
+	// database credentials.
+	SecretARN string
+	// Table is the (optionally schema-qualified) name of the table.
+	Table string
+}
+
+// Help implements infra.Provider.
+func (Exporter) Help() string {
+	return "export run summaries to a Redshift table"
+}
+
+// Flags implements infra.Provider.
+func (e *Exporter) Flags(flags *flag.FlagSet) {
+	flags.StringVar(&e.Cluster, "cluster", "", "the identifier of the Redshift cluster")
+	flags.StringVar(&e.Database, "database", "", "the name of the database")
+	flags.StringVar(&e.DBUser, "dbuser", "", "the database user (if not using secretarn)")
+	flags.StringVar(&e.SecretARN, "secretarn", "", "the ARN of the secret holding the database credentials")
+	flags.StringVar(&e.Table, "table", "", "the table to which run summaries are exported")
+}
+
+// Init implements infra.Provider.
+func (e *Exporter) Init(sess *session.Session) error {
+	switch {
+	case e.Cluster == "" || e.Database == "" || e.Table == "":
+		return fmt.Errorf("redshiftexport: cluster, database and table must be specified")
+	case e.DBUser == "" && e.SecretARN == "":
+		return fmt.Errorf("redshiftexport: one of dbuser or secretarn must be specified")
+	case !tableNameRE.MatchString(e.Table):
+		return fmt.Errorf("redshiftexport: invalid table name %q", e.Table)
+	}
+	if e.Client == nil {
+		e.Client = redshiftdataapiservice.New(sess)
+	}
+	return nil
+}
+
+// literal returns the SQL literal for the value v.
+func literal(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return "'" + strings.ReplaceAll(v, "'", "''") + "'"
+	case time.Time:
+		if v.IsZero() {
+			return "NULL"
+		}
+		return "'" + v.UTC().Format("2006-01-02 15:04:05.999999") + "'"
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		panic(fmt.Sprintf("unsupported value type %T", v))
+	}
+}
+
+// insertSQL returns the SQL statement that inserts the run summary s
+// into the given table.
+func insertSQL(table string, s taskdb.RunSummary) string {
+	values := s.Values()
+	literals := make([]string, len(values))
+	for i, v := range values {
+		literals[i] = literal(v)
+	}
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		table, strings.Join(taskdb.RunSummaryColumns, ", "), strings.Join(literals, ", "))
+}
+
+// ExportRun implements taskdb.Exporter. ExportRun waits for the
+// insert statement to complete.
+func (e *Exporter) ExportRun(ctx context.Context, s taskdb.RunSummary) error {
+	input := &redshiftdataapiservice.ExecuteStatementInput{
+		ClusterIdentifier: aws.String(e.Cluster),
+		Database:          aws.String(e.Database),
+		Sql:               aws.String(insertSQL(e.Table, s)),
+		StatementName:     aws.String("reflow-run-" + s.RunID.IDShort()),
+	}
+	if e.SecretARN != "" {
+		input.SecretArn = aws.String(e.SecretARN)
+	} else {
+		input.DbUser = aws.String(e.DBUser)
+	}
+	out, err := e.Client.ExecuteStatementWithContext(ctx, input)
+	if err != nil {
+		return errors.E("redshiftexport", s.RunID.ID(), err)
+	}
+	for {
+		desc, err := e.Client.DescribeStatementWithContext(ctx, &redshiftdataapiservice.DescribeStatementInput{Id: out.Id})
+		if err != nil {
+			return errors.E("redshiftexport", s.RunID.ID(), err)
+		}
+		switch aws.StringValue(desc.Status) {
+		case "FINISHED":
+			return nil
+		case "FAILED", "ABORTED":
+			return errors.E("redshiftexport", s.RunID.ID(),
+				errors.Errorf("statement %s: %s", aws.StringValue(desc.Status), aws.StringValue(desc.Error)))
+		}
+		select {
+		case <-time.After(pollInterval):
+		case <-ctx.Done():
+			return errors.E("redshiftexport", s.RunID.ID(), ctx.Err())
+		}
+	}
+}
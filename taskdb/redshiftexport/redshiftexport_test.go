@@ -0,0 +1,63 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package redshiftexport
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/redshiftdataapiservice/redshiftdataapiserviceiface"
+	"github.com/grailbio/reflow/errors"
+	"github.com/grailbio/reflow/pool"
+	"github.com/grailbio/reflow/taskdb"
+)
+
+type nopClient struct {
+	redshiftdataapiserviceiface.RedshiftDataAPIServiceAPI
+}
+
+func TestInsertSQL(t *testing.T) {
+	start := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	s := taskdb.RunSummary{
+		RunID:        taskdb.NewRunID(),
+		User:         "o'brien@example.com",
+		Program:      "align.rf",
+		Labels:       pool.Labels{"project": "x", "env": "prod"},
+		Start:        start,
+		End:          start.Add(90 * time.Second),
+		Tasks:        3,
+		Execs:        4,
+		CachedExecs:  1,
+		CostUSD:      0.5,
+		FailureClass: errors.OOM.String(),
+		Error:        "killed",
+	}
+	got := insertSQL("reporting.runs", s)
+	want := "INSERT INTO reporting.runs (run_id, user, program, labels, start_time, end_time, duration_seconds, tasks, execs, cached_execs, cache_hit_rate, cost_usd, failure_class, error) " +
+		"VALUES ('" + s.RunID.ID() + "', 'o''brien@example.com', 'align.rf', 'env=prod,project=x', '2021-03-04 05:06:07', '2021-03-04 05:07:37', " +
+		"90, 3, 4, 1, 0.25, 0.5, 'OOM error', 'killed')"
+	if got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestInit(t *testing.T) {
+	for _, tc := range []struct {
+		e  Exporter
+		ok bool
+	}{
+		{Exporter{Cluster: "c", Database: "d", DBUser: "u", Table: "runs"}, true},
+		{Exporter{Cluster: "c", Database: "d", SecretARN: "arn", Table: "reporting.runs"}, true},
+		{Exporter{Cluster: "c", Database: "d", Table: "runs"}, false},
+		{Exporter{Cluster: "c", DBUser: "u", Table: "runs"}, false},
+		{Exporter{Cluster: "c", Database: "d", DBUser: "u", Table: "runs; DROP TABLE runs"}, false},
+	} {
+		// Provide a client so that Init does not need a session.
+		tc.e.Client = nopClient{}
+		if got, want := tc.e.Init(nil) == nil, tc.ok; got != want {
+			t.Errorf("%+v: got %v, want %v", tc.e, got, want)
+		}
+	}
+}
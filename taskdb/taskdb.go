@@ -206,6 +206,30 @@ type TaskDB interface {
 	Repository() reflow.Repository
 }
 
+// ProgressSetter is implemented by TaskDBs which record the progress
+// reported by running tasks.
+type ProgressSetter interface {
+	// SetTaskProgress sets the progress, in percent, of the task.
+	SetTaskProgress(ctx context.Context, id TaskID, progress float64) error
+}
+
+// TransferSetter is implemented by TaskDBs which record the amount of
+// data moved by tasks.
+type TransferSetter interface {
+	// SetTaskTransfer sets the number of bytes of inputs loaded, and
+	// of results saved, by the task.
+	SetTaskTransfer(ctx context.Context, id TaskID, loaded, saved int64) error
+}
+
+// ArrayCreator is implemented by TaskDBs which record task arrays
+// (see Array). The rows of the tasks of recorded arrays omit their
+// cache keys, which are recorded once, with their array; they are
+// restored when the tasks are queried (see TaskDB.Tasks).
+type ArrayCreator interface {
+	// CreateArray creates a new task array in the taskdb.
+	CreateArray(ctx context.Context, array Array) error
+}
+
 // TimeFields are various common fields found in all taskdb row types.
 type TimeFields struct {
 	// Start is the time the taskdb row was started.
@@ -266,6 +290,9 @@ type Task struct {
 	AllocID digest.Digest
 	// FlowID is the flow id of this task.
 	FlowID digest.Digest
+	// CacheKeys are the cache keys of the task's flow (see
+	// flow.Flow.CacheKeys), unqualified by any cache namespace.
+	CacheKeys []digest.Digest
 	// ResultID is the id of the result, if non zero.
 	ResultID digest.Digest
 	// ImgCmdID is the ID of the underlying exec. It is a digest of an exec's image and cmd.
@@ -276,6 +303,11 @@ type Task struct {
 	Ident string
 	// Attempt stores the (zero-based) current attempt number for this task.
 	Attempt int
+	// Retry stores the number of times the task's flow was previously
+	// retried by the evaluator (e.g., with increased memory after an OOM).
+	// It is counted separately from Attempt, which is the scheduler's
+	// attempt number for the task.
+	Retry int
 	// Err stores the error for failed tasks
 	Err errors.Error
 	// Resources is the amount of resources reserved for this task.
@@ -285,6 +317,17 @@ type Task struct {
 	URI string
 	// Stdout, Stderr and Inspect are the stdout, stderr and inspect ids of the task.
 	Stdout, Stderr, Inspect digest.Digest
+	// Progress is the progress, in percent, last reported by the task's
+	// exec while running (see reflow.ExecProgressPath), or zero if none
+	// was reported.
+	Progress float64
+	// BytesLoaded and BytesSaved are the number of bytes of inputs
+	// loaded onto the task's alloc, and of results saved, by the task.
+	BytesLoaded, BytesSaved int64
+	// ArrayID is the id of the task array of which the task is a
+	// member, if any, and ArrayIndex is the task's index in it.
+	ArrayID    TaskID
+	ArrayIndex int
 
 	// Alloc is the Alloc this task was executed on.
 	Alloc *Alloc
@@ -298,6 +341,30 @@ func (t Task) String() string {
 	return fmt.Sprintf("task %s %s %s %s %s", t.ID.IDShort(), t.RunID.IDShort(), t.FlowID.Short(), t.Start.String(), et.String())
 }
 
+// Array is a task array (see sched.NewTaskArray): a set of tasks,
+// created by the same flow, which share an exec config template and
+// differ only in their index in the array. The attributes common to
+// the array's tasks are recorded once, with the array.
+type Array struct {
+	TimeFields
+	// ID is the array id.
+	ID TaskID
+	// RunID is the run id that created this array.
+	RunID RunID
+	// FlowID is the flow id of the array's tasks.
+	FlowID digest.Digest
+	// CacheKeys are the cache keys of the array's flow (see Task.CacheKeys).
+	CacheKeys []digest.Digest
+	// ImgCmdID and Ident are those of the array's tasks (see Task).
+	ImgCmdID ImgCmdID
+	Ident    string
+	// Resources is the amount of resources reserved for each of the
+	// array's tasks.
+	Resources reflow.Resources
+	// Size is the number of tasks in the array.
+	Size int
+}
+
 // ClusterID is the identifier of a cluster.
 type ClusterID struct {
 	// ClusterName is the name of the cluster.
@@ -23,6 +23,7 @@ type assocKey struct {
 type InmemoryAssoc struct {
 	mu                  sync.Mutex
 	assocs              map[assocKey]digest.Digest
+	accessed            map[digest.Digest]time.Time
 	scanTimeGenerator   func() time.Time
 	scanLabelsGenerator func() []string
 }
@@ -31,7 +32,8 @@ type InmemoryAssoc struct {
 // that stores its mapping in memory.
 func NewInmemoryAssoc() *InmemoryAssoc {
 	return &InmemoryAssoc{
-		assocs: make(map[assocKey]digest.Digest),
+		assocs:   make(map[assocKey]digest.Digest),
+		accessed: make(map[digest.Digest]time.Time),
 	}
 }
 
@@ -43,6 +45,7 @@ func (a *InmemoryAssoc) Store(ctx context.Context, kind assoc.Kind, k, v digest.
 		delete(a.assocs, key)
 	} else {
 		a.assocs[key] = v
+		a.accessed[k] = time.Now()
 	}
 	return nil
 }
@@ -55,6 +58,7 @@ func (a *InmemoryAssoc) Get(ctx context.Context, kind assoc.Kind, k digest.Diges
 	if !ok {
 		return k, digest.Digest{}, errors.E(errors.NotExist, errors.New("key does not exist"))
 	}
+	a.accessed[k] = time.Now()
 	return k, v, nil
 }
 
@@ -62,7 +66,10 @@ func (a *InmemoryAssoc) BatchGet(ctx context.Context, batch assoc.Batch) error {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 	for k := range batch {
-		v := a.assocs[assocKey{k.Kind, k.Digest}]
+		v, ok := a.assocs[assocKey{k.Kind, k.Digest}]
+		if ok {
+			a.accessed[k.Digest] = time.Now()
+		}
 		batch[k] = assoc.Result{Digest: v}
 	}
 	return nil
@@ -86,23 +93,53 @@ func (a *InmemoryAssoc) Count(ctx context.Context) (int64, error) {
 // Note that the handler function may be called asynchronously from multiple threads.
 func (a *InmemoryAssoc) Scan(ctx context.Context, kinds []assoc.Kind, handler assoc.MappingHandler) error {
 	results := make(map[digest.Digest]map[assoc.Kind]digest.Digest)
+	accessed := make(map[digest.Digest]time.Time)
 	a.mu.Lock()
 	for k, v := range a.assocs {
 		if _, ok := results[k.Digest]; !ok {
 			results[k.Digest] = make(map[assoc.Kind]digest.Digest)
 		}
 		results[k.Digest][k.Kind] = v
+		accessed[k.Digest] = a.accessed[k.Digest]
 	}
 	a.mu.Unlock()
 	for k, v := range results {
-		handler.HandleMapping(ctx, k, v, a.scanTimeGenerator(), a.scanLabelsGenerator())
+		// Unless generated, the scanned access time is the key's last access.
+		lastAccess, labels := accessed[k], []string(nil)
+		if a.scanTimeGenerator != nil {
+			lastAccess = a.scanTimeGenerator()
+		}
+		if a.scanLabelsGenerator != nil {
+			labels = a.scanLabelsGenerator()
+		}
+		handler.HandleMapping(ctx, k, v, lastAccess, labels)
 	}
 	return nil
 }
 
 // Delete deletes the key k unconditionally from the provided assoc.
 func (a *InmemoryAssoc) Delete(ctx context.Context, k digest.Digest) error {
-	return errors.E("delete", errors.NotSupported)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var found bool
+	for key := range a.assocs {
+		if key.Digest == k {
+			delete(a.assocs, key)
+			found = true
+		}
+	}
+	delete(a.accessed, k)
+	if !found {
+		return errors.E("delete", k, errors.NotExist)
+	}
+	return nil
+}
+
+// SetAccessTime sets the last access time of the key k.
+func (a *InmemoryAssoc) SetAccessTime(k digest.Digest, t time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.accessed[k] = t
 }
 
 func (a *InmemoryAssoc) RawAssocs() map[assocKey]digest.Digest {
@@ -122,5 +159,8 @@ func (a *InmemoryAssoc) Copy() *InmemoryAssoc {
 	for k, v := range a.assocs {
 		aNew.assocs[k] = v
 	}
+	for k, t := range a.accessed {
+		aNew.accessed[k] = t
+	}
 	return aNew
 }
@@ -53,8 +53,35 @@ func (bc *batchConfig) Configure(b *batch.Batch) {
 func (c *Cmd) batchrun(ctx context.Context, args ...string) {
 	c.Fatal("command batchrun has been renamed runbatch")
 }
+func (c *Cmd) batch(ctx context.Context, args ...string) {
+	help := `Batch manages the batch defined in this directory.
+See runbatch -help for information about Reflow's batching mechanism.
+
+The following subcommands are supported:
+
+	retry	rerun only the failed runs of the batch
+
+Subcommands accept the flags of runbatch.`
+	if len(args) == 0 || args[0] == "-help" || args[0] == "-h" {
+		c.Errorln(help)
+		c.Exit(2)
+	}
+	switch args[0] {
+	case "retry":
+		c.runBatch(ctx, "batch retry", args[1:], true)
+	default:
+		c.Fatalf("unknown batch subcommand %s", args[0])
+	}
+}
+
 func (c *Cmd) runbatch(ctx context.Context, args ...string) {
-	flags := flag.NewFlagSet("runbatch", flag.ExitOnError)
+	c.runBatch(ctx, "runbatch", args, false)
+}
+
+// runBatch runs the batch in this directory. If retryOnly is set,
+// only the batch's failed runs are run (again).
+func (c *Cmd) runBatch(ctx context.Context, name string, args []string, retryOnly bool) {
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
 	help := `Runbatch runs the batch defined in this directory.
 
 A batch is defined by a directory with a batch configuration file named
@@ -93,8 +120,21 @@ Remaining arguments are passed on as parameters to all runs; these
 flags override any parameters in the batch sample file.
 
 The flag -parallelism controls the number of runs in the batch to run concurrently.
+
+With -watch, the runs file is polled at the given interval while the
+batch is running, and runs appended to it are added to the batch, so
+that large batches may be extended without restarting them.
+
+Command "reflow batch retry" reruns only the failed runs of the
+batch, using the same cluster and scheduler for all of them.
 `
+	if retryOnly {
+		help = `Batch retry reruns the failed runs of the batch defined in this
+directory; runs that completed successfully or that were never run
+are not run. See runbatch -help for details.`
+	}
 	retryFlag := flags.Bool("retry", false, "retry failed runs")
+	watchFlag := flags.Duration("watch", 0, "poll the runs file for added runs at this interval")
 	resetFlag := flags.Bool("reset", false, "reset failed runs")
 	parallelismFlag := flags.Int("parallelism", 50, "max number of runs to run in parallel")
 	idsFlag := flags.String("ids", "", "comma-separated list of ids to run; an empty list runs all")
@@ -102,7 +142,7 @@ The flag -parallelism controls the number of runs in the batch to run concurrent
 	bc.Flags(flags)
 	var config runtime.CommonRunFlags
 	config.Flags(flags)
-	c.Parse(flags, args, help, "runbatch [-parallelism=10] [-retry] [-reset] [flags]")
+	c.Parse(flags, args, help, name+" [-parallelism=10] [-retry] [-reset] [-watch=interval] [flags]")
 
 	if err := config.Err(); err != nil {
 		c.Errorln(err)
@@ -135,24 +175,37 @@ The flag -parallelism controls the number of runs in the batch to run concurrent
 	blobMux := rr.Scheduler().Mux
 	b := &batch.Batch{
 		EvalConfig: flow.EvalConfig{
-			Log:                c.Log,
-			Snapshotter:        blobMux,
-			Repository:         repo,
-			Assoc:              assoc,
-			AssertionGenerator: reflow.AssertionGeneratorMux{reflow.BlobAssertionsNamespace: blobMux},
-			CacheMode:          cache.CacheMode,
-			Scheduler:          rr.Scheduler(),
+			Log:         c.Log,
+			Snapshotter: blobMux,
+			Repository:  repo,
+			Assoc:       assoc,
+			// Batch runs do not resolve images: the image assertions of
+			// cached results are not checked.
+			AssertionGenerator: reflow.AssertionGeneratorMux{
+				reflow.BlobAssertionsNamespace:   blobMux,
+				reflow.DockerAssertionsNamespace: new(runtime.ImageAssertions),
+			},
+			CacheMode: cache.CacheMode,
+			Scheduler: rr.Scheduler(),
 		},
 		Args:    flags.Args(),
 		Rundir:  c.rundir(),
 		User:    string(*user),
 		Limiter: limiter.New(),
 		Status:  c.Status.Groupf("batch %s", wd),
+		Watch:   *watchFlag,
 	}
 	b.Limiter.Release(*parallelismFlag)
 	c.must(config.Configure(&b.EvalConfig))
 	bc.Configure(b)
-	c.must(b.Init(*resetFlag, *retryFlag))
+	c.must(b.Init(*resetFlag, *retryFlag || retryOnly))
+	if retryOnly {
+		b.RetryOnly()
+		if len(b.Runs) == 0 {
+			c.Log.Printf("no failed runs to retry")
+			return
+		}
+	}
 
 	defer b.Close()
 	if *idsFlag != "" {
@@ -163,7 +216,7 @@ The flag -parallelism controls the number of runs in the batch to run concurrent
 		}
 		for id := range b.Runs {
 			if !ids[id] {
-				delete(b.Runs, id)
+				b.Exclude(id)
 			}
 		}
 	}
@@ -3,9 +3,12 @@ package tool
 import (
 	"context"
 	"flag"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 
+	"github.com/aws/aws-sdk-go/aws/session"
+	infra2 "github.com/grailbio/reflow/infra"
 	"github.com/grailbio/reflow/syntax"
 )
 
@@ -20,7 +23,13 @@ suffix ".rfx".
 Reflow bundles are interchangeable with other Reflow modules: they
 may be run with command run or imported by other Reflow modules. Any
 flags provided as arguments provide default values to the module's
-parameters.`
+parameters.
+
+Remote modules, i.e., modules imported by HTTP(S), git or S3 URL, are
+fetched and embedded in the bundle, as are the modules they import,
+so that the bundle may be evaluated without access to them. The
+versions of remote modules (e.g., the git commits from which they
+were fetched) are recorded in the bundle.`
 	c.Parse(flags, args, help, "bundle [-o output] path [args]")
 	if flags.NArg() == 0 {
 		flags.Usage()
@@ -41,6 +50,38 @@ parameters.`
 	}
 	f, err := os.Create(*out)
 	c.must(err)
-	c.must(sess.Bundle().WriteTo(f))
+	bundle := sess.Bundle()
+	for _, path := range bundle.Remote() {
+		if version := bundle.Version(path); version != "" {
+			c.Log.Printf("embedding remote module %s (%s)", path, version)
+		} else {
+			c.Log.Printf("embedding remote module %s", path)
+		}
+	}
+	c.must(bundle.WriteTo(f))
 	c.must(f.Close())
 }
+
+// fetchS3Module fetches the source of a module imported by S3 URL,
+// using the configured AWS session. Its version is the object's
+// entity tag.
+func (c *Cmd) fetchS3Module(ctx context.Context, rawurl string) ([]byte, string, error) {
+	var sess *session.Session
+	if err := c.Config.Instance(&sess); err != nil {
+		return nil, "", err
+	}
+	rc, file, err := infra2.BlobMux(c.Config, sess).Get(ctx, rawurl, "")
+	if err != nil {
+		return nil, "", err
+	}
+	defer rc.Close()
+	b, err := ioutil.ReadAll(rc)
+	if err != nil {
+		return nil, "", err
+	}
+	var version string
+	if file.ETag != "" {
+		version = "etag " + file.ETag
+	}
+	return b, version, nil
+}
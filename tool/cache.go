@@ -7,11 +7,20 @@ package tool
 import (
 	"bufio"
 	"context"
+	"encoding/json"
 	"flag"
+	"fmt"
 	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
 
+	"github.com/grailbio/base/digest"
 	"github.com/grailbio/reflow"
 	"github.com/grailbio/reflow/assoc"
+	"github.com/grailbio/reflow/errors"
+	"github.com/grailbio/reflow/flow"
+	"github.com/grailbio/reflow/taskdb"
 )
 
 func (c *Cmd) rmcache(ctx context.Context, args ...string) {
@@ -45,3 +54,241 @@ Items are digests read from the standard input.`
 	}
 	c.Log.Debugf("removed %d keys", n)
 }
+
+func (c *Cmd) cache(ctx context.Context, args ...string) {
+	if len(args) > 0 {
+		switch args[0] {
+		case "invalidate":
+			c.cacheInvalidate(ctx, args[1:]...)
+			return
+		case "verify":
+			c.cacheVerify(ctx, args[1:]...)
+			return
+		}
+	}
+	var (
+		flags = flag.NewFlagSet("cache", flag.ExitOnError)
+		help  = `Cache manages the reflow cache. The following subcommands are supported:
+
+	invalidate	invalidate selected cache entries (see reflow cache invalidate -help)
+	verify		verify cache entries, and repair or delete invalid ones (see reflow cache verify -help)`
+	)
+	c.Parse(flags, args, help, "cache invalidate|verify [flags]")
+	flags.Usage()
+}
+
+// invalidateQuery selects the tasks whose cached results are
+// invalidated by "reflow cache invalidate".
+type invalidateQuery struct {
+	// Ident, if non-empty, is the ident of the tasks' execs.
+	Ident string
+	// Image, if non-empty, is (a part of) the image, typically its
+	// digest, of the tasks' execs.
+	Image string
+	// Labels, if non-nil, matches the labels of the tasks' runs.
+	Labels *filter
+	// Since and Until, if nonzero, bound the time range in which the
+	// tasks were active.
+	Since, Until time.Time
+}
+
+// MatchTask tells whether the provided task has a cached result and
+// matches the query's ident and time range.
+func (q invalidateQuery) MatchTask(task taskdb.Task) bool {
+	if task.FlowID.IsZero() || task.ResultID.IsZero() {
+		return false
+	}
+	if q.Ident != "" && task.Ident != q.Ident {
+		return false
+	}
+	end := task.End
+	if end.IsZero() {
+		end = task.Keepalive
+	}
+	if !q.Since.IsZero() && end.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && task.Start.After(q.Until) {
+		return false
+	}
+	return true
+}
+
+// MatchImage tells whether an exec with the provided image matches
+// the query's image.
+func (q invalidateQuery) MatchImage(image string) bool {
+	return q.Image == "" || image != "" && strings.Contains(image, q.Image)
+}
+
+// invalidateTask deletes the cache entries of the provided task in
+// the provided cache namespace. The task's result is cached under
+// each of its flow's cache keys: the physical digests, which are
+// looked up first, as well as the logical one. Tasks for which no
+// cache keys were recorded are invalidated by their flow digest only.
+func invalidateTask(ctx context.Context, ass assoc.Assoc, namespace string, task taskdb.Task) error {
+	keys := task.CacheKeys
+	if len(keys) == 0 {
+		keys = []digest.Digest{task.FlowID}
+	}
+	for _, key := range keys {
+		if err := ass.Delete(ctx, flow.NamespaceKey(namespace, key)); err != nil && !errors.Is(errors.NotExist, err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Cmd) cacheInvalidate(ctx context.Context, args ...string) {
+	var (
+		flags      = flag.NewFlagSet("cache invalidate", flag.ExitOnError)
+		identFlag  = flags.String("ident", "", "invalidate the results of execs with this ident")
+		imageFlag  = flags.String("image", "", "invalidate the results of execs whose image contains this string (typically an image digest)")
+		labelsFlag = flags.String("labels", "", "invalidate the results of tasks of runs whose labels match these clauses (see reflow collect -help)")
+		sinceFlag  = flags.String("since", "", "invalidate the results of tasks active since (format time.Duration or YYYY-MM-DD UTC)")
+		untilFlag  = flags.String("until", "", "invalidate the results of tasks active until (format time.Duration or YYYY-MM-DD UTC)")
+		dryRunFlag = flags.Bool("dry-run", false, "report the cache entries which would be invalidated without invalidating them")
+		nsFlag     = flags.String("cachenamespace", "", "invalidate the cache entries in this cache namespace (see reflow run -help)")
+		help       = `Cache invalidate invalidates the cache entries of selected tasks, so
+that subsequent runs recompute them; this is useful, for example,
+when a bug is discovered in a tool, to force recomputation of only
+the stages which used it.
+
+Tasks are selected from TaskDB by the ident of their exec (-ident),
+the image of their exec (-image), the labels of their run (-labels;
+e.g., a label identifying the version of the program's modules, as
+given to reflow run -labels), and the time range in which they ran
+(-since and -until). A task is selected only if it matches all of
+the given criteria. Unless -ident is given, -since must be given.
+
+The cache entries of the selected tasks are removed from the
+association table, keyed by the tasks' cache keys; the objects they
+refer to are left in the repository, to be collected by reflow collect
+once they are no longer referenced. Cache entries of downstream
+stages, whose keys do not depend on the invalidated results, should
+be invalidated separately, e.g., by their ident. Cache entries
+written by runs with a cache namespace are invalidated only if the
+same namespace is given with -cachenamespace.
+
+The invalidated cache entries (flow digest, ident and task id) are
+written to standard output.`
+	)
+	c.Parse(flags, args, help, "cache invalidate [-ident ident] [-image image] [-labels labels] [-since time] [-until time] [-cachenamespace namespace] [-dry-run]")
+	if flags.NArg() != 0 {
+		flags.Usage()
+	}
+	var (
+		q   = invalidateQuery{Ident: *identFlag, Image: *imageFlag}
+		err error
+	)
+	if *labelsFlag != "" {
+		q.Labels, err = parseFilter(*labelsFlag)
+		c.must(err)
+	}
+	if s := *sinceFlag; s != "" {
+		if q.Since, err = parseDateStr(s); err != nil {
+			c.Fatalf("invalid -since %s: %v", s, err)
+		}
+	}
+	if s := *untilFlag; s != "" {
+		if q.Until, err = parseDateStr(s); err != nil {
+			c.Fatalf("invalid -until %s: %v", s, err)
+		}
+	}
+	if q.Ident == "" && q.Since.IsZero() {
+		c.Errorln("either -ident or -since must be given")
+		flags.Usage()
+	}
+	var tdb taskdb.TaskDB
+	if err = c.Config.Instance(&tdb); err != nil {
+		c.Fatalf("taskdb: %v", err)
+	}
+	if tdb == nil {
+		c.Fatal("no taskdb configured")
+	}
+	var ass assoc.Assoc
+	c.must(c.Config.Instance(&ass))
+
+	var tasks []taskdb.Task
+	if q.Ident != "" {
+		// Ident queries return partial tasks, so each is looked up in full.
+		found, err := tdb.Tasks(ctx, taskdb.TaskQuery{Ident: q.Ident})
+		if err != nil {
+			c.Fatalf("tasks: %v", err)
+		}
+		for _, task := range found {
+			full, err := tdb.Tasks(ctx, taskdb.TaskQuery{ID: task.ID})
+			if err != nil {
+				c.Fatalf("task %s: %v", task.ID.IDShort(), err)
+			}
+			tasks = append(tasks, full...)
+		}
+	} else {
+		until := q.Until
+		if until.IsZero() {
+			until = time.Now()
+		}
+		if tasks, err = tdb.Tasks(ctx, taskdb.TaskQuery{Since: q.Since, Until: until}); err != nil {
+			c.Fatalf("tasks: %v", err)
+		}
+	}
+
+	runLabels := make(map[taskdb.RunID][]string)
+	invalidated := make(map[digest.Digest]bool)
+	var tw tabwriter.Writer
+	tw.Init(c.Stdout, 4, 4, 1, ' ', 0)
+	for _, task := range tasks {
+		if !q.MatchTask(task) || invalidated[task.FlowID] {
+			continue
+		}
+		if q.Labels != nil {
+			labels, ok := runLabels[task.RunID]
+			if !ok {
+				runs, err := tdb.Runs(ctx, taskdb.RunQuery{ID: task.RunID})
+				if err != nil {
+					c.Fatalf("run %s: %v", task.RunID.IDShort(), err)
+				}
+				for _, run := range runs {
+					for k, v := range run.Labels {
+						labels = append(labels, fmt.Sprintf("%s=%s", k, v))
+					}
+				}
+				runLabels[task.RunID] = labels
+			}
+			if !q.Labels.Match(labels) {
+				continue
+			}
+		}
+		if q.Image != "" {
+			if task.Inspect.IsZero() {
+				c.Log.Debugf("task %s: no inspect recorded; skipping", task.ID.IDShort())
+				continue
+			}
+			rc, err := tdb.Repository().Get(ctx, task.Inspect)
+			if err != nil {
+				c.Fatalf("task %s: inspect %s: %v", task.ID.IDShort(), task.Inspect.Short(), err)
+			}
+			var inspect reflow.ExecInspect
+			err = json.NewDecoder(rc).Decode(&inspect)
+			_ = rc.Close()
+			if err != nil {
+				c.Fatalf("task %s: decode inspect %s: %v", task.ID.IDShort(), task.Inspect.Short(), err)
+			}
+			if !q.MatchImage(inspect.Config.Image) {
+				continue
+			}
+		}
+		if !*dryRunFlag {
+			if err := invalidateTask(ctx, ass, *nsFlag, task); err != nil {
+				c.Fatalf("invalidate %s: %v", task.FlowID, err)
+			}
+		}
+		invalidated[task.FlowID] = true
+		fmt.Fprintf(&tw, "%s\t%s\t%s\n", task.FlowID, task.Ident, task.ID.IDShort())
+	}
+	_ = tw.Flush()
+	if *dryRunFlag {
+		c.Log.Printf("would invalidate %d cache entries", len(invalidated))
+	} else {
+		c.Log.Printf("invalidated %d cache entries", len(invalidated))
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package tool
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grailbio/base/digest"
+	"github.com/grailbio/reflow"
+	"github.com/grailbio/reflow/assoc"
+	"github.com/grailbio/reflow/errors"
+	"github.com/grailbio/reflow/flow"
+	"github.com/grailbio/reflow/taskdb"
+	"github.com/grailbio/reflow/test/testutil"
+)
+
+func TestInvalidateQuery(t *testing.T) {
+	now := time.Now()
+	task := taskdb.Task{
+		FlowID:   reflow.Digester.FromString("flow"),
+		ResultID: reflow.Digester.FromString("result"),
+		Ident:    "align",
+	}
+	task.Start, task.End = now.Add(-2*time.Hour), now.Add(-time.Hour)
+	for _, tc := range []struct {
+		q    invalidateQuery
+		task taskdb.Task
+		want bool
+	}{
+		{invalidateQuery{Ident: "align"}, task, true},
+		{invalidateQuery{Ident: "sort"}, task, false},
+		{invalidateQuery{Since: now.Add(-90 * time.Minute)}, task, true},
+		{invalidateQuery{Since: now.Add(-30 * time.Minute)}, task, false},
+		{invalidateQuery{Since: now.Add(-3 * time.Hour), Until: now.Add(-150 * time.Minute)}, task, false},
+		{invalidateQuery{Ident: "align"}, taskdb.Task{Ident: "align"}, false},
+	} {
+		if got, want := tc.q.MatchTask(tc.task), tc.want; got != want {
+			t.Errorf("%+v: got %v, want %v", tc.q, got, want)
+		}
+	}
+
+	q := invalidateQuery{Image: "sha256:1234"}
+	if !q.MatchImage("ubuntu@sha256:1234abcd") {
+		t.Error("expected image to match")
+	}
+	if q.MatchImage("ubuntu@sha256:5678") || q.MatchImage("") {
+		t.Error("unexpected image match")
+	}
+	if !(invalidateQuery{}).MatchImage("") {
+		t.Error("expected empty query to match")
+	}
+}
+
+func TestInvalidateTask(t *testing.T) {
+	var (
+		ctx      = context.Background()
+		physical = reflow.Digester.FromString("physical")
+		logical  = reflow.Digester.FromString("logical")
+		fsid     = reflow.Digester.FromString("fileset")
+		task     = taskdb.Task{FlowID: logical, CacheKeys: []digest.Digest{physical, logical}}
+	)
+	for _, ns := range []string{"", "test"} {
+		ass := testutil.NewInmemoryAssoc()
+		for _, key := range task.CacheKeys {
+			if err := ass.Store(ctx, assoc.FilesetV2, flow.NamespaceKey(ns, key), fsid); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := invalidateTask(ctx, ass, ns, task); err != nil {
+			t.Fatal(err)
+		}
+		for _, key := range task.CacheKeys {
+			if _, _, err := ass.Get(ctx, assoc.FilesetV2, flow.NamespaceKey(ns, key)); !errors.Is(errors.NotExist, err) {
+				t.Errorf("namespace %q: key %v: got %v, want NotExist", ns, key.Short(), err)
+			}
+		}
+	}
+}
@@ -0,0 +1,322 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package tool
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/grailbio/base/digest"
+	"github.com/grailbio/base/traverse"
+	"github.com/grailbio/reflow"
+	"github.com/grailbio/reflow/assoc"
+	"github.com/grailbio/reflow/errors"
+	infra2 "github.com/grailbio/reflow/infra"
+	"github.com/grailbio/reflow/repository"
+	"github.com/grailbio/reflow/runtime"
+)
+
+// A cacheStatus is the outcome of the verification of a cache entry.
+type cacheStatus int
+
+const (
+	// cacheOK indicates that the entry is valid.
+	cacheOK cacheStatus = iota
+	// cacheError indicates that the entry could not be verified
+	// (e.g., due to a transient error).
+	cacheError
+	// cacheMissing indicates that the entry's fileset, or some of the
+	// files in it, are missing from the repository.
+	cacheMissing
+	// cacheCorrupt indicates that the entry's fileset, or some of the
+	// files in it, do not match their digests, or that the fileset
+	// cannot be parsed.
+	cacheCorrupt
+	// cacheStale indicates that the assertions of the entry's fileset
+	// no longer hold for the current state of the blobs (or images)
+	// they refer to.
+	cacheStale
+
+	maxCacheStatus
+)
+
+var cacheStatusNames = [maxCacheStatus]string{
+	cacheOK:      "ok",
+	cacheError:   "error",
+	cacheMissing: "missing",
+	cacheCorrupt: "corrupt",
+	cacheStale:   "stale",
+}
+
+// String returns the name of the status.
+func (s cacheStatus) String() string {
+	return cacheStatusNames[s]
+}
+
+// statusOf returns the status implied by an error encountered while
+// verifying an object.
+func statusOf(err error) cacheStatus {
+	switch {
+	case err == nil:
+		return cacheOK
+	case errors.Is(errors.NotExist, err):
+		return cacheMissing
+	default:
+		return cacheError
+	}
+}
+
+// cacheVerifier verifies cache entries against a repository.
+type cacheVerifier struct {
+	// Repo is the repository in which the cache's objects are stored.
+	Repo reflow.Repository
+	// Integrity determines whether objects are read in full to verify
+	// their digests, instead of only checking their existence.
+	Integrity bool
+	// Generator, if not nil, is used to validate the assertions of
+	// cached filesets against the current state of their blobs (and
+	// images).
+	Generator reflow.AssertionGenerator
+}
+
+// verifyObject verifies that the object with the provided digest is
+// present in the repository, and, if v.Integrity is set, that its
+// contents match the digest.
+func (v cacheVerifier) verifyObject(ctx context.Context, id digest.Digest) (cacheStatus, error) {
+	if !v.Integrity {
+		_, err := v.Repo.Stat(ctx, id)
+		return statusOf(err), err
+	}
+	rc, err := v.Repo.Get(ctx, id)
+	if err != nil {
+		return statusOf(err), err
+	}
+	defer rc.Close()
+	w := reflow.Digester.NewWriter()
+	if _, err := io.Copy(w, rc); err != nil {
+		return cacheError, err
+	}
+	if got := w.Digest(); got != id {
+		return cacheCorrupt, errors.E("verify", id, errors.Integrity, errors.Errorf("object has digest %v", got))
+	}
+	return cacheOK, nil
+}
+
+// Verify verifies the fileset of the provided kind with the provided
+// digest: the fileset and its (resolved) files must be present in
+// the repository and, if v.Generator is set, its assertions must
+// hold. On failure, Verify returns the error that was encountered.
+func (v cacheVerifier) Verify(ctx context.Context, kind assoc.Kind, id digest.Digest) (reflow.Fileset, cacheStatus, error) {
+	var fs reflow.Fileset
+	if status, err := v.verifyObject(ctx, id); status != cacheOK {
+		return fs, status, err
+	}
+	if err := repository.Unmarshal(ctx, v.Repo, id, &fs, kind); err != nil {
+		switch {
+		case errors.Is(errors.NotExist, err):
+			return fs, cacheMissing, err
+		case errors.Transient(err):
+			return fs, cacheError, err
+		}
+		// The object exists but could not be parsed.
+		return fs, cacheCorrupt, err
+	}
+	for _, file := range fs.Files() {
+		if file.IsRef() {
+			continue
+		}
+		if status, err := v.verifyObject(ctx, file.ID); status != cacheOK {
+			return fs, status, errors.E("file", file.ID, err)
+		}
+	}
+	if v.Generator == nil {
+		return fs, cacheOK, nil
+	}
+	a := fs.Assertions()
+	if a.IsEmpty() {
+		return fs, cacheOK, nil
+	}
+	_, keys := reflow.NewRWAssertions(reflow.NewAssertions()).Filter(a)
+	current := make([]*reflow.Assertions, len(keys))
+	for i, key := range keys {
+		var err error
+		if current[i], err = v.Generator.Generate(ctx, key); err != nil {
+			if errors.Is(errors.NotExist, err) {
+				return fs, cacheStale, err
+			}
+			return fs, cacheError, err
+		}
+	}
+	if !reflow.AssertExact(ctx, []*reflow.Assertions{a}, current) {
+		return fs, cacheStale, errors.New("assertions do not match the current state of their blobs or images")
+	}
+	return fs, cacheOK, nil
+}
+
+// cacheMapping is a cache entry, as scanned from the assoc.
+type cacheMapping struct {
+	Key    digest.Digest
+	Values map[assoc.Kind]digest.Digest
+}
+
+func (c *Cmd) cacheVerify(ctx context.Context, args ...string) {
+	var (
+		flags           = flag.NewFlagSet("cache verify", flag.ExitOnError)
+		sampleFlag      = flags.Float64("sample", 1, "the fraction of cache entries to verify, chosen at random")
+		integrityFlag   = flags.Bool("integrity", false, "verify the digests of objects by reading them in full, instead of only checking their existence")
+		assertionsFlag  = flags.Bool("assertions", true, "validate the assertions of cached filesets against the current state of their blobs and images")
+		fixFlag         = flags.Bool("fix", false, "repair or delete the entries which fail verification")
+		concurrencyFlag = flags.Int("concurrency", 50, "the number of entries verified concurrently")
+		help            = `Cache verify verifies entries in the cache. Each selected entry (all of
+them, or a random sample, given by -sample) is verified as follows: its
+fileset, and the files in it, must be present in the repository (and,
+with -integrity, their contents must match their digests); and, unless
+-assertions=false, the fileset's assertions must hold for the current
+state of the blobs (e.g., S3 objects) they refer to, and of the
+docker images with which they were computed: the image references
+(e.g., tags) must resolve to the same images.
+
+The entries which fail verification (their keys, kinds, statuses and
+the errors encountered) are written to standard output. Entries are
+"missing" if objects are missing from the repository, "corrupt" if
+objects do not match their digests, "stale" if their assertions no
+longer hold, and "error" if they could not be verified.
+
+With -fix, entries which fail verification are repaired or deleted:
+an entry whose fileset is valid in one format (kind) but not the other
+is repaired from the valid one; other entries are deleted from the
+cache, so that their results are recomputed. Entries which could not
+be verified are left untouched.`
+	)
+	c.Parse(flags, args, help, "cache verify [-sample fraction] [-integrity] [-assertions=false] [-fix]")
+	if flags.NArg() != 0 || *sampleFlag <= 0 || *sampleFlag > 1 || *concurrencyFlag <= 0 {
+		flags.Usage()
+	}
+	var ass assoc.Assoc
+	c.must(c.Config.Instance(&ass))
+	var repo reflow.Repository
+	c.must(c.Config.Instance(&repo))
+	v := cacheVerifier{Repo: repo, Integrity: *integrityFlag}
+	if *assertionsFlag {
+		var sess *session.Session
+		c.must(c.Config.Instance(&sess))
+		auth, err := runtime.ImageAuthenticator(c.Config, sess)
+		c.must(err)
+		v.Generator = reflow.AssertionGeneratorMux{
+			reflow.BlobAssertionsNamespace: infra2.BlobMux(c.Config, sess),
+			reflow.DockerAssertionsNamespace: &runtime.ImageAssertions{
+				Resolver: &runtime.ImageResolver{Authenticator: auth},
+			},
+		}
+	}
+
+	var (
+		mu       sync.Mutex
+		mappings []cacheMapping
+		scanned  int
+	)
+	start := time.Now()
+	err := ass.Scan(ctx, []assoc.Kind{assoc.Fileset, assoc.FilesetV2}, assoc.MappingHandlerFunc(func(k digest.Digest, vs map[assoc.Kind]digest.Digest, _ time.Time, _ []string) {
+		mu.Lock()
+		defer mu.Unlock()
+		scanned++
+		if *sampleFlag < 1 && rand.Float64() >= *sampleFlag {
+			return
+		}
+		mappings = append(mappings, cacheMapping{k, vs})
+	}))
+	c.must(err)
+	c.Log.Printf("scanned %d cache entries in %s; verifying %d", scanned, time.Since(start), len(mappings))
+
+	var (
+		counts            [maxCacheStatus]int
+		repaired, deleted int
+		tw                tabwriter.Writer
+	)
+	tw.Init(os.Stdout, 4, 4, 1, ' ', 0)
+	err = traverse.Limit(*concurrencyFlag).Each(len(mappings), func(i int) error {
+		m := mappings[i]
+		var (
+			valid    reflow.Fileset
+			anyValid bool
+			broken   []assoc.Kind
+			status   = cacheOK
+		)
+		for kind, id := range m.Values {
+			fs, s, err := v.Verify(ctx, kind, id)
+			if s == cacheOK {
+				valid, anyValid = fs, true
+				continue
+			}
+			mu.Lock()
+			fmt.Fprintf(&tw, "%s\t%s\t%s\t%v\n", m.Key, kind, s, err)
+			mu.Unlock()
+			if s == cacheError {
+				status = cacheError
+				continue
+			}
+			if status != cacheError {
+				status = s
+			}
+			broken = append(broken, kind)
+		}
+		mu.Lock()
+		counts[status]++
+		mu.Unlock()
+		if !*fixFlag || status == cacheOK || status == cacheError {
+			return nil
+		}
+		if !anyValid {
+			if err := ass.Delete(ctx, m.Key); err != nil && !errors.Is(errors.NotExist, err) {
+				c.Log.Errorf("delete %v: %v", m.Key, err)
+				return nil
+			}
+			mu.Lock()
+			deleted++
+			mu.Unlock()
+			return nil
+		}
+		for _, kind := range broken {
+			if err := c.repairCacheEntry(ctx, ass, repo, m.Key, kind, valid); err != nil {
+				c.Log.Errorf("repair %v (%s): %v", m.Key, kind, err)
+				return nil
+			}
+		}
+		mu.Lock()
+		repaired++
+		mu.Unlock()
+		return nil
+	})
+	_ = tw.Flush()
+	c.must(err)
+	c.Log.Printf("verified %d cache entries: %d ok, %d missing, %d corrupt, %d stale, %d errors",
+		len(mappings), counts[cacheOK], counts[cacheMissing], counts[cacheCorrupt], counts[cacheStale], counts[cacheError])
+	if *fixFlag {
+		c.Log.Printf("repaired %d and deleted %d cache entries", repaired, deleted)
+	}
+}
+
+// repairCacheEntry repairs the mapping of the provided kind for cache
+// key k, given a valid fileset for the key: FilesetV2 mappings are
+// rewritten from the valid fileset, while (legacy) Fileset mappings
+// are removed.
+func (c *Cmd) repairCacheEntry(ctx context.Context, ass assoc.Assoc, repo reflow.Repository, k digest.Digest, kind assoc.Kind, fs reflow.Fileset) error {
+	if kind != assoc.FilesetV2 {
+		return ass.Store(ctx, kind, k, digest.Digest{})
+	}
+	id, err := repository.Marshal(ctx, repo, &fs)
+	if err != nil {
+		return err
+	}
+	return ass.Store(ctx, assoc.FilesetV2, k, id)
+}
@@ -0,0 +1,60 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package tool
+
+import (
+	"context"
+	"testing"
+
+	"github.com/grailbio/reflow"
+	"github.com/grailbio/reflow/assoc"
+	"github.com/grailbio/reflow/repository"
+	"github.com/grailbio/reflow/test/testutil"
+)
+
+type etagGenerator string
+
+func (g etagGenerator) Generate(_ context.Context, key reflow.AssertionKey) (*reflow.Assertions, error) {
+	return reflow.AssertionsFromEntry(key, map[string]string{"etag": string(g)}), nil
+}
+
+func TestCacheVerifier(t *testing.T) {
+	var (
+		ctx  = context.Background()
+		repo = testutil.NewInmemoryRepository("")
+		key  = reflow.AssertionKey{Namespace: reflow.BlobAssertionsNamespace, Subject: "s3://bucket/key"}
+	)
+	fs := testutil.WriteFiles(repo, "a", "b")
+	if err := fs.AddAssertions(reflow.AssertionsFromEntry(key, map[string]string{"etag": "v1"})); err != nil {
+		t.Fatal(err)
+	}
+	id, err := repository.Marshal(ctx, repo, &fs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := cacheVerifier{Repo: repo, Generator: etagGenerator("v1")}
+	if _, status, err := v.Verify(ctx, assoc.FilesetV2, id); status != cacheOK {
+		t.Fatalf("got %v (%v), want %v", status, err, cacheOK)
+	}
+	v.Generator = etagGenerator("v2")
+	if _, status, _ := v.Verify(ctx, assoc.FilesetV2, id); status != cacheStale {
+		t.Errorf("got %v, want %v", status, cacheStale)
+	}
+	v.Generator = nil
+
+	file, _ := fs.Map["a"]
+	repo.RawFiles()[file.ID] = []byte("corrupted")
+	if _, status, _ := v.Verify(ctx, assoc.FilesetV2, id); status != cacheOK {
+		t.Errorf("got %v, want %v", status, cacheOK)
+	}
+	v.Integrity = true
+	if _, status, _ := v.Verify(ctx, assoc.FilesetV2, id); status != cacheCorrupt {
+		t.Errorf("got %v, want %v", status, cacheCorrupt)
+	}
+	repo.Delete(ctx, file.ID)
+	if _, status, _ := v.Verify(ctx, assoc.FilesetV2, id); status != cacheMissing {
+		t.Errorf("got %v, want %v", status, cacheMissing)
+	}
+}
@@ -0,0 +1,174 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package tool
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/grailbio/reflow"
+	"github.com/grailbio/reflow/pool"
+)
+
+// adoptPollInterval is the interval at which adopted allocs are
+// checked for completion.
+const adoptPollInterval = time.Minute
+
+func (c *Cmd) cleanupAllocs(ctx context.Context, args ...string) {
+	var (
+		flags        = flag.NewFlagSet("cleanup-allocs", flag.ExitOnError)
+		allUsers     = c.usersFlag(flags, "examine allocs of any user")
+		graceFlag    = flags.Duration("grace", 10*time.Minute, "allocs are stale if their lease expired at least this long ago")
+		adoptFlag    = flags.Bool("adopt", false, "adopt stale allocs: maintain their leases until their execs complete")
+		teardownFlag = flags.Bool("teardown", false, "tear down stale allocs: kill their execs and free them")
+		logsFlag     = flags.String("logs", "", "before tearing down stale allocs, save the logs of their execs under this directory")
+	)
+	help := `Cleanup-allocs identifies stale allocs: allocs whose owners (e.g.,
+reflow runs whose processes died) stopped maintaining their leases
+while they were running execs, and which thus keep their instances
+busy.
+
+By default, stale allocs are listed. With -adopt, cleanup-allocs
+becomes their owner: it maintains their leases until their execs
+complete, after which they are reclaimed as usual. With -teardown,
+their execs are killed and they are freed; their metadata and logs
+are kept by the reflowlet, and with -logs, the logs of their execs
+are also saved locally, in files named by alloc and exec ID.`
+	c.Parse(flags, args, help, "cleanup-allocs [-users=all] [-grace duration] [-adopt | -teardown [-logs dir]]")
+	if flags.NArg() != 0 || (*adoptFlag && *teardownFlag) || (*logsFlag != "" && !*teardownFlag) {
+		flags.Usage()
+	}
+	cluster := c.CurrentPool(ctx)
+	if allUsers() {
+		cluster = c.AllUsersPool(ctx)
+	}
+	allocsCtx, allocsCancel := context.WithTimeout(ctx, 30*time.Second)
+	stale := pool.StaleAllocs(allocsCtx, pool.Allocs(allocsCtx, cluster, c.Log), *graceFlag)
+	allocsCancel()
+
+	var tw tabwriter.Writer
+	tw.Init(c.Stdout, 4, 4, 1, ' ', 0)
+	fmt.Fprintln(&tw, "alloc\towner\tstale")
+	for _, a := range stale {
+		inspect, err := a.Inspect(ctx)
+		if err != nil {
+			c.Log.Errorf("inspect %s: %v", a.ID(), err)
+			continue
+		}
+		fmt.Fprintf(&tw, "%s\t%s\t%s\n", a.ID(), inspect.Meta.Owner, time.Since(inspect.Expires).Round(time.Second))
+	}
+	tw.Flush()
+
+	switch {
+	case *adoptFlag:
+		var wg sync.WaitGroup
+		for _, a := range stale {
+			a := a
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				c.adoptAlloc(ctx, a)
+			}()
+		}
+		wg.Wait()
+	case *teardownFlag:
+		for _, a := range stale {
+			if *logsFlag != "" {
+				if err := c.saveAllocLogs(ctx, a, filepath.Join(*logsFlag, a.ID())); err != nil {
+					c.Errorf("save logs of %s: %v; not tearing it down\n", a.ID(), err)
+					continue
+				}
+			}
+			if err := a.Free(ctx); err != nil {
+				c.Errorf("tear down %s: %v\n", a.ID(), err)
+				continue
+			}
+			c.Log.Printf("tore down alloc %s", a.ID())
+		}
+	}
+}
+
+// adoptAlloc maintains the lease of the given alloc until none of its
+// execs are running.
+func (c *Cmd) adoptAlloc(ctx context.Context, a pool.Alloc) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	keepalivec := make(chan error, 1)
+	go func() {
+		keepalivec <- pool.Keepalive(ctx, c.Log, a)
+	}()
+	c.Log.Printf("adopted alloc %s", a.ID())
+	ticker := time.NewTicker(adoptPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case err := <-keepalivec:
+			c.Errorf("alloc %s: keepalive: %v\n", a.ID(), err)
+			return
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		busy, err := pool.AllocBusy(ctx, a)
+		if err != nil {
+			c.Log.Errorf("alloc %s: %v", a.ID(), err)
+			continue
+		}
+		if !busy {
+			c.Log.Printf("alloc %s: execs complete; releasing", a.ID())
+			return
+		}
+	}
+}
+
+// saveAllocLogs saves the standard output and error of each of the
+// given alloc's execs in dir.
+func (c *Cmd) saveAllocLogs(ctx context.Context, a pool.Alloc, dir string) error {
+	execs, err := a.Execs(ctx)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return err
+	}
+	for _, x := range execs {
+		for _, stdout := range []bool{true, false} {
+			name := x.ID().Hex() + ".stderr"
+			if stdout {
+				name = x.ID().Hex() + ".stdout"
+			}
+			if err := saveExecLogs(ctx, x, stdout, filepath.Join(dir, name)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// saveExecLogs saves the exec's standard output (or error) in the
+// named file.
+func saveExecLogs(ctx context.Context, x reflow.Exec, stdout bool, path string) error {
+	rc, err := x.Logs(ctx, stdout, !stdout, false)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, rc); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -9,6 +9,8 @@ import (
 	"fmt"
 	"os"
 	"runtime"
+
+	"github.com/grailbio/reflow/errors"
 )
 
 // Parse parses the provided FlagSet from the provided arguments. It
@@ -41,6 +43,20 @@ func (c *Cmd) Parse(fs *flag.FlagSet, args []string, help, usage string) {
 func (c Cmd) must(err error) {
 	if err != nil {
 		_, file, line, _ := runtime.Caller(1)
-		c.Fatalf("%s:%d: %v", file, line, err)
+		fmt.Fprintf(c.Stderr, "%s:%d: %v\n", file, line, err)
+		c.printHints(err)
+		c.Exit(1)
+	}
+}
+
+// printHints prints to stderr the remediation hints for err, if it
+// is a well-known failure, as "What you can do" sections.
+func (c Cmd) printHints(err error) {
+	for _, hint := range errors.Hints(err) {
+		fmt.Fprintln(c.Stderr)
+		fmt.Fprintf(c.Stderr, "What you can do (%s)\n", hint.Problem)
+		for _, action := range hint.Actions {
+			fmt.Fprintf(c.Stderr, "\t- %s\n", action)
+		}
 	}
 }
@@ -16,6 +16,18 @@ import (
 )
 
 func (c *Cmd) config(ctx context.Context, args ...string) {
+	if len(args) > 0 && args[0] == "doc" {
+		c.configDoc(ctx, args[1:]...)
+		return
+	}
+	if len(args) > 0 && args[0] == "set" {
+		c.configSet(ctx, args[1:]...)
+		return
+	}
+	if len(args) > 0 && args[0] == "unset" {
+		c.configUnset(ctx, args[1:]...)
+		return
+	}
 	var (
 		flags  = flag.NewFlagSet("config", flag.ExitOnError)
 		header = `Config writes the current Reflow configuration to standard 
@@ -30,7 +42,15 @@ modified and overriden:
 
 	$ reflow config > myconfig
 	<edit myconfig>
-	$ reflow -config myconfig ...`
+	$ reflow -config myconfig ...
+
+A complete reference of the configuration, including the YAML keys
+of the configured providers, is written by "reflow config doc".
+
+Keys of the configuration file may be edited by "reflow config set"
+and "reflow config unset", e.g.:
+
+	$ reflow config set cluster=ec2cluster ec2cluster.maxinstances=100`
 	)
 	marshalFlag := flags.Bool("marshal", false, "marshal the configuration before displaying it")
 	// Construct a help string from the available providers.
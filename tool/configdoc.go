@@ -0,0 +1,286 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package tool
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// configKeyDoc documents a toplevel configuration key.
+type configKeyDoc struct {
+	Key       string
+	Providers []providerDoc
+}
+
+// providerDoc documents a provider of a configuration key.
+type providerDoc struct {
+	Name  string
+	Usage string
+	// Configured tells whether the provider is the one configured for
+	// the key.
+	Configured bool
+	Args       []argDoc
+	// Fields are the YAML keys of the provider's configuration. They
+	// are known only for configured providers.
+	Fields []fieldDoc
+	// Err is the error, if any, encountered while instantiating the
+	// configured provider.
+	Err error
+}
+
+// argDoc documents a provider argument (e.g., "kv,labels=...").
+type argDoc struct {
+	Name, Help, Default string
+}
+
+// fieldDoc documents a YAML key of a provider's configuration.
+type fieldDoc struct {
+	// Name is the (dotted, for nested keys) YAML key.
+	Name string
+	// Type is the type of the key's values.
+	Type string
+	// Value is the key's current (or default) value.
+	Value string
+}
+
+func (c *Cmd) configDoc(ctx context.Context, args ...string) {
+	var (
+		flags = flag.NewFlagSet("config doc", flag.ExitOnError)
+		help  = `Config doc writes a reference of Reflow's configuration: the
+toplevel configuration keys, the providers available for each key
+together with their arguments and defaults, and the YAML keys of the
+configured providers, with their types and current values.
+
+The configured providers are instantiated in order to determine their
+YAML keys; providers which fail to instantiate are documented without
+them.`
+	)
+	markdownFlag := flags.Bool("markdown", false, "write the reference as Markdown")
+	c.Parse(flags, args, help, "config doc")
+	if flags.NArg() != 0 {
+		flags.Usage()
+	}
+	var docs []configKeyDoc
+	for key, providers := range c.Config.Help() {
+		doc := configKeyDoc{Key: key}
+		configured := c.configuredProvider(key)
+		for _, p := range providers {
+			pdoc := providerDoc{Name: p.Name, Usage: p.Usage, Configured: p.Name == configured}
+			for _, arg := range p.Args {
+				pdoc.Args = append(pdoc.Args, argDoc{arg.Name, arg.Help, arg.DefaultValue})
+			}
+			if pdoc.Configured {
+				pdoc.Fields, pdoc.Err = c.providerFields(key)
+			}
+			doc.Providers = append(doc.Providers, pdoc)
+		}
+		sort.Slice(doc.Providers, func(i, j int) bool { return doc.Providers[i].Name < doc.Providers[j].Name })
+		docs = append(docs, doc)
+	}
+	sort.Slice(docs, func(i, j int) bool { return docs[i].Key < docs[j].Key })
+	if *markdownFlag {
+		writeConfigDocMarkdown(c.Stdout, docs)
+	} else {
+		writeConfigDocText(c.Stdout, docs)
+	}
+}
+
+// configuredProvider returns the name of the provider configured for
+// the given key, or "" if none is.
+func (c *Cmd) configuredProvider(key string) string {
+	v, ok := c.Config.Value(key).(string)
+	if !ok {
+		return ""
+	}
+	return strings.SplitN(v, ",", 2)[0]
+}
+
+// providerFields instantiates the provider configured for the given
+// key and returns the YAML keys of its configuration.
+func (c *Cmd) providerFields(key string) ([]fieldDoc, error) {
+	typ, ok := c.Schema[key]
+	if !ok {
+		return nil, nil
+	}
+	return instanceFields(c.Config, typ)
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// yamlFields returns the YAML keys of the struct (or pointer to, or
+// interface holding, a struct) v, as determined by the fields' yaml
+// tags. Fields without yaml tags, or tagged "-", are omitted. Keys of
+// nested structs are prefixed by the key of the enclosing field.
+func yamlFields(v reflect.Value, prefix string) []fieldDoc {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	var fields []fieldDoc
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, ok := f.Tag.Lookup("yaml")
+		if !ok || f.PkgPath != "" {
+			continue
+		}
+		opts := strings.Split(tag, ",")
+		name := opts[0]
+		if name == "-" {
+			continue
+		}
+		fv := v.Field(i)
+		if fv.Kind() == reflect.Struct && hasOption(opts[1:], "inline") {
+			fields = append(fields, yamlFields(fv, prefix)...)
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+		if fv.Kind() == reflect.Struct && fv.Type() != reflect.TypeOf(time.Time{}) {
+			fields = append(fields, yamlFields(fv, name)...)
+			continue
+		}
+		fields = append(fields, fieldDoc{Name: name, Type: typeName(f.Type), Value: valueString(fv)})
+	}
+	return fields
+}
+
+func hasOption(opts []string, opt string) bool {
+	for _, o := range opts {
+		if o == opt {
+			return true
+		}
+	}
+	return false
+}
+
+// typeName returns a user-facing name of the given type.
+func typeName(t reflect.Type) string {
+	if t == durationType {
+		return "duration"
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return typeName(t.Elem())
+	case reflect.Slice, reflect.Array:
+		return "list of " + typeName(t.Elem())
+	case reflect.Map:
+		return "map of " + typeName(t.Key()) + " to " + typeName(t.Elem())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.String:
+		return "string"
+	default:
+		return t.Kind().String()
+	}
+}
+
+// valueString formats the given value, returning "" for zero values.
+func valueString(v reflect.Value) string {
+	if v.IsZero() {
+		return ""
+	}
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+func writeConfigDocText(w io.Writer, docs []configKeyDoc) {
+	for _, doc := range docs {
+		fmt.Fprintf(w, "%s\n", doc.Key)
+		for _, p := range doc.Providers {
+			configured := ""
+			if p.Configured {
+				configured = " (configured)"
+			}
+			fmt.Fprintf(w, "\t%s%s: %s\n", p.Name, configured, p.Usage)
+			for _, arg := range p.Args {
+				fmt.Fprintf(w, "\t\targument %s: %s%s\n", arg.Name, arg.Help, defaultString(arg.Default))
+			}
+			for _, f := range p.Fields {
+				fmt.Fprintf(w, "\t\t%s: %s%s\n", f.Name, f.Type, valueSuffix(f.Value))
+			}
+			if p.Err != nil {
+				fmt.Fprintf(w, "\t\t(keys unavailable: %v)\n", p.Err)
+			}
+		}
+	}
+}
+
+func writeConfigDocMarkdown(w io.Writer, docs []configKeyDoc) {
+	fmt.Fprintln(w, "# Reflow configuration reference")
+	for _, doc := range docs {
+		fmt.Fprintf(w, "\n## %s\n", doc.Key)
+		for _, p := range doc.Providers {
+			configured := ""
+			if p.Configured {
+				configured = " (configured)"
+			}
+			fmt.Fprintf(w, "\n### %s%s\n\n%s\n", p.Name, configured, p.Usage)
+			if len(p.Args) > 0 {
+				fmt.Fprintf(w, "\n| Argument | Description | Default |\n| --- | --- | --- |\n")
+				for _, arg := range p.Args {
+					fmt.Fprintf(w, "| `%s` | %s | %s |\n", arg.Name, markdownCell(arg.Help), markdownCode(arg.Default))
+				}
+			}
+			if len(p.Fields) > 0 {
+				fmt.Fprintf(w, "\n| Key | Type | Value |\n| --- | --- | --- |\n")
+				for _, f := range p.Fields {
+					fmt.Fprintf(w, "| `%s` | %s | %s |\n", f.Name, f.Type, markdownCode(f.Value))
+				}
+			}
+			if p.Err != nil {
+				fmt.Fprintf(w, "\nKeys unavailable: %s\n", markdownCell(p.Err.Error()))
+			}
+		}
+	}
+}
+
+func defaultString(v string) string {
+	if v == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (default %q)", v)
+}
+
+func valueSuffix(v string) string {
+	if v == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (value %s)", v)
+}
+
+func markdownCode(v string) string {
+	if v == "" {
+		return ""
+	}
+	return "`" + strings.ReplaceAll(v, "`", "'") + "`"
+}
+
+func markdownCell(v string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(v)
+}
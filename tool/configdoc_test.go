@@ -0,0 +1,72 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package tool
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testNested struct {
+	AMI string `yaml:"ami,omitempty"`
+}
+
+type testProvider struct {
+	Ignored    string `yaml:"-"`
+	Untagged   string
+	DiskSlices int                `yaml:"diskslices"`
+	MaxCost    float64            `yaml:"maxhourlycostusd"`
+	Spot       bool               `yaml:"spot,omitempty"`
+	Timeout    time.Duration      `yaml:"timeout"`
+	Types      []string           `yaml:"instancetypes,omitempty"`
+	Coverage   map[string]float64 `yaml:"reservedcoverage,omitempty"`
+	Arm64      testNested         `yaml:"arm64,omitempty"`
+}
+
+func TestYAMLFields(t *testing.T) {
+	p := &testProvider{DiskSlices: 4, Timeout: time.Minute, Arm64: testNested{AMI: "ami-123"}}
+	var v interface{} = p
+	got := yamlFields(reflect.ValueOf(&v).Elem(), "")
+	want := []fieldDoc{
+		{"diskslices", "integer", "4"},
+		{"maxhourlycostusd", "number", ""},
+		{"spot", "boolean", ""},
+		{"timeout", "duration", "1m0s"},
+		{"instancetypes", "list of string", ""},
+		{"reservedcoverage", "map of string to number", ""},
+		{"arm64.ami", "string", "ami-123"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestWriteConfigDocMarkdown(t *testing.T) {
+	docs := []configKeyDoc{{
+		Key: "cluster",
+		Providers: []providerDoc{{
+			Name:       "ec2cluster",
+			Usage:      "configure a cluster",
+			Configured: true,
+			Args:       []argDoc{{"name", "the | name", "default"}},
+			Fields:     []fieldDoc{{"diskslices", "integer", "4"}},
+		}},
+	}}
+	var b bytes.Buffer
+	writeConfigDocMarkdown(&b, docs)
+	for _, want := range []string{
+		"## cluster",
+		"### ec2cluster (configured)",
+		"| `name` | the \\| name | `default` |",
+		"| `diskslices` | integer | `4` |",
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("output does not contain %q:\n%s", want, b.String())
+		}
+	}
+}
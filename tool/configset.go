@@ -0,0 +1,274 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package tool
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/grailbio/infra"
+	"gopkg.in/yaml.v2"
+)
+
+// configEdit is an edit of a configuration key. Keys are dotted
+// paths: toplevel keys (e.g., "cluster") name a provider, while
+// the keys of a provider's configuration are prefixed by the
+// provider's name (e.g., "ec2cluster.maxinstances").
+type configEdit struct {
+	Key string
+	// Value is the key's new value, unless the key is unset.
+	Value interface{}
+	Unset bool
+}
+
+func (c *Cmd) configSet(ctx context.Context, args ...string) {
+	var (
+		flags = flag.NewFlagSet("config set", flag.ExitOnError)
+		help  = `Config set sets the given keys of the configuration file. Each
+argument is of the form key=value, where key is either a toplevel
+key, whose value names its provider (e.g., "cluster=ec2cluster"), or
+a dotted key of the configuration of a provider, prefixed by the
+provider's name (e.g., "ec2cluster.maxinstances=100"). Values are
+parsed as YAML, so that, e.g., "ec2cluster.spot=true" sets a boolean.
+
+The edited configuration is validated before it is written: keys must
+be toplevel keys, or keys of a provider that is configured, and the
+providers of the edited keys must instantiate. The previous
+configuration file is saved with the suffix ".bak". Comments in the
+configuration file are not preserved.`
+	)
+	c.Parse(flags, args, help, "config set key=value...")
+	if flags.NArg() == 0 {
+		flags.Usage()
+	}
+	edits, err := parseConfigEdits(false, flags.Args())
+	if err != nil {
+		c.Fatal(err)
+	}
+	c.editConfig(edits)
+}
+
+func (c *Cmd) configUnset(ctx context.Context, args ...string) {
+	var (
+		flags = flag.NewFlagSet("config unset", flag.ExitOnError)
+		help  = `Config unset removes the given keys from the configuration file.
+Keys are named as in "reflow config set"; removing a toplevel key
+reverts it to its default provider, if any.
+
+The edited configuration is validated before it is written. The
+previous configuration file is saved with the suffix ".bak". Comments
+in the configuration file are not preserved.`
+	)
+	c.Parse(flags, args, help, "config unset key...")
+	if flags.NArg() == 0 {
+		flags.Usage()
+	}
+	edits, err := parseConfigEdits(true, flags.Args())
+	if err != nil {
+		c.Fatal(err)
+	}
+	c.editConfig(edits)
+}
+
+// editConfig applies the given edits to the configuration file,
+// validates the result, and then writes it, after saving the
+// previous version.
+func (c *Cmd) editConfig(edits []configEdit) {
+	if c.ConfigFile == "" {
+		c.Fatal("no configuration file")
+	}
+	b, err := ioutil.ReadFile(c.ConfigFile)
+	if err != nil && !os.IsNotExist(err) {
+		c.Fatal(err)
+	}
+	keys := make(infra.Keys)
+	if err := yaml.Unmarshal(b, keys); err != nil {
+		c.Fatalf("config %v: %v", c.ConfigFile, err)
+	}
+	if err := editConfigKeys(keys, edits); err != nil {
+		c.Fatal(err)
+	}
+	if err := c.checkConfig(keys, edits); err != nil {
+		c.Fatalf("config %v: %v", c.ConfigFile, err)
+	}
+	out, err := yaml.Marshal(keys)
+	c.must(err)
+	if len(b) > 0 {
+		c.must(ioutil.WriteFile(c.ConfigFile+".bak", b, 0666))
+	}
+	c.must(ioutil.WriteFile(c.ConfigFile, out, 0666))
+}
+
+// checkConfig validates the edited keys of the configuration file
+// keys: each edited key must be a toplevel key of the schema, or the
+// key of a configured provider's configuration, and the providers of
+// the edited keys must instantiate.
+func (c *Cmd) checkConfig(keys infra.Keys, edits []configEdit) error {
+	// The configuration file's keys override the builtin ones, as
+	// when the configuration is loaded.
+	merged := make(infra.Keys)
+	for k, v := range c.SchemaKeys {
+		merged[k] = v
+	}
+	for _, e := range edits {
+		if e.Unset && !strings.Contains(e.Key, ".") {
+			delete(merged, e.Key)
+		}
+	}
+	for k, v := range keys {
+		merged[k] = v
+	}
+	config, err := c.Schema.Make(merged)
+	if err != nil {
+		return err
+	}
+	checked := make(map[string][]fieldDoc)
+	for _, e := range edits {
+		parts := strings.SplitN(e.Key, ".", 2)
+		key := parts[0]
+		if _, ok := c.Schema[key]; !ok {
+			key = ""
+			for k := range c.Schema {
+				if v, ok := merged[k].(string); ok && strings.SplitN(v, ",", 2)[0] == parts[0] {
+					key = k
+					break
+				}
+			}
+		}
+		if key == "" {
+			if e.Unset {
+				continue
+			}
+			return fmt.Errorf("%s: %s is neither a configuration key nor a configured provider", e.Key, parts[0])
+		}
+		fields, ok := checked[key]
+		if !ok {
+			if fields, err = instanceFields(config, c.Schema[key]); err != nil {
+				return fmt.Errorf("%s: %v", key, err)
+			}
+			checked[key] = fields
+		}
+		if len(parts) == 2 && !e.Unset && !hasConfigField(fields, parts[1]) {
+			return fmt.Errorf("%s: provider %s has no key %s", e.Key, parts[0], parts[1])
+		}
+	}
+	return nil
+}
+
+// instanceFields instantiates the provider of the given schema type
+// from config and returns the YAML keys of its configuration.
+func instanceFields(config infra.Config, typ interface{}) ([]fieldDoc, error) {
+	ptr := reflect.New(reflect.TypeOf(typ).Elem())
+	if err := config.Instance(ptr.Interface()); err != nil {
+		return nil, err
+	}
+	return yamlFields(ptr.Elem(), ""), nil
+}
+
+// hasConfigField tells whether key is one of the given fields, a
+// prefix of one (i.e., a nested configuration), or a key of a
+// map-valued field.
+func hasConfigField(fields []fieldDoc, key string) bool {
+	for _, f := range fields {
+		switch {
+		case f.Name == key, strings.HasPrefix(f.Name, key+"."):
+			return true
+		case strings.HasPrefix(key, f.Name+".") && strings.HasPrefix(f.Type, "map "):
+			return true
+		}
+	}
+	return false
+}
+
+// parseConfigEdits parses the arguments of "config set" (key=value)
+// or, if unset is true, of "config unset" (key).
+func parseConfigEdits(unset bool, args []string) ([]configEdit, error) {
+	edits := make([]configEdit, len(args))
+	for i, arg := range args {
+		if unset {
+			if arg == "" || strings.Contains(arg, "=") {
+				return nil, fmt.Errorf("invalid key %q", arg)
+			}
+			edits[i] = configEdit{Key: arg, Unset: true}
+			continue
+		}
+		parts := strings.SplitN(arg, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid argument %q: expected key=value", arg)
+		}
+		edits[i].Key = parts[0]
+		if err := yaml.Unmarshal([]byte(parts[1]), &edits[i].Value); err != nil {
+			return nil, fmt.Errorf("%s: invalid value %q: %v", parts[0], parts[1], err)
+		}
+		if edits[i].Value == nil {
+			edits[i].Value = parts[1]
+		}
+	}
+	for _, e := range edits {
+		for _, part := range strings.Split(e.Key, ".") {
+			if part == "" {
+				return nil, fmt.Errorf("invalid key %q", e.Key)
+			}
+		}
+	}
+	return edits, nil
+}
+
+// editConfigKeys applies the given edits to the keys of a
+// configuration file. Configurations left empty by unset keys are
+// removed.
+func editConfigKeys(keys infra.Keys, edits []configEdit) error {
+	m := make(map[interface{}]interface{}, len(keys))
+	for k, v := range keys {
+		m[k] = v
+	}
+	for _, e := range edits {
+		if err := editConfigMap(m, strings.Split(e.Key, "."), e); err != nil {
+			return fmt.Errorf("%s: %v", e.Key, err)
+		}
+	}
+	for k := range keys {
+		delete(keys, k)
+	}
+	for k, v := range m {
+		keys[k.(string)] = v
+	}
+	return nil
+}
+
+func editConfigMap(m map[interface{}]interface{}, path []string, e configEdit) error {
+	if len(path) == 1 {
+		if e.Unset {
+			delete(m, path[0])
+		} else {
+			m[path[0]] = e.Value
+		}
+		return nil
+	}
+	v, ok := m[path[0]]
+	if !ok {
+		if e.Unset {
+			return nil
+		}
+		v = make(map[interface{}]interface{})
+		m[path[0]] = v
+	}
+	sub, ok := v.(map[interface{}]interface{})
+	if !ok {
+		return fmt.Errorf("%s is not a map", path[0])
+	}
+	if err := editConfigMap(sub, path[1:], e); err != nil {
+		return err
+	}
+	if len(sub) == 0 {
+		delete(m, path[0])
+	}
+	return nil
+}
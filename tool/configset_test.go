@@ -0,0 +1,93 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package tool
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/grailbio/infra"
+	"gopkg.in/yaml.v2"
+)
+
+func TestEditConfigKeys(t *testing.T) {
+	keys := make(infra.Keys)
+	if err := yaml.Unmarshal([]byte(`
+cluster: ec2cluster
+ec2cluster:
+  maxinstances: 10
+  ami: ami-123
+tls: tls,file=/tmp/reflow.pem
+`), keys); err != nil {
+		t.Fatal(err)
+	}
+	edits, err := parseConfigEdits(false, []string{"ec2cluster.maxinstances=100", "ec2cluster.spot=true", "ec2cluster.arm64.ami=ami-456", "cache=off"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	unsets, err := parseConfigEdits(true, []string{"ec2cluster.ami", "tls", "assoc.table"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := editConfigKeys(keys, append(edits, unsets...)); err != nil {
+		t.Fatal(err)
+	}
+	want := infra.Keys{
+		"cluster": "ec2cluster",
+		"cache":   "off",
+		"ec2cluster": map[interface{}]interface{}{
+			"maxinstances": 100,
+			"spot":         true,
+			"arm64":        map[interface{}]interface{}{"ami": "ami-456"},
+		},
+	}
+	if !reflect.DeepEqual(keys, want) {
+		t.Errorf("got %v, want %v", keys, want)
+	}
+	// Configurations left empty are removed.
+	unsets, _ = parseConfigEdits(true, []string{"ec2cluster.arm64.ami"})
+	if err := editConfigKeys(keys, unsets); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := keys["ec2cluster"].(map[interface{}]interface{})["arm64"]; ok {
+		t.Error("empty configuration arm64 was not removed")
+	}
+	edits, _ = parseConfigEdits(false, []string{"cluster.maxinstances=1"})
+	if err := editConfigKeys(keys, edits); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestParseConfigEditsInvalid(t *testing.T) {
+	for _, args := range [][]string{{"cluster"}, {"=ec2cluster"}, {"ec2cluster..spot=true"}} {
+		if _, err := parseConfigEdits(false, args); err == nil {
+			t.Errorf("%v: expected error", args)
+		}
+	}
+	if _, err := parseConfigEdits(true, []string{"cluster=ec2cluster"}); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestHasConfigField(t *testing.T) {
+	fields := []fieldDoc{
+		{"maxinstances", "integer", ""},
+		{"arm64.ami", "string", ""},
+		{"reservedcoverage", "map of string to number", ""},
+	}
+	for key, want := range map[string]bool{
+		"maxinstances":               true,
+		"arm64":                      true,
+		"arm64.ami":                  true,
+		"arm64.ami.x":                false,
+		"reservedcoverage.m5.xlarge": true,
+		"maxinstance":                false,
+		"spot":                       false,
+	} {
+		if got := hasConfigField(fields, key); got != want {
+			t.Errorf("%s: got %v, want %v", key, got, want)
+		}
+	}
+}
@@ -62,6 +62,16 @@ func (c *Cost) Mul(v float64) {
 	c.value *= v
 }
 
+// Value returns the cost's value, in USD.
+func (c Cost) Value() float64 {
+	return c.value
+}
+
+// Exact tells whether the cost is exact (and not an upper bound).
+func (c Cost) Exact() bool {
+	return c.typ == costTypeExact
+}
+
 func (c Cost) String() string {
 	if c.typ == costTypeUnknown {
 		return "<unknown>"
@@ -80,18 +90,27 @@ const exactCostTimeDiffTolerance = 2 * time.Minute
 type costComputer struct {
 	q      spotfeed.Querier
 	region string
+	// coverage is the cluster's reserved coverage (see
+	// ec2cluster.Cluster.ReservedCoverage), which is applied to
+	// instances known to be on-demand.
+	coverage map[string]float64
 }
 
 func (c *costComputer) compute(instanceId string, instanceType string, instanceTerminated time.Time, costStart, costEnd time.Time) (cost Cost) {
 	var (
 		exactStart, exactEnd time.Time
 		start, end           = costStart, costEnd
+		onDemand             bool
 	)
 	if c.q != nil {
 		// We don't care about errors here, because we'll simply compute the upper-bound costs instead.
 		if spotcost, err := c.q.Query(instanceId, spotfeed.Period{Start: start, End: end}, instanceTerminated); err == nil {
 			cost.Add(NewCostExact(spotcost.ChargeUSD))
 			exactStart, exactEnd = spotcost.Start, spotcost.End
+		} else {
+			// Instances without spot data feed data are on-demand, and
+			// may thus be covered by reservations.
+			onDemand = true
 		}
 	}
 	ubtimes := make([]spotfeed.Period, 0)
@@ -113,7 +132,11 @@ func (c *costComputer) compute(instanceId string, instanceType string, instanceT
 	}
 	if instanceType != "" {
 		for _, ubt := range ubtimes {
-			if hourlyPriceUsd := ec2cluster.OnDemandPrice(instanceType, c.region); hourlyPriceUsd > 0 {
+			hourlyPriceUsd := ec2cluster.OnDemandPrice(instanceType, c.region)
+			if onDemand {
+				hourlyPriceUsd = ec2cluster.CoveredPrice(instanceType, hourlyPriceUsd, c.coverage)
+			}
+			if hourlyPriceUsd > 0 {
 				cost.Add(NewCostUB(hourlyPriceUsd * ubt.End.Sub(ubt.Start).Hours()))
 			}
 		}
@@ -124,6 +147,8 @@ func (c *costComputer) compute(instanceId string, instanceType string, instanceT
 // costComputer returns a cost computer.
 // If exact is true, then it is backed by AWS Spot instance data feed data for the given {start, end} time range (if available).
 // if exact is false, then start/end times are ignored.
+// The reserved coverage of the configured cluster, if any, is applied to the
+// instances which the spot data feed shows to be on-demand.
 func (c *Cmd) costComputer(ctx context.Context, exact bool, start, end time.Time) (cc *costComputer) {
 	var sess *session.Session
 	if err := c.Config.Instance(&sess); err != nil {
@@ -131,6 +156,10 @@ func (c *Cmd) costComputer(ctx context.Context, exact bool, start, end time.Time
 		return
 	}
 	cc = &costComputer{region: *sess.Config.Region}
+	var cluster *ec2cluster.Cluster
+	if err := c.Config.Instance(&cluster); err == nil {
+		cc.coverage = cluster.ReservedCoverage
+	}
 	if !exact {
 		return
 	}
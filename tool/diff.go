@@ -0,0 +1,276 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package tool
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/grailbio/base/digest"
+	"github.com/grailbio/reflow/taskdb"
+)
+
+// diffKind classifies an exec of the second of two compared runs.
+type diffKind int
+
+const (
+	// diffRerun is an exec which the first run completed, but which
+	// the second ran again: its cached result was not used (e.g., it
+	// was invalidated because its assertions no longer held).
+	diffRerun diffKind = iota
+	// diffRetried is an exec which the first run attempted but did not
+	// complete.
+	diffRetried
+	// diffInputs is an exec whose image and command were run by the
+	// first run (with the same ident), but whose digest differed: its
+	// inputs (or resources) changed.
+	diffInputs
+	// diffCommand is an exec whose ident was run by the first run, but
+	// with a different image or command.
+	diffCommand
+	// diffNew is an exec whose ident was not run by the first run.
+	diffNew
+)
+
+// String returns a description of the kind.
+func (k diffKind) String() string {
+	switch k {
+	case diffRerun:
+		return "rerun (cached result not used)"
+	case diffRetried:
+		return "retried (incomplete in first run)"
+	case diffInputs:
+		return "changed (inputs)"
+	case diffCommand:
+		return "changed (image or command)"
+	case diffNew:
+		return "new"
+	default:
+		return fmt.Sprintf("diffKind(%d)", k)
+	}
+}
+
+// diffExec is an exec of the second of two compared runs.
+type diffExec struct {
+	Kind diffKind
+	taskdb.Task
+}
+
+// diffIdent compares the tasks of two runs with a given ident.
+type diffIdent struct {
+	Ident string
+	// Tasks are the number of tasks (exec attempts) of each run.
+	Tasks [2]int
+	// Duration is the total duration of the tasks of each run.
+	Duration [2]time.Duration
+	// Cost is the total cost of the tasks of each run.
+	Cost [2]Cost
+	// Kinds counts the execs of the second run by their kind.
+	Kinds map[diffKind]int
+}
+
+// runDiff is the difference between two runs, as recorded in taskdb:
+// which execs the second run ran (and why), and how the runtime and
+// cost of each ident changed.
+type runDiff struct {
+	Execs  []diffExec
+	Idents []diffIdent
+}
+
+// diffRuns compares the tasks a and b of two runs, where the cost of
+// each task is computed by taskCost. Execs are ordered by kind and
+// ident, and idents by ident.
+func diffRuns(a, b []taskdb.Task, taskCost func(taskdb.Task) Cost) runDiff {
+	var (
+		flows     = make(map[digest.Digest]bool)
+		completed = make(map[digest.Digest]bool)
+		cmds      = make(map[string]map[taskdb.ImgCmdID]bool)
+		idents    = make(map[string]*diffIdent)
+	)
+	add := func(i int, t taskdb.Task) {
+		id := idents[t.Ident]
+		if id == nil {
+			id = &diffIdent{Ident: t.Ident, Kinds: make(map[diffKind]int)}
+			idents[t.Ident] = id
+		}
+		id.Tasks[i]++
+		if st, et := t.StartEnd(); !st.IsZero() && et.After(st) {
+			id.Duration[i] += et.Sub(st)
+		}
+		id.Cost[i].Add(taskCost(t))
+	}
+	for _, t := range a {
+		if !t.ID.IsValid() {
+			continue
+		}
+		add(0, t)
+		for _, k := range append([]digest.Digest{t.FlowID}, t.CacheKeys...) {
+			flows[k] = true
+			if t.Err.Err == nil && !t.End.IsZero() {
+				completed[k] = true
+			}
+		}
+		if cmds[t.Ident] == nil {
+			cmds[t.Ident] = make(map[taskdb.ImgCmdID]bool)
+		}
+		cmds[t.Ident][t.ImgCmdID] = true
+	}
+	// Only the last attempt of each of the second run's execs is
+	// reported.
+	last := make(map[digest.Digest]taskdb.Task)
+	for _, t := range b {
+		if !t.ID.IsValid() {
+			continue
+		}
+		add(1, t)
+		if prev, ok := last[t.FlowID]; ok && prev.Attempt > t.Attempt {
+			continue
+		}
+		last[t.FlowID] = t
+	}
+	var d runDiff
+	for _, t := range last {
+		x := diffExec{Kind: diffNew, Task: t}
+		for _, k := range append([]digest.Digest{t.FlowID}, t.CacheKeys...) {
+			switch {
+			case completed[k]:
+				x.Kind = diffRerun
+			case flows[k] && x.Kind != diffRerun:
+				x.Kind = diffRetried
+			}
+		}
+		if x.Kind == diffNew && cmds[t.Ident] != nil {
+			x.Kind = diffCommand
+			if cmds[t.Ident][t.ImgCmdID] {
+				x.Kind = diffInputs
+			}
+		}
+		idents[t.Ident].Kinds[x.Kind]++
+		d.Execs = append(d.Execs, x)
+	}
+	sort.Slice(d.Execs, func(i, j int) bool {
+		if d.Execs[i].Kind != d.Execs[j].Kind {
+			return d.Execs[i].Kind < d.Execs[j].Kind
+		}
+		if d.Execs[i].Ident != d.Execs[j].Ident {
+			return d.Execs[i].Ident < d.Execs[j].Ident
+		}
+		return d.Execs[i].FlowID.Less(d.Execs[j].FlowID)
+	})
+	for _, id := range idents {
+		d.Idents = append(d.Idents, *id)
+	}
+	sort.Slice(d.Idents, func(i, j int) bool { return d.Idents[i].Ident < d.Idents[j].Ident })
+	return d
+}
+
+// writeText writes the difference to w; the execs of the second
+// run are listed if execs is set.
+func (d runDiff) writeText(w io.Writer, execs bool) {
+	fmt.Fprintln(w, "ident\ttasks\tduration\tcost\trerun\tretried\tchanged\tnew")
+	for _, id := range d.Idents {
+		costDelta := id.Cost[1].Value() - id.Cost[0].Value()
+		fmt.Fprintf(w, "%s\t%d -> %d\t%s -> %s\t%s -> %s (%+.4f)\t%d\t%d\t%d\t%d\n",
+			id.Ident, id.Tasks[0], id.Tasks[1],
+			id.Duration[0].Round(time.Second), id.Duration[1].Round(time.Second),
+			id.Cost[0], id.Cost[1], costDelta,
+			id.Kinds[diffRerun], id.Kinds[diffRetried], id.Kinds[diffInputs]+id.Kinds[diffCommand], id.Kinds[diffNew])
+	}
+	if !execs {
+		return
+	}
+	fmt.Fprintln(w, "\nident\tflow\ttaskid\twhy")
+	for _, x := range d.Execs {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", x.Ident, x.FlowID.Short(), x.ID.IDShort(), x.Kind)
+	}
+}
+
+func (c *Cmd) diff(ctx context.Context, args ...string) {
+	var (
+		flags         = flag.NewFlagSet("diff", flag.ExitOnError)
+		execsFlag     = flags.Bool("execs", false, "also list each exec run by the second run, and why it ran")
+		exactCostFlag = flags.Bool("exact_cost", true, "use the spot instance data feed to compute exact costs (if available)")
+		help          = `Diff compares two runs, as recorded in taskdb, to explain why the
+second run ran the execs it did, e.g., why a change which should not
+have affected a program's results caused it to recompute much of its
+pipeline.
+
+For each ident, diff shows the number of tasks (exec attempts), their
+total duration and their total cost in each run, followed by the
+number of execs of the second run of each kind:
+
+	rerun    the first run completed the same exec (of the same
+	         digest), but its cached result was not used: it was
+	         invalidated (e.g., its assertions no longer held), or
+	         it was removed from the cache
+	retried  the first run attempted the same exec, but did not
+	         complete it
+	changed  the first run ran the ident, but the exec's digest
+	         differed: either its image or command changed, or its
+	         inputs changed, typically because an exec upstream
+	         changed (-execs tells which)
+	new      the first run did not run the ident
+
+Only the execs which a run computed are recorded in taskdb: results
+which a run retrieved from the cache are not. Execs of the first run
+which the second run did not run were thus either retrieved from the
+cache, or are no longer part of the program.
+` + costHelp
+	)
+	c.Parse(flags, args, help, "diff [-execs] [-exact_cost] runA runB")
+	if flags.NArg() != 2 {
+		flags.Usage()
+	}
+	var tdb taskdb.TaskDB
+	if err := c.Config.Instance(&tdb); err != nil {
+		c.Fatalf("taskdb: %v", err)
+	}
+	if tdb == nil {
+		c.Fatal("no taskdb configured")
+	}
+	var tasks [2][]taskdb.Task
+	for i, arg := range flags.Args() {
+		n, err := parseName(arg)
+		if err != nil {
+			c.Fatal(err)
+		}
+		if n.Kind != idName {
+			c.Fatalf("%s: not a run id", arg)
+		}
+		runs, err := tdb.Runs(ctx, taskdb.RunQuery{ID: taskdb.RunID(n.ID)})
+		if err != nil {
+			c.Fatalf("runs: %v", err)
+		}
+		if len(runs) == 0 {
+			c.Fatalf("run %s not found", arg)
+		}
+		if tasks[i], err = tdb.Tasks(ctx, taskdb.TaskQuery{RunID: runs[0].ID, WithAlloc: true}); err != nil {
+			c.Fatalf("tasks: %v", err)
+		}
+	}
+	var st, et time.Time
+	for _, ts := range tasks {
+		for _, t := range ts {
+			tst, tet := t.StartEnd()
+			if st.IsZero() || (!tst.IsZero() && tst.Before(st)) {
+				st = tst
+			}
+			if tet.After(et) {
+				et = tet
+			}
+		}
+	}
+	cc := c.costComputer(ctx, *exactCostFlag, st, et)
+	d := diffRuns(tasks[0], tasks[1], func(t taskdb.Task) Cost { return taskCost(t, cc) })
+	var tw tabwriter.Writer
+	tw.Init(c.Stdout, 4, 4, 1, ' ', 0)
+	d.writeText(&tw, *execsFlag)
+	c.must(tw.Flush())
+}
@@ -0,0 +1,136 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package tool
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grailbio/base/digest"
+	"github.com/grailbio/reflow"
+	"github.com/grailbio/reflow/errors"
+	"github.com/grailbio/reflow/taskdb"
+)
+
+func TestDiffRuns(t *testing.T) {
+	var (
+		start = time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+		n     int
+	)
+	task := func(ident, flow, cmd string, attempt int, err error) taskdb.Task {
+		n++
+		tk := taskdb.Task{
+			ID:       taskdb.TaskID(reflow.Digester.FromString(fmt.Sprint(n))),
+			Ident:    ident,
+			FlowID:   reflow.Digester.FromString(flow),
+			ImgCmdID: taskdb.ImgCmdID(reflow.Digester.FromString(cmd)),
+			Attempt:  attempt,
+		}
+		tk.Start, tk.End = start, start.Add(time.Minute)
+		if err != nil {
+			tk.Err = *errors.Recover(err)
+		}
+		return tk
+	}
+	a := []taskdb.Task{
+		task("align", "align1", "bwa", 0, nil),
+		task("align", "align2", "bwa", 0, nil),
+		task("sort", "sort1", "samtools", 0, errors.New("exit status 1")),
+		task("call", "call1", "gatk", 0, nil),
+	}
+	b := []taskdb.Task{
+		// Its cached result was not used.
+		task("align", "align1", "bwa", 0, nil),
+		// Inputs changed.
+		task("align", "align3", "bwa", 0, nil),
+		task("sort", "sort1", "samtools", 0, errors.New("lost")),
+		task("sort", "sort1", "samtools", 1, nil),
+		// Command changed.
+		task("call", "call2", "gatk4", 0, nil),
+		task("merge", "merge1", "cat", 0, nil),
+	}
+	// The second run's sort task costs more.
+	cost := func(t taskdb.Task) Cost {
+		if t.Ident == "sort" && t.Attempt == 1 {
+			return NewCostExact(2)
+		}
+		return NewCostExact(1)
+	}
+	d := diffRuns(a, b, cost)
+	kinds := make(map[string]diffKind)
+	for _, x := range d.Execs {
+		if x.Ident == "sort" && x.Attempt != 1 {
+			t.Errorf("got attempt %d of sort, want the last attempt", x.Attempt)
+		}
+		kinds[x.Ident+"/"+x.FlowID.Short()] = x.Kind
+	}
+	short := func(s string) string { return reflow.Digester.FromString(s).Short() }
+	for k, want := range map[string]diffKind{
+		"align/" + short("align1"): diffRerun,
+		"align/" + short("align3"): diffInputs,
+		"sort/" + short("sort1"):   diffRetried,
+		"call/" + short("call2"):   diffCommand,
+		"merge/" + short("merge1"): diffNew,
+	} {
+		if got := kinds[k]; got != want {
+			t.Errorf("%s: got %v, want %v", k, got, want)
+		}
+	}
+	if got, want := len(d.Execs), 5; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if got, want := len(d.Idents), 4; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	sort := d.Idents[3]
+	if got, want := sort.Ident, "sort"; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got, want := sort.Tasks, [2]int{1, 2}; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := sort.Duration, [2]time.Duration{time.Minute, 2 * time.Minute}; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := sort.Cost, [2]Cost{NewCostExact(1), NewCostExact(3)}; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	var buf bytes.Buffer
+	d.writeText(&buf, true)
+	if got, want := buf.String(), "sort\t1 -> 2\t1m0s -> 2m0s\t1.0000 -> 3.0000 (+2.0000)\t0\t1\t0\t0\n"; !strings.Contains(got, want) {
+		t.Errorf("got %q, want it to contain %q", got, want)
+	}
+}
+
+func TestDiffRunsCacheKeys(t *testing.T) {
+	key := reflow.Digester.FromString("key")
+	a := []taskdb.Task{{
+		ID:        taskdb.TaskID(reflow.Digester.FromString("a")),
+		Ident:     "align",
+		FlowID:    reflow.Digester.FromString("flow1"),
+		CacheKeys: []digest.Digest{key},
+	}}
+	a[0].End = time.Now()
+	b := []taskdb.Task{{
+		ID:        taskdb.TaskID(reflow.Digester.FromString("b")),
+		Ident:     "align",
+		FlowID:    reflow.Digester.FromString("flow2"),
+		CacheKeys: []digest.Digest{key},
+	}}
+	d := diffRuns(a, b, func(taskdb.Task) Cost { return Cost{} })
+	if got, want := len(d.Execs), 1; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	// The execs share a cache key: the second run could have used the
+	// first's result.
+	if got, want := d.Execs[0].Kind, diffRerun; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
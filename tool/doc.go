@@ -16,8 +16,16 @@ import (
 
 func (c *Cmd) doc(ctx context.Context, args ...string) {
 	flags := flag.NewFlagSet("doc", flag.ExitOnError)
-	help := "Doc displays documentation for Reflow modules."
-	c.Parse(flags, args, help, "doc path")
+	htmlFlag := flags.String("html", "", "write HTML documentation to this directory")
+	help := `Doc displays documentation for Reflow modules.
+
+With -html, doc instead writes a browsable HTML site documenting the
+module at path, together with the modules it imports from its
+directory, or, if path is a directory, all modules (.rf files) in
+its tree. Each module's page documents its parameters, types, values
+and function signatures, and links to the pages of the modules it
+imports.`
+	c.Parse(flags, args, help, "doc [-html dir] path")
 
 	if flags.NArg() == 0 {
 		c.Println("Reflow's system modules are:")
@@ -31,6 +39,18 @@ func (c *Cmd) doc(ctx context.Context, args ...string) {
 	if flags.NArg() != 1 {
 		flags.Usage()
 	}
+	if *htmlFlag != "" {
+		docs, errs := moduleDocs(flags.Arg(0))
+		for _, err := range errs {
+			c.Errorln(err)
+		}
+		if len(docs) == 0 {
+			c.Fatal("no modules to document")
+		}
+		c.must(writeDocSite(*htmlFlag, docs))
+		c.Printf("wrote documentation for %d modules to %s\n", len(docs), *htmlFlag)
+		return
+	}
 	sess := syntax.NewSession(nil)
 	m, err := sess.Open(flags.Arg(0))
 	c.must(err)
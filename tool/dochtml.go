@@ -0,0 +1,261 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package tool
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/grailbio/reflow/syntax"
+	"github.com/grailbio/reflow/types"
+)
+
+// moduleDoc documents a module of a module tree.
+type moduleDoc struct {
+	// Path is the module's path, relative to the root of the tree.
+	Path string
+	// Page is the path of the module's page, relative to the root of
+	// the site.
+	Page string
+	// Params, Types, Vals and Funcs document the module's parameters,
+	// type declarations, and value and function declarations.
+	Params, Types, Vals, Funcs []declDoc
+	// Imports are the modules instantiated by the module.
+	Imports []importDoc
+}
+
+// declDoc documents a parameter or a declaration.
+type declDoc struct {
+	// Name is the declared identifier.
+	Name string
+	// Sig is the declaration's signature, e.g., "func F(x int) string".
+	Sig string
+	// Doc is the declaration's documentation.
+	Doc string
+}
+
+// importDoc documents a module import.
+type importDoc struct {
+	// Path is the path of the imported module, as written.
+	Path string
+	// Href links to the page of the imported module, relative to the
+	// page of the importing module. It is empty if the imported module
+	// is not part of the tree.
+	Href string
+}
+
+// moduleDocs opens the module at path, or the modules (with extension
+// .rf) in the directory tree at path, and documents them together with
+// the modules they import from the same tree. Modules which fail to
+// open are reported as errors and omitted.
+func moduleDocs(root string) ([]moduleDoc, []error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, []error{err}
+	}
+	var todo []string
+	if info.IsDir() {
+		err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err == nil && !info.IsDir() && filepath.Ext(path) == ".rf" {
+				todo = append(todo, path)
+			}
+			return err
+		})
+		if err != nil {
+			return nil, []error{err}
+		}
+	} else {
+		todo, root = []string{root}, filepath.Dir(root)
+	}
+	var (
+		docs = make(map[string]*moduleDoc)
+		errs []error
+		sess = syntax.NewSession(nil)
+	)
+	for len(todo) > 0 {
+		file := todo[0]
+		todo = todo[1:]
+		rel, err := filepath.Rel(root, file)
+		if err != nil || strings.HasPrefix(rel, "..") || docs[rel] != nil {
+			continue
+		}
+		m, err := sess.Open(file)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		doc := newModuleDoc(filepath.ToSlash(rel), m)
+		docs[rel] = &doc
+		imports, ok := m.(interface{ Imports() []string })
+		if !ok {
+			continue
+		}
+		for _, imp := range imports.Imports() {
+			if strings.HasPrefix(imp, "./") {
+				todo = append(todo, filepath.Join(filepath.Dir(file), imp))
+			}
+		}
+	}
+	list := make([]moduleDoc, 0, len(docs))
+	for rel, doc := range docs {
+		for i, imp := range doc.Imports {
+			if !strings.HasPrefix(imp.Path, "./") {
+				continue
+			}
+			target := filepath.Join(filepath.Dir(rel), imp.Path)
+			if t := docs[target]; t != nil {
+				doc.Imports[i].Href = relativeHref(doc.Page, t.Page)
+			}
+		}
+		list = append(list, *doc)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Path < list[j].Path })
+	return list, errs
+}
+
+// newModuleDoc documents module m with the given (relative) path.
+func newModuleDoc(path string, m syntax.Module) moduleDoc {
+	doc := moduleDoc{Path: path, Page: strings.TrimSuffix(path, filepath.Ext(path)) + ".html"}
+	for _, p := range m.Params() {
+		sig := fmt.Sprintf("val %s %s (required)", p.Ident, p.Type)
+		if !p.Required {
+			sig = fmt.Sprintf("val %s %s = %s", p.Ident, p.Type, p.Expr.Abbrev())
+		}
+		doc.Params = append(doc.Params, declDoc{p.Ident, sig, p.Doc})
+	}
+	typ := m.Type(nil)
+	for _, f := range typ.Aliases {
+		doc.Types = append(doc.Types, declDoc{f.Name, fmt.Sprintf("type %s %s", f.Name, f.T), m.Doc(f.Name)})
+	}
+	for _, f := range typ.Fields {
+		if f.T.Kind == types.FuncKind {
+			sig := fmt.Sprintf("func %s(%s) %s", f.Name, types.FieldsString(f.T.Fields), f.T.Elem)
+			doc.Funcs = append(doc.Funcs, declDoc{f.Name, sig, m.Doc(f.Name)})
+		} else {
+			doc.Vals = append(doc.Vals, declDoc{f.Name, fmt.Sprintf("val %s %s", f.Name, f.T), m.Doc(f.Name)})
+		}
+	}
+	if imports, ok := m.(interface{ Imports() []string }); ok {
+		for _, imp := range imports.Imports() {
+			doc.Imports = append(doc.Imports, importDoc{Path: imp})
+		}
+	}
+	return doc
+}
+
+// relativeHref returns the link to the page to from the page from,
+// both relative to the root of the site.
+func relativeHref(from, to string) string {
+	dir := path.Dir(from)
+	if dir == "." {
+		return to
+	}
+	return strings.Repeat("../", strings.Count(dir, "/")+1) + to
+}
+
+// docSection is a section of a module page.
+type docSection struct {
+	// Title is the section's title.
+	Title string
+	// Kind prefixes the anchors of the section's declarations.
+	Kind string
+	// Decls are the declarations documented in the section.
+	Decls []declDoc
+}
+
+// writeModuleHTML writes the HTML page of the module documented by doc.
+func writeModuleHTML(w io.Writer, doc moduleDoc) error {
+	return moduleTemplate.Execute(w, struct {
+		moduleDoc
+		Index    string
+		Sections []docSection
+	}{
+		doc,
+		relativeHref(doc.Page, "index.html"),
+		[]docSection{
+			{"Parameters", "param", doc.Params},
+			{"Types", "type", doc.Types},
+			{"Values", "val", doc.Vals},
+			{"Functions", "func", doc.Funcs},
+		},
+	})
+}
+
+// writeIndexHTML writes the HTML index of the documented modules.
+func writeIndexHTML(w io.Writer, docs []moduleDoc) error {
+	return indexTemplate.Execute(w, docs)
+}
+
+// writeDocSite writes an HTML page for each of the given modules, as
+// well as an index of them, in the directory dir.
+func writeDocSite(dir string, docs []moduleDoc) error {
+	for _, doc := range docs {
+		doc := doc
+		page := filepath.Join(dir, filepath.FromSlash(doc.Page))
+		if err := os.MkdirAll(filepath.Dir(page), 0777); err != nil {
+			return err
+		}
+		err := writeDocFile(page, func(w io.Writer) error { return writeModuleHTML(w, doc) })
+		if err != nil {
+			return err
+		}
+	}
+	return writeDocFile(filepath.Join(dir, "index.html"), func(w io.Writer) error {
+		return writeIndexHTML(w, docs)
+	})
+}
+
+func writeDocFile(path string, write func(w io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+const docStyle = `<style>
+body { font-family: sans-serif; max-width: 60em; margin: 2em auto; }
+pre.sig { background: #f4f4f4; padding: 0.5em; }
+.doc { white-space: pre-wrap; margin-left: 1em; }
+</style>`
+
+var indexTemplate = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Reflow modules</title>` + docStyle + `</head>
+<body>
+<h1>Reflow modules</h1>
+<ul>
+{{range .}}<li><a href="{{.Page}}">{{.Path}}</a></li>
+{{end}}</ul>
+</body>
+</html>
+`))
+
+var moduleTemplate = template.Must(template.New("module").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>{{.Path}}</title>` + docStyle + `</head>
+<body>
+<p><a href="{{.Index}}">Index</a></p>
+<h1>{{.Path}}</h1>
+{{if .Imports}}<h2>Imports</h2>
+<ul>
+{{range .Imports}}<li>{{if .Href}}<a href="{{.Href}}">{{.Path}}</a>{{else}}<code>{{.Path}}</code>{{end}}</li>
+{{end}}</ul>
+{{end}}{{range .Sections}}{{if .Decls}}<h2>{{.Title}}</h2>
+{{$kind := .Kind}}{{range .Decls}}<pre class="sig" id="{{$kind}}-{{.Name}}">{{.Sig}}</pre>
+{{if .Doc}}<div class="doc">{{.Doc}}</div>
+{{end}}{{end}}{{end}}{{end}}</body>
+</html>
+`))
@@ -0,0 +1,125 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package tool
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var docModules = map[string]string{
+	"main.rf": `
+param (
+	// Sample is the name of the sample.
+	sample string
+	mapq = 60
+)
+
+val util = make("./lib/util.rf")
+val strings = make("$/strings")
+
+// Main greets the sample.
+val Main = util.Greet(sample)
+`,
+	"lib/util.rf": `
+// Greeting is the type of greetings.
+type Greeting string
+
+// Greet greets name.
+func Greet(name string) string = "hello " + name
+`,
+}
+
+func writeDocModules(t *testing.T) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "dochtml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, src := range docModules {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestModuleDocs(t *testing.T) {
+	dir := writeDocModules(t)
+	defer os.RemoveAll(dir)
+	// Documenting the main module includes its local imports.
+	for _, root := range []string{dir, filepath.Join(dir, "main.rf")} {
+		docs, errs := moduleDocs(root)
+		if len(errs) > 0 {
+			t.Fatal(errs)
+		}
+		if got, want := len(docs), 2; got != want {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+		lib, main := docs[0], docs[1]
+		if got, want := lib.Page, "lib/util.html"; got != want {
+			t.Errorf("got %v, want %v", got, want)
+		}
+		if got, want := lib.Types, []declDoc{{"Greeting", "type Greeting string", "Greeting is the type of greetings.\n"}}; !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+		if got, want := lib.Funcs, []declDoc{{"Greet", "func Greet(name string) string", "Greet greets name.\n"}}; !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+		if got, want := main.Path, "main.rf"; got != want {
+			t.Errorf("got %v, want %v", got, want)
+		}
+		if got, want := len(main.Params), 2; got != want {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+		if got, want := main.Params[0].Sig, "val sample string (required)"; got != want {
+			t.Errorf("got %v, want %v", got, want)
+		}
+		if got, want := main.Params[1].Sig, "val mapq int = 60"; got != want {
+			t.Errorf("got %v, want %v", got, want)
+		}
+		if got, want := main.Imports, []importDoc{{"$/strings", ""}, {"./lib/util.rf", "lib/util.html"}}; !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	}
+}
+
+func TestWriteModuleHTML(t *testing.T) {
+	doc := moduleDoc{
+		Path:    "lib/util.rf",
+		Page:    "lib/util.html",
+		Funcs:   []declDoc{{"Greet", "func Greet(name string) string", "Greet <b>greets</b> name."}},
+		Imports: []importDoc{{"./other.rf", "other.html"}, {"$/strings", ""}},
+	}
+	var b bytes.Buffer
+	if err := writeModuleHTML(&b, doc); err != nil {
+		t.Fatal(err)
+	}
+	html := b.String()
+	for _, want := range []string{
+		`<a href="../index.html">Index</a>`,
+		`<a href="other.html">./other.rf</a>`,
+		`<code>$/strings</code>`,
+		`<h2>Functions</h2>`,
+		`<pre class="sig" id="func-Greet">func Greet(name string) string</pre>`,
+		`Greet &lt;b&gt;greets&lt;/b&gt; name.`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("missing %q in:\n%s", want, html)
+		}
+	}
+	if strings.Contains(html, "<h2>Parameters</h2>") {
+		t.Errorf("unexpected parameters section in:\n%s", html)
+	}
+}
@@ -0,0 +1,209 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package tool
+
+import (
+	"context"
+	"flag"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/grailbio/base/traverse"
+	"github.com/grailbio/reflow"
+	"github.com/grailbio/reflow/assoc"
+	infra2 "github.com/grailbio/reflow/infra"
+	"github.com/grailbio/reflow/repository"
+	"github.com/grailbio/reflow/taskdb"
+)
+
+// extractConcurrency is the number of files which are copied
+// concurrently by extract.
+const extractConcurrency = 16
+
+// extractTasks returns the completed tasks of a run, among tasks,
+// whose idents match the regular expression re. Only the last
+// completed attempt of each flow is returned; tasks are ordered by
+// ident and flow ID.
+func extractTasks(tasks []taskdb.Task, re *regexp.Regexp) []taskdb.Task {
+	byFlow := make(map[string]taskdb.Task)
+	for _, t := range tasks {
+		if t.ResultID.IsZero() || t.Err.Err != nil || t.End.IsZero() || !re.MatchString(t.Ident) {
+			continue
+		}
+		key := t.FlowID.String()
+		if prev, ok := byFlow[key]; ok && !prev.End.Before(t.End) {
+			continue
+		}
+		byFlow[key] = t
+	}
+	selected := make([]taskdb.Task, 0, len(byFlow))
+	for _, t := range byFlow {
+		selected = append(selected, t)
+	}
+	sort.Slice(selected, func(i, j int) bool {
+		if selected[i].Ident != selected[j].Ident {
+			return selected[i].Ident < selected[j].Ident
+		}
+		return selected[i].FlowID.Less(selected[j].FlowID)
+	})
+	return selected
+}
+
+// extractPaths adds to paths the files of fileset fs, by their paths
+// relative to the extraction's destination. Files are placed under
+// prefix; the elements of list filesets are placed under their
+// indices.
+func extractPaths(prefix string, fs reflow.Fileset, paths map[string]reflow.File) {
+	if len(fs.List) > 0 {
+		for i := range fs.List {
+			extractPaths(path.Join(prefix, strconv.Itoa(i)), fs.List[i], paths)
+		}
+		return
+	}
+	for k, file := range fs.Map {
+		paths[path.Join(prefix, k)] = file
+	}
+}
+
+// extractDir returns the directory, relative to the extraction's
+// destination, of the result of task t.
+func extractDir(t taskdb.Task) string {
+	ident := t.Ident
+	if ident == "" {
+		ident = "_"
+	}
+	ident = strings.Map(func(r rune) rune {
+		switch {
+		case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z', '0' <= r && r <= '9', r == '.', r == '-', r == '_':
+			return r
+		}
+		return '_'
+	}, ident)
+	return path.Join(ident, t.FlowID.Short())
+}
+
+func (c *Cmd) extract(ctx context.Context, args ...string) {
+	var (
+		flags     = flag.NewFlagSet("extract", flag.ExitOnError)
+		identFlag = flags.String("ident", ".", "regular expression for the identifiers of the execs whose results are extracted")
+		dryRun    = flags.Bool("n", false, "print the files which would be extracted, without extracting them")
+		help      = `Extract copies the results of the execs completed by a run, which may
+have failed, into a local directory or under an S3 prefix (e.g.,
+s3://bucket/prefix), so that the work done by a run which failed
+before producing its result is not lost.
+
+Extract copies the results of the run's completed execs whose
+identifiers (e.g., "align.bwa") match the regular expression given
+by -ident. The result of each exec is placed in the directory
+ident/flowid, where flowid is the (short) ID of the exec's flow;
+the elements of results which are lists (e.g., of execs with
+multiple outputs) are placed in subdirectories named by their
+indices.
+
+Only the results of execs which the run computed are known to
+it: results which the run retrieved from the cache are not
+recorded in taskdb. They may be found by rerunning the program with
+"reflow run -dryrun".`
+	)
+	c.Parse(flags, args, help, "extract [-ident regexp] [-n] runid dst")
+	if flags.NArg() != 2 {
+		flags.Usage()
+	}
+	re, err := regexp.Compile(*identFlag)
+	if err != nil {
+		c.Fatalf("invalid -ident: %v", err)
+	}
+	n, err := parseName(flags.Arg(0))
+	if err != nil {
+		c.Fatal(err)
+	}
+	if n.Kind != idName {
+		c.Fatalf("%s: not a run id", flags.Arg(0))
+	}
+	dst := flags.Arg(1)
+
+	var tdb taskdb.TaskDB
+	if err = c.Config.Instance(&tdb); err != nil {
+		c.Fatalf("taskdb: %v", err)
+	}
+	if tdb == nil {
+		c.Fatal("no taskdb configured")
+	}
+	var repo reflow.Repository
+	c.must(c.Config.Instance(&repo))
+	tasks, err := tdb.Tasks(ctx, taskdb.TaskQuery{RunID: taskdb.RunID(n.ID)})
+	c.must(err)
+	tasks = extractTasks(tasks, re)
+	if len(tasks) == 0 {
+		c.Fatalf("run %s has no completed execs matching %s", flags.Arg(0), *identFlag)
+	}
+	paths := make(map[string]reflow.File)
+	for _, t := range tasks {
+		var fs reflow.Fileset
+		if err := repository.Unmarshal(ctx, repo, t.ResultID, &fs, assoc.FilesetV2); err != nil {
+			c.Errorf("result of %s (%s): %v\n", t.Ident, t.FlowID.Short(), err)
+			continue
+		}
+		extractPaths(extractDir(t), fs, paths)
+	}
+	keys := make([]string, 0, len(paths))
+	for k := range paths {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	if *dryRun {
+		for _, k := range keys {
+			c.Printf("%s\t%s\n", k, paths[k].ID)
+		}
+		return
+	}
+
+	put := func(ctx context.Context, key string, file reflow.File, r io.Reader) error {
+		p := filepath.Join(dst, filepath.FromSlash(key))
+		if err := os.MkdirAll(filepath.Dir(p), 0777); err != nil {
+			return err
+		}
+		w, err := os.Create(p)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(w, r); err != nil {
+			w.Close()
+			return err
+		}
+		return w.Close()
+	}
+	if strings.Contains(dst, "://") {
+		var sess *session.Session
+		c.must(c.Config.Instance(&sess))
+		mux := infra2.BlobMux(c.Config, sess)
+		put = func(ctx context.Context, key string, file reflow.File, r io.Reader) error {
+			return mux.Put(ctx, strings.TrimSuffix(dst, "/")+"/"+key, file.Size, r, "")
+		}
+	}
+	err = traverse.Limit(extractConcurrency).Each(len(keys), func(i int) error {
+		key, file := keys[i], paths[keys[i]]
+		if file.IsRef() {
+			c.Log.Printf("%s: skipping unresolved file %s", key, file.Source)
+			return nil
+		}
+		rc, err := repo.Get(ctx, file.ID)
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		c.Log.Debugf("copying %s %s", key, file.ID.Hex())
+		return put(ctx, key, file, rc)
+	})
+	c.must(err)
+	c.Log.Printf("extracted %d files of %d execs to %s", len(keys), len(tasks), dst)
+}
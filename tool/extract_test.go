@@ -0,0 +1,75 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package tool
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/grailbio/base/digest"
+	"github.com/grailbio/reflow"
+	"github.com/grailbio/reflow/errors"
+	"github.com/grailbio/reflow/taskdb"
+)
+
+func TestExtractTasks(t *testing.T) {
+	var (
+		now            = time.Now()
+		align1, align2 = reflow.Digester.FromString("align1"), reflow.Digester.FromString("align2")
+		merge          = reflow.Digester.FromString("merge")
+		result         = reflow.Digester.FromString("result")
+	)
+	task := func(ident string, flow digest.Digest, end time.Time, result digest.Digest, err error) taskdb.Task {
+		tk := taskdb.Task{Ident: ident, FlowID: flow, ResultID: result}
+		tk.End = end
+		if err != nil {
+			tk.Err = *errors.Recover(err)
+		}
+		return tk
+	}
+	tasks := []taskdb.Task{
+		task("align", align1, now.Add(-time.Hour), result, nil),
+		// A later attempt of the same flow.
+		task("align", align1, now, result, nil),
+		task("align", align2, now, result, errors.New("exit status 1")),
+		task("align", align2, time.Time{}, digest.Digest{}, nil),
+		task("merge", merge, now, result, nil),
+	}
+	got := extractTasks(tasks, regexp.MustCompile("^align$"))
+	if len(got) != 1 || got[0].FlowID != align1 || !got[0].End.Equal(now) {
+		t.Errorf("got %v, want the last attempt of %s", got, align1)
+	}
+	got = extractTasks(tasks, regexp.MustCompile("."))
+	if len(got) != 2 || got[0].Ident != "align" || got[1].Ident != "merge" {
+		t.Errorf("got %v, want align, merge", got)
+	}
+}
+
+func TestExtractPaths(t *testing.T) {
+	file := func(s string) reflow.File {
+		return reflow.File{ID: reflow.Digester.FromString(s), Size: int64(len(s))}
+	}
+	fs := reflow.Fileset{List: []reflow.Fileset{
+		{Map: map[string]reflow.File{".": file("bam")}},
+		{Map: map[string]reflow.File{"a/b": file("b"), "c": file("c")}},
+	}}
+	tk := taskdb.Task{Ident: "align.bwa(x)", FlowID: reflow.Digester.FromString("flow")}
+	dir := extractDir(tk)
+	if want := "align.bwa_x_/" + tk.FlowID.Short(); dir != want {
+		t.Errorf("got %s, want %s", dir, want)
+	}
+	paths := make(map[string]reflow.File)
+	extractPaths(dir, fs, paths)
+	want := map[string]reflow.File{
+		dir + "/0":     file("bam"),
+		dir + "/1/a/b": file("b"),
+		dir + "/1/c":   file("c"),
+	}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("got %v, want %v", paths, want)
+	}
+}
@@ -0,0 +1,296 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package tool
+
+import (
+	"context"
+	"flag"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grailbio/base/data"
+	"github.com/grailbio/base/digest"
+	"github.com/grailbio/reflow"
+	"github.com/grailbio/reflow/assoc"
+	"github.com/grailbio/reflow/errors"
+	"github.com/grailbio/reflow/liveset/bloomlive"
+	"github.com/grailbio/reflow/repository"
+	"github.com/grailbio/reflow/runtime"
+	"github.com/grailbio/reflow/taskdb"
+	"github.com/grailbio/reflow/taskdb/noptaskdb"
+	"github.com/willf/bloom"
+)
+
+// parseRetention parses a retention window, given either as a number
+// of days (e.g., "30d") or as a Go duration (e.g., "36h").
+func parseRetention(s string) (time.Duration, error) {
+	var (
+		d   time.Duration
+		err error
+	)
+	if strings.HasSuffix(s, "d") {
+		var days int
+		days, err = strconv.Atoi(strings.TrimSuffix(s, "d"))
+		d = time.Duration(days) * 24 * time.Hour
+	} else {
+		d, err = time.ParseDuration(s)
+	}
+	if err != nil {
+		return 0, errors.E("parse retention", s, errors.Invalid, err)
+	}
+	if d <= 0 {
+		return 0, errors.E("parse retention", s, errors.Invalid, errors.New("retention must be positive"))
+	}
+	return d, nil
+}
+
+// sizeSuffixes are the suffixes accepted by parseSize.
+var sizeSuffixes = []struct {
+	suffix string
+	mult   int64
+}{
+	{"KiB", 1 << 10},
+	{"MiB", 1 << 20},
+	{"GiB", 1 << 30},
+	{"TiB", 1 << 40},
+	{"PiB", 1 << 50},
+}
+
+// parseSize parses a size in bytes, optionally with a binary suffix
+// (e.g., "500GiB").
+func parseSize(s string) (int64, error) {
+	mult := int64(1)
+	for _, suffix := range sizeSuffixes {
+		if strings.HasSuffix(s, suffix.suffix) {
+			s, mult = strings.TrimSuffix(s, suffix.suffix), suffix.mult
+			break
+		}
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, errors.E("parse size", s, errors.Invalid, errors.New("size must be a positive number of bytes"))
+	}
+	return n * mult, nil
+}
+
+// evictedAssoc is an assoc from whose scans a set of evicted keys is
+// omitted, so that liveness may be computed as if they were removed.
+type evictedAssoc struct {
+	assoc.Assoc
+	evicted mapLiveset
+}
+
+// Scan implements assoc.Assoc.
+func (a evictedAssoc) Scan(ctx context.Context, kinds []assoc.Kind, handler assoc.MappingHandler) error {
+	return a.Assoc.Scan(ctx, kinds, assoc.MappingHandlerFunc(func(k digest.Digest, v map[assoc.Kind]digest.Digest, lastAccessTime time.Time, labels []string) {
+		if !a.evicted.Contains(k) {
+			handler.HandleMapping(ctx, k, v, lastAccessTime, labels)
+		}
+	}))
+}
+
+// evict evicts from the assoc the cache entries selected by the
+// provided policy, and returns their keys. The sizes of entries are
+// those of the filesets they map to. If dryRun is true, the entries
+// are not removed.
+func (c *Cmd) evict(ctx context.Context, ass assoc.Assoc, repo reflow.Repository, policy assoc.EvictionPolicy, dryRun bool) (mapLiveset, error) {
+	var (
+		mu      sync.Mutex
+		entries []assoc.Entry
+	)
+	err := ass.Scan(ctx, []assoc.Kind{assoc.Fileset, assoc.FilesetV2}, assoc.MappingHandlerFunc(func(k digest.Digest, v map[assoc.Kind]digest.Digest, lastAccessTime time.Time, labels []string) {
+		entry := assoc.Entry{Key: k, LastAccess: lastAccessTime}
+		if policy.MaxBytes > 0 {
+			kind := assoc.FilesetV2
+			if _, ok := v[kind]; !ok {
+				kind = assoc.Fileset
+			}
+			var fs reflow.Fileset
+			err := repository.Unmarshal(ctx, repo, v[kind], &fs, kind)
+			switch {
+			case err == nil:
+				entry.Size = fs.Size()
+			case errors.Is(errors.NotExist, err):
+			default:
+				// Entries whose sizes are unknown are never evicted.
+				c.Log.Errorf("%s %v (flow %v): %v", kind, v[kind], k, err)
+				return
+			}
+		}
+		mu.Lock()
+		entries = append(entries, entry)
+		mu.Unlock()
+	}))
+	if err != nil {
+		return nil, err
+	}
+	var (
+		evicted = make(mapLiveset)
+		size    int64
+	)
+	for _, e := range policy.Evict(entries, time.Now()) {
+		if !dryRun {
+			if err := ass.Delete(ctx, e.Key); err != nil && !errors.Is(errors.NotExist, err) {
+				return evicted, err
+			}
+		}
+		evicted.Add(e.Key)
+		size += e.Size
+	}
+	action := "would have been"
+	if !dryRun {
+		action = "were"
+	}
+	c.Log.Printf("%d of %d cache entries (%s) %s evicted under policy %s",
+		len(evicted), len(entries), data.Size(size), action, policy)
+	return evicted, nil
+}
+
+// addTaskDBLiveset adds to the liveset live the repository objects
+// referenced by the provided runs and tasks: their logs, inspects, and
+// results (along with the files in them). It returns the number of
+// objects added.
+func addTaskDBLiveset(ctx context.Context, repo reflow.Repository, runs []taskdb.Run, tasks []taskdb.Task, live *bloom.BloomFilter) int64 {
+	var n int64
+	add := func(d digest.Digest) {
+		if !d.IsZero() {
+			live.Add(d.Bytes())
+			n++
+		}
+	}
+	for _, run := range runs {
+		for _, d := range []digest.Digest{run.RunLog, run.EvalGraph, run.Trace, run.ExecLog, run.SysLog} {
+			add(d)
+		}
+	}
+	for _, task := range tasks {
+		for _, d := range []digest.Digest{task.Stdout, task.Stderr, task.Inspect, task.ResultID} {
+			add(d)
+		}
+		if task.ResultID.IsZero() {
+			continue
+		}
+		// A task's result may or may not have been stored in the
+		// repository; if it was, the files in it are live too.
+		var fs reflow.Fileset
+		if err := repository.Unmarshal(ctx, repo, task.ResultID, &fs, assoc.FilesetV2); err != nil {
+			continue
+		}
+		for _, f := range fs.Files() {
+			add(f.ID)
+		}
+	}
+	return n
+}
+
+func (c *Cmd) gc(ctx context.Context, args ...string) {
+	var (
+		flags         = flag.NewFlagSet("gc", flag.ExitOnError)
+		retentionFlag = flags.String("retention", "30d", "objects created within this window are never collected; either a number of days (30d) or a duration (36h)")
+		dryRunFlag    = flags.Bool("dry-run", true, "when true, reports on what would have been collected without actually removing anything from the repository")
+		ttlFlag       = flags.String("ttl", "", "evict cache entries which have not been accessed within this window; either a number of days (90d) or a duration")
+		maxSizeFlag   = flags.String("max-size", "", "evict the least recently accessed cache entries beyond this total size (e.g., 500TiB)")
+		help          = `Gc performs garbage collection of the reflow repository, removing
+objects which are not referenced by the cache or by the taskdb.
+
+An object is live if it is referenced by any association in the
+cache (directly or as a file in a cached fileset), or by a run or task
+which was active within the retention window (e.g., its logs, inspect
+or result). Objects created within the retention window are never
+collected, so that objects written by in-progress runs (and not yet
+referenced) are retained.
+
+Unlike collect, gc does not otherwise remove entries from the cache,
+and so does not affect cache hits, unless an eviction policy is given
+with -ttl or -max-size. Cache entries are then evicted (in least
+recently accessed order) if they have not been accessed within the
+TTL, or if they do not fit within the size budget, which is applied
+to the total size of the filesets of cache entries. Objects referenced
+only by evicted entries are collected in the same pass. If no taskdb
+is configured, only the cache is consulted for liveness.
+
+Since collected objects may be recorded in presence indexes (see the
+"presenceindex" configuration), gc discards the saved presence index
+of the repository on this host; indexes saved on other hosts expire
+only once they exceed their maximum age. Cache writes always check
+the repository itself, so cache entries never refer to collected
+objects.
+
+Gc runs in dry-run mode by default; -dry-run=false must be given to
+actually remove objects.`
+	)
+	c.Parse(flags, args, help, "gc [-retention window] [-ttl window] [-max-size size] [-dry-run=false]")
+	if flags.NArg() != 0 {
+		flags.Usage()
+	}
+	retention, err := parseRetention(*retentionFlag)
+	if err != nil {
+		c.Errorln(err)
+		flags.Usage()
+	}
+	var policy assoc.EvictionPolicy
+	if *ttlFlag != "" {
+		if policy.TTL, err = parseRetention(*ttlFlag); err != nil {
+			c.Errorln(err)
+			flags.Usage()
+		}
+	}
+	if *maxSizeFlag != "" {
+		if policy.MaxBytes, err = parseSize(*maxSizeFlag); err != nil {
+			c.Errorln(err)
+			flags.Usage()
+		}
+	}
+	var ass assoc.Assoc
+	c.must(c.Config.Instance(&ass))
+	var repo reflow.Repository
+	c.must(c.Config.Instance(&repo))
+
+	var (
+		start     = time.Now()
+		threshold = start.Add(-retention)
+	)
+	if !policy.IsZero() {
+		evicted, err := c.evict(ctx, ass, repo, policy, *dryRunFlag)
+		c.must(err)
+		// Evicted entries are omitted even in dry-run mode, so that the
+		// objects which would be collected with them are reported.
+		ass = evictedAssoc{ass, evicted}
+	}
+	// Every (remaining) association in the cache is live: an empty
+	// conjunction matches all labels. No filesets are migrated.
+	keepAll := &filter{kind: filterAnd}
+	inps, err := c.buildCollectInputsAndMigrate(ctx, ass, repo, keepAll, nil, time.Time{}, 0)
+	// Bail if anything went wrong since we're about to garbage collect based on this liveset.
+	c.must(err)
+	c.Log.Debugf("time to scan associations %s", time.Since(start))
+	c.Log.Printf("scanned %d associations, found %d live associations, %d live objects, %d objects not in repository",
+		inps.itemsScannedCount, inps.liveItemCount, inps.liveObjectsInFilesets, inps.liveObjectsNotInRepository)
+
+	var tdb taskdb.TaskDB
+	if err := c.Config.Instance(&tdb); err != nil {
+		c.Fatalf("taskdb: %v", err)
+	}
+	if _, nop := tdb.(noptaskdb.NopTaskDB); nop || tdb == nil {
+		c.Log.Printf("no taskdb configured; only the cache is consulted for liveness")
+	} else {
+		runs, err := tdb.Runs(ctx, taskdb.RunQuery{Since: threshold, Until: start})
+		c.must(err)
+		tasks, err := tdb.Tasks(ctx, taskdb.TaskQuery{Since: threshold, Until: start})
+		c.must(err)
+		n := addTaskDBLiveset(ctx, repo, runs, tasks, inps.valueFilter)
+		c.Log.Printf("scanned %d runs and %d tasks since %s, found %d live objects",
+			len(runs), len(tasks), threshold.Format(time.RFC3339), n)
+	}
+	c.must(repo.CollectWithThreshold(ctx, bloomlive.New(inps.valueFilter), mapLiveset{}, threshold, *dryRunFlag))
+	if !*dryRunFlag {
+		// The local presence index may refer to collected objects.
+		if err := runtime.ResetPresenceIndex(repo); err != nil {
+			c.Log.Errorf("reset presence index: %v", err)
+		}
+	}
+}
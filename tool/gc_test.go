@@ -0,0 +1,157 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package tool
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grailbio/base/digest"
+	"github.com/grailbio/reflow/assoc"
+	"github.com/grailbio/reflow/errors"
+	"github.com/grailbio/reflow/repository"
+	"github.com/grailbio/reflow/taskdb"
+	"github.com/grailbio/reflow/test/testutil"
+	"github.com/willf/bloom"
+)
+
+func TestParseRetention(t *testing.T) {
+	for _, tc := range []struct {
+		s    string
+		want time.Duration
+		ok   bool
+	}{
+		{"30d", 30 * 24 * time.Hour, true},
+		{"36h", 36 * time.Hour, true},
+		{"0d", 0, false},
+		{"-1h", 0, false},
+		{"xd", 0, false},
+		{"", 0, false},
+	} {
+		got, err := parseRetention(tc.s)
+		if tc.ok != (err == nil) {
+			t.Errorf("%q: unexpected error %v", tc.s, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("%q: got %v, want %v", tc.s, got, tc.want)
+		}
+	}
+}
+
+func TestAddTaskDBLiveset(t *testing.T) {
+	var (
+		ctx  = context.Background()
+		fuzz = testutil.NewFuzz(nil)
+		repo = testutil.NewInmemoryRepository("")
+		fs   = fuzz.FilesetDeep(2, 0, false, false)
+		live = bloom.NewWithEstimates(100, .000001)
+	)
+	resultID, err := repository.Marshal(ctx, repo, &fs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	run := taskdb.Run{RunLog: fuzz.Digest()}
+	task := taskdb.Task{ResultID: resultID, Stdout: fuzz.Digest(), Inspect: fuzz.Digest()}
+	// The result of this task was not stored in the repository.
+	missing := taskdb.Task{ResultID: fuzz.Digest()}
+	n := addTaskDBLiveset(ctx, repo, []taskdb.Run{run}, []taskdb.Task{task, missing}, live)
+	if got, want := n, int64(5+len(fs.Files())); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	want := []digest.Digest{run.RunLog, task.ResultID, task.Stdout, task.Inspect, missing.ResultID}
+	for _, f := range fs.Files() {
+		want = append(want, f.ID)
+	}
+	for _, d := range want {
+		if !live.Test(d.Bytes()) {
+			t.Errorf("expected %v to be live", d)
+		}
+	}
+	if live.Test(fuzz.Digest().Bytes()) {
+		t.Error("unexpected live object")
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	for _, tc := range []struct {
+		s    string
+		want int64
+	}{
+		{"100", 100},
+		{"2KiB", 2 << 10},
+		{"500TiB", 500 << 40},
+		{"0", 0},
+		{"1GB", 0},
+		{"", 0},
+	} {
+		got, err := parseSize(tc.s)
+		if (err == nil) != (tc.want > 0) {
+			t.Errorf("%q: unexpected error %v", tc.s, err)
+		}
+		if got != tc.want {
+			t.Errorf("%q: got %v, want %v", tc.s, got, tc.want)
+		}
+	}
+}
+
+func TestEvict(t *testing.T) {
+	var (
+		ctx  = context.Background()
+		fuzz = testutil.NewFuzz(nil)
+		ass  = testutil.NewInmemoryAssoc()
+		repo = testutil.NewInmemoryRepository("")
+		keys []digest.Digest
+		size int64
+	)
+	// Three entries, each accessed an hour before the next.
+	for i := 0; i < 3; i++ {
+		fs := fuzz.FilesetDeep(2, 0, false, false)
+		v, err := repository.Marshal(ctx, repo, &fs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		k := fuzz.Digest()
+		if err := ass.Store(ctx, assoc.FilesetV2, k, v); err != nil {
+			t.Fatal(err)
+		}
+		ass.SetAccessTime(k, time.Now().Add(time.Duration(i-3)*time.Hour))
+		keys = append(keys, k)
+		if i > 0 {
+			size += fs.Size()
+		}
+	}
+	// The budget retains the two most recently accessed entries.
+	policy := assoc.EvictionPolicy{MaxBytes: size}
+	evicted, err := (&Cmd{}).evict(ctx, ass, repo, policy, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(evicted), 1; got != want || !evicted.Contains(keys[0]) {
+		t.Fatalf("got %v, want %v", evicted, keys[:1])
+	}
+	// The evicted entry is omitted from scans.
+	var n int
+	_ = evictedAssoc{ass, evicted}.Scan(ctx, []assoc.Kind{assoc.FilesetV2}, assoc.MappingHandlerFunc(
+		func(k digest.Digest, _ map[assoc.Kind]digest.Digest, _ time.Time, _ []string) {
+			if k == keys[0] {
+				t.Errorf("evicted key %v scanned", k)
+			}
+			n++
+		}))
+	if got, want := n, 2; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := len(ass.RawAssocs()), 3; got != want {
+		t.Errorf("entries removed in dry-run mode: got %v, want %v", got, want)
+	}
+	if _, err := (&Cmd{}).evict(ctx, ass, repo, policy, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := ass.Get(ctx, assoc.FilesetV2, keys[0]); !errors.Is(errors.NotExist, err) {
+		t.Errorf("expected entry to be evicted, got %v", err)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"net"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -251,6 +252,14 @@ func (c *Cmd) printTdbTaskInfo(ctx context.Context, w io.Writer, taskId digest.D
 	for _, t := range infos {
 		c.writeTask(t, w, true, true)
 	}
+	for _, t := range infos {
+		if t.Err.Err == nil {
+			continue
+		}
+		if parts := strings.SplitN(t.Err.Err.Error(), "\n", 2); len(parts) == 2 {
+			fmt.Fprintf(w, "\ntask %s %s\n", t.ID.IDShort(), parts[1])
+		}
+	}
 	return true
 }
 
@@ -378,6 +387,14 @@ func (c *Cmd) printExec(ctx context.Context, w io.Writer, inspect reflow.ExecIns
 				fmt.Fprintf(w, "\t  arg[%d]: output %d\n", i, arg.Index)
 				continue
 			}
+			if arg.Scratch {
+				fmt.Fprintf(w, "\t  arg[%d]: scratch %d\n", i, arg.Index)
+				continue
+			}
+			if arg.Inline {
+				fmt.Fprintf(w, "\t  arg[%d]: inline %q\n", i, arg.Data)
+				continue
+			}
 			if syns[i] < 0 || arg.Fileset == nil {
 				continue
 			}
@@ -511,7 +528,11 @@ func parseName(raw string) (n name, err error) {
 		return
 	}
 	if !hexRe.MatchString(head) {
-		n.Hostname = strings.Split(head, ":")[0]
+		n.Hostname = head
+		if host, _, err := net.SplitHostPort(head); err == nil {
+			// This also strips the brackets of IPv6 literals.
+			n.Hostname = host
+		}
 		n.HostAndPort = head
 		head, tail = peel(tail, "/")
 	}
@@ -29,6 +29,7 @@ func TestParseName(t *testing.T) {
 		{raw: allocURI, want: name{Kind: allocName, Hostname: hostname, HostAndPort: serviceUrl, AllocID: allocID}},
 		{raw: execURI, want: name{Kind: execName, Hostname: hostname, HostAndPort: serviceUrl, AllocID: allocID, ID: d}},
 		{raw: allocID + "/" + execId, want: name{Kind: execName, AllocID: allocID, ID: d}},
+		{raw: "[2600:1f14::1]:9000/" + allocID, want: name{Kind: allocName, Hostname: "2600:1f14::1", HostAndPort: "[2600:1f14::1]:9000", AllocID: allocID}},
 	} {
 		n, err := parseName(tt.raw)
 		if got, want := err != nil, tt.wantE; got != want {
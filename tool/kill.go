@@ -11,12 +11,16 @@ import (
 
 func (c *Cmd) kill(ctx context.Context, args ...string) {
 	flags := flag.NewFlagSet("kill", flag.ExitOnError)
+	allUsers := c.usersFlag(flags, "kill allocs of any user")
 	help := "Kill terminates and frees allocs."
-	c.Parse(flags, args, help, "kill allocs...")
+	c.Parse(flags, args, help, "kill [-users=all] allocs...")
 	if flags.NArg() == 0 {
 		flags.Usage()
 	}
 	cluster := c.CurrentPool(ctx)
+	if allUsers() {
+		cluster = c.AllUsersPool(ctx)
+	}
 	for _, arg := range flags.Args() {
 		n, err := parseName(arg)
 		if err != nil {
@@ -35,6 +35,7 @@ import (
 	"github.com/grailbio/reflow/log"
 	"github.com/grailbio/reflow/pool"
 	reflowruntime "github.com/grailbio/reflow/runtime"
+	"github.com/grailbio/reflow/syntax"
 	"gopkg.in/yaml.v2"
 )
 
@@ -81,6 +82,7 @@ type Cmd struct {
 	cpuProfileFlag string
 	memProfileFlag string
 	logFlag        string
+	logFormatFlag  string
 	filesetOpLim   int
 
 	memStatsDuration time.Duration
@@ -96,35 +98,48 @@ type Cmd struct {
 }
 
 var commands = map[string]Func{
-	"batchinfo":    (*Cmd).batchinfo,
-	"batchrun":     (*Cmd).batchrun,
-	"bundle":       (*Cmd).bundle,
-	"cat":          (*Cmd).cat,
-	"check":        (*Cmd).check,
-	"collect":      (*Cmd).collect,
-	"config":       (*Cmd).config,
-	"doc":          (*Cmd).doc,
-	"ec2instances": (*Cmd).ec2instances,
-	"ec2verify":    (*Cmd).ec2verify,
-	"genbatch":     (*Cmd).genbatch,
-	"http":         (*Cmd).http,
-	"images":       (*Cmd).images,
-	"info":         (*Cmd).info,
-	"kill":         (*Cmd).kill,
-	"list":         (*Cmd).list,
-	"listbatch":    (*Cmd).listbatch,
-	"logs":         (*Cmd).logs,
-	"pred":         (*Cmd).pred,
-	"ps":           (*Cmd).ps,
-	"repair":       (*Cmd).repair,
-	"rmcache":      (*Cmd).rmcache,
-	"run":          (*Cmd).run,
-	"runbatch":     (*Cmd).runbatch,
-	"serve":        (*Cmd).serveCmd,
-	"shell":        (*Cmd).shell,
-	"sync":         (*Cmd).sync,
-	"upgrade":      (*Cmd).upgrade,
-	"version":      (*Cmd).versionCmd,
+	"batch":          (*Cmd).batch,
+	"batchinfo":      (*Cmd).batchinfo,
+	"batchrun":       (*Cmd).batchrun,
+	"bundle":         (*Cmd).bundle,
+	"cache":          (*Cmd).cache,
+	"cat":            (*Cmd).cat,
+	"check":          (*Cmd).check,
+	"cleanup-allocs": (*Cmd).cleanupAllocs,
+	"collect":        (*Cmd).collect,
+	"config":         (*Cmd).config,
+	"cost":           (*Cmd).runCost,
+	"diff":           (*Cmd).diff,
+	"doc":            (*Cmd).doc,
+	"ec2instances":   (*Cmd).ec2instances,
+	"ec2verify":      (*Cmd).ec2verify,
+	"extract":        (*Cmd).extract,
+	"gc":             (*Cmd).gc,
+	"genbatch":       (*Cmd).genbatch,
+	"http":           (*Cmd).http,
+	"images":         (*Cmd).images,
+	"info":           (*Cmd).info,
+	"kill":           (*Cmd).kill,
+	"list":           (*Cmd).list,
+	"listbatch":      (*Cmd).listbatch,
+	"logs":           (*Cmd).logs,
+	"plan":           (*Cmd).plan,
+	"pred":           (*Cmd).pred,
+	"prewarm":        (*Cmd).prewarm,
+	"ps":             (*Cmd).ps,
+	"repair":         (*Cmd).repair,
+	"rerun":          (*Cmd).rerun,
+	"resume":         (*Cmd).resume,
+	"rmcache":        (*Cmd).rmcache,
+	"run":            (*Cmd).run,
+	"runbatch":       (*Cmd).runbatch,
+	"serve":          (*Cmd).serveCmd,
+	"shell":          (*Cmd).shell,
+	"sync":           (*Cmd).sync,
+	"top":            (*Cmd).top,
+	"upgrade":        (*Cmd).upgrade,
+	"version":        (*Cmd).versionCmd,
+	"watch":          (*Cmd).watch,
 }
 
 var intro = `The reflow command helps users run Reflow programs, ExecInspect their
@@ -244,7 +259,14 @@ func (c *Cmd) Main() {
 
 	// Set the system wide logger with the same level and output
 	// as the one that's threaded through Cmd.
-	log.Std = log.New(golog.New(c.Stderr, logprefix, logflags), level)
+	switch c.logFormatFlag {
+	case "text":
+		log.Std = log.New(golog.New(c.Stderr, logprefix, logflags), level)
+	case "json":
+		log.Std = log.New(log.NewJSONOutputter(c.Stderr), level)
+	default:
+		c.Fatalf("invalid log format %q: must be text or json", c.logFormatFlag)
+	}
 	c.Log = log.Std
 
 	// Set a custom must.Func which logs a message to the command's logger and then fatally exits.
@@ -292,6 +314,9 @@ func (c *Cmd) Main() {
 	c.must(c.Config.Instance(&bootstrapimage))
 	c.must(c.Config.Instance(&dockerconfig))
 
+	// Modules may be imported by S3 URL, using the configured session.
+	syntax.RegisterRemote("s3", c.fetchS3Module)
+
 	// Set the bootstrap image to the official image for this distribution
 	if ok := bootstrapimage.Set(c.BootstrapBinary); !ok {
 		c.Log.Printf("using bootstrap image from config %s (instead of built-in one: %s)\n", bootstrapimage.Value(), c.BootstrapBinary)
@@ -437,6 +462,7 @@ func (c *Cmd) Flags() *flag.FlagSet {
 		c.flags.DurationVar(&c.memStatsDuration, "memstatsduration", 0, "log high-level memory stats at this frequency (eg: 100ms)")
 		c.flags.BoolVar(&c.memStatsGC, "memstatsgc", false, "whether to GC before collecting memstats (at each memstatsduration interval)")
 		c.flags.StringVar(&c.logFlag, "log", "info", "set the log level: off, error, info, debug")
+		c.flags.StringVar(&c.logFormatFlag, "logformat", "text", "set the log format: text, json (one JSON record per line)")
 		c.flags.IntVar(&c.filesetOpLim, "fileset_op_limit", -1, "set the number of concurrent reflow fileset operations allowed (if unset or non-positive, uses default which is number of CPUs)")
 
 		// Add flags to override configuration.
@@ -545,3 +571,43 @@ func (c *Cmd) CurrentPool(ctx context.Context) pool.Pool {
 	}))
 	return c.pool
 }
+
+// requireAdmin fails unless the current user is a cluster administrator,
+// as configured by the infra admin provider.
+func (c *Cmd) requireAdmin() {
+	var (
+		user  *infra2.User
+		admin *infra2.Admin
+	)
+	c.must(c.Config.Instance(&user))
+	if err := c.Config.Instance(&admin); err != nil || !admin.IsAdmin(user.User()) {
+		c.Fatalf("user %s is not a cluster administrator", user.User())
+	}
+}
+
+// usersFlag adds to flags the -users flag, with which cluster
+// administrators select, with -users=all, the allocs (or runs) of all
+// users of the cluster rather than those of the current user. The
+// returned function tells whether all users were selected.
+func (c *Cmd) usersFlag(flags *flag.FlagSet, usage string) func() bool {
+	users := flags.String("users", "", "(administrators only) with \"all\", "+usage)
+	return func() bool {
+		if *users != "" && *users != "all" {
+			c.Fatalf("invalid -users %q: the only supported value is \"all\"", *users)
+		}
+		return *users == "all"
+	}
+}
+
+// AllUsersPool returns a pool of the reflowlets of all users in the
+// current cluster. It is available only to cluster administrators.
+func (c *Cmd) AllUsersPool(ctx context.Context) pool.Pool {
+	c.requireAdmin()
+	ec, ok := c.CurrentPool(ctx).(*ec2cluster.Cluster)
+	if !ok {
+		c.Fatalf("not an ec2cluster")
+	}
+	p, err := ec.AllUsersPool(ctx)
+	c.must(err)
+	return p
+}
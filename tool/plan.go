@@ -0,0 +1,190 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package tool
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/grailbio/reflow"
+	"github.com/grailbio/reflow/ec2cluster"
+	"github.com/grailbio/reflow/flow"
+	"github.com/grailbio/reflow/predictor"
+	"github.com/grailbio/reflow/runtime"
+	"github.com/grailbio/reflow/sched"
+	"github.com/grailbio/reflow/taskdb"
+)
+
+// planEstimate is the estimated cost of the execs of a plan with a
+// given ident.
+type planEstimate struct {
+	flow.PlanIdent
+	// Duration is the predicted duration of each exec, or zero if it
+	// is not known.
+	Duration time.Duration
+	// InstanceType is the cheapest instance type which fits the execs.
+	InstanceType string
+	// LowUSD and HighUSD bound the cost of the execs: at best, the
+	// execs are packed onto instances with no waste, and are charged
+	// only for their (dominant) share of the instance; at worst, each
+	// exec occupies an instance of its own.
+	LowUSD, HighUSD float64
+}
+
+// estimatePlan estimates the costs of the execs of plan p, given the
+// predicted durations of execs by ident and the hourly prices of
+// instance types.
+func estimatePlan(p *flow.Plan, durations map[string]time.Duration, price func(typ string) float64) []planEstimate {
+	estimates := make([]planEstimate, len(p.Idents))
+	for i, id := range p.Idents {
+		est := planEstimate{PlanIdent: id, Duration: durations[id.Ident]}
+		var resources reflow.Resources
+		est.InstanceType, resources = ec2cluster.InstanceType(id.Resources, false, 0)
+		if est.InstanceType != "" && est.Duration > 0 {
+			hourly := price(est.InstanceType) * est.Duration.Hours() * float64(id.Execs)
+			share := id.Resources.MaxRatio(resources)
+			if share > 1 {
+				share = 1
+			}
+			est.LowUSD, est.HighUSD = hourly*share, hourly
+		}
+		estimates[i] = est
+	}
+	return estimates
+}
+
+// writePlan writes a report of plan p and its cost estimates to w.
+func writePlan(w io.Writer, p *flow.Plan, estimates []planEstimate) {
+	fmt.Fprintf(w, "%d execs, %d interns, %d externs\n", p.Execs, p.Interns, p.Externs)
+	if !p.Static() {
+		fmt.Fprintf(w, "the program has %d maps or continuations which are expanded only during evaluation: the estimates are lower bounds\n", p.Dynamic)
+	}
+	fmt.Fprintln(w)
+	var tw tabwriter.Writer
+	tw.Init(w, 4, 4, 1, ' ', 0)
+	fmt.Fprintln(&tw, "ident\texecs\tresources\tinstance type\tduration\tcost")
+	var (
+		low, high float64
+		unknown   int
+		largest   planEstimate
+	)
+	for _, est := range estimates {
+		duration, cost := "unknown", "unknown"
+		if est.Duration > 0 {
+			duration = est.Duration.Round(time.Second).String()
+		}
+		if est.HighUSD > 0 {
+			cost = fmt.Sprintf("$%.2f-$%.2f", est.LowUSD, est.HighUSD)
+			low += est.LowUSD
+			high += est.HighUSD
+		} else {
+			unknown++
+		}
+		typ := est.InstanceType
+		if typ == "" {
+			typ = "none"
+		}
+		fmt.Fprintf(&tw, "%s\t%d\t%s\t%s\t%s\t%s\n", est.Ident, est.Execs, est.Resources, typ, duration, cost)
+		if est.InstanceType != "" && (largest.InstanceType == "" || est.Resources.ScaledDistance(nil) > largest.Resources.ScaledDistance(nil)) {
+			largest = est
+		}
+	}
+	tw.Flush()
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "estimated peak cluster size: %s", p.Peak)
+	if largest.InstanceType != "" {
+		_, resources := ec2cluster.InstanceType(largest.Resources, false, 0)
+		fmt.Fprintf(w, " (about %d %s instances)", int(math.Ceil(p.Peak.MaxRatio(resources))), largest.InstanceType)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "estimated cost: $%.2f-$%.2f", low, high)
+	if unknown > 0 {
+		fmt.Fprintf(w, " (excluding %d idents without duration data)", unknown)
+	}
+	fmt.Fprintln(w)
+}
+
+func (c *Cmd) plan(ctx context.Context, args ...string) {
+	var (
+		flags   = flag.NewFlagSet("plan", flag.ExitOnError)
+		predict = flags.Bool("pred", true, "use the predictor's profiles of earlier runs to estimate the execs' durations")
+		help    = `Plan reports a static estimate of the work required to run a Reflow
+program, without running it: the number of execs by ident, their
+declared resources, the estimated peak cluster size, and the
+estimated cost range of the execs.
+
+The program is evaluated only to its flow graph. Maps over values
+which are computed during evaluation (e.g., over the files of an
+interned directory), and other continuations, cannot be expanded;
+plan reports their number, and its estimates are then lower bounds.
+
+The cost of each ident's execs is estimated from the on-demand price
+of the cheapest instance type which fits them, and from their
+duration as predicted from the profiles (in taskdb) of earlier runs,
+if any. The low estimate assumes execs are perfectly packed onto
+instances; the high estimate assumes each exec occupies an instance
+of its own. Execs at the same depth of the flow graph are assumed to
+run concurrently when estimating the peak cluster size.`
+	)
+	c.Parse(flags, args, help, "plan [-pred=false] path [args]")
+	if flags.NArg() == 0 {
+		flags.Usage()
+	}
+	e := runtime.Eval{InputArgs: flags.Args()}
+	_, err := e.Run(false)
+	c.must(err)
+	f := e.Main()
+	if f == nil {
+		c.Fatal("module has no Main")
+	}
+	p := flow.NewPlan(f)
+
+	durations := make(map[string]time.Duration)
+	if *predict && len(p.Idents) > 0 {
+		durations = c.planDurations(ctx, p)
+	}
+	region := "us-west-2"
+	var sess *session.Session
+	if err := c.Config.Instance(&sess); err == nil && sess.Config.Region != nil {
+		region = *sess.Config.Region
+	}
+	estimates := estimatePlan(p, durations, func(typ string) float64 {
+		return ec2cluster.OnDemandPrice(typ, region)
+	})
+	writePlan(c.Stdout, p, estimates)
+}
+
+// planDurations returns the durations, by ident, of the execs of
+// plan p, as predicted from the profiles of earlier runs. It returns
+// no durations if the predictor is not available.
+func (c *Cmd) planDurations(ctx context.Context, p *flow.Plan) map[string]time.Duration {
+	durations := make(map[string]time.Duration)
+	cfg, err := runtime.PredictorConfig(c.Config, false)
+	if err != nil {
+		c.Log.Debugf("plan: predictor not available: %v", err)
+		return durations
+	}
+	var tdb taskdb.TaskDB
+	if err := c.Config.Instance(&tdb); err != nil || tdb == nil {
+		c.Log.Debugf("plan: predictor not available: no taskdb: %v", err)
+		return durations
+	}
+	pred := predictor.New(tdb, c.Log.Tee(nil, "predictor: "), cfg.MinData, cfg.MaxInspect, cfg.MemPercentile, cfg.CPUPercentile, cfg.DiskPercentile)
+	tasks := make([]*sched.Task, len(p.Idents))
+	for i, id := range p.Idents {
+		tasks[i] = sched.NewTask()
+		tasks[i].Config = reflow.ExecConfig{Type: "exec", Ident: id.Ident, Image: id.Image, Cmd: id.Cmd, Resources: id.Resources}
+	}
+	for task, prediction := range pred.Predict(ctx, tasks...) {
+		durations[task.Config.Ident] = prediction.Duration
+	}
+	return durations
+}
@@ -0,0 +1,57 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package tool
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grailbio/reflow"
+	"github.com/grailbio/reflow/flow"
+)
+
+func TestEstimatePlan(t *testing.T) {
+	p := &flow.Plan{
+		Idents: []flow.PlanIdent{
+			{Ident: "align", Execs: 10, Resources: reflow.Resources{"mem": 4 << 30, "cpu": 2}},
+			{Ident: "merge", Execs: 1, Resources: reflow.Resources{"mem": 1 << 30, "cpu": 1}},
+		},
+		Execs: 11,
+		Peak:  reflow.Resources{"mem": 40 << 30, "cpu": 20},
+	}
+	durations := map[string]time.Duration{"align": 30 * time.Minute}
+	estimates := estimatePlan(p, durations, func(string) float64 { return 1 })
+	align, merge := estimates[0], estimates[1]
+	if align.InstanceType == "" {
+		t.Fatal("no instance type for align")
+	}
+	// 10 execs of 30 minutes at $1/hour.
+	if got, want := align.HighUSD, 5.0; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if align.LowUSD <= 0 || align.LowUSD > align.HighUSD {
+		t.Errorf("low estimate %v out of range (0, %v]", align.LowUSD, align.HighUSD)
+	}
+	if got, want := merge.HighUSD, 0.0; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	var b bytes.Buffer
+	writePlan(&b, p, estimates)
+	for _, want := range []string{
+		"11 execs, 0 interns, 0 externs",
+		"estimated cost: $",
+		"(excluding 1 idents without duration data)",
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("missing %q in:\n%s", want, b.String())
+		}
+	}
+	if strings.Contains(b.String(), "lower bounds") {
+		t.Errorf("unexpected lower bound in:\n%s", b.String())
+	}
+}
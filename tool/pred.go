@@ -47,7 +47,8 @@ For each arg, and for each statistic profiled, the tool prints:
 pred processes MaxInspect number of inspects specified in the predictor config (which can be overrided by -maxinspect).
 
 If -name is provided, pred will only print statistics for the given name and also display the predicted value for that statistic.
-Valid values for -name are "mem" and "duration".
+Valid values for -name are "mem", "cpu", "disk" and "duration". The predicted "disk"
+value includes both the exec's outputs ("disk") and its scratch space ("tmp").
 
 `
 	c.Parse(flags, args, help, "pred names...")
@@ -67,7 +68,7 @@ Valid values for -name are "mem" and "duration".
 	if *maxInspectFlag > 0 {
 		mi = *maxInspectFlag
 	}
-	p := predictor.New(tdb, c.Log.Tee(nil, "predictor: "), cfg.MinData, mi, cfg.MemPercentile)
+	p := predictor.New(tdb, c.Log.Tee(nil, "predictor: "), cfg.MinData, mi, cfg.MemPercentile, cfg.CPUPercentile, cfg.DiskPercentile)
 	results := make(chan profResults)
 	go func() {
 		_ = traverse.Each(len(flags.Args()), func(i int) error {
@@ -143,7 +144,14 @@ Valid values for -name are "mem" and "duration".
 		if *nameFlag == "" {
 			continue
 		}
-		if v, n, err := p.QueryPercentile(profs, *nameFlag, cfg.MemPercentile); err != nil {
+		pct := cfg.MemPercentile
+		switch *nameFlag {
+		case "cpu":
+			pct = cfg.CPUPercentile
+		case "disk":
+			pct = cfg.DiskPercentile
+		}
+		if v, n, err := p.QueryPercentile(profs, *nameFlag, pct); err != nil {
 			c.Log.Errorf("QueryPercentile arg %s: %v", arg, err)
 		} else {
 			switch *nameFlag {
@@ -0,0 +1,190 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package tool
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/grailbio/reflow"
+	"github.com/grailbio/reflow/assoc"
+	"github.com/grailbio/reflow/flow"
+	"github.com/grailbio/reflow/infra"
+	"github.com/grailbio/reflow/runtime"
+	"github.com/grailbio/reflow/wg"
+)
+
+// writePrewarm writes a report of the dry run r, and of the interns
+// and images which are staged ahead of the run, to w.
+func writePrewarm(w io.Writer, r flow.DryRunReport, interns int, images []string) {
+	counts := make(map[string]int)
+	for _, x := range r.Execs {
+		counts[x.Op]++
+	}
+	fmt.Fprintf(w, "%d execs, %d interns, %d externs miss the cache\n",
+		counts[flow.Exec.String()], counts[flow.Intern.String()], counts[flow.Extern.String()])
+	if r.Unexpanded > 0 {
+		fmt.Fprintf(w, "%d maps or continuations depend on nodes which miss the cache and could not be expanded\n", r.Unexpanded)
+	}
+	if len(r.Execs) > 0 {
+		fmt.Fprintln(w)
+		var tw tabwriter.Writer
+		tw.Init(w, 4, 4, 1, ' ', 0)
+		fmt.Fprintln(&tw, "ident\top\tflow\treason")
+		for _, x := range r.Execs {
+			fmt.Fprintf(&tw, "%s\t%s\t%s\t%s\n", x.Ident, x.Op, x.FlowID.Short(), x.Reason)
+		}
+		_ = tw.Flush()
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "%d interns and %d images to stage\n", interns, len(images))
+	for _, image := range images {
+		fmt.Fprintf(w, "\t%s\n", image)
+	}
+}
+
+func (c *Cmd) prewarm(ctx context.Context, args ...string) {
+	var (
+		flags = flag.NewFlagSet("prewarm", flag.ExitOnError)
+		help  = `Prewarm prepares the cache and the cluster for an anticipated run of a
+Reflow program, so that the run starts computing as soon as it begins
+(e.g., at the start of a scheduled window).
+
+The program is evaluated as in a dry run (see reflow run -dryrun): its
+results are looked up in the cache, and the execs, interns and externs
+which would run are reported. The interns which would run are then
+interned into the repository and their results cached, so that the run
+finds them in the cache. The images of the execs which would run are
+prefetched by the allocs on which the interns run, so that they are
+present on the cluster's instances while these are kept alive.
+
+With -dryrun, prewarm only reports what it would stage. Prewarm should
+be given the same run flags (e.g., -cachenamespace) as the run itself.`
+		runFlags runtime.CommonRunFlags
+	)
+	runFlags.Flags(flags)
+	c.Parse(flags, args, help, "prewarm [-dryrun] [flags] path [args]")
+	if flags.NArg() == 0 {
+		flags.Usage()
+	}
+	if err := runFlags.Err(); err != nil {
+		c.Errorln(err)
+		flags.Usage()
+	}
+	e := runtime.Eval{InputArgs: flags.Args()}
+	_, err := e.Run(false)
+	c.must(err)
+	f := e.Main()
+	if f == nil {
+		c.Fatal("module has no Main")
+	}
+	var (
+		sess  *session.Session
+		repo  reflow.Repository
+		ass   assoc.Assoc
+		cache *infra.CacheProvider
+	)
+	c.must(c.Config.Instance(&sess))
+	c.must(c.Config.Instance(&repo))
+	c.must(c.Config.Instance(&ass))
+	c.must(c.Config.Instance(&cache))
+	auth, err := runtime.ImageAuthenticator(c.Config, sess)
+	c.must(err)
+	c.must(e.ResolveImages(auth))
+
+	rr, err := runtime.NewRuntime(runtime.RuntimeParams{
+		Config: c.Config,
+		Logger: c.Log,
+		Status: c.Status,
+	})
+	c.must(err)
+	scheduler := rr.Scheduler()
+
+	// Look up the program's results as a dry run does, without a
+	// scheduler (nothing is run), and thus without snapshotting
+	// interns, so that the interns which would run are reported.
+	config := flow.EvalConfig{
+		Log:        c.Log,
+		Repository: repo,
+		Assoc:      ass,
+		AssertionGenerator: reflow.AssertionGeneratorMux{
+			reflow.BlobAssertionsNamespace: scheduler.Mux,
+			reflow.DockerAssertionsNamespace: &runtime.ImageAssertions{
+				Resolver:      &runtime.ImageResolver{Authenticator: auth},
+				ImageMap:      e.ImageMap,
+				Arm64ImageMap: e.Arm64ImageMap,
+			},
+		},
+		CacheMode:     cache.CacheMode,
+		ImageMap:      e.ImageMap,
+		Arm64ImageMap: e.Arm64ImageMap,
+	}
+	c.must(runFlags.Configure(&config))
+	config.DryRun = true
+	dryrun := flow.NewEval(f, config)
+	if err = dryrun.Do(ctx); err == nil {
+		err = dryrun.Err()
+	}
+	if err != nil && !flow.IsDryRun(err) {
+		c.Fatalf("cache lookup: %v", err)
+	}
+	report := dryrun.DryRunReport()
+	interns, images, err := report.Prewarm()
+	c.must(err)
+	for i, image := range images {
+		if resolved, ok := e.ImageMap[image]; ok {
+			images[i] = resolved
+		}
+	}
+	var ninterns int
+	if interns != nil {
+		ninterns = len(interns.Deps)
+	}
+	writePrewarm(c.Stdout, report, ninterns, images)
+	if !runFlags.DryRun && interns != nil && !config.CacheMode.Writing() {
+		c.Fatal("cache writes are disabled: staged interns would not be cached")
+	}
+	if runFlags.DryRun || interns == nil {
+		if interns == nil && len(images) > 0 {
+			c.Log.Printf("no interns to stage: images are not prefetched")
+		}
+		return
+	}
+
+	if len(images) > scheduler.PrefetchImages {
+		scheduler.PrefetchImages = len(images)
+	}
+	scheduler.WarmImages = images
+	ctx, cancel := context.WithCancel(ctx)
+	defer func() {
+		cancel()
+		rr.WaitDone()
+	}()
+	rr.Start(ctx)
+
+	config.DryRun = false
+	config.Scheduler = scheduler
+	config.Snapshotter = scheduler.Mux
+	if c.Status != nil {
+		config.Status = c.Status.Group("prewarm")
+	}
+	var bg wg.WaitGroup
+	ctx, bgcancel := flow.WithBackground(ctx, &bg)
+	stage := flow.NewEval(interns, config)
+	if err = stage.Do(ctx); err == nil {
+		err = stage.Err()
+	}
+	// Wait for the interns' results to be written to the cache.
+	<-bg.C()
+	bgcancel()
+	if err != nil {
+		c.Fatalf("stage interns: %v", err)
+	}
+	c.Log.Printf("staged %d interns", ninterns)
+}
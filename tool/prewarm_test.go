@@ -0,0 +1,37 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package tool
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/grailbio/reflow"
+	"github.com/grailbio/reflow/flow"
+)
+
+func TestWritePrewarm(t *testing.T) {
+	r := flow.DryRunReport{
+		Execs: []flow.DryRunExec{
+			{Ident: "align", Op: "exec", FlowID: reflow.Digester.FromString("align"), Image: "bwa", Reason: "no cached result"},
+			{Ident: "fastq", Op: "intern", FlowID: reflow.Digester.FromString("fastq"), URL: "s3://bucket/fastq/", Reason: "no cached result"},
+		},
+		Unexpanded: 2,
+	}
+	var b bytes.Buffer
+	writePrewarm(&b, r, 1, []string{"bwa"})
+	for _, want := range []string{
+		"1 execs, 1 interns, 0 externs miss the cache",
+		"2 maps or continuations",
+		"fastq",
+		"1 interns and 1 images to stage",
+		"\tbwa\n",
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("missing %q in:\n%s", want, b.String())
+		}
+	}
+}
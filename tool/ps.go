@@ -67,7 +67,7 @@ var (
 		{"end", "(if completed) the task's end time"},
 		{"taskDur", "the task's run duration"},
 		{"execDur", "the exec's run duration"},
-		{"state", "the task's (current) state"},
+		{"state", "the task's (current) state, with the progress reported by running execs (if any)"},
 		{"mem", "the amount of memory used by the exec"},
 		{"cpu", "the number of CPU cores used by the exec"},
 		{"disk", "the total amount of disk space used by the exec"},
@@ -126,6 +126,7 @@ func (c *Cmd) ps(ctx context.Context, args ...string) {
 	sinceFlag := flags.String("since", "", "runs (or pools) that were active since, default 10m ago (format time.Duration or YYYY-MM-DD UTC)")
 	untilFlag := flags.String("until", "", "runs (or pools) that were active until, default now (format time.Duration or YYYY-MM-DD UTC)")
 	allUsersFlag := flags.Bool("a", false, "show runs (or pools) of all users")
+	clusterWide := c.usersFlag(flags, "show runs, tasks and pools of all users across the cluster")
 	poolsFlag := flags.Bool("p", false, "show pools instead of runs and tasks")
 	verFlag := flags.String("p_version", "", "show pools with this reflow version instead")
 	clustNameFlag := flags.String("p_name", "", "show pools with this cluster name instead")
@@ -151,6 +152,13 @@ information for memory, cpu, and disk utilization in place of live utilization.
 Flag -l shows the long listing; the live exec URI for a running task and the result id
 and inspect for a completed task.
 
+Flag -users=all, which is available only to cluster administrators (as
+configured by the infra "admins" key), shows a cluster-wide view: runs
+of all users and pools of all users, cluster names and reflow versions
+or, without a TaskDB, the execs on the reflowlets of all users. Unlike
+-a, which only lists the runs of all users in TaskDB, it also lists
+pools and execs of any user.
+
 Ps must contact each node in the cluster to gather exec data. If a node 
 does not respond within a predefined timeout, it is skipped, and an error is
 printed on the console.
@@ -183,14 +191,19 @@ To get the exact cost for pools, add -exact_cost.
 (Note that one may still get non-exact costs in this case, depending on availability of spot feed data)
 
 `
-	c.Parse(flags, args, help, "ps [-i] [-l] [-a | -u <user>] [-since <time>] [-p] [-p_version <reflow_version>] [-p_name <cluster_name>] [-exact_cost]")
+	c.Parse(flags, args, help, "ps [-i] [-l] [-a | -users=all | -u <user>] [-since <time>] [-p] [-p_version <reflow_version>] [-p_name <cluster_name>] [-exact_cost]")
 	if flags.NArg() != 0 {
 		flags.Usage()
 	}
 
-	if *userFlag != "" && *allUsersFlag {
+	clusterView := clusterWide()
+	if *userFlag != "" && (*allUsersFlag || clusterView) {
 		flags.Usage()
 	}
+	if clusterView {
+		c.requireAdmin()
+		*allUsersFlag = true
+	}
 
 	if !*poolsFlag && *exactCostFlag {
 		c.Fatalf("-exact_cost only works with -p")
@@ -200,6 +213,9 @@ To get the exact cost for pools, add -exact_cost.
 	err := c.Config.Instance(&tdb)
 	if tdb == nil {
 		cluster := c.CurrentPool(ctx)
+		if clusterView {
+			cluster = c.AllUsersPool(ctx)
+		}
 		allocsCtx, allocsCancel := context.WithTimeout(ctx, 5*time.Second)
 		allocs := pool.Allocs(allocsCtx, cluster, c.Log)
 		allocsCancel()
@@ -266,54 +282,15 @@ To get the exact cost for pools, add -exact_cost.
 			case dur > 24*time.Hour:
 				layout = "Mon3:04PM"
 			}
-			var procs string
-			switch info.Config.Type {
-			case "exec":
-				if len(info.Commands) == 0 {
-					procs = "[exec]"
-				} else {
-					ncmd := make(map[string]int)
-					for _, proc := range info.Commands {
-						// Pick the first token as representative.
-						c := strings.SplitN(proc, " ", 2)[0]
-						c = path.Base(c)
-						// Skip bash, it runs everywhere.
-						if c == "bash" {
-							continue
-						}
-						ncmd[c]++
-					}
-					cmds := make([]string, 0, len(ncmd))
-					for cmd, n := range ncmd {
-						if n > 1 {
-							cmd += fmt.Sprintf("(%d)", n)
-						}
-						cmds = append(cmds, cmd)
-					}
-					procs = strings.Join(cmds, ",")
-				}
-			default:
-				procs = "[" + info.Config.Type + "]"
-			}
-			var mem, cpu, disk float64
-			switch info.State {
-			case "running":
-				mem = info.Gauges["mem"]
-				cpu = info.Gauges["cpu"]
-				disk = info.Gauges["disk"] + info.Gauges["tmp"]
-			case "complete":
-				mem = info.Profile["mem"].Max
-				cpu = info.Profile["cpu"].Mean
-				// This is a conservative estimate--we don't keep track of total max.
-				disk = info.Profile["disk"].Max + info.Profile["tmp"].Max
-			}
+			procs := execProcs(info.ExecInspect)
+			mem, cpu, disk := execUsage(info.ExecInspect)
 			runtime := info.Runtime()
 			fmt.Fprintf(&tw, "%s\t%s\t%s\t%d:%02d\t%s\t%s\t%.1f\t%s\t%s",
 				getShort(info.ID), info.Config.Ident,
 				info.Created.Local().Format(layout),
 				int(runtime.Hours()),
 				int(runtime.Minutes()-60*runtime.Hours()),
-				info.State,
+				execState(info.ExecInspect, 0),
 				data.Size(mem), cpu, data.Size(disk),
 				procs,
 			)
@@ -364,6 +341,16 @@ To get the exact cost for pools, add -exact_cost.
 			c.Fatalf("poolInfo: not applicable for non-ec2 cluster %T", cluster)
 		}
 		q := taskdb.PoolQuery{Since: since, Until: until, Cluster: taskdb.ClusterID{User: user}}
+		if clusterView {
+			// A cluster-wide view includes all cluster names and versions,
+			// unless explicitly restricted.
+			if *verFlag == "" {
+				*verFlag = allFlagValue
+			}
+			if *clustNameFlag == "" {
+				*clustNameFlag = allFlagValue
+			}
+		}
 		switch *verFlag {
 		case "":
 			q.Cluster.ReflowVersion = ec2c.ReflowVersion
@@ -495,14 +482,8 @@ func (c *Cmd) taskInfo(ctx context.Context, q taskdb.TaskQuery, liveOnly, cost b
 		if !t.Task.ID.IsValid() {
 			continue
 		}
-		if cost && t.Alloc != nil && t.Alloc.Pool != nil {
-			a, p := t.Alloc, t.Alloc.Pool
-			pi := poolInfo{*p, cc}
-			t.cost = pi.cost(t.TimeFields) // cost of the pool for the task's duration
-			// Scale the cost by the ratio of the task's resources to the alloc's resources.
-			t.cost.Mul(t.Resources.MaxRatio(a.Resources))
-			// Then scale the cost by the ratio of the alloc's resources to the pool's resources.
-			t.cost.Mul(a.Resources.MaxRatio(p.Resources))
+		if cost {
+			t.cost = taskCost(t.Task, cc)
 		}
 		b = append(b, t)
 	}
@@ -586,6 +567,27 @@ func (p poolInfo) cost(c taskdb.TimeFields) (cost Cost) {
 	return p.cc.compute(p.PoolID.String(), p.PoolType, p.End, start, end)
 }
 
+// taskCost returns the cost of the given task, which must have been
+// queried with its alloc. The cost of a task is the cost of its alloc's
+// pool for the task's duration, scaled by the task's share of the pool's
+// resources.
+func taskCost(t taskdb.Task, cc *costComputer) (cost Cost) {
+	if cc == nil || t.Alloc == nil || t.Alloc.Pool == nil {
+		return
+	}
+	a, p := t.Alloc, t.Alloc.Pool
+	pi := poolInfo{*p, cc}
+	cost = pi.cost(t.TimeFields) // cost of the pool for the task's duration
+	if cost.typ == costTypeUnknown {
+		return
+	}
+	// Scale the cost by the ratio of the task's resources to the alloc's resources.
+	cost.Mul(t.Resources.MaxRatio(a.Resources))
+	// Then scale the cost by the ratio of the alloc's resources to the pool's resources.
+	cost.Mul(a.Resources.MaxRatio(p.Resources))
+	return
+}
+
 func (c *Cmd) poolInfos(ctx context.Context, q taskdb.PoolQuery, exactCost bool) ([]poolInfo, error) {
 	var tdb taskdb.TaskDB
 	if err := c.Config.Instance(&tdb); err != nil {
@@ -687,38 +689,15 @@ func formatStartEnd(c taskdb.TimeFields) (st, et string) {
 
 func (c *Cmd) writeTask(task taskInfo, w io.Writer, longListing, full bool) {
 	var (
-		procs, ident, state string
-		info                = task.ExecInspect
-		runtime             = info.Runtime()
-		st, et              = formatStartEnd(task.TimeFields)
+		ident, state string
+		info         = task.ExecInspect
+		runtime      = info.Runtime()
+		st, et       = formatStartEnd(task.TimeFields)
 	)
 	switch info.Config.Type {
 	case "exec":
 		ident = task.Config.Ident
-		state = info.State
-		if len(info.Commands) == 0 {
-			procs = "[exec]"
-		} else {
-			ncmd := make(map[string]int)
-			for _, proc := range info.Commands {
-				// Pick the first token as representative.
-				c := strings.SplitN(proc, " ", 2)[0]
-				c = path.Base(c)
-				// Skip bash, it runs everywhere.
-				if c == "bash" {
-					continue
-				}
-				ncmd[c]++
-			}
-			cmds := make([]string, 0, len(ncmd))
-			for cmd, n := range ncmd {
-				if n > 1 {
-					cmd += fmt.Sprintf("(%d)", n)
-				}
-				cmds = append(cmds, cmd)
-			}
-			procs = strings.Join(cmds, ",")
-		}
+		state = execState(info, task.Progress)
 	default:
 		ident = task.Ident
 		if !task.End.IsZero() {
@@ -726,20 +705,9 @@ func (c *Cmd) writeTask(task taskInfo, w io.Writer, longListing, full bool) {
 		} else {
 			state = "unknown"
 		}
-		procs = "[" + info.Config.Type + "]"
-	}
-	var mem, cpu, disk float64
-	switch info.State {
-	case "running":
-		mem = info.Gauges["mem"]
-		cpu = info.Gauges["cpu"]
-		disk = info.Gauges["disk"] + info.Gauges["tmp"]
-	case "complete":
-		mem = info.Profile["mem"].Max
-		cpu = info.Profile["cpu"].Mean
-		// This is a conservative estimate--we don't keep track of total max.
-		disk = info.Profile["disk"].Max + info.Profile["tmp"].Max
 	}
+	procs := execProcs(info)
+	mem, cpu, disk := execUsage(info)
 	s, e := task.StartEnd()
 	dur := e.Sub(s).Truncate(time.Second)
 	tid := task.ID.IDShort()
@@ -762,6 +730,70 @@ func (c *Cmd) writeTask(task taskInfo, w io.Writer, longListing, full bool) {
 	fmt.Fprint(w, "\n")
 }
 
+// execProcs returns a summary of the processes running in the exec
+// with the given inspect.
+func execProcs(info reflow.ExecInspect) string {
+	if info.Config.Type != "exec" {
+		return "[" + info.Config.Type + "]"
+	}
+	if len(info.Commands) == 0 {
+		return "[exec]"
+	}
+	ncmd := make(map[string]int)
+	for _, proc := range info.Commands {
+		// Pick the first token as representative.
+		c := strings.SplitN(proc, " ", 2)[0]
+		c = path.Base(c)
+		// Skip bash, it runs everywhere.
+		if c == "bash" {
+			continue
+		}
+		ncmd[c]++
+	}
+	cmds := make([]string, 0, len(ncmd))
+	for cmd, n := range ncmd {
+		if n > 1 {
+			cmd += fmt.Sprintf("(%d)", n)
+		}
+		cmds = append(cmds, cmd)
+	}
+	return strings.Join(cmds, ",")
+}
+
+// execUsage returns the memory, cpu and disk used by the exec with the
+// given inspect: current usage for running execs, and peak (mean, for
+// cpu) usage for completed ones.
+func execUsage(info reflow.ExecInspect) (mem, cpu, disk float64) {
+	switch info.State {
+	case "running":
+		mem = info.Gauges["mem"]
+		cpu = info.Gauges["cpu"]
+		disk = info.Gauges["disk"] + info.Gauges["tmp"]
+	case "complete":
+		mem = info.Profile["mem"].Max
+		cpu = info.Profile["cpu"].Mean
+		// This is a conservative estimate--we don't keep track of total max.
+		disk = info.Profile["disk"].Max + info.Profile["tmp"].Max
+	}
+	return
+}
+
+// execState returns the state of the exec with the given inspect,
+// annotated with the progress reported by the exec (if running), e.g.,
+// "running(42%)". The progress is taken from the exec's gauges or,
+// if none was gauged, from the provided (taskdb) progress.
+func execState(info reflow.ExecInspect, progress float64) string {
+	if info.State != "running" {
+		return info.State
+	}
+	if p, ok := info.Gauges[reflow.ProgressGauge]; ok {
+		progress = p
+	} else if progress <= 0 {
+		return info.State
+	}
+	return fmt.Sprintf("%s(%.0f%%)", info.State, progress)
+}
+
 func getErrStr(terr errors.Error, full bool) string {
 	if terr.Err == nil {
 		return ""
@@ -774,7 +806,9 @@ func getErrStr(terr errors.Error, full bool) string {
 		sb.WriteString(fmt.Sprintf("op: %s, ", terr.Op))
 	}
 	sb.WriteString("err: ")
-	errstr := terr.Err.Error()
+	// Only the first line of the error is rendered in a listing; details
+	// (e.g., an exec's post-mortem) are rendered by reflow info.
+	errstr := strings.SplitN(terr.Err.Error(), "\n", 2)[0]
 	if !full {
 		shortLen := 30
 		mid := shortLen / 2
@@ -50,6 +50,8 @@ supplied via a CSV batch file as in "reflow runbatch".`
 
 	var sess *session.Session
 	c.must(c.Config.Instance(&sess))
+	auth, err := runtime.ImageAuthenticator(c.Config, sess)
+	c.must(err)
 	var assoc assoc.Assoc
 	c.must(c.Config.Instance(&assoc))
 	var repo reflow.Repository
@@ -95,7 +97,7 @@ supplied via a CSV batch file as in "reflow runbatch".`
 				if err != nil {
 					return err
 				}
-				if err := e.ResolveImages(sess); err != nil {
+				if err := e.ResolveImages(auth); err != nil {
 					return err
 				}
 				c.Log.Printf("repair: %s", strings.Join(args, " "))
@@ -112,8 +114,9 @@ supplied via a CSV batch file as in "reflow runbatch".`
 		}
 		_, err := e.Run(false)
 		c.must(err)
-		c.must(e.ResolveImages(sess))
+		c.must(e.ResolveImages(auth))
 		repair.ImageMap = e.ImageMap
+		repair.Arm64ImageMap = e.Arm64ImageMap
 		repair.Do(ctx, e.Main())
 
 	}
@@ -0,0 +1,172 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package tool
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/grailbio/reflow/errors"
+	"github.com/grailbio/reflow/flow"
+	"github.com/grailbio/reflow/runner"
+)
+
+// Exit classes of runs, as recorded in run reports.
+const (
+	exitSuccess   = "success"
+	exitEval      = "eval"
+	exitTransient = "transient"
+	exitError     = "error"
+)
+
+// runReport is the machine-readable report of a run written by
+// "reflow run -report".
+type runReport struct {
+	// RunID is the run's ID.
+	RunID string `json:"runid,omitempty"`
+	// Program and Args are the run's program and its arguments.
+	Program string   `json:"program"`
+	Args    []string `json:"args,omitempty"`
+	// ExitCode is the run's exit code, and ExitClass its class: one of
+	// "success", "eval" (an evaluation error, likely not retriable),
+	// "transient" (a transient runtime error) or "error".
+	ExitCode  int    `json:"exitcode"`
+	ExitClass string `json:"exitclass"`
+	// ErrorKind and Error describe the run's error, if any.
+	ErrorKind string `json:"errorkind,omitempty"`
+	Error     string `json:"error,omitempty"`
+	// Hints are the remediation hints for the run's error, if it is a
+	// well-known failure.
+	Hints []errors.Hint `json:"hints,omitempty"`
+	// Result is the run's result value, rendered as a string.
+	Result string `json:"result,omitempty"`
+	// CostUSD is the estimated cost of the run's tasks.
+	CostUSD float64 `json:"costusd"`
+	// Execs is the number of execs (including interns and externs)
+	// evaluated by the run, and CachedExecs the number of those which
+	// were cache hits.
+	Execs       int `json:"execs"`
+	CachedExecs int `json:"cachedexecs"`
+	// Failures summarizes the run's failed tasks.
+	Failures []runReportFailure `json:"failures,omitempty"`
+	// Started and Completed are the times at which the run started
+	// and completed.
+	Started   time.Time `json:"started"`
+	Completed time.Time `json:"completed"`
+}
+
+type runReportFailure struct {
+	Ident     string `json:"ident"`
+	Position  string `json:"position,omitempty"`
+	Op        string `json:"op"`
+	FlowID    string `json:"flowid"`
+	Exec      string `json:"exec,omitempty"`
+	ErrorKind string `json:"errorkind"`
+	Error     string `json:"error"`
+}
+
+// exitStatus returns the exit code and class of a run which completed
+// with the error err (which may be nil).
+func exitStatus(err error) (code int, class string) {
+	switch {
+	case err == nil:
+		return 0, exitSuccess
+	case errors.Is(errors.Eval, err):
+		// Error that occurred during evaluation. Probably not recoverable.
+		// TODO(marius): if this was caused by an underyling exit (from a tool)
+		// then propagate this here.
+		return 11, exitEval
+	case errors.Restartable(err):
+		return 10, exitTransient
+	default:
+		return 1, exitError
+	}
+}
+
+// newRunReport returns the report of a run with the provided state
+// which completed with the given error; err is either a runtime error
+// or the run's (evaluation) error.
+func newRunReport(st runner.State, started time.Time, err error) runReport {
+	r := runReport{
+		Program:     st.Program,
+		Args:        st.Args,
+		Result:      st.Result,
+		CostUSD:     st.CostUSD,
+		Execs:       st.Execs,
+		CachedExecs: st.CachedExecs,
+		Started:     started,
+		Completed:   time.Now(),
+	}
+	if st.ID.IsValid() {
+		r.RunID = st.ID.ID()
+	}
+	r.ExitCode, r.ExitClass = exitStatus(err)
+	if err != nil {
+		r.ErrorKind, r.Error = errorKind(err), err.Error()
+		r.Hints = errors.Hints(err)
+	}
+	for _, f := range st.Failures {
+		r.Failures = append(r.Failures, newRunReportFailure(f))
+	}
+	return r
+}
+
+func newRunReportFailure(f flow.Failure) runReportFailure {
+	rf := runReportFailure{
+		Ident:    f.Ident,
+		Position: f.Position,
+		Op:       f.Op,
+		FlowID:   f.FlowID.String(),
+		Exec:     f.Exec,
+	}
+	if f.Err != nil {
+		rf.ErrorKind, rf.Error = errorKind(f.Err), f.Err.Error()
+	}
+	return rf
+}
+
+// errorKind returns the name of the kind of error err: the kind of
+// the first error in its chain whose kind is not Other.
+func errorKind(err error) string {
+	e := errors.Recover(err)
+	for e.Kind == errors.Other {
+		inner, ok := e.Err.(*errors.Error)
+		if !ok {
+			break
+		}
+		e = inner
+	}
+	return e.Kind.String()
+}
+
+// writeReport writes the report r to path atomically: it is written
+// to a temporary file, which is then renamed, so that readers never
+// observe a partial report.
+func writeReport(path string, r runReport) error {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(append(b, '\n')); err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+	}
+	return err
+}
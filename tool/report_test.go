@@ -0,0 +1,85 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package tool
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/grailbio/reflow"
+	"github.com/grailbio/reflow/errors"
+	"github.com/grailbio/reflow/flow"
+	"github.com/grailbio/reflow/runner"
+)
+
+func TestExitStatus(t *testing.T) {
+	for _, c := range []struct {
+		err   error
+		code  int
+		class string
+	}{
+		{nil, 0, exitSuccess},
+		{errors.E(errors.Eval, errors.New("eval")), 11, exitEval},
+		{errors.E(errors.Unavailable, errors.New("unavailable")), 10, exitTransient},
+		{errors.E(errors.Invalid, errors.New("invalid")), 1, exitError},
+	} {
+		code, class := exitStatus(c.err)
+		if code != c.code || class != c.class {
+			t.Errorf("exitStatus(%v): got %v, %v, want %v, %v", c.err, code, class, c.code, c.class)
+		}
+	}
+}
+
+func TestRunReport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "report")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	st := runner.State{
+		Program:  "test.rf",
+		Args:     []string{"-x", "1"},
+		CostUSD:  1.5,
+		Failures: []flow.Failure{{Ident: "align", Op: "exec", FlowID: reflow.Digester.FromString("align"), Err: errors.Recover(errors.E(errors.NotExist, errors.New("missing")))}},
+	}
+	st.Execs, st.CachedExecs = 3, 1
+	path := filepath.Join(dir, "report.json")
+	err = errors.E(errors.Eval, errors.E("exec", errors.E(errors.NotExist, errors.New("missing"))))
+	if err := writeReport(path, newRunReport(st, time.Now(), err)); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var r runReport
+	if err := json.Unmarshal(b, &r); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := r.ExitCode, 11; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := r.ErrorKind, errors.Eval.String(); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := r.Execs, 3; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if len(r.Failures) != 1 || r.Failures[0].ErrorKind != errors.NotExist.String() {
+		t.Errorf("bad failures: %+v", r.Failures)
+	}
+	// Only the report is left behind.
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Errorf("got %d files, want 1", len(files))
+	}
+}
@@ -0,0 +1,201 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/grailbio/base/data"
+	"github.com/grailbio/base/digest"
+	"github.com/grailbio/reflow"
+	"github.com/grailbio/reflow/assoc"
+	"github.com/grailbio/reflow/errors"
+	"github.com/grailbio/reflow/infra"
+	"github.com/grailbio/reflow/repository"
+	"github.com/grailbio/reflow/runtime"
+	"github.com/grailbio/reflow/sched"
+	"github.com/grailbio/reflow/taskdb"
+)
+
+func (c *Cmd) rerun(ctx context.Context, args ...string) {
+	var (
+		flags     = flag.NewFlagSet("rerun", flag.ExitOnError)
+		imageFlag = flags.String("image", "", "run the exec with this image instead of the original one")
+		cpuFlag   = flags.Float64("cpu", 0, "number of cores to reserve for the exec (default: as originally reserved)")
+		memFlag   = flags.Float64("mem", 0, "memory (in GiB) to reserve for the exec (default: as originally reserved)")
+		diskFlag  = flags.Float64("disk", 0, "disk space (in GiB) to reserve for the exec (default: as originally reserved)")
+		cacheFlag = flags.Bool("cache", true, "write the exec's result to the cache (ignored with -image)")
+		help      = `Rerun re-executes a single task on the cluster.
+
+The task's exec configuration (image, command, resources and inputs) is
+reconstructed from its inspect, as recorded in TaskDB, and submitted
+as a new task against the cluster, under a new run. This is useful to
+debug or retry a single failed (or flaky) exec of a run without
+re-evaluating the run's program.
+
+The exec's resources may be adjusted with -cpu, -mem and -disk, and
+its image may be replaced with -image. Unless -cache=false is given,
+the result of a successful exec is written to the cache, so that
+subsequent runs of the program use it. Since an exec's image is part
+of its cache key, results of execs run with -image are never cached.
+
+Only execs (not interns or externs) may be rerun.`
+	)
+	c.Parse(flags, args, help, "rerun [-image image] [-cpu n] [-mem GiB] [-disk GiB] [-cache=false] taskid")
+	if flags.NArg() != 1 {
+		flags.Usage()
+	}
+	n, err := parseName(flags.Arg(0))
+	if err != nil {
+		c.Fatal(err)
+	}
+	if n.Kind != idName {
+		c.Fatalf("%s: not a task id", flags.Arg(0))
+	}
+	var tdb taskdb.TaskDB
+	if err = c.Config.Instance(&tdb); err != nil {
+		c.Fatalf("taskdb: %v", err)
+	}
+	if tdb == nil {
+		c.Fatal("no taskdb configured")
+	}
+	tasks, err := tdb.Tasks(ctx, taskdb.TaskQuery{ID: taskdb.TaskID(n.ID)})
+	if err != nil {
+		c.Fatalf("tasks: %v", err)
+	}
+	if len(tasks) == 0 {
+		c.Fatalf("task %s not found", flags.Arg(0))
+	}
+	task := tasks[0]
+	if task.Inspect.IsZero() {
+		c.Fatalf("task %s: no inspect recorded", task.ID.IDShort())
+	}
+	rc, err := tdb.Repository().Get(ctx, task.Inspect)
+	if err != nil {
+		c.Fatalf("task %s: inspect %s: %v", task.ID.IDShort(), task.Inspect.Short(), err)
+	}
+	var inspect reflow.ExecInspect
+	err = json.NewDecoder(rc).Decode(&inspect)
+	_ = rc.Close()
+	if err != nil {
+		c.Fatalf("task %s: decode inspect %s: %v", task.ID.IDShort(), task.Inspect.Short(), err)
+	}
+	resources := make(reflow.Resources)
+	if *cpuFlag > 0 {
+		resources["cpu"] = *cpuFlag
+	}
+	if *memFlag > 0 {
+		resources["mem"] = *memFlag * float64(data.GiB)
+	}
+	if *diskFlag > 0 {
+		resources["disk"] = *diskFlag * float64(data.GiB)
+	}
+	config, err := rerunConfig(inspect.Config, *imageFlag, resources)
+	if err != nil {
+		c.Fatalf("task %s: %v", task.ID.IDShort(), err)
+	}
+
+	var (
+		repo  reflow.Repository
+		ass   assoc.Assoc
+		user  *infra.User
+		runID = taskdb.NewRunID()
+	)
+	c.must(c.Config.Instance(&repo))
+	c.must(c.Config.Instance(&user))
+	if *cacheFlag && *imageFlag == "" {
+		c.must(c.Config.Instance(&ass))
+	}
+	rr, err := runtime.NewRuntime(runtime.RuntimeParams{
+		Config: c.Config,
+		Logger: c.Log,
+		Status: c.Status,
+	})
+	c.must(err)
+	ctx, cancel := context.WithCancel(ctx)
+	defer func() {
+		cancel()
+		rr.WaitDone()
+	}()
+	rr.Start(ctx)
+
+	runCreated := tdb.CreateRun(ctx, runID, user.User()) == nil
+	if runCreated {
+		go func() { _ = taskdb.KeepRunAlive(ctx, tdb, runID) }()
+	}
+	c.Log.Printf("rerunning task %s (%s) as run %s: %s", task.ID.IDShort(), config.Ident, runID.IDShort(), config)
+
+	t := sched.NewTask()
+	t.RunID = runID
+	t.FlowID = task.FlowID
+	t.Config = config
+	t.Repository = repo
+	t.Log = c.Log
+	rr.Scheduler().Submit(t)
+	if err = t.Wait(ctx, sched.TaskDone); err != nil {
+		c.Fatal(err)
+	}
+	if runCreated {
+		if err = tdb.SetRunComplete(ctx, runID, digest.Digest{}, digest.Digest{}, digest.Digest{}, time.Now()); err != nil {
+			c.Log.Debugf("taskdb setruncomplete: %v", err)
+		}
+	}
+
+	var tw tabwriter.Writer
+	tw.Init(c.Stdout, 4, 4, 1, ' ', 0)
+	fmt.Fprintf(&tw, "task %s (rerun of %s)\n", t.ID().IDShort(), task.ID.IDShort())
+	switch {
+	case t.Err != nil:
+		fmt.Fprintf(&tw, "\terror:\t%v\n", t.Err)
+	case t.Result.Err != nil:
+		fmt.Fprintf(&tw, "\terror:\t%v\n", t.Result.Err)
+	default:
+		c.printFileset(&tw, "\t", t.Result.Fileset)
+	}
+	c.must(tw.Flush())
+	if t.Err != nil || t.Result.Err != nil {
+		c.Exit(1)
+	}
+	if ass == nil {
+		return
+	}
+	// The flow's digest is its primary cache key: it does not depend
+	// on the exec's resources.
+	id, err := repository.Marshal(ctx, repo, &t.Result.Fileset)
+	if err == nil {
+		err = ass.Store(ctx, assoc.FilesetV2, task.FlowID, id)
+	}
+	if err != nil {
+		c.Fatalf("cache write %s: %v", task.FlowID.Short(), err)
+	}
+	c.Log.Printf("cached result of flow %s", task.FlowID.Short())
+}
+
+// rerunConfig returns the exec config with which to rerun an exec
+// with the given (original) config, using the given image (if
+// nonempty) and overriding the given resources.
+func rerunConfig(orig reflow.ExecConfig, image string, resources reflow.Resources) (reflow.ExecConfig, error) {
+	if orig.Type != "exec" {
+		return reflow.ExecConfig{}, errors.E("rerun", orig.Ident, errors.NotSupported,
+			fmt.Errorf("cannot rerun %s (only execs can be rerun)", orig.Type))
+	}
+	config := orig
+	config.Args = append([]reflow.Arg{}, orig.Args...)
+	config.Resources = make(reflow.Resources)
+	config.Resources.Set(orig.Resources)
+	for k, v := range resources {
+		config.Resources[k] = v
+	}
+	if image != "" {
+		config.Image = image
+		config.OriginalImage = image
+	}
+	return config, nil
+}
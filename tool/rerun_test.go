@@ -0,0 +1,54 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package tool
+
+import (
+	"testing"
+
+	"github.com/grailbio/reflow"
+	"github.com/grailbio/reflow/errors"
+)
+
+func TestRerunConfig(t *testing.T) {
+	orig := reflow.ExecConfig{
+		Type:          "exec",
+		Ident:         "align",
+		Image:         "ubuntu@sha256:1234",
+		OriginalImage: "ubuntu",
+		Cmd:           "cat {{arg[0][0]}} > {{arg[1][0]}}",
+		Args:          []reflow.Arg{{Fileset: &reflow.Fileset{}}, {Out: true}},
+		Resources:     reflow.Resources{"cpu": 2, "mem": 4 << 30},
+	}
+	config, err := rerunConfig(orig, "", reflow.Resources{"mem": 8 << 30})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := config.Resources, (reflow.Resources{"cpu": 2, "mem": 8 << 30}); !got.Equal(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := config.Image, orig.Image; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	// The original config must not be modified.
+	if got, want := orig.Resources["mem"], float64(4<<30); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	config, err = rerunConfig(orig, "ubuntu:20.04", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := config.Image, "ubuntu:20.04"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := config.OriginalImage, "ubuntu:20.04"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	orig.Type = "intern"
+	if _, err := rerunConfig(orig, "", nil); !errors.Is(errors.NotSupported, err) {
+		t.Errorf("expected NotSupported error, got %v", err)
+	}
+}
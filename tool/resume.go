@@ -0,0 +1,106 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package tool
+
+import (
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+
+	"github.com/grailbio/base/digest"
+	"github.com/grailbio/base/state"
+	"github.com/grailbio/reflow"
+	"github.com/grailbio/reflow/runner"
+	"github.com/grailbio/reflow/runtime"
+	"github.com/grailbio/reflow/taskdb"
+)
+
+func (c *Cmd) resume(ctx context.Context, args ...string) {
+	var (
+		flags = flag.NewFlagSet("resume", flag.ExitOnError)
+		help  = `Resume resumes a run that was interrupted, e.g., because its
+driver was killed.
+
+The run's program and arguments are recovered from the run's state,
+as stored in the local run directory ($HOME/.reflow/runs); the run
+must thus be resumed from the machine on which it was started. The
+program is re-evaluated under the run's original ID, so that the
+tasks of the interrupted and the resumed runs are associated with the
+same run in TaskDB.
+
+Execs, interns and externs completed by the interrupted run are
+recorded in its frontier and are not recomputed, regardless of
+whether they were cached. All other nodes are evaluated as usual
+(and may thus be cache hits).
+
+Resume does not re-attach to work that was in flight when the run was
+interrupted: execs, interns and externs which had not completed are
+run again from the start, even if their allocs are still running them.
+Their tasks are marked in TaskDB as canceled ("run interrupted"), and
+the allocs of the interrupted run are reclaimed once their leases
+expire.
+
+Resume accepts the same flags as run; these are not recovered from
+the interrupted run and must be supplied again if needed.`
+	)
+	var config runtime.RunFlags
+	config.Flags(flags)
+	reportFlag := flags.String("report", "", "write a JSON report of the run's outcome to this path (see reflow run -help)")
+	c.Parse(flags, args, help, "resume [flags] runid")
+	if err := config.Err(); err != nil {
+		c.Errorln(err)
+		flags.Usage()
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+	}
+	id, err := reflow.Digester.Parse(flags.Arg(0))
+	if err != nil {
+		c.Fatalf("%s: not a run id: %v", flags.Arg(0), err)
+	}
+	id = c.expandRunID(id)
+	base := filepath.Join(c.rundir(), id.Hex())
+	if _, err = os.Stat(base + ".json"); err != nil {
+		c.Fatalf("run %s: %v", id.Short(), err)
+	}
+	statefile, err := state.Open(base)
+	if err != nil {
+		c.Fatalf("run %s: %v", id.Short(), err)
+	}
+	var st runner.State
+	if err = statefile.Unmarshal(&st); err != nil {
+		c.Fatalf("run %s: state: %v", id.Short(), err)
+	}
+	if st.Phase == runner.Done && st.Err == nil {
+		c.Fatalf("run %s: already completed: %s", id.Short(), st.Result)
+	}
+	c.Log.Printf("resuming run %s: %s %v", id.Short(), st.Program, st.Args)
+	c.runCommon(ctx, config, st.Program, st.Args, taskdb.RunID(id), *reportFlag)
+}
+
+// expandRunID expands the (possibly abbreviated) run ID id to the ID
+// of a run in the local run directory. The ID is returned unchanged
+// if no such run is found.
+func (c *Cmd) expandRunID(id digest.Digest) digest.Digest {
+	if !id.IsAbbrev() {
+		return id
+	}
+	matches, err := filepath.Glob(filepath.Join(c.rundir(), "*.json"))
+	if err != nil {
+		c.Fatal(err)
+	}
+	for _, path := range matches {
+		name := filepath.Base(path)
+		fullID, err := reflow.Digester.Parse(name[:len(name)-len(".json")])
+		if err != nil {
+			continue
+		}
+		if fullID.Expands(id) {
+			return fullID
+		}
+	}
+	return id
+}
@@ -8,21 +8,23 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"io"
 	golog "log"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/grailbio/base/digest"
 	"github.com/grailbio/reflow"
 	"github.com/grailbio/reflow/ec2cluster"
-	"github.com/grailbio/reflow/errors"
 	"github.com/grailbio/reflow/flow"
 	reflowinfra "github.com/grailbio/reflow/infra"
 	"github.com/grailbio/reflow/log"
 	"github.com/grailbio/reflow/metrics"
 	"github.com/grailbio/reflow/runner"
 	"github.com/grailbio/reflow/runtime"
+	"github.com/grailbio/reflow/taskdb"
 	"github.com/grailbio/reflow/trace"
 	"github.com/grailbio/reflow/types"
 	"github.com/grailbio/reflow/values"
@@ -52,14 +54,45 @@ Reflow logs abbreviated task summaries for execs, interns, and
 externs. On error, or if the logging level is set to debug, the full
 task state is printed together with context.
 
+Instead of a path, run accepts the name of a run template, prefixed
+with "@", as defined by the "runtemplates" provider of the profile
+(see reflow config -help). A template defines a program together with
+its params, run flags and labels, and may inherit these from another
+template. Flags, arguments and labels given on the command line
+override those of the template. For example,
+
+	reflow run -maxcost 100 @wgs -sample=NA12878
+
+runs the program defined by the template "wgs" with its params and
+flags, but with a maximum cost of $100 and the given sample.
+
 Run exits with an error code according to evaluation status. Exit
 code 10 indicates a transient runtime error. Exit codes greater than
 10 indicate errors during program evaluation, which are likely not
-retriable.`
+retriable.
+
+If -report is given, a JSON report of the run's outcome is written
+to the given path when the run exits: its exit code and class
+("success", "eval", "transient" or "error"), the kind and message of
+its error, its result, its estimated cost, the number of execs that
+were evaluated and cached, and summaries of its failed tasks. The
+report is written atomically, so that it may be consumed by CI
+systems without parsing logs.`
 	var config runtime.RunFlags
 	config.Flags(flags)
+	reportFlag := flags.String("report", "", "write a JSON report of the run's outcome to this path")
 
-	c.Parse(flags, args, help, "run [-local] [flags] path [args]")
+	const usage = "run [-local] [flags] path|@template [args]"
+	c.Parse(flags, args, help, usage)
+	if flags.NArg() > 0 && strings.HasPrefix(flags.Arg(0), "@") {
+		// Reparse the arguments as expanded by the template, so that
+		// flags given on the command line override the template's.
+		args = c.expandRunTemplate(flags.Arg(0)[1:], args[:len(args)-flags.NArg()], flags.Args()[1:])
+		flags = flag.NewFlagSet("run", flag.ExitOnError)
+		config = runtime.RunFlags{}
+		config.Flags(flags)
+		c.Parse(flags, args, help, usage)
+	}
 	if err := config.Err(); err != nil {
 		c.Errorln(err)
 		flags.Usage()
@@ -78,14 +111,20 @@ retriable.`
 	}
 	// In the case where a flow is immediate, we print the result and quit.
 	if e.Main().Op == flow.Val {
-		c.Println(sprintval(e.Main().Value, e.MainType()))
+		result := sprintval(e.Main().Value, e.MainType())
+		c.Println(result)
+		if *reportFlag != "" {
+			c.report(*reportFlag, newRunReport(runner.State{Program: file, Args: args, Result: result}, time.Now(), nil))
+		}
 		c.Exit(0)
 	}
-	c.runCommon(ctx, config, file, args)
+	c.runCommon(ctx, config, file, args, taskdb.RunID{}, *reportFlag)
 }
 
-// runCommon is the helper function used by run commands.
-func (c *Cmd) runCommon(ctx context.Context, runFlags runtime.RunFlags, file string, args []string) {
+// runCommon is the helper function used by run commands. If resume
+// is valid, the run with this ID is resumed. If report is non-empty,
+// a report of the run's outcome is written to it (see runReport).
+func (c *Cmd) runCommon(ctx context.Context, runFlags runtime.RunFlags, file string, args []string, resume taskdb.RunID, report string) {
 	if runFlags.Local {
 		dir := runFlags.LocalDir
 		if runFlags.Dir != "" {
@@ -93,6 +132,15 @@ func (c *Cmd) runCommon(ctx context.Context, runFlags runtime.RunFlags, file str
 		}
 		var err error
 		c.SchemaKeys[reflowinfra.Cluster] = fmt.Sprintf("localcluster,dir=%v", dir)
+		if runFlags.Offline {
+			// Offline runs use only local infrastructure: a repository
+			// and assoc on the local disk, and no taskdb.
+			c.SchemaKeys[reflowinfra.Cluster] += ",offline=true"
+			c.SchemaKeys[reflowinfra.Repository] = fmt.Sprintf("filerepo,dir=%v", filepath.Join(dir, "cache", "objects"))
+			c.SchemaKeys[reflowinfra.Assoc] = fmt.Sprintf("fileassoc,dir=%v", filepath.Join(dir, "cache", "assoc"))
+			c.SchemaKeys[reflowinfra.Cache] = "readwrite"
+			c.SchemaKeys[reflowinfra.TaskDB] = "noptaskdb"
+		}
 		c.Config, err = c.Schema.Make(c.SchemaKeys)
 		c.must(err)
 	}
@@ -128,15 +176,25 @@ func (c *Cmd) runCommon(ctx context.Context, runFlags runtime.RunFlags, file str
 		Config:   c.Config,
 	}
 
-	runlog := golog.New(nil, "", golog.LstdFlags)
-	// Use a special logger which includes the log level for each log in the run file
-	runLogger := log.NewWithLevelPrefix(runlog)
+	var (
+		runlog    interface{ SetOutput(io.Writer) }
+		runLogger *log.Logger
+	)
+	if c.logFormatFlag == "json" {
+		out := log.NewJSONOutputter(nil)
+		runlog, runLogger = out, log.New(out, log.DebugLevel)
+	} else {
+		out := golog.New(nil, "", golog.LstdFlags)
+		// Use a special logger which includes the log level for each log in the run file
+		runlog, runLogger = out, log.NewWithLevelPrefix(out)
+	}
 	runLogger.Parent = c.Log
 
 	r, err := rr.NewRunner(runtime.RunnerParams{
 		RunConfig: runConfig,
 		Logger: runLogger,
 		Status: c.Status,
+		RunID:  resume,
 	})
 	c.must(err)
 
@@ -147,7 +205,12 @@ func (c *Cmd) runCommon(ctx context.Context, runFlags runtime.RunFlags, file str
 	var (
 		logfile, dotfile *os.File
 	)
-	if logfile, err = os.Create(base + ".runlog"); err != nil {
+	// A resumed run appends to the log of the interrupted run.
+	logflags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if resume.IsValid() {
+		logflags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	if logfile, err = os.OpenFile(base+".runlog", logflags, 0666); err != nil {
 		c.Fatal(err)
 	}
 	runlog.SetOutput(logfile)
@@ -177,22 +240,36 @@ func (c *Cmd) runCommon(ctx context.Context, runFlags runtime.RunFlags, file str
 	runLogger.Printf("reflow version: %s", c.version())
 
 	var result runner.State
+	started := time.Now()
 	result, err = r.Go(ctx)
 	if err != nil {
 		c.Errorln(err)
+		c.printHints(err)
+		if report != "" {
+			rep := newRunReport(runner.State{ID: r.GetRunID(), Program: file, Args: args}, started, err)
+			rep.ExitCode, rep.ExitClass = 1, exitError
+			c.report(report, rep)
+		}
 		c.Exit(1)
 	}
+	var runErr error
 	if result.Err != nil {
-		if errors.Is(errors.Eval, result.Err) {
-			// Error that occurred during evaluation. Probably not recoverable.
-			// TODO(marius): if this was caused by an underyling exit (from a tool)
-			// then propagate this here.
-			c.Exit(11)
-		}
-		if errors.Restartable(result.Err) {
-			c.Exit(10)
-		}
-		c.Exit(1)
+		runErr = result.Err
+		c.printHints(runErr)
+	}
+	if report != "" {
+		c.report(report, newRunReport(result, started, runErr))
+	}
+	if code, _ := exitStatus(runErr); code != 0 {
+		c.Exit(code)
+	}
+}
+
+// report writes the run report rep to path. Failures are logged:
+// they do not change the run's exit code.
+func (c *Cmd) report(path string, rep runReport) {
+	if err := writeReport(path, rep); err != nil {
+		c.Log.Errorf("write report %s: %v", path, err)
 	}
 }
 
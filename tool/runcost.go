@@ -0,0 +1,222 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package tool
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/grailbio/reflow/taskdb"
+)
+
+func (c *Cmd) runCost(ctx context.Context, args ...string) {
+	var (
+		flags         = flag.NewFlagSet("cost", flag.ExitOnError)
+		formatFlag    = flags.String("format", "text", "output format: text, json, or csv")
+		exactCostFlag = flags.Bool("exact_cost", true, "use the spot instance data feed to compute exact costs (if available)")
+		tasksFlag     = flags.Bool("tasks", false, "(text format only) also show the cost of each task")
+		help          = `Cost reports the cost of a run, attributed to its tasks.
+
+The cost of each task (exec attempt) is the cost of the instance on
+which it ran for the task's duration, scaled by the task's share of
+the instance's resources. Task costs are aggregated by exec identifier
+and in total for the run. Instance costs are taken from the spot
+instance data feed where available (see -exact_cost), or else bounded
+above by on-demand prices.
+
+The text format shows the total cost and the cost of each identifier
+(and, with -tasks, of each task). The json and csv formats always
+include per-task, per-identifier, and total costs; in csv, the "kind"
+column is one of "task", "ident", or "total".
+` + costHelp
+	)
+	c.Parse(flags, args, help, "cost [-format text|json|csv] [-exact_cost] [-tasks] runid")
+	if flags.NArg() != 1 {
+		flags.Usage()
+	}
+	switch *formatFlag {
+	case "text", "json", "csv":
+	default:
+		c.Fatalf("invalid format %q: must be one of text, json, csv", *formatFlag)
+	}
+	n, err := parseName(flags.Arg(0))
+	if err != nil {
+		c.Fatal(err)
+	}
+	if n.Kind != idName {
+		c.Fatalf("%s: not a run id", flags.Arg(0))
+	}
+	var tdb taskdb.TaskDB
+	if err = c.Config.Instance(&tdb); err != nil {
+		c.Fatalf("taskdb: %v", err)
+	}
+	if tdb == nil {
+		c.Fatal("no taskdb configured")
+	}
+	runs, err := tdb.Runs(ctx, taskdb.RunQuery{ID: taskdb.RunID(n.ID)})
+	if err != nil {
+		c.Fatalf("runs: %v", err)
+	}
+	if len(runs) == 0 {
+		c.Fatalf("run %s not found", flags.Arg(0))
+	}
+	run := runs[0]
+	tasks, err := tdb.Tasks(ctx, taskdb.TaskQuery{RunID: run.ID, WithAlloc: true})
+	if err != nil {
+		c.Fatalf("tasks: %v", err)
+	}
+	st, et := run.StartEnd()
+	cc := c.costComputer(ctx, *exactCostFlag, st, et)
+	report := newCostReport(run, tasks, func(t taskdb.Task) Cost { return taskCost(t, cc) })
+	switch *formatFlag {
+	case "json":
+		err = report.writeJSON(c.Stdout)
+	case "csv":
+		err = report.writeCSV(c.Stdout)
+	default:
+		var tw tabwriter.Writer
+		tw.Init(c.Stdout, 4, 4, 1, ' ', 0)
+		report.writeText(&tw, *tasksFlag)
+		err = tw.Flush()
+	}
+	if err != nil {
+		c.Fatal(err)
+	}
+}
+
+// taskCostEntry is the cost of a single task in a cost report.
+type taskCostEntry struct {
+	TaskID  string    `json:"task_id"`
+	Ident   string    `json:"ident"`
+	Attempt int       `json:"attempt"`
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	CostUSD float64   `json:"cost_usd"`
+	Exact   bool      `json:"exact"`
+	cost    Cost
+}
+
+// identCostEntry is the aggregate cost of the tasks with a given ident.
+type identCostEntry struct {
+	Ident   string  `json:"ident"`
+	Tasks   int     `json:"tasks"`
+	CostUSD float64 `json:"cost_usd"`
+	Exact   bool    `json:"exact"`
+	cost    Cost
+}
+
+// costReport attributes the cost of a run to its tasks and idents.
+type costReport struct {
+	RunID   string           `json:"run_id"`
+	User    string           `json:"user"`
+	Start   time.Time        `json:"start"`
+	End     time.Time        `json:"end"`
+	CostUSD float64          `json:"cost_usd"`
+	Exact   bool             `json:"exact"`
+	Idents  []identCostEntry `json:"idents"`
+	Tasks   []taskCostEntry  `json:"tasks"`
+	cost    Cost
+}
+
+// newCostReport returns a cost report for the given run and its
+// tasks, where the cost of each task is computed by taskCost.
+// Idents are ordered by decreasing cost, and tasks by start time.
+func newCostReport(run taskdb.Run, tasks []taskdb.Task, taskCost func(taskdb.Task) Cost) costReport {
+	r := costReport{RunID: run.ID.ID(), User: run.User}
+	r.Start, r.End = run.StartEnd()
+	idents := make(map[string]*identCostEntry)
+	for _, task := range tasks {
+		if !task.ID.IsValid() {
+			continue
+		}
+		cost := taskCost(task)
+		st, et := task.StartEnd()
+		r.Tasks = append(r.Tasks, taskCostEntry{
+			TaskID:  task.ID.ID(),
+			Ident:   task.Ident,
+			Attempt: task.Attempt,
+			Start:   st,
+			End:     et,
+			CostUSD: cost.Value(),
+			Exact:   cost.Exact(),
+			cost:    cost,
+		})
+		e := idents[task.Ident]
+		if e == nil {
+			e = &identCostEntry{Ident: task.Ident}
+			idents[task.Ident] = e
+		}
+		e.Tasks++
+		e.cost.Add(cost)
+		r.cost.Add(cost)
+	}
+	for _, e := range idents {
+		e.CostUSD, e.Exact = e.cost.Value(), e.cost.Exact()
+		r.Idents = append(r.Idents, *e)
+	}
+	r.CostUSD, r.Exact = r.cost.Value(), r.cost.Exact()
+	sort.Slice(r.Idents, func(i, j int) bool {
+		if r.Idents[i].CostUSD == r.Idents[j].CostUSD {
+			return r.Idents[i].Ident < r.Idents[j].Ident
+		}
+		return r.Idents[i].CostUSD > r.Idents[j].CostUSD
+	})
+	sort.SliceStable(r.Tasks, func(i, j int) bool {
+		return r.Tasks[i].Start.Before(r.Tasks[j].Start)
+	})
+	return r
+}
+
+func (r costReport) writeJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+func (r costReport) writeCSV(w io.Writer) error {
+	var (
+		cw      = csv.NewWriter(w)
+		usd     = func(v float64) string { return strconv.FormatFloat(v, 'f', 6, 64) }
+		rfc3339 = func(t time.Time) string {
+			if t.IsZero() {
+				return ""
+			}
+			return t.UTC().Format(time.RFC3339)
+		}
+	)
+	records := [][]string{{"kind", "run_id", "task_id", "ident", "attempt", "tasks", "start", "end", "cost_usd", "exact"}}
+	for _, t := range r.Tasks {
+		records = append(records, []string{"task", r.RunID, t.TaskID, t.Ident, strconv.Itoa(t.Attempt), "1", rfc3339(t.Start), rfc3339(t.End), usd(t.CostUSD), strconv.FormatBool(t.Exact)})
+	}
+	for _, e := range r.Idents {
+		records = append(records, []string{"ident", r.RunID, "", e.Ident, "", strconv.Itoa(e.Tasks), "", "", usd(e.CostUSD), strconv.FormatBool(e.Exact)})
+	}
+	records = append(records, []string{"total", r.RunID, "", "", "", strconv.Itoa(len(r.Tasks)), rfc3339(r.Start), rfc3339(r.End), usd(r.CostUSD), strconv.FormatBool(r.Exact)})
+	return cw.WriteAll(records)
+}
+
+func (r costReport) writeText(w io.Writer, tasks bool) {
+	fmt.Fprintf(w, "run %s (%s): %d tasks, total cost %s\n\n", r.RunID, r.User, len(r.Tasks), r.cost)
+	fmt.Fprintln(w, "ident\ttasks\tcost")
+	for _, e := range r.Idents {
+		fmt.Fprintf(w, "%s\t%d\t%s\n", e.Ident, e.Tasks, e.cost)
+	}
+	if !tasks {
+		return
+	}
+	fmt.Fprintln(w, "\ntaskid\tident\tattempt\tstart\tend\tcost")
+	for _, t := range r.Tasks {
+		st, et := formatStartEnd(taskdb.TimeFields{Start: t.Start, End: t.End})
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%s\n", t.TaskID, t.Ident, t.Attempt, st, et, t.cost)
+	}
+}
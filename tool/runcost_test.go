@@ -0,0 +1,94 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package tool
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/grailbio/reflow"
+	"github.com/grailbio/reflow/taskdb"
+)
+
+func TestCostReport(t *testing.T) {
+	start := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	run := taskdb.Run{ID: taskdb.RunID(reflow.Digester.FromString("run")), User: "test"}
+	run.Start, run.End = start, start.Add(time.Hour)
+	var tasks []taskdb.Task
+	for i, ident := range []string{"align", "align", "sort", "align"} {
+		task := taskdb.Task{
+			ID:      taskdb.TaskID(reflow.Digester.FromString(fmt.Sprint(i))),
+			RunID:   run.ID,
+			Ident:   ident,
+			Attempt: i,
+		}
+		task.Start, task.End = start.Add(time.Duration(3-i)*time.Minute), start.Add(10*time.Minute)
+		tasks = append(tasks, task)
+	}
+	costs := map[taskdb.TaskID]Cost{
+		tasks[0].ID: NewCostExact(1),
+		tasks[1].ID: NewCostExact(0.5),
+		tasks[2].ID: NewCostUB(2),
+		// tasks[3] has an unknown cost.
+	}
+	r := newCostReport(run, tasks, func(t taskdb.Task) Cost { return costs[t.ID] })
+	if got, want := r.cost, NewCostUB(3.5); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := len(r.Idents), 2; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	// Idents are ordered by decreasing cost.
+	if got, want := r.Idents[0], (identCostEntry{Ident: "sort", Tasks: 1, CostUSD: 2, cost: NewCostUB(2)}); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if got, want := r.Idents[1], (identCostEntry{Ident: "align", Tasks: 3, CostUSD: 1.5, Exact: true, cost: NewCostExact(1.5)}); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	// Tasks are ordered by start time.
+	if got, want := len(r.Tasks), 4; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range r.Tasks {
+		if got, want := r.Tasks[i].TaskID, tasks[3-i].ID.ID(); got != want {
+			t.Errorf("task %d: got %v, want %v", i, got, want)
+		}
+	}
+
+	var b bytes.Buffer
+	if err := r.writeCSV(&b); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&b).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A header, four tasks, two idents and the total.
+	if got, want := len(records), 8; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got, want := records[7], []string{"total", run.ID.ID(), "", "", "", "4", "2021-06-01T00:00:00Z", "2021-06-01T01:00:00Z", "3.500000", "false"}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	b.Reset()
+	if err := r.writeJSON(&b); err != nil {
+		t.Fatal(err)
+	}
+	var decoded costReport
+	if err := json.Unmarshal(b.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := decoded.CostUSD, 3.5; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := decoded.Tasks[0].Ident, "align"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
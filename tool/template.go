@@ -0,0 +1,90 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package tool
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	infra2 "github.com/grailbio/reflow/infra"
+)
+
+// expandRunTemplate returns the arguments to run as defined by the
+// run template with the given name, given the run flags and program
+// arguments supplied on the command line. The template's labels are
+// added to the command's configuration. Flags, params and labels
+// supplied on the command line override those of the template.
+func (c *Cmd) expandRunTemplate(name string, flags, args []string) []string {
+	var templates *infra2.RunTemplates
+	if err := c.Config.Instance(&templates); err != nil {
+		c.Fatalf("run template %s: %v", name, err)
+	}
+	tmpl, err := templates.Resolve(name)
+	if err != nil {
+		c.Fatalf("%v (defined templates: %s)", err, strings.Join(templates.Names(), ", "))
+	}
+	if len(tmpl.Labels) > 0 {
+		key, _ := c.SchemaKeys[infra2.Labels].(string)
+		if key, err = templateLabelsKey(key, tmpl.Labels); err != nil {
+			c.Fatalf("run template %s: %v", name, err)
+		}
+		c.SchemaKeys[infra2.Labels] = key
+		c.Config, err = c.Schema.Make(c.SchemaKeys)
+		c.must(err)
+	}
+	args = templateArgs(tmpl, flags, args)
+	c.Log.Debugf("run template %s: run %s", name, strings.Join(args, " "))
+	return args
+}
+
+// templateArgs returns the arguments to run for the given (resolved)
+// template, followed by the run flags and program arguments supplied
+// on the command line. Since later flags override earlier ones, the
+// latter take precedence over the template's.
+func templateArgs(tmpl infra2.RunTemplate, flags, args []string) []string {
+	var expanded []string
+	for _, k := range sortedKeys(tmpl.Flags) {
+		expanded = append(expanded, fmt.Sprintf("-%s=%s", k, tmpl.Flags[k]))
+	}
+	expanded = append(expanded, flags...)
+	expanded = append(expanded, tmpl.Program)
+	for _, k := range sortedKeys(tmpl.Params) {
+		expanded = append(expanded, fmt.Sprintf("-%s=%s", k, tmpl.Params[k]))
+	}
+	return append(expanded, args...)
+}
+
+// templateLabelsKey returns the labels schema key which adds the
+// given labels to those configured by key. Labels configured by key
+// take precedence.
+func templateLabelsKey(key string, labels map[string]string) (string, error) {
+	const prefix = "kv,labels="
+	var configured string
+	switch {
+	case key == "" || key == "kv":
+	case strings.HasPrefix(key, prefix):
+		configured = strings.TrimPrefix(key, prefix)
+	default:
+		return "", fmt.Errorf("cannot add labels to labels provider %q", key)
+	}
+	kvs := make([]string, 0, len(labels)+1)
+	for _, k := range sortedKeys(labels) {
+		kvs = append(kvs, k+"="+labels[k])
+	}
+	if configured != "" {
+		kvs = append(kvs, configured)
+	}
+	return prefix + strings.Join(kvs, ";"), nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,47 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package tool
+
+import (
+	"reflect"
+	"testing"
+
+	infra2 "github.com/grailbio/reflow/infra"
+)
+
+func TestTemplateArgs(t *testing.T) {
+	tmpl := infra2.RunTemplate{
+		Program: "wgs.rf",
+		Params:  map[string]string{"ref": "hg38", "mem": "32GiB"},
+		Flags:   map[string]string{"sched": "true", "maxcost": "50"},
+	}
+	got := templateArgs(tmpl, []string{"-maxcost", "100"}, []string{"-sample=NA12878"})
+	want := []string{"-maxcost=50", "-sched=true", "-maxcost", "100", "wgs.rf", "-mem=32GiB", "-ref=hg38", "-sample=NA12878"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestTemplateLabelsKey(t *testing.T) {
+	labels := map[string]string{"team": "genomics", "tier": "prod"}
+	for _, tt := range []struct {
+		key, want string
+	}{
+		{"", "kv,labels=team=genomics;tier=prod"},
+		{"kv", "kv,labels=team=genomics;tier=prod"},
+		{"kv,labels=tier=dev", "kv,labels=team=genomics;tier=prod;tier=dev"},
+	} {
+		got, err := templateLabelsKey(tt.key, labels)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("%q: got %v, want %v", tt.key, got, tt.want)
+		}
+	}
+	if _, err := templateLabelsKey("otherlabels", labels); err == nil {
+		t.Error("expected error")
+	}
+}
@@ -0,0 +1,268 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package tool
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/grailbio/base/data"
+	"github.com/grailbio/reflow/metrics/monitoring"
+	"github.com/grailbio/reflow/pool"
+	"github.com/grailbio/reflow/runtime"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// clearScreen is the ANSI escape sequence which moves the cursor to
+	// the top left corner and clears the terminal.
+	clearScreen = "\033[H\033[2J"
+	// nodeMetricsURLFmt is the format of the URL from which a reflowlet
+	// serves (proxies) the node_exporter metrics of its instance.
+	nodeMetricsURLFmt = "https://%s/v1/node/metrics"
+)
+
+// nodeStats are the node-level metrics of an alloc's instance, as
+// reported by its node_exporter.
+type nodeStats struct {
+	Load1             float64
+	MemTotal, MemFree float64
+}
+
+// topAlloc is the state of an alloc, and its execs, as shown by top.
+type topAlloc struct {
+	pool.AllocInspect
+	// Execs are the alloc's running execs.
+	Execs []execInfo
+	// Node is the alloc's node metrics, if available.
+	Node *nodeStats
+}
+
+// usage returns the total memory, cpu and disk used by the alloc's execs.
+func (a topAlloc) usage() (mem, cpu, disk float64) {
+	for _, info := range a.Execs {
+		m, c, d := execUsage(info.ExecInspect)
+		mem, cpu, disk = mem+m, cpu+c, disk+d
+	}
+	return
+}
+
+func (c *Cmd) top(ctx context.Context, args ...string) {
+	var (
+		flags        = flag.NewFlagSet("top", flag.ExitOnError)
+		intervalFlag = flags.Duration("interval", 5*time.Second, "refresh interval")
+		nFlag        = flags.Int("n", 0, "number of refreshes after which to exit (0 means run until interrupted)")
+		clusterFlag  = flags.Bool("k", false, "show allocs of all users of the cluster (admin only)")
+		nodeFlag     = flags.Bool("node", true, "show node metrics (load and memory) of each alloc's instance, if available")
+		help         = `Top shows a continuously refreshing view of the utilization of the
+cluster's allocs and of the execs running on them.
+
+For each alloc, top shows its reserved resources and the total memory,
+cpu and disk used by its running execs. If the cluster's reflowlets run
+node_exporter (see nodeexportermetricsport in ec2cluster), top also shows
+the 1-minute load average and the free memory of each alloc's instance.
+Below the allocs, all running execs are listed with their current
+memory, cpu and disk usage and their processes. Both allocs and execs
+are ordered by decreasing cpu usage.
+
+The columns displayed for allocs are:
+
+	alloc     the alloc's id
+	execs     the number of running execs
+	mem       memory used by execs / reserved by the alloc
+	cpu       cores used by execs / reserved by the alloc
+	disk      disk used by execs
+	load      the instance's 1-minute load average
+	free      the instance's free memory / total memory
+
+and for execs:
+
+	exec      the exec's id
+	alloc     the id of the exec's alloc
+	ident     the exec's identifier
+	runtime   the exec's running time
+	mem       memory used / reserved by the exec
+	cpu       cores used / reserved by the exec
+	disk      disk used by the exec
+	procs     the processes running in the exec`
+	)
+	c.Parse(flags, args, help, "top [-interval duration] [-n refreshes] [-k] [-node=false]")
+	if flags.NArg() != 0 {
+		flags.Usage()
+	}
+	if *intervalFlag <= 0 {
+		c.Fatalf("invalid interval %s", *intervalFlag)
+	}
+	cluster := c.CurrentPool(ctx)
+	if *clusterFlag {
+		c.requireAdmin()
+		cluster = c.AllUsersPool(ctx)
+	}
+	var httpClient *http.Client
+	if *nodeFlag {
+		var err error
+		if httpClient, err = runtime.HttpClient(c.Config); err != nil {
+			c.Fatal(err)
+		}
+		httpClient.Timeout = defaultHTTPTimeout
+	}
+	ticker := time.NewTicker(*intervalFlag)
+	defer ticker.Stop()
+	for i := 0; *nFlag == 0 || i < *nFlag; i++ {
+		allocs := c.topAllocs(ctx, cluster, httpClient, nodeMetricsURLFmt)
+		var tw tabwriter.Writer
+		tw.Init(c.Stdout, 4, 4, 1, ' ', 0)
+		if *nFlag != 1 {
+			fmt.Fprint(c.Stdout, clearScreen)
+		}
+		writeTop(&tw, time.Now(), allocs)
+		c.must(tw.Flush())
+		if *nFlag != 0 && i == *nFlag-1 {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// topAllocs retrieves the state of the allocs in the given pool and
+// their running execs. If client is non-nil, it is used to retrieve
+// each alloc's node metrics from the URL given by the format urlFmt,
+// applied to the host (and port) of the alloc's reflowlet.
+func (c *Cmd) topAllocs(ctx context.Context, p pool.Pool, client *http.Client, urlFmt string) []topAlloc {
+	allocsCtx, allocsCancel := context.WithTimeout(ctx, 5*time.Second)
+	allocs := pool.Allocs(allocsCtx, p, c.Log)
+	allocsCancel()
+	var (
+		g, gctx = errgroup.WithContext(ctx)
+		tops    = make([]topAlloc, len(allocs))
+	)
+	for i := range allocs {
+		i, alloc := i, allocs[i]
+		g.Go(func() error {
+			ctx, cancel := context.WithTimeout(gctx, 5*time.Second)
+			defer cancel()
+			execs, err := alloc.Execs(ctx)
+			if err != nil {
+				c.Log.Debugf("execs %s: %v", alloc.ID(), err)
+				return nil
+			}
+			for _, info := range c.execInfos(ctx, execs) {
+				if info.State == "running" {
+					tops[i].Execs = append(tops[i].Execs, info)
+				}
+			}
+			return nil
+		})
+		g.Go(func() error {
+			ctx, cancel := context.WithTimeout(gctx, 5*time.Second)
+			defer cancel()
+			var err error
+			if tops[i].AllocInspect, err = alloc.Inspect(ctx); err != nil {
+				c.Log.Debugf("inspect %s: %v", alloc.ID(), err)
+			}
+			return nil
+		})
+		if client == nil {
+			continue
+		}
+		g.Go(func() error {
+			n, err := parseName(alloc.ID())
+			if err != nil || n.HostAndPort == "" {
+				return nil
+			}
+			ctx, cancel := context.WithTimeout(gctx, 5*time.Second)
+			defer cancel()
+			mfs, err := monitoring.FetchMetrics(ctx, client, fmt.Sprintf(urlFmt, n.HostAndPort))
+			if err != nil {
+				c.Log.Debugf("node metrics %s: %v", alloc.ID(), err)
+				return nil
+			}
+			var stats nodeStats
+			stats.Load1, _ = monitoring.Value(mfs, "node_load1")
+			stats.MemTotal, _ = monitoring.Value(mfs, "node_memory_MemTotal_bytes")
+			stats.MemFree, _ = monitoring.Value(mfs, "node_memory_MemAvailable_bytes")
+			tops[i].Node = &stats
+			return nil
+		})
+	}
+	_ = g.Wait() // errors are logged
+	valid := tops[:0]
+	for _, top := range tops {
+		// Skip allocs which could not be inspected (e.g., because it timed out).
+		if top.ID != "" {
+			valid = append(valid, top)
+		}
+	}
+	return valid
+}
+
+// writeTop writes the top view of the given allocs to w.
+func writeTop(w io.Writer, now time.Time, allocs []topAlloc) {
+	type topExec struct {
+		execInfo
+		mem, cpu, disk float64
+	}
+	var (
+		execs          []topExec
+		mem, cpu, disk float64
+	)
+	for _, a := range allocs {
+		for _, info := range a.Execs {
+			e := topExec{execInfo: info}
+			e.Alloc = a.AllocInspect
+			e.mem, e.cpu, e.disk = execUsage(info.ExecInspect)
+			mem, cpu, disk = mem+e.mem, cpu+e.cpu, disk+e.disk
+			execs = append(execs, e)
+		}
+	}
+	sort.SliceStable(allocs, func(i, j int) bool {
+		_, ci, _ := allocs[i].usage()
+		_, cj, _ := allocs[j].usage()
+		if ci == cj {
+			return allocs[i].ID < allocs[j].ID
+		}
+		return ci > cj
+	})
+	sort.SliceStable(execs, func(i, j int) bool {
+		return execs[i].cpu > execs[j].cpu
+	})
+	fmt.Fprintf(w, "reflow top - %s: %d allocs, %d execs, mem %s, cpu %.1f, disk %s\n\n",
+		now.Local().Format(time.Kitchen), len(allocs), len(execs), data.Size(mem), cpu, data.Size(disk))
+	fmt.Fprintln(w, "alloc\texecs\tmem\tcpu\tdisk\tload\tfree")
+	for _, a := range allocs {
+		mem, cpu, disk := a.usage()
+		load, free := "-", "-"
+		if a.Node != nil {
+			load = fmt.Sprintf("%.2f", a.Node.Load1)
+			free = fmt.Sprintf("%s/%s", data.Size(a.Node.MemFree), data.Size(a.Node.MemTotal))
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s/%s\t%.1f/%.0f\t%s\t%s\t%s\n",
+			a.ID, len(a.Execs),
+			data.Size(mem), data.Size(a.Resources["mem"]),
+			cpu, a.Resources["cpu"],
+			data.Size(disk), load, free)
+	}
+	if len(execs) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "\nexec\talloc\tident\truntime\tmem\tcpu\tdisk\tprocs")
+	for _, e := range execs {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s/%s\t%.1f/%.0f\t%s\t%s\n",
+			getShort(e.ID), e.Alloc.ID, e.Config.Ident, now.Sub(e.Created).Truncate(time.Second),
+			data.Size(e.mem), data.Size(e.Config.Resources["mem"]),
+			e.cpu, e.Config.Resources["cpu"],
+			data.Size(e.disk), execProcs(e.ExecInspect))
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package tool
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"text/tabwriter"
+	"time"
+
+	"github.com/grailbio/reflow"
+	"github.com/grailbio/reflow/pool"
+)
+
+func TestWriteTop(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	exec := func(id, ident string, cpu float64) execInfo {
+		var info execInfo
+		info.ID = reflow.Digester.FromString(id)
+		info.State = "running"
+		info.Created = now.Add(-time.Hour)
+		info.Config.Type = "exec"
+		info.Config.Ident = ident
+		info.Config.Resources = reflow.Resources{"cpu": 4, "mem": 8 << 30}
+		info.Gauges = reflow.Gauges{"cpu": cpu, "mem": 1 << 30}
+		info.Commands = []string{"/bin/bash -c x", "/usr/bin/bwa mem", "/usr/bin/bwa mem"}
+		return info
+	}
+	allocs := []topAlloc{
+		{
+			AllocInspect: pool.AllocInspect{ID: "host1:9000/a1", Resources: reflow.Resources{"cpu": 16, "mem": 64 << 30}},
+			Execs:        []execInfo{exec("x1", "sort", 1)},
+		},
+		{
+			AllocInspect: pool.AllocInspect{ID: "host2:9000/a2", Resources: reflow.Resources{"cpu": 16, "mem": 64 << 30}},
+			Execs:        []execInfo{exec("x2", "align", 3.5), exec("x3", "align", 2)},
+			Node:         &nodeStats{Load1: 5.5, MemTotal: 64 << 30, MemFree: 32 << 30},
+		},
+	}
+	var (
+		b  bytes.Buffer
+		tw tabwriter.Writer
+	)
+	tw.Init(&b, 4, 4, 1, ' ', 0)
+	writeTop(&tw, now, allocs)
+	if err := tw.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if got, want := len(lines), 9; got != want {
+		t.Fatalf("got %d lines, want %d:\n%s", got, want, b.String())
+	}
+	if got, want := lines[0], "reflow top"; !strings.HasPrefix(got, want) {
+		t.Errorf("got %q, want prefix %q", got, want)
+	}
+	for _, want := range []string{"2 allocs, 3 execs", "cpu 6.5"} {
+		if got := lines[0]; !strings.Contains(got, want) {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	}
+	// Allocs and execs are ordered by decreasing cpu usage.
+	for i, want := range []string{"host2:9000/a2", "host1:9000/a1"} {
+		if got := strings.Fields(lines[3+i])[0]; got != want {
+			t.Errorf("alloc %d: got %v, want %v", i, got, want)
+		}
+	}
+	fields := strings.Fields(lines[3])
+	if got, want := fields[1], "2"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := fields[3], "5.5/16"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := fields[5], "5.50"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := strings.Fields(lines[4])[5], "-"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	for i, want := range []string{"align", "align", "sort"} {
+		fields := strings.Fields(lines[6+i])
+		if got := fields[2]; got != want {
+			t.Errorf("exec %d: got %v, want %v", i, got, want)
+		}
+		if got, want := fields[3], "1h0m0s"; got != want {
+			t.Errorf("exec %d: got %v, want %v", i, got, want)
+		}
+		if got, want := fields[len(fields)-1], "bwa(2)"; got != want {
+			t.Errorf("exec %d: got %v, want %v", i, got, want)
+		}
+	}
+}
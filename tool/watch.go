@@ -0,0 +1,207 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package tool
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/grailbio/reflow/taskdb"
+)
+
+// watchStaleAfter is the age of a run's keepalive after which the
+// run is considered dead: its driver no longer keeps it alive.
+const watchStaleAfter = 5 * time.Minute
+
+// watchIdent summarizes the tasks of a run with a given ident.
+type watchIdent struct {
+	Ident                 string
+	Running, Done, Failed int
+	// Progress is the mean progress, in percent, of the running tasks
+	// which report it.
+	Progress float64
+}
+
+// summarizeTasks summarizes the given tasks of a run by ident, as of
+// the time now.
+func summarizeTasks(tasks []taskdb.Task, now time.Time) []watchIdent {
+	var (
+		idents   = make(map[string]*watchIdent)
+		progress = make(map[string]int)
+	)
+	for _, t := range tasks {
+		ident := t.Ident
+		if ident == "" {
+			ident = "(none)"
+		}
+		id := idents[ident]
+		if id == nil {
+			id = &watchIdent{Ident: ident}
+			idents[ident] = id
+		}
+		switch {
+		case t.Err.Err != nil:
+			id.Failed++
+		case !t.End.IsZero():
+			id.Done++
+		case now.Sub(t.Keepalive) < watchStaleAfter:
+			id.Running++
+			if t.Progress > 0 {
+				id.Progress += t.Progress
+				progress[ident]++
+			}
+		}
+	}
+	summary := make([]watchIdent, 0, len(idents))
+	for ident, id := range idents {
+		if n := progress[ident]; n > 0 {
+			id.Progress /= float64(n)
+		}
+		summary = append(summary, *id)
+	}
+	sort.Slice(summary, func(i, j int) bool { return summary[i].Ident < summary[j].Ident })
+	return summary
+}
+
+// writeWatch writes the view of the given run and its tasks, as of
+// the time now, to w.
+func writeWatch(w io.Writer, run taskdb.Run, tasks []taskdb.Task, now time.Time) {
+	state := "running"
+	switch {
+	case !run.End.IsZero():
+		state = fmt.Sprintf("ended %s", run.End.Local().Format(time.RFC3339))
+	case now.Sub(run.Keepalive) >= watchStaleAfter:
+		state = fmt.Sprintf("dead (last seen %s)", run.Keepalive.Local().Format(time.RFC3339))
+	}
+	labels := make([]string, 0, len(run.Labels))
+	for k, v := range run.Labels {
+		labels = append(labels, k+"="+v)
+	}
+	sort.Strings(labels)
+	fmt.Fprintf(w, "run %s (%s) by %s, started %s: %s\n", run.ID.IDShort(), strings.Join(labels, ","), run.User,
+		run.Start.Local().Format(time.RFC3339), state)
+	fmt.Fprintln(w)
+	var tw tabwriter.Writer
+	tw.Init(w, 4, 4, 1, ' ', 0)
+	fmt.Fprintln(&tw, "ident\trunning\tdone\tfailed\tprogress")
+	for _, id := range summarizeTasks(tasks, now) {
+		progress := "-"
+		if id.Progress > 0 {
+			progress = fmt.Sprintf("%.0f%%", id.Progress)
+		}
+		fmt.Fprintf(&tw, "%s\t%d\t%d\t%d\t%s\n", id.Ident, id.Running, id.Done, id.Failed, progress)
+	}
+	tw.Flush()
+}
+
+func (c *Cmd) watch(ctx context.Context, args ...string) {
+	var (
+		flags        = flag.NewFlagSet("watch", flag.ExitOnError)
+		driverFlag   = flags.String("driver", "", "address (host:port) of the run's driver diagnostic HTTP server (reflow -http), from which to show its status")
+		intervalFlag = flags.Duration("interval", 10*time.Second, "refresh interval")
+		nFlag        = flags.Int("n", 0, "number of refreshes after which to exit (0 means run until the run ends, or until interrupted)")
+		help         = `Watch shows a continuously refreshing, read-only view of a run which
+is executing elsewhere, e.g., one started by a teammate. It requires
+only access to the run's taskdb (and, optionally, to its driver).
+
+For each ident of the run's tasks, watch shows the number of tasks
+which are running, done and failed, as well as the mean progress of
+the running tasks which report it. If the run's driver serves its
+diagnostic HTTP server (i.e., it was invoked as reflow -http addr),
+watch also shows the driver's status, as reported on its terminal,
+when given the server's address with -driver.
+
+Watch exits once the run has ended, or once its driver no longer
+keeps it alive.`
+	)
+	c.Parse(flags, args, help, "watch [-driver host:port] [-interval d] [-n count] runid")
+	if flags.NArg() != 1 {
+		flags.Usage()
+	}
+	n, err := parseName(flags.Arg(0))
+	if err != nil {
+		c.Fatal(err)
+	}
+	if n.Kind != idName {
+		c.Fatalf("%s: not a run id", flags.Arg(0))
+	}
+	var tdb taskdb.TaskDB
+	if err = c.Config.Instance(&tdb); err != nil {
+		c.Fatalf("taskdb: %v", err)
+	}
+	if tdb == nil {
+		c.Fatal("no taskdb configured")
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	ticker := time.NewTicker(*intervalFlag)
+	defer ticker.Stop()
+	for i := 0; ; i++ {
+		runs, err := tdb.Runs(ctx, taskdb.RunQuery{ID: taskdb.RunID(n.ID)})
+		if err != nil {
+			c.Fatalf("runs: %v", err)
+		}
+		if len(runs) == 0 {
+			c.Fatalf("run %s not found", flags.Arg(0))
+		}
+		run := runs[0]
+		tasks, err := tdb.Tasks(ctx, taskdb.TaskQuery{RunID: run.ID})
+		if err != nil {
+			c.Fatalf("tasks: %v", err)
+		}
+		now := time.Now()
+		fmt.Fprint(c.Stdout, clearScreen)
+		writeWatch(c.Stdout, run, tasks, now)
+		if *driverFlag != "" {
+			fmt.Fprintln(c.Stdout)
+			status, err := driverStatus(ctx, client, *driverFlag)
+			if err != nil {
+				fmt.Fprintf(c.Stdout, "driver status unavailable: %v\n", err)
+			} else {
+				fmt.Fprint(c.Stdout, status)
+			}
+		}
+		if !run.End.IsZero() || now.Sub(run.Keepalive) >= watchStaleAfter {
+			return
+		}
+		if *nFlag > 0 && i+1 >= *nFlag {
+			return
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// driverStatus returns the status reported by the driver whose
+// diagnostic HTTP server is at the given address.
+func driverStatus(ctx context.Context, client *http.Client, addr string) (string, error) {
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+	req, err := http.NewRequest("GET", strings.TrimSuffix(addr, "/")+"/debug/status", nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s", resp.Status)
+	}
+	b, err := ioutil.ReadAll(resp.Body)
+	return string(b), err
+}
@@ -0,0 +1,62 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package tool
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grailbio/reflow/errors"
+	"github.com/grailbio/reflow/pool"
+	"github.com/grailbio/reflow/taskdb"
+)
+
+func TestWatch(t *testing.T) {
+	now := time.Now()
+	task := func(ident string, end bool, err error, keepalive time.Time, progress float64) taskdb.Task {
+		tk := taskdb.Task{Ident: ident, Progress: progress}
+		tk.Start, tk.Keepalive = now.Add(-time.Hour), keepalive
+		if end {
+			tk.End = keepalive
+		}
+		if err != nil {
+			tk.Err = *errors.Recover(err)
+		}
+		return tk
+	}
+	tasks := []taskdb.Task{
+		task("align", false, nil, now, 20),
+		task("align", false, nil, now, 40),
+		task("align", true, nil, now, 0),
+		task("align", false, nil, now.Add(-time.Hour), 0),
+		task("merge", true, errors.New("exit status 1"), now, 0),
+	}
+	want := []watchIdent{
+		{Ident: "align", Running: 2, Done: 1, Progress: 30},
+		{Ident: "merge", Failed: 1},
+	}
+	if got := summarizeTasks(tasks, now); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	run := taskdb.Run{ID: taskdb.NewRunID(), User: "alice", Labels: pool.Labels{"team": "genomics"}}
+	run.Start, run.Keepalive = now.Add(-time.Hour), now
+	var b bytes.Buffer
+	writeWatch(&b, run, tasks, now)
+	for _, want := range []string{"by alice", "team=genomics", ": running", "30%"} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("missing %q in:\n%s", want, b.String())
+		}
+	}
+	run.Keepalive = now.Add(-time.Hour)
+	b.Reset()
+	writeWatch(&b, run, tasks, now)
+	if !strings.Contains(b.String(), ": dead") {
+		t.Errorf("expected dead run in:\n%s", b.String())
+	}
+}
@@ -151,6 +151,9 @@ func (lt *LocalTracer) Emit(ctx context.Context, e trace.Event) (context.Context
 				"beginTime": e.Time.Format(time.RFC850),
 			},
 		}
+		if e.LogicalId != "" {
+			event.Args["logicalId"] = e.LogicalId
+		}
 		// store the StartEvent in the ctx so that we can update it on subsequent
 		// NoteEvents or complete it when the EndEvent is received
 		return context.WithValue(ctx, eventKey, event), nil
@@ -0,0 +1,473 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+// Package otlptrace implements a reflow tracer which exports spans
+// using the OpenTelemetry protocol (OTLP/HTTP, JSON encoding), so that
+// traces can be viewed in any OTLP-compatible backend, eg, Jaeger,
+// Grafana Tempo or Honeycomb.
+package otlptrace
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grailbio/infra"
+	"github.com/grailbio/reflow/log"
+	"github.com/grailbio/reflow/trace"
+)
+
+func init() {
+	infra.Register("otlp", new(Tracer))
+}
+
+const (
+	// defaultEndpoint is the default OTLP/HTTP traces endpoint of a
+	// locally running OpenTelemetry collector (or Jaeger) instance.
+	defaultEndpoint = "http://localhost:4318/v1/traces"
+	// defaultBatchSize is the number of finished spans after which
+	// they are exported, without waiting for Flush.
+	defaultBatchSize = 512
+	// traceparentHeader is the W3C trace context header.
+	traceparentHeader = "traceparent"
+	// scopeName is the instrumentation scope of exported spans.
+	scopeName = "github.com/grailbio/reflow"
+)
+
+// Tracer is a reflow tracer which exports spans to an OTLP/HTTP endpoint.
+// Spans are buffered and exported in batches in the background; Flush
+// exports any remaining spans synchronously.
+type Tracer struct {
+	// Endpoint is the OTLP/HTTP traces endpoint to which spans are exported.
+	Endpoint string
+	// Headers are additional HTTP headers (comma-separated key=value pairs)
+	// sent with each export request, eg, for authenticating to a backend.
+	Headers string
+	// ServiceName is the value of the service.name resource attribute.
+	ServiceName string
+	// BatchSize is the number of buffered spans which triggers an export.
+	BatchSize int
+
+	// Client is the HTTP client used to export spans.
+	Client *http.Client
+
+	mu    sync.Mutex
+	spans []*span
+	// exportMu serializes exports, so that spans are exported in order.
+	exportMu sync.Mutex
+}
+
+// Help implements infra.Provider.
+func (t *Tracer) Help() string {
+	return "configure a tracer to export traces using the OpenTelemetry protocol (OTLP/HTTP) to an endpoint (eg, Jaeger, Tempo, Honeycomb)"
+}
+
+// Flags implements infra.Provider.
+func (t *Tracer) Flags(flags *flag.FlagSet) {
+	flags.StringVar(&t.Endpoint, "endpoint", defaultEndpoint, "OTLP/HTTP endpoint to which traces are exported")
+	flags.StringVar(&t.Headers, "headers", "", "comma-separated key=value HTTP headers sent with each export request")
+	flags.StringVar(&t.ServiceName, "service", "reflow", "service name with which traces are exported")
+	flags.IntVar(&t.BatchSize, "batchsize", defaultBatchSize, "number of finished spans after which they are exported")
+}
+
+// Init implements infra.Provider.
+func (t *Tracer) Init() error {
+	if t.Endpoint == "" {
+		t.Endpoint = defaultEndpoint
+	}
+	if t.ServiceName == "" {
+		t.ServiceName = "reflow"
+	}
+	if t.BatchSize <= 0 {
+		t.BatchSize = defaultBatchSize
+	}
+	if _, err := parseHeaders(t.Headers); err != nil {
+		return err
+	}
+	if t.Client == nil {
+		t.Client = &http.Client{Timeout: 30 * time.Second}
+	}
+	return nil
+}
+
+// span is an in-progress (or finished) span.
+type span struct {
+	traceID [16]byte
+	spanID  [8]byte
+	parent  [8]byte
+	name    string
+	kind    trace.Kind
+	start   time.Time
+
+	mu    sync.Mutex
+	end   time.Time
+	attrs []attribute
+}
+
+func (s *span) setAttr(key string, value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.attrs {
+		if s.attrs[i].Key == key {
+			s.attrs[i].Value = anyValue(value)
+			return
+		}
+	}
+	s.attrs = append(s.attrs, attribute{key, anyValue(value)})
+}
+
+type key int
+
+const spanKey key = iota
+
+// spanContext returns the span (if any) stored in the given context.
+func spanContext(ctx context.Context) *span {
+	s, _ := ctx.Value(spanKey).(*span)
+	return s
+}
+
+// Emit emits a trace event and implements the trace.Tracer interface. This
+// should never be used directly, instead use trace.Start and trace.Note.
+func (t *Tracer) Emit(ctx context.Context, e trace.Event) (context.Context, error) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	switch e.Kind {
+	case trace.StartEvent:
+		s := &span{name: e.Name, kind: e.SpanKind, start: e.Time}
+		if parent := spanContext(ctx); parent != nil && e.SpanKind != trace.Run {
+			s.traceID, s.parent = parent.traceID, parent.spanID
+		} else {
+			randBytes(s.traceID[:])
+		}
+		randBytes(s.spanID[:])
+		s.attrs = []attribute{{"reflow.span.kind", anyValue(e.SpanKind.String())}}
+		if !e.Id.IsZero() {
+			s.attrs = append(s.attrs, attribute{"reflow.id", anyValue(e.Id.String())})
+		}
+		if e.LogicalId != "" {
+			s.attrs = append(s.attrs, attribute{"reflow.logical_id", anyValue(e.LogicalId)})
+		}
+		return context.WithValue(ctx, spanKey, s), nil
+	case trace.EndEvent:
+		s := spanContext(ctx)
+		if s == nil {
+			return nil, fmt.Errorf("no span found for %v", e.Id)
+		}
+		s.mu.Lock()
+		s.end = e.Time
+		s.mu.Unlock()
+		t.mu.Lock()
+		t.spans = append(t.spans, s)
+		full := len(t.spans) >= t.batchSize()
+		t.mu.Unlock()
+		if full {
+			go t.export()
+		}
+		return nil, nil
+	case trace.NoteEvent:
+		s := spanContext(ctx)
+		if s == nil {
+			return ctx, fmt.Errorf("no current span")
+		}
+		s.setAttr(e.Key, e.Value)
+		return ctx, nil
+	default:
+		panic("unsupported trace event kind")
+	}
+}
+
+// WriteHTTPContext writes the current span's context to the given
+// HTTP header, using the W3C trace context format.
+func (t *Tracer) WriteHTTPContext(ctx context.Context, h *http.Header) {
+	s := spanContext(ctx)
+	if s == nil {
+		return
+	}
+	h.Set(traceparentHeader, fmt.Sprintf("00-%x-%x-01", s.traceID, s.spanID))
+}
+
+// ReadHTTPContext restores the span context from the W3C trace context
+// in the given HTTP header, so that spans started from the returned
+// context become children of the remote span.
+func (t *Tracer) ReadHTTPContext(ctx context.Context, h http.Header) context.Context {
+	parts := strings.Split(h.Get(traceparentHeader), "-")
+	if len(parts) != 4 || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return ctx
+	}
+	var s span
+	if _, err := hex.Decode(s.traceID[:], []byte(parts[1])); err != nil {
+		return ctx
+	}
+	if _, err := hex.Decode(s.spanID[:], []byte(parts[2])); err != nil {
+		return ctx
+	}
+	return context.WithValue(ctx, spanKey, &s)
+}
+
+// CopyTraceContext copies the trace context from src to dst and implements the
+// trace.Tracer interface. Do not use directly, instead use trace.CopyTraceContext.
+func (t *Tracer) CopyTraceContext(src, dst context.Context) context.Context {
+	if s := spanContext(src); s != nil {
+		return context.WithValue(dst, spanKey, s)
+	}
+	return dst
+}
+
+// URL returns the ID of the trace associated with ctx, qualified by
+// the endpoint to which it is exported.
+func (t *Tracer) URL(ctx context.Context) string {
+	s := spanContext(ctx)
+	if s == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s (trace %x)", t.Endpoint, s.traceID)
+}
+
+// Flush exports all finished spans and can be called concurrently.
+func (t *Tracer) Flush() {
+	t.export()
+}
+
+func (t *Tracer) batchSize() int {
+	if t.BatchSize <= 0 {
+		return defaultBatchSize
+	}
+	return t.BatchSize
+}
+
+// export exports all finished spans to the endpoint. Spans which fail
+// to be exported are dropped, since a tracer must not block.
+func (t *Tracer) export() {
+	t.exportMu.Lock()
+	defer t.exportMu.Unlock()
+	t.mu.Lock()
+	spans := t.spans
+	t.spans = nil
+	t.mu.Unlock()
+	if len(spans) == 0 {
+		return
+	}
+	if err := t.post(spans); err != nil {
+		log.Errorf("otlptrace: export %d spans: %v", len(spans), err)
+	}
+}
+
+func (t *Tracer) post(spans []*span) error {
+	b, err := json.Marshal(t.request(spans))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, t.Endpoint, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	headers, _ := parseHeaders(t.Headers)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	client := t.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<10))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// request returns the OTLP export request for the given spans.
+func (t *Tracer) request(spans []*span) exportRequest {
+	service := t.ServiceName
+	if service == "" {
+		service = "reflow"
+	}
+	out := make([]otlpSpan, len(spans))
+	for i, s := range spans {
+		s.mu.Lock()
+		out[i] = otlpSpan{
+			TraceID:           hex.EncodeToString(s.traceID[:]),
+			SpanID:            hex.EncodeToString(s.spanID[:]),
+			Name:              s.name,
+			Kind:              spanKind(s.kind),
+			StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+			Attributes:        append([]attribute(nil), s.attrs...),
+		}
+		if s.parent != ([8]byte{}) {
+			out[i].ParentSpanID = hex.EncodeToString(s.parent[:])
+		}
+		if _, ok := s.attrValue("error"); ok {
+			out[i].Status = &status{Code: statusCodeError}
+		}
+		s.mu.Unlock()
+	}
+	return exportRequest{ResourceSpans: []resourceSpans{{
+		Resource: resource{Attributes: []attribute{{"service.name", anyValue(service)}}},
+		ScopeSpans: []scopeSpans{{
+			Scope: scope{Name: scopeName},
+			Spans: out,
+		}},
+	}}}
+}
+
+// attrValue returns the value of attribute key; s.mu must be held.
+func (s *span) attrValue(key string) (value, bool) {
+	for _, a := range s.attrs {
+		if a.Key == key {
+			return a.Value, true
+		}
+	}
+	return value{}, false
+}
+
+// OTLP span kinds.
+const (
+	spanKindInternal = 1
+	spanKindClient   = 3
+)
+
+// statusCodeError is the OTLP status code of failed spans.
+const statusCodeError = 2
+
+// spanKind returns the OTLP span kind of the given reflow span kind.
+// Allocation requests and transfers are calls to remote services;
+// everything else is internal to the evaluation.
+func spanKind(k trace.Kind) int {
+	switch k {
+	case trace.AllocReq, trace.Transfer:
+		return spanKindClient
+	default:
+		return spanKindInternal
+	}
+}
+
+func randBytes(b []byte) {
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+}
+
+// parseHeaders parses comma-separated key=value pairs.
+func parseHeaders(s string) (map[string]string, error) {
+	headers := make(map[string]string)
+	for _, kv := range strings.Split(s, ",") {
+		if kv = strings.TrimSpace(kv); kv == "" {
+			continue
+		}
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("otlptrace: invalid header %q (must be key=value)", kv)
+		}
+		headers[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	return headers, nil
+}
+
+// The following types mirror the JSON encoding of the OTLP trace
+// export request: https://opentelemetry.io/docs/specs/otlp/#json-protobuf-encoding
+// Trace and span IDs are hex-encoded and 64-bit integers are
+// encoded as decimal strings.
+
+type exportRequest struct {
+	ResourceSpans []resourceSpans `json:"resourceSpans"`
+}
+
+type resourceSpans struct {
+	Resource   resource     `json:"resource"`
+	ScopeSpans []scopeSpans `json:"scopeSpans"`
+}
+
+type resource struct {
+	Attributes []attribute `json:"attributes"`
+}
+
+type scopeSpans struct {
+	Scope scope      `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type scope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string      `json:"traceId"`
+	SpanID            string      `json:"spanId"`
+	ParentSpanID      string      `json:"parentSpanId,omitempty"`
+	Name              string      `json:"name"`
+	Kind              int         `json:"kind"`
+	StartTimeUnixNano string      `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string      `json:"endTimeUnixNano"`
+	Attributes        []attribute `json:"attributes,omitempty"`
+	Status            *status     `json:"status,omitempty"`
+}
+
+type status struct {
+	Code int `json:"code"`
+}
+
+type attribute struct {
+	Key   string `json:"key"`
+	Value value  `json:"value"`
+}
+
+type value struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+// anyValue returns the OTLP attribute value of v. Values of types
+// other than strings, booleans and numbers are formatted as strings.
+func anyValue(v interface{}) value {
+	var intValue = func(i int64) value {
+		s := strconv.FormatInt(i, 10)
+		return value{IntValue: &s}
+	}
+	switch v := v.(type) {
+	case string:
+		return value{StringValue: &v}
+	case bool:
+		return value{BoolValue: &v}
+	case int:
+		return intValue(int64(v))
+	case int32:
+		return intValue(int64(v))
+	case int64:
+		return intValue(v)
+	case uint32:
+		return intValue(int64(v))
+	case float32:
+		f := float64(v)
+		return value{DoubleValue: &f}
+	case float64:
+		return value{DoubleValue: &v}
+	case fmt.Stringer:
+		s := v.String()
+		return value{StringValue: &s}
+	default:
+		s := fmt.Sprint(v)
+		return value{StringValue: &s}
+	}
+}
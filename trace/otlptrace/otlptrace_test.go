@@ -0,0 +1,134 @@
+// Copyright 2021 GRAIL, Inc. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package otlptrace
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/grailbio/reflow"
+	"github.com/grailbio/reflow/trace"
+)
+
+func TestExport(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		reqs []exportRequest
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got, want := r.Header.Get("x-honeycomb-team"), "secret"; got != want {
+			t.Errorf("got %v, want %v", got, want)
+		}
+		var req exportRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		mu.Lock()
+		reqs = append(reqs, req)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	tracer := &Tracer{Endpoint: srv.URL, Headers: "x-honeycomb-team=secret"}
+	if err := tracer.Init(); err != nil {
+		t.Fatal(err)
+	}
+	ctx := trace.WithTracer(context.Background(), tracer)
+	runCtx, runDone := trace.StartNode(ctx, trace.Run, reflow.Digester.FromString("run"), "align.rf", "run")
+	execCtx, execDone := trace.StartNode(runCtx, trace.Exec, reflow.Digester.FromString("exec"), "align.Main.bam", "exec")
+	trace.Note(execCtx, "allocID", "alloc1")
+	trace.Note(execCtx, "hourlyCostUSD", 0.5)
+	trace.Note(execCtx, "retries", 2)
+	execDone()
+	runDone()
+	tracer.Flush()
+
+	if got, want := len(reqs), 1; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	spans := reqs[0].ResourceSpans[0].ScopeSpans[0].Spans
+	if got, want := len(spans), 2; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	exec, run := spans[0], spans[1]
+	if got, want := exec.TraceID, run.TraceID; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := exec.ParentSpanID, run.SpanID; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if run.ParentSpanID != "" {
+		t.Errorf("unexpected parent %s", run.ParentSpanID)
+	}
+	attrs := make(map[string]value)
+	for _, a := range exec.Attributes {
+		attrs[a.Key] = a.Value
+	}
+	if got, want := *attrs["reflow.id"].StringValue, reflow.Digester.FromString("exec").String(); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := *attrs["reflow.logical_id"].StringValue, "align.rf/align.Main.bam"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := *attrs["allocID"].StringValue, "alloc1"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := *attrs["hourlyCostUSD"].DoubleValue, 0.5; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := *attrs["retries"].IntValue, "2"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// Spans are exported only once.
+	tracer.Flush()
+	if got, want := len(reqs), 1; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestHTTPContext(t *testing.T) {
+	tracer := &Tracer{}
+	if err := tracer.Init(); err != nil {
+		t.Fatal(err)
+	}
+	ctx, _ := tracer.Emit(context.Background(), trace.Event{Kind: trace.StartEvent, SpanKind: trace.Run, Name: "run"})
+	h := make(http.Header)
+	tracer.WriteHTTPContext(ctx, &h)
+	remote := spanContext(tracer.ReadHTTPContext(context.Background(), h))
+	if remote == nil {
+		t.Fatal("no span context")
+	}
+	local := spanContext(ctx)
+	if got, want := remote.traceID, local.traceID; got != want {
+		t.Errorf("got %x, want %x", got, want)
+	}
+	if got, want := remote.spanID, local.spanID; got != want {
+		t.Errorf("got %x, want %x", got, want)
+	}
+	if ctx := tracer.ReadHTTPContext(context.Background(), http.Header{}); spanContext(ctx) != nil {
+		t.Error("unexpected span context")
+	}
+}
+
+func TestParseHeaders(t *testing.T) {
+	h, err := parseHeaders(" a=b, c = d=e ,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(h), 2; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got, want := h["c"], "d=e"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if _, err := parseHeaders("a"); err == nil {
+		t.Error("expected error")
+	}
+}
@@ -36,6 +36,7 @@ import (
 	"time"
 
 	"github.com/grailbio/base/digest"
+	rfcontext "github.com/grailbio/reflow/context"
 )
 
 // Kind is the type of spans.
@@ -66,13 +67,41 @@ var nopFunc = func() {}
 // used to create child spans.  Notes on the context will be associated with fresh span/or annotations
 // on the current span (implementation dependent). Calling done() ends the span.
 func Start(ctx context.Context, kind Kind, id digest.Digest, name string) (outctx context.Context, done func()) {
+	return start(ctx, kind, id, "", name)
+}
+
+// StartNode is like Start, but also associates the span with the
+// logical node it represents; see Event.LogicalId. The logical IDs of
+// spans within a run are qualified by the run's logical ID (typically
+// derived from its program), so that nodes of different programs are
+// not confused. If logicalID is empty, StartNode behaves like Start.
+func StartNode(ctx context.Context, kind Kind, id digest.Digest, logicalID, name string) (outctx context.Context, done func()) {
+	if !On(ctx) || logicalID == "" {
+		return Start(ctx, kind, id, name)
+	}
+	if kind == Run {
+		ctx = context.WithValue(ctx, rfcontext.LogicalIdKey, logicalID)
+	} else if run := RunLogicalID(ctx); run != "" {
+		logicalID = run + "/" + logicalID
+	}
+	return start(ctx, kind, id, logicalID, name)
+}
+
+// RunLogicalID returns the logical ID of the run enclosing the span of
+// the provided context, or an empty string if there is none.
+func RunLogicalID(ctx context.Context) string {
+	id, _ := ctx.Value(rfcontext.LogicalIdKey).(string)
+	return id
+}
+
+func start(ctx context.Context, kind Kind, id digest.Digest, logicalID, name string) (context.Context, func()) {
 	if !On(ctx) {
 		return ctx, nopFunc
 	}
 	t := tracer(ctx)
-	ctx, _ = t.Emit(ctx, Event{Time: time.Now(), SpanKind: kind, Id: id, Name: name, Kind: StartEvent})
+	ctx, _ = t.Emit(ctx, Event{Time: time.Now(), SpanKind: kind, Id: id, LogicalId: logicalID, Name: name, Kind: StartEvent})
 	return ctx, func() {
-		t.Emit(ctx, Event{Time: time.Now(), SpanKind: kind, Id: id, Name: name, Kind: EndEvent})
+		t.Emit(ctx, Event{Time: time.Now(), SpanKind: kind, Id: id, LogicalId: logicalID, Name: name, Kind: EndEvent})
 	}
 }
 
@@ -45,6 +45,13 @@ type Event struct {
 	Name string
 	// Kind of span.
 	SpanKind Kind
+	// LogicalId is the stable, logical identifier of the node
+	// represented by the span (e.g., a program, or one of its execs).
+	// Unlike Id, which depends on the node's inputs, LogicalId is the
+	// same across runs, and retried runs, of the same program, so that
+	// tracers may link their spans for comparison. LogicalId is empty
+	// for spans which do not represent a logical node.
+	LogicalId string
 }
 
 // Tracer is a sink for trace events. Tracer implementations should
@@ -84,6 +84,9 @@ func (Tracer) Emit(ctx context.Context, e trace.Event) (context.Context, error)
 		seg.AddAnnotation("id", e.Id.String())
 		seg.AddAnnotation("kind", e.SpanKind.String())
 		seg.AddAnnotation("name", e.Name)
+		if e.LogicalId != "" {
+			seg.AddAnnotation("logical_id", e.LogicalId)
+		}
 		return ctx, nil
 	case trace.EndEvent:
 		seg := xray.GetSegment(ctx)